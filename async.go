@@ -0,0 +1,129 @@
+package txmap
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// ErrAsyncPutterClosed is reported by AsyncPutter.PutAsync after Close was called.
+var ErrAsyncPutterClosed = errors.New("async putter is closed")
+
+// AsyncPutter inserts into a TxMap from a bounded pool of worker goroutines,
+// so that ingestion pipelines can overlap hashing and validation with map
+// insertion without managing their own batching.
+//
+// Writes are grouped by bucket: on a ShardedTxMap every bucket is owned by
+// exactly one worker, so workers never contend for the same bucket lock and
+// the writes to a bucket are applied in the order they were submitted. Other
+// maps are treated as a single bucket, owned by one worker.
+type AsyncPutter struct {
+	m       TxMap
+	buckets uint16
+	queues  []chan asyncPut
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// asyncPut is a single write queued on an AsyncPutter.
+type asyncPut struct {
+	hash  chainhash.Hash
+	value uint64
+	done  chan error
+}
+
+// NewAsyncPutter starts the workers of an AsyncPutter writing to m.
+//
+// Params:
+//   - m: The map to write to.
+//   - workers: The number of worker goroutines. Values below one mean one; it
+//     is capped at the number of buckets of m.
+//   - queueSize: The number of pending writes each worker buffers before
+//     PutAsync blocks.
+//
+// Returns:
+//   - *AsyncPutter: The running AsyncPutter; Close must be called to stop it.
+func NewAsyncPutter(m TxMap, workers, queueSize int) *AsyncPutter {
+	m = nonNilTxMap(m)
+
+	p := &AsyncPutter{m: m}
+
+	if sm, ok := AsSharded(m); ok {
+		p.buckets = sm.Buckets()
+	}
+
+	workers = max(1, min(workers, int(p.buckets)+1))
+	p.queues = make([]chan asyncPut, workers)
+
+	for i := range p.queues {
+		p.queues[i] = make(chan asyncPut, max(0, queueSize))
+
+		p.wg.Add(1)
+
+		go p.work(p.queues[i])
+	}
+
+	return p
+}
+
+// PutAsync queues hash for insertion with value and returns a channel that
+// receives the result of the Put once it ran. It blocks while the queue of
+// the responsible worker is full.
+//
+// Params:
+//   - hash: The hash to add.
+//   - value: The value to associate with the hash.
+//
+// Returns:
+//   - <-chan error: Receives exactly one value, the error returned by Put, or
+//     ErrAsyncPutterClosed if the AsyncPutter was closed.
+func (p *AsyncPutter) PutAsync(hash chainhash.Hash, value uint64) <-chan error {
+	done := make(chan error, 1)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		done <- ErrAsyncPutterClosed
+		return done
+	}
+
+	bucket := uint16(0)
+	if p.buckets > 0 {
+		bucket = Bytes2Uint16Buckets(hash, p.buckets)
+	}
+
+	p.queues[int(bucket)%len(p.queues)] <- asyncPut{hash: hash, value: value, done: done}
+
+	return done
+}
+
+// Close stops accepting writes, waits until all queued writes were applied
+// and stops the workers. It is safe to call more than once.
+func (p *AsyncPutter) Close() {
+	p.mu.Lock()
+
+	if !p.closed {
+		p.closed = true
+
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// work applies the writes of one queue until it is closed.
+func (p *AsyncPutter) work(queue <-chan asyncPut) {
+	defer p.wg.Done()
+
+	for put := range queue {
+		put.done <- p.m.Put(put.hash, put.value)
+	}
+}
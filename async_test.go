@@ -0,0 +1,42 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAsyncPutter tests PutAsync on split and plain maps, including the
+// results of failing writes and writes after Close.
+func TestAsyncPutter(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+			p := NewAsyncPutter(m, 8, 16)
+
+			results := make([]<-chan error, 0, 1001)
+			for i := 0; i < 1000; i++ {
+				results = append(results, p.PutAsync(hashN(i), uint64(i)))
+			}
+
+			results = append(results, p.PutAsync(hashN(0), 1))
+
+			for _, r := range results[:1000] {
+				require.NoError(t, <-r)
+			}
+
+			require.ErrorIs(t, <-results[1000], ErrHashAlreadyExists)
+
+			p.Close()
+			p.Close()
+
+			require.ErrorIs(t, <-p.PutAsync(hashN(2000), 1), ErrAsyncPutterClosed)
+			assert.Equal(t, 1000, m.Length())
+
+			v, ok := m.Get(hashN(999))
+			require.True(t, ok)
+			assert.Equal(t, uint64(999), v)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package txmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Automatic bucket freezing
+//
+// Freeze removes the read lock from every lookup, but only once the whole map
+// is done being written. Most maps of this package are read-mostly per bucket
+// rather than as a whole: after the initial load, the writes of a block touch
+// some buckets and leave the rest cold. AutoFreezeMap is a HybridSplitMap
+// whose buckets are promoted to lock-free tables by their write rate instead
+// of by a quiet period, so that reads of cold buckets are lock-free while the
+// map stays writable.
+//
+// A background loop counts the writes of every bucket per Interval and
+// promotes a bucket after ColdIntervals consecutive intervals with at most
+// MaxColdWrites writes. Promotion is O(1); the first write to a promoted
+// bucket demotes it by copying it, which is O(bucket size), as described in
+// hybrid.go. A hot bucket is therefore never promoted, and a bucket that
+// flips between cold and hot pays one copy per flip.
+
+const (
+	// DefaultAutoFreezeInterval is the AutoFreezeOptions.Interval used when
+	// it is zero.
+	DefaultAutoFreezeInterval = time.Second
+
+	// DefaultAutoFreezeColdIntervals is the AutoFreezeOptions.ColdIntervals
+	// used when it is below one.
+	DefaultAutoFreezeColdIntervals = 3
+)
+
+// check that AutoFreezeMap implements TxMap
+var _ TxMap = (*AutoFreezeMap)(nil)
+
+// AutoFreezeOptions configure the freezing policy of an AutoFreezeMap.
+type AutoFreezeOptions struct {
+	// Interval is the period over which the writes of each bucket are counted.
+	// Defaults to DefaultAutoFreezeInterval when zero; a negative interval
+	// starts no background loop, leaving the calls to Promote to the caller.
+	Interval time.Duration
+
+	// ColdIntervals is the number of consecutive cold intervals after which a
+	// bucket is promoted. Defaults to DefaultAutoFreezeColdIntervals.
+	ColdIntervals int
+
+	// MaxColdWrites is the number of writes an interval may see and still
+	// count as cold.
+	MaxColdWrites uint64
+}
+
+// AutoFreezeStats describes the buckets of an AutoFreezeMap.
+type AutoFreezeStats struct {
+	// Frozen is the number of buckets currently promoted.
+	Frozen int
+
+	// Promotions is the number of times Promote promoted a bucket.
+	Promotions uint64
+
+	// Thaws is the number of times a promoted bucket was copied for a write.
+	Thaws uint64
+}
+
+// AutoFreezeMap is a HybridSplitMap that promotes its cold buckets by their
+// write rate. See the notes at the top of this file.
+type AutoFreezeMap struct {
+	*HybridSplitMap
+
+	opts AutoFreezeOptions
+
+	// promoteMu serializes Promote, which owns the cold counters.
+	promoteMu  sync.Mutex
+	cold       []int
+	promotions atomic.Uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewAutoFreezeMap creates an AutoFreezeMap and starts its background loop
+// unless opts.Interval is negative.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - nrOfBuckets: The highest bucket index; buckets 0..nrOfBuckets exist.
+//   - opts: The freezing policy.
+//
+// Returns:
+//   - *AutoFreezeMap: The map; Close must be called to stop its loop. The
+//     quiet period of its HybridSplitMap, used by PromoteQuiet, is
+//     ColdIntervals intervals.
+func NewAutoFreezeMap(length uint32, nrOfBuckets uint16, opts AutoFreezeOptions) *AutoFreezeMap {
+	if opts.Interval == 0 {
+		opts.Interval = DefaultAutoFreezeInterval
+	}
+
+	if opts.ColdIntervals < 1 {
+		opts.ColdIntervals = DefaultAutoFreezeColdIntervals
+	}
+
+	quiet := max(opts.Interval, 0) * time.Duration(opts.ColdIntervals)
+
+	a := &AutoFreezeMap{
+		HybridSplitMap: NewHybridSplitMap(length, quiet, nrOfBuckets),
+		opts:           opts,
+		cold:           make([]int, int(nrOfBuckets)+1),
+		stop:           make(chan struct{}),
+	}
+
+	if opts.Interval > 0 {
+		a.wg.Add(1)
+
+		go a.run()
+	}
+
+	return a
+}
+
+// Promote runs one pass of the freezing policy: it ends the current interval
+// of every bucket and promotes the buckets that have been cold for long
+// enough. The background loop calls it every Interval.
+//
+// Returns:
+//   - int: The number of buckets promoted by this pass.
+func (a *AutoFreezeMap) Promote() int {
+	a.promoteMu.Lock()
+	defer a.promoteMu.Unlock()
+
+	promoted := 0
+
+	for i, b := range a.m {
+		if b.writes.Swap(0) > a.opts.MaxColdWrites {
+			a.cold[i] = 0
+			continue
+		}
+
+		if a.cold[i]++; a.cold[i] < a.opts.ColdIntervals {
+			continue
+		}
+
+		if b.promote(0, false) {
+			a.promotions.Add(1)
+
+			promoted++
+		}
+	}
+
+	return promoted
+}
+
+// Stats returns the number of promoted buckets and the transitions so far.
+func (a *AutoFreezeMap) Stats() AutoFreezeStats {
+	stats := AutoFreezeStats{
+		Frozen:     a.Promoted(),
+		Promotions: a.promotions.Load(),
+	}
+
+	for _, b := range a.m {
+		stats.Thaws += b.demotions.Load()
+	}
+
+	return stats
+}
+
+// Close stops the background loop. The map remains usable. It is safe to call
+// more than once.
+func (a *AutoFreezeMap) Close() {
+	a.closeOnce.Do(func() { close(a.stop) })
+	a.wg.Wait()
+}
+
+// Clear empties and demotes every bucket, restarts the write counting and
+// un-freezes the map. Like the Clear of the other maps it must not run
+// concurrently with any other operation on the map.
+func (a *AutoFreezeMap) Clear() {
+	a.promoteMu.Lock()
+	defer a.promoteMu.Unlock()
+
+	a.HybridSplitMap.Clear()
+
+	for i, b := range a.m {
+		b.writes.Store(0)
+		a.cold[i] = 0
+	}
+}
+
+// run calls Promote every Interval until Close.
+func (a *AutoFreezeMap) run() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.Promote()
+		}
+	}
+}
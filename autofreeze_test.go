@@ -0,0 +1,116 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAutoFreezeMapPromoteAndThaw tests that cold buckets are promoted after
+// ColdIntervals passes and demoted by the next write.
+func TestAutoFreezeMapPromoteAndThaw(t *testing.T) {
+	a := NewAutoFreezeMap(0, 3, AutoFreezeOptions{Interval: -1, ColdIntervals: 2})
+	defer a.Close()
+
+	for i := range 100 {
+		require.NoError(t, a.Put(hashN(i), uint64(i)))
+	}
+
+	// the writes above make buckets 0..2 hot for the first pass, while bucket
+	// 3, which Bytes2Uint16Buckets never routes to, is cold from the start
+	assert.Equal(t, 0, a.Promote())
+	assert.Equal(t, 1, a.Promote())
+	assert.Equal(t, 3, a.Promote())
+	assert.Equal(t, AutoFreezeStats{Frozen: 4, Promotions: 4}, a.Stats())
+
+	value, ok := a.Get(hashN(7))
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), value)
+
+	table := a.bucket(hashN(7)).table.Load()
+
+	require.NoError(t, a.Set(hashN(7), 70))
+	require.ErrorIs(t, a.Put(hashN(7), 8), ErrHashAlreadyExists)
+	assert.Equal(t, AutoFreezeStats{Frozen: 3, Promotions: 4, Thaws: 1}, a.Stats())
+
+	// the published table is untouched, the map sees the write
+	value, _ = table.Get(hashN(7))
+	assert.Equal(t, uint64(7), value)
+
+	value, _ = a.Get(hashN(7))
+	assert.Equal(t, uint64(70), value)
+	assert.Equal(t, 100, a.Length())
+	assert.Len(t, a.Keys(), 100)
+
+	// the written bucket is hot again and must cool down before refreezing
+	assert.Equal(t, 0, a.Promote())
+	assert.Equal(t, 0, a.Promote())
+	assert.Equal(t, 1, a.Promote())
+}
+
+// TestAutoFreezeMapFreezeClear tests the Freeze and Clear lifecycle.
+func TestAutoFreezeMapFreezeClear(t *testing.T) {
+	a := NewAutoFreezeMap(0, 3, AutoFreezeOptions{Interval: -1})
+	defer a.Close()
+
+	require.NoError(t, a.Put(hashN(1), 1))
+
+	a.Freeze()
+	require.ErrorIs(t, a.Put(hashN(2), 2), ErrMapFrozen)
+	assert.Equal(t, 4, a.Stats().Frozen)
+	assert.True(t, a.Exists(hashN(1)))
+
+	a.Clear()
+	assert.Equal(t, 0, a.Length())
+	assert.Equal(t, 0, a.Stats().Frozen)
+	require.NoError(t, a.Put(hashN(2), 2))
+}
+
+// TestAutoFreezeMapConcurrent tests reads and writes racing the background
+// loop, for the race detector.
+func TestAutoFreezeMapConcurrent(t *testing.T) {
+	a := NewAutoFreezeMap(0, 15, AutoFreezeOptions{Interval: time.Millisecond, ColdIntervals: 1})
+	defer a.Close()
+
+	for i := range 1000 {
+		require.NoError(t, a.Put(hashN(i), uint64(i)))
+	}
+
+	var wg sync.WaitGroup
+
+	for w := range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 2000 {
+				if w == 0 && i%50 == 0 {
+					assert.NoError(t, a.Set(hashN(i%1000), uint64(i)))
+					time.Sleep(100 * time.Microsecond)
+				}
+
+				assert.True(t, a.Exists(hashN(i%1000)))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1000, a.Length())
+	assert.Positive(t, a.Stats().Promotions)
+}
+
+// TestAutoFreezeMapConformance runs the TxMap conformance tests against an
+// AutoFreezeMap whose loop promotes every bucket without writes in the last
+// millisecond, and checks its invariants afterwards.
+func TestAutoFreezeMapConformance(t *testing.T) {
+	a := NewAutoFreezeMap(100, 15, AutoFreezeOptions{Interval: time.Millisecond, ColdIntervals: 1})
+	defer a.Close()
+
+	testTxMap(t, a)
+	require.NoError(t, a.CheckInvariants())
+}
@@ -0,0 +1,412 @@
+package txmap
+
+import (
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// batchBucket is implemented by the leaf maps used as buckets of the split
+// maps, so that the split maps can hand each bucket its share of a batch.
+type batchBucket interface {
+	getMultiAt(hashes []chainhash.Hash, positions []int, values []uint64, found []bool)
+	deleteMultiAt(hashes []chainhash.Hash, positions []int) (int, error)
+	putMultiCheckedAt(hashes []chainhash.Hash, positions []int, value uint64, dupe []bool) error
+	addMultiAt(hashes []chainhash.Hash, positions []int, delta uint64, insert bool) (int, error)
+	countExistingAt(hashes []chainhash.Hash, positions []int) int
+	existsAt(hashes []chainhash.Hash, positions []int, found []bool)
+}
+
+// GetMulti looks up all hashes under a single read lock.
+//
+// Params:
+//   - hashes: The hashes to look up.
+//
+// Returns:
+//   - []uint64: The value of each hash, 0 if it does not exist.
+//   - []bool: Whether each hash exists.
+func (s *SwissMapUint64) GetMulti(hashes []chainhash.Hash) ([]uint64, []bool) {
+	return leafGetMulti(s, hashes)
+}
+
+// DeleteMulti deletes all hashes under a single write lock.
+//
+// Params:
+//   - hashes: The hashes to delete.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen, or an error wrapping
+//     ErrHashDoesNotExist for the first hash that did not exist; all hashes
+//     that existed are deleted either way.
+func (s *SwissMapUint64) DeleteMulti(hashes []chainhash.Hash) error {
+	return leafDeleteMulti(s, hashes)
+}
+
+// getMultiAt looks up hashes[i] for every i in positions and stores the
+// results at index i of values and found.
+func (s *SwissMapUint64) getMultiAt(hashes []chainhash.Hash, positions []int, values []uint64, found []bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	for _, i := range positions {
+		values[i], found[i] = s.m.Get(hashes[i])
+	}
+}
+
+// deleteMultiAt deletes hashes[i] for every i in positions and returns the
+// first position whose hash did not exist, or -1.
+func (s *SwissMapUint64) deleteMultiAt(hashes []chainhash.Hash, positions []int) (int, error) {
+	if s.frozen.Load() {
+		return -1, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missing := -1
+
+	for _, i := range positions {
+		if !s.m.Delete(hashes[i]) {
+			if missing < 0 {
+				missing = i
+			}
+
+			continue
+		}
+
+		debugAssertLength(s.length.Add(-1))
+	}
+
+	return missing, nil
+}
+
+// PutMultiChecked adds every hash that does not exist yet with value n under
+// a single write lock, and returns the hashes that already existed instead of
+// failing on them. A hash occurring twice in hashes is added once and
+// returned once as a duplicate. The duplicate policy of the map is not
+// applied: existing values are never changed.
+//
+// Params:
+//   - hashes: The hashes to add.
+//   - n: The value to associate with every new hash.
+//
+// Returns:
+//   - []chainhash.Hash: The hashes that were not added because they existed,
+//     in the order of hashes.
+//   - error: ErrMapFrozen if the map is frozen, in which case nothing is added.
+func (s *SwissMapUint64) PutMultiChecked(hashes []chainhash.Hash, n uint64) ([]chainhash.Hash, error) {
+	return leafPutMultiChecked(s, hashes, n)
+}
+
+// putMultiCheckedAt adds hashes[i] for every i in positions unless it
+// exists, and sets dupe[i] for those that did.
+func (s *SwissMapUint64) putMultiCheckedAt(hashes []chainhash.Hash, positions []int, value uint64, dupe []bool) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range positions {
+		if _, exists := s.m.Get(hashes[i]); exists {
+			dupe[i] = true
+			continue
+		}
+
+		debugAssertHash(hashes[i])
+		s.m.Put(hashes[i], value)
+		debugAssertLength(s.length.Add(1))
+	}
+
+	return nil
+}
+
+// GetMulti looks up all hashes under a single read lock.
+//
+// Params:
+//   - hashes: The hashes to look up.
+//
+// Returns:
+//   - []uint64: The value of each hash, 0 if it does not exist.
+//   - []bool: Whether each hash exists.
+func (s *NativeMapUint64) GetMulti(hashes []chainhash.Hash) ([]uint64, []bool) {
+	return leafGetMulti(s, hashes)
+}
+
+// DeleteMulti deletes all hashes under a single write lock.
+//
+// Params:
+//   - hashes: The hashes to delete.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen, or an error wrapping
+//     ErrHashDoesNotExist for the first hash that did not exist; all hashes
+//     that existed are deleted either way.
+func (s *NativeMapUint64) DeleteMulti(hashes []chainhash.Hash) error {
+	return leafDeleteMulti(s, hashes)
+}
+
+// getMultiAt looks up hashes[i] for every i in positions and stores the
+// results at index i of values and found.
+func (s *NativeMapUint64) getMultiAt(hashes []chainhash.Hash, positions []int, values []uint64, found []bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	for _, i := range positions {
+		values[i], found[i] = s.m[hashes[i]]
+	}
+}
+
+// deleteMultiAt deletes hashes[i] for every i in positions and returns the
+// first position whose hash did not exist, or -1.
+func (s *NativeMapUint64) deleteMultiAt(hashes []chainhash.Hash, positions []int) (int, error) {
+	if s.frozen.Load() {
+		return -1, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missing := -1
+
+	for _, i := range positions {
+		if _, ok := s.m[hashes[i]]; !ok {
+			if missing < 0 {
+				missing = i
+			}
+
+			continue
+		}
+
+		delete(s.m, hashes[i])
+		debugAssertLength(s.length.Add(-1))
+	}
+
+	return missing, nil
+}
+
+// PutMultiChecked adds every hash that does not exist yet under a single write
+// lock and returns the hashes that already existed. See
+// SwissMapUint64.PutMultiChecked.
+func (s *NativeMapUint64) PutMultiChecked(hashes []chainhash.Hash, n uint64) ([]chainhash.Hash, error) {
+	return leafPutMultiChecked(s, hashes, n)
+}
+
+// putMultiCheckedAt adds hashes[i] for every i in positions unless it
+// exists, and sets dupe[i] for those that did.
+func (s *NativeMapUint64) putMultiCheckedAt(hashes []chainhash.Hash, positions []int, value uint64, dupe []bool) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range positions {
+		if _, exists := s.m[hashes[i]]; exists {
+			dupe[i] = true
+			continue
+		}
+
+		debugAssertHash(hashes[i])
+		s.m[hashes[i]] = value
+		debugAssertLength(s.length.Add(1))
+	}
+
+	return nil
+}
+
+// GetMulti looks up all hashes, taking the read lock of each bucket once.
+func (g *SplitSwissMap) GetMulti(hashes []chainhash.Hash) ([]uint64, []bool) {
+	return splitGetMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// DeleteMulti deletes all hashes, taking the write lock of each bucket once.
+// See SwissMapUint64.DeleteMulti for the error semantics.
+func (g *SplitSwissMap) DeleteMulti(hashes []chainhash.Hash) error {
+	return splitDeleteMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// GetMulti looks up all hashes, taking the read lock of each bucket once.
+func (g *SplitSwissMapUint64) GetMulti(hashes []chainhash.Hash) ([]uint64, []bool) {
+	return splitGetMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// DeleteMulti deletes all hashes, taking the write lock of each bucket once.
+// See SwissMapUint64.DeleteMulti for the error semantics.
+func (g *SplitSwissMapUint64) DeleteMulti(hashes []chainhash.Hash) error {
+	return splitDeleteMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// GetMulti looks up all hashes, taking the read lock of each bucket once.
+func (g *NativeSplitMap) GetMulti(hashes []chainhash.Hash) ([]uint64, []bool) {
+	return splitGetMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// DeleteMulti deletes all hashes, taking the write lock of each bucket once.
+// See NativeMapUint64.DeleteMulti for the error semantics.
+func (g *NativeSplitMap) DeleteMulti(hashes []chainhash.Hash) error {
+	return splitDeleteMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// GetMulti looks up all hashes, taking the read lock of each bucket once.
+func (g *NativeSplitMapUint64) GetMulti(hashes []chainhash.Hash) ([]uint64, []bool) {
+	return splitGetMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// DeleteMulti deletes all hashes, taking the write lock of each bucket once.
+// See NativeMapUint64.DeleteMulti for the error semantics.
+func (g *NativeSplitMapUint64) DeleteMulti(hashes []chainhash.Hash) error {
+	return splitDeleteMulti(g.m, g.nrOfBuckets, hashes)
+}
+
+// PutMultiChecked adds every hash that does not exist yet, taking the write
+// lock of each bucket once, and returns the hashes that already existed. See
+// SwissMapUint64.PutMultiChecked; a frozen map adds nothing.
+func (g *SplitSwissMap) PutMultiChecked(hashes []chainhash.Hash, n uint64) ([]chainhash.Hash, error) {
+	return splitPutMultiChecked(g.m, g.nrOfBuckets, hashes, n)
+}
+
+// PutMultiChecked adds every hash that does not exist yet, taking the write
+// lock of each bucket once, and returns the hashes that already existed. See
+// SwissMapUint64.PutMultiChecked; a frozen map adds nothing.
+func (g *SplitSwissMapUint64) PutMultiChecked(hashes []chainhash.Hash, n uint64) ([]chainhash.Hash, error) {
+	return splitPutMultiChecked(g.m, g.nrOfBuckets, hashes, n)
+}
+
+// PutMultiChecked adds every hash that does not exist yet, taking the write
+// lock of each bucket once, and returns the hashes that already existed. See
+// SwissMapUint64.PutMultiChecked; a frozen map adds nothing.
+func (g *NativeSplitMap) PutMultiChecked(hashes []chainhash.Hash, n uint64) ([]chainhash.Hash, error) {
+	return splitPutMultiChecked(g.m, g.nrOfBuckets, hashes, n)
+}
+
+// PutMultiChecked adds every hash that does not exist yet, taking the write
+// lock of each bucket once, and returns the hashes that already existed. See
+// SwissMapUint64.PutMultiChecked; a frozen map adds nothing.
+func (g *NativeSplitMapUint64) PutMultiChecked(hashes []chainhash.Hash, n uint64) ([]chainhash.Hash, error) {
+	return splitPutMultiChecked(g.m, g.nrOfBuckets, hashes, n)
+}
+
+// leafGetMulti implements GetMulti for a leaf map.
+func leafGetMulti(b batchBucket, hashes []chainhash.Hash) ([]uint64, []bool) {
+	values := make([]uint64, len(hashes))
+	found := make([]bool, len(hashes))
+
+	b.getMultiAt(hashes, allPositions(len(hashes)), values, found)
+
+	return values, found
+}
+
+// leafDeleteMulti implements DeleteMulti for a leaf map.
+func leafDeleteMulti(b batchBucket, hashes []chainhash.Hash) error {
+	missing, err := b.deleteMultiAt(hashes, allPositions(len(hashes)))
+	if err != nil {
+		return err
+	}
+
+	if missing >= 0 {
+		return fmt.Errorf("%w: %s", ErrHashDoesNotExist, hashes[missing])
+	}
+
+	return nil
+}
+
+// splitGetMulti implements GetMulti for a split map.
+func splitGetMulti[B batchBucket](buckets []B, nrOfBuckets uint16, hashes []chainhash.Hash) ([]uint64, []bool) {
+	values := make([]uint64, len(hashes))
+	found := make([]bool, len(hashes))
+
+	for bucket, positions := range bucketGroups(hashes, nrOfBuckets) {
+		buckets[bucket].getMultiAt(hashes, positions, values, found)
+	}
+
+	return values, found
+}
+
+// splitDeleteMulti implements DeleteMulti for a split map.
+func splitDeleteMulti[B batchBucket](buckets []B, nrOfBuckets uint16, hashes []chainhash.Hash) error {
+	missing := -1
+
+	for bucket, positions := range bucketGroups(hashes, nrOfBuckets) {
+		m, err := buckets[bucket].deleteMultiAt(hashes, positions)
+		if err != nil {
+			return err
+		}
+
+		if m >= 0 && (missing < 0 || m < missing) {
+			missing = m
+		}
+	}
+
+	if missing >= 0 {
+		return fmt.Errorf("%w: %s", ErrHashDoesNotExist, hashes[missing])
+	}
+
+	return nil
+}
+
+// leafPutMultiChecked implements PutMultiChecked for a leaf map.
+func leafPutMultiChecked(b batchBucket, hashes []chainhash.Hash, value uint64) ([]chainhash.Hash, error) {
+	dupe := make([]bool, len(hashes))
+
+	if err := b.putMultiCheckedAt(hashes, allPositions(len(hashes)), value, dupe); err != nil {
+		return nil, err
+	}
+
+	return duplicatesOf(hashes, dupe), nil
+}
+
+// splitPutMultiChecked implements PutMultiChecked for a split map.
+func splitPutMultiChecked[B batchBucket](buckets []B, nrOfBuckets uint16, hashes []chainhash.Hash, value uint64) ([]chainhash.Hash, error) {
+	dupe := make([]bool, len(hashes))
+
+	for bucket, positions := range bucketGroups(hashes, nrOfBuckets) {
+		if err := buckets[bucket].putMultiCheckedAt(hashes, positions, value, dupe); err != nil {
+			return nil, err
+		}
+	}
+
+	return duplicatesOf(hashes, dupe), nil
+}
+
+// duplicatesOf returns hashes[i] for every i with dupe[i] set.
+func duplicatesOf(hashes []chainhash.Hash, dupe []bool) []chainhash.Hash {
+	var dupes []chainhash.Hash
+
+	for i, d := range dupe {
+		if d {
+			dupes = append(dupes, hashes[i])
+		}
+	}
+
+	return dupes
+}
+
+// bucketGroups groups the positions of hashes by the bucket they route to.
+func bucketGroups(hashes []chainhash.Hash, nrOfBuckets uint16) map[uint16][]int {
+	groups := make(map[uint16][]int)
+
+	for i, hash := range hashes {
+		bucket := Bytes2Uint16Buckets(hash, nrOfBuckets)
+		groups[bucket] = append(groups[bucket], i)
+	}
+
+	return groups
+}
+
+// allPositions returns the positions 0..n-1.
+func allPositions(n int) []int {
+	positions := make([]int, n)
+
+	for i := range positions {
+		positions[i] = i
+	}
+
+	return positions
+}
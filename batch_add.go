@@ -0,0 +1,201 @@
+package txmap
+
+import (
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// AddMulti adds delta to the value of every hash that exists under a single
+// write lock, for accumulators such as per-transaction seen counts. A hash
+// occurring more than once in hashes gets delta added once per occurrence.
+// Values wrap around modulo 2^64.
+//
+// Params:
+//   - hashes: The hashes whose values to increment.
+//   - delta: The amount to add.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen, in which case nothing is
+//     changed, or an error wrapping ErrHashDoesNotExist naming the first hash
+//     that did not exist; the values of the others are incremented anyway.
+func (s *SwissMapUint64) AddMulti(hashes []chainhash.Hash, delta uint64) error {
+	return leafAddMulti(s, hashes, delta, false)
+}
+
+// AddOrPutMulti adds delta to the value of every hash that exists, and adds
+// every other hash with value delta, under a single write lock. A hash
+// occurring more than once in hashes is added by its first occurrence and
+// incremented by the others.
+//
+// Params:
+//   - hashes: The hashes whose values to increment or add.
+//   - delta: The amount to add, and the value of the hashes added.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen, in which case nothing is
+//     changed.
+func (s *SwissMapUint64) AddOrPutMulti(hashes []chainhash.Hash, delta uint64) error {
+	return leafAddMulti(s, hashes, delta, true)
+}
+
+// addMultiAt adds delta to the value of hashes[i] for every i in positions,
+// adding the missing hashes with value delta if insert is set, and returns
+// the first position whose hash did not exist and was not added, or -1.
+func (s *SwissMapUint64) addMultiAt(hashes []chainhash.Hash, positions []int, delta uint64, insert bool) (int, error) {
+	if s.frozen.Load() {
+		return -1, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missing := -1
+
+	for _, i := range positions {
+		value, exists := s.m.Get(hashes[i])
+
+		switch {
+		case exists:
+			s.m.Put(hashes[i], value+delta)
+		case insert:
+			debugAssertHash(hashes[i])
+			s.m.Put(hashes[i], delta)
+			debugAssertLength(s.length.Add(1))
+		case missing < 0:
+			missing = i
+		}
+	}
+
+	return missing, nil
+}
+
+// AddMulti adds delta to the value of every hash that exists under a single
+// write lock. See SwissMapUint64.AddMulti.
+func (s *NativeMapUint64) AddMulti(hashes []chainhash.Hash, delta uint64) error {
+	return leafAddMulti(s, hashes, delta, false)
+}
+
+// AddOrPutMulti adds delta to the value of every hash that exists, and adds
+// the others with value delta, under a single write lock. See
+// SwissMapUint64.AddOrPutMulti.
+func (s *NativeMapUint64) AddOrPutMulti(hashes []chainhash.Hash, delta uint64) error {
+	return leafAddMulti(s, hashes, delta, true)
+}
+
+// addMultiAt is the NativeMapUint64 version of SwissMapUint64.addMultiAt.
+func (s *NativeMapUint64) addMultiAt(hashes []chainhash.Hash, positions []int, delta uint64, insert bool) (int, error) {
+	if s.frozen.Load() {
+		return -1, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missing := -1
+
+	for _, i := range positions {
+		value, exists := s.m[hashes[i]]
+
+		switch {
+		case exists:
+			s.m[hashes[i]] = value + delta
+		case insert:
+			debugAssertHash(hashes[i])
+			s.m[hashes[i]] = delta
+			debugAssertLength(s.length.Add(1))
+		case missing < 0:
+			missing = i
+		}
+	}
+
+	return missing, nil
+}
+
+// AddMulti adds delta to the value of every hash that exists, taking the
+// write lock of each bucket once. See SwissMapUint64.AddMulti.
+func (g *SplitSwissMap) AddMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, false)
+}
+
+// AddOrPutMulti adds delta to the value of every hash that exists, and adds
+// the others with value delta, taking the write lock of each bucket once. See
+// SwissMapUint64.AddOrPutMulti.
+func (g *SplitSwissMap) AddOrPutMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, true)
+}
+
+// AddMulti adds delta to the value of every hash that exists, taking the
+// write lock of each bucket once. See SwissMapUint64.AddMulti.
+func (g *SplitSwissMapUint64) AddMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, false)
+}
+
+// AddOrPutMulti adds delta to the value of every hash that exists, and adds
+// the others with value delta, taking the write lock of each bucket once. See
+// SwissMapUint64.AddOrPutMulti.
+func (g *SplitSwissMapUint64) AddOrPutMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, true)
+}
+
+// AddMulti adds delta to the value of every hash that exists, taking the
+// write lock of each bucket once. See SwissMapUint64.AddMulti.
+func (g *NativeSplitMap) AddMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, false)
+}
+
+// AddOrPutMulti adds delta to the value of every hash that exists, and adds
+// the others with value delta, taking the write lock of each bucket once. See
+// SwissMapUint64.AddOrPutMulti.
+func (g *NativeSplitMap) AddOrPutMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, true)
+}
+
+// AddMulti adds delta to the value of every hash that exists, taking the
+// write lock of each bucket once. See SwissMapUint64.AddMulti.
+func (g *NativeSplitMapUint64) AddMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, false)
+}
+
+// AddOrPutMulti adds delta to the value of every hash that exists, and adds
+// the others with value delta, taking the write lock of each bucket once. See
+// SwissMapUint64.AddOrPutMulti.
+func (g *NativeSplitMapUint64) AddOrPutMulti(hashes []chainhash.Hash, delta uint64) error {
+	return splitAddMulti(g.m, g.nrOfBuckets, hashes, delta, true)
+}
+
+// leafAddMulti implements AddMulti and AddOrPutMulti for a leaf map.
+func leafAddMulti(b batchBucket, hashes []chainhash.Hash, delta uint64, insert bool) error {
+	missing, err := b.addMultiAt(hashes, allPositions(len(hashes)), delta, insert)
+	if err != nil {
+		return err
+	}
+
+	if missing >= 0 {
+		return fmt.Errorf("%w: %s", ErrHashDoesNotExist, hashes[missing])
+	}
+
+	return nil
+}
+
+// splitAddMulti implements AddMulti and AddOrPutMulti for a split map.
+func splitAddMulti[B batchBucket](buckets []B, nrOfBuckets uint16, hashes []chainhash.Hash, delta uint64, insert bool) error {
+	missing := -1
+
+	for bucket, positions := range bucketGroups(hashes, nrOfBuckets) {
+		m, err := buckets[bucket].addMultiAt(hashes, positions, delta, insert)
+		if err != nil {
+			return err
+		}
+
+		if m >= 0 && (missing < 0 || m < missing) {
+			missing = m
+		}
+	}
+
+	if missing >= 0 {
+		return fmt.Errorf("%w: %s", ErrHashDoesNotExist, hashes[missing])
+	}
+
+	return nil
+}
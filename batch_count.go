@@ -0,0 +1,116 @@
+package txmap
+
+import (
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// CountExisting returns how many of hashes exist in m, e.g. how much of a
+// block a node already has. Maps implementing BatchTxMap count them taking
+// each lock once; other maps are asked with Exists for every hash.
+//
+// Params:
+//   - m: The map to look up in.
+//   - hashes: The hashes to look up; a hash occurring more than once is
+//     counted once per occurrence.
+//
+// Returns:
+//   - int: The number of hashes that exist.
+func CountExisting(m ReadOnlyTxMap, hashes []chainhash.Hash) int {
+	if bm, ok := m.(BatchTxMap); ok {
+		return bm.CountExisting(hashes)
+	}
+
+	count := 0
+
+	for _, hash := range hashes {
+		if m.Exists(hash) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// CountExisting returns how many of hashes exist, looking them up under a
+// single read lock. A hash occurring more than once is counted once per
+// occurrence.
+func (s *SwissMapUint64) CountExisting(hashes []chainhash.Hash) int {
+	return s.countExistingAt(hashes, allPositions(len(hashes)))
+}
+
+// countExistingAt returns how many of hashes[i] for i in positions exist.
+func (s *SwissMapUint64) countExistingAt(hashes []chainhash.Hash, positions []int) int {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	count := 0
+
+	for _, i := range positions {
+		if s.m.Has(hashes[i]) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// CountExisting returns how many of hashes exist, looking them up under a
+// single read lock. See SwissMapUint64.CountExisting.
+func (s *NativeMapUint64) CountExisting(hashes []chainhash.Hash) int {
+	return s.countExistingAt(hashes, allPositions(len(hashes)))
+}
+
+// countExistingAt returns how many of hashes[i] for i in positions exist.
+func (s *NativeMapUint64) countExistingAt(hashes []chainhash.Hash, positions []int) int {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	count := 0
+
+	for _, i := range positions {
+		if _, ok := s.m[hashes[i]]; ok {
+			count++
+		}
+	}
+
+	return count
+}
+
+// CountExisting returns how many of hashes exist, taking the read lock of
+// each bucket once. See SwissMapUint64.CountExisting.
+func (g *SplitSwissMap) CountExisting(hashes []chainhash.Hash) int {
+	return splitCountExisting(g.m, g.nrOfBuckets, hashes)
+}
+
+// CountExisting returns how many of hashes exist, taking the read lock of
+// each bucket once. See SwissMapUint64.CountExisting.
+func (g *SplitSwissMapUint64) CountExisting(hashes []chainhash.Hash) int {
+	return splitCountExisting(g.m, g.nrOfBuckets, hashes)
+}
+
+// CountExisting returns how many of hashes exist, taking the read lock of
+// each bucket once. See SwissMapUint64.CountExisting.
+func (g *NativeSplitMap) CountExisting(hashes []chainhash.Hash) int {
+	return splitCountExisting(g.m, g.nrOfBuckets, hashes)
+}
+
+// CountExisting returns how many of hashes exist, taking the read lock of
+// each bucket once. See SwissMapUint64.CountExisting.
+func (g *NativeSplitMapUint64) CountExisting(hashes []chainhash.Hash) int {
+	return splitCountExisting(g.m, g.nrOfBuckets, hashes)
+}
+
+// splitCountExisting implements CountExisting for a split map.
+func splitCountExisting[B batchBucket](buckets []B, nrOfBuckets uint16, hashes []chainhash.Hash) int {
+	count := 0
+
+	for bucket, positions := range bucketGroups(hashes, nrOfBuckets) {
+		count += buckets[bucket].countExistingAt(hashes, positions)
+	}
+
+	return count
+}
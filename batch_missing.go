@@ -0,0 +1,158 @@
+package txmap
+
+import (
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Missing returns the hashes that do not exist in m, in the order of hashes,
+// e.g. the transactions of an inv message to request from a peer. Maps
+// implementing BatchTxMap look them up taking each lock once; other maps are
+// asked with Exists for every hash.
+//
+// Params:
+//   - m: The map to look up in.
+//   - hashes: The hashes to look up; a missing hash occurring more than once
+//     is returned once per occurrence.
+//
+// Returns:
+//   - []chainhash.Hash: The missing hashes, nil if all exist.
+func Missing(m ReadOnlyTxMap, hashes []chainhash.Hash) []chainhash.Hash {
+	if bm, ok := m.(BatchTxMap); ok {
+		return bm.Missing(hashes)
+	}
+
+	var missing []chainhash.Hash
+
+	for _, hash := range hashes {
+		if !m.Exists(hash) {
+			missing = append(missing, hash)
+		}
+	}
+
+	return missing
+}
+
+// Missing returns the hashes that do not exist, in the order of hashes,
+// looking them up under a single read lock. A missing hash occurring more
+// than once is returned once per occurrence.
+//
+// Params:
+//   - hashes: The hashes to look up.
+//
+// Returns:
+//   - []chainhash.Hash: The missing hashes, nil if all exist.
+func (s *SwissMapUint64) Missing(hashes []chainhash.Hash) []chainhash.Hash {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	var missing []chainhash.Hash
+
+	for _, hash := range hashes {
+		if !s.m.Has(hash) {
+			missing = append(missing, hash)
+		}
+	}
+
+	return missing
+}
+
+// existsAt sets found[i] for every i in positions whose hash exists.
+func (s *SwissMapUint64) existsAt(hashes []chainhash.Hash, positions []int, found []bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	for _, i := range positions {
+		found[i] = s.m.Has(hashes[i])
+	}
+}
+
+// Missing returns the hashes that do not exist, in the order of hashes,
+// looking them up under a single read lock. See SwissMapUint64.Missing.
+func (s *NativeMapUint64) Missing(hashes []chainhash.Hash) []chainhash.Hash {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	var missing []chainhash.Hash
+
+	for _, hash := range hashes {
+		if _, ok := s.m[hash]; !ok {
+			missing = append(missing, hash)
+		}
+	}
+
+	return missing
+}
+
+// existsAt sets found[i] for every i in positions whose hash exists.
+func (s *NativeMapUint64) existsAt(hashes []chainhash.Hash, positions []int, found []bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	for _, i := range positions {
+		_, found[i] = s.m[hashes[i]]
+	}
+}
+
+// Missing returns the hashes that do not exist, in the order of hashes,
+// taking the read lock of each bucket once. See SwissMapUint64.Missing.
+func (g *SplitSwissMap) Missing(hashes []chainhash.Hash) []chainhash.Hash {
+	return splitMissing(g.m, g.nrOfBuckets, hashes)
+}
+
+// Missing returns the hashes that do not exist, in the order of hashes,
+// taking the read lock of each bucket once. See SwissMapUint64.Missing.
+func (g *SplitSwissMapUint64) Missing(hashes []chainhash.Hash) []chainhash.Hash {
+	return splitMissing(g.m, g.nrOfBuckets, hashes)
+}
+
+// Missing returns the hashes that do not exist, in the order of hashes,
+// taking the read lock of each bucket once. See SwissMapUint64.Missing.
+func (g *NativeSplitMap) Missing(hashes []chainhash.Hash) []chainhash.Hash {
+	return splitMissing(g.m, g.nrOfBuckets, hashes)
+}
+
+// Missing returns the hashes that do not exist, in the order of hashes,
+// taking the read lock of each bucket once. See SwissMapUint64.Missing.
+func (g *NativeSplitMapUint64) Missing(hashes []chainhash.Hash) []chainhash.Hash {
+	return splitMissing(g.m, g.nrOfBuckets, hashes)
+}
+
+// splitMissing implements Missing for a split map. The buckets mark the
+// hashes they hold, and the unmarked ones are collected in order, so the
+// only allocations besides the result are the grouping and one byte per hash.
+func splitMissing[B batchBucket](buckets []B, nrOfBuckets uint16, hashes []chainhash.Hash) []chainhash.Hash {
+	found := make([]bool, len(hashes))
+	count := 0
+
+	for bucket, positions := range bucketGroups(hashes, nrOfBuckets) {
+		buckets[bucket].existsAt(hashes, positions, found)
+	}
+
+	for _, f := range found {
+		if !f {
+			count++
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	missing := make([]chainhash.Hash, 0, count)
+
+	for i, f := range found {
+		if !f {
+			missing = append(missing, hashes[i])
+		}
+	}
+
+	return missing
+}
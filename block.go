@@ -0,0 +1,240 @@
+package txmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+const (
+	// blockHeaderSize is the size of a serialized block header in bytes.
+	blockHeaderSize = 80
+
+	// blockMerkleRootOffset is the offset of the merkle root in the header.
+	blockMerkleRootOffset = 36
+)
+
+// ErrInvalidBlock is returned by LoadFromBlockReader for a stream that is not
+// a serialized block, or whose transactions do not match its merkle root.
+var ErrInvalidBlock = errors.New("invalid block")
+
+// LoadFromBlockReader reads a block in the network serialization, the format
+// of the blk*.dat files of a node and of the getblock RPC in raw mode, and
+// adds the txid of each of its transactions to m, the most common way to
+// bootstrap a map.
+//
+// Transactions are hashed as they are read, so only the txids of the block
+// are held in memory. They are checked against the merkle root of the header
+// before any is added, so a truncated or corrupt block leaves m untouched.
+//
+// Params:
+//   - m: The map to add the txids to.
+//   - r: The block; it is read through a buffer up to the end of the block.
+//   - value: Returns the value of the txid at index i of the block; nil stores
+//     the index itself.
+//
+// Returns:
+//   - int: The number of txids added before an error.
+//   - error: An error wrapping ErrInvalidBlock if the block cannot be parsed
+//     or its merkle root does not match, or the first error returned by Put,
+//     as for PutKVs.
+func LoadFromBlockReader(m TxMap, r io.Reader, value func(i int, txid chainhash.Hash) uint64) (int, error) {
+	txids, err := ReadBlockTxIDs(r)
+	if err != nil {
+		return 0, err
+	}
+
+	kvs := make([]KV, len(txids))
+
+	for i, txid := range txids {
+		kvs[i] = KV{Hash: txid, Value: uint64(i)} //nolint:gosec // G115 i is not negative
+
+		if value != nil {
+			kvs[i].Value = value(i, txid)
+		}
+	}
+
+	return PutKVs(m, kvs)
+}
+
+// ReadBlockTxIDs reads a block in the network serialization from r and
+// returns the txids of its transactions in block order, checked against the
+// merkle root of its header.
+//
+// Params:
+//   - r: The block; it is read through a buffer up to the end of the block.
+//
+// Returns:
+//   - []chainhash.Hash: The txids.
+//   - error: An error wrapping ErrInvalidBlock if the block cannot be parsed
+//     or its merkle root does not match.
+func ReadBlockTxIDs(r io.Reader) ([]chainhash.Hash, error) {
+	br := bufio.NewReaderSize(r, 1<<16)
+
+	var header [blockHeaderSize]byte
+
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrInvalidBlock, err)
+	}
+
+	var tx bytes.Buffer
+
+	count, err := readVarInt(br, &tx)
+	if err != nil || count == 0 {
+		return nil, fmt.Errorf("%w: transaction count: %w", ErrInvalidBlock, errors.Join(err, io.ErrUnexpectedEOF))
+	}
+
+	// the count is not trusted for the allocation; a corrupt one fails below
+	txids := make([]chainhash.Hash, 0, min(count, 1<<20))
+
+	for i := uint64(0); i < count; i++ {
+		tx.Reset()
+
+		if err = readTx(br, &tx); err != nil {
+			return nil, fmt.Errorf("%w: transaction %d: %w", ErrInvalidBlock, i, err)
+		}
+
+		txids = append(txids, chainhash.DoubleHashH(tx.Bytes()))
+	}
+
+	if root := merkleRoot(txids); !bytes.Equal(root[:], header[blockMerkleRootOffset:blockMerkleRootOffset+chainhash.HashSize]) {
+		return nil, fmt.Errorf("%w: merkle root mismatch", ErrInvalidBlock)
+	}
+
+	return txids, nil
+}
+
+// readTx copies one serialized transaction from r to tx.
+func readTx(r *bufio.Reader, tx *bytes.Buffer) error {
+	// version
+	if err := copyN(r, tx, 4); err != nil {
+		return err
+	}
+
+	inputs, err := readVarInt(r, tx)
+	if err != nil {
+		return err
+	}
+
+	for ; inputs > 0; inputs-- {
+		// previous txid and output index, script, sequence
+		if err = copyN(r, tx, chainhash.HashSize+4); err != nil {
+			return err
+		}
+
+		if err = copyScript(r, tx); err != nil {
+			return err
+		}
+
+		if err = copyN(r, tx, 4); err != nil {
+			return err
+		}
+	}
+
+	outputs, err := readVarInt(r, tx)
+	if err != nil {
+		return err
+	}
+
+	for ; outputs > 0; outputs-- {
+		// satoshis, script
+		if err = copyN(r, tx, 8); err != nil {
+			return err
+		}
+
+		if err = copyScript(r, tx); err != nil {
+			return err
+		}
+	}
+
+	// lock time
+	return copyN(r, tx, 4)
+}
+
+// copyScript copies a length-prefixed script from r to tx.
+func copyScript(r *bufio.Reader, tx *bytes.Buffer) error {
+	n, err := readVarInt(r, tx)
+	if err != nil {
+		return err
+	}
+
+	if n > 1<<40 {
+		return fmt.Errorf("script length %d: %w", n, io.ErrUnexpectedEOF)
+	}
+
+	return copyN(r, tx, int64(n)) //nolint:gosec // G115 checked above
+}
+
+// copyN copies n bytes from r to tx. The buffer grows with the bytes read, so
+// a corrupt length ends in io.ErrUnexpectedEOF rather than a huge allocation.
+func copyN(r *bufio.Reader, tx *bytes.Buffer, n int64) error {
+	copied, err := io.CopyN(tx, r, n)
+	if copied < n && (err == nil || errors.Is(err, io.EOF)) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// readVarInt reads a Bitcoin variable length integer from r, copying its
+// bytes to tx.
+func readVarInt(r *bufio.Reader, tx *bytes.Buffer) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	tx.WriteByte(prefix)
+
+	var size int
+
+	switch prefix {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(prefix), nil
+	}
+
+	var b [8]byte
+
+	if _, err = io.ReadFull(r, b[:size]); err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	tx.Write(b[:size])
+
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+// merkleRoot returns the merkle root of txids, duplicating the last hash of
+// every level with an odd number of hashes.
+func merkleRoot(txids []chainhash.Hash) chainhash.Hash {
+	level := append([]chainhash.Hash(nil), txids...)
+
+	var pair [2 * chainhash.HashSize]byte
+
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+
+		for i := 0; i < len(level); i += 2 {
+			copy(pair[:], level[i][:])
+			copy(pair[chainhash.HashSize:], level[i+1][:])
+			level[i/2] = chainhash.DoubleHashH(pair[:])
+		}
+
+		level = level[:len(level)/2]
+	}
+
+	return level[0]
+}
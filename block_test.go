@@ -0,0 +1,99 @@
+package txmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// genesisBlock is the serialized genesis block.
+const genesisBlock = "0100000000000000000000000000000000000000000000000000000000000000000000003ba3edfd7a7b12b27ac72c3e67768f617fc81bc3888a51323a9fb8aa4b1e5e4a29ab5f49ffff001d1dac2b7c" +
+	"01" +
+	"01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff4d04ffff001d0104455468652054696d65732030332f4a616e2f32303039204368616e63656c6c6f72206f6e206272696e6b206f66207365636f6e64206261696c6f757420666f722062616e6b73ffffffff0100f2052a01000000434104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac00000000"
+
+// testBlock serializes a block of n transactions, each spending output i of
+// a made-up transaction and with a script of 300 bytes, so that its length
+// takes a 3-byte varint.
+func testBlock(n int) ([]byte, []chainhash.Hash) {
+	txs := make([][]byte, n)
+	txids := make([]chainhash.Hash, n)
+
+	for i := range txs {
+		tx := []byte{1, 0, 0, 0, 1}
+		tx = append(tx, bytes.Repeat([]byte{byte(i)}, chainhash.HashSize)...)
+		tx = binary.LittleEndian.AppendUint32(tx, uint32(i)) //nolint:gosec // test data
+		tx = append(tx, 0xfd, 0x2c, 0x01)
+		tx = append(tx, bytes.Repeat([]byte{0x51}, 300)...)
+		tx = append(tx, 0xff, 0xff, 0xff, 0xff, 1)
+		tx = binary.LittleEndian.AppendUint64(tx, uint64(i))
+		tx = append(tx, 1, 0x51, 0, 0, 0, 0)
+
+		txs[i], txids[i] = tx, chainhash.DoubleHashH(tx)
+	}
+
+	root := merkleRoot(txids)
+	block := make([]byte, blockHeaderSize, blockHeaderSize+1+n*400)
+	copy(block[blockMerkleRootOffset:], root[:])
+	block = append(block, byte(n))
+
+	for _, tx := range txs {
+		block = append(block, tx...)
+	}
+
+	return block, txids
+}
+
+// TestLoadFromBlockReaderGenesis tests the txid of the genesis coinbase.
+func TestLoadFromBlockReaderGenesis(t *testing.T) {
+	raw, err := hex.DecodeString(genesisBlock)
+	require.NoError(t, err)
+
+	m := NewSwissMapUint64(0)
+
+	n, err := LoadFromBlockReader(m, bytes.NewReader(raw), func(int, chainhash.Hash) uint64 { return 7 })
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	coinbase, err := chainhash.NewHashFromStr("4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b")
+	require.NoError(t, err)
+
+	value, ok := m.Get(*coinbase)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(7), value)
+}
+
+// TestLoadFromBlockReader tests a block with an odd number of transactions
+// and the rejection of corrupt blocks.
+func TestLoadFromBlockReader(t *testing.T) {
+	block, txids := testBlock(5)
+
+	m := NewSplitSwissMapUint64(0, 4)
+
+	n, err := LoadFromBlockReader(m, bytes.NewReader(block), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	for i, txid := range txids {
+		value, ok := m.Get(txid)
+		require.True(t, ok)
+		assert.Equal(t, uint64(i), value) //nolint:gosec // test data
+	}
+
+	corrupt := bytes.Clone(block)
+	corrupt[len(corrupt)-10] ^= 1
+
+	_, err = LoadFromBlockReader(NewSwissMapUint64(0), bytes.NewReader(corrupt), nil)
+	require.ErrorIs(t, err, ErrInvalidBlock)
+	assert.Contains(t, err.Error(), "merkle root")
+
+	empty := NewSwissMapUint64(0)
+
+	_, err = LoadFromBlockReader(empty, bytes.NewReader(block[:len(block)-3]), nil)
+	require.ErrorIs(t, err, ErrInvalidBlock)
+	assert.Equal(t, 0, empty.Length())
+}
@@ -0,0 +1,194 @@
+package txmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Bloom filter export format
+//
+//	header: magic "TXBF" | version uint8 | k uint8 | reserved uint16 | bits uint64
+//	words:  ceil(bits / 64) × uint64
+//
+// All integers are little-endian. The filter derives its k bit positions by
+// double hashing two 64-bit words of the transaction hash. Transaction hashes
+// are already uniformly distributed, so no further hashing is needed; bytes 8
+// to 24 are used because the split maps route by the leading bytes, which
+// would correlate a per-bucket filter with its bucket.
+
+const (
+	// bloomMagic identifies an exported bloom filter.
+	bloomMagic = "TXBF"
+
+	// bloomVersion1 is the layout described above.
+	bloomVersion1 = uint8(1)
+
+	// bloomHeaderSize is the encoded size of the header in bytes.
+	bloomHeaderSize = 16
+
+	// bloomMaxHashes caps the number of bit positions per hash.
+	bloomMaxHashes = 30
+
+	// bloomMinFPRate and bloomMaxFPRate bound the false-positive rates accepted
+	// by NewBloomFilter.
+	bloomMinFPRate = 1e-9
+	bloomMaxFPRate = 0.5
+)
+
+// ErrInvalidBloomFilter is returned by NewBloomFromExport for data that is not
+// an exported bloom filter.
+var ErrInvalidBloomFilter = errors.New("invalid bloom filter")
+
+// BloomFilter is an approximate set of transaction hashes: MayContain never
+// reports false for an added hash, and reports true for other hashes with
+// about the false-positive rate the filter was sized for. Add and MayContain
+// are safe for concurrent use.
+type BloomFilter struct {
+	words []atomic.Uint64
+	bits  uint64
+	k     uint8
+}
+
+// NewBloomFilter returns an empty bloom filter sized for n hashes at the given
+// false-positive rate.
+//
+// Params:
+//   - n: The expected number of hashes; values below one mean one.
+//   - fpRate: The target false-positive rate, clamped to [1e-9, 0.5].
+//
+// Returns:
+//   - *BloomFilter: The empty filter.
+func NewBloomFilter(n int, fpRate float64) *BloomFilter {
+	n = max(n, 1)
+	fpRate = min(max(fpRate, bloomMinFPRate), bloomMaxFPRate)
+
+	bits := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	bits = max(64, (bits+63)/64*64)
+	k := int(math.Round(float64(bits) / float64(n) * math.Ln2))
+
+	return newBloomFilter(bits, uint8(min(max(k, 1), bloomMaxHashes))) //nolint:gosec // clamped to 1..30
+}
+
+// newBloomFilter allocates a filter of bits bits with k positions per hash.
+func newBloomFilter(bits uint64, k uint8) *BloomFilter {
+	return &BloomFilter{
+		words: make([]atomic.Uint64, (bits+63)/64),
+		bits:  bits,
+		k:     k,
+	}
+}
+
+// ExportBloom returns an exported bloom filter holding every hash of m, for
+// advertising an approximate summary of a known-transaction set to peers
+// without sending the hashes themselves.
+//
+// Params:
+//   - m: The map to summarize.
+//   - fpRate: The target false-positive rate, clamped to [1e-9, 0.5].
+//
+// Returns:
+//   - []byte: The filter, to be loaded with NewBloomFromExport.
+func ExportBloom(m ReadOnlyTxMap, fpRate float64) []byte {
+	b := NewBloomFilter(m.Length(), fpRate)
+
+	m.Iter(func(hash chainhash.Hash, _ uint64) bool {
+		b.Add(hash)
+		return false
+	})
+
+	return b.Export()
+}
+
+// NewBloomFromExport loads a bloom filter exported by ExportBloom or
+// BloomFilter.Export.
+//
+// Params:
+//   - data: The exported filter.
+//
+// Returns:
+//   - *BloomFilter: The filter.
+//   - error: ErrInvalidBloomFilter if data is not a valid exported filter.
+func NewBloomFromExport(data []byte) (*BloomFilter, error) {
+	if len(data) < bloomHeaderSize || string(data[:4]) != bloomMagic {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidBloomFilter)
+	}
+
+	if data[4] != bloomVersion1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBloomFilter, data[4])
+	}
+
+	k := data[5]
+	bits := binary.LittleEndian.Uint64(data[8:16])
+	words := data[bloomHeaderSize:]
+
+	if k < 1 || k > bloomMaxHashes || bits == 0 || uint64(len(words)) != (bits+63)/64*8 {
+		return nil, fmt.Errorf("%w: %d bits, %d hashes, %d bytes of words", ErrInvalidBloomFilter, bits, k, len(words))
+	}
+
+	b := newBloomFilter(bits, k)
+
+	for i := range b.words {
+		b.words[i].Store(binary.LittleEndian.Uint64(words[i*8:]))
+	}
+
+	return b, nil
+}
+
+// Add adds hash to the filter.
+func (b *BloomFilter) Add(hash chainhash.Hash) {
+	h1, h2 := bloomHashes(hash)
+
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.bits
+		b.words[bit/64].Or(1 << (bit % 64))
+	}
+}
+
+// MayContain reports whether hash may have been added to the filter. False
+// means it definitely was not.
+func (b *BloomFilter) MayContain(hash chainhash.Hash) bool {
+	h1, h2 := bloomHashes(hash)
+
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.bits
+		if b.words[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Clear removes all hashes from the filter. Not safe for concurrent use.
+func (b *BloomFilter) Clear() {
+	for i := range b.words {
+		b.words[i].Store(0)
+	}
+}
+
+// Export encodes the filter in the format read by NewBloomFromExport.
+func (b *BloomFilter) Export() []byte {
+	data := make([]byte, bloomHeaderSize, bloomHeaderSize+len(b.words)*8)
+
+	copy(data[:4], bloomMagic)
+	data[4] = bloomVersion1
+	data[5] = b.k
+	binary.LittleEndian.PutUint64(data[8:16], b.bits)
+
+	for i := range b.words {
+		data = binary.LittleEndian.AppendUint64(data, b.words[i].Load())
+	}
+
+	return data
+}
+
+// bloomHashes returns the two hashes combined by double hashing; h2 is odd so
+// that it never degenerates to a single position.
+func bloomHashes(hash chainhash.Hash) (uint64, uint64) {
+	return binary.LittleEndian.Uint64(hash[8:16]), binary.LittleEndian.Uint64(hash[16:24]) | 1
+}
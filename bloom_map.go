@@ -0,0 +1,274 @@
+package txmap
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that BloomTxMap implements TxMap
+var _ TxMap = (*BloomTxMap)(nil)
+
+// BloomTxMap wraps a TxMap with a bloom filter of its hashes, so that lookups
+// of hashes that are not in the map, the common case when processing inv
+// messages, are answered from the filter without touching the map or its
+// locks.
+//
+// The filter is maintained internally: every hash is added to it before it is
+// added to the map, so a lookup never misses a hash the map holds. A bloom
+// filter cannot forget, so deleted hashes and growth beyond the size the
+// filter was built for raise its false-positive rate; the write that finds
+// the filter more than half over its size, or a quarter of it deleted, builds
+// a new filter from the map, blocking other writes, but no reads, meanwhile.
+// All writes must go through the BloomTxMap.
+type BloomTxMap struct {
+	m        TxMap
+	fpRate   float64
+	expected int
+
+	// mu is held shared by writers and exclusively while the filter is
+	// rebuilt, so that no write is missing from the new filter.
+	mu    sync.RWMutex
+	state atomic.Pointer[bloomState]
+
+	// added counts the hashes in the filter, deleted the ones deleted from
+	// the map since the filter was built.
+	added   atomic.Int64
+	deleted atomic.Int64
+}
+
+// bloomState is a filter and the number of hashes it was sized for.
+type bloomState struct {
+	filter   *BloomFilter
+	capacity int64
+}
+
+// WithBloom returns a BloomTxMap forwarding every operation to m, with a
+// filter built from the current contents of m.
+//
+// Params:
+//   - m: The map to wrap.
+//   - expected: The number of hashes the map is expected to hold; the filter
+//     is sized for at least this many.
+//   - fpRate: The target false-positive rate of the filter, clamped to
+//     [1e-9, 0.5].
+//
+// Returns:
+//   - *BloomTxMap: The wrapping map.
+func WithBloom(m TxMap, expected int, fpRate float64) *BloomTxMap {
+	m = nonNilTxMap(m)
+
+	b := &BloomTxMap{m: m, fpRate: fpRate, expected: expected}
+	b.rebuildLocked()
+
+	return b
+}
+
+// NewSplitSwissMapWithBloom creates a SplitSwissMap behind a bloom filter
+// sized for length hashes, so that Exists and Get of missing hashes take no
+// bucket lock. The returned map must receive every write; see BloomTxMap.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation and to
+//     size the filter.
+//   - fpRate: The target false-positive rate of the filter, as for WithBloom.
+//   - buckets: Optionally the number of buckets, as for NewSplitSwissMap.
+//
+// Returns:
+//   - *BloomTxMap: The split map behind its filter.
+func NewSplitSwissMapWithBloom(length int, fpRate float64, buckets ...uint16) *BloomTxMap {
+	return WithBloom(NewSplitSwissMap(length, buckets...), length, fpRate)
+}
+
+// ExistsFast checks the filter and, unless it rules hash out, the map.
+//
+// Params:
+//   - hash: The hash to look up.
+//
+// Returns:
+//   - bool: True if the filter rules the hash out, without a map lookup.
+//   - bool: True if the hash is in the map; always false if the first result
+//     is true.
+func (b *BloomTxMap) ExistsFast(hash chainhash.Hash) (definitelyNot, inMap bool) {
+	if !b.state.Load().filter.MayContain(hash) {
+		return true, false
+	}
+
+	return false, b.m.Exists(hash)
+}
+
+// Rebuild builds a new filter from the map, sized for twice its current
+// length, restoring the false-positive rate after many deletes. Writes block
+// while it runs; reads do not.
+func (b *BloomTxMap) Rebuild() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rebuildLocked()
+}
+
+// Exists checks if the given hash exists, consulting the filter first.
+func (b *BloomTxMap) Exists(hash chainhash.Hash) bool {
+	_, inMap := b.ExistsFast(hash)
+	return inMap
+}
+
+// Get retrieves the value of the given hash, consulting the filter first.
+func (b *BloomTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	if !b.state.Load().filter.MayContain(hash) {
+		return 0, false
+	}
+
+	return b.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (b *BloomTxMap) Keys() []chainhash.Hash {
+	return b.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (b *BloomTxMap) Length() int {
+	return b.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (b *BloomTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	b.m.Iter(f)
+}
+
+// Put adds hash to the filter and the wrapped map.
+func (b *BloomTxMap) Put(hash chainhash.Hash, value uint64) error {
+	err := b.add(1, func(filter *BloomFilter) error {
+		filter.Add(hash)
+		return b.m.Put(hash, value)
+	})
+
+	b.rebuildIfStale()
+
+	return err
+}
+
+// PutMulti adds hashes to the filter and the wrapped map.
+func (b *BloomTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	err := b.add(int64(len(hashes)), func(filter *BloomFilter) error {
+		for _, hash := range hashes {
+			filter.Add(hash)
+		}
+
+		return b.m.PutMulti(hashes, value)
+	})
+
+	b.rebuildIfStale()
+
+	return err
+}
+
+// Set updates the value of an existing hash in the wrapped map.
+func (b *BloomTxMap) Set(hash chainhash.Hash, value uint64) error {
+	return b.m.Set(hash, value)
+}
+
+// SetIfExists updates the value of hash in the wrapped map if it exists.
+func (b *BloomTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return b.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash to the filter and, if it does not exist yet, to
+// the wrapped map.
+func (b *BloomTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	var set bool
+
+	err := b.add(1, func(filter *BloomFilter) (err error) {
+		filter.Add(hash)
+		set, err = b.m.SetIfNotExists(hash, value)
+
+		return err
+	})
+
+	b.rebuildIfStale()
+
+	return set, err
+}
+
+// Delete removes hash from the wrapped map. The filter keeps it until it is
+// rebuilt.
+func (b *BloomTxMap) Delete(hash chainhash.Hash) error {
+	if err := b.m.Delete(hash); err != nil {
+		return err
+	}
+
+	b.deleted.Add(1)
+	b.rebuildIfStale()
+
+	return nil
+}
+
+// Freeze freezes the wrapped map.
+func (b *BloomTxMap) Freeze() {
+	b.m.Freeze()
+}
+
+// Clear empties the wrapped map and the filter.
+func (b *BloomTxMap) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.m.Clear()
+	b.state.Load().filter.Clear()
+	b.added.Store(0)
+	b.deleted.Store(0)
+}
+
+// add runs write, which adds n hashes to the filter it is passed and then to
+// the map, while holding the write lock shared.
+func (b *BloomTxMap) add(n int64, write func(filter *BloomFilter) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	b.added.Add(n)
+
+	return write(b.state.Load().filter)
+}
+
+// rebuildIfStale rebuilds the filter if more hashes were added than it has
+// room for, or many were deleted.
+func (b *BloomTxMap) rebuildIfStale() {
+	if !b.stale() {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// another writer may have rebuilt it meanwhile
+	if b.stale() {
+		b.rebuildLocked()
+	}
+}
+
+// stale reports whether the filter holds more than one and a half times the
+// hashes it was sized for, or a quarter of them were deleted since.
+func (b *BloomTxMap) stale() bool {
+	capacity := b.state.Load().capacity
+
+	return b.added.Load() > capacity*3/2 || b.deleted.Load() > capacity/4
+}
+
+// rebuildLocked builds a new filter from the map. The caller must hold the
+// write lock exclusively, or be the constructor.
+func (b *BloomTxMap) rebuildLocked() {
+	length := b.m.Length()
+	capacity := max(b.expected, 2*length, 1)
+	filter := NewBloomFilter(capacity, b.fpRate)
+
+	b.m.Iter(func(hash chainhash.Hash, _ uint64) bool {
+		filter.Add(hash)
+		return false
+	})
+
+	b.added.Store(int64(length))
+	b.deleted.Store(0)
+	b.state.Store(&bloomState{filter: filter, capacity: int64(capacity)})
+}
@@ -0,0 +1,141 @@
+package txmap
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBloomTxMapExistsFast tests definite negatives and authoritative
+// positives, including hashes present before wrapping.
+func TestBloomTxMapExistsFast(t *testing.T) {
+	r := rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test data
+	hashes := []chainhash.Hash{randomHash(r), randomHash(r), randomHash(r)}
+
+	inner := NewNativeMapUint64(0)
+	require.NoError(t, inner.Put(hashes[0], 1))
+
+	b := WithBloom(inner, 1000, 0.001)
+	require.NoError(t, b.Put(hashes[1], 2))
+
+	ok, err := b.SetIfNotExists(hashes[2], 3)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	for _, hash := range hashes {
+		definitelyNot, inMap := b.ExistsFast(hash)
+		assert.False(t, definitelyNot)
+		assert.True(t, inMap)
+	}
+
+	negatives := 0
+
+	for range 1000 {
+		definitelyNot, inMap := b.ExistsFast(randomHash(r))
+		assert.False(t, inMap)
+
+		if definitelyNot {
+			negatives++
+		}
+	}
+
+	assert.Greater(t, negatives, 980)
+
+	value, found := b.Get(hashes[1])
+	assert.True(t, found)
+	assert.Equal(t, uint64(2), value)
+}
+
+// TestBloomTxMapRebuild tests that growth and deletes rebuild the filter
+// without ever losing a hash.
+func TestBloomTxMapRebuild(t *testing.T) {
+	r := rand.New(rand.NewSource(2)) //nolint:gosec // deterministic test data
+	hashes := make([]chainhash.Hash, 1000)
+
+	b := WithBloom(NewSplitSwissMapUint64(0, 8), 10, 0.01)
+	first := b.state.Load()
+
+	for i := range hashes {
+		hashes[i] = randomHash(r)
+		require.NoError(t, b.Put(hashes[i], uint64(i)))
+	}
+
+	assert.NotSame(t, first, b.state.Load())
+	assert.GreaterOrEqual(t, b.state.Load().capacity*3/2, int64(1000))
+
+	for i, hash := range hashes {
+		require.True(t, b.Exists(hash), i)
+	}
+
+	grown := b.state.Load()
+
+	for _, hash := range hashes[:900] {
+		require.NoError(t, b.Delete(hash))
+	}
+
+	assert.NotSame(t, grown, b.state.Load())
+
+	for i, hash := range hashes[900:] {
+		require.True(t, b.Exists(hash), i)
+	}
+
+	b.Clear()
+	definitelyNot, _ := b.ExistsFast(hashes[950])
+	assert.True(t, definitelyNot)
+}
+
+// TestNewSplitSwissMapWithBloom tests the split map behind a filter.
+func TestNewSplitSwissMapWithBloom(t *testing.T) {
+	r := rand.New(rand.NewSource(3)) //nolint:gosec // deterministic test data
+
+	b := NewSplitSwissMapWithBloom(1000, 0.001, 8)
+	require.IsType(t, &SplitSwissMap{}, b.m)
+	assert.Equal(t, uint16(8), b.m.(*SplitSwissMap).Buckets())
+
+	hash := randomHash(r)
+	require.NoError(t, b.Put(hash, 1))
+	assert.True(t, b.Exists(hash))
+
+	definitelyNot, _ := b.ExistsFast(randomHash(r))
+	assert.True(t, definitelyNot)
+}
+
+// TestBloomTxMapConcurrent tests that concurrent writes, including the
+// rebuilds they trigger, never hide a hash from a reader.
+func TestBloomTxMapConcurrent(t *testing.T) {
+	r := rand.New(rand.NewSource(3)) //nolint:gosec // deterministic test data
+	hashes := make([]chainhash.Hash, 4000)
+
+	for i := range hashes {
+		hashes[i] = randomHash(r)
+	}
+
+	b := WithBloom(NewSplitSwissMapUint64(0, 16), 100, 0.01)
+
+	var wg sync.WaitGroup
+
+	for w := range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := w; i < len(hashes); i += 4 {
+				assert.NoError(t, b.Put(hashes[i], uint64(i)))
+				assert.True(t, b.Exists(hashes[i]))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, hash := range hashes {
+		definitelyNot, inMap := b.ExistsFast(hash)
+		require.False(t, definitelyNot)
+		require.True(t, inMap)
+	}
+}
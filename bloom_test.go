@@ -0,0 +1,64 @@
+package txmap
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bloomHash returns a uniformly distributed hash derived from i.
+func bloomHash(i int) chainhash.Hash {
+	var b [8]byte
+
+	binary.LittleEndian.PutUint64(b[:], uint64(i)) //nolint:gosec // test indexes are not negative
+
+	return sha256.Sum256(b[:])
+}
+
+// TestExportBloom tests that an exported filter contains every hash of the map
+// and has roughly the requested false-positive rate.
+func TestExportBloom(t *testing.T) {
+	m := NewSplitSwissMapUint64(10000)
+	for i := 0; i < 10000; i++ {
+		require.NoError(t, m.Put(bloomHash(i), 1))
+	}
+
+	b, err := NewBloomFromExport(ExportBloom(m, 0.01))
+	require.NoError(t, err)
+
+	for i := 0; i < 10000; i++ {
+		require.True(t, b.MayContain(bloomHash(i)))
+	}
+
+	falsePositives := 0
+
+	for i := 10000; i < 30000; i++ {
+		if b.MayContain(bloomHash(i)) {
+			falsePositives++
+		}
+	}
+
+	assert.Less(t, falsePositives, 400, "false-positive rate above 2%%")
+
+	b.Clear()
+	assert.False(t, b.MayContain(bloomHash(0)))
+}
+
+// TestNewBloomFromExportInvalid tests that malformed exports are rejected.
+func TestNewBloomFromExportInvalid(t *testing.T) {
+	data := NewBloomFilter(100, 0.01).Export()
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"truncated": data[:len(data)-1],
+		"magic":     append([]byte("TXMP"), data[4:]...),
+		"version":   append(append([]byte(nil), data[:4]...), append([]byte{2}, data[5:]...)...),
+	} {
+		_, err := NewBloomFromExport(bad)
+		require.ErrorIs(t, err, ErrInvalidBloomFilter, name)
+	}
+}
@@ -0,0 +1,82 @@
+package txmap
+
+// Bucket count heuristics
+//
+// The number of buckets of a split map trades lock contention against memory
+// and per-bucket overhead:
+//
+//   - Writers on different buckets never contend. With w writers spread
+//     uniformly over b buckets, a writer finds its bucket taken by another
+//     with probability about w/b, so keeping b at least 16 times w keeps that
+//     below about 6%.
+//
+//   - Every bucket is a map of its own with its own lock and fixed overhead,
+//     and buckets far below a few thousand entries waste more memory on that
+//     overhead and on growth headroom than they save in contention.
+//
+//   - Operations holding a bucket lock for a whole bucket, such as Iter,
+//     Keys and Clear, and the rehash of a growing bucket, stall the writers of
+//     that bucket for a time proportional to its size, so buckets should stay
+//     well below a million entries.
+//
+// RecommendedBuckets applies these bounds around the long-standing default of
+// 1024 buckets and rounds the result up to a power of two, which spreads the
+// hash prefix routed by Bytes2Uint16Buckets evenly.
+
+const (
+	// bucketsPerWriter is the number of buckets per concurrent writer.
+	bucketsPerWriter = 16
+
+	// minEntriesPerBucket is the size below which buckets are not worth their
+	// overhead, unless needed for the writers.
+	minEntriesPerBucket = 1 << 12
+
+	// maxEntriesPerBucket is the size above which whole-bucket operations and
+	// rehashes stall writers for too long.
+	maxEntriesPerBucket = 1 << 20
+
+	// defaultSplitBuckets is the bucket count of the split map constructors.
+	defaultSplitBuckets = 1024
+
+	// maxRecommendedBuckets is the largest power of two below the bucket
+	// limit of the uint16 bucket index.
+	maxRecommendedBuckets = 1 << 15
+)
+
+// RecommendedBuckets returns the number of buckets to pass to the split map
+// constructors for a map expected to hold expectedEntries entries written by
+// up to writers goroutines at once. See the notes at the top of
+// bucket_heuristics.go.
+//
+// Params:
+//   - expectedEntries: The expected number of entries; zero or less if unknown.
+//   - writers: The number of concurrent writers; values below one mean one.
+//
+// Returns:
+//   - uint16: A power of two between 1 and 32768.
+func RecommendedBuckets(expectedEntries, writers int) uint16 {
+	writers = max(writers, 1)
+
+	// the buckets the writers and the entry count need at least
+	lower := writers * bucketsPerWriter
+
+	if expectedEntries > 0 {
+		lower = max(lower, (expectedEntries+maxEntriesPerBucket-1)/maxEntriesPerBucket)
+	}
+
+	// the most buckets worth their overhead, unknown without an entry count
+	upper := max(lower, defaultSplitBuckets)
+
+	if expectedEntries > 0 {
+		upper = max(lower, expectedEntries/minEntriesPerBucket)
+	}
+
+	buckets := min(max(defaultSplitBuckets, lower), upper, maxRecommendedBuckets)
+
+	power := 1
+	for power < buckets {
+		power <<= 1
+	}
+
+	return uint16(min(power, maxRecommendedBuckets)) //nolint:gosec // G115 capped above
+}
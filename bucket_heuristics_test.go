@@ -0,0 +1,51 @@
+package txmap
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRecommendedBuckets tests the bucket counts for typical workloads.
+func TestRecommendedBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries int
+		writers int
+		want    uint16
+	}{
+		{"unknown size, one writer", 0, 0, 1024},
+		{"unknown size, many writers", -1, 256, 4096},
+		{"small map", 10_000, 1, 16},
+		{"small map, many writers", 10_000, 32, 512},
+		{"mempool", 1_000_000, 8, 256},
+		{"large map", 100_000_000, 16, 1024},
+		{"utxo set", 10_000_000_000, 64, 16384},
+		{"huge", 1 << 40, 1, 1 << 15},
+		{"many writers", 0, 1 << 20, 1 << 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RecommendedBuckets(tt.entries, tt.writers))
+		})
+	}
+}
+
+// TestRecommendedBucketsBounds tests that every result is a power of two and
+// keeps the buckets within the size bounds when the writers allow it.
+func TestRecommendedBucketsBounds(t *testing.T) {
+	for entries := 1; entries < 1<<34; entries *= 3 {
+		for _, writers := range []int{1, 4, 64} {
+			buckets := int(RecommendedBuckets(entries, writers))
+
+			assert.Equal(t, 1, bits.OnesCount(uint(buckets)), "entries %d writers %d", entries, writers)
+			assert.GreaterOrEqual(t, buckets, min(writers*bucketsPerWriter, maxRecommendedBuckets))
+
+			if buckets < maxRecommendedBuckets {
+				assert.LessOrEqual(t, entries/buckets, maxEntriesPerBucket)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package txmap
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// BucketView is the read-only view of a single bucket passed to the function
+// of ForEachBucket. It reads the bucket directly, without the routing of the
+// map, so it is only valid during the call.
+type BucketView interface {
+	ReadOnlyTxMap
+}
+
+// ForEachBucket calls fn for every bucket of m from up to parallelism
+// goroutines, for custom bucket-parallel computations such as per-bucket
+// merkle subtrees. Maps that are not split into buckets are passed as bucket
+// 0. Workers take the next bucket from a shared counter, which balances
+// uneven buckets.
+//
+// It has errgroup semantics: the first error returned by fn cancels the
+// remaining work, no bucket is started after that or after ctx is done, and
+// ForEachBucket returns only once all running calls have returned.
+//
+// Params:
+//   - ctx: Stops starting buckets when done.
+//   - m: The map whose buckets to visit.
+//   - parallelism: The number of goroutines; below one means GOMAXPROCS.
+//   - fn: Called once per bucket with its index and a view of it. It may run
+//     concurrently with calls for other buckets and with writes to the map.
+//
+// Returns:
+//   - error: The first error returned by fn, or the context error if ctx was
+//     done before every bucket was visited.
+func ForEachBucket(ctx context.Context, m ReadOnlyTxMap, parallelism int, fn func(bucket uint16, view BucketView) error) error {
+	buckets := txMapBuckets(m)
+
+	if parallelism < 1 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     atomic.Int64
+		visited  atomic.Int64
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for w := min(parallelism, len(buckets)); w > 0; w-- {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := int(next.Add(1) - 1); i < len(buckets); i = int(next.Add(1) - 1) {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if err := fn(uint16(i), buckets[i]); err != nil { //nolint:gosec // G115 i <= nrOfBuckets
+					errOnce.Do(func() {
+						firstErr = err
+
+						cancel()
+					})
+
+					return
+				}
+
+				visited.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// buckets are only skipped once the context of the caller is done
+	if visited.Load() < int64(len(buckets)) {
+		return ctx.Err()
+	}
+
+	return nil
+}
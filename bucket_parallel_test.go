@@ -0,0 +1,112 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestForEachBucket tests that every bucket is visited once and that the
+// views together hold the contents of the map.
+func TestForEachBucket(t *testing.T) {
+	m := NewSplitSwissMapUint64(0, 16)
+	for i := range 1000 {
+		require.NoError(t, m.Put(hashN(i), uint64(i)))
+	}
+
+	var (
+		mu      sync.Mutex
+		visited = make(map[uint16]int)
+		sum     atomic.Uint64
+	)
+
+	err := ForEachBucket(context.Background(), m, 4, func(bucket uint16, view BucketView) error {
+		mu.Lock()
+		visited[bucket]++
+		mu.Unlock()
+
+		view.Iter(func(hash chainhash.Hash, value uint64) bool {
+			assert.Equal(t, bucket, Bytes2Uint16Buckets(hash, 16))
+			sum.Add(value)
+
+			return false
+		})
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, visited, 17)
+
+	for bucket, n := range visited {
+		assert.Equal(t, 1, n, "bucket %d", bucket)
+	}
+
+	assert.Equal(t, uint64(999*1000/2), sum.Load())
+}
+
+// TestForEachBucketSingle tests that a map that is not split is visited as
+// bucket 0.
+func TestForEachBucketSingle(t *testing.T) {
+	m := NewSwissMapUint64(0)
+	require.NoError(t, m.Put(hashN(1), 1))
+
+	var buckets []uint16
+
+	require.NoError(t, ForEachBucket(context.Background(), m, 0, func(bucket uint16, view BucketView) error {
+		buckets = append(buckets, bucket)
+		assert.Equal(t, 1, view.Length())
+
+		return nil
+	}))
+
+	assert.Equal(t, []uint16{0}, buckets)
+}
+
+// TestForEachBucketError tests that the first error stops the remaining
+// buckets and is returned.
+func TestForEachBucketError(t *testing.T) {
+	m := NewNativeSplitMapUint64(0, 64)
+	errBoom := errors.New("boom")
+
+	var calls atomic.Int64
+
+	err := ForEachBucket(context.Background(), m, 1, func(bucket uint16, _ BucketView) error {
+		calls.Add(1)
+
+		if bucket == 3 {
+			return errBoom
+		}
+
+		return nil
+	})
+	require.ErrorIs(t, err, errBoom)
+	assert.Equal(t, int64(4), calls.Load())
+}
+
+// TestForEachBucketContext tests that a done context stops the remaining
+// buckets and is reported.
+func TestForEachBucketContext(t *testing.T) {
+	m := NewSplitSwissMap(0, 64)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int64
+
+	err := ForEachBucket(ctx, m, 2, func(uint16, BucketView) error {
+		if calls.Add(1) == 5 {
+			cancel()
+		}
+
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, calls.Load(), int64(65))
+}
@@ -0,0 +1,243 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// BucketFuncVersion identifies the function routing hashes to buckets,
+	// Bytes2Uint16Buckets: the first two bytes of the hash, big endian, modulo
+	// the number of buckets. PersistBuckets records it, and LoadBuckets only
+	// loads files into the buckets of a map routing with the same function.
+	BucketFuncVersion = 1
+
+	// bucketFuncNone is recorded for maps whose buckets are not determined by
+	// the hash alone, such as TwoChoiceSplitMap, or that are not split.
+	bucketFuncNone = 0
+
+	// bucketFileGlob matches the files written by PersistBuckets.
+	bucketFileGlob = "bucket-*.txmp"
+
+	// bucketFilePattern is the file name of bucket i written by PersistBuckets.
+	bucketFilePattern = "bucket-%05d.txmp"
+
+	// bucketManifestFile is the name of the manifest written by PersistBuckets.
+	bucketManifestFile = "buckets.manifest"
+
+	// bucketManifestFormat is the content of the manifest: the manifest
+	// version, the bucket function version and the number of bucket files.
+	bucketManifestFormat = "txmap-buckets 1\nbucket-func %d\nbuckets %d\n"
+)
+
+// ErrBucketFuncMismatch is returned by LoadBuckets for files persisted with a
+// bucket function other than BucketFuncVersion.
+var ErrBucketFuncMismatch = errors.New("bucket function version mismatch")
+
+// PersistBuckets writes every bucket of m to its own snapshot file in dir, in
+// parallel, so that the files match the sharding of the map in memory and a
+// map with the same layout can load them back bucket by bucket with
+// LoadBuckets. Maps that are not split are written as a single bucket.
+//
+// The manifest describing the files is removed first and written last, so a
+// directory whose persist was interrupted is rejected by LoadBuckets rather
+// than loaded partially. Bucket files are written to temporary files and
+// renamed into place.
+//
+// Params:
+//   - ctx: Checked before each bucket is written.
+//   - m: The map to persist; it must not be written to while this runs.
+//   - dir: The directory to write to; it is created if needed.
+//   - workers: The number of buckets written at once; values below one mean
+//     GOMAXPROCS.
+//
+// Returns:
+//   - error: Any error from Export or the file system.
+func PersistBuckets(ctx context.Context, m ReadOnlyTxMap, dir string, workers int) error {
+	buckets := txMapBuckets(m)
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	manifest := filepath.Join(dir, bucketManifestFile)
+	if err := os.Remove(manifest); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err := forEachIndex(len(buckets), workers, func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf(bucketFilePattern, i)
+
+		tmp, err := exportTempFile(dir, name, buckets[i])
+		if err != nil {
+			return err
+		}
+
+		if err = os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+			_ = os.Remove(tmp)
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	removeStaleBucketFiles(dir, len(buckets))
+
+	content := fmt.Sprintf(bucketManifestFormat, bucketFuncOf(m), len(buckets))
+
+	tmp := manifest + ".tmp"
+	if err = os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, manifest)
+}
+
+// LoadBuckets imports the bucket files written by PersistBuckets in dir into
+// dst, in parallel. If the files come from a split map routing by
+// BucketFuncVersion, and dst is one with as many buckets, every file is
+// loaded straight into its bucket, so the loaders never contend for a lock;
+// otherwise every file is imported into dst as a whole, which routes each
+// hash to the bucket of dst.
+//
+// Params:
+//   - ctx: Cancels the load; passed to Import for every file.
+//   - dst: The map to load into.
+//   - dir: The directory holding the files.
+//   - workers: The number of files loaded at once; values below one mean
+//     GOMAXPROCS.
+//   - opts: Passed to Import for every file, with Progress aggregated as for
+//     ImportSharded.
+//
+// Returns:
+//   - ImportReport: The sum of the reports of all files.
+//   - error: ErrIncompleteShardSet if the manifest or a bucket file is
+//     missing, ErrBucketFuncMismatch if the files were routed by another
+//     bucket function, or the errors returned by Import.
+func LoadBuckets(ctx context.Context, dst TxMap, dir string, workers int, opts ImportOptions) (ImportReport, error) {
+	fn, n, err := readBucketManifest(dir)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	files := make([]string, n)
+
+	for i := range files {
+		files[i] = filepath.Join(dir, fmt.Sprintf(bucketFilePattern, i))
+
+		if _, err = os.Stat(files[i]); err != nil {
+			return ImportReport{}, fmt.Errorf("%w: %w", ErrIncompleteShardSet, err)
+		}
+	}
+
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	dstBuckets := txMapBuckets(dst)
+	direct := fn == BucketFuncVersion && bucketFuncOf(dst) == BucketFuncVersion && len(dstBuckets) == n
+
+	return importFiles(ctx, files, workers, func(i int) TxMap {
+		if direct {
+			if bucket, ok := dstBuckets[i].(TxMap); ok {
+				return bucket
+			}
+		}
+
+		return dst
+	}, opts)
+}
+
+// readBucketManifest reads the manifest in dir and returns the bucket
+// function and the number of bucket files.
+func readBucketManifest(dir string) (int, int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, bucketManifestFile)) //nolint:gosec // dir is chosen by the caller
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %w", ErrIncompleteShardSet, err)
+	}
+
+	var fn, n int
+
+	if _, err = fmt.Sscanf(string(data), bucketManifestFormat, &fn, &n); err != nil || n < 1 {
+		return 0, 0, fmt.Errorf("%w: malformed %s", ErrInvalidSnapshot, bucketManifestFile)
+	}
+
+	if fn != bucketFuncNone && fn != BucketFuncVersion {
+		return 0, 0, fmt.Errorf("%w: files use version %d, this build %d", ErrBucketFuncMismatch, fn, BucketFuncVersion)
+	}
+
+	return fn, n, nil
+}
+
+// bucketFuncOf returns the bucket function of m as recorded in the manifest.
+func bucketFuncOf(m ReadOnlyTxMap) int {
+	if f, ok := m.(readOnlyTxMap); ok {
+		m = f.m
+	}
+
+	switch m.(type) {
+	case *SplitSwissMap, *SplitSwissMapUint64, *NativeSplitMap, *NativeSplitMapUint64:
+		return BucketFuncVersion
+	default:
+		return bucketFuncNone
+	}
+}
+
+// removeStaleBucketFiles removes the bucket files numbered n and above, left
+// behind by an earlier persist of a map with more buckets.
+func removeStaleBucketFiles(dir string, n int) {
+	files, _ := filepath.Glob(filepath.Join(dir, bucketFileGlob))
+
+	for _, file := range files {
+		var i int
+		if _, err := fmt.Sscanf(filepath.Base(file), bucketFilePattern, &i); err == nil && i >= n {
+			_ = os.Remove(file)
+		}
+	}
+}
+
+// forEachIndex calls f for 0..n-1 from at most workers goroutines, GOMAXPROCS
+// for workers below one, and returns the errors joined. Indexes not
+// yet started when an error occurs are skipped.
+func forEachIndex(n, workers int, f func(i int) error) error {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		next   atomic.Int64
+		failed atomic.Bool
+		errs   = make([]error, n)
+	)
+
+	for w := max(1, min(workers, n)); w > 0; w-- {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := int(next.Add(1) - 1); i < n && !failed.Load(); i = int(next.Add(1) - 1) {
+				if errs[i] = f(i); errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
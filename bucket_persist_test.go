@@ -0,0 +1,86 @@
+package txmap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPersistLoadBuckets tests that bucket files load back into maps of the
+// same and of a different layout.
+func TestPersistLoadBuckets(t *testing.T) {
+	ctx := context.Background()
+	src := NewSplitSwissMapUint64(0, 16)
+	require.NoError(t, src.PutMulti(skewedHashes(5000), 1))
+	dir := t.TempDir()
+
+	require.NoError(t, PersistBuckets(ctx, src, dir, 4))
+
+	files, err := filepath.Glob(filepath.Join(dir, bucketFileGlob))
+	require.NoError(t, err)
+	assert.Len(t, files, 17)
+
+	for name, dst := range map[string]TxMap{
+		"same layout":    NewNativeSplitMapUint64(0, 16),
+		"other layout":   NewSplitSwissMapUint64(0, 4),
+		"not split":      NewNativeMapUint64(0),
+		"two-choice map": NewTwoChoiceSplitMap(0, 16),
+	} {
+		t.Run(name, func(t *testing.T) {
+			report, err := LoadBuckets(ctx, dst, dir, 0, ImportOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(5000), report.Inserted)
+			requireSameContents(t, src, dst)
+		})
+	}
+
+	// a smaller map leaves no stale files behind
+	require.NoError(t, PersistBuckets(ctx, NewNativeMapUint64(0), dir, 0))
+
+	files, err = filepath.Glob(filepath.Join(dir, bucketFileGlob))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+// TestPersistBucketsTwoChoice tests that the buckets of a TwoChoiceSplitMap
+// are not loaded directly into the buckets of a prefix-routed map.
+func TestPersistBucketsTwoChoice(t *testing.T) {
+	ctx := context.Background()
+	src := NewTwoChoiceSplitMap(0, 16)
+	require.NoError(t, src.PutMulti(skewedHashes(2000), 3))
+
+	dir := t.TempDir()
+	require.NoError(t, PersistBuckets(ctx, src, dir, 0))
+
+	dst := NewSplitSwissMapUint64(0, 16)
+	_, err := LoadBuckets(ctx, dst, dir, 0, ImportOptions{})
+	require.NoError(t, err)
+	requireSameContents(t, src, dst)
+	require.NoError(t, dst.CheckInvariants())
+}
+
+// TestLoadBucketsErrors tests incomplete directories and foreign bucket
+// functions.
+func TestLoadBucketsErrors(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	_, err := LoadBuckets(ctx, NewNativeMapUint64(0), dir, 0, ImportOptions{})
+	require.ErrorIs(t, err, ErrIncompleteShardSet)
+
+	require.NoError(t, PersistBuckets(ctx, NewNativeSplitMapUint64(0, 4), dir, 0))
+	require.NoError(t, os.Remove(filepath.Join(dir, "bucket-00002.txmp")))
+
+	_, err = LoadBuckets(ctx, NewNativeMapUint64(0), dir, 0, ImportOptions{})
+	require.ErrorIs(t, err, ErrIncompleteShardSet)
+
+	manifest := "txmap-buckets 1\nbucket-func 2\nbuckets 5\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, bucketManifestFile), []byte(manifest), 0o600))
+
+	_, err = LoadBuckets(ctx, NewNativeMapUint64(0), dir, 0, ImportOptions{})
+	require.ErrorIs(t, err, ErrBucketFuncMismatch)
+}
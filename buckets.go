@@ -0,0 +1,54 @@
+package txmap
+
+import (
+	"fmt"
+)
+
+// Bucket storage
+//
+// The split maps keep their buckets in a slice indexed by bucket number,
+// filled with buckets 0..nrOfBuckets by the constructor and never emptied by
+// the package afterwards. The slice is private: Map returns a new map of the
+// buckets, so deleting from it, as callers and tests once did, does not reach
+// the split map, and DeleteBucket empties a bucket instead of removing it.
+//
+// Tests can still empty a slot, with simulateMissingBucket, to exercise the
+// error paths. Every method that returns an error therefore reaches its
+// bucket through bucketAt, which returns an error wrapping
+// ErrBucketDoesNotExist for an empty slot, as for a bucket number out of
+// range given by the caller, e.g. to PutMultiBucket, rather than panicking.
+// GetRelaxed and ApproxLength, which have no error to return, treat an empty
+// slot as an empty bucket. The remaining methods without an error, like Get
+// and Length, index the slice directly.
+
+// bucketAt returns bucket number bucket of buckets.
+//
+// Returns:
+//   - B: The bucket, the zero value on error.
+//   - error: An error wrapping ErrBucketDoesNotExist if bucket is out of
+//     range or its slot is empty.
+func bucketAt[K uint16 | uint64, B comparable](buckets []B, bucket K) (B, error) {
+	var missing B
+
+	if uint64(bucket) >= uint64(len(buckets)) {
+		return missing, fmt.Errorf("%w: %d, max bucket is %d", ErrBucketDoesNotExist, bucket, len(buckets)-1)
+	}
+
+	if buckets[bucket] == missing {
+		return missing, fmt.Errorf("%w: %d", ErrBucketDoesNotExist, bucket)
+	}
+
+	return buckets[bucket], nil
+}
+
+// bucketsMap returns a new map from bucket numbers to the buckets, for the
+// Map methods of the split maps.
+func bucketsMap[K uint16 | uint64, B any](buckets []B) map[K]B {
+	m := make(map[K]B, len(buckets))
+
+	for i, bucket := range buckets {
+		m[K(i)] = bucket //nolint:gosec // bucket numbers fit in K
+	}
+
+	return m
+}
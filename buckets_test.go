@@ -0,0 +1,129 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// simulateMissingBucket empties slot bucket of the bucket storage of a split
+// map, which the map never does itself, to test how a missing bucket is
+// reported. See the notes at the top of buckets.go.
+func simulateMissingBucket[K uint16 | uint64 | int, B any](buckets []*B, bucket K) {
+	buckets[bucket] = nil
+}
+
+// TestSplitMapMissingBucket tests that the error-returning methods of the
+// split maps report a missing or out of range bucket as ErrBucketDoesNotExist
+// instead of panicking.
+func TestSplitMapMissingBucket(t *testing.T) {
+	hash := chainhash.Hash{0x00, 0x01}
+
+	m := NewSplitSwissMapUint64(10, 4)
+	require.ErrorIs(t, m.PutMultiBucket(5, []chainhash.Hash{hash}, 1), ErrBucketDoesNotExist)
+
+	simulateMissingBucket(m.m, Bytes2Uint16Buckets(hash, m.nrOfBuckets))
+
+	require.ErrorIs(t, m.Put(hash, 1), ErrBucketDoesNotExist)
+	require.ErrorIs(t, m.PutMulti([]chainhash.Hash{hash}, 1), ErrBucketDoesNotExist)
+	require.ErrorIs(t, m.Set(hash, 1), ErrBucketDoesNotExist)
+	require.ErrorIs(t, m.Delete(hash), ErrBucketDoesNotExist)
+
+	_, err := m.SetIfExists(hash, 1)
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	_, err = m.SetIfNotExists(hash, 1)
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	n := NewNativeSplitMap(10, 4)
+	simulateMissingBucket(n.m, Bytes2Uint16Buckets(hash, n.nrOfBuckets))
+	require.ErrorIs(t, n.Put(hash, 1), ErrBucketDoesNotExist)
+}
+
+// TestSplitMapMissingBucketExtensions tests that the error-returning methods
+// beyond the TxMap API, and those of the other split map types, report a
+// missing bucket as ErrBucketDoesNotExist, and that GetRelaxed and
+// ApproxLength treat it as empty.
+func TestSplitMapMissingBucketExtensions(t *testing.T) {
+	hash := chainhash.Hash{0x00, 0x01}
+	keep := func(old uint64, _ bool) uint64 { return old }
+
+	m := NewSplitSwissMap(10, 4)
+	require.NoError(t, m.Put(chainhash.Hash{0x00, 0x00}, 1))
+	simulateMissingBucket(m.m, Bytes2Uint16Buckets(hash, m.nrOfBuckets))
+
+	_, err := m.Increment(hash, 1)
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	_, err = m.Decrement(hash, 1)
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	_, _, err = m.GetOrPut(hash, 1)
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	_, err = m.Upsert(hash, keep)
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	require.ErrorIs(t, m.SetLockPolicy(LockSpin), ErrBucketDoesNotExist)
+	assert.Equal(t, defaultLockPolicy, m.LockPolicy())
+
+	_, err = m.Flatten(context.Background(), FlattenOptions{})
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	_, ok := m.GetRelaxed(hash)
+	assert.False(t, ok)
+	assert.Equal(t, 1, m.ApproxLength())
+
+	simulateMissingBucket(m.m, 0)
+	assert.Equal(t, defaultLockPolicy, m.LockPolicy())
+
+	s := NewSplitHashSet(10, 4)
+	simulateMissingBucket(s.m, Bytes2Uint16Buckets(hash, s.nrOfBuckets))
+	require.ErrorIs(t, s.Put(hash), ErrBucketDoesNotExist)
+	require.ErrorIs(t, s.PutMulti([]chainhash.Hash{hash}), ErrBucketDoesNotExist)
+	require.ErrorIs(t, s.Delete(hash), ErrBucketDoesNotExist)
+
+	o := NewSplitSwissMapOf[string](10, 4)
+	simulateMissingBucket(o.m, Bytes2Uint16Buckets(hash, o.nrOfBuckets))
+	require.ErrorIs(t, o.Put(hash, "a"), ErrBucketDoesNotExist)
+	require.ErrorIs(t, o.PutMulti([]chainhash.Hash{hash}, "a"), ErrBucketDoesNotExist)
+	require.ErrorIs(t, o.Delete(hash), ErrBucketDoesNotExist)
+
+	h := NewHybridSplitMap(10, 0, 4)
+	simulateMissingBucket(h.m, Bytes2Uint16Buckets(hash, h.nrOfBuckets))
+	require.ErrorIs(t, h.Put(hash, 1), ErrBucketDoesNotExist)
+
+	_, err = h.Upsert(hash, keep)
+	require.ErrorIs(t, err, ErrBucketDoesNotExist)
+
+	c := NewTwoChoiceSplitMap(10, 4)
+	i, _ := c.candidates(hash)
+	simulateMissingBucket(c.m, i)
+	require.ErrorIs(t, c.Put(hash, 1), ErrBucketDoesNotExist)
+}
+
+// TestSplitMapSealedBuckets tests that changing the map returned by Map, or
+// deleting a bucket of a lock-free map, leaves the split map usable.
+func TestSplitMapSealedBuckets(t *testing.T) {
+	m := NewSplitSwissMapUint64(10, 4)
+	hash := chainhash.Hash{0x00, 0x01}
+
+	buckets := m.Map()
+	require.Len(t, buckets, 5)
+	delete(buckets, Bytes2Uint16Buckets(hash, m.nrOfBuckets))
+
+	require.NoError(t, m.Put(hash, 1))
+	require.NoError(t, m.CheckInvariants())
+
+	l := NewSplitSwissLockFreeMapUint64(10, 4)
+	require.NoError(t, l.Put(5, 1))
+	l.DeleteBucket(5 % 4)
+	l.DeleteBucket(100)
+
+	assert.False(t, l.Exists(5))
+	require.NoError(t, l.Put(5, 2))
+	assert.Equal(t, 1, l.Length())
+}
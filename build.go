@@ -0,0 +1,144 @@
+package txmap
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// buildStagingSize is the number of keys BuildParallel stages per worker
+// before handing them to the worker as one batch.
+const buildStagingSize = 4096
+
+// BuildParallel builds a map from a stream of keys, inserting them from
+// several workers while the stream is still being produced, e.g. while blocks
+// are downloaded.
+//
+// The returned map is a NativeSplitMapUint64 with the default number of
+// buckets. Every bucket is owned by one worker; incoming keys are staged per
+// worker and each staged batch is inserted with one PutMultiBucket call per
+// bucket, so workers never contend for a bucket lock.
+//
+// Params:
+//   - ctx: Cancels the build.
+//   - keys: The keys to insert; the build finishes when it is closed.
+//   - value: The value stored for every key.
+//   - workers: The number of insert workers; values below one mean one.
+//
+// Returns:
+//   - TxMap: The map holding all keys.
+//   - error: The context's error, or an error wrapping ErrHashAlreadyExists
+//     for a key that occurred twice. The stream is not drained on error.
+func BuildParallel(ctx context.Context, keys <-chan chainhash.Hash, value uint64, workers int) (TxMap, error) {
+	m := NewNativeSplitMapUint64(0)
+	workers = max(1, min(workers, int(m.Buckets())+1))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	batches := make([]chan []chainhash.Hash, workers)
+	staging := make([][]chainhash.Hash, workers)
+
+	var wg sync.WaitGroup
+
+	for w := range batches {
+		batches[w] = make(chan []chainhash.Hash, 1)
+
+		wg.Add(1)
+
+		go func(batches <-chan []chainhash.Hash) {
+			defer wg.Done()
+
+			for batch := range batches {
+				if err := buildInsert(m, batch, value); err != nil {
+					cancel(err)
+				}
+			}
+		}(batches[w])
+	}
+
+	err := buildDispatch(ctx, m, keys, batches, staging)
+
+	for _, b := range batches {
+		close(b)
+	}
+
+	wg.Wait()
+
+	if cause := context.Cause(ctx); cause != nil {
+		return nil, cause
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// buildDispatch routes keys to the staging buffer of the worker owning their
+// bucket and sends full buffers to the workers.
+func buildDispatch(ctx context.Context, m ShardedTxMap, keys <-chan chainhash.Hash, batches []chan []chainhash.Hash,
+	staging [][]chainhash.Hash,
+) error {
+	send := func(w int) error {
+		select {
+		case batches[w] <- staging[w]:
+			staging[w] = nil
+			return nil
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case hash, ok := <-keys:
+			if !ok {
+				for w := range staging {
+					if len(staging[w]) > 0 {
+						if err := send(w); err != nil {
+							return err
+						}
+					}
+				}
+
+				return nil
+			}
+
+			w := int(Bytes2Uint16Buckets(hash, m.Buckets())) % len(batches)
+
+			if staging[w] == nil {
+				staging[w] = make([]chainhash.Hash, 0, buildStagingSize)
+			}
+
+			staging[w] = append(staging[w], hash)
+
+			if len(staging[w]) == buildStagingSize {
+				if err := send(w); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// buildInsert inserts a staged batch with one PutMultiBucket call per bucket.
+func buildInsert(m ShardedTxMap, batch []chainhash.Hash, value uint64) error {
+	for bucket, positions := range bucketGroups(batch, m.Buckets()) {
+		hashes := make([]chainhash.Hash, len(positions))
+
+		for i, p := range positions {
+			hashes[i] = batch[p]
+		}
+
+		if err := m.PutMultiBucket(bucket, hashes, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
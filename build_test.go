@@ -0,0 +1,49 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuildParallel tests building a map from a key stream, including
+// duplicate keys and cancellation.
+func TestBuildParallel(t *testing.T) {
+	stream := func(n int, extra ...chainhash.Hash) <-chan chainhash.Hash {
+		keys := make(chan chainhash.Hash)
+
+		go func() {
+			defer close(keys)
+
+			for i := 0; i < n; i++ {
+				keys <- bloomHash(i)
+			}
+
+			for _, h := range extra {
+				keys <- h
+			}
+		}()
+
+		return keys
+	}
+
+	m, err := BuildParallel(context.Background(), stream(20000), 7, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 20000, m.Length())
+
+	v, ok := m.Get(bloomHash(19999))
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), v)
+
+	_, err = BuildParallel(context.Background(), stream(100, bloomHash(5)), 7, 4)
+	require.ErrorIs(t, err, ErrHashAlreadyExists)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = BuildParallel(ctx, make(chan chainhash.Hash), 7, 4)
+	require.ErrorIs(t, err, context.Canceled)
+}
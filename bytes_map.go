@@ -0,0 +1,305 @@
+package txmap
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync/atomic"
+)
+
+// BytesMap is a concurrent-safe map of arbitrary-length byte-slice keys, such
+// as locking scripts or serialized outpoints, to uint64 values. It follows the
+// design of the split maps: the keys are spread over buckets, each a native
+// map guarded by its own lock, and the length is kept in atomic counters.
+//
+// Keys are stored whole, as strings, so distinct keys never collide however
+// they are derived; they are routed to their buckets by a seeded hash. The map
+// copies every key it stores, so callers may reuse their slices.
+type BytesMap struct {
+	buckets     []bytesBucket
+	nrOfBuckets uint16
+	seed        maphash.Seed
+	frozen      atomic.Bool
+}
+
+// bytesBucket is one bucket of a BytesMap.
+type bytesBucket struct {
+	mu     mapLock
+	m      map[string]uint64
+	length atomic.Int64
+}
+
+// NewBytesMap creates a new BytesMap.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - buckets: Optionally the highest bucket index, 1024 by default; buckets
+//     0..buckets inclusive exist, as in the split maps.
+//
+// Returns:
+//   - *BytesMap: A pointer to the newly created BytesMap instance.
+func NewBytesMap(length int, buckets ...uint16) *BytesMap {
+	useBuckets := uint16(1024)
+	if len(buckets) > 0 {
+		useBuckets = buckets[0]
+	}
+
+	b := &BytesMap{
+		buckets:     make([]bytesBucket, int(useBuckets)+1),
+		nrOfBuckets: useBuckets,
+		seed:        maphash.MakeSeed(),
+	}
+
+	for i := range b.buckets {
+		b.buckets[i].m = make(map[string]uint64, length/len(b.buckets))
+	}
+
+	return b
+}
+
+// Buckets returns the highest bucket index of the map.
+func (b *BytesMap) Buckets() uint16 {
+	return b.nrOfBuckets
+}
+
+// bucket returns the bucket of key.
+func (b *BytesMap) bucket(key []byte) *bytesBucket {
+	return &b.buckets[maphash.Bytes(b.seed, key)%uint64(len(b.buckets))]
+}
+
+// rlock read-locks bucket unless the map is frozen and returns the function
+// undoing it.
+func (b *BytesMap) rlock(bucket *bytesBucket) func() {
+	if b.frozen.Load() {
+		return func() {}
+	}
+
+	bucket.mu.RLock()
+
+	return bucket.mu.RUnlock
+}
+
+// Exists checks if the given key exists in the map.
+func (b *BytesMap) Exists(key []byte) bool {
+	bucket := b.bucket(key)
+	defer b.rlock(bucket)()
+
+	_, ok := bucket.m[string(key)]
+
+	return ok
+}
+
+// Get retrieves the value associated with the given key.
+//
+// Returns:
+//   - uint64: The value, or 0 if the key does not exist.
+//   - bool: True if the key was found.
+func (b *BytesMap) Get(key []byte) (uint64, bool) {
+	bucket := b.bucket(key)
+	defer b.rlock(bucket)()
+
+	value, ok := bucket.m[string(key)]
+
+	return value, ok
+}
+
+// Put adds key with value n.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     key already exists.
+func (b *BytesMap) Put(key []byte, n uint64) error {
+	if b.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	bucket := b.bucket(key)
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if _, exists := bucket.m[string(key)]; exists {
+		return fmt.Errorf("%w: %x", ErrHashAlreadyExists, key)
+	}
+
+	bucket.m[string(key)] = n
+	bucket.length.Add(1)
+
+	return nil
+}
+
+// PutMulti adds every key with value n. The keys are added one at a time, so
+// a key that already exists stops the call with the keys before it added.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists naming
+//     the first key that already existed.
+func (b *BytesMap) PutMulti(keys [][]byte, n uint64) error {
+	for _, key := range keys {
+		if err := b.Put(key, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value of an existing key.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist if the
+//     key does not exist.
+func (b *BytesMap) Set(key []byte, value uint64) error {
+	ok, err := b.SetIfExists(key, value)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: %x", ErrHashDoesNotExist, key)
+	}
+
+	return nil
+}
+
+// SetIfExists updates the value of key if it exists.
+//
+// Returns:
+//   - bool: True if the key was found and updated.
+//   - error: ErrMapFrozen if the map is frozen.
+func (b *BytesMap) SetIfExists(key []byte, value uint64) (bool, error) {
+	if b.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	bucket := b.bucket(key)
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if _, exists := bucket.m[string(key)]; !exists {
+		return false, nil
+	}
+
+	bucket.m[string(key)] = value
+
+	return true, nil
+}
+
+// SetIfNotExists adds key with value if it does not exist yet.
+//
+// Returns:
+//   - bool: True if the key was added, false if it already existed.
+//   - error: ErrMapFrozen if the map is frozen.
+func (b *BytesMap) SetIfNotExists(key []byte, value uint64) (bool, error) {
+	if b.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	bucket := b.bucket(key)
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if _, exists := bucket.m[string(key)]; exists {
+		return false, nil
+	}
+
+	bucket.m[string(key)] = value
+	bucket.length.Add(1)
+
+	return true, nil
+}
+
+// Delete removes key from the map.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist if the
+//     key does not exist.
+func (b *BytesMap) Delete(key []byte) error {
+	if b.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	bucket := b.bucket(key)
+
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	if _, exists := bucket.m[string(key)]; !exists {
+		return fmt.Errorf("%w: %x", ErrHashDoesNotExist, key)
+	}
+
+	delete(bucket.m, string(key))
+	bucket.length.Add(-1)
+
+	return nil
+}
+
+// Length returns the number of keys in the map, summing the bucket counters.
+func (b *BytesMap) Length() int {
+	length := int64(0)
+
+	for i := range b.buckets {
+		length += b.buckets[i].length.Load()
+	}
+
+	return int(length)
+}
+
+// Keys returns copies of all keys in the map, in no particular order. Like
+// the split maps, the result is not an atomic snapshot across buckets.
+func (b *BytesMap) Keys() [][]byte {
+	keys := make([][]byte, 0, b.Length())
+
+	b.Iter(func(key []byte, _ uint64) bool {
+		keys = append(keys, key)
+		return false
+	})
+
+	return keys
+}
+
+// Iter calls f for every key and value, one bucket at a time under its read
+// lock. The key passed to f is a copy that f may retain. Stops iterating if f
+// returns true.
+func (b *BytesMap) Iter(f func(key []byte, value uint64) bool) {
+	for i := range b.buckets {
+		if b.iterBucket(&b.buckets[i], f) {
+			return
+		}
+	}
+}
+
+// iterBucket calls f for the entries of bucket and reports whether f stopped.
+func (b *BytesMap) iterBucket(bucket *bytesBucket, f func(key []byte, value uint64) bool) bool {
+	defer b.rlock(bucket)()
+
+	for k, v := range bucket.m {
+		if f([]byte(k), v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Freeze marks the map read-only: reads skip the bucket locks and writes
+// return ErrMapFrozen. See the lifecycle notes at the top of freeze.go.
+func (b *BytesMap) Freeze() {
+	b.frozen.Store(true)
+}
+
+// Clear removes every key and un-freezes the map. Like the Clear of the other
+// maps, it must not run concurrently with reads of a frozen map.
+func (b *BytesMap) Clear() {
+	for i := range b.buckets {
+		bucket := &b.buckets[i]
+
+		bucket.mu.Lock()
+		clear(bucket.m)
+		bucket.length.Store(0)
+		bucket.mu.Unlock()
+	}
+
+	b.frozen.Store(false)
+}
@@ -0,0 +1,103 @@
+package txmap
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBytesMap tests the basic operations with keys of different lengths,
+// including keys that share a prefix and the empty key.
+func TestBytesMap(t *testing.T) {
+	b := NewBytesMap(0, 8)
+	assert.Equal(t, uint16(8), b.Buckets())
+
+	keys := [][]byte{{}, {0x76}, {0x76, 0xa9}, {0x76, 0xa9, 0x14}, make([]byte, 36)}
+
+	for i, key := range keys {
+		require.NoError(t, b.Put(key, uint64(i)))
+	}
+
+	require.ErrorIs(t, b.Put(keys[2], 9), ErrHashAlreadyExists)
+	assert.Equal(t, len(keys), b.Length())
+
+	for i, key := range keys {
+		value, ok := b.Get(key)
+		require.True(t, ok)
+		assert.Equal(t, uint64(i), value)
+	}
+
+	require.NoError(t, b.Set(keys[1], 11))
+	require.ErrorIs(t, b.Set([]byte("missing"), 1), ErrHashDoesNotExist)
+
+	ok, err := b.SetIfNotExists(keys[1], 12)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	value, _ := b.Get(keys[1])
+	assert.Equal(t, uint64(11), value)
+
+	require.NoError(t, b.Delete(keys[0]))
+	require.ErrorIs(t, b.Delete(keys[0]), ErrHashDoesNotExist)
+	assert.False(t, b.Exists(keys[0]))
+	assert.Len(t, b.Keys(), len(keys)-1)
+
+	b.Freeze()
+	require.ErrorIs(t, b.Put([]byte("new"), 1), ErrMapFrozen)
+	assert.True(t, b.Exists(keys[4]))
+
+	b.Clear()
+	assert.Equal(t, 0, b.Length())
+	require.NoError(t, b.Put([]byte("new"), 1))
+}
+
+// TestBytesMapKeyCopied tests that the map does not retain the slice of the
+// caller.
+func TestBytesMapKeyCopied(t *testing.T) {
+	b := NewBytesMap(0)
+	key := []byte("outpoint:0")
+
+	require.NoError(t, b.Put(key, 1))
+
+	key[len(key)-1] = '1'
+	assert.False(t, b.Exists(key))
+	assert.True(t, b.Exists([]byte("outpoint:0")))
+}
+
+// TestBytesMapConcurrent tests concurrent writers on distinct keys.
+func TestBytesMapConcurrent(t *testing.T) {
+	b := NewBytesMap(0, 16)
+
+	var wg sync.WaitGroup
+
+	for w := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				key := binary.BigEndian.AppendUint32([]byte{byte(w)}, uint32(i)) //nolint:gosec // G115 test values
+				assert.NoError(t, b.Put(key, uint64(i)))
+				assert.True(t, b.Exists(key))
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 8000, b.Length())
+
+	seen := 0
+
+	b.Iter(func(key []byte, value uint64) bool {
+		assert.Len(t, key, 5)
+		assert.Equal(t, uint64(binary.BigEndian.Uint32(key[1:])), value)
+		seen++
+
+		return false
+	})
+	assert.Equal(t, 8000, seen)
+}
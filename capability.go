@@ -0,0 +1,261 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Capability probes
+//
+// TxMap is deliberately small so that decorators and alternative
+// implementations stay easy to write. Some maps can do more, and the optional
+// interfaces below describe those extra capabilities. Callers holding a TxMap
+// use the As* probes to discover them rather than type-switching on concrete
+// types, and fall back to the plain TxMap methods when a probe fails.
+
+// ErrMissingCapabilities is returned by Capabilities.Require when a map lacks
+// some of the required capabilities.
+var ErrMissingCapabilities = errors.New("map is missing required capabilities")
+
+// ShardedTxMap is implemented by the split maps, which distribute their hashes
+// over a fixed set of buckets that can be written to directly.
+type ShardedTxMap interface {
+	TxMap
+
+	// Buckets returns the highest bucket index; buckets 0..Buckets() inclusive exist.
+	Buckets() uint16
+
+	// PutMultiBucket adds hashes with value n to the given bucket, bypassing
+	// the bucket routing. The caller must pass only hashes that route to bucket.
+	PutMultiBucket(bucket uint16, hashes []chainhash.Hash, n uint64) error
+
+	// BucketDigests returns an order-independent digest of every bucket; see digest.go.
+	BucketDigests() []uint64
+}
+
+// BatchTxMap is implemented by maps that can look up and delete many hashes
+// while taking each lock once per batch rather than once per hash.
+type BatchTxMap interface {
+	TxMap
+
+	// GetMulti looks up every hash; values[i] and found[i] are the result for hashes[i].
+	GetMulti(hashes []chainhash.Hash) (values []uint64, found []bool)
+
+	// DeleteMulti deletes every hash that exists and returns an error wrapping
+	// ErrHashDoesNotExist for the first hash that did not.
+	DeleteMulti(hashes []chainhash.Hash) error
+
+	// PutMultiChecked adds every hash that does not exist yet and returns the
+	// ones that did, instead of failing on them.
+	PutMultiChecked(hashes []chainhash.Hash, n uint64) (dupes []chainhash.Hash, err error)
+
+	// AddMulti adds delta to the value of every hash that exists and returns
+	// an error wrapping ErrHashDoesNotExist for the first hash that did not.
+	AddMulti(hashes []chainhash.Hash, delta uint64) error
+
+	// AddOrPutMulti adds delta to the value of every hash that exists and
+	// adds the others with value delta.
+	AddOrPutMulti(hashes []chainhash.Hash, delta uint64) error
+
+	// CountExisting returns how many of hashes exist.
+	CountExisting(hashes []chainhash.Hash) int
+
+	// Missing returns the hashes that do not exist, in the order of hashes.
+	Missing(hashes []chainhash.Hash) []chainhash.Hash
+}
+
+// Compile-time checks of the capabilities of the concrete maps.
+var (
+	_ ShardedTxMap = (*SplitSwissMap)(nil)
+	_ ShardedTxMap = (*SplitSwissMapUint64)(nil)
+	_ ShardedTxMap = (*NativeSplitMap)(nil)
+	_ ShardedTxMap = (*NativeSplitMapUint64)(nil)
+
+	_ BatchTxMap = (*SwissMapUint64)(nil)
+	_ BatchTxMap = (*NativeMapUint64)(nil)
+	_ BatchTxMap = (*SplitSwissMap)(nil)
+	_ BatchTxMap = (*SplitSwissMapUint64)(nil)
+	_ BatchTxMap = (*NativeSplitMap)(nil)
+	_ BatchTxMap = (*NativeSplitMapUint64)(nil)
+)
+
+// AsSharded reports whether m is a ShardedTxMap and returns it as one.
+//
+// Params:
+//   - m: The map to probe.
+//
+// Returns:
+//   - ShardedTxMap: m as a ShardedTxMap, or nil.
+//   - bool: True if m implements ShardedTxMap.
+func AsSharded(m TxMap) (ShardedTxMap, bool) {
+	sm, ok := m.(ShardedTxMap)
+	return sm, ok
+}
+
+// AsBatch reports whether m is a BatchTxMap and returns it as one.
+//
+// Params:
+//   - m: The map to probe.
+//
+// Returns:
+//   - BatchTxMap: m as a BatchTxMap, or nil.
+//   - bool: True if m implements BatchTxMap.
+func AsBatch(m TxMap) (BatchTxMap, bool) {
+	bm, ok := m.(BatchTxMap)
+	return bm, ok
+}
+
+// Capabilities lists the optional features a map supports, so that
+// orchestration code can check at startup that the configured backend
+// supports what the deployment needs.
+type Capabilities struct {
+	// Batch is set for maps implementing BatchTxMap.
+	Batch bool
+
+	// Sharded is set for maps implementing ShardedTxMap.
+	Sharded bool
+
+	// Context is set for maps implementing ContextTxMap natively.
+	Context bool
+
+	// Relaxed is set for maps implementing RelaxedReader.
+	Relaxed bool
+
+	// Invariants is set for maps implementing InvariantChecker.
+	Invariants bool
+
+	// ReadOnlyView is set for maps offering FreezeReadOnly.
+	ReadOnlyView bool
+
+	// Snapshots is set for maps offering incremental snapshots through
+	// SnapshotDiff, such as JournaledTxMap.
+	Snapshots bool
+
+	// TTL is set for maps that expire entries on their own. It is only
+	// reported through CapabilityReporter.
+	TTL bool
+
+	// Persistent is set for maps whose contents survive a restart. It is only
+	// reported through CapabilityReporter.
+	Persistent bool
+}
+
+// CapabilityReporter is implemented by maps that declare capabilities the
+// probes in Capabilities cannot detect, such as TTL or persistence, or that
+// forward capabilities of a map they wrap.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// MapCapabilities reports the capabilities of m: those detected by probing
+// for the optional interfaces, plus those m declares as a CapabilityReporter.
+// Decorators hide the capabilities of the map they wrap unless they forward
+// them.
+//
+// Params:
+//   - m: The map to inspect.
+//
+// Returns:
+//   - Capabilities: The supported capabilities.
+func MapCapabilities(m TxMap) Capabilities {
+	_, batch := AsBatch(m)
+	_, sharded := AsSharded(m)
+	_, ctx := AsContext(m)
+	_, relaxed := m.(RelaxedReader)
+	_, invariants := m.(InvariantChecker)
+	_, readOnly := m.(interface{ FreezeReadOnly() ReadOnlyTxMap })
+	_, snapshots := m.(interface {
+		SnapshotDiff(since SnapshotID) ([]ChangeRecord, error)
+	})
+
+	c := Capabilities{
+		Batch:        batch,
+		Sharded:      sharded,
+		Context:      ctx,
+		Relaxed:      relaxed,
+		Invariants:   invariants,
+		ReadOnlyView: readOnly,
+		Snapshots:    snapshots,
+	}
+
+	if r, ok := m.(CapabilityReporter); ok {
+		c = c.union(r.Capabilities())
+	}
+
+	return c
+}
+
+// Require checks that c has every capability set in need.
+//
+// Params:
+//   - need: The required capabilities.
+//
+// Returns:
+//   - error: ErrMissingCapabilities naming the missing ones, or nil.
+func (c Capabilities) Require(need Capabilities) error {
+	var missing []string
+
+	for _, f := range c.fields() {
+		if f.need(need) && !f.need(c) {
+			missing = append(missing, f.name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingCapabilities, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// String lists the supported capabilities by name.
+func (c Capabilities) String() string {
+	var names []string
+
+	for _, f := range c.fields() {
+		if f.need(c) {
+			names = append(names, f.name)
+		}
+	}
+
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+// capabilityField names a field of Capabilities and reads it.
+type capabilityField struct {
+	name string
+	need func(c Capabilities) bool
+}
+
+// fields returns the fields of Capabilities in declaration order.
+func (Capabilities) fields() []capabilityField {
+	return []capabilityField{
+		{"batch", func(c Capabilities) bool { return c.Batch }},
+		{"sharded", func(c Capabilities) bool { return c.Sharded }},
+		{"context", func(c Capabilities) bool { return c.Context }},
+		{"relaxed", func(c Capabilities) bool { return c.Relaxed }},
+		{"invariants", func(c Capabilities) bool { return c.Invariants }},
+		{"read-only-view", func(c Capabilities) bool { return c.ReadOnlyView }},
+		{"snapshots", func(c Capabilities) bool { return c.Snapshots }},
+		{"ttl", func(c Capabilities) bool { return c.TTL }},
+		{"persistent", func(c Capabilities) bool { return c.Persistent }},
+	}
+}
+
+// union returns the capabilities set in c or in o.
+func (c Capabilities) union(o Capabilities) Capabilities {
+	return Capabilities{
+		Batch:        c.Batch || o.Batch,
+		Sharded:      c.Sharded || o.Sharded,
+		Context:      c.Context || o.Context,
+		Relaxed:      c.Relaxed || o.Relaxed,
+		Invariants:   c.Invariants || o.Invariants,
+		ReadOnlyView: c.ReadOnlyView || o.ReadOnlyView,
+		Snapshots:    c.Snapshots || o.Snapshots,
+		TTL:          c.TTL || o.TTL,
+		Persistent:   c.Persistent || o.Persistent,
+	}
+}
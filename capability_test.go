@@ -0,0 +1,277 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCapabilityProbes tests that AsSharded and AsBatch succeed exactly for
+// the maps that have those capabilities.
+func TestCapabilityProbes(t *testing.T) {
+	sharded := map[string]bool{
+		"SplitSwissMap":        true,
+		"SplitSwissMapUint64":  true,
+		"NativeSplitMap":       true,
+		"NativeSplitMapUint64": true,
+	}
+
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			sm, ok := AsSharded(m)
+			assert.Equal(t, sharded[name], ok)
+			assert.Equal(t, sharded[name], sm != nil)
+
+			bm, ok := AsBatch(m)
+			assert.True(t, ok)
+			assert.NotNil(t, bm)
+		})
+	}
+
+	_, ok := AsBatch(NewChildMap(NewNativeMapUint64(0)))
+	assert.False(t, ok)
+
+	_, ok = AsSharded(NewChildMap(NewNativeMapUint64(0)))
+	assert.False(t, ok)
+}
+
+// TestShardedPutMultiBucket tests writing directly to the bucket a hash routes to.
+func TestShardedPutMultiBucket(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		sm, ok := AsSharded(factory())
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			hash := hashN(5)
+			bucket := Bytes2Uint16Buckets(hash, sm.Buckets())
+
+			require.NoError(t, sm.PutMultiBucket(bucket, []chainhash.Hash{hash}, 9))
+
+			v, ok := sm.Get(hash)
+			require.True(t, ok)
+			assert.Equal(t, uint64(9), v)
+
+			require.ErrorIs(t, sm.PutMultiBucket(sm.Buckets()+1, []chainhash.Hash{hash}, 9), ErrBucketDoesNotExist)
+		})
+	}
+}
+
+// TestBatchGetDeleteMulti tests GetMulti and DeleteMulti on every BatchTxMap.
+func TestBatchGetDeleteMulti(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		bm, ok := AsBatch(factory())
+		require.True(t, ok)
+
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i += 2 {
+				require.NoError(t, bm.Put(hashN(i), uint64(i)))
+			}
+
+			hashes := make([]chainhash.Hash, 100)
+			for i := range hashes {
+				hashes[i] = hashN(i)
+			}
+
+			values, found := bm.GetMulti(hashes)
+			require.Len(t, values, 100)
+			require.Len(t, found, 100)
+
+			for i := range hashes {
+				assert.Equal(t, i%2 == 0, found[i])
+				if found[i] {
+					assert.Equal(t, uint64(i), values[i])
+				}
+			}
+
+			// hashN(1) is the first missing hash, all present ones are deleted
+			err := bm.DeleteMulti(hashes[:10])
+			require.ErrorIs(t, err, ErrHashDoesNotExist)
+			assert.Contains(t, err.Error(), hashN(1).String())
+			assert.Equal(t, 45, bm.Length())
+
+			require.NoError(t, bm.DeleteMulti([]chainhash.Hash{hashN(10), hashN(12)}))
+			assert.Equal(t, 43, bm.Length())
+			assert.False(t, bm.Exists(hashN(10)))
+
+			bm.Freeze()
+			require.ErrorIs(t, bm.DeleteMulti([]chainhash.Hash{hashN(14)}), ErrMapFrozen)
+			assert.True(t, bm.Exists(hashN(14)))
+		})
+	}
+}
+
+// TestBatchPutMultiChecked tests that PutMultiChecked adds the new hashes and
+// returns the existing ones, including repeats within the batch.
+func TestBatchPutMultiChecked(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		bm, ok := AsBatch(factory())
+		require.True(t, ok)
+
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 10; i += 2 {
+				require.NoError(t, bm.Put(hashN(i), 100))
+			}
+
+			hashes := make([]chainhash.Hash, 0, 11)
+			for i := range 10 {
+				hashes = append(hashes, hashN(i))
+			}
+
+			hashes = append(hashes, hashN(1))
+
+			dupes, err := bm.PutMultiChecked(hashes, 7)
+			require.NoError(t, err)
+			assert.Equal(t, []chainhash.Hash{hashN(0), hashN(2), hashN(4), hashN(6), hashN(8), hashN(1)}, dupes)
+			assert.Equal(t, 10, bm.Length())
+
+			value, _ := bm.Get(hashN(2))
+			assert.Equal(t, uint64(100), value)
+
+			value, _ = bm.Get(hashN(3))
+			assert.Equal(t, uint64(7), value)
+
+			dupes, err = bm.PutMultiChecked([]chainhash.Hash{hashN(20)}, 7)
+			require.NoError(t, err)
+			assert.Empty(t, dupes)
+
+			bm.Freeze()
+
+			_, err = bm.PutMultiChecked([]chainhash.Hash{hashN(21)}, 7)
+			require.ErrorIs(t, err, ErrMapFrozen)
+			assert.False(t, bm.Exists(hashN(21)))
+		})
+	}
+}
+
+// TestBatchAddMulti tests AddMulti and AddOrPutMulti on every BatchTxMap,
+// including repeated hashes and missing ones.
+func TestBatchAddMulti(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		bm, ok := AsBatch(factory())
+		require.True(t, ok)
+
+		t.Run(name, func(t *testing.T) {
+			for i := range 4 {
+				require.NoError(t, bm.Put(hashN(i), 10))
+			}
+
+			err := bm.AddMulti([]chainhash.Hash{hashN(0), hashN(1), hashN(9), hashN(1), hashN(8)}, 5)
+			require.ErrorIs(t, err, ErrHashDoesNotExist)
+			assert.Contains(t, err.Error(), hashN(9).String())
+			assert.False(t, bm.Exists(hashN(9)))
+
+			value, _ := bm.Get(hashN(1))
+			assert.Equal(t, uint64(20), value)
+
+			require.NoError(t, bm.AddOrPutMulti([]chainhash.Hash{hashN(0), hashN(9), hashN(9)}, 3))
+			assert.Equal(t, 5, bm.Length())
+
+			value, _ = bm.Get(hashN(0))
+			assert.Equal(t, uint64(18), value)
+
+			value, _ = bm.Get(hashN(9))
+			assert.Equal(t, uint64(6), value)
+
+			bm.Freeze()
+
+			require.ErrorIs(t, bm.AddOrPutMulti([]chainhash.Hash{hashN(2)}, 1), ErrMapFrozen)
+
+			value, _ = bm.Get(hashN(2))
+			assert.Equal(t, uint64(10), value)
+		})
+	}
+}
+
+// TestCountExisting tests CountExisting on every BatchTxMap and on a map
+// without batch support.
+func TestCountExisting(t *testing.T) {
+	hashes := []chainhash.Hash{hashN(0), hashN(1), hashN(500), hashN(1), hashN(999)}
+
+	for name, factory := range txMapImpls() {
+		m := factory()
+
+		t.Run(name, func(t *testing.T) {
+			for i := range 10 {
+				require.NoError(t, m.Put(hashN(i), 1))
+			}
+
+			assert.Equal(t, 3, CountExisting(m, hashes))
+			assert.Equal(t, 0, CountExisting(m, nil))
+
+			m.Freeze()
+			assert.Equal(t, 3, CountExisting(m, hashes))
+		})
+	}
+
+	wrapped := NewComputingTxMap(NewSwissMapUint64(0))
+	require.NoError(t, wrapped.Put(hashN(500), 1))
+	assert.Equal(t, 1, CountExisting(wrapped, hashes))
+}
+
+// TestMissing tests Missing on every BatchTxMap and on a map without batch
+// support.
+func TestMissing(t *testing.T) {
+	hashes := []chainhash.Hash{hashN(500), hashN(0), hashN(1), hashN(999), hashN(500)}
+	want := []chainhash.Hash{hashN(500), hashN(999), hashN(500)}
+
+	for name, factory := range txMapImpls() {
+		m := factory()
+
+		t.Run(name, func(t *testing.T) {
+			for i := range 10 {
+				require.NoError(t, m.Put(hashN(i), 1))
+			}
+
+			assert.Equal(t, want, Missing(m, hashes))
+			assert.Nil(t, Missing(m, hashes[1:3]))
+
+			m.Freeze()
+			assert.Equal(t, want, Missing(m, hashes))
+		})
+	}
+
+	wrapped := NewComputingTxMap(NewSwissMapUint64(0))
+	require.NoError(t, wrapped.Put(hashN(500), 1))
+	assert.Equal(t, []chainhash.Hash{hashN(0), hashN(1), hashN(999)}, Missing(wrapped, hashes))
+}
+
+// reportingTxMap declares TTL and persistence on top of a plain map.
+type reportingTxMap struct {
+	*ChildMap
+}
+
+// Capabilities declares TTL and persistence.
+func (reportingTxMap) Capabilities() Capabilities {
+	return Capabilities{TTL: true, Persistent: true}
+}
+
+// TestMapCapabilities tests probing, declared capabilities and Require.
+func TestMapCapabilities(t *testing.T) {
+	c := MapCapabilities(NewNativeSplitMapUint64(10, 4))
+	assert.Equal(t, Capabilities{
+		Batch:        true,
+		Sharded:      true,
+		Relaxed:      true,
+		Invariants:   true,
+		ReadOnlyView: true,
+	}, c)
+	assert.Equal(t, "[batch sharded relaxed invariants read-only-view]", c.String())
+
+	require.NoError(t, c.Require(Capabilities{Batch: true, Sharded: true}))
+
+	err := c.Require(Capabilities{Batch: true, TTL: true, Persistent: true})
+	require.ErrorIs(t, err, ErrMissingCapabilities)
+	assert.Contains(t, err.Error(), "ttl, persistent")
+
+	assert.True(t, MapCapabilities(NewJournaledTxMap(NewNativeMapUint64(0))).Snapshots)
+	assert.True(t, MapCapabilities(WithContext(NewNativeMapUint64(0))).Context)
+
+	reported := MapCapabilities(reportingTxMap{NewChildMap(NewNativeMapUint64(0))})
+	assert.Equal(t, Capabilities{TTL: true, Persistent: true}, reported)
+}
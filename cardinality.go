@@ -0,0 +1,150 @@
+package txmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+const (
+	// cardinalityMinPrecision and cardinalityMaxPrecision bound the number of
+	// index bits of a CardinalityEstimator.
+	cardinalityMinPrecision = 4
+	cardinalityMaxPrecision = 18
+)
+
+// ErrPrecisionMismatch is returned by CardinalityEstimator.Merge for
+// estimators of different precision.
+var ErrPrecisionMismatch = errors.New("cardinality estimator precision mismatch")
+
+// CardinalityEstimator estimates the number of distinct transaction hashes
+// added to it with HyperLogLog, for components that only need "roughly how
+// many distinct txids did we see" and should not pay 32 bytes per key for a
+// full map.
+//
+// With precision p it holds 2^p registers and has a standard error of about
+// 1.04 / sqrt(2^p): 0.8% at the default precision of 14. Transaction hashes
+// are uniformly distributed, so a word of the hash is used directly instead of
+// hashing it again. All methods except Clear are safe for concurrent use.
+type CardinalityEstimator struct {
+	registers []atomic.Uint32
+	precision uint8
+}
+
+// NewCardinalityEstimator returns an empty estimator.
+//
+// Params:
+//   - precision: Optional number of index bits, 14 by default and clamped to
+//     [4, 18]; the estimator uses 2^precision registers.
+//
+// Returns:
+//   - *CardinalityEstimator: The empty estimator.
+func NewCardinalityEstimator(precision ...uint8) *CardinalityEstimator {
+	p := uint8(14)
+	if len(precision) > 0 {
+		p = min(max(precision[0], cardinalityMinPrecision), cardinalityMaxPrecision)
+	}
+
+	return &CardinalityEstimator{
+		registers: make([]atomic.Uint32, 1<<p),
+		precision: p,
+	}
+}
+
+// Add records hash.
+func (c *CardinalityEstimator) Add(hash chainhash.Hash) {
+	w := binary.LittleEndian.Uint64(hash[8:16])
+	index := w >> (64 - c.precision)
+
+	// the rank is the position of the first set bit after the index bits; the
+	// sentinel bit caps it at 64 - precision + 1
+	rank := uint32(bits.LeadingZeros64(w<<c.precision|1<<(c.precision-1))) + 1 //nolint:gosec // at most 65
+
+	register := &c.registers[index]
+
+	for {
+		old := register.Load()
+		if old >= rank || register.CompareAndSwap(old, rank) {
+			return
+		}
+	}
+}
+
+// Estimate returns the estimated number of distinct hashes added.
+func (c *CardinalityEstimator) Estimate() uint64 {
+	m := float64(len(c.registers))
+	sum := 0.0
+	zeros := 0
+
+	for i := range c.registers {
+		r := c.registers[i].Load()
+		if r == 0 {
+			zeros++
+		}
+
+		sum += math.Ldexp(1, -int(r))
+	}
+
+	estimate := cardinalityAlpha(len(c.registers)) * m * m / sum
+
+	// small-range correction: linear counting is more accurate while many
+	// registers are still empty
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// Merge adds all hashes recorded by other to c, as if they had been added to
+// c directly, e.g. to combine the estimators of several peers or shards.
+//
+// Params:
+//   - other: The estimator to merge; it is not modified.
+//
+// Returns:
+//   - error: ErrPrecisionMismatch if the precisions differ.
+func (c *CardinalityEstimator) Merge(other *CardinalityEstimator) error {
+	if other.precision != c.precision {
+		return fmt.Errorf("%w: %d and %d", ErrPrecisionMismatch, c.precision, other.precision)
+	}
+
+	for i := range c.registers {
+		rank := other.registers[i].Load()
+
+		for {
+			old := c.registers[i].Load()
+			if old >= rank || c.registers[i].CompareAndSwap(old, rank) {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// Clear resets the estimator. Not safe for concurrent use.
+func (c *CardinalityEstimator) Clear() {
+	for i := range c.registers {
+		c.registers[i].Store(0)
+	}
+}
+
+// cardinalityAlpha returns the HyperLogLog bias correction constant for m registers.
+func cardinalityAlpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
@@ -0,0 +1,51 @@
+package txmap
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCardinalityEstimator tests estimates for small and large sets, that
+// duplicates are not counted, and merging.
+func TestCardinalityEstimator(t *testing.T) {
+	c := NewCardinalityEstimator()
+	assert.Equal(t, uint64(0), c.Estimate())
+
+	for i := 0; i < 100; i++ {
+		c.Add(bloomHash(i))
+		c.Add(bloomHash(i))
+	}
+
+	assert.InDelta(t, 100, float64(c.Estimate()), 3)
+
+	for i := 100; i < 200000; i++ {
+		c.Add(bloomHash(i))
+	}
+
+	assert.InEpsilon(t, 200000, float64(c.Estimate()), 0.03)
+
+	a := NewCardinalityEstimator(12)
+	b := NewCardinalityEstimator(12)
+
+	for i := 0; i < 30000; i++ {
+		a.Add(bloomHash(i))
+		b.Add(bloomHash(i + 20000))
+	}
+
+	require.NoError(t, a.Merge(b))
+	assert.InEpsilon(t, 50000, float64(a.Estimate()), 0.05)
+
+	require.ErrorIs(t, a.Merge(c), ErrPrecisionMismatch)
+
+	a.Clear()
+	assert.Equal(t, uint64(0), a.Estimate())
+}
+
+// TestCardinalityEstimatorPrecision tests that precisions are clamped.
+func TestCardinalityEstimatorPrecision(t *testing.T) {
+	assert.Len(t, NewCardinalityEstimator(0).registers, 1<<cardinalityMinPrecision)
+	assert.Len(t, NewCardinalityEstimator(math.MaxUint8).registers, 1<<cardinalityMaxPrecision)
+}
@@ -0,0 +1,251 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// ErrInjectedFault is wrapped by every error returned by a ChaosTxMap fault,
+// so tests can tell injected failures from real ones.
+var ErrInjectedFault = errors.New("injected fault")
+
+// ChaosOptions configures the faults a ChaosTxMap injects. Rates are
+// probabilities per call between 0 and 1; zero disables a fault.
+type ChaosOptions struct {
+	// Seed seeds the random choices, making a run reproducible for calls made
+	// in the same order.
+	Seed int64
+
+	// LatencyRate is the fraction of calls delayed by Latency plus a random
+	// share of LatencyJitter before they run.
+	LatencyRate   float64
+	Latency       time.Duration
+	LatencyJitter time.Duration
+
+	// DuplicateRate is the fraction of Put, PutMulti and SetIfNotExists calls
+	// that fail with a spurious ErrHashAlreadyExists without writing anything.
+	// SetIfNotExists reports false instead of an error, like for a real
+	// duplicate.
+	DuplicateRate float64
+
+	// PartialPutMultiRate is the fraction of PutMulti calls that insert only
+	// a random prefix of their hashes and then fail.
+	PartialPutMultiRate float64
+}
+
+// ChaosStats counts the faults a ChaosTxMap injected.
+type ChaosStats struct {
+	Delayed           uint64
+	Duplicates        uint64
+	PartialPutMultis  uint64
+	PartialInsertions uint64
+}
+
+// check that ChaosTxMap implements TxMap
+var _ TxMap = (*ChaosTxMap)(nil)
+
+// ChaosTxMap wraps a TxMap and injects latency, spurious duplicate errors and
+// partial PutMulti failures, so that consumers of TxMap can test their retry
+// and rollback logic against a map that misbehaves the way real ones can.
+// It is meant for tests only.
+type ChaosTxMap struct {
+	m    TxMap
+	opts ChaosOptions
+
+	mu  sync.Mutex
+	rng *rand.Rand
+
+	delayed           atomic.Uint64
+	duplicates        atomic.Uint64
+	partialPutMultis  atomic.Uint64
+	partialInsertions atomic.Uint64
+}
+
+// NewChaosTxMap returns a ChaosTxMap that forwards every operation to m and
+// injects the faults configured in opts.
+//
+// Params:
+//   - m: The map to wrap.
+//   - opts: The faults to inject.
+//
+// Returns:
+//   - *ChaosTxMap: The wrapping map.
+func NewChaosTxMap(m TxMap, opts ChaosOptions) *ChaosTxMap {
+	m = nonNilTxMap(m)
+
+	return &ChaosTxMap{
+		m:    m,
+		opts: opts,
+		rng:  rand.New(rand.NewSource(opts.Seed)), //nolint:gosec // reproducible test faults
+	}
+}
+
+// Stats returns the number of faults injected so far.
+func (c *ChaosTxMap) Stats() ChaosStats {
+	return ChaosStats{
+		Delayed:           c.delayed.Load(),
+		Duplicates:        c.duplicates.Load(),
+		PartialPutMultis:  c.partialPutMultis.Load(),
+		PartialInsertions: c.partialInsertions.Load(),
+	}
+}
+
+// Exists checks if the given hash exists in the wrapped map, after the
+// injected latency.
+func (c *ChaosTxMap) Exists(hash chainhash.Hash) bool {
+	c.delay()
+	return c.m.Exists(hash)
+}
+
+// Get retrieves the value of hash from the wrapped map, after the injected latency.
+func (c *ChaosTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	c.delay()
+	return c.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map, after the injected latency.
+func (c *ChaosTxMap) Keys() []chainhash.Hash {
+	c.delay()
+	return c.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (c *ChaosTxMap) Length() int {
+	return c.m.Length()
+}
+
+// Iter iterates over the wrapped map, after the injected latency. Stops
+// iterating if f returns true.
+func (c *ChaosTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	c.delay()
+	c.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map, unless a spurious duplicate is injected.
+func (c *ChaosTxMap) Put(hash chainhash.Hash, value uint64) error {
+	c.delay()
+
+	if c.chance(c.opts.DuplicateRate) {
+		c.duplicates.Add(1)
+		return fmt.Errorf("%w: %w: %v", ErrHashAlreadyExists, ErrInjectedFault, hash)
+	}
+
+	return c.m.Put(hash, value)
+}
+
+// PutMulti adds hashes to the wrapped map, unless a spurious duplicate or a
+// partial failure is injected.
+func (c *ChaosTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	c.delay()
+
+	if c.chance(c.opts.DuplicateRate) {
+		c.duplicates.Add(1)
+		return fmt.Errorf("%w: %w: batch of %d", ErrHashAlreadyExists, ErrInjectedFault, len(hashes))
+	}
+
+	if len(hashes) == 0 || !c.chance(c.opts.PartialPutMultiRate) {
+		return c.m.PutMulti(hashes, value)
+	}
+
+	n := c.intn(len(hashes))
+
+	c.partialPutMultis.Add(1)
+	c.partialInsertions.Add(uint64(n))
+
+	if err := c.m.PutMulti(hashes[:n], value); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: PutMulti failed after %d of %d hashes", ErrInjectedFault, n, len(hashes))
+}
+
+// Set updates hash in the wrapped map, after the injected latency.
+func (c *ChaosTxMap) Set(hash chainhash.Hash, value uint64) error {
+	c.delay()
+	return c.m.Set(hash, value)
+}
+
+// SetIfExists updates hash in the wrapped map if it exists, after the
+// injected latency.
+func (c *ChaosTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	c.delay()
+	return c.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash to the wrapped map if it does not exist, unless a
+// spurious duplicate is injected, in which case it reports false.
+func (c *ChaosTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	c.delay()
+
+	if c.chance(c.opts.DuplicateRate) {
+		c.duplicates.Add(1)
+		return false, nil
+	}
+
+	return c.m.SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the wrapped map, after the injected latency.
+func (c *ChaosTxMap) Delete(hash chainhash.Hash) error {
+	c.delay()
+	return c.m.Delete(hash)
+}
+
+// Freeze freezes the wrapped map.
+func (c *ChaosTxMap) Freeze() {
+	c.m.Freeze()
+}
+
+// Clear clears the wrapped map.
+func (c *ChaosTxMap) Clear() {
+	c.m.Clear()
+}
+
+// delay sleeps for the injected latency if the call is chosen for it.
+func (c *ChaosTxMap) delay() {
+	if !c.chance(c.opts.LatencyRate) {
+		return
+	}
+
+	d := c.opts.Latency
+	if c.opts.LatencyJitter > 0 {
+		d += time.Duration(c.int63n(int64(c.opts.LatencyJitter)))
+	}
+
+	c.delayed.Add(1)
+	time.Sleep(d)
+}
+
+// chance reports true with probability rate.
+func (c *ChaosTxMap) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rng.Float64() < rate
+}
+
+// intn returns a random int in [0, n).
+func (c *ChaosTxMap) intn(n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rng.Intn(n)
+}
+
+// int63n returns a random int64 in [0, n).
+func (c *ChaosTxMap) int63n(n int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rng.Int63n(n)
+}
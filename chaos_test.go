@@ -0,0 +1,66 @@
+package txmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChaosTxMapDisabled runs the TxMap conformance tests against a ChaosTxMap
+// with every fault disabled.
+func TestChaosTxMapDisabled(t *testing.T) {
+	testTxMap(t, NewChaosTxMap(NewNativeMapUint64(100), ChaosOptions{}))
+}
+
+// TestChaosTxMapDuplicates tests spurious duplicate errors.
+func TestChaosTxMapDuplicates(t *testing.T) {
+	m := NewChaosTxMap(NewNativeMapUint64(10), ChaosOptions{DuplicateRate: 1})
+
+	err := m.Put(hashN(1), 1)
+	require.ErrorIs(t, err, ErrHashAlreadyExists)
+	require.ErrorIs(t, err, ErrInjectedFault)
+
+	require.ErrorIs(t, m.PutMulti([]chainhash.Hash{hashN(1)}, 1), ErrInjectedFault)
+
+	ok, err := m.SetIfNotExists(hashN(1), 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, 0, m.Length())
+	assert.Equal(t, uint64(3), m.Stats().Duplicates)
+}
+
+// TestChaosTxMapPartialPutMulti tests that a partial failure inserts a prefix
+// of the batch, so that callers can test their rollback.
+func TestChaosTxMapPartialPutMulti(t *testing.T) {
+	m := NewChaosTxMap(NewNativeMapUint64(10), ChaosOptions{Seed: 3, PartialPutMultiRate: 1})
+	hashes := []chainhash.Hash{hashN(1), hashN(2), hashN(3), hashN(4)}
+
+	err := m.PutMulti(hashes, 1)
+	require.ErrorIs(t, err, ErrInjectedFault)
+
+	stats := m.Stats()
+	assert.Equal(t, uint64(1), stats.PartialPutMultis)
+
+	n := int(stats.PartialInsertions) //nolint:gosec // at most 4
+	assert.Equal(t, n, m.Length())
+
+	for i, hash := range hashes {
+		assert.Equal(t, i < n, m.Exists(hash))
+	}
+}
+
+// TestChaosTxMapLatency tests injected latency.
+func TestChaosTxMapLatency(t *testing.T) {
+	m := NewChaosTxMap(NewNativeMapUint64(10), ChaosOptions{LatencyRate: 1, Latency: 5 * time.Millisecond})
+
+	start := time.Now()
+	m.Exists(hashN(1))
+	require.NoError(t, m.Put(hashN(1), 1))
+
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	assert.Equal(t, uint64(2), m.Stats().Delayed)
+}
@@ -0,0 +1,321 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that ChildMap implements TxMap
+var _ TxMap = (*ChildMap)(nil)
+
+// ErrParentReadOnly is returned by ChildMap.CommitToParent when the parent map
+// was passed as a ReadOnlyTxMap that does not also implement TxMap.
+var ErrParentReadOnly = errors.New("parent map is read-only")
+
+// ChildMap is a TxMap layered on top of a parent map: reads fall through to the
+// parent, while writes, including deletes of parent entries, stay in the child
+// until CommitToParent applies them. This gives speculative work, such as
+// building a block template on top of the current UTXO view, a private
+// writable copy of the parent without copying it.
+//
+// The parent is never modified before CommitToParent. If it changes
+// underneath the child, the child sees the changes for every hash it has not
+// written or deleted itself.
+type ChildMap struct {
+	parent ReadOnlyTxMap
+
+	mu      sync.RWMutex
+	local   map[chainhash.Hash]uint64
+	deleted map[chainhash.Hash]struct{}
+	frozen  atomic.Bool
+}
+
+// NewChildMap returns an empty child of parent.
+//
+// Params:
+//   - parent: The map reads fall through to. It must implement TxMap for
+//     CommitToParent to succeed; a ChildMap can be the parent of another.
+//
+// Returns:
+//   - *ChildMap: The child map.
+func NewChildMap(parent ReadOnlyTxMap) *ChildMap {
+	if isNilTxMap(parent) {
+		parent = nilTxMap{}
+	}
+
+	return &ChildMap{
+		parent:  parent,
+		local:   make(map[chainhash.Hash]uint64),
+		deleted: make(map[chainhash.Hash]struct{}),
+	}
+}
+
+// CommitToParent applies the child's changes to the parent, deleting the
+// entries the child deleted and putting or updating the entries it wrote, then
+// resets the child to an empty layer over the updated parent.
+//
+// Returns:
+//   - error: ErrParentReadOnly if the parent is not a TxMap, ErrMapFrozen if the
+//     child is frozen, or the first error returned by the parent. Changes
+//     applied before an error remain in the parent; the child keeps all its
+//     changes so the commit can be retried.
+//
+// Considerations: The commit is not atomic with respect to concurrent readers
+// of the parent, which can observe it half-applied.
+func (c *ChildMap) CommitToParent() error {
+	parent, ok := c.parent.(TxMap)
+	if !ok {
+		return ErrParentReadOnly
+	}
+
+	if c.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for hash := range c.deleted {
+		if err := parent.Delete(hash); err != nil && !errors.Is(err, ErrHashDoesNotExist) {
+			return err
+		}
+	}
+
+	for hash, value := range c.local {
+		updated, err := parent.SetIfExists(hash, value)
+		if err != nil {
+			return err
+		}
+
+		if !updated {
+			if err = parent.Put(hash, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	clear(c.local)
+	clear(c.deleted)
+
+	return nil
+}
+
+// Exists checks if the given hash exists in the child or, unless the child
+// deleted it, in the parent.
+func (c *ChildMap) Exists(hash chainhash.Hash) bool {
+	_, ok := c.Get(hash)
+	return ok
+}
+
+// Get retrieves the value of hash from the child or, unless the child deleted
+// it, from the parent.
+func (c *ChildMap) Get(hash chainhash.Hash) (uint64, bool) {
+	if !c.frozen.Load() {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+
+	return c.getUnlocked(hash)
+}
+
+// Keys returns all hashes visible through the child.
+func (c *ChildMap) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, c.Length())
+
+	c.Iter(func(hash chainhash.Hash, _ uint64) bool {
+		keys = append(keys, hash)
+		return false
+	})
+
+	return keys
+}
+
+// Length returns the number of hashes visible through the child. It visits
+// every entry written in the child, but not the entries of the parent.
+func (c *ChildMap) Length() int {
+	if !c.frozen.Load() {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+
+	length := c.parent.Length() - len(c.deleted)
+
+	for hash := range c.local {
+		if !c.parent.Exists(hash) {
+			length++
+		}
+	}
+
+	return length
+}
+
+// Iter iterates over the entries written in the child, then over the parent
+// entries the child did not overwrite or delete. Stops iterating if f returns true.
+//
+// The child is read-locked for the whole iteration, so f must not write to it.
+func (c *ChildMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	if !c.frozen.Load() {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+	}
+
+	for hash, value := range c.local {
+		if f(hash, value) {
+			return
+		}
+	}
+
+	c.parent.Iter(func(hash chainhash.Hash, value uint64) bool {
+		if _, ok := c.local[hash]; ok {
+			return false
+		}
+
+		if _, ok := c.deleted[hash]; ok {
+			return false
+		}
+
+		return f(hash, value)
+	})
+}
+
+// Put adds hash to the child. It fails if the hash is visible through the child.
+func (c *ChildMap) Put(hash chainhash.Hash, value uint64) error {
+	return c.PutMulti([]chainhash.Hash{hash}, value)
+}
+
+// PutMulti adds hashes to the child, stopping at the first hash that is
+// already visible through the child.
+func (c *ChildMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	if c.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, hash := range hashes {
+		if _, ok := c.getUnlocked(hash); ok {
+			return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+		}
+
+		c.setUnlocked(hash, value)
+	}
+
+	return nil
+}
+
+// Set updates the value of a hash visible through the child, in the child.
+func (c *ChildMap) Set(hash chainhash.Hash, value uint64) error {
+	ok, err := c.SetIfExists(hash, value)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return nil
+}
+
+// SetIfExists updates the value of hash in the child if it is visible through
+// the child, and reports whether it was.
+func (c *ChildMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if c.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.getUnlocked(hash); !ok {
+		return false, nil
+	}
+
+	c.setUnlocked(hash, value)
+
+	return true, nil
+}
+
+// SetIfNotExists adds hash to the child if it is not visible through the
+// child, and reports whether it was added.
+func (c *ChildMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if c.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.getUnlocked(hash); ok {
+		return false, nil
+	}
+
+	c.setUnlocked(hash, value)
+
+	return true, nil
+}
+
+// Delete removes hash from the child's view. Parent entries are hidden by a
+// tombstone until CommitToParent deletes them from the parent.
+func (c *ChildMap) Delete(hash chainhash.Hash) error {
+	if c.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.getUnlocked(hash); !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	delete(c.local, hash)
+
+	if c.parent.Exists(hash) {
+		c.deleted[hash] = struct{}{}
+	}
+
+	return nil
+}
+
+// Freeze marks the child read-only: reads skip its lock and writes, including
+// CommitToParent, return ErrMapFrozen. The parent is not frozen.
+func (c *ChildMap) Freeze() {
+	c.frozen.Store(true)
+}
+
+// Clear discards all changes made in the child, which again shows the parent
+// unchanged, and un-freezes it. The parent is not cleared.
+func (c *ChildMap) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.local)
+	clear(c.deleted)
+	c.frozen.Store(false)
+}
+
+// getUnlocked looks hash up in the child, then in the parent unless deleted.
+// The caller must hold the lock.
+func (c *ChildMap) getUnlocked(hash chainhash.Hash) (uint64, bool) {
+	if v, ok := c.local[hash]; ok {
+		return v, true
+	}
+
+	if _, ok := c.deleted[hash]; ok {
+		return 0, false
+	}
+
+	return c.parent.Get(hash)
+}
+
+// setUnlocked writes hash to the child. The caller must hold the write lock.
+func (c *ChildMap) setUnlocked(hash chainhash.Hash, value uint64) {
+	c.local[hash] = value
+	delete(c.deleted, hash)
+}
@@ -0,0 +1,101 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChildMap tests the basic TxMap behavior of a ChildMap over an empty parent.
+func TestChildMap(t *testing.T) {
+	testTxMap(t, NewChildMap(NewNativeMapUint64(100)))
+}
+
+// TestChildMapLayering tests that reads fall through to the parent and that
+// writes and deletes stay in the child until CommitToParent.
+func TestChildMapLayering(t *testing.T) {
+	parent := NewNativeMapUint64(10)
+	require.NoError(t, parent.Put(hashN(1), 1))
+	require.NoError(t, parent.Put(hashN(2), 2))
+	require.NoError(t, parent.Put(hashN(3), 3))
+
+	child := NewChildMap(parent)
+
+	require.ErrorIs(t, child.Put(hashN(1), 10), ErrHashAlreadyExists)
+	require.NoError(t, child.Set(hashN(1), 10))
+	require.NoError(t, child.Delete(hashN(2)))
+	require.NoError(t, child.Put(hashN(4), 4))
+	require.ErrorIs(t, child.Delete(hashN(5)), ErrHashDoesNotExist)
+
+	v, ok := child.Get(hashN(1))
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), v)
+	assert.False(t, child.Exists(hashN(2)))
+	assert.True(t, child.Exists(hashN(3)))
+	assert.Equal(t, 3, child.Length())
+	assert.ElementsMatch(t, []chainhash.Hash{hashN(1), hashN(3), hashN(4)}, child.Keys())
+
+	// the parent is untouched
+	v, _ = parent.Get(hashN(1))
+	assert.Equal(t, uint64(1), v)
+	assert.True(t, parent.Exists(hashN(2)))
+	assert.False(t, parent.Exists(hashN(4)))
+
+	// re-adding a deleted parent entry replaces the tombstone
+	added, err := child.SetIfNotExists(hashN(2), 20)
+	require.NoError(t, err)
+	assert.True(t, added)
+	require.NoError(t, child.Delete(hashN(2)))
+
+	require.NoError(t, child.CommitToParent())
+
+	expected := NewNativeMapUint64(10)
+	require.NoError(t, expected.Put(hashN(1), 10))
+	require.NoError(t, expected.Put(hashN(3), 3))
+	require.NoError(t, expected.Put(hashN(4), 4))
+	requireSameContents(t, expected, parent)
+	requireSameContents(t, expected, child)
+}
+
+// TestChildMapNested tests a child of a child committing level by level.
+func TestChildMapNested(t *testing.T) {
+	root := NewSwissMapUint64(10)
+	require.NoError(t, root.Put(hashN(1), 1))
+
+	block := NewChildMap(root)
+	template := NewChildMap(block)
+
+	require.NoError(t, template.Put(hashN(2), 2))
+	require.NoError(t, template.Delete(hashN(1)))
+	assert.True(t, block.Exists(hashN(1)))
+
+	require.NoError(t, template.CommitToParent())
+	assert.False(t, block.Exists(hashN(1)))
+	assert.True(t, root.Exists(hashN(1)))
+
+	require.NoError(t, block.CommitToParent())
+	assert.False(t, root.Exists(hashN(1)))
+	assert.True(t, root.Exists(hashN(2)))
+}
+
+// TestChildMapClearAndReadOnlyParent tests that Clear only discards the
+// child's changes and that a read-only parent cannot be committed to.
+func TestChildMapClearAndReadOnlyParent(t *testing.T) {
+	parent := NewNativeMapUint64(10)
+	require.NoError(t, parent.Put(hashN(1), 1))
+
+	child := NewChildMap(parent.FreezeReadOnly())
+	require.NoError(t, child.Delete(hashN(1)))
+	require.NoError(t, child.Put(hashN(2), 2))
+	require.ErrorIs(t, child.CommitToParent(), ErrParentReadOnly)
+
+	child.Clear()
+	assert.Equal(t, 1, child.Length())
+	assert.True(t, child.Exists(hashN(1)))
+
+	child.Freeze()
+	require.ErrorIs(t, child.Put(hashN(3), 3), ErrMapFrozen)
+	assert.True(t, child.Exists(hashN(1)))
+}
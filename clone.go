@@ -0,0 +1,373 @@
+package txmap
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dolthub/swiss"
+)
+
+// Clones
+//
+// Clone returns a deep copy of a map that later writes to either map do not
+// affect, so that e.g. block assembly can work from a consistent point-in-time
+// view while the live map keeps mutating.
+//
+// The lock-based maps copy under their read locks. The split maps read-lock
+// all of their buckets at once, in ascending bucket order like the two-bucket
+// writes of TwoChoiceSplitMap, before copying any of them, so the clone is a
+// single point in time rather than a bucket-by-bucket copy: writers stall for
+// the duration of the copy, while readers carry on. Frozen maps and buckets
+// take no lock, as they cannot change.
+//
+// A clone keeps the bucket count, duplicate policy and lock policy of its
+// source, but starts unfrozen, at epoch zero; freeze it to read it without
+// locks. The lock-free maps take no locks here either, so like their other
+// methods Clone must not run concurrently with writes to them.
+
+// copyPolicy gives l the policy of src. Neither lock may be held.
+func (l *mapLock) copyPolicy(src *mapLock) {
+	if src.alt != nil {
+		_ = l.setPolicy(src.policy) // src.policy was accepted by setPolicy before
+	}
+}
+
+// cloneBucket is implemented by the buckets of the lock-based split maps.
+type cloneBucket[B any] interface {
+	// readLock returns the lock guarding the bucket, or nil if it is frozen.
+	readLock() *mapLock
+
+	// cloneUnlocked returns a copy of the bucket. The caller holds the read
+	// lock, unless the bucket is frozen.
+	cloneUnlocked() B
+}
+
+// cloneBuckets returns copies of buckets 0..nrOfBuckets, taken while all of
+// them are read-locked at once. See the notes at the top of clone.go.
+func cloneBuckets[B cloneBucket[B]](buckets []B, nrOfBuckets uint16) []B {
+	locked := make([]*mapLock, 0, int(nrOfBuckets)+1)
+
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		if l := buckets[i].readLock(); l != nil {
+			l.RLock()
+
+			locked = append(locked, l)
+		}
+	}
+
+	defer func() {
+		for _, l := range locked {
+			l.RUnlock()
+		}
+	}()
+
+	clones := make([]B, len(buckets))
+
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		clones[i] = buckets[i].cloneUnlocked()
+	}
+
+	return clones
+}
+
+// cloneSwiss returns a copy of m.
+func cloneSwiss[K comparable, V any](m *swiss.Map[K, V]) *swiss.Map[K, V] {
+	c := swiss.NewMap[K, V](uint32(m.Count())) //nolint:gosec // G115 map sizes fit in uint32
+
+	m.Iter(func(k K, v V) bool {
+		c.Put(k, v)
+		return false
+	})
+
+	return c
+}
+
+// Clone returns a deep copy of the map, taken under its read lock. See the
+// notes at the top of clone.go.
+func (s *SwissMap) Clone() *SwissMap {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	c := &SwissMap{
+		m:          cloneSwiss(s.m),
+		duplicates: s.duplicates,
+	}
+
+	c.mu.copyPolicy(&s.mu)
+	c.length.Store(s.length.Load())
+
+	return c
+}
+
+// Clone returns a deep copy of the map, taken under its read lock. See the
+// notes at the top of clone.go.
+func (s *SwissMapUint64) Clone() *SwissMapUint64 {
+	if l := s.readLock(); l != nil {
+		l.RLock()
+		defer l.RUnlock()
+	}
+
+	return s.cloneUnlocked()
+}
+
+// readLock returns the lock of the map, or nil if it is frozen.
+func (s *SwissMapUint64) readLock() *mapLock {
+	if s.frozen.Load() {
+		return nil
+	}
+
+	return &s.mu
+}
+
+// cloneUnlocked returns a copy of the map; see cloneBucket.
+func (s *SwissMapUint64) cloneUnlocked() *SwissMapUint64 {
+	c := &SwissMapUint64{
+		m:          cloneSwiss(s.m),
+		duplicates: s.duplicates,
+	}
+
+	c.mu.copyPolicy(&s.mu)
+	c.length.Store(s.length.Load())
+
+	return c
+}
+
+// Clone returns a deep copy of the map, taken under its read lock. See the
+// notes at the top of clone.go.
+func (s *NativeMap) Clone() *NativeMap {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	c := &NativeMap{
+		m:          maps.Clone(s.m),
+		duplicates: s.duplicates,
+	}
+
+	c.mu.copyPolicy(&s.mu)
+	c.length.Store(s.length.Load())
+
+	return c
+}
+
+// Clone returns a deep copy of the map, taken under its read lock. See the
+// notes at the top of clone.go.
+func (s *NativeMapUint64) Clone() *NativeMapUint64 {
+	if l := s.readLock(); l != nil {
+		l.RLock()
+		defer l.RUnlock()
+	}
+
+	return s.cloneUnlocked()
+}
+
+// readLock returns the lock of the map, or nil if it is frozen.
+func (s *NativeMapUint64) readLock() *mapLock {
+	if s.frozen.Load() {
+		return nil
+	}
+
+	return &s.mu
+}
+
+// cloneUnlocked returns a copy of the map; see cloneBucket.
+func (s *NativeMapUint64) cloneUnlocked() *NativeMapUint64 {
+	c := &NativeMapUint64{
+		m:          maps.Clone(s.m),
+		duplicates: s.duplicates,
+	}
+
+	c.mu.copyPolicy(&s.mu)
+	c.length.Store(s.length.Load())
+
+	return c
+}
+
+// Clone returns a deep copy of the set, taken under its read lock. See the
+// notes at the top of clone.go.
+func (s *HashSet) Clone() *HashSet {
+	if l := s.readLock(); l != nil {
+		l.RLock()
+		defer l.RUnlock()
+	}
+
+	return s.cloneUnlocked()
+}
+
+// readLock returns the lock of the set, or nil if it is frozen.
+func (s *HashSet) readLock() *mapLock {
+	if s.frozen.Load() {
+		return nil
+	}
+
+	return &s.mu
+}
+
+// cloneUnlocked returns a copy of the set; see cloneBucket.
+func (s *HashSet) cloneUnlocked() *HashSet {
+	c := &HashSet{
+		t: hashSetTable{
+			ctrl:  slices.Clone(s.t.ctrl),
+			keys:  slices.Clone(s.t.keys),
+			count: s.t.count,
+		},
+		duplicates: s.duplicates,
+	}
+
+	c.mu.copyPolicy(&s.mu)
+	c.length.Store(s.length.Load())
+
+	return c
+}
+
+// Clone returns a deep copy of the map, taken while all buckets are
+// read-locked at once. See the notes at the top of clone.go.
+func (g *SplitSwissMap) Clone() *SplitSwissMap {
+	return &SplitSwissMap{
+		m:           cloneBuckets(g.m, g.nrOfBuckets),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+}
+
+// Clone returns a deep copy of the map, taken while all buckets are
+// read-locked at once. See the notes at the top of clone.go.
+func (g *SplitSwissMapUint64) Clone() *SplitSwissMapUint64 {
+	return &SplitSwissMapUint64{
+		m:           cloneBuckets(g.m, g.nrOfBuckets),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+}
+
+// Clone returns a deep copy of the map, taken while all buckets are
+// read-locked at once. See the notes at the top of clone.go.
+func (g *NativeSplitMap) Clone() *NativeSplitMap {
+	return &NativeSplitMap{
+		m:           cloneBuckets(g.m, g.nrOfBuckets),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+}
+
+// Clone returns a deep copy of the map, taken while all buckets are
+// read-locked at once. See the notes at the top of clone.go.
+func (g *NativeSplitMapUint64) Clone() *NativeSplitMapUint64 {
+	return &NativeSplitMapUint64{
+		m:           cloneBuckets(g.m, g.nrOfBuckets),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+}
+
+// Clone returns a deep copy of the set, taken while all buckets are
+// read-locked at once. See the notes at the top of clone.go.
+func (g *SplitHashSet) Clone() *SplitHashSet {
+	return &SplitHashSet{
+		m:           cloneBuckets(g.m, g.nrOfBuckets),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+}
+
+// Clone returns a deep copy of the map, taken while all buckets are
+// read-locked at once, so every hash stays in the bucket it was placed in.
+// See the notes at the top of clone.go.
+func (g *TwoChoiceSplitMap) Clone() *TwoChoiceSplitMap {
+	return &TwoChoiceSplitMap{
+		m:           cloneBuckets(g.m, g.nrOfBuckets),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+}
+
+// Clone returns a deep copy of the map, taken while all buckets are
+// read-locked at once. Every bucket of the clone starts mutable, to be
+// promoted by PromoteQuiet once quiet. See the notes at the top of clone.go.
+func (g *HybridSplitMap) Clone() *HybridSplitMap {
+	locked := make([]*sync.RWMutex, 0, int(g.nrOfBuckets)+1)
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].mu.RLock()
+
+		locked = append(locked, &g.m[i].mu)
+	}
+
+	defer func() {
+		for _, mu := range locked {
+			mu.RUnlock()
+		}
+	}()
+
+	c := &HybridSplitMap{
+		m:           make([]*hybridBucket, len(g.m)),
+		nrOfBuckets: g.nrOfBuckets,
+		quiet:       g.quiet,
+	}
+
+	now := time.Now().UnixNano()
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		b := &hybridBucket{m: cloneSwiss(g.m[i].m)}
+		b.lastWrite.Store(now)
+		b.length.Store(g.m[i].length.Load())
+		c.m[i] = b
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the map. It takes no lock and must not run
+// concurrently with writes; see the notes at the top of clone.go.
+func (s *SwissLockFreeMapUint64) Clone() *SwissLockFreeMapUint64 {
+	c := &SwissLockFreeMapUint64{
+		m:          cloneSwiss(s.m),
+		duplicates: s.duplicates,
+	}
+
+	c.length.Store(s.length.Load())
+
+	return c
+}
+
+// Clone returns a deep copy of the map. It takes no lock and must not run
+// concurrently with writes; see the notes at the top of clone.go.
+func (s *NativeLockFreeMapUint64) Clone() *NativeLockFreeMapUint64 {
+	c := &NativeLockFreeMapUint64{
+		m:          maps.Clone(s.m),
+		duplicates: s.duplicates,
+	}
+
+	c.length.Store(s.length.Load())
+
+	return c
+}
+
+// Clone returns a deep copy of the map. It takes no lock and must not run
+// concurrently with writes; see the notes at the top of clone.go.
+func (g *SplitSwissLockFreeMapUint64) Clone() *SplitSwissLockFreeMapUint64 {
+	c := &SplitSwissLockFreeMapUint64{
+		m:           make([]*SwissLockFreeMapUint64, len(g.m)),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+
+	for i, bucket := range g.m {
+		c.m[i] = bucket.Clone()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the map. It takes no lock and must not run
+// concurrently with writes; see the notes at the top of clone.go.
+func (g *NativeSplitLockFreeMapUint64) Clone() *NativeSplitLockFreeMapUint64 {
+	c := &NativeSplitLockFreeMapUint64{
+		m:           make([]*NativeLockFreeMapUint64, len(g.m)),
+		nrOfBuckets: g.nrOfBuckets,
+	}
+
+	for i, bucket := range g.m {
+		c.m[i] = bucket.Clone()
+	}
+
+	return c
+}
@@ -0,0 +1,187 @@
+package txmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCloneTxMaps tests that the clones of the TxMap implementations hold the
+// same entries and are independent of their source.
+func TestCloneTxMaps(t *testing.T) {
+	impls := map[string]func() (TxMap, func(TxMap) TxMap){
+		"SwissMapUint64": func() (TxMap, func(TxMap) TxMap) {
+			return NewSwissMapUint64(0), func(m TxMap) TxMap { return m.(*SwissMapUint64).Clone() }
+		},
+		"NativeMapUint64": func() (TxMap, func(TxMap) TxMap) {
+			return NewNativeMapUint64(0), func(m TxMap) TxMap { return m.(*NativeMapUint64).Clone() }
+		},
+		"SplitSwissMap": func() (TxMap, func(TxMap) TxMap) {
+			return NewSplitSwissMap(0, 8), func(m TxMap) TxMap { return m.(*SplitSwissMap).Clone() }
+		},
+		"SplitSwissMapUint64": func() (TxMap, func(TxMap) TxMap) {
+			return NewSplitSwissMapUint64(0, 8), func(m TxMap) TxMap { return m.(*SplitSwissMapUint64).Clone() }
+		},
+		"NativeSplitMap": func() (TxMap, func(TxMap) TxMap) {
+			return NewNativeSplitMap(0, 8), func(m TxMap) TxMap { return m.(*NativeSplitMap).Clone() }
+		},
+		"NativeSplitMapUint64": func() (TxMap, func(TxMap) TxMap) {
+			return NewNativeSplitMapUint64(0, 8), func(m TxMap) TxMap { return m.(*NativeSplitMapUint64).Clone() }
+		},
+		"TwoChoiceSplitMap": func() (TxMap, func(TxMap) TxMap) {
+			return NewTwoChoiceSplitMap(0, 8), func(m TxMap) TxMap { return m.(*TwoChoiceSplitMap).Clone() }
+		},
+		"HybridSplitMap": func() (TxMap, func(TxMap) TxMap) {
+			return NewHybridSplitMap(0, time.Hour, 8), func(m TxMap) TxMap { return m.(*HybridSplitMap).Clone() }
+		},
+	}
+
+	for name, newMap := range impls {
+		t.Run(name, func(t *testing.T) {
+			m, clone := newMap()
+
+			for i := range 1000 {
+				require.NoError(t, m.Put(hashN(i), uint64(i)))
+			}
+
+			c := clone(m)
+			requireSameContents(t, m, c)
+
+			require.NoError(t, m.Delete(hashN(0)))
+			require.NoError(t, c.Put(hashN(1000), 1000))
+
+			assert.True(t, c.Exists(hashN(0)))
+			assert.False(t, m.Exists(hashN(1000)))
+			assert.Equal(t, 1001, c.Length())
+
+			m.Freeze()
+
+			frozen := clone(m)
+			requireSameContents(t, m, frozen)
+			require.NoError(t, frozen.Put(hashN(0), 0), "a clone starts unfrozen")
+		})
+	}
+}
+
+// TestCloneHashMaps tests the clones of the hash-only maps and sets.
+func TestCloneHashMaps(t *testing.T) {
+	swissMap := NewSwissMap(0)
+	nativeMap := NewNativeMap(0)
+	hashSet := NewHashSet(0)
+	splitHashSet := NewSplitHashSet(0, 8)
+
+	for i := range 1000 {
+		require.NoError(t, swissMap.Put(hashN(i)))
+		require.NoError(t, nativeMap.Put(hashN(i)))
+		require.NoError(t, hashSet.Put(hashN(i)))
+		require.NoError(t, splitHashSet.Put(hashN(i)))
+	}
+
+	clones := map[string]TxHashMap{
+		"SwissMap":     swissMap.Clone(),
+		"NativeMap":    nativeMap.Clone(),
+		"HashSet":      hashSet.Clone(),
+		"SplitHashSet": splitHashSet.Clone(),
+	}
+
+	require.NoError(t, swissMap.Delete(hashN(0)))
+	require.NoError(t, nativeMap.Delete(hashN(0)))
+	require.NoError(t, hashSet.Delete(hashN(0)))
+	require.NoError(t, splitHashSet.Delete(hashN(0)))
+
+	for name, c := range clones {
+		assert.Equal(t, 1000, c.Length(), name)
+		assert.True(t, c.Exists(hashN(0)), name)
+		assert.Len(t, c.Keys(), 1000, name)
+	}
+}
+
+// TestCloneLockFreeMaps tests the clones of the lock-free uint64 maps.
+func TestCloneLockFreeMaps(t *testing.T) {
+	swissMap := NewSwissLockFreeMapUint64(0)
+	nativeMap := NewNativeLockFreeMapUint64(0)
+	swissSplit := NewSplitSwissLockFreeMapUint64(0, 8)
+	nativeSplit := NewNativeSplitLockFreeMapUint64(0, 8)
+
+	for i := range uint64(1000) {
+		require.NoError(t, swissMap.Put(i, i))
+		require.NoError(t, nativeMap.Put(i, i))
+		require.NoError(t, swissSplit.Put(i, i))
+		require.NoError(t, nativeSplit.Put(i, i))
+	}
+
+	clones := []Uint64{swissMap.Clone(), nativeMap.Clone(), swissSplit.Clone(), nativeSplit.Clone()}
+
+	require.NoError(t, swissMap.Put(1000, 1000))
+	require.NoError(t, nativeMap.Put(1000, 1000))
+	require.NoError(t, swissSplit.Put(1000, 1000))
+	require.NoError(t, nativeSplit.Put(1000, 1000))
+
+	for _, c := range clones {
+		assert.Equal(t, 1000, c.Length())
+		assert.False(t, c.Exists(1000))
+
+		value, ok := c.Get(999)
+		require.True(t, ok)
+		assert.Equal(t, uint64(999), value)
+	}
+}
+
+// TestClonePolicies tests that a clone keeps the duplicate and lock policies
+// of its source.
+func TestClonePolicies(t *testing.T) {
+	m := NewSplitSwissMapUint64(0, 4)
+	require.NoError(t, m.SetLockPolicy(LockReaderPreferring))
+	m.SetDuplicatePolicy(DuplicateIgnore, nil)
+
+	require.NoError(t, m.Put(hashN(1), 1))
+
+	c := m.Clone()
+	assert.Equal(t, LockReaderPreferring, c.LockPolicy())
+	assert.Equal(t, uint16(4), c.Buckets())
+	require.NoError(t, c.Put(hashN(1), 2))
+}
+
+// TestCloneSplitPointInTime tests that a split map cloned during sequential
+// writes holds a prefix of those writes, as a bucket-by-bucket copy would not.
+func TestCloneSplitPointInTime(t *testing.T) {
+	m := NewSplitSwissMapUint64(0, 64)
+
+	var (
+		written atomic.Int64
+		wg      sync.WaitGroup
+		stop    atomic.Bool
+	)
+
+	wg.Add(1)
+
+	// hashN is exact below 65536, so the writer stops at 60000 entries
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 60000 && !stop.Load(); i++ {
+			assert.NoError(t, m.Put(hashN(i), uint64(i)))
+			written.Store(int64(i) + 1)
+		}
+	}()
+
+	for written.Load() < 1000 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for range 20 {
+		c := m.Clone()
+		n := c.Length()
+
+		for i := range n {
+			require.True(t, c.Exists(hashN(i)), "clone of %d entries misses entry %d", n, i)
+		}
+	}
+
+	stop.Store(true)
+	wg.Wait()
+}
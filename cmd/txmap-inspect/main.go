@@ -0,0 +1,310 @@
+// Package main provides txmap-inspect, a tool to look into the snapshots
+// written by the txmap package.
+//
+// A snapshot is either a file written by Export or SaveSnapshot, or a
+// directory written by ExportSharded or PersistBuckets. It is loaded into a
+// SplitSwissMapUint64 and then queried by one of the subcommands:
+//
+//	stats                  the format, entry count, value range and bucket balance
+//	get <txid>             the value stored for a transaction id
+//	sample <n>             n entries chosen at random
+//	diff <other snapshot>  the entries that differ from another snapshot
+//
+// Usage:
+//
+//	txmap-inspect [-seed n] [-limit n] <snapshot> <subcommand> [args]
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	txmap "github.com/bsv-blockchain/go-tx-map"
+)
+
+var (
+	// errUsage is returned for a command line that cannot be run.
+	errUsage = errors.New("usage: txmap-inspect [-seed n] [-limit n] <snapshot> stats | get <txid> | sample <n> | diff <other snapshot>")
+
+	// errNotFound is returned by get for a txid that is not in the snapshot.
+	errNotFound = errors.New("not found")
+
+	// errDifferent is returned by diff when the snapshots differ.
+	errDifferent = errors.New("snapshots differ")
+)
+
+// config holds the command line.
+type config struct {
+	seed  int64
+	limit int
+	path  string
+	cmd   string
+	args  []string
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err = run(ctx, cfg, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		stop()
+		os.Exit(1) //nolint:gocritic // stop has been called
+	}
+}
+
+// parseFlags parses the command line into a config.
+func parseFlags(args []string) (config, error) {
+	var cfg config
+
+	flags := flag.NewFlagSet("txmap-inspect", flag.ContinueOnError)
+	flags.Int64Var(&cfg.seed, "seed", time.Now().UnixNano(), "seed of sample")
+	flags.IntVar(&cfg.limit, "limit", 20, "number of differing entries diff lists per kind, 0 for all")
+
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if flags.NArg() < 2 {
+		return cfg, errUsage
+	}
+
+	cfg.path, cfg.cmd, cfg.args = flags.Arg(0), flags.Arg(1), flags.Args()[2:]
+
+	return cfg, nil
+}
+
+// run loads the snapshot and runs the subcommand of cfg, writing to w.
+func run(ctx context.Context, cfg config, w io.Writer) error {
+	switch {
+	case cfg.cmd == "stats" && len(cfg.args) == 0,
+		cfg.cmd == "get" && len(cfg.args) == 1,
+		cfg.cmd == "sample" && len(cfg.args) == 1,
+		cfg.cmd == "diff" && len(cfg.args) == 1:
+	default:
+		return errUsage
+	}
+
+	m, report, err := load(ctx, cfg.path)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", cfg.path, err)
+	}
+
+	switch cfg.cmd {
+	case "stats":
+		return stats(w, cfg.path, m, report)
+	case "get":
+		return get(w, m, cfg.args[0])
+	case "sample":
+		n, err := strconv.Atoi(cfg.args[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("%w: invalid sample size %q", errUsage, cfg.args[0])
+		}
+
+		return sample(w, m, n, rand.New(rand.NewSource(cfg.seed))) //nolint:gosec // sampling, not security
+	default:
+		other, _, err := load(ctx, cfg.args[0])
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", cfg.args[0], err)
+		}
+
+		return diff(w, m, other, cfg.limit)
+	}
+}
+
+// load imports the snapshot file or directory at path into a new map.
+// Duplicate records are skipped and counted in the report.
+func load(ctx context.Context, path string) (*txmap.SplitSwissMapUint64, txmap.ImportReport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, txmap.ImportReport{}, err
+	}
+
+	m := txmap.NewSplitSwissMapUint64(0)
+	opts := txmap.ImportOptions{SkipExisting: true}
+
+	if info.IsDir() {
+		var report txmap.ImportReport
+
+		if _, err = os.Stat(filepath.Join(path, "buckets.manifest")); err == nil {
+			report, err = txmap.LoadBuckets(ctx, m, path, 0, opts)
+		} else {
+			report, err = txmap.ImportSharded(ctx, m, path, opts)
+		}
+
+		return m, report, err
+	}
+
+	f, err := os.Open(path) //nolint:gosec // path is chosen by the operator
+	if err != nil {
+		return nil, txmap.ImportReport{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	report, err := txmap.Import(ctx, m, f, opts)
+
+	return m, report, err
+}
+
+// stats prints the format, size and contents of the snapshot at path.
+func stats(w io.Writer, path string, m *txmap.SplitSwissMapUint64, report txmap.ImportReport) error {
+	if version, ok := snapshotVersion(path); ok {
+		_, _ = fmt.Fprintf(w, "format:     snapshot version %d\n", version)
+	} else {
+		_, _ = fmt.Fprintf(w, "format:     directory\n")
+	}
+
+	_, _ = fmt.Fprintf(w, "records:    %d\n", report.Read)
+	_, _ = fmt.Fprintf(w, "entries:    %d\n", m.Length())
+	_, _ = fmt.Fprintf(w, "duplicates: %d\n", report.Skipped)
+
+	low, high, zero := uint64(math.MaxUint64), uint64(0), 0
+
+	m.Iter(func(_ chainhash.Hash, value uint64) bool {
+		low, high = min(low, value), max(high, value)
+
+		if value == 0 {
+			zero++
+		}
+
+		return false
+	})
+
+	if m.Length() > 0 {
+		_, _ = fmt.Fprintf(w, "values:     %d to %d, %d zero\n", low, high, zero)
+	}
+
+	_, err := fmt.Fprintf(w, "buckets:    %s\n", m.Stats())
+
+	return err
+}
+
+// snapshotVersion returns the version in the header of the snapshot file at
+// path, and false for directories and unreadable files.
+func snapshotVersion(path string) (uint16, bool) {
+	f, err := os.Open(path) //nolint:gosec // path is chosen by the operator
+	if err != nil {
+		return 0, false
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, 6)
+	if _, err = io.ReadFull(f, header); err != nil || string(header[:4]) != "TXMP" {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint16(header[4:]), true
+}
+
+// get prints the value of txid.
+func get(w io.Writer, m *txmap.SplitSwissMapUint64, txid string) error {
+	hash, err := chainhash.NewHashFromStr(txid)
+	if err != nil {
+		return fmt.Errorf("%w: invalid txid %q: %w", errUsage, txid, err)
+	}
+
+	value, ok := m.Get(*hash)
+	if !ok {
+		return fmt.Errorf("%w: %s", errNotFound, hash)
+	}
+
+	_, err = fmt.Fprintf(w, "%s %d\n", hash, value)
+
+	return err
+}
+
+// sample prints n entries of m chosen uniformly at random by reservoir
+// sampling.
+func sample(w io.Writer, m *txmap.SplitSwissMapUint64, n int, rng *rand.Rand) error {
+	type entry struct {
+		hash  chainhash.Hash
+		value uint64
+	}
+
+	reservoir := make([]entry, 0, min(n, m.Length()))
+	seen := 0
+
+	m.Iter(func(hash chainhash.Hash, value uint64) bool {
+		seen++
+
+		if len(reservoir) < n {
+			reservoir = append(reservoir, entry{hash, value})
+		} else if i := rng.Intn(seen); i < n {
+			reservoir[i] = entry{hash, value}
+		}
+
+		return false
+	})
+
+	for _, e := range reservoir {
+		if _, err := fmt.Fprintf(w, "%s %d\n", e.hash, e.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diff prints the entries only in a, only in b and in both with different
+// values, at most limit of each, followed by the counts. It returns
+// errDifferent if there are any.
+func diff(w io.Writer, a, b *txmap.SplitSwissMapUint64, limit int) error {
+	var onlyA, onlyB, changed int
+
+	show := func(count int, format string, args ...any) {
+		if limit <= 0 || count <= limit {
+			_, _ = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	a.Iter(func(hash chainhash.Hash, value uint64) bool {
+		other, ok := b.Get(hash)
+
+		switch {
+		case !ok:
+			onlyA++
+			show(onlyA, "- %s %d\n", hash, value)
+		case other != value:
+			changed++
+			show(changed, "~ %s %d -> %d\n", hash, value, other)
+		}
+
+		return false
+	})
+
+	b.Iter(func(hash chainhash.Hash, value uint64) bool {
+		if !a.Exists(hash) {
+			onlyB++
+			show(onlyB, "+ %s %d\n", hash, value)
+		}
+
+		return false
+	})
+
+	_, _ = fmt.Fprintf(w, "%d only in first, %d only in second, %d changed\n", onlyA, onlyB, changed)
+
+	if onlyA+onlyB+changed > 0 {
+		return errDifferent
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+// Package main provides txmap-soak, a soak test for the maps of the txmap
+// package.
+//
+// It runs a mixed workload of Put, PutMulti, Get, Set, SetIfNotExists and
+// Delete calls from a number of workers against one map for a long time. At
+// every check interval the workers are paused, the map's CheckInvariants is
+// run, and the number of goroutines and the live heap are compared with the
+// baseline taken at the first check. Since the key space is bounded, neither
+// should keep growing; a failed check stops the run with exit status 1.
+//
+// Usage:
+//
+//	txmap-soak -backend SplitSwissMapUint64 -duration 4h -workers 16
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	txmap "github.com/bsv-blockchain/go-tx-map"
+)
+
+var (
+	// errUnknownBackend is returned for a -backend that is not in backends.
+	errUnknownBackend = errors.New("unknown backend")
+
+	// errGoroutineLeak is returned when the number of goroutines grew beyond
+	// the allowed slack.
+	errGoroutineLeak = errors.New("goroutine leak")
+
+	// errHeapGrowth is returned when the live heap grew beyond the allowed ratio.
+	errHeapGrowth = errors.New("heap growth")
+)
+
+// soakMap is a map that can be soak tested.
+type soakMap interface {
+	txmap.TxMap
+	txmap.InvariantChecker
+}
+
+// backends returns the constructors of the maps that can be soak tested.
+func backends(length uint32) map[string]func() soakMap {
+	return map[string]func() soakMap{
+		"SwissMapUint64":       func() soakMap { return txmap.NewSwissMapUint64(length) },
+		"SplitSwissMap":        func() soakMap { return txmap.NewSplitSwissMap(int(length)) },
+		"SplitSwissMapUint64":  func() soakMap { return txmap.NewSplitSwissMapUint64(length) },
+		"NativeMapUint64":      func() soakMap { return txmap.NewNativeMapUint64(length) },
+		"NativeSplitMap":       func() soakMap { return txmap.NewNativeSplitMap(int(length)) },
+		"NativeSplitMapUint64": func() soakMap { return txmap.NewNativeSplitMapUint64(length) },
+	}
+}
+
+// config holds the command line flags.
+type config struct {
+	backend         string
+	duration        time.Duration
+	checkInterval   time.Duration
+	workers         int
+	keys            int
+	seed            int64
+	goroutineSlack  int
+	maxHeapGrowth   float64
+	clearEveryCheck int
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg); err != nil {
+		log.Printf("FAIL: %v", err)
+		stop()
+		os.Exit(1) //nolint:gocritic // stop has been called
+	}
+
+	log.Print("PASS")
+}
+
+// parseFlags parses the command line into a config.
+func parseFlags() config {
+	var cfg config
+
+	flag.StringVar(&cfg.backend, "backend", "SplitSwissMapUint64", "map to test, one of: "+backendNames())
+	flag.DurationVar(&cfg.duration, "duration", time.Hour, "how long to run")
+	flag.DurationVar(&cfg.checkInterval, "check-interval", time.Minute, "time between invariant and leak checks")
+	flag.IntVar(&cfg.workers, "workers", runtime.GOMAXPROCS(0), "number of concurrent workers")
+	flag.IntVar(&cfg.keys, "keys", 1_000_000, "size of the key space")
+	flag.Int64Var(&cfg.seed, "seed", time.Now().UnixNano(), "seed of the workload")
+	flag.IntVar(&cfg.goroutineSlack, "goroutine-slack", 10, "number of goroutines allowed above the baseline")
+	flag.Float64Var(&cfg.maxHeapGrowth, "max-heap-growth", 1.5, "ratio of the live heap to the baseline that fails the run")
+	flag.IntVar(&cfg.clearEveryCheck, "clear-every", 10, "clear the map every n checks, 0 to never clear")
+	flag.Parse()
+
+	return cfg
+}
+
+// backendNames returns the sorted names of the backends.
+func backendNames() string {
+	names := make([]string, 0)
+	for name := range backends(0) {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return fmt.Sprint(names)
+}
+
+// run soak tests the configured backend until the duration elapses, ctx is
+// done or a check fails.
+func run(ctx context.Context, cfg config) error {
+	newMap, ok := backends(uint32(cfg.keys))[cfg.backend] //nolint:gosec // G115: key space fits in uint32
+	if !ok {
+		return fmt.Errorf("%w: %q", errUnknownBackend, cfg.backend)
+	}
+
+	log.Printf("soaking %s for %s with %d workers, %d keys, seed %d", cfg.backend, cfg.duration, cfg.workers, cfg.keys, cfg.seed)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	s := &soak{cfg: cfg, m: newMap()}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+
+		go func(seed int64) {
+			defer wg.Done()
+			s.work(ctx, rand.New(rand.NewSource(seed))) //nolint:gosec // reproducible workload
+		}(cfg.seed + int64(i))
+	}
+
+	err := s.checkEvery(ctx)
+
+	cancel()
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%d operations, %d entries", s.ops.Load(), s.m.Length())
+
+	return s.m.CheckInvariants()
+}
+
+// soak is the state of a running soak test.
+type soak struct {
+	cfg config
+	m   soakMap
+
+	// pause is held for reading by the workers while they run a batch of
+	// operations, and for writing by the checks.
+	pause sync.RWMutex
+	ops   atomic.Uint64
+
+	goroutines int
+	heap       uint64
+}
+
+// work runs batches of random operations until ctx is done.
+func (s *soak) work(ctx context.Context, rng *rand.Rand) {
+	const batch = 1000
+
+	for ctx.Err() == nil {
+		s.pause.RLock()
+
+		for i := 0; i < batch; i++ {
+			s.operate(rng)
+		}
+
+		s.pause.RUnlock()
+		s.ops.Add(batch)
+	}
+}
+
+// operate runs one random operation. Errors such as ErrHashAlreadyExists are
+// expected from a random workload and ignored; only the invariants are checked.
+func (s *soak) operate(rng *rand.Rand) {
+	hash := s.key(rng)
+	value := rng.Uint64()
+
+	switch op := rng.Intn(100); {
+	case op < 25:
+		_ = s.m.Put(hash, value)
+	case op < 30:
+		_ = s.m.PutMulti([]chainhash.Hash{hash, s.key(rng), s.key(rng)}, value)
+	case op < 60:
+		_, _ = s.m.Get(hash)
+	case op < 70:
+		_ = s.m.Set(hash, value)
+	case op < 75:
+		_, _ = s.m.SetIfNotExists(hash, value)
+	default:
+		_ = s.m.Delete(hash)
+	}
+}
+
+// key returns a random key of the key space.
+func (s *soak) key(rng *rand.Rand) chainhash.Hash {
+	var hash chainhash.Hash
+
+	i := rng.Intn(s.cfg.keys)
+	for j := 0; j < 8; j++ {
+		hash[j] = byte(i >> (8 * j))
+	}
+
+	// spread the keys over the buckets of the split maps
+	hash[0], hash[1] = hash[1]^hash[0]*31, hash[0]
+	hash[31] = 1
+
+	return hash
+}
+
+// checkEvery runs check every check interval until ctx is done.
+func (s *soak) checkEvery(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.checkInterval)
+	defer ticker.Stop()
+
+	for n := 1; ; n++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.check(n); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// check pauses the workers and runs check n: the invariants of the map, and
+// the goroutine count and live heap against the baseline of the first check.
+func (s *soak) check(n int) error {
+	s.pause.Lock()
+	defer s.pause.Unlock()
+
+	if err := s.m.CheckInvariants(); err != nil {
+		return fmt.Errorf("check %d: %w", n, err)
+	}
+
+	runtime.GC()
+
+	var mstats runtime.MemStats
+
+	runtime.ReadMemStats(&mstats)
+
+	goroutines := runtime.NumGoroutine()
+
+	log.Printf("check %d: %d entries, %d goroutines, %d MiB live heap", n, s.m.Length(), goroutines, mstats.HeapAlloc>>20)
+
+	if n == 1 {
+		s.goroutines, s.heap = goroutines, mstats.HeapAlloc
+	} else if err := s.checkLeaks(goroutines, mstats.HeapAlloc); err != nil {
+		return fmt.Errorf("check %d: %w", n, err)
+	}
+
+	if s.cfg.clearEveryCheck > 0 && n%s.cfg.clearEveryCheck == 0 {
+		s.m.Clear()
+	}
+
+	return nil
+}
+
+// checkLeaks compares the goroutine count and the live heap with the baseline.
+func (s *soak) checkLeaks(goroutines int, heap uint64) error {
+	if goroutines > s.goroutines+s.cfg.goroutineSlack {
+		return fmt.Errorf("%w: %d goroutines, baseline %d", errGoroutineLeak, goroutines, s.goroutines)
+	}
+
+	if float64(heap) > float64(s.heap)*s.cfg.maxHeapGrowth {
+		return fmt.Errorf("%w: %d bytes, baseline %d", errHeapGrowth, heap, s.heap)
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that ComputingTxMap implements TxMap
+var _ TxMap = (*ComputingTxMap)(nil)
+
+// ErrComputePanicked is returned by GetOrCompute to the callers that were
+// waiting on a computation that panicked. The panic itself propagates in the
+// goroutine that ran the computation.
+var ErrComputePanicked = errors.New("compute function panicked")
+
+// computeCall is an in-flight GetOrCompute computation shared by all callers
+// asking for the same hash.
+type computeCall struct {
+	done  chan struct{}
+	value uint64
+	err   error
+}
+
+// ComputingTxMap wraps a TxMap with GetOrCompute, which fills in missing
+// entries on demand while collapsing concurrent misses for the same hash into
+// a single computation. It is meant for read-through caches in front of a
+// slower store, where a hot unknown txid would otherwise send every concurrent
+// request to the store at once.
+type ComputingTxMap struct {
+	m TxMap
+
+	mu       sync.Mutex
+	inflight map[chainhash.Hash]*computeCall
+}
+
+// NewComputingTxMap returns a ComputingTxMap that forwards every operation to m.
+//
+// Params:
+//   - m: The map to wrap.
+//
+// Returns:
+//   - *ComputingTxMap: The wrapping map.
+func NewComputingTxMap(m TxMap) *ComputingTxMap {
+	m = nonNilTxMap(m)
+
+	return &ComputingTxMap{
+		m:        m,
+		inflight: make(map[chainhash.Hash]*computeCall),
+	}
+}
+
+// GetOrCompute returns the value of hash, calling compute to produce and store
+// it if the hash is missing. Concurrent callers for the same missing hash
+// share one call of compute and all receive its result.
+//
+// Errors are not cached: the callers sharing a failed computation all receive
+// its error, and the next call for the hash computes again.
+//
+// Params:
+//   - hash: The hash to look up.
+//   - compute: Produces the value of a missing hash. It must not call
+//     GetOrCompute for the same hash, which would deadlock.
+//
+// Returns:
+//   - uint64: The stored value. If the hash was inserted by another writer
+//     while compute ran, that value wins and is returned instead.
+//   - error: The error returned by compute, ErrComputePanicked for callers that
+//     shared a computation that panicked, or the error from storing the value
+//     (e.g. ErrMapFrozen, in which case the computed value is still returned).
+func (c *ComputingTxMap) GetOrCompute(hash chainhash.Hash, compute func() (uint64, error)) (uint64, error) {
+	if v, ok := c.m.Get(hash); ok {
+		return v, nil
+	}
+
+	c.mu.Lock()
+
+	if call, ok := c.inflight[hash]; ok {
+		c.mu.Unlock()
+		<-call.done
+
+		return call.value, call.err
+	}
+
+	// a computation may have stored the value between the Get above and the lock
+	if v, ok := c.m.Get(hash); ok {
+		c.mu.Unlock()
+		return v, nil
+	}
+
+	call := &computeCall{done: make(chan struct{})}
+	c.inflight[hash] = call
+	c.mu.Unlock()
+
+	c.run(hash, call, compute)
+
+	return call.value, call.err
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (c *ComputingTxMap) Exists(hash chainhash.Hash) bool {
+	return c.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (c *ComputingTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return c.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (c *ComputingTxMap) Keys() []chainhash.Hash {
+	return c.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (c *ComputingTxMap) Length() int {
+	return c.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (c *ComputingTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	c.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map.
+func (c *ComputingTxMap) Put(hash chainhash.Hash, value uint64) error {
+	return c.m.Put(hash, value)
+}
+
+// PutMulti adds hashes to the wrapped map.
+func (c *ComputingTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	return c.m.PutMulti(hashes, value)
+}
+
+// Set updates the value of an existing hash in the wrapped map.
+func (c *ComputingTxMap) Set(hash chainhash.Hash, value uint64) error {
+	return c.m.Set(hash, value)
+}
+
+// SetIfExists updates the value of hash in the wrapped map if it exists.
+func (c *ComputingTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return c.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash to the wrapped map if it does not exist yet.
+func (c *ComputingTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return c.m.SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the wrapped map.
+func (c *ComputingTxMap) Delete(hash chainhash.Hash) error {
+	return c.m.Delete(hash)
+}
+
+// Freeze freezes the wrapped map. GetOrCompute still computes missing values
+// but can no longer store them.
+func (c *ComputingTxMap) Freeze() {
+	c.m.Freeze()
+}
+
+// Clear empties the wrapped map. Computations in flight store their result
+// into the cleared map when they finish.
+func (c *ComputingTxMap) Clear() {
+	c.m.Clear()
+}
+
+// run executes compute for call, stores its result and releases the waiters,
+// also when compute panics.
+func (c *ComputingTxMap) run(hash chainhash.Hash, call *computeCall, compute func() (uint64, error)) {
+	finished := false
+
+	defer func() {
+		if !finished {
+			call.err = ErrComputePanicked
+		}
+
+		c.mu.Lock()
+		delete(c.inflight, hash)
+		c.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	value, err := compute()
+	finished = true
+
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	added, err := c.m.SetIfNotExists(hash, value)
+
+	switch {
+	case err != nil:
+		call.value, call.err = value, fmt.Errorf("storing computed value: %w", err)
+	case !added:
+		// another writer inserted the hash while compute ran; report its value
+		call.value, _ = c.m.Get(hash)
+	default:
+		call.value = value
+	}
+}
@@ -0,0 +1,95 @@
+package txmap
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errStoreUnavailable = errors.New("store unavailable")
+
+// TestComputingTxMap tests the basic TxMap behavior of ComputingTxMap.
+func TestComputingTxMap(t *testing.T) {
+	testTxMap(t, NewComputingTxMap(NewNativeMapUint64(100)))
+}
+
+// TestGetOrComputeSingleflight tests that concurrent misses for the same hash
+// share one computation whose result is stored.
+func TestGetOrComputeSingleflight(t *testing.T) {
+	const callers = 50
+
+	m := NewComputingTxMap(NewSwissMapUint64(10))
+
+	var (
+		calls   atomic.Int32
+		started = make(chan struct{})
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+
+	compute := func() (uint64, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+
+		<-release
+
+		return 42, nil
+	}
+
+	wg.Add(callers)
+
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+
+			v, err := m.GetOrCompute(hashN(1), compute)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(42), v)
+		}()
+	}
+
+	<-started
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+
+	v, ok := m.Get(hashN(1))
+	require.True(t, ok)
+	assert.Equal(t, uint64(42), v)
+
+	// a stored value is returned without computing
+	v, err := m.GetOrCompute(hashN(1), func() (uint64, error) { panic("must not be called") })
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), v)
+}
+
+// TestGetOrComputeErrors tests that errors are not cached and that waiters of
+// a panicking computation are released.
+func TestGetOrComputeErrors(t *testing.T) {
+	m := NewComputingTxMap(NewNativeMapUint64(10))
+
+	_, err := m.GetOrCompute(hashN(1), func() (uint64, error) { return 0, errStoreUnavailable })
+	require.ErrorIs(t, err, errStoreUnavailable)
+	assert.False(t, m.Exists(hashN(1)))
+
+	v, err := m.GetOrCompute(hashN(1), func() (uint64, error) { return 7, nil })
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), v)
+
+	require.Panics(t, func() {
+		_, _ = m.GetOrCompute(hashN(2), func() (uint64, error) { panic("boom") })
+	})
+	assert.Empty(t, m.inflight)
+
+	m.Freeze()
+
+	v, err = m.GetOrCompute(hashN(3), func() (uint64, error) { return 3, nil })
+	require.ErrorIs(t, err, ErrMapFrozen)
+	assert.Equal(t, uint64(3), v)
+}
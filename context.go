@@ -0,0 +1,202 @@
+package txmap
+
+import (
+	"context"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// ContextTxMap is implemented by maps whose operations may block on disk or
+// network, such as maps backed by an embedded database or a remote service.
+// Every method takes a context so that callers can enforce deadlines and
+// cancel slow operations; a method returns the context's error if it gives up.
+//
+// The in-memory maps of this package never block for long, so they do not
+// implement ContextTxMap themselves. Use WithContext to obtain a ContextTxMap
+// for any TxMap.
+type ContextTxMap interface {
+	TxMap
+
+	// ExistsCtx is Exists with a context.
+	ExistsCtx(ctx context.Context, hash chainhash.Hash) (bool, error)
+
+	// GetCtx is Get with a context.
+	GetCtx(ctx context.Context, hash chainhash.Hash) (uint64, bool, error)
+
+	// PutCtx is Put with a context.
+	PutCtx(ctx context.Context, hash chainhash.Hash, value uint64) error
+
+	// PutMultiCtx is PutMulti with a context.
+	PutMultiCtx(ctx context.Context, hashes []chainhash.Hash, value uint64) error
+
+	// SetCtx is Set with a context.
+	SetCtx(ctx context.Context, hash chainhash.Hash, value uint64) error
+
+	// SetIfExistsCtx is SetIfExists with a context.
+	SetIfExistsCtx(ctx context.Context, hash chainhash.Hash, value uint64) (bool, error)
+
+	// SetIfNotExistsCtx is SetIfNotExists with a context.
+	SetIfNotExistsCtx(ctx context.Context, hash chainhash.Hash, value uint64) (bool, error)
+
+	// DeleteCtx is Delete with a context.
+	DeleteCtx(ctx context.Context, hash chainhash.Hash) error
+}
+
+// AsContext reports whether m is a ContextTxMap and returns it as one.
+//
+// Params:
+//   - m: The map to probe.
+//
+// Returns:
+//   - ContextTxMap: m as a ContextTxMap, or nil.
+//   - bool: True if m implements ContextTxMap.
+func AsContext(m TxMap) (ContextTxMap, bool) {
+	cm, ok := m.(ContextTxMap)
+	return cm, ok
+}
+
+// WithContext returns m as a ContextTxMap. Maps that implement ContextTxMap
+// are returned unchanged; any other map is wrapped so that every ctx method
+// checks the context before running the plain, non-blocking operation.
+//
+// Params:
+//   - m: The map to use through contexts.
+//
+// Returns:
+//   - ContextTxMap: m itself or the wrapper.
+func WithContext(m TxMap) ContextTxMap {
+	m = nonNilTxMap(m)
+
+	if cm, ok := AsContext(m); ok {
+		return cm
+	}
+
+	return &contextTxMap{m: m}
+}
+
+// check that contextTxMap implements ContextTxMap
+var _ ContextTxMap = (*contextTxMap)(nil)
+
+// contextTxMap implements ContextTxMap for maps whose operations do not block.
+type contextTxMap struct {
+	m TxMap
+}
+
+// ExistsCtx checks if the given hash exists in the map, unless ctx is done.
+func (c *contextTxMap) ExistsCtx(ctx context.Context, hash chainhash.Hash) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.m.Exists(hash), nil
+}
+
+// GetCtx retrieves the value associated with the given hash, unless ctx is done.
+func (c *contextTxMap) GetCtx(ctx context.Context, hash chainhash.Hash) (uint64, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	value, ok := c.m.Get(hash)
+
+	return value, ok, nil
+}
+
+// PutCtx adds a hash with the given value to the map, unless ctx is done.
+func (c *contextTxMap) PutCtx(ctx context.Context, hash chainhash.Hash, value uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.m.Put(hash, value)
+}
+
+// PutMultiCtx adds hashes with the given value to the map, unless ctx is done.
+func (c *contextTxMap) PutMultiCtx(ctx context.Context, hashes []chainhash.Hash, value uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.m.PutMulti(hashes, value)
+}
+
+// SetCtx updates the value of an existing hash, unless ctx is done.
+func (c *contextTxMap) SetCtx(ctx context.Context, hash chainhash.Hash, value uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.m.Set(hash, value)
+}
+
+// SetIfExistsCtx updates the value of hash if it exists, unless ctx is done.
+func (c *contextTxMap) SetIfExistsCtx(ctx context.Context, hash chainhash.Hash, value uint64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExistsCtx adds hash if it does not exist, unless ctx is done.
+func (c *contextTxMap) SetIfNotExistsCtx(ctx context.Context, hash chainhash.Hash, value uint64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return c.m.SetIfNotExists(hash, value)
+}
+
+// DeleteCtx removes hash from the map, unless ctx is done.
+func (c *contextTxMap) DeleteCtx(ctx context.Context, hash chainhash.Hash) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.m.Delete(hash)
+}
+
+// Exists checks if the given hash exists in the map.
+func (c *contextTxMap) Exists(hash chainhash.Hash) bool { return c.m.Exists(hash) }
+
+// Get retrieves the value associated with the given hash.
+func (c *contextTxMap) Get(hash chainhash.Hash) (uint64, bool) { return c.m.Get(hash) }
+
+// Keys returns all hashes in the map.
+func (c *contextTxMap) Keys() []chainhash.Hash { return c.m.Keys() }
+
+// Length returns the number of hashes in the map.
+func (c *contextTxMap) Length() int { return c.m.Length() }
+
+// Iter iterates over the map. Stops iterating if f returns true.
+func (c *contextTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) { c.m.Iter(f) }
+
+// Put adds a hash with the given value to the map.
+func (c *contextTxMap) Put(hash chainhash.Hash, value uint64) error { return c.m.Put(hash, value) }
+
+// PutMulti adds hashes with the given value to the map.
+func (c *contextTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	return c.m.PutMulti(hashes, value)
+}
+
+// Set updates the value of an existing hash.
+func (c *contextTxMap) Set(hash chainhash.Hash, value uint64) error { return c.m.Set(hash, value) }
+
+// SetIfExists updates the value of hash if it exists.
+func (c *contextTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return c.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash if it does not exist.
+func (c *contextTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return c.m.SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the map.
+func (c *contextTxMap) Delete(hash chainhash.Hash) error { return c.m.Delete(hash) }
+
+// Freeze freezes the wrapped map.
+func (c *contextTxMap) Freeze() { c.m.Freeze() }
+
+// Clear clears the wrapped map.
+func (c *contextTxMap) Clear() { c.m.Clear() }
@@ -0,0 +1,60 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithContext tests the ctx methods of the WithContext wrapper with live
+// and canceled contexts.
+func TestWithContext(t *testing.T) {
+	inner := NewNativeMapUint64(16)
+	m := WithContext(inner)
+
+	_, ok := AsContext(inner)
+	assert.False(t, ok)
+
+	cm, ok := AsContext(m)
+	require.True(t, ok)
+	assert.Same(t, m, WithContext(cm))
+
+	ctx := context.Background()
+
+	require.NoError(t, m.PutCtx(ctx, hashN(1), 1))
+	require.NoError(t, m.PutMultiCtx(ctx, []chainhash.Hash{hashN(2), hashN(3)}, 2))
+	require.NoError(t, m.SetCtx(ctx, hashN(2), 5))
+
+	value, ok, err := m.GetCtx(ctx, hashN(2))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5), value)
+
+	updated, err := m.SetIfExistsCtx(ctx, hashN(4), 1)
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	added, err := m.SetIfNotExistsCtx(ctx, hashN(4), 1)
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	require.NoError(t, m.DeleteCtx(ctx, hashN(3)))
+
+	exists, err := m.ExistsCtx(ctx, hashN(3))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, 3, inner.Length())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	require.ErrorIs(t, m.PutCtx(canceled, hashN(5), 1), context.Canceled)
+	require.ErrorIs(t, m.DeleteCtx(canceled, hashN(1)), context.Canceled)
+
+	_, _, err = m.GetCtx(canceled, hashN(1))
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 3, inner.Length())
+}
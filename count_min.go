@@ -0,0 +1,148 @@
+package txmap
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+const (
+	// countMinMinEpsilon and countMinMinDelta bound the error parameters
+	// accepted by NewCountMinSketch, keeping the table at a sane size.
+	countMinMinEpsilon = 1e-7
+	countMinMinDelta   = 1e-9
+)
+
+// CountMinSketch approximately counts how often each transaction hash was
+// seen, in a fixed amount of memory independent of the number of hashes, for
+// uses like counting how often a txid is announced to detect relay abuse.
+//
+// Estimate never undercounts. It overcounts by at most epsilon times the total
+// of all counts, with probability at least 1 - delta. Counters are decayed by
+// halving them, so that old activity fades instead of accumulating forever.
+//
+// Rows are indexed by double hashing two words of the transaction hash, like
+// BloomFilter; transaction hashes are uniformly distributed already. All
+// methods are safe for concurrent use.
+type CountMinSketch struct {
+	counters []atomic.Uint32
+	width    uint64
+	depth    uint64
+	total    atomic.Uint64
+}
+
+// NewCountMinSketch returns an empty sketch with the given error bounds.
+//
+// Params:
+//   - epsilon: The overcount bound relative to the total count; clamped to
+//     [1e-7, 1]. The sketch holds ceil(e / epsilon) counters per row.
+//   - delta: The probability of exceeding the bound; clamped to [1e-9, 1).
+//     The sketch has ceil(ln(1 / delta)) rows.
+//
+// Returns:
+//   - *CountMinSketch: The empty sketch.
+func NewCountMinSketch(epsilon, delta float64) *CountMinSketch {
+	epsilon = min(max(epsilon, countMinMinEpsilon), 1)
+	delta = min(max(delta, countMinMinDelta), 0.5)
+
+	width := uint64(math.Ceil(math.E / epsilon))
+	depth := uint64(math.Ceil(math.Log(1 / delta)))
+
+	return &CountMinSketch{
+		counters: make([]atomic.Uint32, width*depth),
+		width:    width,
+		depth:    depth,
+	}
+}
+
+// Add counts one occurrence of hash.
+//
+// Params:
+//   - hash: The hash seen.
+//
+// Returns:
+//   - uint32: The estimated count of hash after adding it.
+func (c *CountMinSketch) Add(hash chainhash.Hash) uint32 {
+	return c.AddN(hash, 1)
+}
+
+// AddN counts n occurrences of hash. Counters saturate at math.MaxUint32.
+//
+// Params:
+//   - hash: The hash seen.
+//   - n: The number of occurrences.
+//
+// Returns:
+//   - uint32: The estimated count of hash after adding them.
+func (c *CountMinSketch) AddN(hash chainhash.Hash, n uint32) uint32 {
+	h1, h2 := bloomHashes(hash)
+	estimate := uint32(math.MaxUint32)
+
+	for row := uint64(0); row < c.depth; row++ {
+		counter := &c.counters[row*c.width+(h1+row*h2)%c.width]
+
+		for {
+			old := counter.Load()
+			updated := old + min(n, math.MaxUint32-old)
+
+			if counter.CompareAndSwap(old, updated) {
+				estimate = min(estimate, updated)
+				break
+			}
+		}
+	}
+
+	c.total.Add(uint64(n))
+
+	return estimate
+}
+
+// Estimate returns the estimated number of occurrences of hash: the minimum
+// of its counters, which is never below the true count since the last Clear,
+// ignoring decay.
+func (c *CountMinSketch) Estimate(hash chainhash.Hash) uint32 {
+	h1, h2 := bloomHashes(hash)
+	estimate := uint32(math.MaxUint32)
+
+	for row := uint64(0); row < c.depth; row++ {
+		estimate = min(estimate, c.counters[row*c.width+(h1+row*h2)%c.width].Load())
+	}
+
+	return estimate
+}
+
+// Total returns the number of occurrences added since the last Clear,
+// halved by every Decay like the counters.
+func (c *CountMinSketch) Total() uint64 {
+	return c.total.Load()
+}
+
+// Decay halves every counter, so that recent occurrences weigh more than old
+// ones. Adds racing the call may or may not be halved.
+func (c *CountMinSketch) Decay() {
+	for i := range c.counters {
+		for {
+			old := c.counters[i].Load()
+			if old == 0 || c.counters[i].CompareAndSwap(old, old/2) {
+				break
+			}
+		}
+	}
+
+	for {
+		old := c.total.Load()
+		if c.total.CompareAndSwap(old, old/2) {
+			break
+		}
+	}
+}
+
+// Clear resets every counter. Not safe for concurrent use.
+func (c *CountMinSketch) Clear() {
+	for i := range c.counters {
+		c.counters[i].Store(0)
+	}
+
+	c.total.Store(0)
+}
@@ -0,0 +1,77 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCountMinSketch tests that estimates never undercount, stay within the
+// error bound, and halve on Decay.
+func TestCountMinSketch(t *testing.T) {
+	const epsilon = 0.001
+
+	c := NewCountMinSketch(epsilon, 0.01)
+
+	// hash i is seen i%10+1 times
+	for i := 0; i < 5000; i++ {
+		for n := 0; n <= i%10; n++ {
+			c.Add(bloomHash(i))
+		}
+	}
+
+	total := c.Total()
+	assert.Equal(t, uint64(500*55), total)
+
+	bound := uint32(epsilon * float64(total))
+	overBound := 0
+
+	for i := 0; i < 5000; i++ {
+		want := uint32(i%10 + 1) //nolint:gosec // small test counts
+		got := c.Estimate(bloomHash(i))
+
+		require.GreaterOrEqual(t, got, want)
+
+		if got > want+bound {
+			overBound++
+		}
+	}
+
+	assert.LessOrEqual(t, overBound, 50, "at most delta of the estimates may exceed the bound")
+	assert.Equal(t, uint32(0), NewCountMinSketch(epsilon, 0.01).Estimate(bloomHash(1)))
+
+	before := c.Estimate(bloomHash(9))
+	c.Decay()
+	assert.Equal(t, before/2, c.Estimate(bloomHash(9)))
+	assert.Equal(t, total/2, c.Total())
+
+	c.Clear()
+	assert.Equal(t, uint32(0), c.Estimate(bloomHash(9)))
+	assert.Equal(t, uint64(0), c.Total())
+}
+
+// TestCountMinSketchConcurrent tests concurrent adds of the same hash.
+func TestCountMinSketchConcurrent(t *testing.T) {
+	c := NewCountMinSketch(0.01, 0.01)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				c.Add(bloomHash(1))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, uint32(8000), c.Estimate(bloomHash(1)))
+	assert.Equal(t, uint32(5), c.AddN(bloomHash(2), 5))
+}
@@ -0,0 +1,250 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Counters
+//
+// Increment and Decrement treat the value of a hash as a counter, e.g. the
+// number of transactions spending a parent, and change it under a single
+// hold of the lock of the hash's bucket, so concurrent callers never lose an
+// update. Unlike AddMulti, which wraps around, they refuse to take a counter
+// past zero or 2^64-1, leaving it unchanged. Increment adds a missing hash
+// with value delta; Decrement keeps a hash that reaches zero, so that the
+// caller decides whether to Delete it.
+
+// ErrCounterOutOfRange is returned by Increment and Decrement when the result
+// would overflow or fall below zero.
+var ErrCounterOutOfRange = errors.New("counter out of range")
+
+// Increment adds delta to the value of hash, adding hash with value delta if
+// it does not exist. See the notes at the top of counter.go.
+//
+// Params:
+//   - hash: The hash whose counter to increment.
+//   - delta: The amount to add.
+//
+// Returns:
+//   - uint64: The new value.
+//   - error: ErrMapFrozen, or an error wrapping ErrCounterOutOfRange if the
+//     value would overflow.
+func (s *SwissMapUint64) Increment(hash chainhash.Hash, delta uint64) (uint64, error) {
+	if s.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, exists := s.m.Get(hash)
+
+	value, err := incremented(hash, value, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		debugAssertHash(hash)
+		debugAssertLength(s.length.Add(1))
+	}
+
+	s.m.Put(hash, value)
+
+	return value, nil
+}
+
+// Decrement subtracts delta from the value of hash. See the notes at the top
+// of counter.go.
+//
+// Params:
+//   - hash: The hash whose counter to decrement.
+//   - delta: The amount to subtract.
+//
+// Returns:
+//   - uint64: The new value.
+//   - error: ErrMapFrozen, an error wrapping ErrHashDoesNotExist, or an error
+//     wrapping ErrCounterOutOfRange if the value would fall below zero.
+func (s *SwissMapUint64) Decrement(hash chainhash.Hash, delta uint64) (uint64, error) {
+	if s.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, exists := s.m.Get(hash)
+
+	value, err := decremented(hash, value, exists, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	s.m.Put(hash, value)
+
+	return value, nil
+}
+
+// Increment adds delta to the value of hash, adding hash with value delta if
+// it does not exist. See SwissMapUint64.Increment.
+func (s *NativeMapUint64) Increment(hash chainhash.Hash, delta uint64) (uint64, error) {
+	if s.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, exists := s.m[hash]
+
+	value, err := incremented(hash, value, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		debugAssertHash(hash)
+		debugAssertLength(s.length.Add(1))
+	}
+
+	s.m[hash] = value
+
+	return value, nil
+}
+
+// Decrement subtracts delta from the value of hash. See
+// SwissMapUint64.Decrement.
+func (s *NativeMapUint64) Decrement(hash chainhash.Hash, delta uint64) (uint64, error) {
+	if s.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, exists := s.m[hash]
+
+	value, err := decremented(hash, value, exists, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	s.m[hash] = value
+
+	return value, nil
+}
+
+// Increment adds delta to the value of hash under the lock of its bucket. See
+// SwissMapUint64.Increment.
+func (g *SplitSwissMap) Increment(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Increment(hash, delta)
+}
+
+// Decrement subtracts delta from the value of hash under the lock of its
+// bucket. See SwissMapUint64.Decrement.
+func (g *SplitSwissMap) Decrement(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Decrement(hash, delta)
+}
+
+// Increment adds delta to the value of hash under the lock of its bucket. See
+// SwissMapUint64.Increment.
+func (g *SplitSwissMapUint64) Increment(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Increment(hash, delta)
+}
+
+// Decrement subtracts delta from the value of hash under the lock of its
+// bucket. See SwissMapUint64.Decrement.
+func (g *SplitSwissMapUint64) Decrement(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Decrement(hash, delta)
+}
+
+// Increment adds delta to the value of hash under the lock of its bucket. See
+// SwissMapUint64.Increment.
+func (g *NativeSplitMap) Increment(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Increment(hash, delta)
+}
+
+// Decrement subtracts delta from the value of hash under the lock of its
+// bucket. See SwissMapUint64.Decrement.
+func (g *NativeSplitMap) Decrement(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Decrement(hash, delta)
+}
+
+// Increment adds delta to the value of hash under the lock of its bucket. See
+// SwissMapUint64.Increment.
+func (g *NativeSplitMapUint64) Increment(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Increment(hash, delta)
+}
+
+// Decrement subtracts delta from the value of hash under the lock of its
+// bucket. See SwissMapUint64.Decrement.
+func (g *NativeSplitMapUint64) Decrement(hash chainhash.Hash, delta uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Decrement(hash, delta)
+}
+
+// incremented returns value+delta, or an error if it overflows.
+func incremented(hash chainhash.Hash, value, delta uint64) (uint64, error) {
+	if value > math.MaxUint64-delta {
+		return 0, fmt.Errorf("%w: %v: %d + %d", ErrCounterOutOfRange, hash, value, delta)
+	}
+
+	return value + delta, nil
+}
+
+// decremented returns value-delta, or an error if hash does not exist or the
+// result is below zero.
+func decremented(hash chainhash.Hash, value uint64, exists bool, delta uint64) (uint64, error) {
+	if !exists {
+		return 0, fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	if value < delta {
+		return 0, fmt.Errorf("%w: %v: %d - %d", ErrCounterOutOfRange, hash, value, delta)
+	}
+
+	return value - delta, nil
+}
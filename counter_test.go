@@ -0,0 +1,84 @@
+package txmap
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counterMap is implemented by the maps with Increment and Decrement.
+type counterMap interface {
+	TxMap
+	Increment(hash chainhash.Hash, delta uint64) (uint64, error)
+	Decrement(hash chainhash.Hash, delta uint64) (uint64, error)
+}
+
+// TestIncrementDecrement tests the counter methods of every map offering
+// them, including their range checks and concurrent use.
+func TestIncrementDecrement(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m, ok := factory().(counterMap)
+			require.True(t, ok)
+
+			value, err := m.Increment(hashN(1), 2)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(2), value)
+			assert.Equal(t, 1, m.Length())
+
+			value, err = m.Increment(hashN(1), 3)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(5), value)
+
+			value, err = m.Decrement(hashN(1), 5)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(0), value)
+			assert.True(t, m.Exists(hashN(1)), "a counter reaching zero is kept")
+
+			_, err = m.Decrement(hashN(1), 1)
+			require.ErrorIs(t, err, ErrCounterOutOfRange)
+
+			_, err = m.Decrement(hashN(2), 1)
+			require.ErrorIs(t, err, ErrHashDoesNotExist)
+
+			require.NoError(t, m.Put(hashN(3), math.MaxUint64))
+			_, err = m.Increment(hashN(3), 1)
+			require.ErrorIs(t, err, ErrCounterOutOfRange)
+
+			got, _ := m.Get(hashN(3))
+			assert.Equal(t, uint64(math.MaxUint64), got, "a failed increment changes nothing")
+
+			var wg sync.WaitGroup
+
+			for range 8 {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					for range 1000 {
+						_, err := m.Increment(hashN(4), 2)
+						assert.NoError(t, err)
+						_, err = m.Decrement(hashN(4), 1)
+						assert.NoError(t, err)
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			got, _ = m.Get(hashN(4))
+			assert.Equal(t, uint64(8000), got)
+
+			m.Freeze()
+			_, err = m.Increment(hashN(4), 1)
+			require.ErrorIs(t, err, ErrMapFrozen)
+			_, err = m.Decrement(hashN(4), 1)
+			require.ErrorIs(t, err, ErrMapFrozen)
+		})
+	}
+}
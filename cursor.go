@@ -0,0 +1,154 @@
+package txmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+const (
+	// iterCursorVersion1 is the version byte of a marshaled IterCursor.
+	iterCursorVersion1 = uint8(1)
+
+	// iterCursorSize is the size of a marshaled IterCursor:
+	// version uint8 | buckets uint32 | bucket uint32 | hasAfter uint8 | after [32]byte.
+	iterCursorSize = 1 + 4 + 4 + 1 + chainhash.HashSize
+)
+
+var (
+	// ErrInvalidCursor is returned when unmarshaling data that is not an IterCursor.
+	ErrInvalidCursor = errors.New("invalid iteration cursor")
+
+	// ErrCursorMismatch is returned by IterFrom when a cursor was taken on a
+	// map with a different number of buckets.
+	ErrCursorMismatch = errors.New("iteration cursor does not match map")
+)
+
+// IterCursor is a resumable position in a scan of a map by IterFrom. The zero
+// value starts a new scan. A cursor can be marshaled and resumed in another
+// process, so that a background auditor can scan a huge map in small time
+// slices across restarts.
+//
+// A scan visits the buckets in order and the entries of each bucket in hash
+// order, and the cursor records the bucket and the last hash visited in it.
+// Entries removed before the scan reaches them are not visited, entries added
+// before the scan reaches their position are, and every entry present for the
+// whole scan is visited exactly once.
+type IterCursor struct {
+	buckets  uint32
+	bucket   uint32
+	hasAfter bool
+	after    chainhash.Hash
+}
+
+// IterFrom visits up to limit entries of m, starting at cursor, and returns
+// the cursor after the last entry visited.
+//
+// Every call copies and sorts the not yet visited entries of the bucket it is
+// in, so scanning a map that is not split costs time proportional to its size
+// per call; the split maps keep this to the size of one bucket.
+//
+// Params:
+//   - m: The map to scan.
+//   - cursor: The position to resume from; the zero value starts a new scan.
+//   - limit: The maximum number of entries to visit; values below one mean no limit.
+//   - f: Called for every entry. Stops iterating if it returns true; the
+//     returned cursor then resumes after that entry.
+//
+// Returns:
+//   - IterCursor: The position to resume from; Done reports the end of the scan.
+//   - error: ErrCursorMismatch if cursor was taken on a map with a different
+//     number of buckets.
+func IterFrom(m ReadOnlyTxMap, cursor IterCursor, limit int, f func(hash chainhash.Hash, value uint64) bool) (IterCursor, error) {
+	buckets := txMapBuckets(m)
+
+	if cursor.buckets == 0 {
+		cursor.buckets = uint32(len(buckets)) //nolint:gosec // at most 65536 buckets
+	}
+
+	if cursor.buckets != uint32(len(buckets)) { //nolint:gosec // at most 65536 buckets
+		return cursor, fmt.Errorf("%w: cursor has %d buckets, map has %d", ErrCursorMismatch, cursor.buckets, len(buckets))
+	}
+
+	visited := 0
+
+	for ; cursor.bucket < cursor.buckets; cursor.bucket, cursor.hasAfter = cursor.bucket+1, false {
+		for _, e := range cursor.remaining(buckets[cursor.bucket]) {
+			if limit > 0 && visited == limit {
+				return cursor, nil
+			}
+
+			visited++
+			cursor.after, cursor.hasAfter = e.Hash, true
+
+			if f(e.Hash, e.Value) {
+				return cursor, nil
+			}
+		}
+	}
+
+	return cursor, nil
+}
+
+// Done reports whether the scan has visited every bucket.
+func (c IterCursor) Done() bool {
+	return c.buckets > 0 && c.bucket >= c.buckets
+}
+
+// MarshalBinary encodes the cursor.
+func (c IterCursor) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1, iterCursorSize)
+
+	data[0] = iterCursorVersion1
+	data = binary.LittleEndian.AppendUint32(data, c.buckets)
+	data = binary.LittleEndian.AppendUint32(data, c.bucket)
+
+	if c.hasAfter {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
+
+	return append(data, c.after[:]...), nil
+}
+
+// UnmarshalBinary decodes a cursor encoded by MarshalBinary.
+func (c *IterCursor) UnmarshalBinary(data []byte) error {
+	if len(data) != iterCursorSize || data[0] != iterCursorVersion1 || data[9] > 1 {
+		return ErrInvalidCursor
+	}
+
+	c.buckets = binary.LittleEndian.Uint32(data[1:5])
+	c.bucket = binary.LittleEndian.Uint32(data[5:9])
+	c.hasAfter = data[9] == 1
+	copy(c.after[:], data[10:])
+
+	if c.bucket > c.buckets {
+		return ErrInvalidCursor
+	}
+
+	return nil
+}
+
+// remaining returns the entries of bucket after the cursor, in hash order.
+func (c IterCursor) remaining(bucket ReadOnlyTxMap) []KV {
+	var entries []KV
+
+	bucket.Iter(func(hash chainhash.Hash, value uint64) bool {
+		if !c.hasAfter || bytes.Compare(hash[:], c.after[:]) > 0 {
+			entries = append(entries, KV{Hash: hash, Value: value})
+		}
+
+		return false
+	})
+
+	slices.SortFunc(entries, func(a, b KV) int {
+		return bytes.Compare(a.Hash[:], b.Hash[:])
+	})
+
+	return entries
+}
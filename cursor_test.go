@@ -0,0 +1,90 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIterFrom tests scanning maps in small slices, marshaling the cursor
+// between slices as if the process restarted.
+func TestIterFrom(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			for i := 0; i < 2500; i++ {
+				require.NoError(t, m.Put(bloomHash(i), uint64(i)))
+			}
+
+			seen := make(map[chainhash.Hash]int)
+
+			var (
+				cursor IterCursor
+				err    error
+				calls  int
+			)
+
+			for !cursor.Done() {
+				cursor, err = IterFrom(m, cursor, 100, func(hash chainhash.Hash, _ uint64) bool {
+					seen[hash]++
+					return false
+				})
+				require.NoError(t, err)
+
+				data, err := cursor.MarshalBinary()
+				require.NoError(t, err)
+
+				cursor = IterCursor{}
+				require.NoError(t, cursor.UnmarshalBinary(data))
+
+				calls++
+			}
+
+			assert.Len(t, seen, 2500)
+			assert.GreaterOrEqual(t, calls, 25)
+
+			for _, n := range seen {
+				require.Equal(t, 1, n)
+			}
+		})
+	}
+}
+
+// TestIterFromStopAndMismatch tests resuming after f stopped the scan, and
+// resuming on a map with a different number of buckets.
+func TestIterFromStopAndMismatch(t *testing.T) {
+	m := NewNativeSplitMapUint64(100, 16)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, m.Put(hashN(i), uint64(i)))
+	}
+
+	var first chainhash.Hash
+
+	cursor, err := IterFrom(m, IterCursor{}, 0, func(hash chainhash.Hash, _ uint64) bool {
+		first = hash
+		return true
+	})
+	require.NoError(t, err)
+	assert.False(t, cursor.Done())
+
+	count := 0
+
+	cursor, err = IterFrom(m, cursor, 0, func(hash chainhash.Hash, _ uint64) bool {
+		assert.NotEqual(t, first, hash)
+
+		count++
+
+		return false
+	})
+	require.NoError(t, err)
+	assert.True(t, cursor.Done())
+	assert.Equal(t, 99, count)
+
+	_, err = IterFrom(NewNativeSplitMapUint64(100, 8), cursor, 0, func(chainhash.Hash, uint64) bool { return false })
+	require.ErrorIs(t, err, ErrCursorMismatch)
+
+	require.ErrorIs(t, new(IterCursor).UnmarshalBinary([]byte{1, 2}), ErrInvalidCursor)
+}
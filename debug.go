@@ -0,0 +1,55 @@
+//go:build txmapdebug
+
+package txmap
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Debug assertions
+//
+// Building with the txmapdebug tag enables cheap assertions on every Put and
+// Delete of the lock-based maps: the tracked length never goes negative, the
+// bucket a split map routes to is in range, and the all-zero hash is not
+// inserted unless DebugAllowZeroHash(true) was called. A failed assertion
+// panics at the operation that broke the invariant, instead of surfacing much
+// later as a wrong Length or a bogus entry. Without the tag the assertions are
+// empty functions that the compiler removes, so production builds pay
+// nothing. Run CI soak tests with: go test -tags txmapdebug ./...
+
+// debugAllowZeroHash makes debugAssertHash accept the all-zero hash.
+var debugAllowZeroHash atomic.Bool //nolint:gochecknoglobals // debug-only switch
+
+// debugEnabled reports whether the txmapdebug assertions are compiled in.
+const debugEnabled = true
+
+// DebugAllowZeroHash controls whether the txmapdebug build accepts inserting
+// the all-zero hash; it is rejected by default. It is a no-op in production
+// builds.
+func DebugAllowZeroHash(allow bool) {
+	debugAllowZeroHash.Store(allow)
+}
+
+// debugAssertLength panics if a tracked length went negative.
+func debugAssertLength(length int64) {
+	if length < 0 {
+		panic(fmt.Sprintf("txmapdebug: length went negative: %d", length))
+	}
+}
+
+// debugAssertBucket panics if a split map routed a hash outside its buckets.
+func debugAssertBucket(bucket, nrOfBuckets uint16) {
+	if bucket > nrOfBuckets {
+		panic(fmt.Sprintf("txmapdebug: bucket %d out of range 0..%d", bucket, nrOfBuckets))
+	}
+}
+
+// debugAssertHash panics if the all-zero hash is inserted without being allowed.
+func debugAssertHash(hash chainhash.Hash) {
+	if hash == (chainhash.Hash{}) && !debugAllowZeroHash.Load() {
+		panic("txmapdebug: inserting the all-zero hash")
+	}
+}
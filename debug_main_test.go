@@ -0,0 +1,15 @@
+//go:build txmapdebug
+
+package txmap
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain allows the all-zero hash in txmapdebug test runs, since many tests
+// use hashN(0).
+func TestMain(m *testing.M) {
+	DebugAllowZeroHash(true)
+	os.Exit(m.Run())
+}
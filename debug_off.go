@@ -0,0 +1,20 @@
+//go:build !txmapdebug
+
+package txmap
+
+import "github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+// debugEnabled reports whether the txmapdebug assertions are compiled in; see debug.go.
+const debugEnabled = false
+
+// DebugAllowZeroHash is a no-op without the txmapdebug build tag.
+func DebugAllowZeroHash(bool) {}
+
+// debugAssertLength is a no-op without the txmapdebug build tag.
+func debugAssertLength(int64) {}
+
+// debugAssertBucket is a no-op without the txmapdebug build tag.
+func debugAssertBucket(uint16, uint16) {}
+
+// debugAssertHash is a no-op without the txmapdebug build tag.
+func debugAssertHash(chainhash.Hash) {}
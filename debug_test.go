@@ -0,0 +1,52 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDebugAssertions tests that the txmapdebug assertions fire with the
+// build tag and compile to no-ops without it.
+func TestDebugAssertions(t *testing.T) {
+	DebugAllowZeroHash(false)
+	defer DebugAllowZeroHash(true)
+
+	m := NewNativeMapUint64(8)
+	put := func() { _ = m.Put(chainhash.Hash{}, 1) }
+
+	negative := func() { debugAssertLength(-1) }
+
+	if !debugEnabled {
+		assert.NotPanics(t, put)
+		assert.NotPanics(t, negative)
+
+		return
+	}
+
+	assert.PanicsWithValue(t, "txmapdebug: inserting the all-zero hash", put)
+	assert.PanicsWithValue(t, "txmapdebug: length went negative: -1", negative)
+
+	DebugAllowZeroHash(true)
+	require.NotPanics(t, put)
+	assert.True(t, m.Exists(chainhash.Hash{}))
+}
+
+// TestDebugDeleteMissing tests that deleting a missing hash from the maps
+// without a not-found error neither trips the txmapdebug length assertion nor
+// changes the length.
+func TestDebugDeleteMissing(t *testing.T) {
+	for name, m := range map[string]TxHashMap{"SwissMap": NewSwissMap(8), "NativeMap": NewNativeMap(8)} {
+		t.Run(name, func(t *testing.T) {
+			require.NotPanics(t, func() { require.NoError(t, m.Delete(hashN(1))) })
+			assert.Equal(t, 0, m.Length())
+
+			require.NoError(t, m.Put(hashN(1)))
+			require.NoError(t, m.Delete(hashN(1)))
+			require.NoError(t, m.Delete(hashN(1)))
+			assert.Equal(t, 0, m.Length())
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// DeferredDeleter accumulates hashes to delete from a map and applies them in
+// batches, on Flush or once a size threshold is reached. Deleting the spent
+// outputs of a block one by one takes a bucket write lock per hash, and the
+// readers validating the next transactions queue behind every one of them;
+// applied as a batch, maps implementing BatchTxMap take each bucket lock once
+// for all of the batch's hashes in that bucket.
+//
+// Queued hashes remain visible in the map until they are flushed.
+type DeferredDeleter struct {
+	m         TxMap
+	threshold int
+
+	mu      sync.Mutex
+	pending []chainhash.Hash
+}
+
+// NewDeferredDeleter returns a DeferredDeleter for m.
+//
+// Params:
+//   - m: The map to delete from.
+//   - threshold: The number of queued hashes that triggers a flush from
+//     Delete or DeleteMulti. Values below one disable automatic flushes.
+//
+// Returns:
+//   - *DeferredDeleter: The deleter.
+func NewDeferredDeleter(m TxMap, threshold int) *DeferredDeleter {
+	m = nonNilTxMap(m)
+
+	return &DeferredDeleter{
+		m:         m,
+		threshold: threshold,
+		pending:   make([]chainhash.Hash, 0, max(threshold, 0)),
+	}
+}
+
+// Delete queues hash for deletion, flushing the queue if it reached the
+// threshold.
+//
+// Params:
+//   - hash: The hash to delete.
+//
+// Returns:
+//   - error: The error of the flush it triggered, if any; see Flush.
+func (d *DeferredDeleter) Delete(hash chainhash.Hash) error {
+	return d.DeleteMulti([]chainhash.Hash{hash})
+}
+
+// DeleteMulti queues hashes for deletion, flushing the queue if it reached the
+// threshold.
+//
+// Params:
+//   - hashes: The hashes to delete.
+//
+// Returns:
+//   - error: The error of the flush it triggered, if any; see Flush.
+func (d *DeferredDeleter) DeleteMulti(hashes []chainhash.Hash) error {
+	d.mu.Lock()
+
+	d.pending = append(d.pending, hashes...)
+	full := d.threshold > 0 && len(d.pending) >= d.threshold
+
+	d.mu.Unlock()
+
+	if !full {
+		return nil
+	}
+
+	return d.Flush()
+}
+
+// Pending returns the number of queued hashes.
+func (d *DeferredDeleter) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.pending)
+}
+
+// Flush deletes all queued hashes from the map. The queue is taken before the
+// map is touched, so hashes queued concurrently wait for the next flush.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen, in which case the hashes stay
+//     queued; otherwise an error wrapping ErrHashDoesNotExist for the first
+//     queued hash that did not exist. Every queued hash that existed is deleted
+//     either way.
+func (d *DeferredDeleter) Flush() error {
+	d.mu.Lock()
+	hashes := d.pending
+	d.pending = make([]chainhash.Hash, 0, cap(hashes))
+	d.mu.Unlock()
+
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	err := d.apply(hashes)
+	if errors.Is(err, ErrMapFrozen) {
+		d.mu.Lock()
+		d.pending = append(hashes, d.pending...)
+		d.mu.Unlock()
+	}
+
+	return err
+}
+
+// apply deletes hashes, with DeleteMulti if the map supports it.
+func (d *DeferredDeleter) apply(hashes []chainhash.Hash) error {
+	if batch, ok := AsBatch(d.m); ok {
+		return batch.DeleteMulti(hashes)
+	}
+
+	var firstErr error
+
+	for _, hash := range hashes {
+		err := d.m.Delete(hash)
+
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrMapFrozen):
+			return err
+		case firstErr == nil:
+			firstErr = fmt.Errorf("failed to delete deferred hash: %w", err)
+		}
+	}
+
+	return firstErr
+}
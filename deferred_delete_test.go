@@ -0,0 +1,64 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeferredDeleter tests queueing, threshold flushes and explicit flushes
+// for batch and non-batch maps.
+func TestDeferredDeleter(t *testing.T) {
+	maps := map[string]TxMap{
+		"batch":    NewNativeSplitMapUint64(100, 8),
+		"nonbatch": NewChildMap(NewNativeMapUint64(0)),
+	}
+
+	for name, m := range maps {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i)))
+			}
+
+			d := NewDeferredDeleter(m, 5)
+
+			require.NoError(t, d.DeleteMulti([]chainhash.Hash{hashN(0), hashN(1), hashN(2)}))
+			assert.Equal(t, 3, d.Pending())
+			assert.True(t, m.Exists(hashN(0)), "queued hashes stay visible")
+
+			require.NoError(t, d.Delete(hashN(3)))
+			require.NoError(t, d.Delete(hashN(4)))
+			assert.Equal(t, 0, d.Pending())
+			assert.Equal(t, 15, m.Length())
+
+			require.NoError(t, d.Delete(hashN(5)))
+			require.NoError(t, d.Delete(hashN(100)))
+			require.ErrorIs(t, d.Flush(), ErrHashDoesNotExist)
+			assert.False(t, m.Exists(hashN(5)))
+			assert.Equal(t, 14, m.Length())
+
+			require.NoError(t, d.Flush())
+		})
+	}
+}
+
+// TestDeferredDeleterFrozen tests that a flush into a frozen map keeps the
+// hashes queued.
+func TestDeferredDeleterFrozen(t *testing.T) {
+	m := NewNativeSplitMapUint64(10, 4)
+	require.NoError(t, m.Put(hashN(1), 1))
+
+	d := NewDeferredDeleter(m, 0)
+	require.NoError(t, d.Delete(hashN(1)))
+
+	m.Freeze()
+	require.ErrorIs(t, d.Flush(), ErrMapFrozen)
+	assert.Equal(t, 1, d.Pending())
+
+	m.Clear()
+	require.NoError(t, m.Put(hashN(1), 1))
+	require.NoError(t, d.Flush())
+	assert.False(t, m.Exists(hashN(1)))
+}
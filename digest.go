@@ -0,0 +1,125 @@
+package txmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Bucket digests
+//
+// A bucket digest is the sum, modulo 2^64, of a 64-bit mix of every
+// (hash, value) entry in the bucket. Addition makes the digest independent of
+// insertion and iteration order, so two replicas holding the same entries
+// compute the same digests, and it is additive: the digest of a range of
+// buckets is the sum of their digests. Replicas can therefore compare the sums
+// of halves of the bucket range to binary-search the diverged buckets, and
+// then exchange only the keys of those buckets.
+//
+// The digest detects accidental divergence; it is not a cryptographic
+// commitment and must not be relied on against an adversary.
+
+// ErrDigestLengthMismatch is returned by DivergedBuckets when the digests were
+// taken from maps with different numbers of buckets.
+var ErrDigestLengthMismatch = errors.New("bucket digest lengths differ")
+
+// BucketDigests returns the digest of each bucket, indexed by bucket.
+func (g *SplitSwissMap) BucketDigests() []uint64 {
+	return splitBucketDigests(g.m, g.nrOfBuckets)
+}
+
+// BucketDigests returns the digest of each bucket, indexed by bucket.
+func (g *SplitSwissMapUint64) BucketDigests() []uint64 {
+	return splitBucketDigests(g.m, g.nrOfBuckets)
+}
+
+// BucketDigests returns the digest of each bucket, indexed by bucket.
+func (g *NativeSplitMap) BucketDigests() []uint64 {
+	return splitBucketDigests(g.m, g.nrOfBuckets)
+}
+
+// BucketDigests returns the digest of each bucket, indexed by bucket.
+func (g *NativeSplitMapUint64) BucketDigests() []uint64 {
+	return splitBucketDigests(g.m, g.nrOfBuckets)
+}
+
+// DigestRange returns the digest of the buckets digests[from:to], for
+// binary-searching diverged buckets without exchanging every bucket digest.
+//
+// Params:
+//   - digests: The bucket digests returned by BucketDigests.
+//   - from: The first bucket of the range.
+//   - to: The bucket after the last bucket of the range.
+//
+// Returns:
+//   - uint64: The combined digest of the range.
+func DigestRange(digests []uint64, from, to int) uint64 {
+	var sum uint64
+
+	for _, d := range digests[from:to] {
+		sum += d
+	}
+
+	return sum
+}
+
+// DivergedBuckets compares the bucket digests of two replicas.
+//
+// Params:
+//   - local: The bucket digests of this replica.
+//   - remote: The bucket digests of the other replica.
+//
+// Returns:
+//   - []uint16: The buckets whose digests differ, in ascending order.
+//   - error: ErrDigestLengthMismatch if the maps have different numbers of buckets.
+func DivergedBuckets(local, remote []uint64) ([]uint16, error) {
+	if len(local) != len(remote) {
+		return nil, fmt.Errorf("%w: %d and %d", ErrDigestLengthMismatch, len(local), len(remote))
+	}
+
+	var diverged []uint16
+
+	for i := range local {
+		if local[i] != remote[i] {
+			diverged = append(diverged, uint16(i)) //nolint:gosec // split maps have at most 65536 buckets
+		}
+	}
+
+	return diverged, nil
+}
+
+// splitBucketDigests digests buckets 0..nrOfBuckets of a split map.
+func splitBucketDigests[M ReadOnlyTxMap](buckets []M, nrOfBuckets uint16) []uint64 {
+	digests := make([]uint64, int(nrOfBuckets)+1)
+
+	for i := range digests {
+		buckets[uint16(i)].Iter(func(hash chainhash.Hash, value uint64) bool { //nolint:gosec // i <= nrOfBuckets
+			digests[i] += entryDigest(hash, value)
+			return false
+		})
+	}
+
+	return digests
+}
+
+// entryDigest mixes an entry into 64 bits with the splitmix64 finalizer.
+func entryDigest(hash chainhash.Hash, value uint64) uint64 {
+	h := value
+
+	for i := 0; i < chainhash.HashSize; i += 8 {
+		h = mix64(h ^ binary.LittleEndian.Uint64(hash[i:]))
+	}
+
+	return h
+}
+
+// mix64 is the splitmix64 finalizer.
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,51 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBucketDigests tests that replicas with the same entries agree on every
+// bucket digest regardless of insertion order, and that a single differing
+// entry is pinpointed to its bucket.
+func TestBucketDigests(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		a, ok := AsSharded(factory())
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			b, _ := AsSharded(factory())
+
+			for i := 0; i < 2000; i++ {
+				require.NoError(t, a.Put(hashN(i), uint64(i)))
+				require.NoError(t, b.Put(hashN(1999-i), uint64(1999-i)))
+			}
+
+			da, db := a.BucketDigests(), b.BucketDigests()
+			require.Len(t, da, int(a.Buckets())+1)
+			assert.Equal(t, da, db)
+
+			diverged, err := DivergedBuckets(da, db)
+			require.NoError(t, err)
+			assert.Empty(t, diverged)
+
+			require.NoError(t, b.Set(hashN(700), 1))
+
+			db = b.BucketDigests()
+			diverged, err = DivergedBuckets(da, db)
+			require.NoError(t, err)
+			assert.Equal(t, []uint16{Bytes2Uint16Buckets(hashN(700), b.Buckets())}, diverged)
+
+			half := len(da) / 2
+			assert.NotEqual(t, DigestRange(da, 0, len(da)), DigestRange(db, 0, len(db)))
+			assert.Equal(t, DigestRange(da, 0, half)+DigestRange(da, half, len(da)), DigestRange(da, 0, len(da)))
+
+			_, err = DivergedBuckets(da, db[1:])
+			require.ErrorIs(t, err, ErrDigestLengthMismatch)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package txmap
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+)
+
+// distributionSize is the number of keys inserted by the distribution benchmarks.
+const distributionSize = 100000
+
+// keyDistribution generates n keys following some distribution.
+type keyDistribution struct {
+	name string
+	keys func(n int) []chainhash.Hash
+}
+
+// keyDistributions returns the key generators used by the distribution
+// benchmarks: uniformly random keys as a baseline, keys sharing a long prefix
+// (e.g. from a broken or test hasher), and keys crafted so that they all
+// route to the same bucket of the default 1024 buckets.
+func keyDistributions() []keyDistribution {
+	return []keyDistribution{
+		{"uniform", uniformKeys},
+		{"sequential-prefix", sequentialPrefixKeys},
+		{"bucket-colliding", bucketCollidingKeys},
+	}
+}
+
+// uniformKeys returns n uniformly distributed keys.
+func uniformKeys(n int) []chainhash.Hash {
+	keys := make([]chainhash.Hash, n)
+
+	for i := range keys {
+		var seed [8]byte
+
+		binary.LittleEndian.PutUint64(seed[:], uint64(i)) //nolint:gosec // i is non-negative
+		keys[i] = sha256.Sum256(seed[:])
+	}
+
+	return keys
+}
+
+// sequentialPrefixKeys returns n keys that share their first 24 bytes and
+// count up in the last 8.
+func sequentialPrefixKeys(n int) []chainhash.Hash {
+	keys := make([]chainhash.Hash, n)
+
+	for i := range keys {
+		for j := 0; j < 24; j++ {
+			keys[i][j] = 0xab
+		}
+
+		binary.BigEndian.PutUint64(keys[i][24:], uint64(i)) //nolint:gosec // i is non-negative
+	}
+
+	return keys
+}
+
+// bucketCollidingKeys returns n keys whose routing bytes are all congruent
+// modulo 1024, so that they land in a single bucket of a default split map,
+// while the remaining bytes are uniformly random.
+func bucketCollidingKeys(n int) []chainhash.Hash {
+	keys := uniformKeys(n)
+
+	for i := range keys {
+		route := uint16(7 + 1024*(i%64)) //nolint:gosec // below 65536
+		binary.BigEndian.PutUint16(keys[i][:2], route)
+	}
+
+	return keys
+}
+
+// zipfIndexes returns n indexes into a key set of the given size following a
+// zipfian distribution, so that a few hot keys take most of the lookups.
+func zipfIndexes(n, size int) []int {
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, uint64(size-1)) //nolint:gosec // deterministic benchmark input
+	indexes := make([]int, n)
+
+	for i := range indexes {
+		indexes[i] = int(z.Uint64()) //nolint:gosec // below size
+	}
+
+	return indexes
+}
+
+// TestKeyDistributions tests that the generators produce distinct keys with
+// the intended bucket spread.
+func TestKeyDistributions(t *testing.T) {
+	for _, d := range keyDistributions() {
+		keys := d.keys(4096)
+		seen := make(map[chainhash.Hash]struct{}, len(keys))
+		buckets := make(map[uint16]struct{})
+
+		for _, key := range keys {
+			seen[key] = struct{}{}
+			buckets[Bytes2Uint16Buckets(key, 1024)] = struct{}{}
+		}
+
+		assert.Len(t, seen, len(keys), d.name)
+
+		switch d.name {
+		case "uniform":
+			assert.Greater(t, len(buckets), 900)
+		default:
+			assert.Len(t, buckets, 1, d.name)
+		}
+	}
+
+	for _, i := range zipfIndexes(1000, 10) {
+		assert.Less(t, i, 10)
+	}
+}
+
+// BenchmarkKeyDistributionPut measures Put for every TxMap implementation and
+// key distribution. Skewed distributions concentrate the split maps on a
+// single bucket and its lock.
+// Run with: go test -bench=BenchmarkKeyDistributionPut -benchmem
+func BenchmarkKeyDistributionPut(b *testing.B) {
+	for _, d := range keyDistributions() {
+		keys := d.keys(distributionSize)
+
+		impls := txMapImpls()
+
+		for _, name := range slices.Sorted(maps.Keys(impls)) {
+			factory := impls[name]
+
+			b.Run(d.name+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+
+				for i := 0; i < b.N; i++ {
+					if i%distributionSize == 0 {
+						b.StopTimer()
+						m := factory()
+						b.StartTimer()
+
+						for _, key := range keys[:min(distributionSize, b.N-i)] {
+							_ = m.Put(key, 1)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkKeyDistributionGet measures parallel Get with zipfian lookups of
+// keys from every distribution, so that hot keys and hot buckets compound.
+// Run with: go test -bench=BenchmarkKeyDistributionGet -benchmem
+func BenchmarkKeyDistributionGet(b *testing.B) {
+	lookups := zipfIndexes(distributionSize, distributionSize)
+
+	for _, d := range keyDistributions() {
+		keys := d.keys(distributionSize)
+
+		impls := txMapImpls()
+
+		for _, name := range slices.Sorted(maps.Keys(impls)) {
+			m := impls[name]()
+			_ = m.PutMulti(keys, 1)
+
+			b.Run(d.name+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				b.RunParallel(func(pb *testing.PB) {
+					for i := 0; pb.Next(); i++ {
+						_, _ = m.Get(keys[lookups[i%len(lookups)]])
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkBucketSkew reports how evenly Bytes2Uint16Buckets spreads every
+// key distribution over the default 1024 buckets, as the ratio of the fullest
+// bucket to the mean.
+func BenchmarkBucketSkew(b *testing.B) {
+	for _, d := range keyDistributions() {
+		keys := d.keys(distributionSize)
+
+		b.Run(d.name, func(b *testing.B) {
+			var counts [1025]int
+
+			for i := 0; i < b.N; i++ {
+				counts = [1025]int{}
+
+				for _, key := range keys {
+					counts[Bytes2Uint16Buckets(key, 1024)]++
+				}
+			}
+
+			fullest := 0
+			for _, c := range counts {
+				fullest = max(fullest, c)
+			}
+
+			b.ReportMetric(float64(fullest)/(float64(len(keys))/1024), "max/mean")
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// ErrSecondaryHashInUse is returned by DualKeyMap.Put when the secondary hash
+// of an entry is already the primary or secondary hash of another entry.
+var ErrSecondaryHashInUse = errors.New("secondary hash already in use")
+
+// DualKeyMap stores entries that can be looked up by either of two hashes,
+// typically the txid and a secondary id such as the wtxid. Both keys are
+// inserted and deleted together under one lock, so the two indexes can never
+// disagree.
+//
+// An entry's secondary hash may equal its primary hash (e.g. a transaction
+// without witness data); otherwise no hash may identify more than one entry.
+type DualKeyMap struct {
+	mu        sync.RWMutex
+	primary   map[chainhash.Hash]dualKeyEntry
+	secondary map[chainhash.Hash]chainhash.Hash
+}
+
+// dualKeyEntry is the value stored under a primary hash.
+type dualKeyEntry struct {
+	secondary chainhash.Hash
+	value     uint64
+}
+
+// NewDualKeyMap returns an empty DualKeyMap sized for length entries.
+//
+// Params:
+//   - length: The expected number of entries.
+//
+// Returns:
+//   - *DualKeyMap: The empty map.
+func NewDualKeyMap(length uint32) *DualKeyMap {
+	return &DualKeyMap{
+		primary:   make(map[chainhash.Hash]dualKeyEntry, length),
+		secondary: make(map[chainhash.Hash]chainhash.Hash, length),
+	}
+}
+
+// Put adds an entry under both hashes.
+//
+// Params:
+//   - primary: The primary hash, e.g. the txid.
+//   - secondary: The secondary hash, e.g. the wtxid.
+//   - value: The value of the entry.
+//
+// Returns:
+//   - error: An error wrapping ErrHashAlreadyExists if primary is already the
+//     primary hash of an entry, or ErrSecondaryHashInUse if either hash
+//     already identifies another entry. Nothing is inserted on error.
+func (d *DualKeyMap) Put(primary, secondary chainhash.Hash, value uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.primary[primary]; ok {
+		return fmt.Errorf("%w: %s", ErrHashAlreadyExists, primary)
+	}
+
+	if _, ok := d.secondary[primary]; ok {
+		return fmt.Errorf("%w: %s", ErrSecondaryHashInUse, primary)
+	}
+
+	if secondary != primary {
+		_, isPrimary := d.primary[secondary]
+		_, isSecondary := d.secondary[secondary]
+
+		if isPrimary || isSecondary {
+			return fmt.Errorf("%w: %s", ErrSecondaryHashInUse, secondary)
+		}
+
+		d.secondary[secondary] = primary
+	}
+
+	d.primary[primary] = dualKeyEntry{secondary: secondary, value: value}
+
+	return nil
+}
+
+// Get retrieves the value of the entry identified by hash, which may be
+// either its primary or its secondary hash.
+func (d *DualKeyMap) Get(hash chainhash.Hash) (uint64, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	_, entry, ok := d.lookupUnlocked(hash)
+
+	return entry.value, ok
+}
+
+// Exists checks if hash is the primary or secondary hash of an entry.
+func (d *DualKeyMap) Exists(hash chainhash.Hash) bool {
+	_, ok := d.Get(hash)
+	return ok
+}
+
+// Keys returns both hashes of the entry identified by hash.
+//
+// Returns:
+//   - chainhash.Hash: The primary hash.
+//   - chainhash.Hash: The secondary hash.
+//   - bool: False if no entry is identified by hash.
+func (d *DualKeyMap) Keys(hash chainhash.Hash) (chainhash.Hash, chainhash.Hash, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	primary, entry, ok := d.lookupUnlocked(hash)
+
+	return primary, entry.secondary, ok
+}
+
+// Set updates the value of the entry identified by hash.
+//
+// Returns:
+//   - error: An error wrapping ErrHashDoesNotExist if no entry is identified by hash.
+func (d *DualKeyMap) Set(hash chainhash.Hash, value uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	primary, entry, ok := d.lookupUnlocked(hash)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrHashDoesNotExist, hash)
+	}
+
+	entry.value = value
+	d.primary[primary] = entry
+
+	return nil
+}
+
+// Delete removes the entry identified by hash under both of its hashes.
+//
+// Returns:
+//   - error: An error wrapping ErrHashDoesNotExist if no entry is identified by hash.
+func (d *DualKeyMap) Delete(hash chainhash.Hash) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	primary, entry, ok := d.lookupUnlocked(hash)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrHashDoesNotExist, hash)
+	}
+
+	delete(d.primary, primary)
+	delete(d.secondary, entry.secondary)
+
+	return nil
+}
+
+// Length returns the number of entries.
+func (d *DualKeyMap) Length() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return len(d.primary)
+}
+
+// Iter iterates over the entries. Stops iterating if f returns true.
+func (d *DualKeyMap) Iter(f func(primary, secondary chainhash.Hash, value uint64) bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for primary, entry := range d.primary {
+		if f(primary, entry.secondary, entry.value) {
+			return
+		}
+	}
+}
+
+// Clear empties the map.
+func (d *DualKeyMap) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	clear(d.primary)
+	clear(d.secondary)
+}
+
+// lookupUnlocked finds the entry identified by hash. The caller must hold the lock.
+func (d *DualKeyMap) lookupUnlocked(hash chainhash.Hash) (chainhash.Hash, dualKeyEntry, bool) {
+	if entry, ok := d.primary[hash]; ok {
+		return hash, entry, true
+	}
+
+	if primary, ok := d.secondary[hash]; ok {
+		return primary, d.primary[primary], true
+	}
+
+	return chainhash.Hash{}, dualKeyEntry{}, false
+}
@@ -0,0 +1,57 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDualKeyMap tests lookups, updates and deletes by either hash, and the
+// rejection of hashes that would identify two entries.
+func TestDualKeyMap(t *testing.T) {
+	m := NewDualKeyMap(8)
+
+	txid, wtxid := hashN(1), hashN(101)
+
+	require.NoError(t, m.Put(txid, wtxid, 5))
+	require.NoError(t, m.Put(hashN(2), hashN(2), 6))
+
+	for _, hash := range []chainhash.Hash{txid, wtxid} {
+		v, ok := m.Get(hash)
+		require.True(t, ok)
+		assert.Equal(t, uint64(5), v)
+
+		primary, secondary, ok := m.Keys(hash)
+		require.True(t, ok)
+		assert.Equal(t, txid, primary)
+		assert.Equal(t, wtxid, secondary)
+	}
+
+	require.ErrorIs(t, m.Put(txid, hashN(3), 1), ErrHashAlreadyExists)
+	require.ErrorIs(t, m.Put(hashN(3), wtxid, 1), ErrSecondaryHashInUse)
+	require.ErrorIs(t, m.Put(hashN(3), txid, 1), ErrSecondaryHashInUse)
+	require.ErrorIs(t, m.Put(wtxid, hashN(3), 1), ErrSecondaryHashInUse)
+	assert.False(t, m.Exists(hashN(3)))
+	assert.Equal(t, 2, m.Length())
+
+	require.NoError(t, m.Set(wtxid, 9))
+
+	v, _ := m.Get(txid)
+	assert.Equal(t, uint64(9), v)
+
+	require.NoError(t, m.Delete(wtxid))
+	assert.False(t, m.Exists(txid))
+	assert.False(t, m.Exists(wtxid))
+	require.ErrorIs(t, m.Delete(txid), ErrHashDoesNotExist)
+
+	// both hashes are free again
+	require.NoError(t, m.Put(wtxid, txid, 1))
+
+	require.NoError(t, m.Delete(hashN(2)))
+	assert.Equal(t, 1, m.Length())
+
+	m.Clear()
+	assert.Equal(t, 0, m.Length())
+}
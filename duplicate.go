@@ -0,0 +1,200 @@
+package txmap
+
+import (
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// DuplicatePolicy selects what Put and PutMulti do with a key that is already
+// in the map. It is configured per map with SetDuplicatePolicy and applies the
+// same way on every backend, including the split variants, whose setters fan
+// out to every bucket.
+//
+// The default is DuplicateError for the maps with values (TxMap and Uint64
+// implementations) and DuplicateIgnore for the hash-only TxHashMap
+// implementations, matching their documented behavior. In every mode the
+// length counts distinct keys, so a duplicate is never counted twice.
+type DuplicatePolicy uint8
+
+const (
+	// DuplicateError rejects the duplicate with an error wrapping
+	// ErrHashAlreadyExists. PutMulti stops at the first duplicate, keeping the
+	// keys put before it.
+	DuplicateError DuplicatePolicy = iota
+
+	// DuplicateIgnore keeps the existing value and reports no error.
+	DuplicateIgnore
+
+	// DuplicateOverwrite replaces the existing value with the new one.
+	DuplicateOverwrite
+
+	// DuplicateCallback asks the DuplicateFunc passed to SetDuplicatePolicy.
+	DuplicateCallback
+)
+
+// String returns the lower-case name of the policy.
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case DuplicateError:
+		return "error"
+	case DuplicateIgnore:
+		return "ignore"
+	case DuplicateOverwrite:
+		return "overwrite"
+	case DuplicateCallback:
+		return "callback"
+	default:
+		return "unknown"
+	}
+}
+
+// DuplicateFunc decides the outcome of a Put of key with value while key
+// already holds existing, under DuplicateCallback. It returns the value to
+// keep (existing to leave the entry unchanged), or an error to reject the put,
+// which Put and PutMulti return as is. Hash-only maps pass zero for both
+// values and ignore the returned value.
+//
+// It is called with the map (or bucket) write lock held and must not call
+// back into the map.
+type DuplicateFunc[K comparable] func(key K, existing, value uint64) (uint64, error)
+
+// duplicateHandler applies a DuplicatePolicy. It is held by every leaf map.
+type duplicateHandler[K comparable] struct {
+	policy      DuplicatePolicy
+	onDuplicate DuplicateFunc[K]
+}
+
+// set configures the handler. DuplicateCallback without a function falls back
+// to DuplicateError.
+func (d *duplicateHandler[K]) set(policy DuplicatePolicy, onDuplicate DuplicateFunc[K]) {
+	if policy == DuplicateCallback && onDuplicate == nil {
+		policy = DuplicateError
+	}
+
+	d.policy = policy
+	d.onDuplicate = onDuplicate
+}
+
+// resolve decides the outcome of putting value for key, which already holds
+// existing. It returns the value to store and whether it differs from existing
+// and must be written, or the error to return to the caller.
+func (d *duplicateHandler[K]) resolve(key K, existing, value uint64) (uint64, bool, error) {
+	switch d.policy {
+	case DuplicateIgnore:
+		return existing, false, nil
+	case DuplicateOverwrite:
+		return value, value != existing, nil
+	case DuplicateCallback:
+		v, err := d.onDuplicate(key, existing, value)
+		if err != nil {
+			return existing, false, err
+		}
+
+		return v, v != existing, nil
+	default:
+		return existing, false, fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, key)
+	}
+}
+
+// --- dolthub/swiss-backed leaf maps -----------------------------------------
+
+// SetDuplicatePolicy sets what Put and PutMulti do with hashes already in the
+// map; see DuplicatePolicy. onDuplicate is only used with DuplicateCallback.
+// Must be called before the map is shared between goroutines.
+func (s *SwissMap) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.duplicates.set(policy, onDuplicate)
+}
+
+// SetDuplicatePolicy sets what Put and PutMulti do with hashes already in the
+// map; see DuplicatePolicy. onDuplicate is only used with DuplicateCallback.
+// Must be called before the map is shared between goroutines.
+func (s *SwissMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.duplicates.set(policy, onDuplicate)
+}
+
+// SetDuplicatePolicy sets what Put does with keys already in the map; see
+// DuplicatePolicy. onDuplicate is only used with DuplicateCallback.
+// Must be called before the map is shared between goroutines.
+func (s *SwissLockFreeMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[uint64]) {
+	s.duplicates.set(policy, onDuplicate)
+}
+
+// --- native-map-backed leaf maps --------------------------------------------
+
+// SetDuplicatePolicy sets what Put and PutMulti do with hashes already in the
+// map; see DuplicatePolicy. onDuplicate is only used with DuplicateCallback.
+// Must be called before the map is shared between goroutines.
+func (s *NativeMap) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.duplicates.set(policy, onDuplicate)
+}
+
+// SetDuplicatePolicy sets what Put and PutMulti do with hashes already in the
+// map; see DuplicatePolicy. onDuplicate is only used with DuplicateCallback.
+// Must be called before the map is shared between goroutines.
+func (s *NativeMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.duplicates.set(policy, onDuplicate)
+}
+
+// SetDuplicatePolicy sets what Put does with keys already in the map; see
+// DuplicatePolicy. onDuplicate is only used with DuplicateCallback.
+// Must be called before the map is shared between goroutines.
+func (s *NativeLockFreeMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[uint64]) {
+	s.duplicates.set(policy, onDuplicate)
+}
+
+// --- split maps: SetDuplicatePolicy fans out to every bucket ----------------
+
+// SetDuplicatePolicy sets the duplicate policy of every bucket.
+func (g *SplitSwissMap) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].SetDuplicatePolicy(policy, onDuplicate)
+	}
+}
+
+// SetDuplicatePolicy sets the duplicate policy of every bucket.
+func (g *SplitSwissMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].SetDuplicatePolicy(policy, onDuplicate)
+	}
+}
+
+// SetDuplicatePolicy sets the duplicate policy of every bucket.
+func (g *SplitSwissLockFreeMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[uint64]) {
+	for i := uint64(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].SetDuplicatePolicy(policy, onDuplicate)
+	}
+}
+
+// SetDuplicatePolicy sets the duplicate policy of every bucket.
+func (g *NativeSplitMap) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].SetDuplicatePolicy(policy, onDuplicate)
+	}
+}
+
+// SetDuplicatePolicy sets the duplicate policy of every bucket.
+func (g *NativeSplitMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].SetDuplicatePolicy(policy, onDuplicate)
+	}
+}
+
+// SetDuplicatePolicy sets the duplicate policy of every bucket.
+func (g *NativeSplitLockFreeMapUint64) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[uint64]) {
+	for i := uint64(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].SetDuplicatePolicy(policy, onDuplicate)
+	}
+}
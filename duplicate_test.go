@@ -0,0 +1,128 @@
+package txmap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDuplicateRejected = errors.New("duplicate rejected")
+
+// duplicatePolicySetter is implemented by every TxMap and TxHashMap backend.
+type duplicatePolicySetter interface {
+	SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash])
+}
+
+// TestTxMapDuplicatePolicy tests every DuplicatePolicy on every TxMap backend.
+func TestTxMapDuplicatePolicy(t *testing.T) {
+	sum := func(_ chainhash.Hash, existing, value uint64) (uint64, error) {
+		if value == 0 {
+			return 0, errDuplicateRejected
+		}
+
+		return existing + value, nil
+	}
+
+	tests := map[DuplicatePolicy]struct {
+		err   error
+		value uint64
+	}{
+		DuplicateError:     {err: ErrHashAlreadyExists, value: 1},
+		DuplicateIgnore:    {value: 1},
+		DuplicateOverwrite: {value: 5},
+		DuplicateCallback:  {value: 6},
+	}
+
+	for name, factory := range txMapImpls() {
+		for policy, tt := range tests {
+			t.Run(name+"/"+policy.String(), func(t *testing.T) {
+				m := factory()
+				m.(duplicatePolicySetter).SetDuplicatePolicy(policy, sum)
+
+				require.NoError(t, m.Put(hashN(1), 1))
+
+				err := m.Put(hashN(1), 5)
+				if tt.err != nil {
+					require.ErrorIs(t, err, tt.err)
+				} else {
+					require.NoError(t, err)
+				}
+
+				v, ok := m.Get(hashN(1))
+				require.True(t, ok)
+				assert.Equal(t, tt.value, v)
+				assert.Equal(t, 1, m.Length())
+
+				// PutMulti applies the same policy per hash
+				err = m.PutMulti([]chainhash.Hash{hashN(2), hashN(1), hashN(3)}, 5)
+				if tt.err != nil {
+					require.ErrorIs(t, err, tt.err)
+					assert.Equal(t, 2, m.Length())
+				} else {
+					require.NoError(t, err)
+					assert.Equal(t, 3, m.Length())
+				}
+			})
+		}
+
+		t.Run(name+"/callback error", func(t *testing.T) {
+			m := factory()
+			m.(duplicatePolicySetter).SetDuplicatePolicy(DuplicateCallback, sum)
+
+			require.NoError(t, m.Put(hashN(1), 1))
+			require.ErrorIs(t, m.Put(hashN(1), 0), errDuplicateRejected)
+
+			v, _ := m.Get(hashN(1))
+			assert.Equal(t, uint64(1), v)
+		})
+	}
+}
+
+// TestTxHashMapDuplicatePolicy tests that the hash-only maps ignore duplicates
+// by default without counting them twice, and honor DuplicateError.
+func TestTxHashMapDuplicatePolicy(t *testing.T) {
+	for name, factory := range txHashMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			require.NoError(t, m.Put(hashN(1)))
+			require.NoError(t, m.Put(hashN(1)))
+			require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(1), hashN(2)}))
+			assert.Equal(t, 2, m.Length())
+
+			m.(duplicatePolicySetter).SetDuplicatePolicy(DuplicateError, nil)
+			require.ErrorIs(t, m.Put(hashN(1)), ErrHashAlreadyExists)
+			assert.Equal(t, 2, m.Length())
+		})
+	}
+}
+
+// TestUint64DuplicatePolicy tests the duplicate policy of the lock-free maps.
+func TestUint64DuplicatePolicy(t *testing.T) {
+	for name, factory := range uint64Impls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+			setter, ok := m.(interface {
+				SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[uint64])
+			})
+			require.True(t, ok)
+
+			require.NoError(t, m.Put(1, 1))
+			require.ErrorIs(t, m.Put(1, 2), ErrHashAlreadyExists)
+
+			setter.SetDuplicatePolicy(DuplicateOverwrite, nil)
+			require.NoError(t, m.Put(1, 2))
+
+			v, _ := m.Get(1)
+			assert.Equal(t, uint64(2), v)
+			assert.Equal(t, 1, m.Length())
+
+			// a callback policy without a callback falls back to DuplicateError
+			setter.SetDuplicatePolicy(DuplicateCallback, nil)
+			require.ErrorIs(t, m.Put(1, 3), ErrHashAlreadyExists)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package txmap
+
+import (
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// EntriesTxMap is implemented by maps that can copy out their hashes together
+// with their values, which costs one pass under each lock instead of Keys
+// followed by a Get per hash.
+type EntriesTxMap interface {
+	TxMap
+
+	// Entries returns all pairs of the map, in no particular order.
+	Entries() []KV
+
+	// EntriesAppend appends all pairs of the map to dst and returns the
+	// extended slice, so that a buffer can be reused across calls.
+	EntriesAppend(dst []KV) []KV
+}
+
+// Compile-time checks of the maps that implement EntriesTxMap.
+var (
+	_ EntriesTxMap = (*SwissMapUint64)(nil)
+	_ EntriesTxMap = (*NativeMapUint64)(nil)
+	_ EntriesTxMap = (*SplitSwissMap)(nil)
+	_ EntriesTxMap = (*SplitSwissMapUint64)(nil)
+	_ EntriesTxMap = (*NativeSplitMap)(nil)
+	_ EntriesTxMap = (*NativeSplitMapUint64)(nil)
+)
+
+// kvBufferPool holds the buffers handed out by AcquireKVs.
+var kvBufferPool = sync.Pool{ //nolint:gochecknoglobals // shared buffer pool
+	New: func() any { return new([]KV) },
+}
+
+// AcquireKVs returns an empty KV buffer from a shared pool, for use with
+// EntriesAppend. Return it with ReleaseKVs once its contents are no longer
+// used.
+//
+// Returns:
+//   - []KV: An empty slice, with the capacity of a previously released buffer.
+func AcquireKVs() []KV {
+	buf, _ := kvBufferPool.Get().(*[]KV)
+	return (*buf)[:0]
+}
+
+// ReleaseKVs returns a buffer obtained from AcquireKVs to the pool. The buffer
+// must not be used afterwards.
+//
+// Params:
+//   - kvs: The buffer to release.
+func ReleaseKVs(kvs []KV) {
+	kvs = kvs[:0]
+	kvBufferPool.Put(&kvs)
+}
+
+// Entries returns all pairs of the map under a single read lock.
+func (s *SwissMapUint64) Entries() []KV {
+	return s.EntriesAppend(make([]KV, 0, s.length.Load()))
+}
+
+// EntriesAppend appends all pairs of the map to dst under a single read lock.
+func (s *SwissMapUint64) EntriesAppend(dst []KV) []KV {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	s.m.Iter(func(hash chainhash.Hash, value uint64) bool {
+		dst = append(dst, KV{Hash: hash, Value: value})
+		return false
+	})
+
+	return dst
+}
+
+// Entries returns all pairs of the map under a single read lock.
+func (s *NativeMapUint64) Entries() []KV {
+	return s.EntriesAppend(make([]KV, 0, s.length.Load()))
+}
+
+// EntriesAppend appends all pairs of the map to dst under a single read lock.
+func (s *NativeMapUint64) EntriesAppend(dst []KV) []KV {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	for hash, value := range s.m {
+		dst = append(dst, KV{Hash: hash, Value: value})
+	}
+
+	return dst
+}
+
+// Entries returns all pairs of the map, one bucket at a time.
+func (g *SplitSwissMap) Entries() []KV {
+	return g.EntriesAppend(make([]KV, 0, g.Length()))
+}
+
+// EntriesAppend appends all pairs of the map to dst, one bucket at a time.
+// Like Keys, the result is not an atomic snapshot across buckets.
+func (g *SplitSwissMap) EntriesAppend(dst []KV) []KV {
+	return splitEntriesAppend(g.m, g.nrOfBuckets, dst)
+}
+
+// Entries returns all pairs of the map, one bucket at a time.
+func (g *SplitSwissMapUint64) Entries() []KV {
+	return g.EntriesAppend(make([]KV, 0, g.Length()))
+}
+
+// EntriesAppend appends all pairs of the map to dst, one bucket at a time.
+// Like Keys, the result is not an atomic snapshot across buckets.
+func (g *SplitSwissMapUint64) EntriesAppend(dst []KV) []KV {
+	return splitEntriesAppend(g.m, g.nrOfBuckets, dst)
+}
+
+// Entries returns all pairs of the map, one bucket at a time.
+func (g *NativeSplitMap) Entries() []KV {
+	return g.EntriesAppend(make([]KV, 0, g.Length()))
+}
+
+// EntriesAppend appends all pairs of the map to dst, one bucket at a time.
+// Like Keys, the result is not an atomic snapshot across buckets.
+func (g *NativeSplitMap) EntriesAppend(dst []KV) []KV {
+	return splitEntriesAppend(g.m, g.nrOfBuckets, dst)
+}
+
+// Entries returns all pairs of the map, one bucket at a time.
+func (g *NativeSplitMapUint64) Entries() []KV {
+	return g.EntriesAppend(make([]KV, 0, g.Length()))
+}
+
+// EntriesAppend appends all pairs of the map to dst, one bucket at a time.
+// Like Keys, the result is not an atomic snapshot across buckets.
+func (g *NativeSplitMapUint64) EntriesAppend(dst []KV) []KV {
+	return splitEntriesAppend(g.m, g.nrOfBuckets, dst)
+}
+
+// splitEntriesAppend implements EntriesAppend for a split map.
+func splitEntriesAppend[M interface{ EntriesAppend(dst []KV) []KV }](buckets []M, nrOfBuckets uint16, dst []KV) []KV {
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		dst = buckets[i].EntriesAppend(dst)
+	}
+
+	return dst
+}
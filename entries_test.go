@@ -0,0 +1,39 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEntries tests Entries and EntriesAppend of every TxMap implementation.
+func TestEntries(t *testing.T) {
+	const n = 500
+
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+			want := make([]KV, n)
+
+			for i := range want {
+				want[i] = KV{Hash: hashN(i), Value: uint64(i) + 1} //nolint:gosec // test sizes are small
+				require.NoError(t, m.Put(want[i].Hash, want[i].Value))
+			}
+
+			em, ok := m.(EntriesTxMap)
+			require.True(t, ok)
+			assert.ElementsMatch(t, want, em.Entries())
+
+			buf := AcquireKVs()
+			buf = append(buf, KV{Value: 42})
+			buf = em.EntriesAppend(buf)
+			assert.Equal(t, KV{Value: 42}, buf[0])
+			assert.ElementsMatch(t, want, buf[1:])
+			ReleaseKVs(buf)
+
+			m.Freeze()
+			assert.Len(t, em.Entries(), n)
+		})
+	}
+}
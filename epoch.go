@@ -0,0 +1,71 @@
+package txmap
+
+// Epochs
+//
+// Consumers that keep state derived from a map, such as a bloom filter of its
+// hashes or a cache of its values, can update that state along with their own
+// writes, but not when the map is replaced as a whole. The epoch of a map
+// counts those replacements: Clear, a completed SwapBackend and a
+// RestoreSnapshot into the map each increment it, while Put, Set and Delete
+// never do. A consumer records the epoch together with its derived state and
+// rebuilds the state once Epoch returns a different value.
+
+// EpochTxMap is implemented by maps that count their wholesale replacements.
+type EpochTxMap interface {
+	TxMap
+
+	// Epoch returns the number of times the map was cleared, swapped or
+	// restored since it was created.
+	Epoch() uint64
+}
+
+// epochAdvancer is implemented by the maps whose epoch RestoreSnapshot
+// advances.
+type epochAdvancer interface {
+	advanceEpoch()
+}
+
+// Compile-time checks of the maps that implement EpochTxMap.
+var (
+	_ EpochTxMap = (*SwissMapUint64)(nil)
+	_ EpochTxMap = (*NativeMapUint64)(nil)
+	_ EpochTxMap = (*SplitSwissMap)(nil)
+	_ EpochTxMap = (*SplitSwissMapUint64)(nil)
+	_ EpochTxMap = (*NativeSplitMap)(nil)
+	_ EpochTxMap = (*NativeSplitMapUint64)(nil)
+	_ EpochTxMap = (*SwappableTxMap)(nil)
+)
+
+// Epoch returns the number of times the map was cleared or restored.
+func (s *SwissMapUint64) Epoch() uint64 { return s.epoch.Load() }
+
+// Epoch returns the number of times the map was cleared or restored.
+func (s *NativeMapUint64) Epoch() uint64 { return s.epoch.Load() }
+
+// Epoch returns the number of times the map was cleared or restored. Clearing
+// a single bucket directly does not change it.
+func (g *SplitSwissMap) Epoch() uint64 { return g.epoch.Load() }
+
+// Epoch returns the number of times the map was cleared or restored. Clearing
+// a single bucket directly does not change it.
+func (g *SplitSwissMapUint64) Epoch() uint64 { return g.epoch.Load() }
+
+// Epoch returns the number of times the map was cleared or restored. Clearing
+// a single bucket directly does not change it.
+func (g *NativeSplitMap) Epoch() uint64 { return g.epoch.Load() }
+
+// Epoch returns the number of times the map was cleared or restored. Clearing
+// a single bucket directly does not change it.
+func (g *NativeSplitMapUint64) Epoch() uint64 { return g.epoch.Load() }
+
+// Epoch returns the number of times the map was cleared, had its backend
+// swapped or was restored. It is independent of the epochs of the backends.
+func (s *SwappableTxMap) Epoch() uint64 { return s.epoch.Load() }
+
+func (s *SwissMapUint64) advanceEpoch()       { s.epoch.Add(1) }
+func (s *NativeMapUint64) advanceEpoch()      { s.epoch.Add(1) }
+func (g *SplitSwissMap) advanceEpoch()        { g.epoch.Add(1) }
+func (g *SplitSwissMapUint64) advanceEpoch()  { g.epoch.Add(1) }
+func (g *NativeSplitMap) advanceEpoch()       { g.epoch.Add(1) }
+func (g *NativeSplitMapUint64) advanceEpoch() { g.epoch.Add(1) }
+func (s *SwappableTxMap) advanceEpoch()       { s.epoch.Add(1) }
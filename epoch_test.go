@@ -0,0 +1,60 @@
+package txmap
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEpoch tests that Clear advances the epoch of every map and that writes
+// do not.
+func TestEpoch(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		em, ok := factory().(EpochTxMap)
+		require.True(t, ok, name)
+
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, uint64(0), em.Epoch())
+
+			require.NoError(t, em.Put(hashN(1), 1))
+			require.NoError(t, em.Set(hashN(1), 2))
+			require.NoError(t, em.Delete(hashN(1)))
+			assert.Equal(t, uint64(0), em.Epoch())
+
+			em.Clear()
+			em.Clear()
+			assert.Equal(t, uint64(2), em.Epoch())
+		})
+	}
+}
+
+// TestEpochSwapAndRestore tests that SwapBackend and RestoreSnapshot advance
+// the epoch.
+func TestEpochSwapAndRestore(t *testing.T) {
+	ctx := context.Background()
+
+	s := NewSwappableTxMap(NewNativeMapUint64(0))
+	require.NoError(t, s.Put(hashN(1), 1))
+
+	_, err := s.SwapBackend(ctx, NewSplitSwissMapUint64(0, 4), 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), s.Epoch())
+
+	store, err := NewFileSnapshotStore(filepath.Join(t.TempDir(), "snapshots"))
+	require.NoError(t, err)
+	require.NoError(t, SaveSnapshot(ctx, store, "s", s))
+
+	dst := NewNativeSplitMapUint64(0, 4)
+
+	_, err = RestoreSnapshot(ctx, store, "s", dst, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), dst.Epoch())
+
+	// nothing inserted, nothing replaced
+	_, err = RestoreSnapshot(ctx, store, "s", dst, ImportOptions{SkipExisting: true})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), dst.Epoch())
+}
@@ -0,0 +1,48 @@
+package txmap
+
+// EvictReason identifies why an entry left a map other than through an
+// explicit Delete by the caller. It is passed to an EvictCallback so that
+// downstream structures derived from the map (bloom filters, reverse indices,
+// metrics) can stay consistent with it.
+type EvictReason uint8
+
+const (
+	// EvictReasonExpired is used when an entry outlived its time-to-live.
+	EvictReasonExpired EvictReason = iota + 1
+
+	// EvictReasonLRU is used when an entry was the least recently used one in
+	// a size-bounded map that needed room for a new entry.
+	EvictReasonLRU
+
+	// EvictReasonCapacity is used when a map with an item limit dropped an
+	// entry to make room for a new one, without regard to recency.
+	EvictReasonCapacity
+
+	// EvictReasonCleared is used for every entry removed by Clear.
+	EvictReasonCleared
+)
+
+// String returns a short, stable name for the reason, suitable for log fields
+// and metric labels.
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonLRU:
+		return "lru"
+	case EvictReasonCapacity:
+		return "capacity"
+	case EvictReasonCleared:
+		return "cleared"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictCallback is invoked once for every entry a map removes on its own
+// initiative, with the key and value that were removed and the reason.
+//
+// Callbacks run synchronously while the map holds its write lock, so that an
+// observer never sees the map and its derived structures disagree. They must
+// therefore be fast and must not call back into the map that invoked them.
+type EvictCallback[K comparable, V any] func(key K, value V, reason EvictReason)
@@ -0,0 +1,93 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// evictRecord captures a single EvictCallback invocation.
+type evictRecord struct {
+	key    string
+	value  int
+	reason EvictReason
+}
+
+// TestEvictReasonString tests the String method of EvictReason.
+func TestEvictReasonString(t *testing.T) {
+	assert.Equal(t, "expired", EvictReasonExpired.String())
+	assert.Equal(t, "lru", EvictReasonLRU.String())
+	assert.Equal(t, "capacity", EvictReasonCapacity.String())
+	assert.Equal(t, "cleared", EvictReasonCleared.String())
+	assert.Equal(t, "unknown", EvictReason(0).String())
+}
+
+// TestSyncedMapOnEvict tests that SyncedMap reports capacity evictions and
+// Clear through the registered callback, but not explicit deletes.
+func TestSyncedMapOnEvict(t *testing.T) {
+	t.Run("capacity", func(t *testing.T) {
+		var evicted []evictRecord
+
+		m := NewSyncedMap[string, int](1)
+		m.OnEvict(func(key string, value int, reason EvictReason) {
+			evicted = append(evicted, evictRecord{key, value, reason})
+		})
+
+		m.Set("key1", 1)
+		m.Set("key1", 10) // overwriting an existing key does not evict
+		require.Empty(t, evicted)
+
+		m.Set("key2", 2)
+		require.Equal(t, []evictRecord{{"key1", 10, EvictReasonCapacity}}, evicted)
+		assert.True(t, m.Exists("key2"))
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		evicted := map[string]EvictReason{}
+
+		m := NewSyncedMap[string, int]()
+		m.OnEvict(func(key string, _ int, reason EvictReason) {
+			evicted[key] = reason
+		})
+
+		m.Set("key1", 1)
+		m.Set("key2", 2)
+		m.Delete("key2")
+		m.Clear()
+
+		assert.Equal(t, map[string]EvictReason{"key1": EvictReasonCleared}, evicted)
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		calls := 0
+
+		m := NewSyncedMap[string, int]()
+		m.OnEvict(func(string, int, EvictReason) { calls++ })
+		m.OnEvict(nil)
+
+		m.Set("key1", 1)
+		m.Clear()
+
+		assert.Equal(t, 0, calls)
+	})
+}
+
+// TestSyncedSwissMapOnEvict tests that SyncedSwissMap reports entries removed
+// by Clear through the registered callback.
+func TestSyncedSwissMapOnEvict(t *testing.T) {
+	evicted := map[string]int{}
+
+	m := NewSyncedSwissMap[string, int](10)
+	m.OnEvict(func(key string, value int, reason EvictReason) {
+		assert.Equal(t, EvictReasonCleared, reason)
+		evicted[key] = value
+	})
+
+	m.Set("key1", 1)
+	m.Set("key2", 2)
+	m.Clear()
+
+	assert.Equal(t, map[string]int{"key1": 1, "key2": 2}, evicted)
+	assert.Equal(t, 0, m.Length())
+}
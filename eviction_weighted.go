@@ -0,0 +1,105 @@
+package txmap
+
+import "math/rand"
+
+// Weighted eviction
+//
+// A SyncedMap with an item limit drops an entry whenever a new key would
+// exceed the limit. By default that entry is an arbitrary one. With a weight
+// function the victim is drawn at random with probability proportional to
+// the weight of its value instead, so that a mempool-style policy, such as
+// preferring to evict low fee rate transactions by weighing them with the
+// inverse fee rate, needs no priority structure next to the map.
+//
+// Weighing every entry on every eviction would make inserts at the limit
+// linear in the size of the map, so the draw is made among a sample of
+// entries: the first ones of a map iteration, which starts at a random
+// position. Every entry is therefore a candidate, and among the candidates
+// the draw is exact. A larger sample tracks the weights more closely at a
+// proportionally higher cost per eviction.
+
+// DefaultEvictionSamples is the sample size of weighted eviction used when
+// SetEvictionWeight is passed zero.
+const DefaultEvictionSamples = 16
+
+// SetEvictionWeight makes the map choose the entries it drops at its item
+// limit at random, with probability proportional to weight(value), among a
+// sample of entries. See the notes at the top of eviction_weighted.go.
+//
+// Parameters:
+//   - weight: The weight of a value. Values of weight zero or less, or NaN,
+//     are only evicted if every sampled value has such a weight, and then
+//     uniformly. Passing nil restores the default arbitrary choice.
+//   - samples: The number of entries weighed per eviction;
+//     DefaultEvictionSamples when zero, and the whole map when negative.
+func (m *SyncedMap[K, V]) SetEvictionWeight(weight func(value V) float64, samples int) {
+	if samples == 0 {
+		samples = DefaultEvictionSamples
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evictWeight = weight
+	m.evictSamples = samples
+}
+
+// evictUnlocked drops one entry to make room for a new one and reports it
+// with EvictReasonCapacity. The caller must hold the write lock.
+func (m *SyncedMap[K, V]) evictUnlocked() {
+	key, value, ok := m.evictionVictim()
+	if !ok {
+		return
+	}
+
+	delete(m.m, key)
+
+	if m.onEvict != nil {
+		m.onEvict(key, value, EvictReasonCapacity)
+	}
+}
+
+// evictionVictim chooses the entry to evict: the first one of a map
+// iteration, or a weighted draw among a sample of them if a weight function
+// is set.
+func (m *SyncedMap[K, V]) evictionVictim() (K, V, bool) {
+	var (
+		victimKey   K
+		victimValue V
+		found       bool
+		total       float64
+		seen        int
+	)
+
+	for k, v := range m.m {
+		if m.evictWeight == nil {
+			return k, v, true
+		}
+
+		seen++
+
+		// a reservoir draw: the i-th candidate replaces the current victim
+		// with probability w_i / (w_1 + ... + w_i), or 1 / i while every
+		// weight so far is zero
+		w := m.evictWeight(v)
+
+		switch {
+		case w > 0:
+			total += w
+
+			if rand.Float64()*total < w { //nolint:gosec // eviction is not security sensitive
+				victimKey, victimValue, found = k, v, true
+			}
+		case total == 0:
+			if rand.Intn(seen) == 0 { //nolint:gosec // eviction is not security sensitive
+				victimKey, victimValue, found = k, v, true
+			}
+		}
+
+		if m.evictSamples > 0 && seen >= m.evictSamples {
+			break
+		}
+	}
+
+	return victimKey, victimValue, found
+}
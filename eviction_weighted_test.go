@@ -0,0 +1,83 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSyncedMapEvictionWeight tests that weighted eviction keeps mostly light
+// values and reports the evictions with EvictReasonCapacity.
+func TestSyncedMapEvictionWeight(t *testing.T) {
+	m := NewSyncedMap[int, uint64](100)
+	m.SetEvictionWeight(func(value uint64) float64 { return float64(value) }, -1)
+
+	evicted := 0
+
+	m.OnEvict(func(_ int, _ uint64, reason EvictReason) {
+		assert.Equal(t, EvictReasonCapacity, reason)
+		evicted++
+	})
+
+	// even keys weigh 1, odd keys weigh 99; each insert past the limit evicts
+	for i := range 10_000 {
+		value := uint64(1)
+		if i%2 == 1 {
+			value = 99
+		}
+
+		m.Set(i, value)
+	}
+
+	require.Equal(t, 100, m.Length())
+	assert.Equal(t, 9_900, evicted)
+
+	heavy := 0
+
+	m.Iterate(func(_ int, value uint64) bool {
+		if value == 99 {
+			heavy++
+		}
+
+		return true
+	})
+
+	// uniform eviction would keep about half of them
+	assert.Less(t, heavy, 20)
+}
+
+// TestSyncedMapEvictionWeightZero tests that values of weight zero are only
+// evicted when every sampled value weighs zero.
+func TestSyncedMapEvictionWeightZero(t *testing.T) {
+	m := NewSyncedMap[int, uint64](10)
+	m.SetEvictionWeight(func(value uint64) float64 { return float64(value) }, -1)
+
+	for i := range 10 {
+		m.Set(i, 0)
+	}
+
+	m.Set(100, 1)
+	assert.Equal(t, 10, m.Length())
+	assert.True(t, m.Exists(100))
+
+	// the only positive weight is the one to go
+	m.Set(101, 0)
+	assert.False(t, m.Exists(100))
+	assert.Equal(t, 10, m.Length())
+}
+
+// TestSyncedMapEvictionWeightReset tests that a nil weight restores the
+// arbitrary choice.
+func TestSyncedMapEvictionWeightReset(t *testing.T) {
+	m := NewSyncedMap[int, int](5)
+	m.SetEvictionWeight(func(int) float64 { return 1 }, 0)
+	m.SetEvictionWeight(nil, 0)
+
+	for i := range 50 {
+		m.Set(i, i)
+	}
+
+	assert.Equal(t, 5, m.Length())
+	assert.True(t, m.Exists(49))
+}
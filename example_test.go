@@ -0,0 +1,140 @@
+package txmap_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+	txmap "github.com/bsv-blockchain/go-tx-map"
+)
+
+// txids are the ids of three well-known transactions: the coinbase of the
+// genesis block, the first transaction between two people, in block 170, and
+// the 10,000 BTC pizza payment.
+var txids = []string{ //nolint:gochecknoglobals // shared example data
+	"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+	"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+	"a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d",
+}
+
+// exampleHashes parses txids.
+func exampleHashes() []chainhash.Hash {
+	hashes := make([]chainhash.Hash, len(txids))
+
+	for i, txid := range txids {
+		hash, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			panic(err)
+		}
+
+		hashes[i] = *hash
+	}
+
+	return hashes
+}
+
+// ExampleSwissMap_Put records which transactions of a block were seen,
+// ignoring repeated announcements.
+func ExampleSwissMap_Put() {
+	hashes := exampleHashes()
+	seen := txmap.NewSwissMap(uint32(len(hashes)))
+
+	for _, hash := range append(hashes, hashes[0]) {
+		_ = seen.Put(hash)
+	}
+
+	fmt.Println(seen.Length(), seen.Exists(hashes[2]))
+	// Output: 3 true
+}
+
+// ExampleSwissMapUint64_Put maps transactions to their index in a block and
+// rejects a transaction included twice.
+func ExampleSwissMapUint64_Put() {
+	hashes := exampleHashes()
+	index := txmap.NewSwissMapUint64(uint32(len(hashes)))
+
+	for i, hash := range hashes {
+		_ = index.Put(hash, uint64(i))
+	}
+
+	err := index.Put(hashes[1], 7)
+	position, _ := index.Get(hashes[1])
+
+	fmt.Println(errors.Is(err, txmap.ErrHashAlreadyExists), position)
+	// Output: true 1
+}
+
+// ExampleSplitSwissMap_PutMulti adds the transactions of a block in one call,
+// spread over buckets by their first two bytes.
+func ExampleSplitSwissMap_PutMulti() {
+	hashes := exampleHashes()
+	block := txmap.NewSplitSwissMap(len(hashes), 16)
+
+	if err := block.PutMulti(hashes, 0); err != nil {
+		panic(err)
+	}
+
+	fmt.Println(block.Length(), block.Exists(hashes[0]))
+	// Output: 3 true
+}
+
+// ExampleSplitSwissMapUint64_SetIfNotExists keeps the height a transaction
+// was first mined at when it is seen again in a reorg.
+func ExampleSplitSwissMapUint64_SetIfNotExists() {
+	hashes := exampleHashes()
+	heights := txmap.NewSplitSwissMapUint64(uint32(len(hashes)), 16)
+
+	first, _ := heights.SetIfNotExists(hashes[2], 57043)
+	again, _ := heights.SetIfNotExists(hashes[2], 57044)
+	height, _ := heights.Get(hashes[2])
+
+	fmt.Println(first, again, height)
+	// Output: true false 57043
+}
+
+// ExampleNativeSplitMapUint64_Delete removes a spent transaction from a set
+// of unspent ones.
+func ExampleNativeSplitMapUint64_Delete() {
+	hashes := exampleHashes()
+	unspent := txmap.NewNativeSplitMapUint64(uint32(len(hashes)), 16)
+
+	_ = unspent.PutMulti(hashes, 1)
+	_ = unspent.Delete(hashes[1])
+
+	err := unspent.Delete(hashes[1])
+
+	fmt.Println(unspent.Length(), errors.Is(err, txmap.ErrHashDoesNotExist))
+	// Output: 2 true
+}
+
+// ExampleNativeMapUint64_Freeze freezes a finished block index so that
+// lookups skip the lock and writes are refused.
+func ExampleNativeMapUint64_Freeze() {
+	hashes := exampleHashes()
+	index := txmap.NewNativeMapUint64(uint32(len(hashes)))
+
+	_ = index.PutMulti(hashes, 170)
+	index.Freeze()
+
+	height, _ := index.Get(hashes[1])
+	err := index.Put(hashes[0], 0)
+
+	fmt.Println(height, errors.Is(err, txmap.ErrMapFrozen))
+	// Output: 170 true
+}
+
+// ExampleSplitSwissLockFreeMapUint64_Put indexes outputs by a uint64 id from
+// a single writer.
+func ExampleSplitSwissLockFreeMapUint64_Put() {
+	outputs := txmap.NewSplitSwissLockFreeMapUint64(16, 4)
+
+	for id := uint64(1); id <= 3; id++ {
+		_ = outputs.Put(id, id*50)
+	}
+
+	value, _ := outputs.Get(2)
+
+	fmt.Println(outputs.Length(), value)
+	// Output: 3 100
+}
@@ -1,6 +0,0 @@
-// Package main provides examples for the package.
-package main
-
-func main() {
-	// Implement the examples
-}
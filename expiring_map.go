@@ -0,0 +1,255 @@
+package txmap
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that ExpiringMap implements TxMap
+var _ TxMap = (*ExpiringMap)(nil)
+
+// ExpiringMap is a TxMap whose entries expire after a time to live given per
+// entry or by default, for mempool-style use where transactions older than
+// some minutes should disappear on their own. It is split into buckets by
+// hash like the split maps, each with its own lock, and safe for concurrent
+// use. See the notes at the top of expiry.go.
+//
+// Expired entries are missing to every method from the moment they expire;
+// a write of an expired hash replaces it as if it were missing.
+type ExpiringMap struct {
+	e      *expiringShards[chainhash.Hash]
+	frozen atomic.Bool
+}
+
+// NewExpiringMap creates an ExpiringMap and starts its sweep loop unless
+// opts.SweepInterval is negative.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - opts: The default time to live, the sweep interval and the clock.
+//   - buckets: Optionally the number of buckets, 1024 by default.
+//
+// Returns:
+//   - *ExpiringMap: The map; Close must be called to stop its loop.
+func NewExpiringMap(length int, opts ExpiryOptions, buckets ...uint16) *ExpiringMap {
+	useBuckets := uint16(1024)
+	if len(buckets) > 0 && buckets[0] > 0 {
+		useBuckets = buckets[0]
+	}
+
+	shardOf := func(hash chainhash.Hash) int {
+		return int(Bytes2Uint16Buckets(hash, useBuckets))
+	}
+
+	return &ExpiringMap{
+		e: newExpiringShards(int(useBuckets), length, shardOf, opts),
+	}
+}
+
+// OnEvict registers a callback that is invoked for every entry removed by a
+// sweep, with EvictReasonExpired, or by Clear, with EvictReasonCleared.
+// Explicit Delete calls are not reported. Passing nil removes a previously
+// registered callback.
+//
+// Params:
+//   - cb: The callback to invoke; it runs under the lock of a bucket and must
+//     not call back into the map.
+func (x *ExpiringMap) OnEvict(cb EvictCallback[chainhash.Hash, uint64]) {
+	x.e.setOnEvict(cb)
+}
+
+// Exists checks if the hash exists and has not expired.
+func (x *ExpiringMap) Exists(hash chainhash.Hash) bool {
+	_, ok := x.e.get(hash)
+	return ok
+}
+
+// Get retrieves the value of the hash, unless it is missing or expired.
+func (x *ExpiringMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return x.e.get(hash)
+}
+
+// TTL returns the time the hash has left to live.
+//
+// Returns:
+//   - time.Duration: The time left.
+//   - bool: False if the hash is missing, expired or never expires.
+func (x *ExpiringMap) TTL(hash chainhash.Hash) (time.Duration, bool) {
+	return x.e.ttlOf(hash)
+}
+
+// Length returns the number of entries that have not expired, matching Keys
+// and Iter. It walks every entry, so it takes time linear in the size of the
+// map.
+func (x *ExpiringMap) Length() int {
+	return x.e.length()
+}
+
+// Keys returns the hashes that have not expired. The order of keys is not
+// guaranteed.
+func (x *ExpiringMap) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, x.e.length())
+
+	x.e.iter(func(hash chainhash.Hash, _ uint64) bool {
+		keys = append(keys, hash)
+		return false
+	})
+
+	return keys
+}
+
+// Iter calls f for every entry that has not expired, one bucket at a time.
+// Stops iterating if f returns true.
+func (x *ExpiringMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	x.e.iter(f)
+}
+
+// Put adds the hash with the default time to live of the options.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     hash exists and has not expired.
+func (x *ExpiringMap) Put(hash chainhash.Hash, value uint64) error {
+	return x.PutWithTTL(hash, value, x.e.ttl)
+}
+
+// PutWithTTL adds the hash with its own time to live.
+//
+// Params:
+//   - hash: The hash to add.
+//   - value: The value to associate with the hash.
+//   - ttl: The time to live; zero or negative means the entry never expires.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     hash exists and has not expired.
+func (x *ExpiringMap) PutWithTTL(hash chainhash.Hash, value uint64, ttl time.Duration) error {
+	if x.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	return x.e.put(hash, value, ttl, func(_ uint64, exists bool) (uint64, bool, error) {
+		if exists {
+			return 0, false, fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+		}
+
+		return value, true, nil
+	})
+}
+
+// PutMulti adds the hashes with the default time to live, stopping at the
+// first error.
+func (x *ExpiringMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	for _, hash := range hashes {
+		if err := x.Put(hash, value); err != nil {
+			return fmt.Errorf("failed to put multi: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value of an existing hash and renews its time to live with
+// the default of the options.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist if the
+//     hash is missing or expired.
+func (x *ExpiringMap) Set(hash chainhash.Hash, value uint64) error {
+	set, err := x.SetIfExists(hash, value)
+	if err == nil && !set {
+		err = fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return err
+}
+
+// SetIfExists updates the value of the hash and renews its time to live with
+// the default of the options, if it exists and has not expired.
+//
+// Returns:
+//   - bool: True if the hash was updated.
+//   - error: ErrMapFrozen if the map is frozen.
+func (x *ExpiringMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if x.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	var set bool
+
+	err := x.e.put(hash, value, x.e.ttl, func(_ uint64, exists bool) (uint64, bool, error) {
+		set = exists
+		return value, exists, nil
+	})
+
+	return set, err
+}
+
+// SetIfNotExists adds the hash with the default time to live if it is missing
+// or expired.
+//
+// Returns:
+//   - bool: True if the hash was added.
+//   - error: ErrMapFrozen if the map is frozen.
+func (x *ExpiringMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if x.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	var added bool
+
+	err := x.e.put(hash, value, x.e.ttl, func(_ uint64, exists bool) (uint64, bool, error) {
+		added = !exists
+		return value, added, nil
+	})
+
+	return added, err
+}
+
+// Delete removes the hash.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist if the
+//     hash was missing or had expired.
+func (x *ExpiringMap) Delete(hash chainhash.Hash) error {
+	if x.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	if !x.e.delete(hash) {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return nil
+}
+
+// Sweep removes the expired entries now, as the background loop does every
+// SweepInterval.
+//
+// Returns:
+//   - int: The number of entries removed.
+func (x *ExpiringMap) Sweep() int {
+	return x.e.sweep()
+}
+
+// Freeze makes all writes return ErrMapFrozen until Clear. Entries still
+// expire and are swept.
+func (x *ExpiringMap) Freeze() {
+	x.frozen.Store(true)
+}
+
+// Clear removes every entry, reporting each to OnEvict, and un-freezes the
+// map.
+func (x *ExpiringMap) Clear() {
+	x.e.clear()
+	x.frozen.Store(false)
+}
+
+// Close stops the sweep loop. The map remains usable. It is safe to call more
+// than once.
+func (x *ExpiringMap) Close() {
+	x.e.close()
+}
@@ -0,0 +1,106 @@
+package txmap
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExpiringMap tests the TxMap methods of ExpiringMap around the expiry of
+// its entries.
+func TestExpiringMap(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+
+	m := NewExpiringMap(0, ExpiryOptions{TTL: time.Minute, SweepInterval: -1, Now: clock.Now}, 8)
+	defer m.Close()
+
+	var evicted []chainhash.Hash
+
+	m.OnEvict(func(hash chainhash.Hash, _ uint64, reason EvictReason) {
+		assert.Equal(t, EvictReasonExpired, reason)
+		evicted = append(evicted, hash)
+	})
+
+	require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(1), hashN(2)}, 10))
+	require.NoError(t, m.PutWithTTL(hashN(3), 30, 0))
+	require.ErrorIs(t, m.Put(hashN(1), 11), ErrHashAlreadyExists)
+
+	clock.Advance(30 * time.Second)
+	require.NoError(t, m.Set(hashN(2), 20), "Set renews the time to live")
+
+	clock.Advance(30 * time.Second)
+
+	assert.False(t, m.Exists(hashN(1)), "expired entries are missing before the sweep")
+	assert.Equal(t, []chainhash.Hash{hashN(1)}, evicted, "a lookup removes the expired entry")
+	assert.ElementsMatch(t, []chainhash.Hash{hashN(2), hashN(3)}, m.Keys())
+	assert.Equal(t, 2, m.Length(), "expired entries are not counted before the sweep")
+	require.ErrorIs(t, m.Delete(hashN(1)), ErrHashDoesNotExist)
+
+	set, err := m.SetIfExists(hashN(1), 12)
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	added, err := m.SetIfNotExists(hashN(1), 13)
+	require.NoError(t, err)
+	assert.True(t, added, "an expired hash can be added again")
+
+	value, ok := m.Get(hashN(1))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(13), value)
+
+	clock.Advance(time.Minute)
+
+	assert.Equal(t, 2, m.Sweep())
+	assert.ElementsMatch(t, []chainhash.Hash{hashN(1), hashN(1), hashN(2)}, evicted)
+
+	_, ok = m.TTL(hashN(3))
+	assert.False(t, ok, "entries without a time to live never expire")
+
+	m.Freeze()
+	require.ErrorIs(t, m.Put(hashN(4), 40), ErrMapFrozen)
+
+	m.OnEvict(nil)
+	m.Clear()
+	assert.Equal(t, 0, m.Length())
+	require.NoError(t, m.Put(hashN(4), 40))
+}
+
+// TestExpiringMapExport tests that a map holding an expired entry the sweep
+// has not removed yet exports only its live entries.
+func TestExpiringMapExport(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+
+	m := NewExpiringMap(0, ExpiryOptions{TTL: time.Minute, SweepInterval: -1, Now: clock.Now})
+	defer m.Close()
+
+	require.NoError(t, m.PutWithTTL(hashN(1), 1, time.Second))
+	require.NoError(t, m.Put(hashN(2), 2))
+
+	clock.Advance(time.Second)
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(&buf, m))
+
+	dst := NewSwissMapUint64(0)
+	_, err := Import(context.Background(), dst, &buf, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []chainhash.Hash{hashN(2)}, dst.Keys())
+}
+
+// TestExpiringMapSweeper tests that the background loop removes expired
+// entries.
+func TestExpiringMapSweeper(t *testing.T) {
+	m := NewExpiringMap(0, ExpiryOptions{TTL: time.Millisecond, SweepInterval: time.Millisecond})
+	defer m.Close()
+
+	for i := range 100 {
+		require.NoError(t, m.Put(hashN(i), uint64(i)))
+	}
+
+	assert.Eventually(t, func() bool { return m.Length() == 0 }, time.Second, time.Millisecond)
+}
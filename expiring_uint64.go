@@ -0,0 +1,184 @@
+package txmap
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// check that ExpiringMapUint64 implements Uint64
+var _ Uint64 = (*ExpiringMapUint64)(nil)
+
+// ExpiringMapUint64 is a map of uint64 keys, such as the short ids of compact
+// blocks, whose entries expire after a time to live given per entry or by
+// default. It is split into buckets by key, each with its own lock, and safe
+// for concurrent use. See the notes at the top of expiry.go.
+type ExpiringMapUint64 struct {
+	e      *expiringShards[uint64]
+	frozen atomic.Bool
+}
+
+// NewExpiringMapUint64 creates an ExpiringMapUint64 and starts its sweep loop
+// unless opts.SweepInterval is negative.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - opts: The default time to live, the sweep interval and the clock.
+//   - buckets: Optionally the number of buckets, 1024 by default.
+//
+// Returns:
+//   - *ExpiringMapUint64: The map; Close must be called to stop its loop.
+func NewExpiringMapUint64(length int, opts ExpiryOptions, buckets ...uint64) *ExpiringMapUint64 {
+	useBuckets := uint64(1024)
+	if len(buckets) > 0 && buckets[0] > 0 {
+		useBuckets = buckets[0]
+	}
+
+	shardOf := func(key uint64) int {
+		return int(key % useBuckets) //nolint:gosec // G115 below the number of buckets
+	}
+
+	return &ExpiringMapUint64{
+		e: newExpiringShards(int(useBuckets), length, shardOf, opts), //nolint:gosec // G115 bucket counts are small
+	}
+}
+
+// OnEvict registers a callback that is invoked for every entry removed by a
+// sweep, with EvictReasonExpired, or by Clear, with EvictReasonCleared.
+// Explicit Delete calls are not reported. Passing nil removes a previously
+// registered callback.
+//
+// Params:
+//   - cb: The callback to invoke; it runs under the lock of a bucket and must
+//     not call back into the map.
+func (x *ExpiringMapUint64) OnEvict(cb EvictCallback[uint64, uint64]) {
+	x.e.setOnEvict(cb)
+}
+
+// Exists checks if the key exists and has not expired.
+func (x *ExpiringMapUint64) Exists(key uint64) bool {
+	_, ok := x.e.get(key)
+	return ok
+}
+
+// Get retrieves the value of the key, unless it is missing or expired.
+func (x *ExpiringMapUint64) Get(key uint64) (uint64, bool) {
+	return x.e.get(key)
+}
+
+// TTL returns the time the key has left to live.
+//
+// Returns:
+//   - time.Duration: The time left.
+//   - bool: False if the key is missing, expired or never expires.
+func (x *ExpiringMapUint64) TTL(key uint64) (time.Duration, bool) {
+	return x.e.ttlOf(key)
+}
+
+// Length returns the number of entries that have not expired, matching Keys
+// and Iter. It walks every entry, so it takes time linear in the size of the
+// map.
+func (x *ExpiringMapUint64) Length() int {
+	return x.e.length()
+}
+
+// Put adds the key with the default time to live of the options.
+func (x *ExpiringMapUint64) Put(key, value uint64) error {
+	return x.PutWithTTL(key, value, x.e.ttl)
+}
+
+// PutWithTTL adds the key with its own time to live. An expired entry of the
+// key is replaced as if it were missing.
+//
+// Params:
+//   - key: The key to add.
+//   - value: The value to associate with the key.
+//   - ttl: The time to live; zero or negative means the entry never expires.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     key exists and has not expired.
+func (x *ExpiringMapUint64) PutWithTTL(key, value uint64, ttl time.Duration) error {
+	if x.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	return x.e.put(key, value, ttl, func(_ uint64, exists bool) (uint64, bool, error) {
+		if exists {
+			return 0, false, fmt.Errorf("%w: %d", ErrHashAlreadyExists, key)
+		}
+
+		return value, true, nil
+	})
+}
+
+// Set stores the value of the key with ttl, whether or not it exists, which
+// also renews the time to live of an existing key.
+//
+// Params:
+//   - key: The key to store.
+//   - value: The value to associate with the key.
+//   - ttl: The time to live; zero or negative means the entry never expires.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen.
+func (x *ExpiringMapUint64) Set(key, value uint64, ttl time.Duration) error {
+	if x.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	return x.e.put(key, value, ttl, func(uint64, bool) (uint64, bool, error) {
+		return value, true, nil
+	})
+}
+
+// Delete removes the key.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist if the key
+//     was missing or had expired.
+func (x *ExpiringMapUint64) Delete(key uint64) error {
+	if x.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	if !x.e.delete(key) {
+		return fmt.Errorf("%w: %d", ErrHashDoesNotExist, key)
+	}
+
+	return nil
+}
+
+// Iter calls f for every entry that has not expired, one bucket at a time.
+// Stops iterating if f returns true.
+func (x *ExpiringMapUint64) Iter(f func(key, value uint64) bool) {
+	x.e.iter(f)
+}
+
+// Sweep removes the expired entries now, as the background loop does every
+// SweepInterval.
+//
+// Returns:
+//   - int: The number of entries removed.
+func (x *ExpiringMapUint64) Sweep() int {
+	return x.e.sweep()
+}
+
+// Freeze makes all writes return ErrMapFrozen until Clear. Entries still
+// expire and are swept.
+func (x *ExpiringMapUint64) Freeze() {
+	x.frozen.Store(true)
+}
+
+// Clear removes every entry, reporting each to OnEvict, and un-freezes the
+// map.
+func (x *ExpiringMapUint64) Clear() {
+	x.e.clear()
+	x.frozen.Store(false)
+}
+
+// Close stops the sweep loop. The map remains usable. It is safe to call more
+// than once.
+func (x *ExpiringMapUint64) Close() {
+	x.e.close()
+}
@@ -0,0 +1,112 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock is a clock advanced by the test.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// Now returns the current time of the clock.
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// TestExpiringMapUint64 tests per-key and default TTLs, lazy expiry and the
+// sweep with its eviction callback.
+func TestExpiringMapUint64(t *testing.T) {
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+
+	m := NewExpiringMapUint64(0, ExpiryOptions{TTL: time.Minute, SweepInterval: -1, Now: clock.Now}, 8)
+	defer m.Close()
+
+	var evicted []uint64
+
+	m.OnEvict(func(key, _ uint64, reason EvictReason) {
+		assert.Equal(t, EvictReasonExpired, reason)
+		evicted = append(evicted, key)
+	})
+
+	require.NoError(t, m.Put(1, 10))
+	require.NoError(t, m.PutWithTTL(2, 20, 10*time.Second))
+	require.NoError(t, m.PutWithTTL(3, 30, 0))
+	require.ErrorIs(t, m.Put(1, 11), ErrHashAlreadyExists)
+
+	ttl, ok := m.TTL(2)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, ttl)
+
+	clock.Advance(10 * time.Second)
+
+	assert.False(t, m.Exists(2), "expired entries are missing before the sweep")
+	assert.Equal(t, []uint64{2}, evicted, "a lookup removes the expired entry")
+	assert.Equal(t, 2, m.Length(), "expired entries are not counted before the sweep")
+
+	iterated := 0
+
+	m.Iter(func(_, _ uint64) bool {
+		iterated++
+		return false
+	})
+	assert.Equal(t, m.Length(), iterated)
+
+	require.NoError(t, m.Put(2, 21), "an expired key can be put again")
+
+	clock.Advance(time.Minute)
+
+	_, ok = m.Get(1)
+	assert.False(t, ok)
+	assert.Equal(t, 1, m.Sweep(), "key 1 was removed by Get")
+	assert.ElementsMatch(t, []uint64{2, 1, 2}, evicted)
+
+	value, ok := m.Get(3)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(30), value)
+
+	require.NoError(t, m.Set(3, 31, time.Second))
+	ttl, _ = m.TTL(3)
+	assert.Equal(t, time.Second, ttl)
+
+	m.Freeze()
+	require.ErrorIs(t, m.Put(4, 40), ErrMapFrozen)
+
+	m.OnEvict(nil)
+	m.Clear()
+	assert.Equal(t, 0, m.Length())
+	require.NoError(t, m.Put(4, 40))
+}
+
+// TestExpiringMapUint64Sweeper tests that the background loop removes
+// expired entries.
+func TestExpiringMapUint64Sweeper(t *testing.T) {
+	m := NewExpiringMapUint64(0, ExpiryOptions{TTL: time.Millisecond, SweepInterval: time.Millisecond})
+	defer m.Close()
+
+	for key := range uint64(100) {
+		require.NoError(t, m.Put(key, key))
+	}
+
+	assert.Eventually(t, func() bool { return m.Length() == 0 }, time.Second, time.Millisecond)
+
+	m.Close()
+	m.Close()
+}
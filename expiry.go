@@ -0,0 +1,350 @@
+package txmap
+
+import (
+	"sync"
+	"time"
+)
+
+// Expiry
+//
+// The expiring maps store a deadline next to every value. An entry past its
+// deadline is treated as missing by every lookup, and is not counted by
+// Length, from that moment on. Get and Exists remove an expired entry they
+// find; the others are removed by the next sweep: a background loop runs one
+// every SweepInterval, walking the shards one at a time under their write
+// locks. Either way the removed entry is reported to the OnEvict callback
+// with EvictReasonExpired. Lookups thus never see an expired entry, while the
+// cost of removing the entries nobody asks for is paid in batches off the
+// request path.
+//
+// expiringShards holds the entries and implements the sweep for any key type;
+// the exported maps choose the key type and the shard of a key.
+
+const (
+	// DefaultSweepInterval is the ExpiryOptions.SweepInterval used when it is
+	// zero.
+	DefaultSweepInterval = time.Second
+)
+
+// ExpiryOptions configure an expiring map.
+type ExpiryOptions struct {
+	// TTL is the time to live of entries written without one of their own.
+	// Zero or negative means they never expire.
+	TTL time.Duration
+
+	// SweepInterval is the period of the background sweep. Defaults to
+	// DefaultSweepInterval when zero; a negative interval starts no
+	// background loop, leaving the calls to Sweep to the caller.
+	SweepInterval time.Duration
+
+	// Now returns the current time, time.Now when nil. Tests set it to
+	// control expiry.
+	Now func() time.Time
+}
+
+// expiringEntry is a value and its deadline in unix nanoseconds, 0 for none.
+type expiringEntry struct {
+	value    uint64
+	deadline int64
+}
+
+// expired reports whether e is past its deadline at now.
+func (e expiringEntry) expired(now int64) bool {
+	return e.deadline != 0 && now >= e.deadline
+}
+
+// expiringShard is one shard of an expiringShards.
+type expiringShard[K comparable] struct {
+	mu sync.RWMutex
+	m  map[K]expiringEntry
+}
+
+// expiringShards holds the entries of an expiring map in shards, each with
+// its own lock, and runs the background sweep.
+type expiringShards[K comparable] struct {
+	shards  []expiringShard[K]
+	shardOf func(key K) int
+	ttl     time.Duration
+	now     func() time.Time
+
+	// evictMu guards onEvict, which the sweep reads with a shard locked.
+	evictMu sync.RWMutex
+	onEvict EvictCallback[K, uint64]
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// newExpiringShards creates n shards sized for length entries in total and
+// starts the sweep loop unless opts.SweepInterval is negative.
+func newExpiringShards[K comparable](n, length int, shardOf func(key K) int, opts ExpiryOptions) *expiringShards[K] {
+	if opts.SweepInterval == 0 {
+		opts.SweepInterval = DefaultSweepInterval
+	}
+
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	e := &expiringShards[K]{
+		shards:  make([]expiringShard[K], n),
+		shardOf: shardOf,
+		ttl:     opts.TTL,
+		now:     opts.Now,
+		stop:    make(chan struct{}),
+	}
+
+	for i := range e.shards {
+		e.shards[i].m = make(map[K]expiringEntry, length/n)
+	}
+
+	if opts.SweepInterval > 0 {
+		e.wg.Add(1)
+
+		go e.run(opts.SweepInterval)
+	}
+
+	return e
+}
+
+// setOnEvict replaces the eviction callback.
+func (e *expiringShards[K]) setOnEvict(cb EvictCallback[K, uint64]) {
+	e.evictMu.Lock()
+	defer e.evictMu.Unlock()
+
+	e.onEvict = cb
+}
+
+// deadline returns the deadline of an entry written now with ttl, 0 for none.
+func (e *expiringShards[K]) deadline(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+
+	return e.now().Add(ttl).UnixNano()
+}
+
+// get returns the value of key unless it is missing or expired. An expired
+// entry is removed on the spot and reported to the eviction callback.
+func (e *expiringShards[K]) get(key K) (uint64, bool) {
+	s := &e.shards[e.shardOf(key)]
+
+	s.mu.RLock()
+	entry, ok := s.m[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return 0, false
+	}
+
+	if entry.expired(e.now().UnixNano()) {
+		e.expire(s, key)
+		return 0, false
+	}
+
+	return entry.value, true
+}
+
+// expire removes key from s if it has expired, and reports it to the
+// eviction callback with EvictReasonExpired. The entry is checked again under
+// the write lock, as it may have been renewed or removed meanwhile.
+func (e *expiringShards[K]) expire(s *expiringShard[K], key K) {
+	e.evictMu.RLock()
+	defer e.evictMu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.m[key]
+	if !ok || !entry.expired(e.now().UnixNano()) {
+		return
+	}
+
+	delete(s.m, key)
+
+	if e.onEvict != nil {
+		e.onEvict(key, entry.value, EvictReasonExpired)
+	}
+}
+
+// ttlOf returns the time key has left to live, and false if it is missing,
+// expired or never expires.
+func (e *expiringShards[K]) ttlOf(key K) (time.Duration, bool) {
+	s := &e.shards[e.shardOf(key)]
+
+	s.mu.RLock()
+	entry, ok := s.m[key]
+	s.mu.RUnlock()
+
+	now := e.now().UnixNano()
+	if !ok || entry.deadline == 0 || entry.expired(now) {
+		return 0, false
+	}
+
+	return time.Duration(entry.deadline - now), true
+}
+
+// put stores key with value and ttl. If key exists and has not expired,
+// write decides the outcome: it returns the value to store and whether to
+// store it, or an error. An expired entry is replaced as if it were missing.
+func (e *expiringShards[K]) put(key K, value uint64, ttl time.Duration,
+	write func(existing uint64, exists bool) (uint64, bool, error),
+) error {
+	s := &e.shards[e.shardOf(key)]
+	deadline := e.deadline(ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.m[key]
+	exists = exists && !entry.expired(e.now().UnixNano())
+
+	value, store, err := write(entry.value, exists)
+	if store {
+		s.m[key] = expiringEntry{value: value, deadline: deadline}
+	}
+
+	return err
+}
+
+// delete removes key and reports whether it existed and had not expired.
+func (e *expiringShards[K]) delete(key K) bool {
+	s := &e.shards[e.shardOf(key)]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.m[key]
+	delete(s.m, key)
+
+	return ok && !entry.expired(e.now().UnixNano())
+}
+
+// length returns the number of entries that have not expired, the number
+// iter visits at the same moment. It walks every entry, one shard at a time
+// under its read lock, as expired entries are only removed in batches.
+func (e *expiringShards[K]) length() int {
+	now := e.now().UnixNano()
+	length := 0
+
+	for i := range e.shards {
+		s := &e.shards[i]
+
+		s.mu.RLock()
+
+		for _, entry := range s.m {
+			if !entry.expired(now) {
+				length++
+			}
+		}
+
+		s.mu.RUnlock()
+	}
+
+	return length
+}
+
+// iter calls f for every entry that has not expired, one shard at a time
+// under its read lock, until f returns true.
+func (e *expiringShards[K]) iter(f func(key K, value uint64) bool) {
+	now := e.now().UnixNano()
+
+	for i := range e.shards {
+		if e.iterShard(&e.shards[i], now, f) {
+			return
+		}
+	}
+}
+
+// iterShard calls f for the entries of s that have not expired at now and
+// reports whether f stopped.
+func (e *expiringShards[K]) iterShard(s *expiringShard[K], now int64, f func(key K, value uint64) bool) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for key, entry := range s.m {
+		if !entry.expired(now) && f(key, entry.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sweep removes the expired entries and reports them to the eviction
+// callback, and returns how many it removed.
+func (e *expiringShards[K]) sweep() int {
+	e.evictMu.RLock()
+	defer e.evictMu.RUnlock()
+
+	now := e.now().UnixNano()
+	removed := 0
+
+	for i := range e.shards {
+		removed += e.removeShard(&e.shards[i], EvictReasonExpired, func(entry expiringEntry) bool {
+			return entry.expired(now)
+		})
+	}
+
+	return removed
+}
+
+// clear removes every entry and reports them to the eviction callback with
+// EvictReasonCleared.
+func (e *expiringShards[K]) clear() {
+	e.evictMu.RLock()
+	defer e.evictMu.RUnlock()
+
+	for i := range e.shards {
+		e.removeShard(&e.shards[i], EvictReasonCleared, func(expiringEntry) bool { return true })
+	}
+}
+
+// removeShard removes the entries of s for which remove returns true, and
+// reports them to the eviction callback with reason. The caller holds
+// evictMu for reading.
+func (e *expiringShards[K]) removeShard(s *expiringShard[K], reason EvictReason, remove func(entry expiringEntry) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+
+	for key, entry := range s.m {
+		if !remove(entry) {
+			continue
+		}
+
+		delete(s.m, key)
+		removed++
+
+		if e.onEvict != nil {
+			e.onEvict(key, entry.value, reason)
+		}
+	}
+
+	return removed
+}
+
+// close stops the sweep loop.
+func (e *expiringShards[K]) close() {
+	e.closeOnce.Do(func() { close(e.stop) })
+	e.wg.Wait()
+}
+
+// run sweeps every interval until close.
+func (e *expiringShards[K]) run(interval time.Duration) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			e.sweep()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package txmap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"strconv"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// TextExportOptions controls how ExportCSV and ExportNDJSON write a map.
+type TextExportOptions struct {
+	// Gzip compresses the output with gzip.
+	Gzip bool
+
+	// NoHeader leaves out the "txid,value" header line of ExportCSV.
+	NoHeader bool
+}
+
+// ExportCSV writes the contents of m to w as CSV with the columns txid and
+// value, one row per entry, for analytics tools. Txids are written in the
+// usual display form, the reversed hex of the hash bytes, and values in
+// decimal. Rows are streamed in the map's iteration order, which is
+// unspecified.
+//
+// Params:
+//   - w: The destination stream. It is written through a buffer; ExportCSV
+//     does not close it.
+//   - m: The map to export.
+//   - opts: Compression and header.
+//
+// Returns:
+//   - error: Any error returned by w.
+//
+// Considerations: as for Export, m should not be written to meanwhile.
+func ExportCSV(w io.Writer, m ReadOnlyTxMap, opts TextExportOptions) error {
+	header := "txid,value\n"
+	if opts.NoHeader {
+		header = ""
+	}
+
+	return exportText(w, m, opts, header, func(line []byte, hash chainhash.Hash, value uint64) []byte {
+		line = append(line, hash.String()...)
+		line = append(line, ',')
+		line = strconv.AppendUint(line, value, 10)
+
+		return append(line, '\n')
+	})
+}
+
+// ExportNDJSON writes the contents of m to w as newline-delimited JSON, one
+// object {"txid":"<hex>","value":<n>} per line, for analytics tools. Txids and
+// values are formatted as for ExportCSV; values are JSON numbers, so readers
+// parsing numbers as doubles lose precision above 2^53.
+//
+// Params:
+//   - w: The destination stream. It is written through a buffer;
+//     ExportNDJSON does not close it.
+//   - m: The map to export.
+//   - opts: Compression; NoHeader does not apply.
+//
+// Returns:
+//   - error: Any error returned by w.
+//
+// Considerations: as for Export, m should not be written to meanwhile.
+func ExportNDJSON(w io.Writer, m ReadOnlyTxMap, opts TextExportOptions) error {
+	return exportText(w, m, opts, "", func(line []byte, hash chainhash.Hash, value uint64) []byte {
+		line = append(line, `{"txid":"`...)
+		line = append(line, hash.String()...)
+		line = append(line, `","value":`...)
+		line = strconv.AppendUint(line, value, 10)
+
+		return append(line, "}\n"...)
+	})
+}
+
+// exportText writes header and then the line format appends for every entry
+// of m, through a buffer and, if requested, gzip.
+func exportText(w io.Writer, m ReadOnlyTxMap, opts TextExportOptions, header string,
+	format func(line []byte, hash chainhash.Hash, value uint64) []byte,
+) error {
+	var zw *gzip.Writer
+
+	if opts.Gzip {
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+
+	bw := bufio.NewWriterSize(w, 1<<16)
+
+	_, err := bw.WriteString(header)
+
+	line := make([]byte, 0, 128)
+
+	if err == nil {
+		m.Iter(func(hash chainhash.Hash, value uint64) bool {
+			line = format(line[:0], hash, value)
+			_, err = bw.Write(line)
+
+			return err != nil
+		})
+	}
+
+	if err == nil {
+		err = bw.Flush()
+	}
+
+	if zw != nil && err == nil {
+		err = zw.Close()
+	}
+
+	return err
+}
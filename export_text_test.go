@@ -0,0 +1,81 @@
+package txmap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportCSV tests that the rows parse as CSV and match the map, with and
+// without header and gzip.
+func TestExportCSV(t *testing.T) {
+	m := NewSwissMapUint64(0)
+	for i := range 3 {
+		require.NoError(t, m.Put(hashN(i), uint64(i*10)))
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, ExportCSV(&buf, m, TextExportOptions{}))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{"txid", "value"}, rows[0])
+
+	got := rows[1:]
+	sort.Slice(got, func(i, j int) bool { return got[i][1] < got[j][1] })
+	assert.Equal(t, []string{hashN(1).String(), "10"}, got[1])
+
+	buf.Reset()
+	require.NoError(t, ExportCSV(&buf, m, TextExportOptions{Gzip: true, NoHeader: true}))
+
+	zr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(plain), "\n"))
+	assert.NotContains(t, string(plain), "txid")
+}
+
+// TestExportNDJSON tests that every line decodes as a JSON object matching
+// the map.
+func TestExportNDJSON(t *testing.T) {
+	m := NewNativeSplitMapUint64(0, 4)
+	for i := range 100 {
+		require.NoError(t, m.Put(hashN(i), uint64(i)<<40))
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, ExportNDJSON(&buf, m, TextExportOptions{}))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 100)
+
+	for _, line := range lines {
+		var record struct {
+			TxID  string `json:"txid"`
+			Value uint64 `json:"value"`
+		}
+
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+
+		hash, err := chainhash.NewHashFromStr(record.TxID)
+		require.NoError(t, err)
+
+		value, ok := m.Get(*hash)
+		require.True(t, ok)
+		assert.Equal(t, value, record.Value)
+	}
+}
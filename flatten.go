@@ -0,0 +1,128 @@
+package txmap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Flattening
+//
+// A split map is itself the combined view of its buckets: every TxMap method
+// routes to the right bucket, so code that needs to read "the whole map"
+// should take the split map as a ReadOnlyTxMap rather than a copy. Flatten is
+// for the rare caller that needs a single-bucket map, e.g. to hand to an API
+// taking *SwissMapUint64: it copies the buckets one at a time, each under its
+// read lock, into a new map, reporting progress on the way, stopping between
+// buckets once ctx is done, and returning the first error rather than
+// dropping entries. The copy holds all entries a second time, so on large
+// maps the memory for it must be planned for.
+
+// FlattenOptions controls how Flatten copies a split map.
+type FlattenOptions struct {
+	// OnProgress, if set, is called every ProgressInterval entries and once
+	// more when the copy finishes successfully.
+	OnProgress func(progress Progress)
+
+	// ProgressInterval is the number of entries between OnProgress calls.
+	// Defaults to 1,048,576 when zero or negative.
+	ProgressInterval int
+}
+
+// flattenTarget is implemented by the maps Flatten copies into. It is only
+// called before the map is shared, so it need not lock.
+type flattenTarget interface {
+	putUnlocked(hash chainhash.Hash, n uint64) error
+}
+
+// Flatten copies the map into a single SwissMapUint64. See the notes at the
+// top of flatten.go.
+//
+// Params:
+//   - ctx: Stops the copy between buckets once done.
+//   - opts: The progress callback and its interval.
+//
+// Returns:
+//   - *SwissMapUint64: The copy, nil on error.
+//   - error: ErrBucketDoesNotExist for a missing bucket, ctx.Err(), or an
+//     error wrapping the first failed Put.
+func (g *SplitSwissMap) Flatten(ctx context.Context, opts FlattenOptions) (*SwissMapUint64, error) {
+	return flattenBuckets(ctx, opts, g.m, g.nrOfBuckets, NewSwissMapUint64)
+}
+
+// Flatten copies the map into a single NativeMapUint64. See the notes at the
+// top of flatten.go.
+//
+// Params:
+//   - ctx: Stops the copy between buckets once done.
+//   - opts: The progress callback and its interval.
+//
+// Returns:
+//   - *NativeMapUint64: The copy, nil on error.
+//   - error: ErrBucketDoesNotExist for a missing bucket, ctx.Err(), or an
+//     error wrapping the first failed Put.
+func (g *NativeSplitMap) Flatten(ctx context.Context, opts FlattenOptions) (*NativeMapUint64, error) {
+	return flattenBuckets(ctx, opts, g.m, g.nrOfBuckets, NewNativeMapUint64)
+}
+
+// flattenBuckets copies buckets 0..nrOfBuckets into a new map made by newDst
+// with room for all their entries, each bucket through its Iter, which holds
+// the bucket's read lock.
+func flattenBuckets[B interface {
+	comparable
+	ReadOnlyTxMap
+}, T flattenTarget](ctx context.Context, opts FlattenOptions, buckets []B, nrOfBuckets uint16,
+	newDst func(length uint32) T,
+) (T, error) {
+	var (
+		missing T
+		total   uint64
+	)
+
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		bucket, err := bucketAt(buckets, i)
+		if err != nil {
+			return missing, err
+		}
+
+		total += uint64(bucket.Length()) //nolint:gosec // length is never negative
+	}
+
+	dst := newDst(uint32(total)) //nolint:gosec // integer overflow conversion uint64 -> uint32
+	progress := newProgressTracker(opts.OnProgress, opts.ProgressInterval, total)
+
+	var (
+		copied uint64
+		err    error
+	)
+
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		if err = ctx.Err(); err != nil {
+			return missing, err
+		}
+
+		buckets[i].Iter(func(hash chainhash.Hash, value uint64) bool {
+			if err = dst.putUnlocked(hash, value); err != nil {
+				err = fmt.Errorf("failed to flatten bucket %d: %w", i, err)
+				return true
+			}
+
+			copied++
+
+			if progress.due(copied) {
+				progress.report(copied, 0)
+			}
+
+			return false
+		})
+
+		if err != nil {
+			return missing, err
+		}
+	}
+
+	progress.report(copied, 0)
+
+	return dst, nil
+}
@@ -0,0 +1,53 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFlatten tests that Flatten copies every bucket and reports progress.
+func TestFlatten(t *testing.T) {
+	swissSplit := NewSplitSwissMap(0, 8)
+	nativeSplit := NewNativeSplitMap(0, 8)
+
+	for i := range 1000 {
+		require.NoError(t, swissSplit.Put(hashN(i), uint64(i)))
+		require.NoError(t, nativeSplit.Put(hashN(i), uint64(i)))
+	}
+
+	var reports []Progress
+
+	opts := FlattenOptions{
+		OnProgress:       func(p Progress) { reports = append(reports, p) },
+		ProgressInterval: 300,
+	}
+
+	swissCopy, err := swissSplit.Flatten(context.Background(), opts)
+	require.NoError(t, err)
+	requireSameContents(t, swissSplit, swissCopy)
+
+	require.Len(t, reports, 4)
+	assert.Equal(t, uint64(300), reports[0].Processed)
+	assert.Equal(t, uint64(1000), reports[3].Processed, "a final report follows the copy")
+	assert.Equal(t, uint64(1000), reports[3].Total)
+
+	nativeCopy, err := nativeSplit.Flatten(context.Background(), FlattenOptions{})
+	require.NoError(t, err)
+	requireSameContents(t, nativeSplit, nativeCopy)
+}
+
+// TestFlattenCanceled tests that Flatten stops once its context is done.
+func TestFlattenCanceled(t *testing.T) {
+	m := NewSplitSwissMap(0, 8)
+	require.NoError(t, m.Put(hashN(1), 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	flat, err := m.Flatten(ctx, FlattenOptions{})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, flat)
+}
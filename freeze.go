@@ -1,5 +1,7 @@
 package txmap
 
+import "github.com/bsv-blockchain/go-bt/v2/chainhash"
+
 // Freeze / Clear lifecycle
 //
 // Freeze marks a map read-only. It exists to eliminate read-side lock
@@ -30,6 +32,13 @@ package txmap
 // phase). Freeze and Clear are not safe to call concurrently with other
 // operations on the same map.
 
+// FreezeReadOnly is Freeze for callers that hand the map to other goroutines:
+// it freezes the map and returns a ReadOnlyTxMap view of it that has no write
+// methods, and cannot be type-asserted back to the writable map, so the
+// compiler rejects any attempt by the holder to modify it. Reads through the
+// view take no locks. The owner keeps the writable map and remains bound by
+// the Clear contract above: a Clear while views are in use races their reads.
+
 // Compile-time checks that every concrete map type satisfies its interface,
 // including the Freeze/Clear methods now required by TxMap, TxHashMap and
 // Uint64. (The TxMap assertions are duplicated next to each type definition;
@@ -52,6 +61,35 @@ var (
 	_ Uint64 = (*NativeSplitLockFreeMapUint64)(nil)
 )
 
+// readOnlyTxMap is the ReadOnlyTxMap returned by FreezeReadOnly and
+// SubtreeIndex.Map. Wrapping the map in a distinct type hides its write
+// methods from type assertions.
+type readOnlyTxMap struct {
+	m TxMap
+}
+
+// freezeReadOnly freezes m and returns a read-only view of it.
+func freezeReadOnly(m TxMap) ReadOnlyTxMap {
+	m.Freeze()
+
+	return readOnlyTxMap{m: m}
+}
+
+// Exists checks if the given hash exists in the map.
+func (f readOnlyTxMap) Exists(hash chainhash.Hash) bool { return f.m.Exists(hash) }
+
+// Get retrieves the value associated with the given hash from the map.
+func (f readOnlyTxMap) Get(hash chainhash.Hash) (uint64, bool) { return f.m.Get(hash) }
+
+// Keys returns all hashes in the map.
+func (f readOnlyTxMap) Keys() []chainhash.Hash { return f.m.Keys() }
+
+// Length returns the number of hashes in the map.
+func (f readOnlyTxMap) Length() int { return f.m.Length() }
+
+// Iter iterates over the map. Stops iterating if fn returns true.
+func (f readOnlyTxMap) Iter(fn func(hash chainhash.Hash, value uint64) bool) { f.m.Iter(fn) }
+
 // --- dolthub/swiss-backed leaf maps -----------------------------------------
 
 // Freeze marks the map read-only. See the lifecycle notes at the top of this file.
@@ -60,6 +98,9 @@ func (s *SwissMap) Freeze() { s.frozen.Store(true) }
 // Freeze marks the map read-only. See the lifecycle notes at the top of this file.
 func (s *SwissMapUint64) Freeze() { s.frozen.Store(true) }
 
+// FreezeReadOnly freezes the map and returns a read-only view of it.
+func (s *SwissMapUint64) FreezeReadOnly() ReadOnlyTxMap { return freezeReadOnly(s) }
+
 // Freeze marks the map read-only; subsequent Put calls return ErrMapFrozen.
 func (s *SwissLockFreeMapUint64) Freeze() { s.frozen.Store(true) }
 
@@ -85,13 +126,16 @@ func (s *NativeMap) Clear() {
 	defer s.mu.Unlock()
 
 	clear(s.m)
-	s.length = 0
+	s.length.Store(0)
 	s.frozen.Store(false)
 }
 
 // Freeze marks the map read-only. See the lifecycle notes at the top of this file.
 func (s *NativeMapUint64) Freeze() { s.frozen.Store(true) }
 
+// FreezeReadOnly freezes the map and returns a read-only view of it.
+func (s *NativeMapUint64) FreezeReadOnly() ReadOnlyTxMap { return freezeReadOnly(s) }
+
 // Clear empties the map (retaining its allocated capacity) and un-freezes it
 // for reuse. Per the lifecycle contract above, Clear must not run concurrently
 // with other operations on the map (a frozen reader skips the lock that Clear
@@ -101,8 +145,9 @@ func (s *NativeMapUint64) Clear() {
 	defer s.mu.Unlock()
 
 	clear(s.m)
-	s.length = 0
+	s.length.Store(0)
 	s.frozen.Store(false)
+	s.epoch.Add(1)
 }
 
 // Freeze marks the map read-only; subsequent Put calls return ErrMapFrozen.
@@ -125,11 +170,16 @@ func (g *SplitSwissMap) Freeze() {
 	}
 }
 
+// FreezeReadOnly freezes every bucket and returns a read-only view of the map.
+func (g *SplitSwissMap) FreezeReadOnly() ReadOnlyTxMap { return freezeReadOnly(g) }
+
 // Clear empties and un-freezes every bucket, recycling the split map for reuse.
 func (g *SplitSwissMap) Clear() {
 	for i := uint16(0); i <= g.nrOfBuckets; i++ {
 		g.m[i].Clear()
 	}
+
+	g.epoch.Add(1)
 }
 
 // Freeze freezes every bucket, so reads across the whole split map become
@@ -140,6 +190,9 @@ func (g *SplitSwissMapUint64) Freeze() {
 	}
 }
 
+// FreezeReadOnly freezes every bucket and returns a read-only view of the map.
+func (g *SplitSwissMapUint64) FreezeReadOnly() ReadOnlyTxMap { return freezeReadOnly(g) }
+
 // Freeze freezes every bucket; subsequent Put calls return ErrMapFrozen.
 func (g *SplitSwissLockFreeMapUint64) Freeze() {
 	for i := uint64(0); i <= g.nrOfBuckets; i++ {
@@ -162,11 +215,16 @@ func (g *NativeSplitMap) Freeze() {
 	}
 }
 
+// FreezeReadOnly freezes every bucket and returns a read-only view of the map.
+func (g *NativeSplitMap) FreezeReadOnly() ReadOnlyTxMap { return freezeReadOnly(g) }
+
 // Clear empties and un-freezes every bucket, recycling the split map for reuse.
 func (g *NativeSplitMap) Clear() {
 	for i := uint16(0); i <= g.nrOfBuckets; i++ {
 		g.m[i].Clear()
 	}
+
+	g.epoch.Add(1)
 }
 
 // Freeze freezes every bucket, so reads across the whole split map become
@@ -177,11 +235,16 @@ func (g *NativeSplitMapUint64) Freeze() {
 	}
 }
 
+// FreezeReadOnly freezes every bucket and returns a read-only view of the map.
+func (g *NativeSplitMapUint64) FreezeReadOnly() ReadOnlyTxMap { return freezeReadOnly(g) }
+
 // Clear empties and un-freezes every bucket, recycling the split map for reuse.
 func (g *NativeSplitMapUint64) Clear() {
 	for i := uint16(0); i <= g.nrOfBuckets; i++ {
 		g.m[i].Clear()
 	}
+
+	g.epoch.Add(1)
 }
 
 // Freeze freezes every bucket; subsequent Put calls return ErrMapFrozen.
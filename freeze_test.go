@@ -39,8 +39,10 @@ func txMapImpls() map[string]func() TxMap {
 // implements TxHashMap, keyed by type name.
 func txHashMapImpls() map[string]func() TxHashMap {
 	return map[string]func() TxHashMap{
-		"SwissMap":  func() TxHashMap { return NewSwissMap(1024) },
-		"NativeMap": func() TxHashMap { return NewNativeMap(1024) },
+		"SwissMap":     func() TxHashMap { return NewSwissMap(1024) },
+		"NativeMap":    func() TxHashMap { return NewNativeMap(1024) },
+		"HashSet":      func() TxHashMap { return NewHashSet(1024) },
+		"SplitHashSet": func() TxHashMap { return NewSplitHashSet(1024) },
 	}
 }
 
@@ -212,3 +214,44 @@ func TestFrozenConcurrentReads(t *testing.T) {
 		})
 	}
 }
+
+// TestFreezeReadOnly tests that FreezeReadOnly freezes the map and returns a
+// view that reads the same entries but cannot be turned back into a TxMap.
+func TestFreezeReadOnly(t *testing.T) {
+	const n = 100
+
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+			for i := 0; i < n; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i)))
+			}
+
+			freezer, ok := m.(interface{ FreezeReadOnly() ReadOnlyTxMap })
+			require.True(t, ok)
+
+			ro := freezer.FreezeReadOnly()
+
+			_, ok = ro.(TxMap)
+			require.False(t, ok, "read-only view must not expose write methods")
+
+			require.ErrorIs(t, m.Put(hashN(n), n), ErrMapFrozen)
+
+			require.Equal(t, n, ro.Length())
+			require.Len(t, ro.Keys(), n)
+			require.True(t, ro.Exists(hashN(7)))
+
+			v, ok := ro.Get(hashN(7))
+			require.True(t, ok)
+			require.Equal(t, uint64(7), v)
+
+			seen := 0
+
+			ro.Iter(func(chainhash.Hash, uint64) bool {
+				seen++
+				return false
+			})
+			require.Equal(t, n, seen)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package txmap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// generationStripes is the number of lock stripes of a GenerationTxMap.
+const generationStripes = 256
+
+// check that GenerationTxMap implements TxMap
+var _ TxMap = (*GenerationTxMap)(nil)
+
+// GenerationTxMap wraps a TxMap and stamps every entry with a generation that
+// changes on each write to it. A compare-and-swap on the value alone cannot
+// tell that an entry was deleted and re-added with the same value between a
+// read and a write (the ABA problem); comparing generations can, because the
+// re-added entry carries a new one.
+//
+// Generations are drawn from a single counter per map, so they never repeat
+// for a hash, even across a Delete and a later Put.
+//
+// All writes must go through the GenerationTxMap; entries written directly to
+// the wrapped map have no generation and are reported with generation 0.
+type GenerationTxMap struct {
+	m       TxMap
+	counter atomic.Uint64
+	stripes [generationStripes]generationStripe
+}
+
+// generationStripe guards the generations of the hashes routed to it, and the
+// wrapped map's entries for those hashes.
+type generationStripe struct {
+	mu   sync.RWMutex
+	gens map[chainhash.Hash]uint64
+}
+
+// NewGenerationTxMap returns a GenerationTxMap that forwards every operation to m.
+//
+// Params:
+//   - m: The map to wrap; it should be empty.
+//
+// Returns:
+//   - *GenerationTxMap: The wrapping map.
+func NewGenerationTxMap(m TxMap) *GenerationTxMap {
+	m = nonNilTxMap(m)
+
+	g := &GenerationTxMap{m: m}
+
+	for i := range g.stripes {
+		g.stripes[i].gens = make(map[chainhash.Hash]uint64)
+	}
+
+	return g
+}
+
+// GetWithGeneration retrieves the value and generation of hash.
+//
+// Params:
+//   - hash: The hash to look up.
+//
+// Returns:
+//   - uint64: The value, 0 if the hash does not exist.
+//   - uint64: The generation to pass to SetIfGeneration, 0 if the hash does not exist.
+//   - bool: True if the hash exists.
+func (g *GenerationTxMap) GetWithGeneration(hash chainhash.Hash) (uint64, uint64, bool) {
+	s := g.stripe(hash)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := g.m.Get(hash)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return value, s.gens[hash], true
+}
+
+// SetIfGeneration updates the value of hash only if its generation is still
+// gen, i.e. nothing has written, deleted or re-added it since gen was read.
+//
+// Params:
+//   - hash: The hash to update.
+//   - value: The new value.
+//   - gen: The generation returned by GetWithGeneration.
+//
+// Returns:
+//   - bool: True if the value was updated.
+//   - error: Any error returned by the wrapped map.
+func (g *GenerationTxMap) SetIfGeneration(hash chainhash.Hash, value, gen uint64) (bool, error) {
+	s := g.stripe(hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.gens[hash]; !ok || current != gen {
+		return false, nil
+	}
+
+	ok, err := g.m.SetIfExists(hash, value)
+	if ok {
+		s.gens[hash] = g.counter.Add(1)
+	}
+
+	return ok, err
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (g *GenerationTxMap) Exists(hash chainhash.Hash) bool {
+	return g.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (g *GenerationTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return g.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (g *GenerationTxMap) Keys() []chainhash.Hash {
+	return g.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (g *GenerationTxMap) Length() int {
+	return g.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (g *GenerationTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	g.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map with a new generation.
+func (g *GenerationTxMap) Put(hash chainhash.Hash, value uint64) error {
+	s := g.stripe(hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := g.m.Put(hash, value); err != nil {
+		return err
+	}
+
+	s.gens[hash] = g.counter.Add(1)
+
+	return nil
+}
+
+// PutMulti adds hashes to the wrapped map one by one, each with a new
+// generation, stopping at the first error.
+func (g *GenerationTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	for _, hash := range hashes {
+		if err := g.Put(hash, value); err != nil {
+			return fmt.Errorf("failed to put multi: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value of hash in the wrapped map and gives it a new generation.
+func (g *GenerationTxMap) Set(hash chainhash.Hash, value uint64) error {
+	s := g.stripe(hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := g.m.Set(hash, value); err != nil {
+		return err
+	}
+
+	s.gens[hash] = g.counter.Add(1)
+
+	return nil
+}
+
+// SetIfExists updates the value of hash if it exists and gives it a new generation.
+func (g *GenerationTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	s := g.stripe(hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok, err := g.m.SetIfExists(hash, value)
+	if ok {
+		s.gens[hash] = g.counter.Add(1)
+	}
+
+	return ok, err
+}
+
+// SetIfNotExists adds hash with a new generation if it does not exist.
+func (g *GenerationTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	s := g.stripe(hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok, err := g.m.SetIfNotExists(hash, value)
+	if ok {
+		s.gens[hash] = g.counter.Add(1)
+	}
+
+	return ok, err
+}
+
+// Delete removes hash and its generation.
+func (g *GenerationTxMap) Delete(hash chainhash.Hash) error {
+	s := g.stripe(hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := g.m.Delete(hash); err != nil {
+		return err
+	}
+
+	delete(s.gens, hash)
+
+	return nil
+}
+
+// Freeze freezes the wrapped map.
+func (g *GenerationTxMap) Freeze() {
+	g.m.Freeze()
+}
+
+// Clear clears the wrapped map and all generations. The generation counter is
+// not reset, so generations read before Clear never match again.
+func (g *GenerationTxMap) Clear() {
+	for i := range g.stripes {
+		g.stripes[i].mu.Lock()
+	}
+
+	g.m.Clear()
+
+	for i := range g.stripes {
+		clear(g.stripes[i].gens)
+		g.stripes[i].mu.Unlock()
+	}
+}
+
+// stripe returns the lock stripe of hash.
+func (g *GenerationTxMap) stripe(hash chainhash.Hash) *generationStripe {
+	return &g.stripes[Bytes2Uint16Buckets(hash, generationStripes)]
+}
@@ -0,0 +1,61 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerationTxMap runs the TxMap conformance tests against a GenerationTxMap.
+func TestGenerationTxMap(t *testing.T) {
+	testTxMap(t, NewGenerationTxMap(NewSplitSwissMapUint64(100, 16)))
+}
+
+// TestGenerationTxMapABA tests that a delete and re-add with the same value
+// invalidates the generation read before it.
+func TestGenerationTxMapABA(t *testing.T) {
+	m := NewGenerationTxMap(NewNativeMapUint64(10))
+	hash := hashN(1)
+
+	_, _, ok := m.GetWithGeneration(hash)
+	assert.False(t, ok)
+
+	require.NoError(t, m.Put(hash, 5))
+
+	value, gen, ok := m.GetWithGeneration(hash)
+	require.True(t, ok)
+	assert.Equal(t, uint64(5), value)
+	assert.NotZero(t, gen)
+
+	require.NoError(t, m.Delete(hash))
+	require.NoError(t, m.Put(hash, 5))
+
+	value, _, _ = m.GetWithGeneration(hash)
+	assert.Equal(t, uint64(5), value, "the value alone cannot reveal the re-add")
+
+	ok, err := m.SetIfGeneration(hash, 6, gen)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, gen, _ = m.GetWithGeneration(hash)
+
+	ok, err = m.SetIfGeneration(hash, 6, gen)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// the successful write itself moved the generation on
+	ok, err = m.SetIfGeneration(hash, 7, gen)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	value, _ = m.Get(hash)
+	assert.Equal(t, uint64(6), value)
+
+	_, gen, _ = m.GetWithGeneration(hash)
+	m.Clear()
+
+	ok, err = m.SetIfGeneration(hash, 7, gen)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,574 @@
+package txmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Hash sets
+//
+// SwissMap and NativeMap store hashes without values, but through key/value
+// backends whose slots, groups and growth policy are sized for generic
+// entries. HashSet is a table built for nothing but 32-byte hashes: a flat
+// array of keys with one control byte per slot, probed linearly. A control
+// byte is zero for an empty slot, and otherwise holds seven bits of the hash
+// as a fingerprint, so that a probe compares a key only when its fingerprint
+// matches. Deletes shift the following entries back instead of leaving
+// tombstones, so the table never needs a cleanup rehash.
+//
+// Filled with a million hashes without preallocation, a HashSet uses about
+// 45 bytes per hash, against about 70 for SwissMap and 84 for NativeMap;
+// preallocated, it matches SwissMap at about 38 bytes, less than half of
+// NativeMap. See BenchmarkHashSetMemoryPerEntry.
+//
+// Transaction hashes are double SHA-256 digests, but test data and derived
+// keys often are not: sequential counters, or hashes that differ only in the
+// bytes the split maps route on. The table therefore mixes the four words of
+// a hash with the splitmix64 finalizer, and takes the slot index from the high
+// bits of the result and the fingerprint from its low bits.
+
+const (
+	// hashSetMinSlots is the smallest number of slots of a hash set table.
+	hashSetMinSlots = 16
+
+	// hashSetMaxLoadNum and hashSetMaxLoadDen set the load factor, 7/8, at
+	// which a hash set table grows.
+	hashSetMaxLoadNum = 7
+	hashSetMaxLoadDen = 8
+
+	// hashSetFull marks a control byte of an occupied slot.
+	hashSetFull = 0x80
+)
+
+// hashSetTable is the unsynchronized open-addressing table of a HashSet. The
+// number of slots is not a power of two: a mixed hash is mapped onto the slots
+// by multiplication, so that a preallocated table has exactly the slots its
+// load factor needs and a growing one grows by half instead of doubling.
+type hashSetTable struct {
+	ctrl  []uint8
+	keys  []chainhash.Hash
+	count int
+}
+
+// newHashSetTable returns a table that holds length hashes without growing.
+func newHashSetTable(length uint32) hashSetTable {
+	return newHashSetTableSlots(max(hashSetMinSlots, uint64(length)*hashSetMaxLoadDen/hashSetMaxLoadNum+1))
+}
+
+// newHashSetTableSlots returns an empty table with the given number of slots.
+func newHashSetTableSlots(slots uint64) hashSetTable {
+	return hashSetTable{
+		ctrl: make([]uint8, slots),
+		keys: make([]chainhash.Hash, slots),
+	}
+}
+
+// hashSetMix returns the mixed 64 bits of hash that select its slot and
+// fingerprint.
+func hashSetMix(hash chainhash.Hash) uint64 {
+	h := binary.LittleEndian.Uint64(hash[0:8]) ^ binary.LittleEndian.Uint64(hash[8:16]) ^
+		binary.LittleEndian.Uint64(hash[16:24]) ^ binary.LittleEndian.Uint64(hash[24:32])
+
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+
+	return h
+}
+
+// hashSetCtrl returns the control byte of a hash with the mixed bits h. The
+// slot is selected by the high bits of h, the fingerprint is taken from the
+// low ones.
+func hashSetCtrl(h uint64) uint8 {
+	return hashSetFull | uint8(h)&^hashSetFull
+}
+
+// home returns the first slot probed for a hash with the mixed bits h.
+func (t *hashSetTable) home(h uint64) uint64 {
+	hi, _ := bits.Mul64(h, uint64(len(t.ctrl)))
+	return hi
+}
+
+// next returns the slot probed after slot i.
+func (t *hashSetTable) next(i uint64) uint64 {
+	if i++; i == uint64(len(t.ctrl)) {
+		return 0
+	}
+
+	return i
+}
+
+// distance returns how many probes slot to lies after slot from.
+func (t *hashSetTable) distance(from, to uint64) uint64 {
+	if to >= from {
+		return to - from
+	}
+
+	return to + uint64(len(t.ctrl)) - from
+}
+
+// find returns the slot holding hash and true, or the empty slot ending its
+// probe sequence and false.
+func (t *hashSetTable) find(hash chainhash.Hash) (uint64, bool) {
+	h := hashSetMix(hash)
+	fp := hashSetCtrl(h)
+
+	for i := t.home(h); ; i = t.next(i) {
+		switch t.ctrl[i] {
+		case 0:
+			return i, false
+		case fp:
+			if t.keys[i] == hash {
+				return i, true
+			}
+		}
+	}
+}
+
+// has reports whether hash is in the table.
+func (t *hashSetTable) has(hash chainhash.Hash) bool {
+	_, ok := t.find(hash)
+	return ok
+}
+
+// insert adds hash, which must not be in the table yet.
+func (t *hashSetTable) insert(hash chainhash.Hash) {
+	if (t.count+1)*hashSetMaxLoadDen > len(t.ctrl)*hashSetMaxLoadNum {
+		t.grow()
+	}
+
+	i, _ := t.find(hash)
+	t.ctrl[i] = hashSetCtrl(hashSetMix(hash))
+	t.keys[i] = hash
+	t.count++
+}
+
+// remove deletes hash and reports whether it was in the table. The entries
+// after it in its probe run are shifted back, so that no probe sequence
+// crosses an empty slot before reaching its hash.
+func (t *hashSetTable) remove(hash chainhash.Hash) bool {
+	hole, ok := t.find(hash)
+	if !ok {
+		return false
+	}
+
+	for j := t.next(hole); t.ctrl[j] != 0; j = t.next(j) {
+		// an entry may move into the hole only if the hole lies between its
+		// home slot and its current slot
+		if t.distance(t.home(hashSetMix(t.keys[j])), j) >= t.distance(hole, j) {
+			t.ctrl[hole] = t.ctrl[j]
+			t.keys[hole] = t.keys[j]
+			hole = j
+		}
+	}
+
+	t.ctrl[hole] = 0
+	t.keys[hole] = chainhash.Hash{}
+	t.count--
+
+	return true
+}
+
+// grow adds half the number of slots and reinserts every hash.
+func (t *hashSetTable) grow() {
+	old := *t
+	*t = newHashSetTableSlots(uint64(len(old.ctrl)) * 3 / 2)
+	t.count = old.count
+
+	for i, c := range old.ctrl {
+		if c != 0 {
+			j, _ := t.find(old.keys[i])
+			t.ctrl[j] = c
+			t.keys[j] = old.keys[i]
+		}
+	}
+}
+
+// reset empties the table, keeping its slots.
+func (t *hashSetTable) reset() {
+	clear(t.ctrl)
+	clear(t.keys)
+	t.count = 0
+}
+
+// iter calls f for every hash until it returns true, and reports whether it did.
+func (t *hashSetTable) iter(f func(hash chainhash.Hash) bool) bool {
+	for i, c := range t.ctrl {
+		if c != 0 && f(t.keys[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check that HashSet implements TxHashMap
+var _ TxHashMap = (*HashSet)(nil)
+
+// HashSet is a concurrent-safe TxHashMap backed by a table dedicated to
+// hashes; see the notes at the top of this file. Like SwissMap it ignores
+// duplicate hashes by default.
+type HashSet struct {
+	mu         mapLock
+	t          hashSetTable
+	length     atomic.Int64
+	frozen     atomic.Bool
+	duplicates duplicateHandler[chainhash.Hash]
+}
+
+// NewHashSet creates a new HashSet with room for length hashes.
+//
+// Params:
+//   - length: The number of hashes to preallocate room for.
+//
+// Returns:
+//   - *HashSet: A pointer to the newly created HashSet instance.
+func NewHashSet(length uint32) *HashSet {
+	return &HashSet{
+		t:          newHashSetTable(length),
+		duplicates: duplicateHandler[chainhash.Hash]{policy: DuplicateIgnore},
+	}
+}
+
+// SetDuplicatePolicy sets what Put and PutMulti do with hashes already in the
+// set; see DuplicatePolicy. onDuplicate is only used with DuplicateCallback.
+// Must be called before the set is shared between goroutines.
+func (s *HashSet) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.duplicates.set(policy, onDuplicate)
+}
+
+// Exists checks if the given hash exists in the set.
+func (s *HashSet) Exists(hash chainhash.Hash) bool {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return s.t.has(hash)
+}
+
+// Get reports whether the given hash exists in the set.
+//
+// Returns:
+//   - uint64: Always 0, as the set stores no values.
+//   - bool: True if the hash was found in the set, false otherwise.
+func (s *HashSet) Get(hash chainhash.Hash) (uint64, bool) {
+	return 0, s.Exists(hash)
+}
+
+// Put adds a hash to the set; an existing hash is handled by the duplicate
+// policy (DuplicateIgnore by default).
+//
+// Params:
+//   - hash: The hash to add to the set.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error of the duplicate policy.
+func (s *HashSet) Put(hash chainhash.Hash) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.putUnlocked(hash)
+}
+
+// PutMulti adds multiple hashes to the set under a single write lock.
+//
+// Params:
+//   - hashes: A slice of hashes to add to the set.
+//
+// Returns:
+//   - error: ErrMapFrozen, or the first error of the duplicate policy.
+func (s *HashSet) PutMulti(hashes []chainhash.Hash) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hash := range hashes {
+		if err := s.putUnlocked(hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a hash from the set.
+//
+// Params:
+//   - hash: The hash to remove from the set.
+//
+// Returns:
+//   - error: ErrMapFrozen if the set is frozen; deleting a missing hash is not
+//     an error.
+func (s *HashSet) Delete(hash chainhash.Hash) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.t.remove(hash) {
+		debugAssertLength(s.length.Add(-1))
+	}
+
+	return nil
+}
+
+// Length returns the number of hashes in the set.
+func (s *HashSet) Length() int {
+	return int(s.length.Load())
+}
+
+// Keys returns all hashes in the set, in no particular order.
+func (s *HashSet) Keys() []chainhash.Hash {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	keys := make([]chainhash.Hash, 0, s.t.count)
+
+	s.t.iter(func(hash chainhash.Hash) bool {
+		keys = append(keys, hash)
+		return false
+	})
+
+	return keys
+}
+
+// Iter calls f with every hash and a value of 0, until f returns true.
+func (s *HashSet) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	s.t.iter(func(hash chainhash.Hash) bool {
+		return f(hash, 0)
+	})
+}
+
+// Freeze marks the set read-only. See the lifecycle notes in freeze.go.
+func (s *HashSet) Freeze() { s.frozen.Store(true) }
+
+// Clear empties the set, keeping its slots, and un-freezes it for reuse. It
+// must not run concurrently with other operations on the set.
+func (s *HashSet) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.t.reset()
+	s.length.Store(0)
+	s.frozen.Store(false)
+}
+
+// putUnlocked adds hash, applying the duplicate policy if it already exists.
+// The caller must hold the write lock.
+func (s *HashSet) putUnlocked(hash chainhash.Hash) error {
+	debugAssertHash(hash)
+
+	if s.t.has(hash) {
+		_, _, err := s.duplicates.resolve(hash, 0, 0)
+		return err
+	}
+
+	s.t.insert(hash)
+	debugAssertLength(s.length.Add(1))
+
+	return nil
+}
+
+// check that SplitHashSet implements TxHashMap
+var _ TxHashMap = (*SplitHashSet)(nil)
+
+// SplitHashSet spreads hashes over HashSet buckets, so that no single table
+// has to grow, or be locked, as a whole.
+type SplitHashSet struct {
+	m           []*HashSet
+	nrOfBuckets uint16
+}
+
+// NewSplitHashSet creates a new SplitHashSet with room for length hashes.
+//
+// Params:
+//   - length: The number of hashes to preallocate room for.
+//   - buckets: Optionally the number of buckets, 1024 by default.
+//
+// Returns:
+//   - *SplitHashSet: A pointer to the newly created SplitHashSet instance.
+func NewSplitHashSet(length uint32, buckets ...uint16) *SplitHashSet {
+	useBuckets := uint16(1024)
+	if len(buckets) > 0 {
+		useBuckets = buckets[0]
+	}
+
+	s := &SplitHashSet{
+		m:           make([]*HashSet, int(useBuckets)+1),
+		nrOfBuckets: useBuckets,
+	}
+
+	// the same 20% headroom as NewSplitSwissMapUint64
+	perBucket := (length + length/5) / uint32(useBuckets)
+
+	for i := uint16(0); i <= useBuckets; i++ {
+		s.m[i] = NewHashSet(perBucket)
+	}
+
+	return s
+}
+
+// Buckets returns the highest bucket index; buckets 0..Buckets() inclusive exist.
+func (g *SplitHashSet) Buckets() uint16 {
+	return g.nrOfBuckets
+}
+
+// SetDuplicatePolicy sets the duplicate policy of every bucket.
+func (g *SplitHashSet) SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash]) {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].SetDuplicatePolicy(policy, onDuplicate)
+	}
+}
+
+// Exists checks if the given hash exists in the set.
+func (g *SplitHashSet) Exists(hash chainhash.Hash) bool {
+	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Exists(hash)
+}
+
+// Get reports whether the given hash exists in the set, with a value of 0.
+func (g *SplitHashSet) Get(hash chainhash.Hash) (uint64, bool) {
+	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Get(hash)
+}
+
+// Put adds a hash to its bucket.
+func (g *SplitHashSet) Put(hash chainhash.Hash) error {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash)
+}
+
+// PutMulti adds multiple hashes, taking the write lock of each bucket once.
+func (g *SplitHashSet) PutMulti(hashes []chainhash.Hash) error {
+	for bucket, positions := range bucketGroups(hashes, g.nrOfBuckets) {
+		batch := make([]chainhash.Hash, len(positions))
+		for i, p := range positions {
+			batch[i] = hashes[p]
+		}
+
+		b, err := bucketAt(g.m, bucket)
+		if err != nil {
+			return err
+		}
+
+		if err = b.PutMulti(batch); err != nil {
+			return fmt.Errorf("failed to put multi in bucket %d: %w", bucket, err)
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a hash from its bucket.
+func (g *SplitHashSet) Delete(hash chainhash.Hash) error {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(hash)
+}
+
+// Length returns the number of hashes in the set.
+func (g *SplitHashSet) Length() int {
+	length := 0
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		length += g.m[i].Length()
+	}
+
+	return length
+}
+
+// Keys returns all hashes in the set, one bucket at a time.
+func (g *SplitHashSet) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, g.Length())
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		keys = append(keys, g.m[i].Keys()...)
+	}
+
+	return keys
+}
+
+// Iter iterates over the buckets in order. Stops iterating if f returns true.
+func (g *SplitHashSet) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
+	}
+}
+
+// Freeze freezes every bucket. See the lifecycle notes in freeze.go.
+func (g *SplitHashSet) Freeze() {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].Freeze()
+	}
+}
+
+// Clear empties and un-freezes every bucket.
+func (g *SplitHashSet) Clear() {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].Clear()
+	}
+}
+
+// CheckInvariants verifies the tracked length against the table, and the
+// count of the table against its occupied slots. See InvariantChecker.
+func (s *HashSet) CheckInvariants() error {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	occupied := 0
+
+	for _, c := range s.t.ctrl {
+		if c != 0 {
+			occupied++
+		}
+	}
+
+	if s.t.count != occupied {
+		return fmt.Errorf("%w: table count %d, %d slots occupied", ErrInvariantViolation, s.t.count, occupied)
+	}
+
+	return checkLength(s.length.Load(), s.t.count)
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *SplitHashSet) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
@@ -0,0 +1,166 @@
+package txmap
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomHash returns a uniformly random hash from r.
+func randomHash(r *rand.Rand) chainhash.Hash {
+	var h chainhash.Hash
+
+	_, _ = r.Read(h[:])
+
+	return h
+}
+
+// TestHashSetAgainstMap runs random puts and deletes against a HashSet and a
+// Go map, growing the set from its minimum size.
+func TestHashSetAgainstMap(t *testing.T) {
+	r := rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test data
+	s := NewHashSet(0)
+	want := make(map[chainhash.Hash]struct{})
+
+	pool := make([]chainhash.Hash, 5000)
+	for i := range pool {
+		pool[i] = randomHash(r)
+	}
+
+	for range 50_000 {
+		hash := pool[r.Intn(len(pool))]
+
+		if r.Intn(3) == 0 {
+			require.NoError(t, s.Delete(hash))
+			delete(want, hash)
+		} else {
+			require.NoError(t, s.Put(hash))
+			want[hash] = struct{}{}
+		}
+	}
+
+	require.Equal(t, len(want), s.Length())
+
+	for _, hash := range pool {
+		_, ok := want[hash]
+		assert.Equal(t, ok, s.Exists(hash))
+	}
+
+	assert.ElementsMatch(t, keysOf(want), s.Keys())
+}
+
+// TestHashSetSequentialHashes tests hashes that differ only in bytes 0 and 1,
+// like the ones hashN produces.
+func TestHashSetSequentialHashes(t *testing.T) {
+	s := NewHashSet(0)
+
+	for i := range 3000 {
+		require.NoError(t, s.Put(hashN(i)))
+	}
+
+	for i := 0; i < 3000; i += 3 {
+		require.NoError(t, s.Delete(hashN(i)))
+	}
+
+	assert.Equal(t, 2000, s.Length())
+
+	for i := range 3000 {
+		assert.Equal(t, i%3 != 0, s.Exists(hashN(i)), i)
+	}
+}
+
+// TestHashSetLifecycle tests duplicates, Freeze and Clear on both sets.
+func TestHashSetLifecycle(t *testing.T) {
+	for name, s := range map[string]interface {
+		TxHashMap
+		SetDuplicatePolicy(policy DuplicatePolicy, onDuplicate DuplicateFunc[chainhash.Hash])
+	}{
+		"HashSet":      NewHashSet(10),
+		"SplitHashSet": NewSplitHashSet(10, 4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, s.PutMulti([]chainhash.Hash{hashN(1), hashN(2), hashN(1)}))
+			assert.Equal(t, 2, s.Length())
+
+			value, ok := s.Get(hashN(2))
+			assert.True(t, ok)
+			assert.Equal(t, uint64(0), value)
+
+			s.SetDuplicatePolicy(DuplicateError, nil)
+			require.ErrorIs(t, s.Put(hashN(1)), ErrHashAlreadyExists)
+
+			s.Freeze()
+			require.ErrorIs(t, s.Put(hashN(3)), ErrMapFrozen)
+			require.ErrorIs(t, s.Delete(hashN(1)), ErrMapFrozen)
+			assert.True(t, s.Exists(hashN(1)))
+
+			s.Clear()
+			assert.Equal(t, 0, s.Length())
+			assert.False(t, s.Exists(hashN(1)))
+			require.NoError(t, s.Put(hashN(3)))
+		})
+	}
+}
+
+// keysOf returns the keys of m.
+func keysOf(m map[chainhash.Hash]struct{}) []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// BenchmarkHashSetMemoryPerEntry compares the heap used per hash by the
+// hash-only maps, grown from empty and preallocated for all hashes.
+func BenchmarkHashSetMemoryPerEntry(b *testing.B) {
+	impls := []struct {
+		name string
+		new  func(length uint32) TxHashMap
+	}{
+		{"HashSet", func(length uint32) TxHashMap { return NewHashSet(length) }},
+		{"SwissMap", func(length uint32) TxHashMap { return NewSwissMap(length) }},
+		{"NativeMap", func(length uint32) TxHashMap { return NewNativeMap(length) }},
+	}
+
+	for _, n := range []int{100_000, 1_000_000} {
+		hashes := getTestHashes(n)
+
+		for _, impl := range impls {
+			for _, prealloc := range []bool{false, true} {
+				length := uint32(0)
+				if prealloc {
+					length = uint32(n) //nolint:gosec // G115 n is a small constant
+				}
+
+				b.Run(fmt.Sprintf("%s/%d/prealloc=%t", impl.name, n, prealloc), func(b *testing.B) {
+					benchmarkTxHashMapMemory(b, hashes, func() TxHashMap { return impl.new(length) })
+				})
+			}
+		}
+	}
+}
+
+// benchmarkTxHashMapMemory reports the heap used per hash by the maps
+// returned by newMap.
+func benchmarkTxHashMapMemory(b *testing.B, hashes []chainhash.Hash, newMap func() TxHashMap) {
+	var total heapUsage
+
+	for i := 0; i < b.N; i++ {
+		usage := measureHeap(func() interface{} {
+			m := newMap()
+			_ = m.PutMulti(hashes)
+
+			return m
+		})
+
+		total.inuse += usage.inuse
+	}
+
+	b.ReportMetric(float64(total.inuse)/(float64(b.N)*float64(len(hashes))), "inuse-B/entry")
+}
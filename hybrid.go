@@ -0,0 +1,460 @@
+package txmap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/dolthub/swiss"
+)
+
+// Hybrid buckets
+//
+// A UTXO-style map is written almost exclusively in the buckets holding recent
+// transactions, while the buckets holding historical ones only serve reads.
+// For those stabilized buckets the RWMutex.RLock of the split maps is pure
+// overhead: every read writes the lock's reader counter, and under many cores
+// that cache line ping-pongs between CPUs (see the notes in freeze.go).
+//
+// HybridSplitMap keeps every bucket as a RWMutex-guarded swiss map, but once a
+// bucket has seen no write for the configured quiet period it is promoted: the
+// swiss map is published through an atomic pointer as an immutable table, and
+// reads of that bucket load the pointer instead of taking the lock. The first
+// write to a promoted bucket demotes it again by copying the table into a new
+// mutable map, so readers still holding the old table never observe a write.
+//
+// Promotion only happens through PromoteQuiet, called e.g. from a ticker or
+// by AutoFreezeMap. The read path never promotes: it would read the clock on
+// every locked read, and with a short quiet period a mixed load would promote
+// a bucket on a read and copy it back on the next write, making every write
+// O(bucket size). Demotion copies the whole bucket, so the quiet period, and
+// the period between PromoteQuiet calls, should be long compared to the gaps
+// between writes to a bucket that is still active; BenchmarkHybridSplitMapMixed
+// shows the cost of getting it wrong.
+
+// check that HybridSplitMap implements TxMap
+var _ TxMap = (*HybridSplitMap)(nil)
+
+// HybridSplitMap is a split map whose buckets promote themselves to lock-free,
+// immutable tables after a period without writes. See the notes at the top of
+// this file.
+type HybridSplitMap struct {
+	m           []*hybridBucket
+	nrOfBuckets uint16
+	quiet       time.Duration
+	frozen      atomic.Bool
+}
+
+// hybridBucket is a single bucket of a HybridSplitMap. m is the current
+// contents and is only accessed under mu; table is nil while the bucket is
+// mutable, and points to m once the bucket is promoted, after which m is never
+// written again. writes counts the writes for the policy of AutoFreezeMap,
+// which resets it, and demotions the copies made by writes to the table.
+type hybridBucket struct {
+	mu        sync.RWMutex
+	m         *swiss.Map[chainhash.Hash, uint64]
+	table     atomic.Pointer[swiss.Map[chainhash.Hash, uint64]]
+	lastWrite atomic.Int64
+	length    atomic.Int64
+	writes    atomic.Uint64
+	demotions atomic.Uint64
+}
+
+// NewHybridSplitMap creates a new HybridSplitMap with the specified initial
+// length, preallocating every bucket like NewSplitSwissMapUint64.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - quiet: How long a bucket must go without writes before PromoteQuiet
+//     promotes it to an immutable table. Zero or less makes PromoteQuiet
+//     promote every mutable bucket.
+//   - buckets: Optional number of buckets, 1024 by default.
+//
+// Returns:
+//   - *HybridSplitMap: A pointer to the newly created HybridSplitMap instance.
+func NewHybridSplitMap(length uint32, quiet time.Duration, buckets ...uint16) *HybridSplitMap {
+	useBuckets := uint16(1024)
+	if len(buckets) > 0 {
+		useBuckets = buckets[0]
+	}
+
+	g := &HybridSplitMap{
+		m:           make([]*hybridBucket, int(useBuckets)+1),
+		nrOfBuckets: useBuckets,
+		quiet:       quiet,
+	}
+
+	perBucket := (length + length/5) / uint32(g.nrOfBuckets)
+	now := time.Now().UnixNano()
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		b := &hybridBucket{m: swiss.NewMap[chainhash.Hash, uint64](perBucket)}
+		b.lastWrite.Store(now)
+		g.m[i] = b
+	}
+
+	return g
+}
+
+// Buckets returns the highest bucket index; buckets 0..Buckets() inclusive exist.
+func (g *HybridSplitMap) Buckets() uint16 {
+	return g.nrOfBuckets
+}
+
+// Promoted returns the number of buckets currently served as immutable tables.
+func (g *HybridSplitMap) Promoted() int {
+	promoted := 0
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		if g.m[i].table.Load() != nil {
+			promoted++
+		}
+	}
+
+	return promoted
+}
+
+// PromoteQuiet promotes every bucket that has gone without writes for the
+// quiet period, without waiting for a read to do it.
+//
+// Returns:
+//   - int: The number of buckets promoted by this call.
+func (g *HybridSplitMap) PromoteQuiet() int {
+	promoted := 0
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		if g.m[i].promote(g.quiet, true) {
+			promoted++
+		}
+	}
+
+	return promoted
+}
+
+// Exists checks if the given hash exists in the map.
+func (g *HybridSplitMap) Exists(hash chainhash.Hash) bool {
+	_, ok := g.Get(hash)
+	return ok
+}
+
+// Get retrieves the value associated with the given hash from the map. Reads
+// of promoted buckets take no lock.
+func (g *HybridSplitMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return g.bucket(hash).get(hash)
+}
+
+// Keys returns all hashes in the map. The order of keys is not guaranteed.
+func (g *HybridSplitMap) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, g.Length())
+
+	g.Iter(func(hash chainhash.Hash, _ uint64) bool {
+		keys = append(keys, hash)
+		return false
+	})
+
+	return keys
+}
+
+// Length returns the number of hashes in the map.
+func (g *HybridSplitMap) Length() int {
+	length := int64(0)
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		length += g.m[i].length.Load()
+	}
+
+	return int(length)
+}
+
+// Iter iterates over all key-value pairs in the map, bucket by bucket. Stops
+// iterating if f returns true. Mutable buckets are read-locked while they are
+// iterated, so f must not write to the map.
+func (g *HybridSplitMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
+	}
+}
+
+// Put adds a new hash with an associated uint64 value to the map.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen, or ErrHashAlreadyExists if the
+//     hash is already in the map.
+func (g *HybridSplitMap) Put(hash chainhash.Hash, n uint64) error {
+	if g.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.m.Has(hash) {
+		return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+	}
+
+	b.writable().Put(hash, n)
+	b.length.Add(1)
+
+	return nil
+}
+
+// PutMulti adds multiple hashes with an associated uint64 value to the map,
+// stopping at the first error.
+func (g *HybridSplitMap) PutMulti(hashes []chainhash.Hash, n uint64) error {
+	for _, hash := range hashes {
+		if err := g.Put(hash, n); err != nil {
+			return fmt.Errorf("failed to put multi in bucket %d: %w", Bytes2Uint16Buckets(hash, g.nrOfBuckets), err)
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value associated with the given hash in the map. It will
+// error out if the hash does not exist.
+func (g *HybridSplitMap) Set(hash chainhash.Hash, value uint64) error {
+	ok, err := g.SetIfExists(hash, value)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return nil
+}
+
+// SetIfExists updates the value associated with the given hash if it exists,
+// and reports whether it did.
+func (g *HybridSplitMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if g.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.m.Has(hash) {
+		return false, nil
+	}
+
+	b.writable().Put(hash, value)
+
+	return true, nil
+}
+
+// SetIfNotExists adds the hash with the given value if it does not exist yet,
+// and reports whether it was added.
+func (g *HybridSplitMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if g.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.m.Has(hash) {
+		return false, nil
+	}
+
+	b.writable().Put(hash, value)
+	b.length.Add(1)
+
+	return true, nil
+}
+
+// Delete removes a hash from the map.
+//
+// Returns:
+//   - error: ErrMapFrozen if the map is frozen, or ErrHashDoesNotExist if the
+//     hash is not in the map.
+func (g *HybridSplitMap) Delete(hash chainhash.Hash) error {
+	if g.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.m.Has(hash) {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	b.writable().Delete(hash)
+	b.length.Add(-1)
+
+	return nil
+}
+
+// Freeze marks the map read-only and promotes every bucket, whether or not it
+// has been quiet, so all reads become lock-free. See the lifecycle notes in
+// freeze.go.
+func (g *HybridSplitMap) Freeze() {
+	g.frozen.Store(true)
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].promote(0, false)
+	}
+}
+
+// FreezeReadOnly freezes every bucket and returns a read-only view of the map.
+func (g *HybridSplitMap) FreezeReadOnly() ReadOnlyTxMap { return freezeReadOnly(g) }
+
+// Clear empties every bucket, demoting the promoted ones, and un-freezes the
+// map for reuse. Not safe for concurrent use.
+func (g *HybridSplitMap) Clear() {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].clear()
+	}
+
+	g.frozen.Store(false)
+}
+
+// bucket returns the bucket hash belongs to.
+func (g *HybridSplitMap) bucket(hash chainhash.Hash) *hybridBucket {
+	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
+
+	return g.m[bucket]
+}
+
+// get looks hash up in the table if the bucket is promoted, and otherwise
+// under the read lock.
+func (b *hybridBucket) get(hash chainhash.Hash) (uint64, bool) {
+	if table := b.table.Load(); table != nil {
+		return table.Get(hash)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.m.Get(hash)
+}
+
+// iter calls f for every entry of the bucket until f returns true.
+func (b *hybridBucket) iter(f func(hash chainhash.Hash, value uint64) bool) {
+	if table := b.table.Load(); table != nil {
+		table.Iter(f)
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	b.m.Iter(f)
+}
+
+// promote publishes the bucket as an immutable table if it is mutable and has
+// not been written for quiet. With try set it gives up instead of waiting
+// when the lock is held, so PromoteQuiet never queues behind a writer.
+func (b *hybridBucket) promote(quiet time.Duration, try bool) bool {
+	if b.table.Load() != nil || time.Since(time.Unix(0, b.lastWrite.Load())) < quiet {
+		return false
+	}
+
+	if try {
+		if !b.mu.TryLock() {
+			return false
+		}
+	} else {
+		b.mu.Lock()
+	}
+
+	defer b.mu.Unlock()
+
+	if b.table.Load() != nil || time.Since(time.Unix(0, b.lastWrite.Load())) < quiet {
+		return false
+	}
+
+	b.table.Store(b.m)
+
+	return true
+}
+
+// writable records a write and returns the map to apply it to, demoting the
+// bucket first if it is promoted. The caller must hold the write lock.
+func (b *hybridBucket) writable() *swiss.Map[chainhash.Hash, uint64] {
+	b.lastWrite.Store(time.Now().UnixNano())
+	b.writes.Add(1)
+
+	if b.table.Load() == nil {
+		return b.m
+	}
+
+	b.demotions.Add(1)
+
+	m := swiss.NewMap[chainhash.Hash, uint64](uint32(b.m.Count())) //nolint:gosec // bucket sizes fit in uint32
+
+	b.m.Iter(func(hash chainhash.Hash, value uint64) bool {
+		m.Put(hash, value)
+		return false
+	})
+
+	b.m = m
+	b.table.Store(nil)
+
+	return m
+}
+
+// clear empties the bucket, replacing the map instead of clearing it if it is
+// still published as a table.
+func (b *hybridBucket) clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.table.Load() != nil {
+		b.m = swiss.NewMap[chainhash.Hash, uint64](uint32(b.m.Count())) //nolint:gosec // bucket sizes fit in uint32
+		b.table.Store(nil)
+	} else {
+		b.m.Clear()
+	}
+
+	b.length.Store(0)
+	b.lastWrite.Store(time.Now().UnixNano())
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *HybridSplitMap) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
+
+// CheckInvariants verifies the tracked length and that a promoted bucket
+// publishes its current map.
+func (b *hybridBucket) CheckInvariants() error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if table := b.table.Load(); table != nil && table != b.m {
+		return fmt.Errorf("%w: published table is not the current map", ErrInvariantViolation)
+	}
+
+	return checkLength(b.length.Load(), b.m.Count())
+}
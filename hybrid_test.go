@@ -0,0 +1,187 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// promotingHybrid is a HybridSplitMap that promotes every mutable bucket
+// before each read, so that the reads and writes of a test hit promoted
+// buckets as often as possible.
+type promotingHybrid struct {
+	*HybridSplitMap
+}
+
+// Exists promotes the buckets and checks if the hash exists.
+func (p promotingHybrid) Exists(hash chainhash.Hash) bool {
+	p.PromoteQuiet()
+	return p.HybridSplitMap.Exists(hash)
+}
+
+// Get promotes the buckets and retrieves the value of the hash.
+func (p promotingHybrid) Get(hash chainhash.Hash) (uint64, bool) {
+	p.PromoteQuiet()
+	return p.HybridSplitMap.Get(hash)
+}
+
+// TestHybridSplitMap runs the TxMap conformance tests against a HybridSplitMap
+// that promotes buckets before every read and one that never does.
+func TestHybridSplitMap(t *testing.T) {
+	t.Run("promoting", func(t *testing.T) {
+		testTxMap(t, promotingHybrid{NewHybridSplitMap(100, 0, 16)})
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		testTxMap(t, NewHybridSplitMap(100, time.Hour, 16))
+	})
+}
+
+// TestHybridSplitMapPromotion tests that quiet buckets are promoted, that a
+// write demotes its bucket without changing what older tables see, and that
+// Freeze and Clear promote and demote everything.
+func TestHybridSplitMapPromotion(t *testing.T) {
+	m := NewHybridSplitMap(100, 10*time.Millisecond, 4)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, m.Put(hashN(i), uint64(i)))
+	}
+
+	assert.Equal(t, 0, m.PromoteQuiet())
+	assert.Equal(t, 0, m.Promoted())
+
+	time.Sleep(20 * time.Millisecond)
+
+	// reads never promote
+	v, ok := m.Get(hashN(1))
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), v)
+	assert.Equal(t, 0, m.Promoted())
+
+	assert.Equal(t, 5, m.PromoteQuiet())
+	assert.Equal(t, 5, m.Promoted())
+
+	bucket := m.bucket(hashN(1))
+	table := bucket.table.Load()
+
+	require.NoError(t, m.Set(hashN(1), 1000))
+	assert.Equal(t, 4, m.Promoted())
+
+	v, _ = m.Get(hashN(1))
+	assert.Equal(t, uint64(1000), v)
+
+	v, _ = table.Get(hashN(1))
+	assert.Equal(t, uint64(1), v, "a published table must never change")
+
+	// the demoted bucket is not promoted again before the quiet period
+	assert.Equal(t, 0, m.PromoteQuiet())
+	assert.Equal(t, 4, m.Promoted())
+
+	m.Freeze()
+	assert.Equal(t, 5, m.Promoted())
+	require.ErrorIs(t, m.Put(hashN(200), 1), ErrMapFrozen)
+
+	m.Clear()
+	assert.Equal(t, 0, m.Promoted())
+	assert.Equal(t, 0, m.Length())
+	require.NoError(t, m.Put(hashN(1), 1))
+}
+
+// TestHybridSplitMapConcurrent tests reads racing writes that repeatedly
+// promote and demote the same buckets; run with -race.
+func TestHybridSplitMapConcurrent(t *testing.T) {
+	m := promotingHybrid{NewHybridSplitMap(1000, 0, 4)}
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, m.Put(hashN(i), uint64(i)))
+	}
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				v, ok := m.Get(hashN(i))
+				assert.True(t, ok)
+				assert.Equal(t, uint64(i), v%10000)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for i := w; i < 1000; i += 4 {
+				assert.NoError(t, m.Set(hashN(i), uint64(10000+i)))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1000, m.Length())
+	assert.Len(t, m.Keys(), 1000)
+}
+
+// BenchmarkHybridSplitMapMixed measures a load of nine reads to one write
+// over 64 buckets of 1,000 entries, with PromoteQuiet called every 100
+// operations. With a zero quiet period every write to a promoted bucket
+// copies it back; with a quiet period longer than the gaps between writes
+// only the PromoteQuiet scans add to the cost of the plain split map.
+func BenchmarkHybridSplitMapMixed(b *testing.B) {
+	const (
+		buckets = 64
+		entries = buckets * 1000
+	)
+
+	for _, quiet := range []time.Duration{0, time.Hour} {
+		b.Run("quiet="+quiet.String(), func(b *testing.B) {
+			m := NewHybridSplitMap(entries, quiet, buckets)
+			for i := range entries {
+				require.NoError(b, m.Put(hashN(i), uint64(i))) //nolint:gosec // G115 test values
+			}
+
+			i := 0
+
+			for b.Loop() {
+				if i%100 == 0 {
+					m.PromoteQuiet()
+				}
+
+				if i%10 == 0 {
+					_ = m.Set(hashN(i%entries), uint64(i)) //nolint:gosec // G115 test values
+				} else {
+					m.Get(hashN(i % entries))
+				}
+
+				i++
+			}
+		})
+	}
+
+	b.Run("SplitSwissMapUint64", func(b *testing.B) {
+		m := NewSplitSwissMapUint64(entries, buckets)
+		for i := range entries {
+			require.NoError(b, m.Put(hashN(i), uint64(i))) //nolint:gosec // G115 test values
+		}
+
+		i := 0
+
+		for b.Loop() {
+			if i%10 == 0 {
+				_ = m.Set(hashN(i%entries), uint64(i)) //nolint:gosec // G115 test values
+			} else {
+				m.Get(hashN(i % entries))
+			}
+
+			i++
+		}
+	})
+}
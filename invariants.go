@@ -0,0 +1,187 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvariantViolation is returned by CheckInvariants when a map's internal
+// bookkeeping does not match its contents.
+var ErrInvariantViolation = errors.New("tx map invariant violated")
+
+// InvariantChecker is implemented by every map in this package.
+type InvariantChecker interface {
+	// CheckInvariants verifies that the tracked length of the map, and of each
+	// of its buckets, matches the number of entries actually stored, that no
+	// counter is negative and that no bucket is missing. It returns an error
+	// wrapping ErrInvariantViolation describing the first violation.
+	//
+	// The lock-based maps check each bucket under its read lock; the lock-free
+	// maps must not be written to while the check runs.
+	CheckInvariants() error
+}
+
+// Compile-time checks that every concrete map type can check its invariants.
+var (
+	_ InvariantChecker = (*SwissMap)(nil)
+	_ InvariantChecker = (*SwissMapUint64)(nil)
+	_ InvariantChecker = (*SwissLockFreeMapUint64)(nil)
+	_ InvariantChecker = (*SplitSwissMap)(nil)
+	_ InvariantChecker = (*SplitSwissMapUint64)(nil)
+	_ InvariantChecker = (*SplitSwissLockFreeMapUint64)(nil)
+	_ InvariantChecker = (*NativeMap)(nil)
+	_ InvariantChecker = (*NativeMapUint64)(nil)
+	_ InvariantChecker = (*NativeLockFreeMapUint64)(nil)
+	_ InvariantChecker = (*NativeSplitMap)(nil)
+	_ InvariantChecker = (*NativeSplitMapUint64)(nil)
+	_ InvariantChecker = (*NativeSplitLockFreeMapUint64)(nil)
+	_ InvariantChecker = (*HashSet)(nil)
+	_ InvariantChecker = (*SplitHashSet)(nil)
+)
+
+// CheckInvariantsEvery runs m.CheckInvariants every interval until ctx is
+// done, reporting violations to onViolation. It is meant for debug builds and
+// soak tests, where length-tracking drift should be caught close to the
+// operation that caused it; it blocks, so run it in its own goroutine.
+//
+// Params:
+//   - ctx: Stops the checks when done.
+//   - m: The map to check; lock-free maps must not be written to meanwhile.
+//   - interval: The time between checks.
+//   - onViolation: Called with every error returned by CheckInvariants.
+func CheckInvariantsEvery(ctx context.Context, m InvariantChecker, interval time.Duration, onViolation func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.CheckInvariants(); err != nil {
+				onViolation(err)
+			}
+		}
+	}
+}
+
+// checkLength compares a tracked length with the number of stored entries.
+func checkLength(tracked int64, actual int) error {
+	if tracked < 0 {
+		return fmt.Errorf("%w: negative length %d", ErrInvariantViolation, tracked)
+	}
+
+	if tracked != int64(actual) {
+		return fmt.Errorf("%w: tracked length %d, %d entries stored", ErrInvariantViolation, tracked, actual)
+	}
+
+	return nil
+}
+
+// checkBuckets checks buckets 0..nrOfBuckets of a split map.
+func checkBuckets[K uint16 | uint64, M interface {
+	comparable
+	InvariantChecker
+}](buckets []M, nrOfBuckets K,
+) error {
+	var missing M
+
+	if len(buckets) != int(nrOfBuckets)+1 {
+		return fmt.Errorf("%w: %d buckets, expected %d", ErrInvariantViolation, len(buckets), int(nrOfBuckets)+1)
+	}
+
+	for i := K(0); i <= nrOfBuckets; i++ {
+		bucket := buckets[i]
+		if bucket == missing {
+			return fmt.Errorf("%w: bucket %d is missing", ErrInvariantViolation, i)
+		}
+
+		if err := bucket.CheckInvariants(); err != nil {
+			return fmt.Errorf("bucket %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckInvariants verifies the tracked length. See InvariantChecker.
+func (s *SwissMap) CheckInvariants() error {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return checkLength(s.length.Load(), s.m.Count())
+}
+
+// CheckInvariants verifies the tracked length. See InvariantChecker.
+func (s *SwissMapUint64) CheckInvariants() error {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return checkLength(s.length.Load(), s.m.Count())
+}
+
+// CheckInvariants verifies the tracked length. See InvariantChecker.
+func (s *SwissLockFreeMapUint64) CheckInvariants() error {
+	return checkLength(int64(s.length.Load()), s.m.Count())
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *SplitSwissMap) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *SplitSwissMapUint64) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *SplitSwissLockFreeMapUint64) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
+
+// CheckInvariants verifies the tracked length. See InvariantChecker.
+func (s *NativeMap) CheckInvariants() error {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return checkLength(s.length.Load(), len(s.m))
+}
+
+// CheckInvariants verifies the tracked length. See InvariantChecker.
+func (s *NativeMapUint64) CheckInvariants() error {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return checkLength(s.length.Load(), len(s.m))
+}
+
+// CheckInvariants verifies the tracked length. See InvariantChecker.
+func (s *NativeLockFreeMapUint64) CheckInvariants() error {
+	return checkLength(int64(s.length.Load()), len(s.m))
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *NativeSplitMap) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *NativeSplitMapUint64) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
+
+// CheckInvariants verifies every bucket. See InvariantChecker.
+func (g *NativeSplitLockFreeMapUint64) CheckInvariants() error {
+	return checkBuckets(g.m, g.nrOfBuckets)
+}
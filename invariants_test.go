@@ -0,0 +1,129 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCheckInvariants runs a mixed workload on every map and checks that the
+// tracked lengths still match the contents.
+func TestCheckInvariants(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			for i := 0; i < 500; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i)))
+			}
+
+			require.Error(t, m.Put(hashN(1), 1))
+			require.Error(t, m.PutMulti([]chainhash.Hash{hashN(600), hashN(2)}, 1))
+
+			for i := 0; i < 500; i += 3 {
+				require.NoError(t, m.Delete(hashN(i)))
+			}
+
+			// deleting a missing hash must not drift the length
+			require.Error(t, m.Delete(hashN(0)))
+			require.NoError(t, m.(InvariantChecker).CheckInvariants())
+
+			_, err := m.SetIfNotExists(hashN(0), 1)
+			require.NoError(t, err)
+
+			require.NoError(t, m.(InvariantChecker).CheckInvariants())
+
+			m.Clear()
+			require.NoError(t, m.(InvariantChecker).CheckInvariants())
+		})
+	}
+
+	for name, factory := range txHashMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(1), hashN(2), hashN(1)}))
+			require.NoError(t, m.Delete(hashN(2)))
+			require.NoError(t, m.(InvariantChecker).CheckInvariants())
+
+			// deleting a missing hash must not drift the length
+			require.NoError(t, m.Delete(hashN(2)))
+			require.NoError(t, m.(InvariantChecker).CheckInvariants())
+			assert.Equal(t, 1, m.Length())
+		})
+	}
+
+	for name, factory := range uint64Impls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			for i := uint64(0); i < 500; i++ {
+				require.NoError(t, m.Put(i, i))
+			}
+
+			require.NoError(t, m.(InvariantChecker).CheckInvariants())
+		})
+	}
+}
+
+// TestCheckInvariantsViolations tests that length drift, a hash set table
+// count out of step with its slots and missing buckets are detected,
+// including by CheckInvariantsEvery.
+func TestCheckInvariantsViolations(t *testing.T) {
+	leaf := NewNativeMapUint64(8)
+	require.NoError(t, leaf.Put(hashN(1), 1))
+	leaf.length.Add(1)
+	require.ErrorIs(t, leaf.CheckInvariants(), ErrInvariantViolation)
+
+	leaf.length.Store(-1)
+	require.ErrorContains(t, leaf.CheckInvariants(), "negative length")
+
+	split := NewSplitSwissMapUint64(8, 4)
+	require.NoError(t, split.CheckInvariants())
+
+	split.m[2].length.Add(1)
+	require.ErrorContains(t, split.CheckInvariants(), "bucket 2")
+
+	simulateMissingBucket(split.m, 3)
+	split.m[2].length.Add(-1)
+	require.ErrorContains(t, split.CheckInvariants(), "bucket 3 is missing")
+
+	set := NewSplitHashSet(8, 4)
+	require.NoError(t, set.Put(hashN(1)))
+	require.NoError(t, set.CheckInvariants())
+
+	bucket := set.m[Bytes2Uint16Buckets(hashN(1), set.nrOfBuckets)]
+	bucket.length.Store(-1)
+	require.ErrorContains(t, set.CheckInvariants(), "negative length")
+
+	bucket.length.Store(1)
+	bucket.t.count++
+	require.ErrorContains(t, bucket.CheckInvariants(), "slots occupied")
+
+	bucket.t.count--
+	simulateMissingBucket(set.m, 3)
+	require.ErrorContains(t, set.CheckInvariants(), "bucket 3 is missing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	violations := make(chan error, 1)
+
+	go CheckInvariantsEvery(ctx, leaf, time.Millisecond, func(err error) {
+		select {
+		case violations <- err:
+		default:
+		}
+	})
+
+	select {
+	case err := <-violations:
+		assert.ErrorIs(t, err, ErrInvariantViolation)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no violation reported")
+	}
+
+	cancel()
+}
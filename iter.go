@@ -0,0 +1,60 @@
+package txmap
+
+import "github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+// Iteration semantics
+//
+// Iter on the lock-based maps (SwissMapUint64, NativeMapUint64, SwissMap,
+// NativeMap) holds the map's read lock for the whole iteration; the split
+// maps iterate their buckets one after another, holding the read lock of one
+// bucket at a time. This defines what a mutation during Iter does:
+//
+//   - A write from another goroutine to the bucket being iterated blocks
+//     until Iter has finished that bucket. Iter never observes a bucket in a
+//     half-modified state.
+//   - A write from another goroutine to another bucket of a split map runs
+//     concurrently: it is included if its bucket has not been iterated yet
+//     and skipped otherwise. Iter is therefore not an atomic snapshot across
+//     buckets; Freeze the map first if one is needed.
+//   - A write from inside f to the bucket being iterated deadlocks, since f
+//     runs under that bucket's read lock. Use SafeIter to write from f.
+//
+// Frozen maps take no locks, and no writes can happen, so Iter sees exactly
+// the frozen contents.
+//
+// The lock-free maps (Uint64 implementations) do not support concurrent
+// writers at all; mutating them during Iter, from f or from another goroutine,
+// is undefined, as with the underlying Go and dolthub/swiss maps.
+
+// SafeIter iterates over m like Iter, but copies each bucket under its read
+// lock and calls f on the copy after releasing the lock. f may therefore
+// write to m, including to the entry it is called for, and writers are only
+// blocked for the time it takes to copy a bucket.
+//
+// Each bucket is seen as it was when it was copied: writes made by f or by
+// other goroutines to a bucket that was already copied are not visited, and
+// writes to buckets not copied yet are. Maps that are not split are copied
+// as a single bucket, which costs 40 bytes per entry for the duration of the
+// call.
+//
+// Params:
+//   - m: The map to iterate over.
+//   - f: Called for every entry. Stops iterating if it returns true.
+func SafeIter(m ReadOnlyTxMap, f func(hash chainhash.Hash, value uint64) bool) {
+	var entries []KV
+
+	for _, bucket := range txMapBuckets(m) {
+		entries = entries[:0]
+
+		bucket.Iter(func(hash chainhash.Hash, value uint64) bool {
+			entries = append(entries, KV{Hash: hash, Value: value})
+			return false
+		})
+
+		for _, e := range entries {
+			if f(e.Hash, e.Value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSafeIter tests that f may update and delete entries of the map it is
+// iterating over, which would deadlock with Iter.
+func TestSafeIter(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			for i := 0; i < 1000; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i)))
+			}
+
+			visited := 0
+
+			SafeIter(m, func(hash chainhash.Hash, value uint64) bool {
+				visited++
+
+				if value%2 == 0 {
+					require.NoError(t, m.Delete(hash))
+				} else {
+					require.NoError(t, m.Set(hash, value*10))
+				}
+
+				return false
+			})
+
+			assert.Equal(t, 1000, visited)
+			assert.Equal(t, 500, m.Length())
+
+			v, ok := m.Get(hashN(7))
+			require.True(t, ok)
+			assert.Equal(t, uint64(70), v)
+
+			visited = 0
+
+			SafeIter(m, func(chainhash.Hash, uint64) bool {
+				visited++
+				return visited == 3
+			})
+
+			assert.Equal(t, 3, visited)
+		})
+	}
+}
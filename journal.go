@@ -0,0 +1,352 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that JournaledTxMap implements TxMap
+var _ TxMap = (*JournaledTxMap)(nil)
+
+var (
+	// ErrJournalTruncated is returned by SnapshotDiff when the journal entries
+	// needed to compute the diff have already been discarded by TruncateJournal.
+	ErrJournalTruncated = errors.New("change journal truncated past requested snapshot")
+
+	// ErrUnknownSnapshotID is returned by SnapshotDiff for an ID this map never
+	// handed out.
+	ErrUnknownSnapshotID = errors.New("unknown snapshot id")
+)
+
+// SnapshotID identifies a point in the change history of a JournaledTxMap.
+// IDs increase by one with every recorded change; the zero ID is the empty,
+// freshly created map.
+type SnapshotID uint64
+
+// ChangeOp is the kind of change described by a ChangeRecord.
+type ChangeOp uint8
+
+const (
+	// ChangeOpPut means the hash is present with ChangeRecord.Value.
+	ChangeOpPut ChangeOp = iota + 1
+
+	// ChangeOpDelete means the hash is no longer present.
+	ChangeOpDelete
+
+	// ChangeOpClear means every entry was removed. It is always the first
+	// record of a diff and carries no hash.
+	ChangeOpClear
+)
+
+// ChangeRecord is a single entry of a diff returned by SnapshotDiff.
+type ChangeRecord struct {
+	// ID is the journal position of the latest change this record reflects.
+	// The highest ID in a diff is the SnapshotID to pass to the next call.
+	ID SnapshotID
+
+	Op    ChangeOp
+	Hash  chainhash.Hash
+	Value uint64
+}
+
+// journalEntry is a single recorded change. Only the hash and the kind of
+// change are kept: diffs are resolved against the live map, which keeps the
+// journal small and makes the result independent of the order in which
+// concurrent writers appended their entries.
+type journalEntry struct {
+	id    SnapshotID
+	clear bool
+	hash  chainhash.Hash
+}
+
+// JournaledTxMap wraps a TxMap and journals every change made through it, so a
+// backup process can take a full snapshot once and afterwards ship only the
+// entries that changed since the previous snapshot.
+//
+// The journal grows with every write until TruncateJournal is called; call it
+// once a snapshot or diff has been durably stored.
+type JournaledTxMap struct {
+	m TxMap
+
+	// writeMu is held shared by every mutation and exclusively by Snapshot and
+	// SnapshotDiff, so those see the map and the journal at a single point.
+	writeMu sync.RWMutex
+
+	mu          sync.Mutex
+	lastID      SnapshotID
+	truncatedAt SnapshotID
+	journal     []journalEntry
+}
+
+// NewJournaledTxMap returns a JournaledTxMap that forwards every operation to m
+// and records the hashes touched by successful writes.
+//
+// Params:
+//   - m: The map to wrap. It should be empty, or its current contents must be
+//     captured with Snapshot before diffs are taken.
+//
+// Returns:
+//   - *JournaledTxMap: The wrapping map.
+func NewJournaledTxMap(m TxMap) *JournaledTxMap {
+	m = nonNilTxMap(m)
+
+	return &JournaledTxMap{m: m}
+}
+
+// Snapshot writes a full snapshot of the map to w (see Export) and returns the
+// SnapshotID it corresponds to. Writers are blocked while it runs.
+//
+// Params:
+//   - w: The destination stream.
+//
+// Returns:
+//   - SnapshotID: The ID to pass to SnapshotDiff for the next incremental backup.
+//   - error: Any error returned by Export.
+func (j *JournaledTxMap) Snapshot(w io.Writer) (SnapshotID, error) {
+	j.writeMu.Lock()
+	defer j.writeMu.Unlock()
+
+	if err := Export(w, j.m); err != nil {
+		return 0, err
+	}
+
+	return j.LastID(), nil
+}
+
+// SnapshotDiff returns the changes needed to bring a copy of the map taken at
+// since up to date. Changes to the same hash are coalesced into one record
+// holding its current state, ordered by the ID of the hash's latest change.
+// If the map was cleared after since, the diff starts with a ChangeOpClear
+// record and only covers changes made after the last Clear.
+//
+// Params:
+//   - since: The ID returned by Snapshot, or the highest record ID of a
+//     previous diff.
+//
+// Returns:
+//   - []ChangeRecord: The coalesced changes; empty if nothing changed.
+//   - error: ErrJournalTruncated if the journal no longer reaches back to since,
+//     ErrUnknownSnapshotID if since is in the future.
+func (j *JournaledTxMap) SnapshotDiff(since SnapshotID) ([]ChangeRecord, error) {
+	j.writeMu.Lock()
+	defer j.writeMu.Unlock()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if since < j.truncatedAt {
+		return nil, fmt.Errorf("%w: requested %d, journal starts after %d", ErrJournalTruncated, since, j.truncatedAt)
+	}
+
+	if since > j.lastID {
+		return nil, fmt.Errorf("%w: %d, latest is %d", ErrUnknownSnapshotID, since, j.lastID)
+	}
+
+	start := sort.Search(len(j.journal), func(i int) bool { return j.journal[i].id > since })
+
+	var (
+		records []ChangeRecord
+		latest  = make(map[chainhash.Hash]SnapshotID)
+	)
+
+	for _, e := range j.journal[start:] {
+		if e.clear {
+			records = append(records[:0], ChangeRecord{ID: e.id, Op: ChangeOpClear})
+			clear(latest)
+
+			continue
+		}
+
+		latest[e.hash] = e.id
+	}
+
+	changed := make([]ChangeRecord, 0, len(latest))
+
+	for hash, id := range latest {
+		if value, ok := j.m.Get(hash); ok {
+			changed = append(changed, ChangeRecord{ID: id, Op: ChangeOpPut, Hash: hash, Value: value})
+		} else {
+			changed = append(changed, ChangeRecord{ID: id, Op: ChangeOpDelete, Hash: hash})
+		}
+	}
+
+	sort.Slice(changed, func(a, b int) bool { return changed[a].ID < changed[b].ID })
+
+	return append(records, changed...), nil
+}
+
+// TruncateJournal discards the journal entries up to and including upTo,
+// releasing their memory. Diffs can afterwards only be taken from upTo or later.
+//
+// Params:
+//   - upTo: The oldest SnapshotID that still needs to be diffable.
+func (j *JournaledTxMap) TruncateJournal(upTo SnapshotID) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if upTo <= j.truncatedAt {
+		return
+	}
+
+	if upTo > j.lastID {
+		upTo = j.lastID
+	}
+
+	n := sort.Search(len(j.journal), func(i int) bool { return j.journal[i].id > upTo })
+	j.journal = append(j.journal[:0:0], j.journal[n:]...)
+	j.truncatedAt = upTo
+}
+
+// LastID returns the SnapshotID of the most recent recorded change.
+func (j *JournaledTxMap) LastID() SnapshotID {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.lastID
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (j *JournaledTxMap) Exists(hash chainhash.Hash) bool {
+	return j.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (j *JournaledTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return j.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (j *JournaledTxMap) Keys() []chainhash.Hash {
+	return j.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (j *JournaledTxMap) Length() int {
+	return j.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (j *JournaledTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	j.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map and journals it on success.
+func (j *JournaledTxMap) Put(hash chainhash.Hash, value uint64) error {
+	j.writeMu.RLock()
+	defer j.writeMu.RUnlock()
+
+	if err := j.m.Put(hash, value); err != nil {
+		return err
+	}
+
+	j.record(hash)
+
+	return nil
+}
+
+// PutMulti adds hashes to the wrapped map and journals them. On a partial
+// failure every hash is journaled; hashes that were not inserted resolve to
+// their unchanged state when a diff is taken.
+func (j *JournaledTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	j.writeMu.RLock()
+	defer j.writeMu.RUnlock()
+
+	err := j.m.PutMulti(hashes, value)
+	if errors.Is(err, ErrMapFrozen) {
+		return err
+	}
+
+	j.record(hashes...)
+
+	return err
+}
+
+// Set updates the value of an existing hash and journals it on success.
+func (j *JournaledTxMap) Set(hash chainhash.Hash, value uint64) error {
+	j.writeMu.RLock()
+	defer j.writeMu.RUnlock()
+
+	if err := j.m.Set(hash, value); err != nil {
+		return err
+	}
+
+	j.record(hash)
+
+	return nil
+}
+
+// SetIfExists updates the value of hash if it exists and journals it when it did.
+func (j *JournaledTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	j.writeMu.RLock()
+	defer j.writeMu.RUnlock()
+
+	ok, err := j.m.SetIfExists(hash, value)
+	if ok {
+		j.record(hash)
+	}
+
+	return ok, err
+}
+
+// SetIfNotExists adds hash if it does not exist yet and journals it when it was added.
+func (j *JournaledTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	j.writeMu.RLock()
+	defer j.writeMu.RUnlock()
+
+	ok, err := j.m.SetIfNotExists(hash, value)
+	if ok {
+		j.record(hash)
+	}
+
+	return ok, err
+}
+
+// Delete removes hash from the wrapped map and journals it on success.
+func (j *JournaledTxMap) Delete(hash chainhash.Hash) error {
+	j.writeMu.RLock()
+	defer j.writeMu.RUnlock()
+
+	if err := j.m.Delete(hash); err != nil {
+		return err
+	}
+
+	j.record(hash)
+
+	return nil
+}
+
+// Freeze freezes the wrapped map.
+func (j *JournaledTxMap) Freeze() {
+	j.m.Freeze()
+}
+
+// Clear empties the wrapped map and journals a clear marker, so diffs spanning
+// it start with a ChangeOpClear record instead of one delete per hash.
+func (j *JournaledTxMap) Clear() {
+	j.writeMu.RLock()
+	defer j.writeMu.RUnlock()
+
+	j.m.Clear()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.lastID++
+	j.journal = append(j.journal, journalEntry{id: j.lastID, clear: true})
+}
+
+// record appends a journal entry for each of hashes.
+func (j *JournaledTxMap) record(hashes ...chainhash.Hash) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, hash := range hashes {
+		j.lastID++
+		j.journal = append(j.journal, journalEntry{id: j.lastID, hash: hash})
+	}
+}
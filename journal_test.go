@@ -0,0 +1,147 @@
+package txmap
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyDiff applies the records of a SnapshotDiff to m.
+func applyDiff(t *testing.T, m TxMap, records []ChangeRecord) {
+	t.Helper()
+
+	for _, r := range records {
+		switch r.Op {
+		case ChangeOpPut:
+			updated, err := m.SetIfExists(r.Hash, r.Value)
+			require.NoError(t, err)
+
+			if !updated {
+				require.NoError(t, m.Put(r.Hash, r.Value))
+			}
+		case ChangeOpDelete:
+			if m.Exists(r.Hash) {
+				require.NoError(t, m.Delete(r.Hash))
+			}
+		case ChangeOpClear:
+			m.Clear()
+		}
+	}
+}
+
+// requireSameContents fails the test unless a and b hold the same entries.
+func requireSameContents(t *testing.T, a, b ReadOnlyTxMap) {
+	t.Helper()
+
+	require.Equal(t, a.Length(), b.Length())
+
+	a.Iter(func(hash chainhash.Hash, value uint64) bool {
+		v, ok := b.Get(hash)
+		require.True(t, ok, "missing %s", hash)
+		require.Equal(t, value, v)
+
+		return false
+	})
+}
+
+// TestJournaledTxMap tests the basic TxMap behavior of JournaledTxMap.
+func TestJournaledTxMap(t *testing.T) {
+	testTxMap(t, NewJournaledTxMap(NewSwissMapUint64(100)))
+}
+
+// TestJournaledTxMapSnapshotDiff tests that a full snapshot plus a diff
+// reproduces the live map.
+func TestJournaledTxMapSnapshotDiff(t *testing.T) {
+	m := NewJournaledTxMap(NewNativeSplitMapUint64(100))
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, m.Put(hashN(i), uint64(i))) //nolint:gosec // test sizes are small
+	}
+
+	var buf bytes.Buffer
+
+	id, err := m.Snapshot(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, SnapshotID(10), id)
+
+	backup := NewNativeMapUint64(100)
+	_, err = Import(context.Background(), backup, &buf, ImportOptions{})
+	require.NoError(t, err)
+
+	// nothing changed yet
+	records, err := m.SnapshotDiff(id)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	require.NoError(t, m.Set(hashN(1), 100))
+	require.NoError(t, m.Set(hashN(1), 101))
+	require.NoError(t, m.Delete(hashN(2)))
+	require.NoError(t, m.Put(hashN(20), 20))
+	require.NoError(t, m.Put(hashN(21), 21))
+	require.NoError(t, m.Delete(hashN(21)))
+
+	records, err = m.SnapshotDiff(id)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, ChangeRecord{ID: 12, Op: ChangeOpPut, Hash: hashN(1), Value: 101}, records[0])
+	assert.Equal(t, ChangeRecord{ID: 13, Op: ChangeOpDelete, Hash: hashN(2)}, records[1])
+	assert.Equal(t, m.LastID(), records[3].ID)
+
+	applyDiff(t, backup, records)
+	requireSameContents(t, m, backup)
+}
+
+// TestJournaledTxMapClear tests that a diff spanning a Clear starts with a
+// clear record and only carries later changes.
+func TestJournaledTxMapClear(t *testing.T) {
+	m := NewJournaledTxMap(NewNativeMapUint64(100))
+
+	require.NoError(t, m.Put(hashN(1), 1))
+	require.NoError(t, m.Put(hashN(2), 2))
+	m.Clear()
+	require.NoError(t, m.Put(hashN(3), 3))
+
+	records, err := m.SnapshotDiff(0)
+	require.NoError(t, err)
+	assert.Equal(t, []ChangeRecord{
+		{ID: 3, Op: ChangeOpClear},
+		{ID: 4, Op: ChangeOpPut, Hash: hashN(3), Value: 3},
+	}, records)
+}
+
+// TestJournaledTxMapTruncate tests TruncateJournal and the errors returned for
+// IDs outside the journal.
+func TestJournaledTxMapTruncate(t *testing.T) {
+	m := NewJournaledTxMap(NewNativeMapUint64(100))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.Put(hashN(i), uint64(i))) //nolint:gosec // test sizes are small
+	}
+
+	m.TruncateJournal(3)
+	assert.Len(t, m.journal, 2)
+
+	_, err := m.SnapshotDiff(2)
+	require.ErrorIs(t, err, ErrJournalTruncated)
+
+	_, err = m.SnapshotDiff(6)
+	require.ErrorIs(t, err, ErrUnknownSnapshotID)
+
+	records, err := m.SnapshotDiff(3)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+// TestJournaledTxMapFrozen tests that rejected writes are not journaled.
+func TestJournaledTxMapFrozen(t *testing.T) {
+	m := NewJournaledTxMap(NewNativeMapUint64(100))
+	m.Freeze()
+
+	require.ErrorIs(t, m.Put(hashN(1), 1), ErrMapFrozen)
+	require.ErrorIs(t, m.PutMulti([]chainhash.Hash{hashN(1), hashN(2)}, 1), ErrMapFrozen)
+	assert.Equal(t, SnapshotID(0), m.LastID())
+}
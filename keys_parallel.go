@@ -0,0 +1,90 @@
+package txmap
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// KeysParallel returns all hashes in the map like Keys, copying the buckets
+// from several goroutines. See splitKeysParallel.
+func (g *SplitSwissMap) KeysParallel(workers int) []chainhash.Hash {
+	return splitKeysParallel(g.m, g.nrOfBuckets, workers)
+}
+
+// KeysParallel returns all hashes in the map like Keys, copying the buckets
+// from several goroutines. See splitKeysParallel.
+func (g *SplitSwissMapUint64) KeysParallel(workers int) []chainhash.Hash {
+	return splitKeysParallel(g.m, g.nrOfBuckets, workers)
+}
+
+// KeysParallel returns all hashes in the map like Keys, copying the buckets
+// from several goroutines. See splitKeysParallel.
+func (g *NativeSplitMap) KeysParallel(workers int) []chainhash.Hash {
+	return splitKeysParallel(g.m, g.nrOfBuckets, workers)
+}
+
+// KeysParallel returns all hashes in the map like Keys, copying the buckets
+// from several goroutines. See splitKeysParallel.
+func (g *NativeSplitMapUint64) KeysParallel(workers int) []chainhash.Hash {
+	return splitKeysParallel(g.m, g.nrOfBuckets, workers)
+}
+
+// splitKeysParallel collects the keys of a split map. The result is sized up
+// front from the bucket lengths and every bucket is copied into its own range
+// of it, so the copies need no coordination; workers take the next uncopied
+// bucket from a shared counter, which balances uneven buckets.
+//
+// Like Keys, the result is not an atomic snapshot across buckets. A bucket
+// that grew after the result was sized contributes only as many keys as it had
+// then; the ranges of buckets that shrank are compacted away.
+func splitKeysParallel[M ReadOnlyTxMap](buckets []M, nrOfBuckets uint16, workers int) []chainhash.Hash {
+	n := int(nrOfBuckets) + 1
+	starts := make([]int, n+1)
+
+	for i := 0; i < n; i++ {
+		starts[i+1] = starts[i] + buckets[uint16(i)].Length() //nolint:gosec // i <= nrOfBuckets
+	}
+
+	keys := make([]chainhash.Hash, starts[n])
+	copied := make([]int, n)
+
+	var (
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+
+	for w := max(1, min(workers, n)); w > 0; w-- {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := int(next.Add(1) - 1); i < n; i = int(next.Add(1) - 1) {
+				dst := keys[starts[i]:starts[i+1]]
+				if len(dst) == 0 {
+					continue
+				}
+
+				buckets[uint16(i)].Iter(func(hash chainhash.Hash, _ uint64) bool { //nolint:gosec // i <= nrOfBuckets
+					dst[copied[i]] = hash
+					copied[i]++
+
+					return copied[i] == len(dst)
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// compact the ranges of buckets that shrank after sizing
+	end := 0
+
+	for i := 0; i < n; i++ {
+		end += copy(keys[end:], keys[starts[i]:starts[i]+copied[i]])
+	}
+
+	return keys[:end]
+}
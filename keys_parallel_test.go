@@ -0,0 +1,37 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeysParallel tests that KeysParallel returns the same keys as Keys on
+// every split map, for several worker counts.
+func TestKeysParallel(t *testing.T) {
+	type keysParallel interface {
+		TxMap
+		KeysParallel(workers int) []chainhash.Hash
+	}
+
+	for name, factory := range txMapImpls() {
+		m, ok := factory().(keysParallel)
+		if !ok {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			assert.Empty(t, m.KeysParallel(4))
+
+			for i := 0; i < 3000; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i)))
+			}
+
+			for _, workers := range []int{0, 1, 8, 5000} {
+				assert.ElementsMatch(t, m.Keys(), m.KeysParallel(workers))
+			}
+		})
+	}
+}
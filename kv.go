@@ -0,0 +1,121 @@
+package txmap
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// KV is a hash and its value, the unit of the bulk APIs of this package.
+type KV struct {
+	Hash  chainhash.Hash
+	Value uint64
+}
+
+// PutKVs adds every pair of kvs to m, like Put.
+//
+// Params:
+//   - m: The map to add to.
+//   - kvs: The pairs to add.
+//
+// Returns:
+//   - int: The number of pairs added before an error.
+//   - error: The first error returned by Put, wrapped with the index of its
+//     pair, e.g. ErrHashAlreadyExists. The pairs added before it remain in m.
+func PutKVs(m TxMap, kvs []KV) (int, error) {
+	for i, kv := range kvs {
+		if err := m.Put(kv.Hash, kv.Value); err != nil {
+			return i, fmt.Errorf("pair %d: %w", i, err)
+		}
+	}
+
+	return len(kvs), nil
+}
+
+// GetKVs looks up every hash and returns the pairs of the hashes that exist,
+// in the order of hashes. Maps implementing BatchTxMap look them up with
+// GetMulti.
+//
+// Params:
+//   - m: The map to look up in.
+//   - hashes: The hashes to look up.
+//
+// Returns:
+//   - []KV: The pairs of the hashes found.
+func GetKVs(m ReadOnlyTxMap, hashes []chainhash.Hash) []KV {
+	kvs := make([]KV, 0, len(hashes))
+
+	if bm, ok := m.(BatchTxMap); ok {
+		values, found := bm.GetMulti(hashes)
+
+		for i, hash := range hashes {
+			if found[i] {
+				kvs = append(kvs, KV{Hash: hash, Value: values[i]})
+			}
+		}
+
+		return kvs
+	}
+
+	for _, hash := range hashes {
+		if value, ok := m.Get(hash); ok {
+			kvs = append(kvs, KV{Hash: hash, Value: value})
+		}
+	}
+
+	return kvs
+}
+
+// IterKV returns an iterator over the pairs of m, with the semantics of m's Iter.
+//
+// Params:
+//   - m: The map to iterate over.
+//
+// Returns:
+//   - iter.Seq[KV]: Yields every pair; breaking out of the loop stops Iter.
+func IterKV(m ReadOnlyTxMap) iter.Seq[KV] {
+	return func(yield func(KV) bool) {
+		m.Iter(func(hash chainhash.Hash, value uint64) bool {
+			return !yield(KV{Hash: hash, Value: value})
+		})
+	}
+}
+
+// All returns an iterator over the hashes and values of m, with the semantics
+// of m's Iter, like maps.All for a Go map.
+//
+// Params:
+//   - m: The map to iterate over.
+//
+// Returns:
+//   - iter.Seq2[chainhash.Hash, uint64]: Yields every hash with its value;
+//     breaking out of the loop stops Iter.
+func All(m ReadOnlyTxMap) iter.Seq2[chainhash.Hash, uint64] {
+	return func(yield func(chainhash.Hash, uint64) bool) {
+		m.Iter(func(hash chainhash.Hash, value uint64) bool {
+			return !yield(hash, value)
+		})
+	}
+}
+
+// KVs returns all pairs of m, with Entries for maps implementing EntriesTxMap.
+//
+// Params:
+//   - m: The map to copy.
+//
+// Returns:
+//   - []KV: The pairs of m, in no particular order.
+func KVs(m ReadOnlyTxMap) []KV {
+	if em, ok := m.(EntriesTxMap); ok {
+		return em.Entries()
+	}
+
+	kvs := make([]KV, 0, m.Length())
+
+	for kv := range IterKV(m) {
+		kvs = append(kvs, kv)
+	}
+
+	return kvs
+}
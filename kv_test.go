@@ -0,0 +1,52 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKVs tests the bulk KV APIs against every TxMap implementation and a map
+// without GetMulti.
+func TestKVs(t *testing.T) {
+	impls := txMapImpls()
+	impls["ChildMap"] = func() TxMap { return NewChildMap(NewNativeMapUint64(0)) }
+
+	kvs := make([]KV, 100)
+	for i := range kvs {
+		kvs[i] = KV{Hash: hashN(i), Value: uint64(i) * 3} //nolint:gosec // test sizes are small
+	}
+
+	for name, factory := range impls {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			n, err := PutKVs(m, kvs)
+			require.NoError(t, err)
+			assert.Equal(t, len(kvs), n)
+
+			n, err = PutKVs(m, []KV{{Hash: hashN(1000)}, kvs[5]})
+			require.ErrorIs(t, err, ErrHashAlreadyExists)
+			assert.Equal(t, 1, n)
+
+			got := GetKVs(m, []chainhash.Hash{hashN(7), hashN(2000), hashN(3)})
+			assert.Equal(t, []KV{kvs[7], kvs[3]}, got)
+
+			all := KVs(m)
+			assert.Len(t, all, len(kvs)+1)
+			assert.Subset(t, all, kvs)
+
+			seen := 0
+			for range IterKV(m) {
+				seen++
+				if seen == 10 {
+					break
+				}
+			}
+
+			assert.Equal(t, 10, seen)
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package txmap
+
+import (
+	"errors"
+	"sync"
+)
+
+// Lock policies
+//
+// The lock-based maps, and the buckets of the split maps, guard their
+// contents with a read-write lock. By default this is a sync.RWMutex, which
+// prefers writers: once a writer waits for the lock, new readers queue behind
+// it, so a stream of reads cannot starve the writer, at the cost of stalling
+// those reads for as long as the writer waits for the current readers.
+//
+// Maps that must serve reads with the lowest latency and can tolerate delayed
+// writes can prefer readers instead: readers never wait for a waiting writer,
+// only for one holding the lock, and a writer gets the lock only once no
+// reader holds it. Under continuous reads such a writer can wait
+// indefinitely, so LockReaderPreferring is only suitable for maps whose reads
+// come in bursts.
+//
+// The critical sections of the maps are mostly tens of nanoseconds, so at
+// high core counts the cost of sync.RWMutex itself, which parks and wakes
+// goroutines through the runtime, can dominate. LockSpin replaces it with an
+// adaptive spin lock that busy-waits for a short while and then yields the
+// processor, which suits short critical sections but wastes CPU behind long
+// ones such as Iter, Keys and Clear on large buckets. It can be selected per
+// map with SetLockPolicy, or made the default of every map by building with
+// the txmapspin tag.
+//
+// Writers stalled behind long scans rather than behind many short reads are
+// better helped by IterYield and KeysYield, which release the lock during the
+// scan under either policy.
+
+// LockPolicy selects how a map's read-write locks arbitrate between readers
+// and writers.
+type LockPolicy uint8
+
+const (
+	// LockWriterPreferring blocks new readers while a writer waits. It is the
+	// default.
+	LockWriterPreferring LockPolicy = iota
+
+	// LockReaderPreferring lets readers in while a writer waits, as long as
+	// another reader holds the lock.
+	LockReaderPreferring
+
+	// LockSpin spins instead of parking the goroutine while the lock is
+	// held. See spinRWLock.
+	LockSpin
+)
+
+// ErrUnknownLockPolicy is returned by SetLockPolicy for an undefined policy.
+var ErrUnknownLockPolicy = errors.New("unknown lock policy")
+
+// String returns the name of the policy.
+func (p LockPolicy) String() string {
+	switch p {
+	case LockWriterPreferring:
+		return "writer-preferring"
+	case LockReaderPreferring:
+		return "reader-preferring"
+	case LockSpin:
+		return "spin"
+	default:
+		return "unknown"
+	}
+}
+
+// rwLocker is the method set shared by the read-write locks of mapLock.
+type rwLocker interface {
+	Lock()
+	TryLock() bool
+	Unlock()
+	RLock()
+	TryRLock() bool
+	RUnlock()
+}
+
+// mapLock is the read-write lock of the lock-based maps. Its zero value uses
+// the default policy of the build, see defaultLockPolicy; setPolicy installs
+// another lock in alt. defaultLockPolicy is a constant, so the dispatch of a
+// map without a policy of its own compiles to a nil check of alt in front of
+// the build's default lock; BenchmarkMapLock compares it with a bare
+// sync.RWMutex.
+type mapLock struct {
+	rw     sync.RWMutex
+	spin   spinRWLock
+	alt    rwLocker
+	policy LockPolicy
+}
+
+// setPolicy switches the lock to policy. It must not be called while the
+// lock is held or may be acquired concurrently.
+func (l *mapLock) setPolicy(policy LockPolicy) error {
+	switch policy {
+	case LockWriterPreferring:
+		l.alt = &l.rw
+	case LockReaderPreferring:
+		l.alt = &readerPreferringLock{}
+	case LockSpin:
+		l.alt = &l.spin
+	default:
+		return ErrUnknownLockPolicy
+	}
+
+	l.policy = policy
+
+	return nil
+}
+
+// currentPolicy returns the policy of the lock.
+func (l *mapLock) currentPolicy() LockPolicy {
+	if l.alt == nil {
+		return defaultLockPolicy
+	}
+
+	return l.policy
+}
+
+// Lock locks for writing.
+func (l *mapLock) Lock() {
+	switch {
+	case l.alt != nil:
+		l.alt.Lock()
+	case defaultLockPolicy == LockSpin:
+		l.spin.Lock()
+	default:
+		l.rw.Lock()
+	}
+}
+
+// TryLock tries to lock for writing and reports whether it succeeded.
+func (l *mapLock) TryLock() bool {
+	switch {
+	case l.alt != nil:
+		return l.alt.TryLock()
+	case defaultLockPolicy == LockSpin:
+		return l.spin.TryLock()
+	default:
+		return l.rw.TryLock()
+	}
+}
+
+// Unlock unlocks for writing.
+func (l *mapLock) Unlock() {
+	switch {
+	case l.alt != nil:
+		l.alt.Unlock()
+	case defaultLockPolicy == LockSpin:
+		l.spin.Unlock()
+	default:
+		l.rw.Unlock()
+	}
+}
+
+// RLock locks for reading.
+func (l *mapLock) RLock() {
+	switch {
+	case l.alt != nil:
+		l.alt.RLock()
+	case defaultLockPolicy == LockSpin:
+		l.spin.RLock()
+	default:
+		l.rw.RLock()
+	}
+}
+
+// TryRLock tries to lock for reading and reports whether it succeeded.
+func (l *mapLock) TryRLock() bool {
+	switch {
+	case l.alt != nil:
+		return l.alt.TryRLock()
+	case defaultLockPolicy == LockSpin:
+		return l.spin.TryRLock()
+	default:
+		return l.rw.TryRLock()
+	}
+}
+
+// RUnlock unlocks for reading.
+func (l *mapLock) RUnlock() {
+	switch {
+	case l.alt != nil:
+		l.alt.RUnlock()
+	case defaultLockPolicy == LockSpin:
+		l.spin.RUnlock()
+	default:
+		l.rw.RUnlock()
+	}
+}
+
+// readerPreferringLock is a read-write lock in which the first reader takes
+// the write lock on behalf of all readers and the last one releases it, so
+// that readers arriving while others hold the lock never wait.
+type readerPreferringLock struct {
+	mu      sync.Mutex
+	readers int
+	write   sync.Mutex
+}
+
+// Lock locks for writing.
+func (l *readerPreferringLock) Lock() {
+	l.write.Lock()
+}
+
+// TryLock tries to lock for writing and reports whether it succeeded.
+func (l *readerPreferringLock) TryLock() bool {
+	return l.write.TryLock()
+}
+
+// Unlock unlocks for writing.
+func (l *readerPreferringLock) Unlock() {
+	l.write.Unlock()
+}
+
+// RLock locks for reading.
+func (l *readerPreferringLock) RLock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.readers == 0 {
+		l.write.Lock()
+	}
+
+	l.readers++
+}
+
+// TryRLock tries to lock for reading without waiting for a writer.
+func (l *readerPreferringLock) TryRLock() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.readers == 0 && !l.write.TryLock() {
+		return false
+	}
+
+	l.readers++
+
+	return true
+}
+
+// RUnlock unlocks for reading.
+func (l *readerPreferringLock) RUnlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.readers--
+	if l.readers == 0 {
+		l.write.Unlock()
+	}
+}
+
+// SetLockPolicy sets the lock policy of the map. It must be called before the
+// map is shared between goroutines.
+func (s *SwissMap) SetLockPolicy(policy LockPolicy) error { return s.mu.setPolicy(policy) }
+
+// SetLockPolicy sets the lock policy of the map. It must be called before the
+// map is shared between goroutines.
+func (s *SwissMapUint64) SetLockPolicy(policy LockPolicy) error { return s.mu.setPolicy(policy) }
+
+// SetLockPolicy sets the lock policy of the map. It must be called before the
+// map is shared between goroutines.
+func (s *NativeMap) SetLockPolicy(policy LockPolicy) error { return s.mu.setPolicy(policy) }
+
+// SetLockPolicy sets the lock policy of the map. It must be called before the
+// map is shared between goroutines.
+func (s *NativeMapUint64) SetLockPolicy(policy LockPolicy) error { return s.mu.setPolicy(policy) }
+
+// LockPolicy returns the lock policy of the map.
+func (s *SwissMap) LockPolicy() LockPolicy { return s.mu.currentPolicy() }
+
+// LockPolicy returns the lock policy of the map.
+func (s *SwissMapUint64) LockPolicy() LockPolicy { return s.mu.currentPolicy() }
+
+// LockPolicy returns the lock policy of the map.
+func (s *NativeMap) LockPolicy() LockPolicy { return s.mu.currentPolicy() }
+
+// LockPolicy returns the lock policy of the map.
+func (s *NativeMapUint64) LockPolicy() LockPolicy { return s.mu.currentPolicy() }
+
+// SetLockPolicy sets the lock policy of every bucket. It must be called
+// before the map is shared between goroutines.
+func (g *SplitSwissMap) SetLockPolicy(policy LockPolicy) error {
+	return splitSetLockPolicy(g.m, g.nrOfBuckets, policy)
+}
+
+// SetLockPolicy sets the lock policy of every bucket. It must be called
+// before the map is shared between goroutines.
+func (g *SplitSwissMapUint64) SetLockPolicy(policy LockPolicy) error {
+	return splitSetLockPolicy(g.m, g.nrOfBuckets, policy)
+}
+
+// SetLockPolicy sets the lock policy of every bucket. It must be called
+// before the map is shared between goroutines.
+func (g *NativeSplitMap) SetLockPolicy(policy LockPolicy) error {
+	return splitSetLockPolicy(g.m, g.nrOfBuckets, policy)
+}
+
+// SetLockPolicy sets the lock policy of every bucket. It must be called
+// before the map is shared between goroutines.
+func (g *NativeSplitMapUint64) SetLockPolicy(policy LockPolicy) error {
+	return splitSetLockPolicy(g.m, g.nrOfBuckets, policy)
+}
+
+// LockPolicy returns the lock policy of the buckets.
+func (g *SplitSwissMap) LockPolicy() LockPolicy { return splitLockPolicy(g.m) }
+
+// LockPolicy returns the lock policy of the buckets.
+func (g *SplitSwissMapUint64) LockPolicy() LockPolicy { return splitLockPolicy(g.m) }
+
+// LockPolicy returns the lock policy of the buckets.
+func (g *NativeSplitMap) LockPolicy() LockPolicy { return splitLockPolicy(g.m) }
+
+// LockPolicy returns the lock policy of the buckets.
+func (g *NativeSplitMapUint64) LockPolicy() LockPolicy { return splitLockPolicy(g.m) }
+
+// splitSetLockPolicy sets the lock policy of every bucket of a split map. It
+// checks that all buckets exist first, so a missing bucket changes none.
+func splitSetLockPolicy[M interface {
+	comparable
+	SetLockPolicy(LockPolicy) error
+}](buckets []M, nrOfBuckets uint16, policy LockPolicy,
+) error {
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		if _, err := bucketAt(buckets, i); err != nil {
+			return err
+		}
+	}
+
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		if err := buckets[i].SetLockPolicy(policy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitLockPolicy returns the lock policy of bucket 0 of a split map, which
+// splitSetLockPolicy keeps equal to that of the others, or the default policy
+// if the bucket is missing.
+func splitLockPolicy[M interface {
+	comparable
+	LockPolicy() LockPolicy
+}](buckets []M,
+) LockPolicy {
+	bucket, err := bucketAt(buckets, uint16(0))
+	if err != nil {
+		return defaultLockPolicy
+	}
+
+	return bucket.LockPolicy()
+}
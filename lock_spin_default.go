@@ -0,0 +1,7 @@
+//go:build txmapspin
+
+package txmap
+
+// defaultLockPolicy is the lock policy of maps that did not call
+// SetLockPolicy. Building with the txmapspin tag makes it LockSpin; see lock.go.
+const defaultLockPolicy = LockSpin
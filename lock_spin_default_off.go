@@ -0,0 +1,7 @@
+//go:build !txmapspin
+
+package txmap
+
+// defaultLockPolicy is the lock policy of maps that did not call
+// SetLockPolicy; see lock.go.
+const defaultLockPolicy = LockWriterPreferring
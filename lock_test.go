@@ -0,0 +1,166 @@
+package txmap
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockPolicyMap is a TxMap whose lock policy can be set.
+type lockPolicyMap interface {
+	TxMap
+	SetLockPolicy(policy LockPolicy) error
+	LockPolicy() LockPolicy
+}
+
+// TestLockPolicyConformance runs the TxMap conformance tests against every
+// TxMap implementation with every lock policy.
+func TestLockPolicyConformance(t *testing.T) {
+	for _, policy := range []LockPolicy{LockWriterPreferring, LockReaderPreferring, LockSpin} {
+		for name, factory := range txMapImpls() {
+			t.Run(policy.String()+"/"+name, func(t *testing.T) {
+				m, ok := factory().(lockPolicyMap)
+				require.True(t, ok)
+				assert.Equal(t, defaultLockPolicy, m.LockPolicy())
+
+				require.NoError(t, m.SetLockPolicy(policy))
+				assert.Equal(t, policy, m.LockPolicy())
+
+				testTxMap(t, m)
+			})
+		}
+	}
+
+	require.ErrorIs(t, NewNativeMap(0).SetLockPolicy(LockPolicy(9)), ErrUnknownLockPolicy)
+	require.NoError(t, NewSwissMap(0).SetLockPolicy(LockWriterPreferring))
+}
+
+// TestLockPolicyReadersPassWaitingWriter tests that with reader-preferring
+// locks a reader is not blocked by a writer waiting for another reader, while
+// with the writer-preferring policies it is.
+func TestLockPolicyReadersPassWaitingWriter(t *testing.T) {
+	for _, policy := range []LockPolicy{LockWriterPreferring, LockReaderPreferring, LockSpin} {
+		t.Run(policy.String(), func(t *testing.T) {
+			var l mapLock
+			require.NoError(t, l.setPolicy(policy))
+
+			l.RLock()
+
+			var writing sync.WaitGroup
+
+			writing.Add(1)
+
+			go func() {
+				defer writing.Done()
+				l.Lock()
+				l.Unlock()
+			}()
+
+			// give the writer time to start waiting
+			time.Sleep(20 * time.Millisecond)
+
+			var read atomic.Bool
+
+			go func() {
+				l.RLock()
+				read.Store(true)
+				l.RUnlock()
+			}()
+
+			time.Sleep(20 * time.Millisecond)
+			assert.Equal(t, policy == LockReaderPreferring, read.Load())
+
+			l.RUnlock()
+			writing.Wait()
+			assert.Eventually(t, read.Load, time.Second, time.Millisecond)
+		})
+	}
+}
+
+// TestSpinRWLock tests mutual exclusion of the spin lock under contention.
+func TestSpinRWLock(t *testing.T) {
+	var (
+		l       spinRWLock
+		counter int
+		wg      sync.WaitGroup
+	)
+
+	for w := 0; w < 8; w++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				l.Lock()
+				counter++
+				l.Unlock()
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				l.RLock()
+				_ = counter
+				l.RUnlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 8000, counter)
+
+	require.True(t, l.TryRLock())
+	assert.False(t, l.TryLock())
+	l.RUnlock()
+	require.True(t, l.TryLock())
+	assert.False(t, l.TryRLock())
+	l.Unlock()
+}
+
+// BenchmarkMapLock compares an uncontended write and read lock round trip of
+// mapLock, under every policy, with a bare sync.RWMutex, to show what the
+// dispatch on the policy costs.
+func BenchmarkMapLock(b *testing.B) {
+	b.Run("RWMutex", func(b *testing.B) {
+		var l sync.RWMutex
+
+		for b.Loop() {
+			l.Lock()
+			l.Unlock()
+			l.RLock()
+			l.RUnlock()
+		}
+	})
+
+	b.Run("default", func(b *testing.B) {
+		var l mapLock
+
+		for b.Loop() {
+			l.Lock()
+			l.Unlock()
+			l.RLock()
+			l.RUnlock()
+		}
+	})
+
+	for _, policy := range []LockPolicy{LockWriterPreferring, LockReaderPreferring, LockSpin} {
+		b.Run(policy.String(), func(b *testing.B) {
+			var l mapLock
+			require.NoError(b, l.setPolicy(policy))
+
+			for b.Loop() {
+				l.Lock()
+				l.Unlock()
+				l.RLock()
+				l.RUnlock()
+			}
+		})
+	}
+}
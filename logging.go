@@ -0,0 +1,225 @@
+package txmap
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"math"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that LoggingTxMap implements TxMap
+var _ TxMap = (*LoggingTxMap)(nil)
+
+// LoggingOptions configures a LoggingTxMap.
+type LoggingOptions struct {
+	// Name identifies the map in every record, e.g. "utxo" or "block-txs".
+	Name string
+
+	// Labels are added to every record.
+	Labels []slog.Attr
+
+	// SampleRate is the fraction of hashes whose mutations are logged. Sampling
+	// is by hash, so every mutation of a sampled hash is logged and the history
+	// of that hash is complete. Values <= 0 or >= 1 log every mutation.
+	SampleRate float64
+}
+
+// LoggingTxMap wraps a TxMap and logs every mutation and every failed
+// operation with structured attributes, for forensic debugging of incidents
+// like "where did this hash go".
+//
+// Mutations of sampled hashes are logged at the configured level; failed
+// mutations are always logged, at slog.LevelWarn or the configured level if
+// it is higher. Reads are not logged. On a ShardedTxMap every record carries
+// the bucket of its hash.
+//
+// All writes must go through the LoggingTxMap; changes made directly to the
+// wrapped map are not logged.
+type LoggingTxMap struct {
+	m       TxMap
+	logger  *slog.Logger
+	level   slog.Level
+	buckets uint16
+
+	// threshold is SampleRate scaled to the uint64 range; a hash is sampled
+	// if its sample key is below it
+	threshold uint64
+	sampleAll bool
+}
+
+// WrapWithLogging returns a LoggingTxMap that logs the mutations of m to logger.
+//
+// Params:
+//   - m: The map to wrap.
+//   - logger: The logger to write to.
+//   - level: The level of mutation records.
+//   - opts: The name, labels and sampling rate of the records.
+//
+// Returns:
+//   - *LoggingTxMap: The wrapping map.
+func WrapWithLogging(m TxMap, logger *slog.Logger, level slog.Level, opts LoggingOptions) *LoggingTxMap {
+	m = nonNilTxMap(m)
+
+	attrs := make([]any, 0, len(opts.Labels)+1)
+	attrs = append(attrs, slog.String("map", opts.Name))
+
+	for _, label := range opts.Labels {
+		attrs = append(attrs, label)
+	}
+
+	l := &LoggingTxMap{
+		m:         m,
+		logger:    logger.With(attrs...),
+		level:     level,
+		sampleAll: opts.SampleRate <= 0 || opts.SampleRate >= 1,
+	}
+
+	if !l.sampleAll {
+		l.threshold = uint64(opts.SampleRate * math.MaxUint64)
+	}
+
+	if sm, ok := AsSharded(m); ok {
+		l.buckets = sm.Buckets()
+	}
+
+	return l
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (l *LoggingTxMap) Exists(hash chainhash.Hash) bool {
+	return l.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (l *LoggingTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return l.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (l *LoggingTxMap) Keys() []chainhash.Hash {
+	return l.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (l *LoggingTxMap) Length() int {
+	return l.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (l *LoggingTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	l.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map and logs it.
+func (l *LoggingTxMap) Put(hash chainhash.Hash, value uint64) error {
+	err := l.m.Put(hash, value)
+	l.logHash("Put", hash, err, slog.Uint64("value", value))
+
+	return err
+}
+
+// PutMulti adds hashes to the wrapped map and logs every sampled hash, or the
+// failure with the number of hashes.
+func (l *LoggingTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	err := l.m.PutMulti(hashes, value)
+
+	if err != nil {
+		l.log(l.errorLevel(), "PutMulti", slog.Int("count", len(hashes)), slog.Any("error", err))
+		return err
+	}
+
+	for _, hash := range hashes {
+		l.logHash("PutMulti", hash, nil, slog.Uint64("value", value))
+	}
+
+	return nil
+}
+
+// Set updates the value of an existing hash and logs it.
+func (l *LoggingTxMap) Set(hash chainhash.Hash, value uint64) error {
+	err := l.m.Set(hash, value)
+	l.logHash("Set", hash, err, slog.Uint64("value", value))
+
+	return err
+}
+
+// SetIfExists updates the value of hash if it exists and logs the outcome.
+func (l *LoggingTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	updated, err := l.m.SetIfExists(hash, value)
+	l.logHash("SetIfExists", hash, err, slog.Uint64("value", value), slog.Bool("updated", updated))
+
+	return updated, err
+}
+
+// SetIfNotExists adds hash if it does not exist yet and logs the outcome.
+func (l *LoggingTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	added, err := l.m.SetIfNotExists(hash, value)
+	l.logHash("SetIfNotExists", hash, err, slog.Uint64("value", value), slog.Bool("added", added))
+
+	return added, err
+}
+
+// Delete removes hash from the wrapped map and logs it.
+func (l *LoggingTxMap) Delete(hash chainhash.Hash) error {
+	err := l.m.Delete(hash)
+	l.logHash("Delete", hash, err)
+
+	return err
+}
+
+// Freeze freezes the wrapped map and logs it.
+func (l *LoggingTxMap) Freeze() {
+	l.m.Freeze()
+	l.log(l.level, "Freeze", slog.Int("length", l.m.Length()))
+}
+
+// Clear empties the wrapped map and logs the number of entries discarded.
+func (l *LoggingTxMap) Clear() {
+	length := l.m.Length()
+
+	l.m.Clear()
+	l.log(l.level, "Clear", slog.Int("length", length))
+}
+
+// logHash logs an operation on hash if hash is sampled or err is set.
+func (l *LoggingTxMap) logHash(op string, hash chainhash.Hash, err error, attrs ...slog.Attr) {
+	level := l.level
+
+	if err != nil {
+		level = l.errorLevel()
+		attrs = append(attrs, slog.Any("error", err))
+	} else if !l.sampled(hash) {
+		return
+	}
+
+	attrs = append(attrs, slog.String("hash", hash.String()))
+
+	if l.buckets > 0 {
+		attrs = append(attrs, slog.Int("bucket", int(Bytes2Uint16Buckets(hash, l.buckets))))
+	}
+
+	l.log(level, op, attrs...)
+}
+
+// log writes a record for op if the logger is enabled for level.
+func (l *LoggingTxMap) log(level slog.Level, op string, attrs ...slog.Attr) {
+	ctx := context.Background()
+
+	if l.logger.Enabled(ctx, level) {
+		l.logger.LogAttrs(ctx, level, "txmap "+op, attrs...)
+	}
+}
+
+// errorLevel returns the level of failed operations.
+func (l *LoggingTxMap) errorLevel() slog.Level {
+	return max(l.level, slog.LevelWarn)
+}
+
+// sampled reports whether the mutations of hash are logged. The sample key is
+// taken from bytes not used for bucket routing, so sampling is independent of
+// the bucket.
+func (l *LoggingTxMap) sampled(hash chainhash.Hash) bool {
+	return l.sampleAll || binary.LittleEndian.Uint64(hash[24:32]) < l.threshold
+}
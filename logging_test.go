@@ -0,0 +1,90 @@
+package txmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords decodes the JSON records written to buf.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// TestLoggingTxMap tests the records written for mutations and failures.
+func TestLoggingTxMap(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	m := WrapWithLogging(NewSplitSwissMapUint64(16, 4), logger, slog.LevelDebug, LoggingOptions{
+		Name:   "utxo",
+		Labels: []slog.Attr{slog.String("node", "a")},
+	})
+
+	require.NoError(t, m.Put(hashN(1), 5))
+	require.ErrorIs(t, m.Put(hashN(1), 6), ErrHashAlreadyExists)
+	require.NoError(t, m.Delete(hashN(1)))
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 3)
+
+	assert.Equal(t, "txmap Put", records[0]["msg"])
+	assert.Equal(t, "utxo", records[0]["map"])
+	assert.Equal(t, "a", records[0]["node"])
+	assert.Equal(t, hashN(1).String(), records[0]["hash"])
+	assert.InDelta(t, 1, records[0]["bucket"], 0)
+	assert.Equal(t, "DEBUG", records[0]["level"])
+
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Contains(t, records[1]["error"], ErrHashAlreadyExists.Error())
+
+	assert.Equal(t, "txmap Delete", records[2]["msg"])
+}
+
+// TestLoggingTxMapSampling tests that sampling logs a consistent subset of
+// hashes but always logs failures.
+func TestLoggingTxMapSampling(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	m := WrapWithLogging(NewNativeMapUint64(1000), logger, slog.LevelInfo, LoggingOptions{SampleRate: 0.1})
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, m.Put(bloomHash(i), 1))
+	}
+
+	sampled := len(logRecords(t, &buf))
+	assert.InDelta(t, 100, sampled, 50)
+
+	buf.Reset()
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, m.Set(bloomHash(i), 2))
+	}
+
+	assert.Len(t, logRecords(t, &buf), sampled)
+
+	buf.Reset()
+	require.Error(t, m.Delete(hashN(5000)))
+	assert.Len(t, logRecords(t, &buf), 1)
+}
@@ -0,0 +1,364 @@
+package txmap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that LRUMap implements TxMap
+var _ TxMap = (*LRUMap)(nil)
+
+// LRUMap is a TxMap bounded to a capacity: once a write adds an entry beyond
+// it, the least recently used entry is removed and reported to the OnEvict
+// callback with EvictReasonLRU, unlike the item limit of SyncedMap, which
+// drops arbitrary keys. It suits admission control, e.g. of a mempool, where
+// the oldest idle hashes should give way to new ones.
+//
+// Get, Put, Set and their variants mark an entry as used; Exists, Keys and
+// Iter only peek. Since a Get reorders the entries, every method takes a
+// single mutex, which makes LRUMap a poor fit for read-heavy workloads on
+// many cores, except once it is frozen: reads of a frozen map take no lock
+// and no longer mark entries, as nothing can be evicted until Clear.
+type LRUMap struct {
+	mu       sync.Mutex
+	m        map[chainhash.Hash]*lruEntry
+	capacity int
+	onEvict  EvictCallback[chainhash.Hash, uint64]
+	frozen   atomic.Bool
+
+	// head is the sentinel of the recency list: head.next is the most and
+	// head.prev the least recently used entry.
+	head lruEntry
+}
+
+// lruEntry is an entry of an LRUMap, linked into its recency list.
+type lruEntry struct {
+	hash       chainhash.Hash
+	value      uint64
+	prev, next *lruEntry
+}
+
+// NewLRUMap creates a new LRUMap holding up to capacity entries.
+//
+// Params:
+//   - capacity: The maximum number of entries; zero or less means no limit.
+//
+// Returns:
+//   - *LRUMap: A pointer to the newly created LRUMap instance.
+func NewLRUMap(capacity int) *LRUMap {
+	m := &LRUMap{
+		m:        make(map[chainhash.Hash]*lruEntry, max(capacity, 0)),
+		capacity: capacity,
+	}
+
+	m.head.prev, m.head.next = &m.head, &m.head
+
+	return m
+}
+
+// Capacity returns the maximum number of entries, zero or less for none.
+func (m *LRUMap) Capacity() int {
+	return m.capacity
+}
+
+// OnEvict registers a callback that is invoked for every entry the map
+// removes on its own: the least recently used entries with EvictReasonLRU,
+// and the entries removed by Clear with EvictReasonCleared. Explicit Delete
+// calls are not reported. Passing nil removes a previously registered
+// callback.
+//
+// Params:
+//   - cb: The callback to invoke; it runs under the map's lock and must not
+//     call back into the map.
+func (m *LRUMap) OnEvict(cb EvictCallback[chainhash.Hash, uint64]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onEvict = cb
+}
+
+// Exists checks if the hash exists in the map, without marking it as used.
+func (m *LRUMap) Exists(hash chainhash.Hash) bool {
+	if !m.frozen.Load() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+	}
+
+	_, ok := m.m[hash]
+
+	return ok
+}
+
+// Get retrieves the value of the hash and marks it as the most recently used.
+//
+// Returns:
+//   - uint64: The value, 0 if the hash does not exist.
+//   - bool: True if the hash was found.
+func (m *LRUMap) Get(hash chainhash.Hash) (uint64, bool) {
+	if m.frozen.Load() {
+		if e, ok := m.m[hash]; ok {
+			return e.value, true
+		}
+
+		return 0, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.m[hash]
+	if !ok {
+		return 0, false
+	}
+
+	m.moveToFront(e)
+
+	return e.value, true
+}
+
+// Length returns the number of entries in the map.
+func (m *LRUMap) Length() int {
+	if !m.frozen.Load() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+	}
+
+	return len(m.m)
+}
+
+// Keys returns the hashes in the map, from the most to the least recently
+// used.
+func (m *LRUMap) Keys() []chainhash.Hash {
+	if !m.frozen.Load() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+	}
+
+	keys := make([]chainhash.Hash, 0, len(m.m))
+
+	for e := m.head.next; e != &m.head; e = e.next {
+		keys = append(keys, e.hash)
+	}
+
+	return keys
+}
+
+// Iter calls f for every entry, from the most to the least recently used,
+// under the map's lock, without marking them as used. Stops iterating if f
+// returns true; f must not call back into the map.
+func (m *LRUMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	if !m.frozen.Load() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+	}
+
+	for e := m.head.next; e != &m.head; e = e.next {
+		if f(e.hash, e.value) {
+			return
+		}
+	}
+}
+
+// Put adds the hash with the given value as the most recently used entry,
+// evicting the least recently used one if the map is full.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     hash exists.
+func (m *LRUMap) Put(hash chainhash.Hash, value uint64) error {
+	if m.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.putUnlocked(hash, value)
+}
+
+// PutMulti adds the hashes with the given value under a single lock, stopping
+// at the first error. With more hashes than the capacity, the first ones are
+// evicted again by the last ones.
+func (m *LRUMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	if m.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, hash := range hashes {
+		if err := m.putUnlocked(hash, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putUnlocked adds hash with value, evicting if the map is full. The caller
+// holds the lock.
+func (m *LRUMap) putUnlocked(hash chainhash.Hash, value uint64) error {
+	if _, ok := m.m[hash]; ok {
+		return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+	}
+
+	m.insertUnlocked(hash, value)
+
+	return nil
+}
+
+// insertUnlocked adds the missing hash with value as the most recently used
+// entry, and evicts the least recently used entries beyond the capacity. The
+// caller holds the lock.
+func (m *LRUMap) insertUnlocked(hash chainhash.Hash, value uint64) {
+	e := &lruEntry{hash: hash, value: value}
+	m.m[hash] = e
+	m.pushFront(e)
+
+	for m.capacity > 0 && len(m.m) > m.capacity {
+		victim := m.head.prev
+		m.unlink(victim)
+		delete(m.m, victim.hash)
+
+		if m.onEvict != nil {
+			m.onEvict(victim.hash, victim.value, EvictReasonLRU)
+		}
+	}
+}
+
+// Set updates the value of an existing hash and marks it as the most
+// recently used.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist if the
+//     hash does not exist.
+func (m *LRUMap) Set(hash chainhash.Hash, value uint64) error {
+	set, err := m.SetIfExists(hash, value)
+	if err == nil && !set {
+		err = fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return err
+}
+
+// SetIfExists updates the value of the hash and marks it as the most
+// recently used, if it exists.
+//
+// Returns:
+//   - bool: True if the hash was updated.
+//   - error: ErrMapFrozen if the map is frozen.
+func (m *LRUMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if m.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.m[hash]
+	if !ok {
+		return false, nil
+	}
+
+	e.value = value
+	m.moveToFront(e)
+
+	return true, nil
+}
+
+// SetIfNotExists adds the hash like Put if it does not exist. An existing
+// hash is left as it is, and is not marked as used.
+//
+// Returns:
+//   - bool: True if the hash was added.
+//   - error: ErrMapFrozen if the map is frozen.
+func (m *LRUMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if m.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.m[hash]; ok {
+		return false, nil
+	}
+
+	m.insertUnlocked(hash, value)
+
+	return true, nil
+}
+
+// Delete removes the hash from the map. It is not reported to OnEvict.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist if the
+//     hash does not exist.
+func (m *LRUMap) Delete(hash chainhash.Hash) error {
+	if m.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.m[hash]
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	m.unlink(e)
+	delete(m.m, hash)
+
+	return nil
+}
+
+// Freeze marks the map read-only; see the notes on LRUMap and in freeze.go.
+func (m *LRUMap) Freeze() {
+	m.frozen.Store(true)
+}
+
+// Clear removes every entry, reporting each to OnEvict with
+// EvictReasonCleared, and un-freezes the map.
+func (m *LRUMap) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.onEvict != nil {
+		for e := m.head.next; e != &m.head; e = e.next {
+			m.onEvict(e.hash, e.value, EvictReasonCleared)
+		}
+	}
+
+	clear(m.m)
+	m.head.prev, m.head.next = &m.head, &m.head
+	m.frozen.Store(false)
+}
+
+// pushFront links e as the most recently used entry.
+func (m *LRUMap) pushFront(e *lruEntry) {
+	e.prev, e.next = &m.head, m.head.next
+	m.head.next.prev = e
+	m.head.next = e
+}
+
+// unlink removes e from the recency list.
+func (m *LRUMap) unlink(e *lruEntry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next = nil, nil
+}
+
+// moveToFront marks e as the most recently used entry.
+func (m *LRUMap) moveToFront(e *lruEntry) {
+	if m.head.next == e {
+		return
+	}
+
+	m.unlink(e)
+	m.pushFront(e)
+}
@@ -0,0 +1,103 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLRUMap tests that LRUMap evicts the least recently used hashes beyond
+// its capacity and reports them to the callback.
+func TestLRUMap(t *testing.T) {
+	m := NewLRUMap(3)
+	assert.Equal(t, 3, m.Capacity())
+
+	var evicted []chainhash.Hash
+
+	m.OnEvict(func(hash chainhash.Hash, _ uint64, reason EvictReason) {
+		assert.Equal(t, EvictReasonLRU, reason)
+		evicted = append(evicted, hash)
+	})
+
+	require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(1), hashN(2), hashN(3)}, 0))
+	require.ErrorIs(t, m.Put(hashN(1), 1), ErrHashAlreadyExists)
+
+	// hashN(2) becomes the least recently used entry
+	_, ok := m.Get(hashN(1))
+	require.True(t, ok)
+	require.NoError(t, m.Set(hashN(3), 3))
+	assert.True(t, m.Exists(hashN(2)), "Exists does not mark hashes as used")
+
+	require.NoError(t, m.Put(hashN(4), 4))
+	assert.Equal(t, []chainhash.Hash{hashN(2)}, evicted)
+	assert.Equal(t, []chainhash.Hash{hashN(4), hashN(3), hashN(1)}, m.Keys())
+
+	added, err := m.SetIfNotExists(hashN(5), 5)
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, []chainhash.Hash{hashN(2), hashN(1)}, evicted)
+
+	require.NoError(t, m.Delete(hashN(3)))
+	require.ErrorIs(t, m.Set(hashN(3), 3), ErrHashDoesNotExist)
+	assert.Equal(t, 2, m.Length())
+	assert.Len(t, evicted, 2, "Delete is not reported")
+}
+
+// TestLRUMapFreezeClear tests that a frozen LRUMap serves reads without
+// reordering, and that Clear reports its entries and un-freezes it.
+func TestLRUMapFreezeClear(t *testing.T) {
+	m := NewLRUMap(0)
+
+	for i := range 100 {
+		require.NoError(t, m.Put(hashN(i), uint64(i)))
+	}
+
+	m.Freeze()
+	require.ErrorIs(t, m.Put(hashN(100), 100), ErrMapFrozen)
+
+	value, ok := m.Get(hashN(0))
+	require.True(t, ok)
+	assert.Equal(t, uint64(0), value)
+	assert.Equal(t, hashN(99), m.Keys()[0], "a frozen map keeps its order")
+
+	cleared := 0
+
+	m.OnEvict(func(_ chainhash.Hash, _ uint64, reason EvictReason) {
+		assert.Equal(t, EvictReasonCleared, reason)
+		cleared++
+	})
+
+	m.Clear()
+	assert.Equal(t, 100, cleared)
+	assert.Equal(t, 0, m.Length())
+	require.NoError(t, m.Put(hashN(0), 0))
+}
+
+// TestLRUMapConcurrent tests that concurrent writers never exceed the
+// capacity.
+func TestLRUMapConcurrent(t *testing.T) {
+	m := NewLRUMap(100)
+
+	var wg sync.WaitGroup
+
+	for w := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				_, err := m.SetIfNotExists(hashN(w*1000+i), uint64(i))
+				assert.NoError(t, err)
+				m.Get(hashN(w * 1000))
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 100, m.Length())
+	assert.Len(t, m.Keys(), 100)
+}
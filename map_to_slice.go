@@ -1,43 +1,77 @@
 package txmap
 
 import (
+	"bytes"
+	"cmp"
+	"slices"
 	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
 )
 
+// SliceOptions controls the slices returned by the Convert*Slice helpers.
+type SliceOptions struct {
+	// Sorted returns the elements in ascending order (hashes by their bytes).
+	Sorted bool
+
+	// Dedup removes duplicate elements. It implies Sorted.
+	Dedup bool
+
+	// Capacity preallocates the slice for this many elements.
+	Capacity int
+}
+
 // ConvertSyncMapToUint32Slice returns a slice of uint32 keys from the provided *sync.Map.
 //
 // Parameters:
 //   - syncMap: A pointer to a sync.Map where keys are of type uint32.
+//   - opts (optional): Sorting, deduplication and capacity of the result.
 //
 // Returns:
 //   - []uint32: A slice containing all uint32 keys from the map.
 //   - bool: True if the map contained any elements, false otherwise.
-func ConvertSyncMapToUint32Slice(syncMap *sync.Map) ([]uint32, bool) {
-	var sliceWithMapElements []uint32
-
-	mapHasAnyElements := false
-
-	syncMap.Range(func(key, _ interface{}) bool {
-		mapHasAnyElements = true
-		val := key.(uint32)
-		sliceWithMapElements = append(sliceWithMapElements, val)
+func ConvertSyncMapToUint32Slice(syncMap *sync.Map, opts ...SliceOptions) ([]uint32, bool) {
+	return convertSyncMapKeys[uint32](syncMap, cmp.Compare[uint32], opts)
+}
 
-		return true
-	})
+// ConvertSyncMapToUint64Slice returns a slice of uint64 keys from the provided *sync.Map.
+//
+// Parameters:
+//   - syncMap: A pointer to a sync.Map where keys are of type uint64.
+//   - opts (optional): Sorting, deduplication and capacity of the result.
+//
+// Returns:
+//   - []uint64: A slice containing all uint64 keys from the map.
+//   - bool: True if the map contained any elements, false otherwise.
+func ConvertSyncMapToUint64Slice(syncMap *sync.Map, opts ...SliceOptions) ([]uint64, bool) {
+	return convertSyncMapKeys[uint64](syncMap, cmp.Compare[uint64], opts)
+}
 
-	return sliceWithMapElements, mapHasAnyElements
+// ConvertSyncMapToHashSlice returns a slice of chainhash.Hash keys from the provided *sync.Map.
+//
+// Parameters:
+//   - syncMap: A pointer to a sync.Map where keys are of type chainhash.Hash.
+//   - opts (optional): Sorting, deduplication and capacity of the result.
+//
+// Returns:
+//   - []chainhash.Hash: A slice containing all hash keys from the map.
+//   - bool: True if the map contained any elements, false otherwise.
+func ConvertSyncMapToHashSlice(syncMap *sync.Map, opts ...SliceOptions) ([]chainhash.Hash, bool) {
+	return convertSyncMapKeys[chainhash.Hash](syncMap, compareHashes, opts)
 }
 
 // ConvertSyncedMapToUint32Slice returns a slice of all uint32 values from the provided SyncedMap.
 //
 // Parameters:
 //   - syncMap: A pointer to a SyncedMap with any comparable key type and []uint32 values.
+//   - opts (optional): Sorting, deduplication and capacity of the result.
 //
 // Returns:
 //   - []uint32: A slice containing all uint32 values from the map (flattened).
 //   - bool: True if the map contained any elements, false otherwise.
-func ConvertSyncedMapToUint32Slice[K comparable](syncMap *SyncedMap[K, []uint32]) ([]uint32, bool) {
-	var sliceWithMapElements []uint32
+func ConvertSyncedMapToUint32Slice[K comparable](syncMap *SyncedMap[K, []uint32], opts ...SliceOptions) ([]uint32, bool) {
+	o := sliceOptions(opts)
+	sliceWithMapElements := make([]uint32, 0, o.Capacity)
 
 	mapHasAnyElements := false
 
@@ -49,5 +83,55 @@ func ConvertSyncedMapToUint32Slice[K comparable](syncMap *SyncedMap[K, []uint32]
 		return true
 	})
 
-	return sliceWithMapElements, mapHasAnyElements
+	return finishSlice(sliceWithMapElements, cmp.Compare[uint32], o), mapHasAnyElements
+}
+
+// convertSyncMapKeys collects the keys of syncMap, which must all be of type T.
+func convertSyncMapKeys[T comparable](syncMap *sync.Map, compare func(a, b T) int, opts []SliceOptions) ([]T, bool) {
+	o := sliceOptions(opts)
+	sliceWithMapElements := make([]T, 0, o.Capacity)
+
+	mapHasAnyElements := false
+
+	syncMap.Range(func(key, _ interface{}) bool {
+		mapHasAnyElements = true
+		val := key.(T)
+		sliceWithMapElements = append(sliceWithMapElements, val)
+
+		return true
+	})
+
+	return finishSlice(sliceWithMapElements, compare, o), mapHasAnyElements
+}
+
+// sliceOptions returns the first of the optional SliceOptions, or the zero value.
+func sliceOptions(opts []SliceOptions) SliceOptions {
+	if len(opts) == 0 {
+		return SliceOptions{}
+	}
+
+	return opts[0]
+}
+
+// finishSlice sorts and deduplicates s as requested by o. An empty result is
+// returned as nil, as before the options existed.
+func finishSlice[T comparable](s []T, compare func(a, b T) int, o SliceOptions) []T {
+	if len(s) == 0 {
+		return nil
+	}
+
+	if o.Sorted || o.Dedup {
+		slices.SortFunc(s, compare)
+	}
+
+	if o.Dedup {
+		s = slices.Compact(s)
+	}
+
+	return s
+}
+
+// compareHashes orders hashes by their bytes.
+func compareHashes(a, b chainhash.Hash) int {
+	return bytes.Compare(a[:], b[:])
 }
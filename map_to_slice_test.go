@@ -54,13 +54,57 @@ func TestGenericConvertSyncMapToUint32Slice(t *testing.T) {
 	})
 }
 
+// TestConvertSyncMapTypedSlices tests the uint64 and hash variants and the
+// sorting, deduplication and capacity options.
+func TestConvertSyncMapTypedSlices(t *testing.T) {
+	t.Run("uint64 sorted", func(t *testing.T) {
+		var ids sync.Map
+
+		for _, id := range []uint64{5, 1, 3} {
+			ids.Store(id, struct{}{})
+		}
+
+		result, ok := ConvertSyncMapToUint64Slice(&ids, SliceOptions{Sorted: true, Capacity: 10})
+		assert.True(t, ok)
+		assert.Equal(t, []uint64{1, 3, 5}, result)
+		assert.Equal(t, 10, cap(result))
+	})
+
+	t.Run("hash sorted", func(t *testing.T) {
+		var hashes sync.Map
+
+		for _, i := range []int{3, 1, 2} {
+			hashes.Store(hashN(i), struct{}{})
+		}
+
+		result, ok := ConvertSyncMapToHashSlice(&hashes, SliceOptions{Sorted: true})
+		assert.True(t, ok)
+		assert.Equal(t, []chainhash.Hash{hashN(1), hashN(2), hashN(3)}, result)
+
+		empty, ok := ConvertSyncMapToHashSlice(&sync.Map{})
+		assert.False(t, ok)
+		assert.Nil(t, empty)
+	})
+
+	t.Run("synced map dedup", func(t *testing.T) {
+		blockIDs := NewSyncedMap[int, []uint32]()
+
+		blockIDs.Set(1, []uint32{4, 2})
+		blockIDs.Set(2, []uint32{2, 9})
+
+		result, ok := ConvertSyncedMapToUint32Slice(blockIDs, SliceOptions{Dedup: true})
+		require.True(t, ok)
+		assert.Equal(t, []uint32{2, 4, 9}, result)
+	})
+}
+
 // TestSplitSwissMapUint64Delete tests the Delete method of SplitSwissMapUint64.
 func TestSplitSwissMapUint64Delete(t *testing.T) {
 	t.Run("bucket does not exist", func(t *testing.T) {
 		m := NewSplitSwissMapUint64(10)
 		h := chainhash.Hash{0x00, 0x01}
 		bucket := Bytes2Uint16Buckets(h, m.nrOfBuckets)
-		delete(m.m, bucket)
+		simulateMissingBucket(m.m, bucket)
 
 		err := m.Delete(h)
 		require.Error(t, err)
@@ -0,0 +1,126 @@
+package txmap
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// MigrationReport summarizes the work done by MigrateValues.
+type MigrationReport struct {
+	// Visited is the number of entries passed to the migration function.
+	Visited uint64
+
+	// Updated is the number of entries whose value was changed.
+	Updated uint64
+
+	// Deleted is the number of entries removed because the migration function
+	// returned false.
+	Deleted uint64
+}
+
+// migrationChange is a pending write of MigrateValues.
+type migrationChange struct {
+	hash   chainhash.Hash
+	value  uint64
+	delete bool
+}
+
+// MigrateValues rewrites every value of m in place with fn, for schema changes
+// such as repacking a (fileID, offset) encoding across the whole map. Entries
+// for which fn returns false are deleted; entries for which fn returns the old
+// value are left untouched.
+//
+// The buckets of the split maps are migrated in parallel, each worker taking
+// the next unmigrated bucket. A bucket is read in full before its changes are
+// written, so fn never runs while a bucket lock is held for writing and may be
+// slow. Other maps are migrated as a single bucket.
+//
+// Params:
+//   - m: The map to migrate; it must not be written to while this runs, or
+//     concurrent writes may be overwritten with migrated stale values.
+//   - workers: The number of buckets migrated at once; values below one mean
+//     GOMAXPROCS.
+//   - fn: Returns the new value of hash, or false to delete it. It is called
+//     from several goroutines at once.
+//
+// Returns:
+//   - MigrationReport: What was visited, updated and deleted, also on error.
+//   - error: The first error returned by m, e.g. ErrMapFrozen; the remaining
+//     buckets are then not migrated.
+func MigrateValues(m TxMap, workers int, fn func(hash chainhash.Hash, old uint64) (uint64, bool)) (MigrationReport, error) {
+	buckets := txMapBuckets(m)
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		next                      atomic.Int64
+		visited, updated, deleted atomic.Uint64
+		firstErr                  atomic.Pointer[error]
+		wg                        sync.WaitGroup
+	)
+
+	for w := min(workers, len(buckets)); w > 0; w-- {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var changes []migrationChange
+
+			for i := int(next.Add(1) - 1); i < len(buckets) && firstErr.Load() == nil; i = int(next.Add(1) - 1) {
+				changes = changes[:0]
+
+				buckets[i].Iter(func(hash chainhash.Hash, old uint64) bool {
+					visited.Add(1)
+
+					if value, keep := fn(hash, old); !keep || value != old {
+						changes = append(changes, migrationChange{hash: hash, value: value, delete: !keep})
+					}
+
+					return false
+				})
+
+				if err := applyMigration(m, changes, &updated, &deleted); err != nil {
+					firstErr.CompareAndSwap(nil, &err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	report := MigrationReport{Visited: visited.Load(), Updated: updated.Load(), Deleted: deleted.Load()}
+
+	if err := firstErr.Load(); err != nil {
+		return report, *err
+	}
+
+	return report, nil
+}
+
+// applyMigration writes the changes of one bucket to m, counting them.
+func applyMigration(m TxMap, changes []migrationChange, updated, deleted *atomic.Uint64) error {
+	for _, c := range changes {
+		if c.delete {
+			if err := m.Delete(c.hash); err != nil {
+				return err
+			}
+
+			deleted.Add(1)
+
+			continue
+		}
+
+		if err := m.Set(c.hash, c.value); err != nil {
+			return err
+		}
+
+		updated.Add(1)
+	}
+
+	return nil
+}
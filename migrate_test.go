@@ -0,0 +1,67 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrateValues tests repacking and deleting values of every TxMap
+// implementation.
+func TestMigrateValues(t *testing.T) {
+	const n = 2000
+
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+			for i := 0; i < n; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i))) //nolint:gosec // test sizes are small
+			}
+
+			// repack odd values as value<<32, drop multiples of 10, keep the rest
+			report, err := MigrateValues(m, 4, func(_ chainhash.Hash, old uint64) (uint64, bool) {
+				switch {
+				case old%10 == 0:
+					return 0, false
+				case old%2 == 1:
+					return old << 32, true
+				default:
+					return old, true
+				}
+			})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(n), report.Visited)
+			assert.Equal(t, uint64(n/10), report.Deleted)
+			assert.Equal(t, uint64(n/2), report.Updated)
+			require.Equal(t, n-n/10, m.Length())
+
+			for i := 0; i < n; i++ {
+				v, ok := m.Get(hashN(i))
+
+				switch {
+				case i%10 == 0:
+					assert.False(t, ok)
+				case i%2 == 1:
+					assert.Equal(t, uint64(i)<<32, v) //nolint:gosec // test sizes are small
+				default:
+					assert.Equal(t, uint64(i), v) //nolint:gosec // test sizes are small
+				}
+			}
+		})
+	}
+}
+
+// TestMigrateValuesFrozen tests that a frozen map reports ErrMapFrozen and is
+// left unchanged.
+func TestMigrateValuesFrozen(t *testing.T) {
+	m := populatedMap(t, 100)
+	m.Freeze()
+
+	_, err := MigrateValues(m, 0, func(_ chainhash.Hash, old uint64) (uint64, bool) {
+		return old + 1, true
+	})
+	require.ErrorIs(t, err, ErrMapFrozen)
+	requireSameContents(t, populatedMap(t, 100), m)
+}
@@ -0,0 +1,99 @@
+package txmap
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// ErrNilTxMap is returned by the writes of a wrapper constructed around a nil
+// map, such as NewRecordingTxMap(nil, rec), instead of a nil pointer panic.
+// Use NoopTxMap to disable tracking on purpose.
+var ErrNilTxMap = errors.New("tx map is nil")
+
+// check that NoopTxMap implements TxMap
+var _ TxMap = NoopTxMap{}
+
+// NoopTxMap is a TxMap that stores nothing and whose operations all succeed,
+// so that tracking can be disabled by configuration without nil checks at the
+// call sites. Lookups find nothing and writes are discarded: SetIfNotExists
+// reports the hash as set, SetIfExists reports it as missing.
+type NoopTxMap struct{}
+
+// Exists reports false.
+func (NoopTxMap) Exists(chainhash.Hash) bool { return false }
+
+// Get reports the hash as missing.
+func (NoopTxMap) Get(chainhash.Hash) (uint64, bool) { return 0, false }
+
+// Keys returns nil.
+func (NoopTxMap) Keys() []chainhash.Hash { return nil }
+
+// Length returns 0.
+func (NoopTxMap) Length() int { return 0 }
+
+// Iter does not call f.
+func (NoopTxMap) Iter(func(hash chainhash.Hash, value uint64) bool) {}
+
+// Put discards the hash.
+func (NoopTxMap) Put(chainhash.Hash, uint64) error { return nil }
+
+// PutMulti discards the hashes.
+func (NoopTxMap) PutMulti([]chainhash.Hash, uint64) error { return nil }
+
+// Set discards the value.
+func (NoopTxMap) Set(chainhash.Hash, uint64) error { return nil }
+
+// SetIfExists reports the hash as missing.
+func (NoopTxMap) SetIfExists(chainhash.Hash, uint64) (bool, error) { return false, nil }
+
+// SetIfNotExists discards the hash and reports it as set.
+func (NoopTxMap) SetIfNotExists(chainhash.Hash, uint64) (bool, error) { return true, nil }
+
+// Delete does nothing.
+func (NoopTxMap) Delete(chainhash.Hash) error { return nil }
+
+// Freeze does nothing.
+func (NoopTxMap) Freeze() {}
+
+// Clear does nothing.
+func (NoopTxMap) Clear() {}
+
+// nilTxMap stands in for the nil map a wrapper was constructed around: reads
+// find nothing and writes return ErrNilTxMap.
+type nilTxMap struct{}
+
+func (nilTxMap) Exists(chainhash.Hash) bool                          { return false }
+func (nilTxMap) Get(chainhash.Hash) (uint64, bool)                   { return 0, false }
+func (nilTxMap) Keys() []chainhash.Hash                              { return nil }
+func (nilTxMap) Length() int                                         { return 0 }
+func (nilTxMap) Iter(func(hash chainhash.Hash, value uint64) bool)   {}
+func (nilTxMap) Put(chainhash.Hash, uint64) error                    { return ErrNilTxMap }
+func (nilTxMap) PutMulti([]chainhash.Hash, uint64) error             { return ErrNilTxMap }
+func (nilTxMap) Set(chainhash.Hash, uint64) error                    { return ErrNilTxMap }
+func (nilTxMap) SetIfExists(chainhash.Hash, uint64) (bool, error)    { return false, ErrNilTxMap }
+func (nilTxMap) SetIfNotExists(chainhash.Hash, uint64) (bool, error) { return false, ErrNilTxMap }
+func (nilTxMap) Delete(chainhash.Hash) error                         { return ErrNilTxMap }
+func (nilTxMap) Freeze()                                             {}
+func (nilTxMap) Clear()                                              {}
+
+// nonNilTxMap returns m, or a nilTxMap if m is nil or a nil pointer.
+func nonNilTxMap(m TxMap) TxMap {
+	if isNilTxMap(m) {
+		return nilTxMap{}
+	}
+
+	return m
+}
+
+// isNilTxMap reports whether m is nil or holds a nil pointer.
+func isNilTxMap(m ReadOnlyTxMap) bool {
+	if m == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(m)
+
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,70 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNoopTxMap tests that every operation succeeds and nothing is stored.
+func TestNoopTxMap(t *testing.T) {
+	var m TxMap = NoopTxMap{}
+
+	require.NoError(t, m.Put(hashN(1), 1))
+	require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(2), hashN(3)}, 1))
+	require.NoError(t, m.Set(hashN(1), 2))
+	require.NoError(t, m.Delete(hashN(1)))
+
+	set, err := m.SetIfNotExists(hashN(1), 1)
+	require.NoError(t, err)
+	assert.True(t, set)
+
+	set, err = m.SetIfExists(hashN(1), 1)
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	assert.False(t, m.Exists(hashN(1)))
+	assert.Equal(t, 0, m.Length())
+	assert.Empty(t, m.Keys())
+}
+
+// TestDecoratorsTolerateNilMap tests that wrappers around a nil map, or a nil
+// pointer to one, return ErrNilTxMap instead of panicking.
+func TestDecoratorsTolerateNilMap(t *testing.T) {
+	var nilPointer *SwissMapUint64
+
+	wrappers := map[string]func(m TxMap) TxMap{
+		"Recording":  func(m TxMap) TxMap { return NewRecordingTxMap(m, NewMutationRing(4)) },
+		"Computing":  func(m TxMap) TxMap { return NewComputingTxMap(m) },
+		"Swappable":  func(m TxMap) TxMap { return NewSwappableTxMap(m) },
+		"ZeroHash":   func(m TxMap) TxMap { return WithRejectZeroHash(m) },
+		"Bloom":      func(m TxMap) TxMap { return WithBloom(m, 10, 0.01) },
+		"Generation": func(m TxMap) TxMap { return NewGenerationTxMap(m) },
+		"Child":      func(m TxMap) TxMap { return NewChildMap(m) },
+		"Quiesce":    func(m TxMap) TxMap { return WithQuiesce(m) },
+	}
+
+	for name, wrap := range wrappers {
+		for _, inner := range []TxMap{nil, nilPointer} {
+			t.Run(name, func(t *testing.T) {
+				m := wrap(inner)
+
+				assert.False(t, m.Exists(hashN(1)))
+				assert.Equal(t, 0, m.Length())
+
+				if name != "Child" {
+					require.ErrorIs(t, m.Put(hashN(1), 1), ErrNilTxMap)
+				}
+			})
+		}
+	}
+
+	s := NewSwappableTxMap(NewSwissMapUint64(0))
+
+	_, err := s.SwapBackend(context.Background(), nil, 1)
+	require.ErrorIs(t, err, ErrNilTxMap)
+}
@@ -0,0 +1,75 @@
+package txmap
+
+import (
+	"bytes"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// KeysWithPrefix returns the hashes in m whose bytes start with prefix.
+//
+// The split maps select a hash's bucket from its first two bytes, so a prefix
+// of two or more bytes is answered by scanning a single bucket and a one-byte
+// prefix by scanning at most 256 buckets. Shorter prefixes, and maps that are
+// not split, fall back to a filtered scan of the whole map.
+//
+// The prefix applies to the hash bytes in their internal order. Transaction
+// ids are displayed in reversed byte order, so a prefix of a displayed txid in
+// hex is the reversed suffix of the hash bytes, not a prefix of them.
+//
+// Params:
+//   - m: The map to search.
+//   - prefix: The leading bytes to match; an empty prefix matches every hash.
+//
+// Returns:
+//   - []chainhash.Hash: The matching hashes, in unspecified order.
+func KeysWithPrefix(m ReadOnlyTxMap, prefix []byte) []chainhash.Hash {
+	if len(prefix) > chainhash.HashSize {
+		return nil
+	}
+
+	var keys []chainhash.Hash
+
+	for _, bucket := range prefixBuckets(txMapBuckets(m), prefix) {
+		bucket.Iter(func(hash chainhash.Hash, _ uint64) bool {
+			if bytes.HasPrefix(hash[:], prefix) {
+				keys = append(keys, hash)
+			}
+
+			return false
+		})
+	}
+
+	return keys
+}
+
+// prefixBuckets returns the buckets that can hold hashes starting with prefix.
+func prefixBuckets(buckets []ReadOnlyTxMap, prefix []byte) []ReadOnlyTxMap {
+	if len(buckets) == 1 || len(prefix) == 0 {
+		return buckets
+	}
+
+	nrOfBuckets := uint16(len(buckets) - 1) //nolint:gosec // split maps have at most 65536 buckets
+
+	var hash chainhash.Hash
+
+	copy(hash[:], prefix)
+
+	if len(prefix) >= 2 {
+		return []ReadOnlyTxMap{buckets[Bytes2Uint16Buckets(hash, nrOfBuckets)]}
+	}
+
+	seen := make(map[uint16]bool)
+	candidates := make([]ReadOnlyTxMap, 0, 256)
+
+	for b := 0; b < 256; b++ {
+		hash[1] = byte(b)
+
+		if bucket := Bytes2Uint16Buckets(hash, nrOfBuckets); !seen[bucket] {
+			seen[bucket] = true
+			candidates = append(candidates, buckets[bucket])
+		}
+	}
+
+	return candidates
+}
@@ -0,0 +1,41 @@
+package txmap
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeysWithPrefix compares KeysWithPrefix with a filtered Keys for prefixes
+// of every length class on every TxMap.
+func TestKeysWithPrefix(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			for i := 0; i < 3000; i++ {
+				require.NoError(t, m.Put(bloomHash(i), uint64(i)))
+			}
+
+			sample := bloomHash(42)
+
+			for _, prefix := range [][]byte{nil, sample[:1], sample[:2], sample[:3], sample[:], {0xde, 0xad, 0xbe, 0xef}} {
+				var want []chainhash.Hash
+
+				for _, hash := range m.Keys() {
+					if bytes.HasPrefix(hash[:], prefix) {
+						want = append(want, hash)
+					}
+				}
+
+				assert.ElementsMatch(t, want, KeysWithPrefix(m, prefix), "prefix %x", prefix)
+			}
+
+			assert.Equal(t, []chainhash.Hash{sample}, KeysWithPrefix(m, sample[:]))
+			assert.Empty(t, KeysWithPrefix(m, make([]byte, 33)))
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package txmap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// ErrKeyCollision is returned by PrefixUint64Map.Put when the uint64 key of a
+// hash is already held by an entry the verification callback rejects, i.e. by
+// a different hash sharing those 8 bytes.
+var ErrKeyCollision = errors.New("uint64 key held by a different hash")
+
+// HashKeyPart selects the 8 bytes of a hash that HashUint64Key turns into a
+// key.
+type HashKeyPart int
+
+const (
+	// HashKeyFirst8 selects the first 8 bytes of the hash in internal byte
+	// order, which are the last 8 of the displayed txid.
+	HashKeyFirst8 HashKeyPart = iota
+
+	// HashKeyLast8 selects the last 8 bytes of the hash in internal byte
+	// order, which are the first 8 of the displayed txid.
+	HashKeyLast8
+)
+
+// HashUint64Key returns 8 bytes of hash, chosen by part, as a little-endian
+// uint64. The bytes of transaction ids are uniformly distributed, so any 8 of
+// them make a well-spread key; two of n ids share a key with a probability of
+// about n²/2^65, around one in 3,700 for 100M ids.
+//
+// Params:
+//   - hash: The hash to derive the key from.
+//   - part: Which 8 bytes to use; values other than HashKeyLast8 mean
+//     HashKeyFirst8.
+//
+// Returns:
+//   - uint64: The key.
+func HashUint64Key(hash chainhash.Hash, part HashKeyPart) uint64 {
+	if part == HashKeyLast8 {
+		return binary.LittleEndian.Uint64(hash[chainhash.HashSize-8:])
+	}
+
+	return binary.LittleEndian.Uint64(hash[:8])
+}
+
+// PrefixUint64Options configure a PrefixUint64Map.
+type PrefixUint64Options struct {
+	// Part selects the bytes of each hash used as its key.
+	Part HashKeyPart
+
+	// Buckets is the number of buckets, 1024 when zero.
+	Buckets uint64
+
+	// Verify, if set, is asked whether an entry found under the key of hash
+	// really belongs to hash, typically by checking value, e.g. a position,
+	// against an authoritative store. Without it the map trusts the key, so a
+	// lookup of a hash sharing a key with an entry reports that entry.
+	Verify func(hash chainhash.Hash, value uint64) bool
+}
+
+// PrefixUint64Map is a presence map keyed by 8 bytes of every hash instead of
+// all 32, storing 16 bytes per entry instead of 40. It is built on
+// SplitSwissLockFreeMapUint64 and shares its concurrency rules: writes must
+// not run concurrently with any other operation, reads may run concurrently
+// with each other.
+//
+// Different hashes sharing a key are told apart only by the Verify callback
+// of the options; see HashUint64Key for how rare that is.
+type PrefixUint64Map struct {
+	m      *SplitSwissLockFreeMapUint64
+	part   HashKeyPart
+	verify func(hash chainhash.Hash, value uint64) bool
+}
+
+// NewPrefixUint64Map creates a new PrefixUint64Map.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - opts: The key derivation, bucket count and verification callback.
+//
+// Returns:
+//   - *PrefixUint64Map: A pointer to the newly created PrefixUint64Map instance.
+func NewPrefixUint64Map(length int, opts PrefixUint64Options) *PrefixUint64Map {
+	buckets := opts.Buckets
+	if buckets == 0 {
+		buckets = 1024
+	}
+
+	return &PrefixUint64Map{
+		m:      NewSplitSwissLockFreeMapUint64(length, buckets),
+		part:   opts.Part,
+		verify: opts.Verify,
+	}
+}
+
+// Key returns the uint64 key the map stores hash under.
+func (p *PrefixUint64Map) Key(hash chainhash.Hash) uint64 {
+	return HashUint64Key(hash, p.part)
+}
+
+// Map returns the underlying split map, keyed by Key.
+//
+// Considerations: This method does not lock the map, so it is not suitable for concurrent access.
+func (p *PrefixUint64Map) Map() *SplitSwissLockFreeMapUint64 {
+	return p.m
+}
+
+// Exists checks if an entry is stored under the key of hash and, if Verify is
+// set, accepted by it.
+func (p *PrefixUint64Map) Exists(hash chainhash.Hash) bool {
+	_, ok := p.Get(hash)
+	return ok
+}
+
+// Get retrieves the value stored under the key of hash, if Verify, when set,
+// accepts it.
+func (p *PrefixUint64Map) Get(hash chainhash.Hash) (uint64, bool) {
+	value, ok := p.m.Get(p.Key(hash))
+	if !ok || (p.verify != nil && !p.verify(hash, value)) {
+		return 0, false
+	}
+
+	return value, true
+}
+
+// Put adds hash with value under its key.
+//
+// Params:
+//   - hash: The hash to add.
+//   - value: The value to store, and to pass to Verify on lookups.
+//
+// Returns:
+//   - error: An error wrapping ErrKeyCollision if Verify rejects the entry
+//     already stored under the key, the duplicate policy's error if it accepts
+//     it, or ErrMapFrozen.
+func (p *PrefixUint64Map) Put(hash chainhash.Hash, value uint64) error {
+	key := p.Key(hash)
+
+	if p.verify != nil {
+		if existing, ok := p.m.Get(key); ok && !p.verify(hash, existing) {
+			return fmt.Errorf("%w: %s, key %d", ErrKeyCollision, hash, key)
+		}
+	}
+
+	return p.m.Put(key, value)
+}
+
+// Length returns the number of entries in the map.
+func (p *PrefixUint64Map) Length() int {
+	return p.m.Length()
+}
+
+// Freeze marks the map read-only; subsequent Put calls return ErrMapFrozen.
+func (p *PrefixUint64Map) Freeze() {
+	p.m.Freeze()
+}
+
+// Clear empties the map and un-freezes it for reuse.
+func (p *PrefixUint64Map) Clear() {
+	p.m.Clear()
+}
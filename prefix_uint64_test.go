@@ -0,0 +1,68 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHashUint64Key tests the byte selection of HashUint64Key.
+func TestHashUint64Key(t *testing.T) {
+	var hash chainhash.Hash
+	for i := range hash {
+		hash[i] = byte(i)
+	}
+
+	assert.Equal(t, uint64(0x0706050403020100), HashUint64Key(hash, HashKeyFirst8))
+	assert.Equal(t, uint64(0x1f1e1d1c1b1a1918), HashUint64Key(hash, HashKeyLast8))
+}
+
+// TestPrefixUint64Map tests lookups with and without verification, including
+// two hashes sharing a key.
+func TestPrefixUint64Map(t *testing.T) {
+	a, b := hashN(1), hashN(1)
+	b[31] = 0xff // same first 8 bytes, different hash
+
+	t.Run("unverified", func(t *testing.T) {
+		m := NewPrefixUint64Map(16, PrefixUint64Options{Buckets: 4})
+		require.NoError(t, m.Put(a, 10))
+
+		assert.True(t, m.Exists(a))
+		assert.True(t, m.Exists(b), "without Verify a shared key is trusted")
+		require.ErrorIs(t, m.Put(b, 20), ErrHashAlreadyExists)
+		assert.Equal(t, 1, m.Length())
+	})
+
+	t.Run("verified", func(t *testing.T) {
+		owners := map[uint64]chainhash.Hash{10: a}
+		m := NewPrefixUint64Map(16, PrefixUint64Options{
+			Buckets: 4,
+			Verify:  func(hash chainhash.Hash, value uint64) bool { return owners[value] == hash },
+		})
+		require.NoError(t, m.Put(a, 10))
+
+		value, ok := m.Get(a)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(10), value)
+
+		assert.False(t, m.Exists(b))
+		require.ErrorIs(t, m.Put(b, 20), ErrKeyCollision)
+		require.ErrorIs(t, m.Put(a, 10), ErrHashAlreadyExists)
+	})
+
+	t.Run("last8", func(t *testing.T) {
+		m := NewPrefixUint64Map(16, PrefixUint64Options{Part: HashKeyLast8})
+		require.NoError(t, m.Put(a, 1))
+		require.NoError(t, m.Put(b, 2))
+		assert.Equal(t, 2, m.Length())
+
+		m.Freeze()
+		require.ErrorIs(t, m.Put(hashN(2), 3), ErrMapFrozen)
+
+		m.Clear()
+		assert.Equal(t, 0, m.Length())
+		assert.False(t, m.Exists(a))
+	})
+}
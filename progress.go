@@ -0,0 +1,122 @@
+package txmap
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Progress describes how far a long-running operation such as Export or
+// Import has come, so that operators can see that it is alive and when it
+// will finish.
+type Progress struct {
+	// Processed is the number of entries processed so far.
+	Processed uint64
+
+	// Total is the number of entries the operation will process.
+	Total uint64
+
+	// Bytes is the number of bytes written or read so far.
+	Bytes uint64
+
+	// Elapsed is the time since the operation started.
+	Elapsed time.Duration
+
+	// ETA is the estimated time until the operation finishes, extrapolated
+	// from the rate so far; 0 until the first entry was processed.
+	ETA time.Duration
+}
+
+// String formats the progress for logs, e.g.
+// "1048576/4194304 entries (25.0%), 40 MiB, 12s elapsed, ETA 36s".
+func (p Progress) String() string {
+	percent := 100.0
+	if p.Total > 0 {
+		percent = 100 * float64(p.Processed) / float64(p.Total)
+	}
+
+	return fmt.Sprintf("%d/%d entries (%.1f%%), %d MiB, %s elapsed, ETA %s",
+		p.Processed, p.Total, percent, p.Bytes>>20, p.Elapsed.Round(time.Second), p.ETA.Round(time.Second))
+}
+
+// progressTracker calls a progress callback every interval entries.
+type progressTracker struct {
+	fn       func(Progress)
+	interval uint64
+	total    uint64
+	start    time.Time
+}
+
+// newProgressTracker returns a tracker for an operation over total entries,
+// calling fn every interval entries, or every defaultImportProgressInterval
+// entries for intervals below one. fn may be nil.
+func newProgressTracker(fn func(Progress), interval int, total uint64) *progressTracker {
+	t := &progressTracker{
+		fn:       fn,
+		interval: defaultImportProgressInterval,
+		total:    total,
+		start:    time.Now(),
+	}
+
+	if interval > 0 {
+		t.interval = uint64(interval)
+	}
+
+	return t
+}
+
+// due reports whether processed entries are a reporting point.
+func (t *progressTracker) due(processed uint64) bool {
+	return t.fn != nil && processed > 0 && processed%t.interval == 0
+}
+
+// report calls the callback with processed entries and bytes.
+func (t *progressTracker) report(processed, bytes uint64) {
+	if t.fn != nil {
+		t.fn(t.measure(processed, bytes))
+	}
+}
+
+// measure returns the progress after processed entries and bytes.
+func (t *progressTracker) measure(processed, bytes uint64) Progress {
+	p := Progress{
+		Processed: processed,
+		Total:     t.total,
+		Bytes:     bytes,
+		Elapsed:   time.Since(t.start),
+	}
+
+	if processed > 0 && processed < t.total {
+		p.ETA = time.Duration(float64(p.Elapsed) * float64(t.total-processed) / float64(processed))
+	}
+
+	return p
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n uint64
+}
+
+// Write writes p to the underlying writer and counts the bytes written.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n) //nolint:gosec // n is never negative
+
+	return n, err
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+// Read reads from the underlying reader and counts the bytes read.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n) //nolint:gosec // n is never negative
+
+	return n, err
+}
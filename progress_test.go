@@ -0,0 +1,58 @@
+package txmap
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportImportProgress tests the progress reported by ExportWithOptions
+// and Import.
+func TestExportImportProgress(t *testing.T) {
+	const n = 1000
+
+	var (
+		buf     bytes.Buffer
+		exports []Progress
+		imports []ImportReport
+	)
+
+	require.NoError(t, ExportWithOptions(&buf, populatedMap(t, n), ExportOptions{
+		OnProgress:       func(p Progress) { exports = append(exports, p) },
+		ProgressInterval: 300,
+	}))
+
+	require.Len(t, exports, 4)
+	assert.Equal(t, uint64(300), exports[0].Processed)
+	assert.Equal(t, uint64(n), exports[0].Total)
+	assert.Positive(t, exports[0].ETA)
+
+	last := exports[len(exports)-1]
+	assert.Equal(t, uint64(n), last.Processed)
+	assert.Equal(t, uint64(buf.Len()), last.Bytes) //nolint:gosec // buffer length is never negative
+	assert.Zero(t, last.ETA)
+
+	report, err := Import(context.Background(), NewNativeMapUint64(n), bytes.NewReader(buf.Bytes()), ImportOptions{
+		Progress:         func(r ImportReport) { imports = append(imports, r) },
+		ProgressInterval: 300,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, imports, 4)
+	assert.Equal(t, []uint64{300, 600, 900, n}, []uint64{imports[0].Read, imports[1].Read, imports[2].Read, imports[3].Read})
+	assert.Positive(t, imports[0].ETA)
+	assert.Equal(t, report, imports[3])
+	assert.Equal(t, uint64(buf.Len()), report.Bytes) //nolint:gosec // buffer length is never negative
+	assert.Zero(t, report.ETA)
+	assert.Equal(t, report.Bytes, report.Progress().Bytes)
+}
+
+// TestProgressString tests the log format of Progress.
+func TestProgressString(t *testing.T) {
+	p := Progress{Processed: 1 << 20, Total: 1 << 22, Bytes: 40 << 20, Elapsed: 12 * time.Second, ETA: 36 * time.Second}
+	assert.Equal(t, "1048576/4194304 entries (25.0%), 40 MiB, 12s elapsed, ETA 36s", p.String())
+}
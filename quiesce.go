@@ -0,0 +1,277 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+var (
+	// ErrWritesQuiesced is returned by the writes of a QuiescingTxMap whose
+	// writes were quiesced with QuiesceFail.
+	ErrWritesQuiesced = errors.New("writes are quiesced")
+
+	// ErrAlreadyQuiesced is returned by QuiesceWrites while the writes are
+	// already quiesced.
+	ErrAlreadyQuiesced = errors.New("writes are already quiesced")
+)
+
+// QuiesceMode selects what the writes of a quiesced QuiescingTxMap do.
+type QuiesceMode uint8
+
+const (
+	// QuiesceBlock makes writes wait until ResumeWrites.
+	QuiesceBlock QuiesceMode = iota
+
+	// QuiesceFail makes writes return ErrWritesQuiesced at once. Freeze and
+	// Clear, which cannot report an error, wait as with QuiesceBlock.
+	QuiesceFail
+)
+
+// check that QuiescingTxMap implements TxMap
+var _ TxMap = (*QuiescingTxMap)(nil)
+
+// QuiescingTxMap wraps a TxMap whose writes can be switched off while
+// maintenance such as a snapshot, a reshard or a backend swap runs. The
+// switch lives in the map every writer goes through, so the coordination
+// does not depend on each caller checking a flag of its own. Reads are never
+// affected.
+type QuiescingTxMap struct {
+	m TxMap
+
+	mu       sync.Mutex
+	quiesced bool
+	mode     QuiesceMode
+	active   int
+
+	// resumed is closed by ResumeWrites; drained is closed when the last
+	// write running at QuiesceWrites returns.
+	resumed chan struct{}
+	drained chan struct{}
+}
+
+// WithQuiesce returns a QuiescingTxMap forwarding every operation to m, with
+// writes enabled.
+//
+// Params:
+//   - m: The map to wrap.
+//
+// Returns:
+//   - *QuiescingTxMap: The wrapping map.
+func WithQuiesce(m TxMap) *QuiescingTxMap {
+	m = nonNilTxMap(m)
+
+	return &QuiescingTxMap{m: m}
+}
+
+// QuiesceWrites stops new writes as selected by mode and waits until the
+// writes already running have returned, so that the map is stable once it
+// returns without error.
+//
+// Params:
+//   - ctx: Ends the wait for running writes; the writes are resumed then.
+//   - mode: Whether new writes wait or fail while quiesced.
+//
+// Returns:
+//   - error: ErrAlreadyQuiesced, or an error wrapping the context error if
+//     the running writes did not return in time.
+func (q *QuiescingTxMap) QuiesceWrites(ctx context.Context, mode QuiesceMode) error {
+	q.mu.Lock()
+
+	if q.quiesced {
+		q.mu.Unlock()
+		return ErrAlreadyQuiesced
+	}
+
+	q.quiesced, q.mode = true, mode
+	q.resumed = make(chan struct{})
+
+	var drained chan struct{}
+
+	if q.active > 0 {
+		drained = make(chan struct{})
+		q.drained = drained
+	}
+
+	q.mu.Unlock()
+
+	if drained == nil {
+		return nil
+	}
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		q.ResumeWrites()
+		return fmt.Errorf("waiting for running writes: %w", ctx.Err())
+	}
+}
+
+// ResumeWrites enables the writes again and releases the writes waiting for
+// it. It does nothing if the writes are not quiesced.
+func (q *QuiescingTxMap) ResumeWrites() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.quiesced {
+		return
+	}
+
+	q.quiesced = false
+	q.drained = nil
+	close(q.resumed)
+}
+
+// Quiesced reports whether the writes are quiesced.
+func (q *QuiescingTxMap) Quiesced() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.quiesced
+}
+
+// enter registers a write, waiting while the writes are quiesced, or fails
+// with ErrWritesQuiesced if fail is set and they are quiesced with
+// QuiesceFail. Every successful enter must be followed by exit.
+func (q *QuiescingTxMap) enter(fail bool) error {
+	for {
+		q.mu.Lock()
+
+		if !q.quiesced {
+			q.active++
+			q.mu.Unlock()
+
+			return nil
+		}
+
+		if fail && q.mode == QuiesceFail {
+			q.mu.Unlock()
+			return ErrWritesQuiesced
+		}
+
+		resumed := q.resumed
+		q.mu.Unlock()
+
+		<-resumed
+	}
+}
+
+// exit unregisters a write and wakes QuiesceWrites after the last one.
+func (q *QuiescingTxMap) exit() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.active--
+
+	if q.active == 0 && q.drained != nil {
+		close(q.drained)
+		q.drained = nil
+	}
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (q *QuiescingTxMap) Exists(hash chainhash.Hash) bool {
+	return q.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (q *QuiescingTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return q.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (q *QuiescingTxMap) Keys() []chainhash.Hash {
+	return q.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (q *QuiescingTxMap) Length() int {
+	return q.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (q *QuiescingTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	q.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map unless writes are quiesced.
+func (q *QuiescingTxMap) Put(hash chainhash.Hash, value uint64) error {
+	if err := q.enter(true); err != nil {
+		return err
+	}
+	defer q.exit()
+
+	return q.m.Put(hash, value)
+}
+
+// PutMulti adds hashes to the wrapped map unless writes are quiesced.
+func (q *QuiescingTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	if err := q.enter(true); err != nil {
+		return err
+	}
+	defer q.exit()
+
+	return q.m.PutMulti(hashes, value)
+}
+
+// Set updates hash in the wrapped map unless writes are quiesced.
+func (q *QuiescingTxMap) Set(hash chainhash.Hash, value uint64) error {
+	if err := q.enter(true); err != nil {
+		return err
+	}
+	defer q.exit()
+
+	return q.m.Set(hash, value)
+}
+
+// SetIfExists updates hash in the wrapped map if it exists, unless writes are
+// quiesced.
+func (q *QuiescingTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if err := q.enter(true); err != nil {
+		return false, err
+	}
+	defer q.exit()
+
+	return q.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash to the wrapped map if it does not exist, unless
+// writes are quiesced.
+func (q *QuiescingTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if err := q.enter(true); err != nil {
+		return false, err
+	}
+	defer q.exit()
+
+	return q.m.SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the wrapped map unless writes are quiesced.
+func (q *QuiescingTxMap) Delete(hash chainhash.Hash) error {
+	if err := q.enter(true); err != nil {
+		return err
+	}
+	defer q.exit()
+
+	return q.m.Delete(hash)
+}
+
+// Freeze freezes the wrapped map, waiting while writes are quiesced.
+func (q *QuiescingTxMap) Freeze() {
+	_ = q.enter(false)
+	defer q.exit()
+
+	q.m.Freeze()
+}
+
+// Clear clears the wrapped map, waiting while writes are quiesced.
+func (q *QuiescingTxMap) Clear() {
+	_ = q.enter(false)
+	defer q.exit()
+
+	q.m.Clear()
+}
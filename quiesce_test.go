@@ -0,0 +1,94 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuiesceFail tests that writes fail with ErrWritesQuiesced while
+// quiesced with QuiesceFail, reads keep working, and writes resume.
+func TestQuiesceFail(t *testing.T) {
+	q := WithQuiesce(NewSwissMapUint64(0))
+	require.NoError(t, q.Put(hashN(1), 1))
+
+	require.NoError(t, q.QuiesceWrites(context.Background(), QuiesceFail))
+	assert.True(t, q.Quiesced())
+	require.ErrorIs(t, q.QuiesceWrites(context.Background(), QuiesceFail), ErrAlreadyQuiesced)
+
+	require.ErrorIs(t, q.Put(hashN(2), 2), ErrWritesQuiesced)
+	require.ErrorIs(t, q.Delete(hashN(1)), ErrWritesQuiesced)
+
+	_, err := q.SetIfNotExists(hashN(2), 2)
+	require.ErrorIs(t, err, ErrWritesQuiesced)
+
+	value, ok := q.Get(hashN(1))
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), value)
+
+	q.ResumeWrites()
+	q.ResumeWrites()
+	assert.False(t, q.Quiesced())
+	require.NoError(t, q.Put(hashN(2), 2))
+}
+
+// TestQuiesceBlock tests that writes wait while quiesced with QuiesceBlock
+// and are applied after ResumeWrites.
+func TestQuiesceBlock(t *testing.T) {
+	q := WithQuiesce(NewSplitSwissMapUint64(0, 4))
+	require.NoError(t, q.QuiesceWrites(context.Background(), QuiesceBlock))
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- q.Put(hashN(1), 1)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("write was not held off")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.False(t, q.Exists(hashN(1)))
+
+	q.ResumeWrites()
+	require.NoError(t, <-done)
+	assert.True(t, q.Exists(hashN(1)))
+}
+
+// TestQuiesceDrain tests that QuiesceWrites waits for a running write, and
+// gives up with the context error, resuming writes, if it does not return.
+func TestQuiesceDrain(t *testing.T) {
+	q := WithQuiesce(NewNativeMapUint64(0))
+
+	// hold a write open
+	require.NoError(t, q.enter(true))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, q.QuiesceWrites(ctx, QuiesceFail), context.DeadlineExceeded)
+	assert.False(t, q.Quiesced())
+
+	quiesced := make(chan error, 1)
+
+	go func() {
+		quiesced <- q.QuiesceWrites(context.Background(), QuiesceFail)
+	}()
+
+	require.Eventually(t, q.Quiesced, time.Second, time.Millisecond)
+
+	select {
+	case <-quiesced:
+		t.Fatal("QuiesceWrites returned before the running write")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.exit()
+	require.NoError(t, <-quiesced)
+	require.ErrorIs(t, q.Put(hashN(1), 1), ErrWritesQuiesced)
+}
@@ -0,0 +1,474 @@
+package txmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// MutationOp is the operation described by a MutationRecord.
+type MutationOp uint8
+
+const (
+	// MutationPut records Put.
+	MutationPut MutationOp = iota + 1
+
+	// MutationPutMulti records PutMulti.
+	MutationPutMulti
+
+	// MutationSet records Set.
+	MutationSet
+
+	// MutationSetIfExists records SetIfExists.
+	MutationSetIfExists
+
+	// MutationSetIfNotExists records SetIfNotExists.
+	MutationSetIfNotExists
+
+	// MutationDelete records Delete.
+	MutationDelete
+
+	// MutationFreeze records Freeze.
+	MutationFreeze
+
+	// MutationClear records Clear.
+	MutationClear
+)
+
+// ErrReplayDiverged is returned by Replay when an operation has a different
+// outcome than when it was recorded.
+var ErrReplayDiverged = errors.New("replay diverged from recording")
+
+// String returns the name of the method the operation records.
+func (op MutationOp) String() string {
+	switch op {
+	case MutationPut:
+		return "Put"
+	case MutationPutMulti:
+		return "PutMulti"
+	case MutationSet:
+		return "Set"
+	case MutationSetIfExists:
+		return "SetIfExists"
+	case MutationSetIfNotExists:
+		return "SetIfNotExists"
+	case MutationDelete:
+		return "Delete"
+	case MutationFreeze:
+		return "Freeze"
+	case MutationClear:
+		return "Clear"
+	default:
+		return "MutationOp(" + strconv.Itoa(int(op)) + ")"
+	}
+}
+
+// MutationRecord describes one write made through a RecordingTxMap.
+type MutationRecord struct {
+	// Seq numbers the records of a RecordingTxMap from 1 in the order the
+	// writes were applied.
+	Seq uint64 `json:"seq"`
+
+	// Time is when the write completed.
+	Time time.Time `json:"time"`
+
+	// Goroutine is the ID of the goroutine that made the write.
+	Goroutine uint64 `json:"goroutine"`
+
+	Op     MutationOp       `json:"op"`
+	Hashes []chainhash.Hash `json:"hashes,omitempty"`
+	Value  uint64           `json:"value,omitempty"`
+
+	// Applied is the bool result of SetIfExists and SetIfNotExists, and
+	// whether the other writes returned no error.
+	Applied bool `json:"applied"`
+
+	// Err is the error returned by the write, if any.
+	Err string `json:"err,omitempty"`
+
+	// Replayed is set for the records a Subscription replays from the
+	// contents of the map rather than from a write.
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// MutationRecorder receives the records of a RecordingTxMap. RecordMutation
+// is called with the map's write lock held, so it must not call back into it.
+type MutationRecorder interface {
+	RecordMutation(r MutationRecord)
+}
+
+// check that RecordingTxMap implements TxMap
+var _ TxMap = (*RecordingTxMap)(nil)
+
+// RecordingTxMap wraps a TxMap and records every write, with a timestamp and
+// the ID of the calling goroutine, so that the sequence of operations that got
+// a production map into an unexpected state can be inspected and re-applied to
+// a fresh map with Replay.
+//
+// Writes are serialized so that the recorded order is exactly the order in
+// which they were applied; this is a debugging aid, not meant for hot paths.
+type RecordingTxMap struct {
+	m   TxMap
+	rec MutationRecorder
+
+	mu   sync.Mutex
+	seq  uint64
+	subs map[*Subscription]struct{}
+}
+
+// NewRecordingTxMap returns a RecordingTxMap that forwards every operation to
+// m and records writes to rec.
+//
+// Params:
+//   - m: The map to wrap.
+//   - rec: Receives the records, e.g. a MutationRing or a MutationLogWriter.
+//
+// Returns:
+//   - *RecordingTxMap: The wrapping map.
+func NewRecordingTxMap(m TxMap, rec MutationRecorder) *RecordingTxMap {
+	m = nonNilTxMap(m)
+
+	return &RecordingTxMap{m: m, rec: rec}
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (r *RecordingTxMap) Exists(hash chainhash.Hash) bool {
+	return r.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (r *RecordingTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return r.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (r *RecordingTxMap) Keys() []chainhash.Hash {
+	return r.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (r *RecordingTxMap) Length() int {
+	return r.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (r *RecordingTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	r.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map and records it.
+func (r *RecordingTxMap) Put(hash chainhash.Hash, value uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.m.Put(hash, value)
+	r.record(MutationPut, []chainhash.Hash{hash}, value, err == nil, err)
+
+	return err
+}
+
+// PutMulti adds hashes to the wrapped map and records it.
+func (r *RecordingTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.m.PutMulti(hashes, value)
+	r.record(MutationPutMulti, append([]chainhash.Hash(nil), hashes...), value, err == nil, err)
+
+	return err
+}
+
+// Set updates hash in the wrapped map and records it.
+func (r *RecordingTxMap) Set(hash chainhash.Hash, value uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.m.Set(hash, value)
+	r.record(MutationSet, []chainhash.Hash{hash}, value, err == nil, err)
+
+	return err
+}
+
+// SetIfExists updates hash in the wrapped map if it exists and records it.
+func (r *RecordingTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ok, err := r.m.SetIfExists(hash, value)
+	r.record(MutationSetIfExists, []chainhash.Hash{hash}, value, ok, err)
+
+	return ok, err
+}
+
+// SetIfNotExists adds hash to the wrapped map if it does not exist and records it.
+func (r *RecordingTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ok, err := r.m.SetIfNotExists(hash, value)
+	r.record(MutationSetIfNotExists, []chainhash.Hash{hash}, value, ok, err)
+
+	return ok, err
+}
+
+// Delete removes hash from the wrapped map and records it.
+func (r *RecordingTxMap) Delete(hash chainhash.Hash) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.m.Delete(hash)
+	r.record(MutationDelete, []chainhash.Hash{hash}, 0, err == nil, err)
+
+	return err
+}
+
+// Freeze freezes the wrapped map and records it.
+func (r *RecordingTxMap) Freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.m.Freeze()
+	r.record(MutationFreeze, nil, 0, true, nil)
+}
+
+// Clear clears the wrapped map and records it.
+func (r *RecordingTxMap) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.m.Clear()
+	r.record(MutationClear, nil, 0, true, nil)
+}
+
+// record passes a record of a completed write to the recorder. The caller
+// must hold the lock.
+func (r *RecordingTxMap) record(op MutationOp, hashes []chainhash.Hash, value uint64, applied bool, err error) {
+	r.seq++
+
+	rec := MutationRecord{
+		Seq:       r.seq,
+		Time:      time.Now(),
+		Goroutine: goroutineID(),
+		Op:        op,
+		Hashes:    hashes,
+		Value:     value,
+		Applied:   applied,
+	}
+
+	if err != nil {
+		rec.Err = err.Error()
+	}
+
+	r.rec.RecordMutation(rec)
+
+	for s := range r.subs {
+		s.push(rec)
+	}
+}
+
+// Replay re-applies recorded writes to dst in sequence order, checking that
+// each has the outcome it had when it was recorded.
+//
+// Params:
+//   - dst: The map to apply the writes to, normally a fresh one. When the
+//     records come from a MutationRing that dropped older records, dst must
+//     hold the state before the first remaining record for outcomes to match.
+//   - records: The records to apply; they are sorted by Seq in place.
+//
+// Returns:
+//   - error: ErrReplayDiverged for the first write whose outcome differs from
+//     the recording; records after it are not applied.
+func Replay(dst TxMap, records []MutationRecord) error {
+	sort.Slice(records, func(i, j int) bool { return records[i].Seq < records[j].Seq })
+
+	for _, rec := range records {
+		applied, err := replayRecord(dst, rec)
+
+		if applied != rec.Applied || (err != nil) != (rec.Err != "") {
+			return fmt.Errorf("%w: %s #%d: applied %t, err %v; recorded applied %t, err %q",
+				ErrReplayDiverged, rec.Op, rec.Seq, applied, err, rec.Applied, rec.Err)
+		}
+	}
+
+	return nil
+}
+
+// replayRecord applies a single record to dst.
+func replayRecord(dst TxMap, rec MutationRecord) (bool, error) {
+	var hash chainhash.Hash
+	if len(rec.Hashes) > 0 {
+		hash = rec.Hashes[0]
+	}
+
+	var err error
+
+	switch rec.Op {
+	case MutationPut:
+		err = dst.Put(hash, rec.Value)
+	case MutationPutMulti:
+		err = dst.PutMulti(rec.Hashes, rec.Value)
+	case MutationSet:
+		err = dst.Set(hash, rec.Value)
+	case MutationSetIfExists:
+		return dst.SetIfExists(hash, rec.Value)
+	case MutationSetIfNotExists:
+		return dst.SetIfNotExists(hash, rec.Value)
+	case MutationDelete:
+		err = dst.Delete(hash)
+	case MutationFreeze:
+		dst.Freeze()
+	case MutationClear:
+		dst.Clear()
+	default:
+		return false, fmt.Errorf("%w: unknown operation %d", ErrReplayDiverged, rec.Op)
+	}
+
+	return err == nil, err
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from the header
+// of its stack trace. It is only meant for diagnostics.
+func goroutineID() uint64 {
+	var buf [64]byte
+
+	n := runtime.Stack(buf[:], false)
+	fields := bytes.Fields(bytes.TrimPrefix(buf[:n], []byte("goroutine ")))
+
+	if len(fields) == 0 {
+		return 0
+	}
+
+	id, _ := strconv.ParseUint(string(fields[0]), 10, 64)
+
+	return id
+}
+
+// MutationRing is a MutationRecorder keeping the most recent records in a
+// bounded ring buffer.
+type MutationRing struct {
+	mu      sync.Mutex
+	records []MutationRecord
+	next    int
+	full    bool
+}
+
+// NewMutationRing returns an empty ring holding up to capacity records.
+//
+// Params:
+//   - capacity: The maximum number of records kept; values below one mean one.
+//
+// Returns:
+//   - *MutationRing: The empty ring.
+func NewMutationRing(capacity int) *MutationRing {
+	return &MutationRing{records: make([]MutationRecord, max(capacity, 1))}
+}
+
+// RecordMutation stores r, dropping the oldest record if the ring is full.
+func (m *MutationRing) RecordMutation(r MutationRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[m.next] = r
+	m.next = (m.next + 1) % len(m.records)
+
+	if m.next == 0 {
+		m.full = true
+	}
+}
+
+// Records returns the stored records, oldest first.
+func (m *MutationRing) Records() []MutationRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.full {
+		return append([]MutationRecord(nil), m.records[:m.next]...)
+	}
+
+	return append(append([]MutationRecord(nil), m.records[m.next:]...), m.records[:m.next]...)
+}
+
+// MutationLogWriter is a MutationRecorder writing every record to an
+// io.Writer as a line of JSON, to be read back with ReadMutationLog.
+type MutationLogWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewMutationLogWriter returns a MutationLogWriter writing to w.
+//
+// Params:
+//   - w: The destination, e.g. a file; writes are not buffered.
+//
+// Returns:
+//   - *MutationLogWriter: The log writer.
+func NewMutationLogWriter(w io.Writer) *MutationLogWriter {
+	return &MutationLogWriter{w: w}
+}
+
+// RecordMutation writes r. After the first error, further records are
+// dropped; see Err.
+func (l *MutationLogWriter) RecordMutation(r MutationRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return
+	}
+
+	line, err := json.Marshal(r)
+	if err == nil {
+		_, err = l.w.Write(append(line, '\n'))
+	}
+
+	l.err = err
+}
+
+// Err returns the first error writing the log, if any.
+func (l *MutationLogWriter) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// ReadMutationLog reads the records written by a MutationLogWriter.
+//
+// Params:
+//   - r: The log.
+//
+// Returns:
+//   - []MutationRecord: The records in the order they were written.
+//   - error: Any read or decoding error, naming the line.
+func ReadMutationLog(r io.Reader) ([]MutationRecord, error) {
+	var records []MutationRecord
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var rec MutationRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return records, fmt.Errorf("mutation log line %d: %w", line, err)
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, scanner.Err()
+}
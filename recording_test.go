@@ -0,0 +1,94 @@
+package txmap
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecordingTxMap runs the TxMap conformance tests against a RecordingTxMap.
+func TestRecordingTxMap(t *testing.T) {
+	testTxMap(t, NewRecordingTxMap(NewNativeMapUint64(100), NewMutationRing(16)))
+}
+
+// applyRecordedWrites makes concurrent writes, including failing ones, to m.
+func applyRecordedWrites(t *testing.T, m TxMap) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := w * 100; i < w*100+100; i++ {
+				_ = m.Put(hashN(i), uint64(i))
+				_ = m.Put(hashN(i), uint64(i)) // fails
+				_, _ = m.SetIfExists(hashN(i), uint64(i+1))
+				_, _ = m.SetIfNotExists(hashN(i%7), 1)
+
+				if i%3 == 0 {
+					_ = m.Delete(hashN(i))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(1000), hashN(1001)}, 5))
+}
+
+// TestRecordingTxMapReplay tests that replaying the recorded log on a fresh
+// map reproduces the recorded map, and detects divergence.
+func TestRecordingTxMapReplay(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := NewMutationLogWriter(&buf)
+	src := NewRecordingTxMap(NewNativeMapUint64(500), log)
+
+	applyRecordedWrites(t, src)
+	require.NoError(t, log.Err())
+
+	records, err := ReadMutationLog(&buf)
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	assert.Equal(t, uint64(1), records[0].Seq)
+	assert.NotZero(t, records[0].Goroutine)
+	assert.False(t, records[0].Time.IsZero())
+
+	dst := NewSplitSwissMapUint64(500, 16)
+	require.NoError(t, Replay(dst, records))
+	requireSameContents(t, src, dst)
+
+	// replaying on a map that already holds the entries diverges
+	require.ErrorIs(t, Replay(dst, records), ErrReplayDiverged)
+}
+
+// TestMutationRing tests that the ring keeps the most recent records in order.
+func TestMutationRing(t *testing.T) {
+	ring := NewMutationRing(3)
+	m := NewRecordingTxMap(NewNativeMapUint64(10), ring)
+
+	require.NoError(t, m.Put(hashN(1), 1))
+	assert.Len(t, ring.Records(), 1)
+
+	require.NoError(t, m.Set(hashN(1), 2))
+	require.Error(t, m.Delete(hashN(2)))
+	m.Freeze()
+	m.Clear()
+
+	records := ring.Records()
+	require.Len(t, records, 3)
+	assert.Equal(t, []uint64{3, 4, 5}, []uint64{records[0].Seq, records[1].Seq, records[2].Seq})
+	assert.Equal(t, MutationDelete, records[0].Op)
+	assert.False(t, records[0].Applied)
+	assert.NotEmpty(t, records[0].Err)
+	assert.Equal(t, "Clear", records[2].Op.String())
+}
@@ -0,0 +1,158 @@
+package txmap
+
+import (
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// RefCountMap tracks how many holders, e.g. subtrees or blocks, still
+// reference each transaction: AddRef increments the count of a hash, Release
+// decrements it, and the hash is removed once its count drops to zero.
+//
+// Hashes are spread over buckets like the split maps, each with its own lock,
+// so concurrent AddRef and Release calls on different hashes rarely contend.
+type RefCountMap struct {
+	buckets     []refCountBucket
+	nrOfBuckets uint16
+}
+
+// refCountBucket is one lock-protected bucket of a RefCountMap.
+type refCountBucket struct {
+	mu sync.RWMutex
+	m  map[chainhash.Hash]uint64
+}
+
+// NewRefCountMap returns an empty RefCountMap sized for length hashes.
+//
+// Params:
+//   - length: The expected number of referenced hashes.
+//   - buckets (optional): The number of buckets, 1024 by default.
+//
+// Returns:
+//   - *RefCountMap: The empty map.
+func NewRefCountMap(length uint32, buckets ...uint16) *RefCountMap {
+	useBuckets := uint16(1024)
+	if len(buckets) > 0 {
+		useBuckets = buckets[0]
+	}
+
+	r := &RefCountMap{
+		buckets:     make([]refCountBucket, useBuckets),
+		nrOfBuckets: useBuckets,
+	}
+
+	for i := range r.buckets {
+		r.buckets[i].m = make(map[chainhash.Hash]uint64, length/uint32(useBuckets))
+	}
+
+	return r
+}
+
+// AddRef adds a reference to hash, inserting it with a count of one if it is
+// not referenced yet.
+//
+// Params:
+//   - hash: The referenced hash.
+//
+// Returns:
+//   - uint64: The count after the increment.
+func (r *RefCountMap) AddRef(hash chainhash.Hash) uint64 {
+	b := r.bucket(hash)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.m[hash]++
+
+	return b.m[hash]
+}
+
+// Release drops a reference to hash and removes it when no references remain.
+// Releasing a hash that is not referenced does nothing.
+//
+// Params:
+//   - hash: The released hash.
+//
+// Returns:
+//   - uint64: The count after the decrement.
+//   - bool: True if this call removed the hash.
+func (r *RefCountMap) Release(hash chainhash.Hash) (uint64, bool) {
+	b := r.bucket(hash)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count, ok := b.m[hash]
+	if !ok {
+		return 0, false
+	}
+
+	if count == 1 {
+		delete(b.m, hash)
+		return 0, true
+	}
+
+	b.m[hash] = count - 1
+
+	return count - 1, false
+}
+
+// Count returns the number of references to hash, 0 if it is not referenced.
+func (r *RefCountMap) Count(hash chainhash.Hash) uint64 {
+	b := r.bucket(hash)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.m[hash]
+}
+
+// Exists checks if hash has at least one reference.
+func (r *RefCountMap) Exists(hash chainhash.Hash) bool {
+	return r.Count(hash) > 0
+}
+
+// Length returns the number of referenced hashes.
+func (r *RefCountMap) Length() int {
+	n := 0
+
+	for i := range r.buckets {
+		b := &r.buckets[i]
+
+		b.mu.RLock()
+		n += len(b.m)
+		b.mu.RUnlock()
+	}
+
+	return n
+}
+
+// Iter iterates over the referenced hashes and their counts, one bucket at a
+// time. Stops iterating if f returns true.
+func (r *RefCountMap) Iter(f func(hash chainhash.Hash, count uint64) bool) {
+	for i := range r.buckets {
+		if r.buckets[i].iter(f) {
+			return
+		}
+	}
+}
+
+// iter iterates over the bucket under its read lock and reports whether f stopped.
+func (b *refCountBucket) iter(f func(hash chainhash.Hash, count uint64) bool) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for hash, count := range b.m {
+		if f(hash, count) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bucket returns the bucket of hash.
+func (r *RefCountMap) bucket(hash chainhash.Hash) *refCountBucket {
+	return &r.buckets[Bytes2Uint16Buckets(hash, r.nrOfBuckets)]
+}
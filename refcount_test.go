@@ -0,0 +1,88 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRefCountMap tests that hashes are removed exactly when their last
+// reference is released.
+func TestRefCountMap(t *testing.T) {
+	m := NewRefCountMap(16, 4)
+
+	assert.Equal(t, uint64(1), m.AddRef(hashN(1)))
+	assert.Equal(t, uint64(2), m.AddRef(hashN(1)))
+	assert.Equal(t, uint64(1), m.AddRef(hashN(2)))
+	assert.Equal(t, 2, m.Length())
+
+	count, removed := m.Release(hashN(1))
+	assert.Equal(t, uint64(1), count)
+	assert.False(t, removed)
+	assert.True(t, m.Exists(hashN(1)))
+
+	count, removed = m.Release(hashN(1))
+	assert.Equal(t, uint64(0), count)
+	assert.True(t, removed)
+	assert.False(t, m.Exists(hashN(1)))
+
+	count, removed = m.Release(hashN(1))
+	assert.Equal(t, uint64(0), count)
+	assert.False(t, removed)
+
+	counts := make(map[chainhash.Hash]uint64)
+
+	m.Iter(func(hash chainhash.Hash, count uint64) bool {
+		counts[hash] = count
+		return false
+	})
+
+	assert.Equal(t, map[chainhash.Hash]uint64{hashN(2): 1}, counts)
+}
+
+// TestRefCountMapConcurrent tests that concurrent references and releases
+// balance out.
+func TestRefCountMapConcurrent(t *testing.T) {
+	m := NewRefCountMap(64)
+
+	var (
+		wg      sync.WaitGroup
+		removed sync.Map
+	)
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				m.AddRef(hashN(i % 50))
+			}
+		}()
+	}
+
+	wg.Wait()
+	require.Equal(t, uint64(160), m.Count(hashN(0)))
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				if _, ok := m.Release(hashN(i % 50)); ok {
+					_, dup := removed.LoadOrStore(i%50, true)
+					assert.False(t, dup)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 0, m.Length())
+}
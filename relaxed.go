@@ -0,0 +1,172 @@
+package txmap
+
+import "github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+// Relaxed-consistency reads
+//
+// Monitoring consumers (metrics exporters, dashboards, debug endpoints) poll
+// map sizes and spot-check entries at high frequency but only need roughly
+// correct answers. Taking the per-bucket RWMutex for those reads contends with
+// the writers on the hot path, so the lock-based maps offer two relaxed reads:
+//
+//   - ApproxLength reads the atomically maintained entry counters without any
+//     lock. For the split maps the buckets are summed one by one while writers
+//     keep going, so the total may never have been the exact length at any
+//     single instant; it is off by at most the writes racing the call.
+//
+//   - GetRelaxed never blocks: if a writer holds the bucket lock it returns a
+//     miss instead of waiting. A false result therefore means "absent or busy",
+//     and must not be used for correctness decisions.
+//
+// Both treat a missing bucket (see buckets.go) as an empty one rather than
+// failing, as neither has an error to report it with.
+//
+// Skipping the lock entirely is not an option for reads of the entries
+// themselves: a Go map read racing a write is a fatal runtime error, and the
+// swiss map gives no better guarantee. The lock-free maps already read their
+// length atomically and take no locks, so they need neither method.
+
+// RelaxedReader is implemented by the lock-based TxMap backends.
+type RelaxedReader interface {
+	// ApproxLength returns the number of entries without taking any lock.
+	ApproxLength() int
+
+	// GetRelaxed looks up hash without ever blocking; it reports a miss if the
+	// entry is absent or its bucket is currently locked by a writer.
+	GetRelaxed(hash chainhash.Hash) (uint64, bool)
+}
+
+// check that the lock-based TxMap backends implement RelaxedReader
+var (
+	_ RelaxedReader = (*SwissMapUint64)(nil)
+	_ RelaxedReader = (*SplitSwissMap)(nil)
+	_ RelaxedReader = (*SplitSwissMapUint64)(nil)
+	_ RelaxedReader = (*NativeMapUint64)(nil)
+	_ RelaxedReader = (*NativeSplitMap)(nil)
+	_ RelaxedReader = (*NativeSplitMapUint64)(nil)
+)
+
+// --- dolthub/swiss-backed maps ----------------------------------------------
+
+// ApproxLength returns the number of hashes without taking the lock.
+func (s *SwissMap) ApproxLength() int {
+	return int(s.length.Load())
+}
+
+// ApproxLength returns the number of hashes without taking the lock.
+func (s *SwissMapUint64) ApproxLength() int {
+	return int(s.length.Load())
+}
+
+// GetRelaxed returns the value of hash, or a miss if a writer holds the lock.
+func (s *SwissMapUint64) GetRelaxed(hash chainhash.Hash) (uint64, bool) {
+	if !s.frozen.Load() {
+		if !s.mu.TryRLock() {
+			return 0, false
+		}
+
+		defer s.mu.RUnlock()
+	}
+
+	return s.m.Get(hash)
+}
+
+// ApproxLength sums the bucket lengths without taking any bucket lock.
+func (g *SplitSwissMap) ApproxLength() int {
+	return splitApproxLength(g.m)
+}
+
+// GetRelaxed returns the value of hash, or a miss if a writer holds its bucket.
+func (g *SplitSwissMap) GetRelaxed(hash chainhash.Hash) (uint64, bool) {
+	return splitGetRelaxed(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets), hash)
+}
+
+// ApproxLength sums the bucket lengths without taking any bucket lock.
+func (g *SplitSwissMapUint64) ApproxLength() int {
+	return splitApproxLength(g.m)
+}
+
+// GetRelaxed returns the value of hash, or a miss if a writer holds its bucket.
+func (g *SplitSwissMapUint64) GetRelaxed(hash chainhash.Hash) (uint64, bool) {
+	return splitGetRelaxed(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets), hash)
+}
+
+// --- native-map-backed maps -------------------------------------------------
+
+// ApproxLength returns the number of hashes without taking the lock.
+func (s *NativeMap) ApproxLength() int {
+	return int(s.length.Load())
+}
+
+// ApproxLength returns the number of hashes without taking the lock.
+func (s *NativeMapUint64) ApproxLength() int {
+	return int(s.length.Load())
+}
+
+// GetRelaxed returns the value of hash, or a miss if a writer holds the lock.
+func (s *NativeMapUint64) GetRelaxed(hash chainhash.Hash) (uint64, bool) {
+	if !s.frozen.Load() {
+		if !s.mu.TryRLock() {
+			return 0, false
+		}
+
+		defer s.mu.RUnlock()
+	}
+
+	n, ok := s.m[hash]
+
+	return n, ok
+}
+
+// ApproxLength sums the bucket lengths without taking any bucket lock.
+func (g *NativeSplitMap) ApproxLength() int {
+	return splitApproxLength(g.m)
+}
+
+// GetRelaxed returns the value of hash, or a miss if a writer holds its bucket.
+func (g *NativeSplitMap) GetRelaxed(hash chainhash.Hash) (uint64, bool) {
+	return splitGetRelaxed(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets), hash)
+}
+
+// ApproxLength sums the bucket lengths without taking any bucket lock.
+func (g *NativeSplitMapUint64) ApproxLength() int {
+	return splitApproxLength(g.m)
+}
+
+// GetRelaxed returns the value of hash, or a miss if a writer holds its bucket.
+func (g *NativeSplitMapUint64) GetRelaxed(hash chainhash.Hash) (uint64, bool) {
+	return splitGetRelaxed(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets), hash)
+}
+
+// splitApproxLength sums the ApproxLength of the buckets of a split map,
+// skipping missing buckets.
+func splitApproxLength[M interface {
+	comparable
+	ApproxLength() int
+}](buckets []M,
+) int {
+	length := 0
+
+	for i := range buckets {
+		if bucket, err := bucketAt(buckets, uint64(i)); err == nil {
+			length += bucket.ApproxLength()
+		}
+	}
+
+	return length
+}
+
+// splitGetRelaxed looks hash up in its bucket with GetRelaxed, reporting a
+// miss if the bucket is missing.
+func splitGetRelaxed[M interface {
+	comparable
+	GetRelaxed(hash chainhash.Hash) (uint64, bool)
+}](buckets []M, bucket uint16, hash chainhash.Hash,
+) (uint64, bool) {
+	b, err := bucketAt(buckets, bucket)
+	if err != nil {
+		return 0, false
+	}
+
+	return b.GetRelaxed(hash)
+}
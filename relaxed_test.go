@@ -0,0 +1,75 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRelaxedReads tests ApproxLength and GetRelaxed on every lock-based
+// TxMap, including concurrently with writers (run with -race).
+func TestRelaxedReads(t *testing.T) {
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+			r, ok := m.(RelaxedReader)
+			require.True(t, ok)
+
+			var wg sync.WaitGroup
+
+			wg.Add(2)
+
+			go func() {
+				defer wg.Done()
+
+				for i := 0; i < 1000; i++ {
+					_ = m.Put(hashN(i), uint64(i)) //nolint:gosec // test sizes are small
+				}
+			}()
+
+			go func() {
+				defer wg.Done()
+
+				for i := 0; i < 1000; i++ {
+					assert.LessOrEqual(t, r.ApproxLength(), 1000)
+					_, _ = r.GetRelaxed(hashN(i))
+				}
+			}()
+
+			wg.Wait()
+
+			assert.Equal(t, 1000, r.ApproxLength())
+
+			v, found := r.GetRelaxed(hashN(42))
+			assert.True(t, found)
+			assert.Equal(t, uint64(42), v)
+
+			_, found = r.GetRelaxed(hashN(5000))
+			assert.False(t, found)
+		})
+	}
+}
+
+// TestGetRelaxedDoesNotBlock tests that GetRelaxed reports a miss instead of
+// waiting while a writer holds the lock, and reads lock-free once frozen.
+func TestGetRelaxedDoesNotBlock(t *testing.T) {
+	m := NewNativeMapUint64(10)
+	require.NoError(t, m.Put(hashN(1), 1))
+
+	m.mu.Lock()
+	_, found := m.GetRelaxed(hashN(1))
+	assert.False(t, found)
+	assert.Equal(t, 1, m.ApproxLength())
+	m.mu.Unlock()
+
+	_, found = m.GetRelaxed(hashN(1))
+	assert.True(t, found)
+
+	m.Freeze()
+	m.mu.Lock()
+	_, found = m.GetRelaxed(hashN(1))
+	assert.True(t, found)
+	m.mu.Unlock()
+}
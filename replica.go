@@ -0,0 +1,237 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Read-your-writes
+//
+// A ReadReplica serves reads from its own copy of a RecordingTxMap, kept up
+// to date from a Subscription, so that read-heavy consumers do not contend
+// with the writers. Its reads lag behind the writes: a consumer that has just
+// written through the source and reads from the replica may not see its own
+// write yet.
+//
+// Write tokens close that gap where it matters. WriteToken on the source
+// returns the sequence number of the last write made through it; WaitFor on
+// the replica blocks until that write has been applied. A writer hands the
+// token it took after its write to the reader, and the reader waits for it
+// before reading, getting read-your-writes consistency for those reads while
+// all others stay relaxed.
+//
+// The replica applies the records in sequence order, so after WaitFor(token)
+// returns it shows the state of the source as of the write of token or a
+// later one, never a mix.
+
+// ErrReplicaClosed is returned by ReadReplica.WaitFor after Close.
+var ErrReplicaClosed = errors.New("read replica is closed")
+
+// WriteToken identifies a write made through a RecordingTxMap by the Seq of
+// its record. Tokens of later writes are greater.
+type WriteToken uint64
+
+// WriteToken returns the token of the last write made through the map, 0 if
+// there was none, to be passed to ReadReplica.WaitFor.
+func (r *RecordingTxMap) WriteToken() WriteToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return WriteToken(r.seq)
+}
+
+// check that ReadReplica implements ReadOnlyTxMap
+var _ ReadOnlyTxMap = (*ReadReplica)(nil)
+
+// ReadReplica is a read-only copy of a RecordingTxMap that follows its writes
+// asynchronously. See the notes at the top of replica.go.
+type ReadReplica struct {
+	m   TxMap
+	sub *Subscription
+
+	mu      sync.Mutex
+	applied WriteToken
+	err     error
+
+	// advanced is closed and replaced whenever applied or err changes.
+	advanced chan struct{}
+	done     chan struct{}
+}
+
+// NewReadReplica copies the contents of src into dst and keeps dst following
+// the writes made through src until ctx is done or Close is called. Writes to
+// src are held off while the contents are copied.
+//
+// Params:
+//   - ctx: Stops the replica when done.
+//   - src: The map to follow.
+//   - dst: The map holding the copy; it is cleared first and must not be
+//     written to by anything else.
+//   - maxPending: The number of writes the replica may fall behind before it
+//     stops with ErrSubscriberTooSlow, DefaultMaxPending when zero or
+//     negative.
+//
+// Returns:
+//   - *ReadReplica: The replica, caught up to the token of src at creation;
+//     Close must be called to release it.
+//   - error: An error from copying the contents into dst.
+func NewReadReplica(ctx context.Context, src *RecordingTxMap, dst TxMap, maxPending int) (*ReadReplica, error) {
+	dst = nonNilTxMap(dst)
+	dst.Clear()
+
+	src.mu.Lock()
+
+	var err error
+
+	src.m.Iter(func(hash chainhash.Hash, value uint64) bool {
+		err = dst.Put(hash, value)
+		return err != nil
+	})
+
+	if err != nil {
+		src.mu.Unlock()
+		return nil, fmt.Errorf("copying into replica: %w", err)
+	}
+
+	opts := SubscribeOptions{MaxPending: maxPending}
+	sub := src.subscribeLocked(opts)
+	applied := WriteToken(src.seq)
+	src.mu.Unlock()
+
+	go sub.run(ctx, opts)
+
+	rr := &ReadReplica{
+		m:        dst,
+		sub:      sub,
+		applied:  applied,
+		advanced: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go rr.run()
+
+	return rr, nil
+}
+
+// Applied returns the token of the last write the replica has applied.
+func (rr *ReadReplica) Applied() WriteToken {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	return rr.applied
+}
+
+// WaitFor blocks until the replica has applied the write of token.
+//
+// Params:
+//   - ctx: Ends the wait when done.
+//   - token: A token returned by WriteToken of the source.
+//
+// Returns:
+//   - error: nil once the write is visible; the context error; or the reason
+//     the replica stopped before applying it: ErrReplicaClosed,
+//     ErrSubscriberTooSlow, the context error of the replica or an error
+//     wrapping ErrReplayDiverged.
+func (rr *ReadReplica) WaitFor(ctx context.Context, token WriteToken) error {
+	for {
+		rr.mu.Lock()
+		applied, err, advanced := rr.applied, rr.err, rr.advanced
+		rr.mu.Unlock()
+
+		if applied >= token {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-advanced:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops following the source and waits until the replica has stopped.
+// The replica remains readable. It is safe to call more than once.
+func (rr *ReadReplica) Close() {
+	rr.sub.Close()
+	<-rr.done
+}
+
+// Exists checks if the given hash exists in the replica.
+func (rr *ReadReplica) Exists(hash chainhash.Hash) bool {
+	return rr.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the replica.
+func (rr *ReadReplica) Get(hash chainhash.Hash) (uint64, bool) {
+	return rr.m.Get(hash)
+}
+
+// Keys returns all hashes in the replica.
+func (rr *ReadReplica) Keys() []chainhash.Hash {
+	return rr.m.Keys()
+}
+
+// Length returns the number of hashes in the replica.
+func (rr *ReadReplica) Length() int {
+	return rr.m.Length()
+}
+
+// Iter iterates over the replica. Stops iterating if f returns true.
+func (rr *ReadReplica) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	rr.m.Iter(f)
+}
+
+// run applies the records of the subscription until it ends.
+func (rr *ReadReplica) run() {
+	defer close(rr.done)
+
+	for rec := range rr.sub.C {
+		applied, err := replayRecord(rr.m, rec)
+
+		if applied != rec.Applied || (err != nil) != (rec.Err != "") {
+			rr.advance(0, fmt.Errorf("%w: %s #%d", ErrReplayDiverged, rec.Op, rec.Seq))
+			rr.sub.Close()
+
+			for range rr.sub.C {
+			}
+
+			return
+		}
+
+		rr.advance(WriteToken(rec.Seq), nil)
+	}
+
+	err := rr.sub.Err()
+	if err == nil {
+		err = ErrReplicaClosed
+	}
+
+	rr.advance(0, err)
+}
+
+// advance records the token of an applied write, or the reason the replica
+// stopped, and wakes the waiters.
+func (rr *ReadReplica) advance(token WriteToken, err error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if token > rr.applied {
+		rr.applied = token
+	}
+
+	if err != nil && rr.err == nil {
+		rr.err = err
+	}
+
+	close(rr.advanced)
+	rr.advanced = make(chan struct{})
+}
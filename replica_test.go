@@ -0,0 +1,83 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadReplicaWaitFor tests that a read after WaitFor sees the write whose
+// token was waited for, and that the replica ends up a copy of the source.
+func TestReadReplicaWaitFor(t *testing.T) {
+	src := NewRecordingTxMap(NewSplitSwissMapUint64(0, 8), NewMutationRing(1))
+	for i := range 100 {
+		require.NoError(t, src.Put(hashN(i), uint64(i)))
+	}
+
+	rr, err := NewReadReplica(context.Background(), src, NewSplitSwissMapUint64(0, 8), 0)
+	require.NoError(t, err)
+
+	defer rr.Close()
+
+	assert.Equal(t, src.WriteToken(), rr.Applied())
+	assert.Equal(t, 100, rr.Length())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 100; i < 200; i++ {
+		require.NoError(t, src.Put(hashN(i), uint64(i)))
+		require.NoError(t, src.Delete(hashN(i-100)))
+
+		token := src.WriteToken()
+		require.NoError(t, rr.WaitFor(ctx, token))
+		assert.GreaterOrEqual(t, rr.Applied(), token)
+
+		value, ok := rr.Get(hashN(i))
+		require.True(t, ok)
+		assert.Equal(t, uint64(i), value)
+		assert.False(t, rr.Exists(hashN(i-100)))
+	}
+
+	requireSameContents(t, src, rr)
+}
+
+// TestReadReplicaWaitForContext tests that WaitFor for a write that was never
+// made returns when its context ends.
+func TestReadReplicaWaitForContext(t *testing.T) {
+	src := NewRecordingTxMap(NewSwissMapUint64(0), NewMutationRing(1))
+
+	rr, err := NewReadReplica(context.Background(), src, NewSwissMapUint64(0), 0)
+	require.NoError(t, err)
+
+	defer rr.Close()
+
+	require.NoError(t, rr.WaitFor(context.Background(), 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, rr.WaitFor(ctx, src.WriteToken()+1), context.DeadlineExceeded)
+}
+
+// TestReadReplicaClose tests that WaitFor reports ErrReplicaClosed after
+// Close, and that the copy stays readable.
+func TestReadReplicaClose(t *testing.T) {
+	src := NewRecordingTxMap(NewNativeMapUint64(0), NewMutationRing(1))
+	require.NoError(t, src.Put(hashN(1), 1))
+
+	rr, err := NewReadReplica(context.Background(), src, NewNativeMapUint64(0), 0)
+	require.NoError(t, err)
+
+	rr.Close()
+	rr.Close()
+
+	require.NoError(t, src.Put(hashN(2), 2))
+	require.ErrorIs(t, rr.WaitFor(context.Background(), src.WriteToken()), ErrReplicaClosed)
+
+	assert.True(t, rr.Exists(hashN(1)))
+	assert.False(t, rr.Exists(hashN(2)))
+}
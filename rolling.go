@@ -0,0 +1,313 @@
+package txmap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that RollingTxMap implements TxMap
+var _ TxMap = (*RollingTxMap)(nil)
+
+// RollingTxMap is a ring of time-sliced sub-maps for sets like "seen in the
+// last hour". Inserts go to the current slice and lookups check every slice;
+// Rotate drops the oldest slice wholesale and makes it the new current one, so
+// expiring a whole slice costs a single Clear instead of a delete per entry.
+//
+// With n slices rotated every interval, an entry stays visible for between
+// (n-1)*interval and n*interval after it was inserted. A hash lives in exactly
+// one slice: Put fails for hashes in any slice, and updates stay in the slice
+// that holds the hash, so they do not extend its life.
+type RollingTxMap struct {
+	mu      sync.RWMutex
+	slices  []TxMap
+	current int
+	frozen  atomic.Bool
+}
+
+// NewRollingTxMap returns a RollingTxMap of n slices.
+//
+// Params:
+//   - n: The number of slices; values below two mean two.
+//   - newSlice: Creates each slice, e.g. a NativeMapUint64 sized for the
+//     inserts expected per interval. Slices are recycled with Clear.
+//
+// Returns:
+//   - *RollingTxMap: The rolling map.
+func NewRollingTxMap(n int, newSlice func() TxMap) *RollingTxMap {
+	r := &RollingTxMap{slices: make([]TxMap, max(n, 2))}
+
+	for i := range r.slices {
+		r.slices[i] = newSlice()
+	}
+
+	return r
+}
+
+// Rotate drops all entries of the oldest slice and makes it the current one.
+//
+// Returns:
+//   - int: The number of entries dropped.
+//   - error: ErrMapFrozen if the map is frozen.
+func (r *RollingTxMap) Rotate() (int, error) {
+	if r.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldest := (r.current + 1) % len(r.slices)
+	dropped := r.slices[oldest].Length()
+
+	r.slices[oldest].Clear()
+	r.current = oldest
+
+	return dropped, nil
+}
+
+// RotateEvery calls Rotate every interval until ctx is done. It blocks, so run
+// it in its own goroutine.
+//
+// Params:
+//   - ctx: Stops the rotation when done.
+//   - interval: The time span covered by each slice.
+//   - onRotate: Called with the result of every Rotate; may be nil.
+func (r *RollingTxMap) RotateEvery(ctx context.Context, interval time.Duration, onRotate func(dropped int, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dropped, err := r.Rotate()
+			if onRotate != nil {
+				onRotate(dropped, err)
+			}
+		}
+	}
+}
+
+// Slices returns the number of slices.
+func (r *RollingTxMap) Slices() int {
+	return len(r.slices)
+}
+
+// Exists checks if the given hash exists in any slice.
+func (r *RollingTxMap) Exists(hash chainhash.Hash) bool {
+	_, ok := r.Get(hash)
+	return ok
+}
+
+// Get retrieves the value of hash, checking the newest slice first.
+func (r *RollingTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if m := r.find(hash); m != nil {
+		return m.Get(hash)
+	}
+
+	return 0, false
+}
+
+// Keys returns the hashes of all slices.
+func (r *RollingTxMap) Keys() []chainhash.Hash {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]chainhash.Hash, 0, r.lengthLocked())
+
+	for _, m := range r.slices {
+		keys = append(keys, m.Keys()...)
+	}
+
+	return keys
+}
+
+// Length returns the number of entries in all slices.
+func (r *RollingTxMap) Length() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lengthLocked()
+}
+
+// Iter iterates over all slices, newest first. Stops iterating if f returns
+// true. Rotation waits until the iteration is done.
+func (r *RollingTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stopped := false
+
+	for age := 0; age < len(r.slices) && !stopped; age++ {
+		r.slice(age).Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
+	}
+}
+
+// Put adds hash to the current slice. It fails if the hash is in any slice.
+func (r *RollingTxMap) Put(hash chainhash.Hash, value uint64) error {
+	if r.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.putLocked(hash, value)
+}
+
+// PutMulti adds hashes to the current slice, stopping at the first hash that
+// is already in any slice.
+func (r *RollingTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	if r.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, hash := range hashes {
+		if err := r.putLocked(hash, value); err != nil {
+			return fmt.Errorf("failed to put multi: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value of hash in the slice that holds it.
+func (r *RollingTxMap) Set(hash chainhash.Hash, value uint64) error {
+	ok, err := r.SetIfExists(hash, value)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return nil
+}
+
+// SetIfExists updates the value of hash in the slice that holds it, if any.
+func (r *RollingTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if r.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if m := r.find(hash); m != nil {
+		return m.SetIfExists(hash, value)
+	}
+
+	return false, nil
+}
+
+// SetIfNotExists adds hash to the current slice if it is in no slice.
+func (r *RollingTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if r.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if m := r.find(hash); m != nil {
+		return false, nil
+	}
+
+	return r.slices[r.current].SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the slice that holds it.
+func (r *RollingTxMap) Delete(hash chainhash.Hash) error {
+	if r.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if m := r.find(hash); m != nil {
+		return m.Delete(hash)
+	}
+
+	return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+}
+
+// Freeze freezes every slice; writes and Rotate return ErrMapFrozen.
+func (r *RollingTxMap) Freeze() {
+	r.frozen.Store(true)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, m := range r.slices {
+		m.Freeze()
+	}
+}
+
+// Clear empties and un-freezes every slice.
+func (r *RollingTxMap) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.slices {
+		m.Clear()
+	}
+
+	r.frozen.Store(false)
+}
+
+// putLocked adds hash to the current slice unless an older slice holds it.
+// The current slice itself rejects duplicates. The caller must hold the read lock.
+func (r *RollingTxMap) putLocked(hash chainhash.Hash, value uint64) error {
+	for age := 1; age < len(r.slices); age++ {
+		if r.slice(age).Exists(hash) {
+			return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+		}
+	}
+
+	return r.slices[r.current].Put(hash, value)
+}
+
+// find returns the slice holding hash, or nil, checking the newest slice first.
+// The caller must hold the read lock.
+func (r *RollingTxMap) find(hash chainhash.Hash) TxMap {
+	for age := 0; age < len(r.slices); age++ {
+		if m := r.slice(age); m.Exists(hash) {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// slice returns the slice of the given age, 0 being the current one.
+func (r *RollingTxMap) slice(age int) TxMap {
+	return r.slices[(r.current-age+len(r.slices))%len(r.slices)]
+}
+
+// lengthLocked sums the slice lengths. The caller must hold the read lock.
+func (r *RollingTxMap) lengthLocked() int {
+	length := 0
+
+	for _, m := range r.slices {
+		length += m.Length()
+	}
+
+	return length
+}
@@ -0,0 +1,65 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRollingNative returns a RollingTxMap of n NativeMapUint64 slices.
+func newRollingNative(n int) *RollingTxMap {
+	return NewRollingTxMap(n, func() TxMap { return NewNativeMapUint64(16) })
+}
+
+// TestRollingTxMap runs the TxMap conformance tests against a RollingTxMap.
+func TestRollingTxMap(t *testing.T) {
+	testTxMap(t, newRollingNative(3))
+}
+
+// TestRollingTxMapRotate tests that entries expire after a full ring of
+// rotations and that hashes in older slices stay unique and updatable.
+func TestRollingTxMapRotate(t *testing.T) {
+	r := newRollingNative(3)
+	assert.Equal(t, 3, r.Slices())
+
+	require.NoError(t, r.Put(hashN(1), 1))
+
+	dropped, err := r.Rotate()
+	require.NoError(t, err)
+	assert.Equal(t, 0, dropped)
+
+	require.NoError(t, r.Put(hashN(2), 2))
+	require.ErrorIs(t, r.Put(hashN(1), 1), ErrHashAlreadyExists)
+
+	ok, err := r.SetIfNotExists(hashN(1), 5)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, r.Set(hashN(1), 10))
+
+	_, err = r.Rotate()
+	require.NoError(t, err)
+	assert.Equal(t, 2, r.Length())
+
+	v, ok := r.Get(hashN(1))
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), v)
+
+	// the slice holding hash 1 is now the oldest and is dropped next
+	dropped, err = r.Rotate()
+	require.NoError(t, err)
+	assert.Equal(t, 1, dropped)
+	assert.False(t, r.Exists(hashN(1)))
+	assert.True(t, r.Exists(hashN(2)))
+
+	require.NoError(t, r.Put(hashN(1), 1))
+	require.NoError(t, r.Delete(hashN(2)))
+	assert.Equal(t, 1, r.Length())
+
+	r.Freeze()
+
+	_, err = r.Rotate()
+	require.ErrorIs(t, err, ErrMapFrozen)
+	assert.True(t, r.Exists(hashN(1)))
+}
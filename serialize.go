@@ -0,0 +1,181 @@
+package txmap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Serialization
+//
+// Serialize writes a map in the snapshot format of snapshot.go, and the
+// NewXFromReader constructors build a map of the same type from such a
+// stream, so that a populated map can be persisted at shutdown and reloaded
+// at the next start instead of being rebuilt from the UTXO set. The
+// constructors size the new map from the entry count in the snapshot header,
+// so reloading does not grow it step by step. Snapshots are interchangeable
+// between the map types: a SplitSwissMapUint64 can be reloaded as a
+// SwissMapUint64, and a SwissMap reloads any snapshot, dropping the values.
+//
+// Use Export and Import directly for progress reporting, cancellation or
+// older format versions.
+
+// Serialize writes the contents of the map to w in the snapshot format. The
+// map must not be written to meanwhile; see Export.
+func (s *SwissMap) Serialize(w io.Writer) error {
+	return Export(w, s)
+}
+
+// Serialize writes the contents of the map to w in the snapshot format. The
+// map must not be written to meanwhile; see Export.
+func (s *SwissMapUint64) Serialize(w io.Writer) error {
+	return Export(w, s)
+}
+
+// Serialize writes the contents of the map to w in the snapshot format. The
+// map must not be written to meanwhile; see Export.
+func (g *SplitSwissMap) Serialize(w io.Writer) error {
+	return Export(w, g)
+}
+
+// Serialize writes the contents of the map to w in the snapshot format. The
+// map must not be written to meanwhile; see Export.
+func (g *SplitSwissMapUint64) Serialize(w io.Writer) error {
+	return Export(w, g)
+}
+
+// NewSwissMapFromReader creates a SwissMap holding the hashes of the snapshot
+// read from r.
+//
+// Params:
+//   - r: The snapshot stream, e.g. written by Serialize; it is read through a
+//     buffer, past the end of the snapshot.
+//
+// Returns:
+//   - *SwissMap: The map.
+//   - error: Any error Import returns.
+func NewSwissMapFromReader(r io.Reader) (*SwissMap, error) {
+	br, count, err := peekSnapshotCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewSwissMap(count)
+
+	if _, err = Import(context.Background(), hashMapImporter{m: m}, br, ImportOptions{}); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// NewSwissMapUint64FromReader creates a SwissMapUint64 holding the entries of
+// the snapshot read from r.
+//
+// Params:
+//   - r: The snapshot stream, e.g. written by Serialize; it is read through a
+//     buffer, past the end of the snapshot.
+//
+// Returns:
+//   - *SwissMapUint64: The map.
+//   - error: Any error Import returns, e.g. for a duplicate hash.
+func NewSwissMapUint64FromReader(r io.Reader) (*SwissMapUint64, error) {
+	br, count, err := peekSnapshotCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewSwissMapUint64(count)
+
+	if _, err = Import(context.Background(), m, br, ImportOptions{}); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// NewSplitSwissMapFromReader creates a SplitSwissMap holding the entries of
+// the snapshot read from r.
+//
+// Params:
+//   - r: The snapshot stream, e.g. written by Serialize; it is read through a
+//     buffer, past the end of the snapshot.
+//   - buckets: Optionally the number of buckets, as for NewSplitSwissMap.
+//
+// Returns:
+//   - *SplitSwissMap: The map.
+//   - error: Any error Import returns, e.g. for a duplicate hash.
+func NewSplitSwissMapFromReader(r io.Reader, buckets ...uint16) (*SplitSwissMap, error) {
+	br, count, err := peekSnapshotCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewSplitSwissMap(int(count), buckets...)
+
+	if _, err = Import(context.Background(), m, br, ImportOptions{}); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// NewSplitSwissMapUint64FromReader creates a SplitSwissMapUint64 holding the
+// entries of the snapshot read from r.
+//
+// Params:
+//   - r: The snapshot stream, e.g. written by Serialize; it is read through a
+//     buffer, past the end of the snapshot.
+//   - buckets: Optionally the number of buckets, as for NewSplitSwissMapUint64.
+//
+// Returns:
+//   - *SplitSwissMapUint64: The map.
+//   - error: Any error Import returns, e.g. for a duplicate hash.
+func NewSplitSwissMapUint64FromReader(r io.Reader, buckets ...uint16) (*SplitSwissMapUint64, error) {
+	br, count, err := peekSnapshotCount(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewSplitSwissMapUint64(count, buckets...)
+
+	if _, err = Import(context.Background(), m, br, ImportOptions{}); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// peekSnapshotCount returns a buffered reader over r and the entry count of
+// the snapshot header, capped for preallocation, without consuming the header.
+func peekSnapshotCount(r io.Reader) (*bufio.Reader, uint32, error) {
+	br := bufio.NewReader(r)
+
+	// a short stream leaves buf short, which readSnapshotHeader reports
+	buf, _ := br.Peek(snapshotHeaderSize)
+
+	h, err := readSnapshotHeader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the count is untrusted until the records were read, so a corrupt header
+	// must not allocate without bound
+	return br, uint32(min(h.count, 1<<28)), nil //nolint:gosec // G115 capped above
+}
+
+// hashMapImporter adapts a TxHashMap to the Put calls of Import, dropping the
+// values. Import makes no other calls.
+type hashMapImporter struct {
+	nilTxMap
+
+	m TxHashMap
+}
+
+// Put adds hash to the wrapped map.
+func (h hashMapImporter) Put(hash chainhash.Hash, _ uint64) error {
+	return h.m.Put(hash)
+}
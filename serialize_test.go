@@ -0,0 +1,75 @@
+package txmap
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSerializeRoundTrip tests that every serializable map reloads into a
+// map of its own type with the same contents.
+func TestSerializeRoundTrip(t *testing.T) {
+	swissMap := NewSwissMap(0)
+	swissUint64 := NewSwissMapUint64(0)
+	split := NewSplitSwissMap(0, 8)
+	splitUint64 := NewSplitSwissMapUint64(0, 8)
+
+	for i := range 5000 {
+		require.NoError(t, swissMap.Put(hashN(i)))
+		require.NoError(t, swissUint64.Put(hashN(i), uint64(i)))
+		require.NoError(t, split.Put(hashN(i), uint64(i)))
+		require.NoError(t, splitUint64.Put(hashN(i), uint64(i)))
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, swissMap.Serialize(&buf))
+
+	loadedSwiss, err := NewSwissMapFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 5000, loadedSwiss.Length())
+	assert.True(t, loadedSwiss.Exists(hashN(4999)))
+
+	buf.Reset()
+	require.NoError(t, swissUint64.Serialize(&buf))
+
+	loadedUint64, err := NewSwissMapUint64FromReader(&buf)
+	require.NoError(t, err)
+	requireSameContents(t, swissUint64, loadedUint64)
+
+	buf.Reset()
+	require.NoError(t, split.Serialize(&buf))
+
+	loadedSplit, err := NewSplitSwissMapFromReader(&buf, 8)
+	require.NoError(t, err)
+	requireSameContents(t, split, loadedSplit)
+
+	buf.Reset()
+	require.NoError(t, splitUint64.Serialize(&buf))
+
+	loadedSplitUint64, err := NewSplitSwissMapUint64FromReader(&buf, 16)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(16), loadedSplitUint64.Buckets())
+	requireSameContents(t, splitUint64, loadedSplitUint64)
+}
+
+// TestSerializeInvalid tests that the constructors reject streams that are
+// not snapshots.
+func TestSerializeInvalid(t *testing.T) {
+	_, err := NewSwissMapUint64FromReader(bytes.NewReader([]byte("TXM")))
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+
+	_, err = NewSplitSwissMapFromReader(bytes.NewReader(bytes.Repeat([]byte{1}, 64)))
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+
+	var buf bytes.Buffer
+
+	m := NewSwissMapUint64(0)
+	require.NoError(t, m.Put(hashN(1), 1))
+	require.NoError(t, m.Serialize(&buf))
+
+	_, err = NewSwissMapFromReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+}
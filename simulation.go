@@ -0,0 +1,384 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Deterministic simulation
+//
+// Concurrency bugs found by running real goroutines against a map are hard to
+// reproduce, because the Go scheduler picks a different interleaving every
+// run. Simulate instead runs a number of virtual goroutines, each executing
+// its own random program of map operations, on a single real goroutine: a
+// seeded RNG decides which virtual goroutine takes the next step. The same
+// seed therefore always produces the same interleaving, and a failure reported
+// for a seed reproduces on every run.
+//
+// After every step the result of the operation is checked against a model (a
+// plain Go map), and every FullCheckEvery steps and at the end the whole
+// contents of the map are compared with the model and, for maps implementing
+// InvariantChecker, its invariants are checked.
+
+// ErrSimulationMismatch is wrapped by the SimulationError returned when the
+// map under test disagrees with the model.
+var ErrSimulationMismatch = errors.New("map disagrees with model")
+
+// SimulationOptions configures Simulate. Zero fields take the defaults noted.
+type SimulationOptions struct {
+	// Seed drives every random choice of the simulation.
+	Seed int64
+
+	// Actors is the number of virtual goroutines, 4 by default.
+	Actors int
+
+	// Steps is the total number of operations, 10000 by default.
+	Steps int
+
+	// Keys is the size of the key space; small key spaces make operations on
+	// the same hash collide often. 256 by default.
+	Keys int
+
+	// FullCheckEvery is the number of steps between full comparisons of the
+	// map with the model, 100 by default.
+	FullCheckEvery int
+
+	// IgnoreValues skips value comparisons, for maps that only store hashes.
+	IgnoreValues bool
+}
+
+// SimulationError reports the step at which a simulation failed, with the
+// seed that reproduces it.
+type SimulationError struct {
+	Seed  int64
+	Step  int
+	Actor int
+	Op    string
+	Err   error
+}
+
+// Error describes the failure and how to reproduce it.
+func (e *SimulationError) Error() string {
+	return fmt.Sprintf("simulation seed %d, step %d, actor %d, %s: %v", e.Seed, e.Step, e.Actor, e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SimulationError) Unwrap() error {
+	return e.Err
+}
+
+// Simulate runs a deterministic simulation against a fresh map; see the notes
+// at the top of this file.
+//
+// Params:
+//   - newMap: Creates the map under test.
+//   - opts: The simulation parameters.
+//
+// Returns:
+//   - error: A *SimulationError for the first failed check, or nil.
+func Simulate(newMap func() TxMap, opts SimulationOptions) error {
+	opts = opts.withDefaults()
+
+	sim := &simulation{
+		opts:  opts,
+		m:     newMap(),
+		model: make(map[chainhash.Hash]uint64),
+		sched: rand.New(rand.NewSource(opts.Seed)), //nolint:gosec // deterministic by design
+	}
+
+	for i := 0; i < opts.Actors; i++ {
+		sim.actors = append(sim.actors, rand.New(rand.NewSource(opts.Seed+int64(i)+1))) //nolint:gosec // deterministic by design
+	}
+
+	for step := 1; step <= opts.Steps; step++ {
+		actor := sim.sched.Intn(opts.Actors)
+
+		op, err := sim.step(sim.actors[actor])
+		if err == nil && (step%opts.FullCheckEvery == 0 || step == opts.Steps) {
+			err = sim.fullCheck()
+		}
+
+		if err != nil {
+			return &SimulationError{Seed: opts.Seed, Step: step, Actor: actor, Op: op, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// withDefaults fills in the zero fields.
+func (o SimulationOptions) withDefaults() SimulationOptions {
+	if o.Actors <= 0 {
+		o.Actors = 4
+	}
+
+	if o.Steps <= 0 {
+		o.Steps = 10000
+	}
+
+	if o.Keys <= 0 {
+		o.Keys = 256
+	}
+
+	if o.FullCheckEvery <= 0 {
+		o.FullCheckEvery = 100
+	}
+
+	return o
+}
+
+// simulation is the state of a running Simulate call.
+type simulation struct {
+	opts   SimulationOptions
+	m      TxMap
+	model  map[chainhash.Hash]uint64
+	sched  *rand.Rand
+	actors []*rand.Rand
+}
+
+// simulationKey returns key i of the key space. The bytes that route to
+// buckets vary, so that the keys spread over the buckets of the split maps.
+func simulationKey(i int) chainhash.Hash {
+	var hash chainhash.Hash
+
+	hash[0] = byte(i * 97)
+	hash[1] = byte(i)
+	hash[2] = byte(i >> 8)
+	hash[31] = 1
+
+	return hash
+}
+
+// step runs one random operation for the actor drawing from rng and checks
+// its result.
+func (s *simulation) step(rng *rand.Rand) (string, error) {
+	hash := simulationKey(rng.Intn(s.opts.Keys))
+	value := uint64(rng.Intn(1000)) //nolint:gosec // non-negative
+
+	switch op := rng.Intn(100); {
+	case op < 20:
+		return "Put " + hash.String(), s.checkPut(hash, value)
+	case op < 30:
+		hashes := make([]chainhash.Hash, 1+rng.Intn(4))
+		for i := range hashes {
+			hashes[i] = simulationKey(rng.Intn(s.opts.Keys))
+		}
+
+		return "PutMulti", s.checkPutMulti(hashes, value)
+	case op < 40:
+		return "Set " + hash.String(), s.checkSet(hash, value)
+	case op < 50:
+		return "SetIfExists " + hash.String(), s.checkSetIfExists(hash, value)
+	case op < 60:
+		return "SetIfNotExists " + hash.String(), s.checkSetIfNotExists(hash, value)
+	case op < 75:
+		return "Delete " + hash.String(), s.checkDelete(hash)
+	case op < 99:
+		return "Get " + hash.String(), s.checkGet(hash)
+	default:
+		s.m.Clear()
+		clear(s.model)
+
+		return "Clear", s.checkLength()
+	}
+}
+
+// checkPut runs Put and compares its outcome with the model.
+func (s *simulation) checkPut(hash chainhash.Hash, value uint64) error {
+	err := s.m.Put(hash, value)
+
+	if _, exists := s.model[hash]; exists {
+		if !errors.Is(err, ErrHashAlreadyExists) {
+			return fmt.Errorf("%w: Put of existing hash returned %v", ErrSimulationMismatch, err)
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: Put of new hash returned %w", ErrSimulationMismatch, err)
+	}
+
+	s.model[hash] = value
+
+	return s.checkLength()
+}
+
+// checkPutMulti runs PutMulti. Maps differ in how much of a batch with a
+// duplicate they insert, so after a failure only the consistency of each
+// hash of the batch is checked and the model follows the map.
+func (s *simulation) checkPutMulti(hashes []chainhash.Hash, value uint64) error {
+	err := s.m.PutMulti(hashes, value)
+
+	duplicate := false
+	seen := make(map[chainhash.Hash]bool, len(hashes))
+
+	for _, hash := range hashes {
+		_, exists := s.model[hash]
+		duplicate = duplicate || exists || seen[hash]
+		seen[hash] = true
+	}
+
+	if !duplicate && err != nil {
+		return fmt.Errorf("%w: PutMulti of new hashes returned %w", ErrSimulationMismatch, err)
+	}
+
+	if duplicate && !errors.Is(err, ErrHashAlreadyExists) {
+		return fmt.Errorf("%w: PutMulti with a duplicate returned %v", ErrSimulationMismatch, err)
+	}
+
+	for _, hash := range hashes {
+		if before, existed := s.model[hash]; existed && seen[hash] {
+			if got, ok := s.m.Get(hash); !ok || (!s.opts.IgnoreValues && got != before) {
+				return fmt.Errorf("%w: PutMulti changed existing hash %s", ErrSimulationMismatch, hash)
+			}
+
+			continue
+		}
+
+		if _, ok := s.m.Get(hash); ok {
+			s.model[hash] = value
+		}
+	}
+
+	return s.checkLength()
+}
+
+// checkSet runs Set and compares its outcome with the model.
+func (s *simulation) checkSet(hash chainhash.Hash, value uint64) error {
+	err := s.m.Set(hash, value)
+
+	if _, exists := s.model[hash]; !exists {
+		if !errors.Is(err, ErrHashDoesNotExist) {
+			return fmt.Errorf("%w: Set of missing hash returned %v", ErrSimulationMismatch, err)
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: Set of existing hash returned %w", ErrSimulationMismatch, err)
+	}
+
+	s.model[hash] = value
+
+	return s.checkGet(hash)
+}
+
+// checkSetIfExists runs SetIfExists and compares its outcome with the model.
+func (s *simulation) checkSetIfExists(hash chainhash.Hash, value uint64) error {
+	ok, err := s.m.SetIfExists(hash, value)
+	if err != nil {
+		return fmt.Errorf("%w: SetIfExists returned %w", ErrSimulationMismatch, err)
+	}
+
+	if _, exists := s.model[hash]; ok != exists {
+		return fmt.Errorf("%w: SetIfExists returned %t for a hash that exists: %t", ErrSimulationMismatch, ok, exists)
+	}
+
+	if ok {
+		s.model[hash] = value
+	}
+
+	return s.checkGet(hash)
+}
+
+// checkSetIfNotExists runs SetIfNotExists and compares its outcome with the model.
+func (s *simulation) checkSetIfNotExists(hash chainhash.Hash, value uint64) error {
+	ok, err := s.m.SetIfNotExists(hash, value)
+	if err != nil {
+		return fmt.Errorf("%w: SetIfNotExists returned %w", ErrSimulationMismatch, err)
+	}
+
+	if _, exists := s.model[hash]; ok == exists {
+		return fmt.Errorf("%w: SetIfNotExists returned %t for a hash that exists: %t", ErrSimulationMismatch, ok, exists)
+	}
+
+	if ok {
+		s.model[hash] = value
+	}
+
+	return s.checkLength()
+}
+
+// checkDelete runs Delete and compares its outcome with the model.
+func (s *simulation) checkDelete(hash chainhash.Hash) error {
+	err := s.m.Delete(hash)
+
+	if _, exists := s.model[hash]; !exists {
+		if !errors.Is(err, ErrHashDoesNotExist) {
+			return fmt.Errorf("%w: Delete of missing hash returned %v", ErrSimulationMismatch, err)
+		}
+
+		return s.checkLength()
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: Delete of existing hash returned %w", ErrSimulationMismatch, err)
+	}
+
+	delete(s.model, hash)
+
+	return s.checkLength()
+}
+
+// checkGet compares Get and Exists of hash with the model.
+func (s *simulation) checkGet(hash chainhash.Hash) error {
+	want, exists := s.model[hash]
+	got, ok := s.m.Get(hash)
+
+	if ok != exists || s.m.Exists(hash) != exists {
+		return fmt.Errorf("%w: hash %s exists: %t, model: %t", ErrSimulationMismatch, hash, ok, exists)
+	}
+
+	if ok && !s.opts.IgnoreValues && got != want {
+		return fmt.Errorf("%w: hash %s has value %d, model: %d", ErrSimulationMismatch, hash, got, want)
+	}
+
+	return nil
+}
+
+// checkLength compares the length of the map with the model.
+func (s *simulation) checkLength() error {
+	if got := s.m.Length(); got != len(s.model) {
+		return fmt.Errorf("%w: length %d, model: %d", ErrSimulationMismatch, got, len(s.model))
+	}
+
+	return nil
+}
+
+// fullCheck compares the whole map with the model and checks its invariants.
+func (s *simulation) fullCheck() error {
+	if err := s.checkLength(); err != nil {
+		return err
+	}
+
+	for hash := range s.model {
+		if err := s.checkGet(hash); err != nil {
+			return err
+		}
+	}
+
+	var err error
+
+	s.m.Iter(func(hash chainhash.Hash, _ uint64) bool {
+		if _, ok := s.model[hash]; !ok {
+			err = fmt.Errorf("%w: Iter returned unknown hash %s", ErrSimulationMismatch, hash)
+		}
+
+		return err != nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if c, ok := s.m.(InvariantChecker); ok {
+		return c.CheckInvariants()
+	}
+
+	return nil
+}
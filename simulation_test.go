@@ -0,0 +1,66 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimulate runs the simulation against every TxMap implementation and a
+// few decorators for several seeds.
+func TestSimulate(t *testing.T) {
+	impls := txMapImpls()
+	impls["ChildMap"] = func() TxMap { return NewChildMap(NewNativeMapUint64(0)) }
+	impls["RollingTxMap"] = func() TxMap { return newRollingNative(3) }
+	impls["HybridSplitMap"] = func() TxMap { return promotingHybrid{NewHybridSplitMap(0, 0, 8)} }
+
+	for name, factory := range impls {
+		t.Run(name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				require.NoError(t, Simulate(factory, SimulationOptions{
+					Seed:         seed,
+					Steps:        2000,
+					IgnoreValues: name == "SplitSwissMap",
+				}))
+			}
+		})
+	}
+}
+
+// lossyTxMap forgets every tenth successful Put.
+type lossyTxMap struct {
+	*NativeMapUint64
+	puts int
+}
+
+// Put drops every tenth new hash.
+func (l *lossyTxMap) Put(hash chainhash.Hash, value uint64) error {
+	if l.NativeMapUint64.Exists(hash) {
+		return l.NativeMapUint64.Put(hash, value)
+	}
+
+	l.puts++
+	if l.puts%10 == 0 {
+		return nil
+	}
+
+	return l.NativeMapUint64.Put(hash, value)
+}
+
+// TestSimulateReproducible tests that a failure is reported with the same
+// step for the same seed.
+func TestSimulateReproducible(t *testing.T) {
+	newLossy := func() TxMap { return &lossyTxMap{NativeMapUint64: NewNativeMapUint64(0)} }
+
+	first := Simulate(newLossy, SimulationOptions{Seed: 42})
+	second := Simulate(newLossy, SimulationOptions{Seed: 42})
+
+	var simErr *SimulationError
+
+	require.ErrorAs(t, first, &simErr)
+	require.ErrorIs(t, first, ErrSimulationMismatch)
+	assert.Equal(t, int64(42), simErr.Seed)
+	assert.Equal(t, first.Error(), second.Error())
+}
@@ -0,0 +1,685 @@
+package txmap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Snapshot format
+//
+// A snapshot is the streamable serialization of a TxMap:
+//
+//	header:  magic "TXMP" | version uint16 | reserved uint16 | count uint64
+//	chunks:  ceil(count / 4096) × (records [n × (hash [32]byte | value uint64)] | crc32c uint32)
+//	trailer: sha256 [32]byte
+//
+// All integers are little-endian. The header carries the entry count so a
+// reader can report progress against a known total and detect truncation.
+// Records are written in the map's iteration order, which is unspecified.
+//
+// Records are grouped into chunks of 4096 (the last chunk holds the rest),
+// each followed by the CRC-32C of its records, and the stream ends with the
+// SHA-256 of everything before the trailer. A chunk's records are only handed
+// to the importer after its checksum was verified, so corruption is reported
+// with the chunk it occurred in instead of surfacing as garbage entries.
+//
+// Version 1 snapshots have the same header followed by the bare records, with
+// no chunk checksums or trailer.
+//
+// Compatibility: Import reads every version up to SnapshotVersion, so a node
+// can always restore snapshots written by older releases, and ExportVersion
+// writes any of them, so a snapshot can be handed to a node running an older
+// release. A version newer than SnapshotVersion is rejected with
+// ErrUnsupportedSnapshotVersion rather than misread. Golden files of every
+// version in testdata/snapshot guard the layouts against accidental changes;
+// a new layout must get a new version number and a new golden file.
+
+const (
+	// SnapshotVersion1 is the original header + bare records layout.
+	SnapshotVersion1 = uint16(1)
+
+	// SnapshotVersion2 is the checksummed layout described above.
+	SnapshotVersion2 = uint16(2)
+
+	// SnapshotVersion is the version written by Export and the newest version
+	// Import reads.
+	SnapshotVersion = SnapshotVersion2
+)
+
+const (
+	// snapshotMagic identifies a tx map snapshot stream.
+	snapshotMagic = "TXMP"
+
+	// snapshotHeaderSize is the encoded size of the snapshot header in bytes.
+	snapshotHeaderSize = 16
+
+	// snapshotRecordSize is the encoded size of one (hash, value) record in bytes.
+	snapshotRecordSize = chainhash.HashSize + 8
+
+	// snapshotChunkRecords is the number of records per checksummed chunk.
+	snapshotChunkRecords = 4096
+
+	// snapshotChunkChecksumSize is the size of the CRC-32C following each chunk.
+	snapshotChunkChecksumSize = 4
+
+	// defaultImportProgressInterval is the number of records between progress
+	// callbacks when ImportOptions.ProgressInterval is not set.
+	defaultImportProgressInterval = 1 << 20
+)
+
+var (
+	// ErrInvalidSnapshot is returned when a stream is not a snapshot, uses an
+	// unsupported version, or ends before all of its records were read.
+	ErrInvalidSnapshot = errors.New("invalid tx map snapshot")
+
+	// ErrUnsupportedSnapshotVersion is returned, together with
+	// ErrInvalidSnapshot, for a snapshot version this release cannot read or
+	// write.
+	ErrUnsupportedSnapshotVersion = errors.New("unsupported tx map snapshot version")
+
+	// ErrMapChangedDuringExport is returned by Export when the number of
+	// entries iterated does not match the length recorded in the header,
+	// which means the map was written to while it was being exported.
+	ErrMapChangedDuringExport = errors.New("map changed during export")
+)
+
+// SnapshotCorruptionError is returned by Import when a checksummed snapshot
+// fails verification. It wraps ErrInvalidSnapshot.
+type SnapshotCorruptionError struct {
+	// Chunk is the zero-based index of the offending chunk, or -1 if the chunks
+	// verified but the file-level digest did not match.
+	Chunk int64
+
+	// Offset is the byte offset of the chunk (or trailer) in the stream.
+	Offset int64
+
+	// Reason describes the failure, e.g. "checksum mismatch" or "truncated".
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *SnapshotCorruptionError) Error() string {
+	if e.Chunk < 0 {
+		return fmt.Sprintf("%v: digest at offset %d: %s", ErrInvalidSnapshot, e.Offset, e.Reason)
+	}
+
+	return fmt.Sprintf("%v: chunk %d at offset %d: %s", ErrInvalidSnapshot, e.Chunk, e.Offset, e.Reason)
+}
+
+// Unwrap returns ErrInvalidSnapshot, so errors.Is matches corruption errors.
+func (e *SnapshotCorruptionError) Unwrap() error {
+	return ErrInvalidSnapshot
+}
+
+// snapshotHeader is the decoded form of the snapshot header.
+type snapshotHeader struct {
+	version uint16
+	count   uint64
+}
+
+// ImportOptions controls how Import applies a snapshot to a map.
+type ImportOptions struct {
+	// SkipExisting makes Import leave hashes that already exist in the
+	// destination untouched and count them as skipped. When false, the first
+	// such hash aborts the import with an error wrapping ErrHashAlreadyExists.
+	SkipExisting bool
+
+	// Progress, if set, is called every ProgressInterval records and once more
+	// when the import finishes successfully, with the report so far.
+	Progress func(report ImportReport)
+
+	// ProgressInterval is the number of records between Progress calls.
+	// Defaults to 1,048,576 when zero or negative.
+	ProgressInterval int
+}
+
+// ExportOptions controls how ExportWithOptions writes a snapshot.
+type ExportOptions struct {
+	// Version is the format version to write, SnapshotVersion when zero.
+	Version uint16
+
+	// OnProgress, if set, is called every ProgressInterval records and once
+	// more when the export finishes successfully.
+	OnProgress func(progress Progress)
+
+	// ProgressInterval is the number of records between OnProgress calls.
+	// Defaults to 1,048,576 when zero or negative.
+	ProgressInterval int
+
+	// YieldEvery, if positive, makes the export of maps implementing
+	// YieldingTxMap release each bucket's read lock every YieldEvery records,
+	// so that writers are not stalled for the whole export; the map must then
+	// not change length while it is exported. See IterYield.
+	YieldEvery int
+}
+
+// ImportReport summarizes the work done by Import.
+type ImportReport struct {
+	// Total is the number of records the snapshot header announced.
+	Total uint64
+
+	// Read is the number of records read from the stream so far.
+	Read uint64
+
+	// Inserted is the number of records added to the destination map.
+	Inserted uint64
+
+	// Skipped is the number of records whose hash already existed in the
+	// destination map (only with ImportOptions.SkipExisting).
+	Skipped uint64
+
+	// Bytes is the number of bytes read from the stream so far.
+	Bytes uint64
+
+	// Elapsed is the time since the import started.
+	Elapsed time.Duration
+
+	// ETA is the estimated time until the import finishes, extrapolated from
+	// the rate so far; 0 before the first and after the last record.
+	ETA time.Duration
+}
+
+// Progress returns the progress of the import described by r, e.g. to log it
+// with Progress.String.
+func (r ImportReport) Progress() Progress {
+	return Progress{Processed: r.Read, Total: r.Total, Bytes: r.Bytes, Elapsed: r.Elapsed, ETA: r.ETA}
+}
+
+// measured returns r with the bytes read, the elapsed time and the ETA
+// measured by progress.
+func (r ImportReport) measured(progress *progressTracker, bytes uint64) ImportReport {
+	p := progress.measure(r.Read, bytes)
+	r.Bytes, r.Elapsed, r.ETA = p.Bytes, p.Elapsed, p.ETA
+
+	return r
+}
+
+// Export writes the contents of m to w in the snapshot format.
+//
+// Params:
+//   - w: The destination stream. It is written through a buffer; Export does
+//     not close it.
+//   - m: The map to export.
+//
+// Returns:
+//   - error: ErrMapChangedDuringExport if m was mutated while being exported,
+//     or any error returned by w.
+//
+// Considerations: m must not be written to for the duration of the export;
+// freezing it first also makes the iteration lock-free.
+func Export(w io.Writer, m ReadOnlyTxMap) error {
+	return exportSource(w, m)
+}
+
+// ExportVersion writes the contents of m to w in the given version of the
+// snapshot format, e.g. SnapshotVersion1 for a node that predates checksummed
+// snapshots. It otherwise behaves like Export.
+//
+// Params:
+//   - w: The destination stream; ExportVersion does not close it.
+//   - m: The map to export.
+//   - version: The format version, from SnapshotVersion1 to SnapshotVersion;
+//     zero means SnapshotVersion.
+//
+// Returns:
+//   - error: An error wrapping ErrUnsupportedSnapshotVersion for an unknown
+//     version, or any error Export returns.
+func ExportVersion(w io.Writer, m ReadOnlyTxMap, version uint16) error {
+	return exportSourceOptions(w, m, ExportOptions{Version: version})
+}
+
+// ExportWithOptions writes the contents of m to w like Export, in the format
+// version and with the progress reporting of opts.
+//
+// Params:
+//   - w: The destination stream; ExportWithOptions does not close it.
+//   - m: The map to export.
+//   - opts: The format version and progress reporting.
+//
+// Returns:
+//   - error: An error wrapping ErrUnsupportedSnapshotVersion for an unknown
+//     version, or any error Export returns.
+func ExportWithOptions(w io.Writer, m ReadOnlyTxMap, opts ExportOptions) error {
+	return exportSourceOptions(w, m, opts)
+}
+
+// snapshotSource is the read-only subset of TxMap needed to export a snapshot.
+type snapshotSource interface {
+	Length() int
+	Iter(f func(hash chainhash.Hash, value uint64) bool)
+}
+
+// exportSource implements Export for any snapshotSource.
+func exportSource(w io.Writer, m snapshotSource) error {
+	return exportSourceOptions(w, m, ExportOptions{})
+}
+
+// exportSourceOptions implements ExportWithOptions for any snapshotSource.
+func exportSourceOptions(w io.Writer, m snapshotSource, opts ExportOptions) error {
+	version := opts.Version
+	if version == 0 {
+		version = SnapshotVersion
+	}
+
+	if version != SnapshotVersion1 && version != SnapshotVersion2 {
+		return fmt.Errorf("%w: %w %d", ErrInvalidSnapshot, ErrUnsupportedSnapshotVersion, version)
+	}
+
+	counter := &countingWriter{w: w}
+	bw := bufio.NewWriter(counter)
+	cw := newSnapshotChunkWriter(bw, version)
+
+	count := uint64(m.Length()) //nolint:gosec // length is never negative
+	if err := writeSnapshotHeader(cw.w, snapshotHeader{version: version, count: count}); err != nil {
+		return err
+	}
+
+	progress := newProgressTracker(opts.OnProgress, opts.ProgressInterval, count)
+
+	var (
+		written uint64
+		err     error
+	)
+
+	snapshotIter(m, opts.YieldEvery)(func(hash chainhash.Hash, value uint64) bool {
+		written++
+		if written > count {
+			return true
+		}
+
+		err = cw.writeRecord(hash, value)
+
+		if progress.due(written) {
+			progress.report(written, counter.n)
+		}
+
+		return err != nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if written != count {
+		return fmt.Errorf("%w: header announced %d entries, iterated %d", ErrMapChangedDuringExport, count, written)
+	}
+
+	if err = cw.close(); err != nil {
+		return err
+	}
+
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+
+	progress.report(written, counter.n)
+
+	return nil
+}
+
+// snapshotIter returns the Iter of m, or its IterYield if yieldEvery is
+// positive and m supports it.
+func snapshotIter(m snapshotSource, yieldEvery int) func(f func(hash chainhash.Hash, value uint64) bool) {
+	if y, ok := m.(iterYielder); ok && yieldEvery > 0 {
+		return func(f func(hash chainhash.Hash, value uint64) bool) { y.IterYield(yieldEvery, f) }
+	}
+
+	return m.Iter
+}
+
+// Import reads a snapshot from r and inserts its records into dst.
+//
+// This function performs the following steps:
+//   - Validates the snapshot header and reads the announced record count.
+//   - For checksummed snapshots, verifies each chunk before any of its records
+//     is inserted, and the file-level digest before the last chunk is.
+//   - Streams the records through a buffer, inserting each into dst with Put,
+//     or with SetIfNotExists when opts.SkipExisting is set.
+//   - Checks ctx between batches of records and reports progress every
+//     opts.ProgressInterval records.
+//
+// Params:
+//   - ctx: Cancels a long-running import; checked between batches.
+//   - dst: The map to insert into. It does not need to be empty.
+//   - r: The snapshot stream.
+//   - opts: Duplicate handling and progress reporting.
+//
+// Returns:
+//   - ImportReport: What was read, inserted and skipped, and how many bytes
+//     and how long it took, also on error.
+//   - error: ErrInvalidSnapshot for a malformed or truncated stream (a
+//     *SnapshotCorruptionError for checksummed snapshots), an error
+//     wrapping ErrHashAlreadyExists on a duplicate (unless SkipExisting), the
+//     context error on cancellation, or any error returned by r or dst.
+//
+// Side Effects:
+//   - Records inserted before an error remain in dst; Import does not roll back.
+func Import(ctx context.Context, dst TxMap, r io.Reader, opts ImportOptions) (ImportReport, error) {
+	var report ImportReport
+
+	progress := newProgressTracker(nil, opts.ProgressInterval, 0)
+	counter := &countingReader{r: r}
+	br := bufio.NewReader(counter)
+
+	header, err := readSnapshotHeader(br)
+	if err != nil {
+		return report.measured(progress, counter.n), err
+	}
+
+	report.Total = header.count
+	progress.total = header.count
+
+	records := io.Reader(br)
+	if header.version == SnapshotVersion2 {
+		if records, err = newSnapshotChunkReader(br, header); err != nil {
+			return report.measured(progress, counter.n), err
+		}
+	}
+
+	interval := progress.interval
+
+	var record [snapshotRecordSize]byte
+
+	for report.Read < report.Total {
+		// checking the context for every record would dominate the cost of a
+		// multi-GB import, so it is only checked once per progress interval
+		if report.Read%interval == 0 {
+			if report.Read > 0 && opts.Progress != nil {
+				opts.Progress(report.measured(progress, counter.n))
+			}
+
+			if err = ctx.Err(); err != nil {
+				return report.measured(progress, counter.n), err
+			}
+		}
+
+		if _, err = io.ReadFull(records, record[:]); err != nil {
+			var corrupt *SnapshotCorruptionError
+			if errors.As(err, &corrupt) {
+				return report.measured(progress, counter.n), err
+			}
+
+			return report.measured(progress, counter.n), fmt.Errorf("%w: truncated after %d of %d records: %w",
+				ErrInvalidSnapshot, report.Read, report.Total, err)
+		}
+
+		report.Read++
+
+		if err = importRecord(dst, record[:], opts.SkipExisting, &report); err != nil {
+			return report.measured(progress, counter.n), err
+		}
+	}
+
+	report = report.measured(progress, counter.n)
+
+	if opts.Progress != nil {
+		opts.Progress(report)
+	}
+
+	return report, nil
+}
+
+// importRecord decodes a single snapshot record and inserts it into dst,
+// updating the inserted/skipped counters of report.
+func importRecord(dst TxMap, record []byte, skipExisting bool, report *ImportReport) error {
+	var hash chainhash.Hash
+
+	copy(hash[:], record[:chainhash.HashSize])
+	value := binary.LittleEndian.Uint64(record[chainhash.HashSize:])
+
+	if !skipExisting {
+		if err := dst.Put(hash, value); err != nil {
+			return err
+		}
+
+		report.Inserted++
+
+		return nil
+	}
+
+	added, err := dst.SetIfNotExists(hash, value)
+	if err != nil {
+		return err
+	}
+
+	if added {
+		report.Inserted++
+	} else {
+		report.Skipped++
+	}
+
+	return nil
+}
+
+// writeSnapshotHeader encodes h to w.
+func writeSnapshotHeader(w io.Writer, h snapshotHeader) error {
+	var buf [snapshotHeaderSize]byte
+
+	copy(buf[:4], snapshotMagic)
+	binary.LittleEndian.PutUint16(buf[4:6], h.version)
+	binary.LittleEndian.PutUint64(buf[8:16], h.count)
+
+	_, err := w.Write(buf[:])
+
+	return err
+}
+
+// readSnapshotHeader decodes and validates a snapshot header from r.
+func readSnapshotHeader(r io.Reader) (snapshotHeader, error) {
+	var buf [snapshotHeaderSize]byte
+
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return snapshotHeader{}, fmt.Errorf("%w: reading header: %w", ErrInvalidSnapshot, err)
+	}
+
+	if string(buf[:4]) != snapshotMagic {
+		return snapshotHeader{}, fmt.Errorf("%w: bad magic %q", ErrInvalidSnapshot, buf[:4])
+	}
+
+	h := snapshotHeader{
+		version: binary.LittleEndian.Uint16(buf[4:6]),
+		count:   binary.LittleEndian.Uint64(buf[8:16]),
+	}
+
+	if h.version != SnapshotVersion1 && h.version != SnapshotVersion2 {
+		return snapshotHeader{}, fmt.Errorf("%w: %w %d", ErrInvalidSnapshot, ErrUnsupportedSnapshotVersion, h.version)
+	}
+
+	return h, nil
+}
+
+// snapshotChunkWriter groups records into checksummed chunks and maintains the
+// file-level digest of everything written through w. For version 1 snapshots
+// it writes the bare records instead.
+type snapshotChunkWriter struct {
+	// w writes to the output and the digest; out writes to the output only
+	w      io.Writer
+	out    io.Writer
+	digest hash.Hash
+	table  *crc32.Table
+	chunk  []byte
+	bare   bool
+}
+
+// newSnapshotChunkWriter returns a snapshotChunkWriter writing the given
+// snapshot version to out.
+func newSnapshotChunkWriter(out io.Writer, version uint16) *snapshotChunkWriter {
+	digest := sha256.New()
+
+	return &snapshotChunkWriter{
+		w:      io.MultiWriter(out, digest),
+		out:    out,
+		digest: digest,
+		table:  crc32.MakeTable(crc32.Castagnoli),
+		chunk:  make([]byte, 0, snapshotChunkRecords*snapshotRecordSize+snapshotChunkChecksumSize),
+		bare:   version == SnapshotVersion1,
+	}
+}
+
+// writeRecord appends a record to the current chunk, flushing it when full.
+func (c *snapshotChunkWriter) writeRecord(hash chainhash.Hash, value uint64) error {
+	c.chunk = append(c.chunk, hash[:]...)
+	c.chunk = binary.LittleEndian.AppendUint64(c.chunk, value)
+
+	if c.bare {
+		_, err := c.out.Write(c.chunk)
+		c.chunk = c.chunk[:0]
+
+		return err
+	}
+
+	if len(c.chunk) == snapshotChunkRecords*snapshotRecordSize {
+		return c.flushChunk()
+	}
+
+	return nil
+}
+
+// flushChunk writes the buffered records followed by their checksum.
+func (c *snapshotChunkWriter) flushChunk() error {
+	if len(c.chunk) == 0 {
+		return nil
+	}
+
+	c.chunk = binary.LittleEndian.AppendUint32(c.chunk, crc32.Checksum(c.chunk, c.table))
+
+	_, err := c.w.Write(c.chunk)
+	c.chunk = c.chunk[:0]
+
+	return err
+}
+
+// close flushes the last chunk and writes the trailer digest.
+func (c *snapshotChunkWriter) close() error {
+	if c.bare {
+		return nil
+	}
+
+	if err := c.flushChunk(); err != nil {
+		return err
+	}
+
+	_, err := c.out.Write(c.digest.Sum(nil))
+
+	return err
+}
+
+// snapshotChunkReader is an io.Reader over the records of a checksummed
+// snapshot. It only returns the records of a chunk once its checksum was
+// verified, and verifies the trailer digest before returning the last chunk.
+type snapshotChunkReader struct {
+	r         io.Reader
+	digest    hash.Hash
+	table     *crc32.Table
+	remaining uint64
+	chunk     int64
+	offset    int64
+	buf       []byte
+	pending   []byte
+}
+
+// newSnapshotChunkReader returns a reader for the chunks following header in r.
+// An empty snapshot has no chunks, so its digest is verified immediately.
+func newSnapshotChunkReader(r io.Reader, header snapshotHeader) (*snapshotChunkReader, error) {
+	c := &snapshotChunkReader{
+		r:         r,
+		digest:    sha256.New(),
+		table:     crc32.MakeTable(crc32.Castagnoli),
+		remaining: header.count,
+		offset:    snapshotHeaderSize,
+		buf:       make([]byte, 0, snapshotChunkRecords*snapshotRecordSize+snapshotChunkChecksumSize),
+	}
+
+	if err := writeSnapshotHeader(c.digest, header); err != nil {
+		return nil, err
+	}
+
+	if header.count == 0 {
+		if err := c.verifyDigest(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Read returns verified record bytes, loading the next chunk when needed.
+func (c *snapshotChunkReader) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		if c.remaining == 0 {
+			return 0, io.EOF
+		}
+
+		if err := c.loadChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+
+	return n, nil
+}
+
+// loadChunk reads and verifies the next chunk.
+func (c *snapshotChunkReader) loadChunk() error {
+	records := min(c.remaining, snapshotChunkRecords)
+	size := int(records) * snapshotRecordSize //nolint:gosec // bounded by snapshotChunkRecords
+
+	c.buf = c.buf[:size+snapshotChunkChecksumSize]
+
+	if _, err := io.ReadFull(c.r, c.buf); err != nil {
+		return c.corrupt(c.chunk, fmt.Sprintf("truncated: %v", err))
+	}
+
+	if crc32.Checksum(c.buf[:size], c.table) != binary.LittleEndian.Uint32(c.buf[size:]) {
+		return c.corrupt(c.chunk, "checksum mismatch")
+	}
+
+	_, _ = c.digest.Write(c.buf)
+
+	c.remaining -= records
+	c.chunk++
+	c.offset += int64(len(c.buf))
+
+	if c.remaining == 0 {
+		if err := c.verifyDigest(); err != nil {
+			return err
+		}
+	}
+
+	c.pending = c.buf[:size]
+
+	return nil
+}
+
+// verifyDigest reads the trailer and compares it with the computed digest.
+func (c *snapshotChunkReader) verifyDigest() error {
+	var trailer [sha256.Size]byte
+
+	if _, err := io.ReadFull(c.r, trailer[:]); err != nil {
+		return c.corrupt(-1, fmt.Sprintf("truncated: %v", err))
+	}
+
+	if !bytes.Equal(trailer[:], c.digest.Sum(nil)) {
+		return c.corrupt(-1, "digest mismatch")
+	}
+
+	return nil
+}
+
+// corrupt returns a SnapshotCorruptionError for chunk at the current offset.
+func (c *snapshotChunkReader) corrupt(chunk int64, reason string) error {
+	return &SnapshotCorruptionError{Chunk: chunk, Offset: c.offset, Reason: reason}
+}
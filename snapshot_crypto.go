@@ -0,0 +1,378 @@
+package txmap
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted snapshot stream
+//
+// Snapshots can be multi-GB, so they are encrypted as a sequence of
+// independently authenticated AES-GCM chunks rather than as one message that
+// would have to be buffered whole before it could be verified:
+//
+//	header: magic "TXME" | version uint8 | nonce prefix [7]byte
+//	chunks: final uint8 | length uint32 | ciphertext [length]byte
+//
+// Each chunk seals up to 64 KiB of plaintext with the nonce
+// prefix || chunk counter (uint32 big-endian) || final flag, the construction
+// used by STREAM-style online AEAD schemes. The counter stops chunks from
+// being reordered or dropped, and the final flag stops the stream from being
+// truncated at a chunk boundary without detection.
+
+const (
+	// encryptedSnapshotMagic identifies an encrypted snapshot stream.
+	encryptedSnapshotMagic = "TXME"
+
+	// encryptedSnapshotVersion1 is the chunked AES-GCM layout described above.
+	encryptedSnapshotVersion1 = uint8(1)
+
+	// encryptionNoncePrefixSize is the size of the random per-stream nonce prefix.
+	encryptionNoncePrefixSize = 7
+
+	// encryptionHeaderSize is the encoded size of the stream header in bytes.
+	encryptionHeaderSize = 4 + 1 + encryptionNoncePrefixSize
+
+	// encryptionChunkSize is the maximum plaintext size of a single chunk.
+	encryptionChunkSize = 64 << 10
+
+	// encryptionFrameHeaderSize is the size of the final flag and length that
+	// precede each chunk's ciphertext.
+	encryptionFrameHeaderSize = 1 + 4
+)
+
+var (
+	// ErrSnapshotDecryption is returned when an encrypted snapshot cannot be
+	// decrypted: the key is wrong, the stream was modified, or it was truncated.
+	ErrSnapshotDecryption = errors.New("snapshot decryption failed")
+
+	// errEncryptedStreamTooLong is returned when a stream would need more chunks
+	// than the nonce counter can address (256 TiB).
+	errEncryptedStreamTooLong = errors.New("encrypted snapshot stream too long")
+)
+
+// NewEncryptingWriter returns a writer that encrypts everything written to it
+// with AES-GCM under key and writes the encrypted stream to w. Close must be
+// called to write the final chunk; it does not close w.
+//
+// Params:
+//   - w: The destination for the encrypted stream.
+//   - key: A 16, 24 or 32 byte AES key (AES-128, AES-192 or AES-256).
+//
+// Returns:
+//   - io.WriteCloser: The encrypting writer.
+//   - error: An error for an invalid key size, or if the header cannot be written.
+func NewEncryptingWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	ew := &encryptingWriter{
+		w:    w,
+		aead: aead,
+		buf:  make([]byte, 0, encryptionChunkSize),
+	}
+
+	if _, err = rand.Read(ew.prefix[:]); err != nil {
+		return nil, err
+	}
+
+	var header [encryptionHeaderSize]byte
+
+	copy(header[:4], encryptedSnapshotMagic)
+	header[4] = encryptedSnapshotVersion1
+	copy(header[5:], ew.prefix[:])
+
+	if _, err = w.Write(header[:]); err != nil {
+		return nil, err
+	}
+
+	return ew, nil
+}
+
+// NewDecryptingReader returns a reader that decrypts a stream produced by
+// NewEncryptingWriter. Every chunk is authenticated before any of its
+// plaintext is returned, and the stream ends with io.EOF only after the final
+// chunk was verified.
+//
+// Params:
+//   - r: The encrypted stream.
+//   - key: The key the stream was encrypted with.
+//
+// Returns:
+//   - io.Reader: The decrypting reader; its errors wrap ErrSnapshotDecryption.
+//   - error: An error for an invalid key size or a stream without a valid header.
+func NewDecryptingReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var header [encryptionHeaderSize]byte
+
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrSnapshotDecryption, err)
+	}
+
+	if string(header[:4]) != encryptedSnapshotMagic || header[4] != encryptedSnapshotVersion1 {
+		return nil, fmt.Errorf("%w: not an encrypted snapshot (version %d)", ErrSnapshotDecryption, header[4])
+	}
+
+	dr := &decryptingReader{
+		r:    r,
+		aead: aead,
+		buf:  make([]byte, 0, encryptionChunkSize+aead.Overhead()),
+	}
+
+	copy(dr.prefix[:], header[5:])
+
+	return dr, nil
+}
+
+// newSnapshotAEAD returns an AES-GCM AEAD for key.
+func newSnapshotAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce builds the nonce for chunk number counter of a stream.
+func chunkNonce(prefix [encryptionNoncePrefixSize]byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, encryptionNoncePrefixSize+5)
+
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[encryptionNoncePrefixSize:], counter)
+
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+
+	return nonce
+}
+
+// encryptingWriter implements the writing side of the encrypted stream.
+type encryptingWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  [encryptionNoncePrefixSize]byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// Write buffers p and seals every full chunk. A full chunk is only sealed once
+// more data arrives, so the last chunk is always the one sealed by Close.
+func (e *encryptingWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n := len(p)
+
+	for len(p) > 0 {
+		if len(e.buf) == encryptionChunkSize {
+			if err := e.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+
+		c := copy(e.buf[len(e.buf):encryptionChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+c]
+		p = p[c:]
+	}
+
+	return n, nil
+}
+
+// Close seals the remaining buffered data as the final chunk.
+func (e *encryptingWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+
+	return e.seal(true)
+}
+
+// seal encrypts the buffered plaintext as one chunk and writes its frame.
+func (e *encryptingWriter) seal(final bool) error {
+	if e.counter == ^uint32(0) {
+		return errEncryptedStreamTooLong
+	}
+
+	frame := make([]byte, encryptionFrameHeaderSize, encryptionFrameHeaderSize+len(e.buf)+e.aead.Overhead())
+	frame = e.aead.Seal(frame, chunkNonce(e.prefix, e.counter, final), e.buf, nil)
+
+	if final {
+		frame[0] = 1
+	}
+
+	binary.LittleEndian.PutUint32(frame[1:encryptionFrameHeaderSize], uint32(len(frame)-encryptionFrameHeaderSize)) //nolint:gosec // bounded by chunk size
+
+	e.counter++
+	e.buf = e.buf[:0]
+
+	_, err := e.w.Write(frame)
+
+	return err
+}
+
+// decryptingReader implements the reading side of the encrypted stream.
+type decryptingReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  [encryptionNoncePrefixSize]byte
+	counter uint32
+	buf     []byte
+	plain   []byte
+	done    bool
+}
+
+// Read returns decrypted plaintext, opening the next chunk when needed.
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+
+	return n, nil
+}
+
+// open reads, authenticates and decrypts the next chunk.
+func (d *decryptingReader) open() error {
+	var frameHeader [encryptionFrameHeaderSize]byte
+
+	if _, err := io.ReadFull(d.r, frameHeader[:]); err != nil {
+		return fmt.Errorf("%w: stream truncated before final chunk: %v", ErrSnapshotDecryption, err)
+	}
+
+	final := frameHeader[0] == 1
+	length := binary.LittleEndian.Uint32(frameHeader[1:])
+
+	if frameHeader[0] > 1 || int(length) > cap(d.buf) {
+		return fmt.Errorf("%w: malformed chunk %d", ErrSnapshotDecryption, d.counter)
+	}
+
+	d.buf = d.buf[:length]
+
+	if _, err := io.ReadFull(d.r, d.buf); err != nil {
+		return fmt.Errorf("%w: chunk %d truncated: %v", ErrSnapshotDecryption, d.counter, err)
+	}
+
+	plain, err := d.aead.Open(d.buf[:0], chunkNonce(d.prefix, d.counter, final), d.buf, nil)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d failed authentication", ErrSnapshotDecryption, d.counter)
+	}
+
+	d.counter++
+	d.plain = plain
+	d.done = final
+
+	return nil
+}
+
+// check that EncryptedSnapshotStore implements SnapshotStore
+var _ SnapshotStore = (*EncryptedSnapshotStore)(nil)
+
+// EncryptedSnapshotStore wraps a SnapshotStore so that every snapshot is
+// encrypted before it reaches the underlying store and decrypted when read
+// back. Snapshot names are not encrypted.
+type EncryptedSnapshotStore struct {
+	store SnapshotStore
+	key   []byte
+}
+
+// NewEncryptedSnapshotStore returns an EncryptedSnapshotStore that encrypts
+// snapshots stored in store with key.
+//
+// Params:
+//   - store: The store holding the encrypted snapshots.
+//   - key: A 16, 24 or 32 byte AES key. It is copied.
+//
+// Returns:
+//   - *EncryptedSnapshotStore: The encrypting store.
+//   - error: An error for an invalid key size.
+func NewEncryptedSnapshotStore(store SnapshotStore, key []byte) (*EncryptedSnapshotStore, error) {
+	if _, err := newSnapshotAEAD(key); err != nil {
+		return nil, err
+	}
+
+	return &EncryptedSnapshotStore{
+		store: store,
+		key:   append([]byte(nil), key...),
+	}, nil
+}
+
+// Put encrypts the stream read from r while uploading it to the wrapped store.
+// It does not return before it has stopped reading r, which it does once the
+// wrapped store fails or stops reading; a read of r that blocks blocks Put.
+func (s *EncryptedSnapshotStore) Put(ctx context.Context, name string, r io.Reader) error {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ew, err := NewEncryptingWriter(pw, s.key)
+		if err == nil {
+			if _, err = io.Copy(ew, r); err == nil {
+				err = ew.Close()
+			}
+		}
+
+		_ = pw.CloseWithError(err)
+	}()
+
+	err := s.store.Put(ctx, name, pr)
+
+	// unblock the encrypting goroutine if the store stopped reading early, and
+	// wait for it to stop reading r before returning
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+
+	return err
+}
+
+// Get opens the snapshot in the wrapped store and returns a decrypting reader
+// for it. Closing it closes the underlying snapshot.
+func (s *EncryptedSnapshotStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	rc, err := s.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := NewDecryptingReader(rc, s.key)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{dr, rc}, nil
+}
+
+// List lists the snapshots of the wrapped store.
+func (s *EncryptedSnapshotStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.store.List(ctx, prefix)
+}
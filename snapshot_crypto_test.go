@@ -0,0 +1,178 @@
+package txmap
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encryptBytes encrypts plain with key into a new buffer.
+func encryptBytes(t *testing.T, key, plain []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	ew, err := NewEncryptingWriter(&buf, key)
+	require.NoError(t, err)
+
+	_, err = ew.Write(plain)
+	require.NoError(t, err)
+	require.NoError(t, ew.Close())
+
+	return buf.Bytes()
+}
+
+// decryptBytes decrypts data with key.
+func decryptBytes(key, data []byte) ([]byte, error) {
+	dr, err := NewDecryptingReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(dr)
+}
+
+// TestEncryptedSnapshotRoundTrip tests exporting through an encrypting writer
+// and importing through a decrypting reader, for snapshots of several chunks.
+func TestEncryptedSnapshotRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	for _, n := range []int{0, 10, 5000} {
+		src := populatedMap(t, n)
+
+		var buf bytes.Buffer
+
+		ew, err := NewEncryptingWriter(&buf, key)
+		require.NoError(t, err)
+		require.NoError(t, Export(ew, src))
+		require.NoError(t, ew.Close())
+
+		assert.NotContains(t, buf.String(), snapshotMagic)
+
+		dr, err := NewDecryptingReader(&buf, key)
+		require.NoError(t, err)
+
+		dst := NewNativeMapUint64(uint32(n)) //nolint:gosec // test sizes are small
+		_, err = Import(context.Background(), dst, dr, ImportOptions{})
+		require.NoError(t, err)
+		requireSameContents(t, src, dst)
+	}
+}
+
+// TestEncryptedSnapshotTampering tests that a wrong key, modified bytes and
+// truncation are all reported as ErrSnapshotDecryption.
+func TestEncryptedSnapshotTampering(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 16)
+	plain := bytes.Repeat([]byte("0123456789abcdef"), encryptionChunkSize/8)
+	data := encryptBytes(t, key, plain)
+
+	got, err := decryptBytes(key, data)
+	require.NoError(t, err)
+	require.Equal(t, plain, got)
+
+	_, err = decryptBytes(bytes.Repeat([]byte{2}, 16), data)
+	require.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)/2] ^= 1
+	_, err = decryptBytes(key, flipped)
+	require.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	// cut at the boundary after the first full chunk
+	firstChunk := encryptionHeaderSize + encryptionFrameHeaderSize + encryptionChunkSize + 16
+	_, err = decryptBytes(key, data[:firstChunk])
+	require.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	_, err = decryptBytes(key, data[:len(data)-1])
+	require.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	_, err = decryptBytes(key, []byte("TXMP"))
+	require.ErrorIs(t, err, ErrSnapshotDecryption)
+
+	_, err = NewEncryptingWriter(io.Discard, []byte("short"))
+	require.ErrorAs(t, err, new(aes.KeySizeError))
+}
+
+// TestEncryptedSnapshotStore tests saving and restoring through an
+// EncryptedSnapshotStore wrapping every SnapshotStore implementation.
+func TestEncryptedSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{9}, 32)
+
+	for name, inner := range snapshotStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store, err := NewEncryptedSnapshotStore(inner, key)
+			require.NoError(t, err)
+
+			src := populatedMap(t, 500)
+			require.NoError(t, SaveSnapshot(ctx, store, "snap", src))
+
+			// the underlying store only holds ciphertext
+			_, err = RestoreSnapshot(ctx, inner, "snap", NewNativeMapUint64(500), ImportOptions{})
+			require.ErrorIs(t, err, ErrInvalidSnapshot)
+
+			dst := NewNativeMapUint64(500)
+			_, err = RestoreSnapshot(ctx, store, "snap", dst, ImportOptions{})
+			require.NoError(t, err)
+			requireSameContents(t, src, dst)
+
+			wrongKey, err := NewEncryptedSnapshotStore(inner, bytes.Repeat([]byte{8}, 32))
+			require.NoError(t, err)
+
+			_, err = RestoreSnapshot(ctx, wrongKey, "snap", NewNativeMapUint64(500), ImportOptions{})
+			require.ErrorIs(t, err, ErrSnapshotDecryption)
+
+			names, err := store.List(ctx, "")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"snap"}, names)
+		})
+	}
+}
+
+// slowReader returns zeros, sleeping in every read. It counts the reads in
+// progress, and the reads started after returned was set.
+type slowReader struct {
+	returned atomic.Bool
+	reading  atomic.Int64
+	late     atomic.Int64
+}
+
+// Read fills p with zeros after a short sleep.
+func (r *slowReader) Read(p []byte) (int, error) {
+	r.reading.Add(1)
+	defer r.reading.Add(-1)
+
+	if r.returned.Load() {
+		r.late.Add(1)
+	}
+
+	time.Sleep(time.Millisecond)
+	clear(p)
+
+	return len(p), nil
+}
+
+// TestEncryptedSnapshotStorePutFails tests that Put does not return while
+// the encrypting goroutine may still read the stream, when the wrapped store
+// fails right after the header.
+func TestEncryptedSnapshotStorePutFails(t *testing.T) {
+	inner := failingSnapshotStore{prefix: encryptionHeaderSize}
+
+	store, err := NewEncryptedSnapshotStore(inner, bytes.Repeat([]byte{9}, 32))
+	require.NoError(t, err)
+
+	r := &slowReader{}
+	require.ErrorIs(t, store.Put(context.Background(), "snap", r), errStoreFailed)
+	r.returned.Store(true)
+	assert.Zero(t, r.reading.Load(), "no read is in progress")
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, r.late.Load(), "no read starts later")
+}
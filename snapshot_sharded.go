@@ -0,0 +1,346 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+const (
+	// shardFilePattern is the file name of shard i of n written by ExportSharded.
+	shardFilePattern = "shard-%05d-of-%05d.txmp"
+
+	// shardFileGlob matches the shard files written by ExportSharded.
+	shardFileGlob = "shard-*-of-*.txmp"
+)
+
+// ErrIncompleteShardSet is returned by ImportSharded when the shard files in a
+// directory do not form one complete set written by a single ExportSharded call.
+var ErrIncompleteShardSet = errors.New("incomplete snapshot shard set")
+
+// ExportSharded exports m as shards separate snapshot files in dir, writing
+// them in parallel so that large maps can be saved using all cores and, when
+// dir spans several disks (e.g. a RAID-0 or striped volume), all disks.
+//
+// For the split maps the shards are contiguous ranges of buckets, so every
+// shard is exported by its own goroutine without any coordination. Other maps
+// cannot be partitioned and are always exported as a single shard.
+//
+// Each shard is a regular snapshot (see Export) written to a temporary file and
+// renamed into place once all shards succeeded; shard files of a previous
+// export in dir are replaced.
+//
+// Params:
+//   - ctx: Checked before each shard is written.
+//   - m: The map to export; it must not be written to while this runs.
+//   - dir: The directory to write the shard files to; it is created if needed.
+//   - shards: The number of shard files to write. Values below one mean one;
+//     it is capped at the number of buckets of m.
+//
+// Returns:
+//   - error: Any error from Export or the file system. No shard file of this
+//     export is left behind on error.
+func ExportSharded(ctx context.Context, m ReadOnlyTxMap, dir string, shards int) error {
+	buckets := txMapBuckets(m)
+	shards = max(1, min(shards, len(buckets)))
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	tmpFiles := make([]string, shards)
+	errs := make([]error, shards)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < shards; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+
+			part := bucketRange(buckets[i*len(buckets)/shards : (i+1)*len(buckets)/shards])
+			tmpFiles[i], errs[i] = exportShardFile(dir, i, shards, part)
+		}(i)
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		removeFiles(tmpFiles)
+		return err
+	}
+
+	old, err := filepath.Glob(filepath.Join(dir, shardFileGlob))
+	if err != nil {
+		removeFiles(tmpFiles)
+		return err
+	}
+
+	removeFiles(old)
+
+	for i, tmp := range tmpFiles {
+		if err = os.Rename(tmp, filepath.Join(dir, fmt.Sprintf(shardFilePattern, i, shards))); err != nil {
+			removeFiles(tmpFiles[i:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportSharded imports all shard files written by ExportSharded in dir into
+// dst, loading the shards in parallel. dst must be safe for concurrent writes,
+// which all maps in this package are.
+//
+// Params:
+//   - ctx: Cancels the import; passed to Import for every shard.
+//   - dst: The map to import into.
+//   - dir: The directory holding the shard files.
+//   - opts: Passed to Import for every shard. Progress, if set, is called
+//     with the sum over all shards and never concurrently; its total and
+//     ETA only cover the shards whose header was read.
+//
+// Returns:
+//   - ImportReport: The sum of the reports of all shards.
+//   - error: ErrIncompleteShardSet if shard files are missing or belong to
+//     different exports, or the errors returned by Import.
+func ImportSharded(ctx context.Context, dst TxMap, dir string, opts ImportOptions) (ImportReport, error) {
+	files, err := shardFiles(dir)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	return importFiles(ctx, files, len(files), func(int) TxMap { return dst }, opts)
+}
+
+// importFiles imports files in parallel, file i into dstOf(i), from at most
+// workers goroutines. Progress of opts is called with the sum over all files,
+// as described for ImportSharded.
+func importFiles(ctx context.Context, files []string, workers int, dstOf func(i int) TxMap, opts ImportOptions) (ImportReport, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		next    atomic.Int64
+		reports = make([]ImportReport, len(files))
+		errs    = make([]error, len(files))
+	)
+
+	progress := newProgressTracker(nil, opts.ProgressInterval, 0)
+
+	fileOpts := func(i int) ImportOptions {
+		fo := opts
+
+		if opts.Progress != nil {
+			fo.Progress = func(report ImportReport) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				reports[i] = report
+				opts.Progress(sumImportReports(reports, progress))
+			}
+		}
+
+		return fo
+	}
+
+	for w := max(1, min(workers, len(files))); w > 0; w-- {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := int(next.Add(1) - 1); i < len(files); i = int(next.Add(1) - 1) {
+				report, err := importShardFile(ctx, dstOf(i), files[i], fileOpts(i))
+
+				mu.Lock()
+				reports[i] = report
+				errs[i] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return sumImportReports(reports, progress), errors.Join(errs...)
+}
+
+// exportShardFile exports part to a temporary file in dir and returns its name.
+func exportShardFile(dir string, shard, shards int, part bucketRange) (string, error) {
+	return exportTempFile(dir, fmt.Sprintf(shardFilePattern, shard, shards), part)
+}
+
+// exportTempFile exports src to a synced temporary file in dir, named after
+// name, and returns its name.
+func exportTempFile(dir, name string, src snapshotSource) (string, error) {
+	f, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	err = exportSource(f, src)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// importShardFile imports a single shard file into dst.
+func importShardFile(ctx context.Context, dst TxMap, file string, opts ImportOptions) (ImportReport, error) {
+	f, err := os.Open(file) //nolint:gosec // file names come from shardFiles
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	report, err := Import(ctx, dst, f, opts)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+
+	return report, nil
+}
+
+// shardFiles returns the shard files in dir in shard order, checking that they
+// form exactly one complete set.
+func shardFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, shardFileGlob))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no shard files in %s", ErrIncompleteShardSet, dir)
+	}
+
+	sort.Strings(files)
+
+	for i, file := range files {
+		var shard, shards int
+
+		if _, err = fmt.Sscanf(filepath.Base(file), shardFilePattern, &shard, &shards); err != nil {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrIncompleteShardSet, filepath.Base(file))
+		}
+
+		if shard != i || shards != len(files) {
+			return nil, fmt.Errorf("%w: found %s as file %d of %d", ErrIncompleteShardSet, filepath.Base(file), i+1, len(files))
+		}
+	}
+
+	return files, nil
+}
+
+// sumImportReports adds up the reports of several shards, measuring the
+// elapsed time and the ETA of the sum with progress.
+func sumImportReports(reports []ImportReport, progress *progressTracker) ImportReport {
+	var sum ImportReport
+
+	for _, r := range reports {
+		sum.Total += r.Total
+		sum.Read += r.Read
+		sum.Inserted += r.Inserted
+		sum.Skipped += r.Skipped
+		sum.Bytes += r.Bytes
+	}
+
+	progress.total = sum.Total
+
+	return sum.measured(progress, sum.Bytes)
+}
+
+// removeFiles removes the named files, ignoring empty names and errors.
+func removeFiles(files []string) {
+	for _, file := range files {
+		if file != "" {
+			_ = os.Remove(file)
+		}
+	}
+}
+
+// txMapBuckets returns the buckets of the split maps in bucket order, or m
+// itself for maps that are not split.
+func txMapBuckets(m ReadOnlyTxMap) []ReadOnlyTxMap {
+	switch sm := m.(type) {
+	case readOnlyTxMap:
+		return txMapBuckets(sm.m)
+	case *SplitSwissMap:
+		return splitBuckets(sm.m, sm.nrOfBuckets)
+	case *SplitSwissMapUint64:
+		return splitBuckets(sm.m, sm.nrOfBuckets)
+	case *NativeSplitMap:
+		return splitBuckets(sm.m, sm.nrOfBuckets)
+	case *NativeSplitMapUint64:
+		return splitBuckets(sm.m, sm.nrOfBuckets)
+	case *TwoChoiceSplitMap:
+		return splitBuckets(sm.m, sm.nrOfBuckets)
+	default:
+		return []ReadOnlyTxMap{m}
+	}
+}
+
+// splitBuckets returns buckets 0..nrOfBuckets of a split map as a slice.
+func splitBuckets[M ReadOnlyTxMap](buckets []M, nrOfBuckets uint16) []ReadOnlyTxMap {
+	out := make([]ReadOnlyTxMap, 0, int(nrOfBuckets)+1)
+
+	for i := uint16(0); i <= nrOfBuckets; i++ {
+		out = append(out, buckets[i])
+	}
+
+	return out
+}
+
+// bucketRange is a snapshotSource over a contiguous range of buckets.
+type bucketRange []ReadOnlyTxMap
+
+// Length returns the number of entries in all buckets of the range.
+func (b bucketRange) Length() int {
+	n := 0
+
+	for _, m := range b {
+		n += m.Length()
+	}
+
+	return n
+}
+
+// Iter iterates over all buckets of the range. Stops iterating if f returns true.
+func (b bucketRange) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	stopped := false
+
+	for _, m := range b {
+		m.Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
+
+		if stopped {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package txmap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportImportSharded tests a sharded round trip for split and unsplit maps.
+func TestExportImportSharded(t *testing.T) {
+	const n = 2000
+
+	frozen := copyInto(t, populatedMap(t, n), NewNativeSplitMapUint64(n, 4)).(*NativeSplitMapUint64).FreezeReadOnly()
+
+	tests := map[string]struct {
+		src    ReadOnlyTxMap
+		shards int
+		files  int
+	}{
+		"NativeSplitMapUint64": {src: populatedMap(t, n), shards: 8, files: 8},
+		"SplitSwissMapUint64":  {src: copyInto(t, populatedMap(t, n), NewSplitSwissMapUint64(n, 16)), shards: 64, files: 17},
+		"NativeMapUint64":      {src: copyInto(t, populatedMap(t, n), NewNativeMapUint64(n)), shards: 8, files: 1},
+		"FrozenView":           {src: frozen, shards: 8, files: 5},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			require.NoError(t, ExportSharded(context.Background(), tt.src, dir, tt.shards))
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, tt.files)
+
+			var (
+				mu    sync.Mutex
+				calls int
+			)
+
+			dst := NewSplitSwissMapUint64(n)
+			report, err := ImportSharded(context.Background(), dst, dir, ImportOptions{
+				ProgressInterval: 100,
+				Progress: func(ImportReport) {
+					mu.Lock()
+					calls++
+					mu.Unlock()
+				},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, ImportReport{Total: n, Read: n, Inserted: n}, importCounts(report))
+			assert.Positive(t, calls)
+			assert.Positive(t, report.Bytes, "the bytes of all shards are summed")
+			requireSameContents(t, tt.src, dst)
+		})
+	}
+}
+
+// TestExportShardedReplacesPreviousExport tests that a new export with fewer
+// shards removes the shard files of the previous one.
+func TestExportShardedReplacesPreviousExport(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, ExportSharded(context.Background(), populatedMap(t, 100), dir, 8))
+	require.NoError(t, ExportSharded(context.Background(), populatedMap(t, 50), dir, 2))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	dst := NewNativeMapUint64(50)
+	_, err = ImportSharded(context.Background(), dst, dir, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 50, dst.Length())
+}
+
+// TestImportShardedIncomplete tests that missing shard files are detected.
+func TestImportShardedIncomplete(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := ImportSharded(context.Background(), NewNativeMapUint64(0), dir, ImportOptions{})
+	require.ErrorIs(t, err, ErrIncompleteShardSet)
+
+	require.NoError(t, ExportSharded(context.Background(), populatedMap(t, 100), dir, 4))
+	require.NoError(t, os.Remove(filepath.Join(dir, "shard-00002-of-00004.txmp")))
+
+	_, err = ImportSharded(context.Background(), NewNativeMapUint64(0), dir, ImportOptions{})
+	require.ErrorIs(t, err, ErrIncompleteShardSet)
+}
+
+// copyInto copies all entries of src into dst and returns dst.
+func copyInto(t *testing.T, src, dst TxMap) TxMap {
+	t.Helper()
+
+	src.Iter(func(hash chainhash.Hash, value uint64) bool {
+		require.NoError(t, dst.Put(hash, value))
+		return false
+	})
+
+	return dst
+}
@@ -0,0 +1,328 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrSnapshotNotFound is returned by SnapshotStore.Get when no snapshot
+	// with the requested name exists.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+
+	// ErrInvalidSnapshotName is returned for snapshot names that are empty,
+	// contain path separators, or start with a dot (reserved for temporary files).
+	ErrInvalidSnapshotName = errors.New("invalid snapshot name")
+)
+
+// SnapshotStore persists named snapshot streams. Implementations must make Put
+// atomic from the point of view of Get and List: a snapshot is either fully
+// visible under its name or not at all, so a crash mid-upload never leaves a
+// truncated snapshot that a later restore would pick up.
+type SnapshotStore interface {
+	// Put stores the stream read from r under name, replacing any existing
+	// snapshot with that name.
+	Put(ctx context.Context, name string, r io.Reader) error
+
+	// Get opens the snapshot stored under name. The caller must close it.
+	// It returns an error wrapping ErrSnapshotNotFound if there is none.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+
+	// List returns the names of all stored snapshots that start with prefix,
+	// in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// SaveSnapshot exports m (see Export) and stores it in store under name,
+// streaming the export straight into the store without buffering it whole.
+//
+// Params:
+//   - ctx: Passed to store.Put.
+//   - store: The destination store.
+//   - name: The snapshot name.
+//   - m: The map to snapshot; it must not be written to while this runs.
+//
+// Returns:
+//   - error: Any error from Export or the store.
+func SaveSnapshot(ctx context.Context, store SnapshotStore, name string, m ReadOnlyTxMap) error {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_ = pw.CloseWithError(Export(pw, m))
+	}()
+
+	err := store.Put(ctx, name, pr)
+
+	// unblock the exporter if the store stopped reading early, and wait for it
+	// to stop iterating m before the caller may write to it again
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+
+	return err
+}
+
+// RestoreSnapshot reads the snapshot stored under name and imports it into dst
+// (see Import). If dst counts its epochs (see EpochTxMap), its epoch is
+// advanced when any entry was inserted, even if the restore then failed.
+//
+// Params:
+//   - ctx: Passed to store.Get and Import.
+//   - store: The store holding the snapshot.
+//   - name: The snapshot name.
+//   - dst: The map to import into.
+//   - opts: Passed to Import.
+//
+// Returns:
+//   - ImportReport: The report returned by Import.
+//   - error: Any error from the store or Import.
+func RestoreSnapshot(ctx context.Context, store SnapshotStore, name string, dst TxMap, opts ImportOptions) (ImportReport, error) {
+	rc, err := store.Get(ctx, name)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	defer func() {
+		_ = rc.Close()
+	}()
+
+	report, err := Import(ctx, dst, rc, opts)
+
+	if ea, ok := dst.(epochAdvancer); ok && report.Inserted > 0 {
+		ea.advanceEpoch()
+	}
+
+	return report, err
+}
+
+// check that FileSnapshotStore implements SnapshotStore
+var _ SnapshotStore = (*FileSnapshotStore)(nil)
+
+// FileSnapshotStore is a SnapshotStore that keeps one file per snapshot in a
+// local directory. Put writes to a temporary file in the same directory and
+// renames it into place, so readers never observe a partial snapshot.
+type FileSnapshotStore struct {
+	dir string
+}
+
+// NewFileSnapshotStore returns a FileSnapshotStore rooted at dir, creating the
+// directory if it does not exist.
+//
+// Params:
+//   - dir: The directory to store snapshots in.
+//
+// Returns:
+//   - *FileSnapshotStore: The store.
+//   - error: Any error creating the directory.
+func NewFileSnapshotStore(dir string) (*FileSnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &FileSnapshotStore{dir: dir}, nil
+}
+
+// Put writes the snapshot to a temporary file, syncs it, and renames it to name.
+func (s *FileSnapshotStore) Put(ctx context.Context, name string, r io.Reader) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+
+	if err = writeFileFrom(ctx, f, r); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err = os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// Get opens the snapshot file called name.
+func (s *FileSnapshotStore) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	if err := validateSnapshotName(name); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+
+	return f, err
+}
+
+// List returns the snapshot files whose names start with prefix. Temporary
+// files of in-progress Put calls are not included.
+func (s *FileSnapshotStore) List(_ context.Context, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	// os.ReadDir already sorts by file name; sort anyway so the documented
+	// ordering does not depend on that implementation detail
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// writeFileFrom copies r into f, checking ctx between chunks, then syncs and
+// closes f.
+func writeFileFrom(ctx context.Context, f *os.File, r io.Reader) error {
+	_, err := io.Copy(f, contextReader{ctx: ctx, r: r})
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// check that S3SnapshotStore implements SnapshotStore
+var _ SnapshotStore = (*S3SnapshotStore)(nil)
+
+// S3Client is the subset of an S3-compatible object storage API used by
+// S3SnapshotStore. It is deliberately small so that it can be satisfied by a
+// thin adapter over any SDK (AWS, MinIO, GCS interoperability mode, ...)
+// without this package depending on one.
+type S3Client interface {
+	// PutObject uploads body to bucket/key. S3 object uploads are atomic, which
+	// gives S3SnapshotStore its all-or-nothing Put semantics.
+	PutObject(ctx context.Context, bucket, key string, body io.Reader) error
+
+	// GetObject downloads bucket/key. It must return an error wrapping
+	// ErrSnapshotNotFound if the object does not exist.
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+
+	// ListObjects returns the keys in bucket starting with prefix.
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// S3SnapshotStore is a SnapshotStore that keeps snapshots as objects in an
+// S3-compatible bucket, under an optional key prefix.
+type S3SnapshotStore struct {
+	client S3Client
+	bucket string
+	prefix string
+}
+
+// NewS3SnapshotStore returns an S3SnapshotStore that stores snapshot name as
+// the object prefix+name in bucket.
+//
+// Params:
+//   - client: The object storage client.
+//   - bucket: The bucket to store snapshots in.
+//   - prefix: A key prefix such as "txmaps/node-1/"; may be empty.
+//
+// Returns:
+//   - *S3SnapshotStore: The store.
+func NewS3SnapshotStore(client S3Client, bucket, prefix string) *S3SnapshotStore {
+	return &S3SnapshotStore{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+	}
+}
+
+// Put uploads the snapshot as object prefix+name.
+func (s *S3SnapshotStore) Put(ctx context.Context, name string, r io.Reader) error {
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	return s.client.PutObject(ctx, s.bucket, s.prefix+name, r)
+}
+
+// Get downloads object prefix+name.
+func (s *S3SnapshotStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	if err := validateSnapshotName(name); err != nil {
+		return nil, err
+	}
+
+	return s.client.GetObject(ctx, s.bucket, s.prefix+name)
+}
+
+// List returns the names (without the store's key prefix) of the snapshots
+// starting with prefix.
+func (s *S3SnapshotStore) List(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := s.client.ListObjects(ctx, s.bucket, s.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		name, ok := strings.CutPrefix(key, s.prefix)
+		if !ok || strings.Contains(name, "/") {
+			// objects in nested "directories" belong to other stores
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// validateSnapshotName rejects names that are empty, could address anything
+// other than a single entry directly inside the store, or would collide with
+// the temporary files of FileSnapshotStore.
+func validateSnapshotName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidSnapshotName, name)
+	}
+
+	return nil
+}
+
+// contextReader is an io.Reader that fails with the context's error once the
+// context is done, so long copies can be cancelled between reads.
+type contextReader struct {
+	ctx context.Context //nolint:containedctx // scoped to a single io.Copy call
+	r   io.Reader
+}
+
+// Read reads from the wrapped reader unless the context is done.
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
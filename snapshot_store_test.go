@@ -0,0 +1,181 @@
+package txmap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memS3Client is an in-memory S3Client for tests.
+type memS3Client struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemS3Client() *memS3Client {
+	return &memS3Client{objects: make(map[string][]byte)}
+}
+
+func (c *memS3Client) PutObject(_ context.Context, bucket, key string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.objects[bucket+"/"+key] = data
+
+	return nil
+}
+
+func (c *memS3Client) GetObject(_ context.Context, bucket, key string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.objects[bucket+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, key)
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (c *memS3Client) ListObjects(_ context.Context, bucket, prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var keys []string
+
+	for k := range c.objects {
+		if key, ok := strings.CutPrefix(k, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// snapshotStores returns a fresh instance of every SnapshotStore implementation.
+func snapshotStores(t *testing.T) map[string]SnapshotStore {
+	t.Helper()
+
+	fs, err := NewFileSnapshotStore(filepath.Join(t.TempDir(), "snapshots"))
+	require.NoError(t, err)
+
+	return map[string]SnapshotStore{
+		"File": fs,
+		"S3":   NewS3SnapshotStore(newMemS3Client(), "bucket", "txmaps/"),
+	}
+}
+
+// TestSnapshotStoreSaveRestore tests saving a map to every store and restoring it.
+func TestSnapshotStoreSaveRestore(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range snapshotStores(t) {
+		t.Run(name, func(t *testing.T) {
+			src := populatedMap(t, 500)
+
+			require.NoError(t, SaveSnapshot(ctx, store, "block-100", src))
+			require.NoError(t, SaveSnapshot(ctx, store, "block-101", src))
+			require.NoError(t, SaveSnapshot(ctx, store, "other", src))
+
+			names, err := store.List(ctx, "block-")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"block-100", "block-101"}, names)
+
+			dst := NewNativeMapUint64(500)
+			report, err := RestoreSnapshot(ctx, store, "block-101", dst, ImportOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(500), report.Inserted)
+			requireSameContents(t, src, dst)
+
+			_, err = store.Get(ctx, "missing")
+			require.ErrorIs(t, err, ErrSnapshotNotFound)
+
+			for _, invalid := range []string{"", ".hidden", "../escape", `a\b`} {
+				require.ErrorIs(t, store.Put(ctx, invalid, strings.NewReader("x")), ErrInvalidSnapshotName)
+			}
+		})
+	}
+}
+
+// TestFileSnapshotStoreAtomicPut tests that a failed Put leaves neither a
+// snapshot nor a temporary file behind.
+func TestFileSnapshotStoreAtomicPut(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileSnapshotStore(dir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, store.Put(ctx, "snap", strings.NewReader("data")), context.Canceled)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// errStoreFailed is returned by failingSnapshotStore.Put.
+var errStoreFailed = errors.New("store failed")
+
+// failingSnapshotStore is a SnapshotStore whose Put fails after reading the
+// first prefix bytes.
+type failingSnapshotStore struct {
+	SnapshotStore
+	prefix int
+}
+
+// Put reads prefix bytes of r and returns errStoreFailed.
+func (s failingSnapshotStore) Put(_ context.Context, _ string, r io.Reader) error {
+	_, _ = io.ReadFull(r, make([]byte, s.prefix))
+	return errStoreFailed
+}
+
+// watchedMap is a ReadOnlyTxMap that counts the entries iterated after
+// returned was set.
+type watchedMap struct {
+	ReadOnlyTxMap
+	returned atomic.Bool
+	late     atomic.Int64
+}
+
+// Iter iterates the wrapped map, counting late entries.
+func (m *watchedMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	m.ReadOnlyTxMap.Iter(func(hash chainhash.Hash, value uint64) bool {
+		if m.returned.Load() {
+			m.late.Add(1)
+		}
+
+		return f(hash, value)
+	})
+}
+
+// TestSaveSnapshotStoreFails tests that SaveSnapshot does not return while
+// the export is still iterating the map when the store fails right away.
+func TestSaveSnapshotStoreFails(t *testing.T) {
+	m := &watchedMap{ReadOnlyTxMap: populatedMap(t, 60_000)}
+
+	require.ErrorIs(t, SaveSnapshot(context.Background(), failingSnapshotStore{}, "snap", m), errStoreFailed)
+	m.returned.Store(true)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Zero(t, m.late.Load())
+}
@@ -0,0 +1,351 @@
+package txmap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the golden snapshot files instead of comparing with them.
+// Only use it when adding a new snapshot version; existing golden files must
+// never change.
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden snapshot files") //nolint:gochecknoglobals // test flag
+
+// goldenSnapshotRecords is the number of records in the golden snapshots, one
+// more than a chunk so that version 2 has a full and a partial chunk.
+const goldenSnapshotRecords = snapshotChunkRecords + 1
+
+// goldenSnapshotSource is a snapshotSource with a fixed iteration order, so
+// that its snapshots are byte-for-byte reproducible.
+type goldenSnapshotSource []chainhash.Hash
+
+// newGoldenSnapshotSource returns the contents of the golden snapshots.
+func newGoldenSnapshotSource() goldenSnapshotSource {
+	src := make(goldenSnapshotSource, goldenSnapshotRecords)
+	for i := range src {
+		src[i] = sha256.Sum256(binary.LittleEndian.AppendUint64(nil, uint64(i))) //nolint:gosec // i is non-negative
+	}
+
+	return src
+}
+
+// Length returns the number of records.
+func (g goldenSnapshotSource) Length() int {
+	return len(g)
+}
+
+// Iter yields the hashes in order, each with its index times 7 as value.
+func (g goldenSnapshotSource) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	for i, hash := range g {
+		if f(hash, uint64(i)*7) { //nolint:gosec // i is non-negative
+			return
+		}
+	}
+}
+
+// TestSnapshotGolden tests that every snapshot version is still written and
+// read exactly as by the release that introduced it, using the golden files in
+// testdata/snapshot.
+func TestSnapshotGolden(t *testing.T) {
+	src := newGoldenSnapshotSource()
+
+	for _, version := range []uint16{SnapshotVersion1, SnapshotVersion2} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			path := filepath.Join("testdata", "snapshot", fmt.Sprintf("v%d.golden", version))
+
+			var buf bytes.Buffer
+			require.NoError(t, exportSourceOptions(&buf, src, ExportOptions{Version: version}))
+
+			if *updateGolden {
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+				require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+			}
+
+			golden, err := os.ReadFile(path) //nolint:gosec // fixed test path
+			require.NoError(t, err)
+			require.Equal(t, golden, buf.Bytes(), "version %d layout changed", version)
+
+			dst := NewNativeMapUint64(goldenSnapshotRecords)
+			report, err := Import(context.Background(), dst, bytes.NewReader(golden), ImportOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(goldenSnapshotRecords), report.Inserted)
+
+			src.Iter(func(hash chainhash.Hash, value uint64) bool {
+				got, ok := dst.Get(hash)
+				require.True(t, ok)
+				require.Equal(t, value, got)
+
+				return false
+			})
+		})
+	}
+}
+
+// TestSnapshotUnsupportedVersion tests that snapshots of a newer version are
+// rejected instead of misread, and that they cannot be written.
+func TestSnapshotUnsupportedVersion(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, writeSnapshotHeader(&buf, snapshotHeader{version: SnapshotVersion + 1}))
+
+	_, err := Import(context.Background(), NewNativeMapUint64(0), &buf, ImportOptions{})
+	require.ErrorIs(t, err, ErrUnsupportedSnapshotVersion)
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+
+	err = ExportVersion(&buf, NewNativeMapUint64(0), SnapshotVersion+1)
+	require.ErrorIs(t, err, ErrUnsupportedSnapshotVersion)
+}
+
+// TestExportVersion1 tests that ExportVersion writes snapshots that older
+// releases can read, and that the current release reads them back.
+func TestExportVersion1(t *testing.T) {
+	const n = 100
+
+	m := populatedMap(t, n)
+
+	var buf bytes.Buffer
+	require.NoError(t, ExportVersion(&buf, m, SnapshotVersion1))
+	assert.Len(t, buf.Bytes(), snapshotHeaderSize+n*snapshotRecordSize)
+	assert.Equal(t, SnapshotVersion1, binary.LittleEndian.Uint16(buf.Bytes()[4:6]))
+
+	dst := NewNativeMapUint64(n)
+	_, err := Import(context.Background(), dst, &buf, ImportOptions{})
+	require.NoError(t, err)
+	requireSameContents(t, m, dst)
+}
+
+// populatedMap returns a NativeSplitMapUint64 holding hashN(i) -> i for i in [0, n).
+func populatedMap(t *testing.T, n int) TxMap {
+	t.Helper()
+
+	m := NewNativeSplitMapUint64(uint32(n)) //nolint:gosec // test sizes are small
+	for i := 0; i < n; i++ {
+		require.NoError(t, m.Put(hashN(i), uint64(i))) //nolint:gosec // test sizes are small
+	}
+
+	return m
+}
+
+// exportMap exports m and returns the snapshot bytes.
+func exportMap(t *testing.T, m TxMap) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(&buf, m))
+
+	return buf.Bytes()
+}
+
+// TestExportImportRoundTrip tests that a snapshot imported into every TxMap
+// implementation reproduces the exported contents.
+func TestExportImportRoundTrip(t *testing.T) {
+	const n = 1000
+
+	snapshot := exportMap(t, populatedMap(t, n))
+	assert.Len(t, snapshot, snapshotHeaderSize+n*snapshotRecordSize+snapshotChunkChecksumSize+sha256.Size)
+
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			dst := factory()
+
+			report, err := Import(context.Background(), dst, bytes.NewReader(snapshot), ImportOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, ImportReport{Total: n, Read: n, Inserted: n}, importCounts(report))
+
+			require.Equal(t, n, dst.Length())
+
+			for i := 0; i < n; i++ {
+				v, ok := dst.Get(hashN(i))
+				require.True(t, ok)
+				require.Equal(t, uint64(i), v) //nolint:gosec // test sizes are small
+			}
+		})
+	}
+}
+
+// TestImportDuplicates tests the skip-existing and error-on-duplicate modes.
+func TestImportDuplicates(t *testing.T) {
+	snapshot := exportMap(t, populatedMap(t, 10))
+
+	t.Run("error on duplicate", func(t *testing.T) {
+		dst := NewSwissMapUint64(10)
+		require.NoError(t, dst.Put(hashN(5), 500))
+
+		_, err := Import(context.Background(), dst, bytes.NewReader(snapshot), ImportOptions{})
+		require.ErrorIs(t, err, ErrHashAlreadyExists)
+	})
+
+	t.Run("skip existing", func(t *testing.T) {
+		dst := NewSwissMapUint64(10)
+		require.NoError(t, dst.Put(hashN(5), 500))
+
+		report, err := Import(context.Background(), dst, bytes.NewReader(snapshot), ImportOptions{SkipExisting: true})
+		require.NoError(t, err)
+		assert.Equal(t, ImportReport{Total: 10, Read: 10, Inserted: 9, Skipped: 1}, importCounts(report))
+
+		v, _ := dst.Get(hashN(5))
+		assert.Equal(t, uint64(500), v)
+	})
+}
+
+// TestImportProgressAndCancellation tests progress callbacks and that a
+// cancelled context aborts the import.
+func TestImportProgressAndCancellation(t *testing.T) {
+	snapshot := exportMap(t, populatedMap(t, 100))
+
+	t.Run("progress", func(t *testing.T) {
+		var reads []uint64
+
+		_, err := Import(context.Background(), NewNativeMapUint64(100), bytes.NewReader(snapshot), ImportOptions{
+			ProgressInterval: 30,
+			Progress:         func(r ImportReport) { reads = append(reads, r.Read) },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{30, 60, 90, 100}, reads)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		dst := NewNativeMapUint64(100)
+		report, err := Import(ctx, dst, bytes.NewReader(snapshot), ImportOptions{
+			ProgressInterval: 10,
+			Progress: func(r ImportReport) {
+				if r.Read == 50 {
+					cancel()
+				}
+			},
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, uint64(50), report.Read)
+		assert.Equal(t, 50, dst.Length())
+	})
+}
+
+// TestImportInvalidSnapshot tests that malformed and truncated streams fail
+// with ErrInvalidSnapshot.
+func TestImportInvalidSnapshot(t *testing.T) {
+	snapshot := exportMap(t, populatedMap(t, 10))
+
+	tests := map[string][]byte{
+		"empty":     nil,
+		"bad magic": append([]byte("NOPE"), snapshot[4:]...),
+		"version":   append(append([]byte{}, snapshot[:4]...), append([]byte{9, 0}, snapshot[6:]...)...),
+		"truncated": snapshot[:len(snapshot)-1],
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Import(context.Background(), NewNativeMapUint64(10), bytes.NewReader(data), ImportOptions{})
+			require.ErrorIs(t, err, ErrInvalidSnapshot)
+		})
+	}
+}
+
+// TestImportCorruptedSnapshot tests that a flipped bit or truncation in a
+// checksummed snapshot is reported as a SnapshotCorruptionError naming the
+// offending chunk, before any of that chunk's records are inserted.
+func TestImportCorruptedSnapshot(t *testing.T) {
+	const n = 3*snapshotChunkRecords + 10
+
+	snapshot := exportMap(t, populatedMap(t, n))
+	chunkSize := snapshotChunkRecords*snapshotRecordSize + snapshotChunkChecksumSize
+
+	tests := map[string]struct {
+		data  []byte
+		chunk int64
+	}{
+		"flipped record": {
+			data:  flipByte(snapshot, snapshotHeaderSize+chunkSize+100),
+			chunk: 1,
+		},
+		"flipped checksum": {
+			data:  flipByte(snapshot, snapshotHeaderSize+3*chunkSize-1),
+			chunk: 2,
+		},
+		"truncated chunk": {
+			data:  snapshot[:snapshotHeaderSize+2*chunkSize+10],
+			chunk: 2,
+		},
+		"truncated at chunk boundary": {
+			data:  snapshot[:snapshotHeaderSize+2*chunkSize],
+			chunk: 2,
+		},
+		"flipped digest": {
+			data:  flipByte(snapshot, len(snapshot)-1),
+			chunk: -1,
+		},
+		"missing digest": {
+			data:  snapshot[:len(snapshot)-sha256.Size],
+			chunk: -1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dst := NewNativeMapUint64(n)
+
+			report, err := Import(context.Background(), dst, bytes.NewReader(tt.data), ImportOptions{})
+			require.ErrorIs(t, err, ErrInvalidSnapshot)
+
+			var corrupt *SnapshotCorruptionError
+			require.ErrorAs(t, err, &corrupt)
+			assert.Equal(t, tt.chunk, corrupt.Chunk)
+
+			// only the records of fully verified chunks were inserted
+			if tt.chunk >= 0 {
+				assert.Equal(t, uint64(tt.chunk)*snapshotChunkRecords, report.Inserted) //nolint:gosec // chunk is not negative here
+			}
+		})
+	}
+
+	_, err := Import(context.Background(), NewNativeMapUint64(0), bytes.NewReader(exportMap(t, populatedMap(t, 0))[:snapshotHeaderSize]), ImportOptions{})
+	require.ErrorIs(t, err, ErrInvalidSnapshot)
+}
+
+// TestImportVersion1Snapshot tests that snapshots in the original unchecksummed
+// format can still be imported.
+func TestImportVersion1Snapshot(t *testing.T) {
+	const n = 100
+
+	var buf bytes.Buffer
+
+	require.NoError(t, writeSnapshotHeader(&buf, snapshotHeader{version: SnapshotVersion1, count: n}))
+
+	for i := 0; i < n; i++ {
+		h := hashN(i)
+		buf.Write(h[:])
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(i))) //nolint:gosec // test sizes are small
+	}
+
+	dst := NewNativeMapUint64(n)
+	_, err := Import(context.Background(), dst, &buf, ImportOptions{})
+	require.NoError(t, err)
+	requireSameContents(t, populatedMap(t, n), dst)
+}
+
+// flipByte returns a copy of data with one bit of the byte at i flipped.
+func flipByte(data []byte, i int) []byte {
+	flipped := bytes.Clone(data)
+	flipped[i] ^= 1
+
+	return flipped
+}
+
+// importCounts returns the counters of report, without the bytes read and the
+// timings, which vary between runs.
+func importCounts(report ImportReport) ImportReport {
+	report.Bytes, report.Elapsed, report.ETA = 0, 0, 0
+	return report
+}
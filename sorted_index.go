@@ -0,0 +1,283 @@
+package txmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Sorted index format
+//
+// A sorted index is a read-only archive of a TxMap that can be queried in
+// place, without loading it into memory:
+//
+//	header:  magic "TXSI" | version uint16 | reserved uint16 | count uint64
+//	records: count × (hash [32]byte | value uint64), sorted by hash bytes
+//
+// Integers are little-endian and records have the snapshot record layout.
+// Because transaction hashes are uniformly distributed, the position of a
+// hash in the sorted file is well predicted by its leading bytes, so lookups
+// use interpolation search and touch only a handful of records.
+
+const (
+	// sortedIndexMagic identifies a sorted index file.
+	sortedIndexMagic = "TXSI"
+
+	// sortedIndexVersion1 is the layout described above.
+	sortedIndexVersion1 = uint16(1)
+
+	// sortedIndexWriteRecords is the number of records WriteSortedIndex
+	// buffers per WriteAt call.
+	sortedIndexWriteRecords = 4096
+)
+
+// ErrInvalidSortedIndex is returned when a file is not a sorted index or its
+// size does not match the record count in its header.
+var ErrInvalidSortedIndex = errors.New("invalid sorted index")
+
+// WriteSortedIndex writes all entries of m to w as a sorted index file, for
+// cold archival lookups through OpenSortedIndex.
+//
+// The entries are sorted in memory, so this needs about 40 bytes per entry of
+// m. The header is written last: a write that fails midway leaves a file that
+// OpenSortedIndex rejects.
+//
+// Params:
+//   - w: The destination, typically an *os.File.
+//   - m: The map to write; it must not be written to while this runs.
+//
+// Returns:
+//   - error: ErrMapChangedDuringExport if m changed length while it was read,
+//     or any error returned by w.
+func WriteSortedIndex(w io.WriterAt, m ReadOnlyTxMap) error {
+	entries := KVs(m)
+
+	if len(entries) != m.Length() {
+		return fmt.Errorf("%w: read %d entries, map has %d", ErrMapChangedDuringExport, len(entries), m.Length())
+	}
+
+	slices.SortFunc(entries, func(a, b KV) int {
+		return bytes.Compare(a.Hash[:], b.Hash[:])
+	})
+
+	buf := make([]byte, 0, sortedIndexWriteRecords*snapshotRecordSize)
+	offset := int64(snapshotHeaderSize)
+
+	for i, e := range entries {
+		buf = append(buf, e.Hash[:]...)
+		buf = binary.LittleEndian.AppendUint64(buf, e.Value)
+
+		if len(buf) == cap(buf) || i == len(entries)-1 {
+			if _, err := w.WriteAt(buf, offset); err != nil {
+				return err
+			}
+
+			offset += int64(len(buf))
+			buf = buf[:0]
+		}
+	}
+
+	var header [snapshotHeaderSize]byte
+
+	copy(header[:4], sortedIndexMagic)
+	binary.LittleEndian.PutUint16(header[4:6], sortedIndexVersion1)
+	binary.LittleEndian.PutUint64(header[8:16], uint64(len(entries)))
+
+	_, err := w.WriteAt(header[:], 0)
+
+	return err
+}
+
+// check that SortedIndex implements ReadOnlyTxMap
+var _ ReadOnlyTxMap = (*SortedIndex)(nil)
+
+// SortedIndex is a ReadOnlyTxMap that answers queries from a sorted index file
+// on disk, keeping only the open file in memory. It is safe for concurrent use.
+//
+// ReadOnlyTxMap cannot report I/O errors, so a record that cannot be read is
+// treated as missing: Get and Exists report a miss and Iter stops early. Err
+// returns the first such error.
+type SortedIndex struct {
+	f     *os.File
+	count int64
+
+	mu  sync.Mutex
+	err error
+}
+
+// OpenSortedIndex opens a sorted index file written by WriteSortedIndex.
+//
+// Params:
+//   - path: The path of the file.
+//
+// Returns:
+//   - *SortedIndex: The index; Close must be called to release the file.
+//   - error: ErrInvalidSortedIndex if the file is not a valid sorted index, or
+//     the error from opening it.
+func OpenSortedIndex(path string) (*SortedIndex, error) {
+	f, err := os.Open(path) //nolint:gosec // opening the caller's file is the point
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSortedIndex(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// newSortedIndex validates the header and size of f.
+func newSortedIndex(f *os.File) (*SortedIndex, error) {
+	var header [snapshotHeaderSize]byte
+
+	if _, err := f.ReadAt(header[:], 0); err != nil {
+		return nil, fmt.Errorf("%w: reading header: %w", ErrInvalidSortedIndex, err)
+	}
+
+	if string(header[:4]) != sortedIndexMagic {
+		return nil, fmt.Errorf("%w: bad magic %q", ErrInvalidSortedIndex, header[:4])
+	}
+
+	if version := binary.LittleEndian.Uint16(header[4:6]); version != sortedIndexVersion1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSortedIndex, version)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	count := binary.LittleEndian.Uint64(header[8:16])
+	records := (info.Size() - snapshotHeaderSize) / snapshotRecordSize
+
+	if count != uint64(records) || (info.Size()-snapshotHeaderSize)%snapshotRecordSize != 0 { //nolint:gosec // records is not negative
+		return nil, fmt.Errorf("%w: header has %d records, file size %d", ErrInvalidSortedIndex, count, info.Size())
+	}
+
+	return &SortedIndex{f: f, count: records}, nil
+}
+
+// Close closes the index file.
+func (s *SortedIndex) Close() error {
+	return s.f.Close()
+}
+
+// Err returns the first I/O error encountered while reading the index.
+func (s *SortedIndex) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Exists checks if the given hash exists in the index.
+func (s *SortedIndex) Exists(hash chainhash.Hash) bool {
+	_, ok := s.Get(hash)
+	return ok
+}
+
+// Get looks hash up with interpolation search, alternating with bisection
+// steps so that skewed data cannot make it worse than twice a binary search.
+func (s *SortedIndex) Get(hash chainhash.Hash) (uint64, bool) {
+	var rec [snapshotRecordSize]byte
+
+	target := binary.BigEndian.Uint64(hash[:8])
+	lo, hi := int64(0), s.count
+	loKey, hiKey := uint64(0), ^uint64(0)
+
+	for step := 0; lo < hi; step++ {
+		mid := lo + (hi-lo)/2
+
+		if step%2 == 0 && hiKey > loKey && target >= loKey {
+			fraction := float64(target-loKey) / float64(hiKey-loKey)
+			mid = min(hi-1, lo+int64(fraction*float64(hi-lo)))
+		}
+
+		if !s.readRecord(mid, rec[:]) {
+			return 0, false
+		}
+
+		switch bytes.Compare(rec[:chainhash.HashSize], hash[:]) {
+		case 0:
+			return binary.LittleEndian.Uint64(rec[chainhash.HashSize:]), true
+		case -1:
+			lo, loKey = mid+1, binary.BigEndian.Uint64(rec[:8])
+		default:
+			hi, hiKey = mid, binary.BigEndian.Uint64(rec[:8])
+		}
+	}
+
+	return 0, false
+}
+
+// Keys returns all hashes in the index, in sorted order.
+func (s *SortedIndex) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, s.count)
+
+	s.Iter(func(hash chainhash.Hash, _ uint64) bool {
+		keys = append(keys, hash)
+		return false
+	})
+
+	return keys
+}
+
+// Length returns the number of entries in the index.
+func (s *SortedIndex) Length() int {
+	return int(s.count)
+}
+
+// Iter iterates over the entries in sorted order, reading the file
+// sequentially. Stops iterating if f returns true.
+func (s *SortedIndex) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	r := bufio.NewReaderSize(io.NewSectionReader(s.f, snapshotHeaderSize, s.count*snapshotRecordSize), 1<<16)
+
+	var (
+		rec  [snapshotRecordSize]byte
+		hash chainhash.Hash
+	)
+
+	for i := int64(0); i < s.count; i++ {
+		if _, err := io.ReadFull(r, rec[:]); err != nil {
+			s.setErr(err)
+			return
+		}
+
+		copy(hash[:], rec[:chainhash.HashSize])
+
+		if f(hash, binary.LittleEndian.Uint64(rec[chainhash.HashSize:])) {
+			return
+		}
+	}
+}
+
+// readRecord reads record i into rec, recording any error.
+func (s *SortedIndex) readRecord(i int64, rec []byte) bool {
+	if _, err := s.f.ReadAt(rec, snapshotHeaderSize+i*snapshotRecordSize); err != nil {
+		s.setErr(err)
+		return false
+	}
+
+	return true
+}
+
+// setErr records err if it is the first error.
+func (s *SortedIndex) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
@@ -0,0 +1,95 @@
+package txmap
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSortedIndexFile writes m to a sorted index file and returns its path.
+func writeSortedIndexFile(t *testing.T, m ReadOnlyTxMap) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "index.txsi")
+
+	f, err := os.Create(path) //nolint:gosec // test file
+	require.NoError(t, err)
+	require.NoError(t, WriteSortedIndex(f, m))
+	require.NoError(t, f.Close())
+
+	return path
+}
+
+// TestSortedIndex tests lookups and iteration on sorted indexes of random
+// hashes and of densely clustered hashes.
+func TestSortedIndex(t *testing.T) {
+	random := NewNativeMapUint64(5000)
+	for i := 0; i < 5000; i++ {
+		require.NoError(t, random.Put(sha256.Sum256([]byte{byte(i), byte(i >> 8)}), uint64(i)))
+	}
+
+	clustered := NewNativeMapUint64(5000)
+	for i := 0; i < 5000; i++ {
+		require.NoError(t, clustered.Put(hashN(i), uint64(i)))
+	}
+
+	for name, m := range map[string]TxMap{"random": random, "clustered": clustered, "empty": NewNativeMapUint64(0)} {
+		t.Run(name, func(t *testing.T) {
+			idx, err := OpenSortedIndex(writeSortedIndexFile(t, m))
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, idx.Close())
+			}()
+
+			requireSameContents(t, m, idx)
+
+			m.Iter(func(hash chainhash.Hash, value uint64) bool {
+				v, ok := idx.Get(hash)
+				require.True(t, ok)
+				require.Equal(t, value, v)
+
+				return false
+			})
+
+			for _, miss := range []chainhash.Hash{{0, 0, 1}, {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1}, sha256.Sum256([]byte("missing"))} {
+				assert.False(t, idx.Exists(miss))
+			}
+
+			keys := idx.Keys()
+			assert.True(t, slices.IsSortedFunc(keys, func(a, b chainhash.Hash) int {
+				return slices.Compare(a[:], b[:])
+			}))
+			require.NoError(t, idx.Err())
+		})
+	}
+}
+
+// TestOpenSortedIndexInvalid tests that snapshots and truncated files are rejected.
+func TestOpenSortedIndexInvalid(t *testing.T) {
+	path := writeSortedIndexFile(t, populatedMap(t, 10))
+
+	data, err := os.ReadFile(path) //nolint:gosec // test file
+	require.NoError(t, err)
+
+	truncated := filepath.Join(t.TempDir(), "truncated.txsi")
+	require.NoError(t, os.WriteFile(truncated, data[:len(data)-1], 0o600))
+
+	_, err = OpenSortedIndex(truncated)
+	require.ErrorIs(t, err, ErrInvalidSortedIndex)
+
+	snapshot := filepath.Join(t.TempDir(), "snapshot.txmp")
+	f, err := os.Create(snapshot) //nolint:gosec // test file
+	require.NoError(t, err)
+	require.NoError(t, Export(f, populatedMap(t, 10)))
+	require.NoError(t, f.Close())
+
+	_, err = OpenSortedIndex(snapshot)
+	require.ErrorIs(t, err, ErrInvalidSortedIndex)
+}
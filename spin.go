@@ -0,0 +1,97 @@
+package txmap
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+const (
+	// spinWriter is set in spinRWLock.state while a writer holds the lock.
+	spinWriter = int32(1) << 30
+
+	// spinWriterWaiting is set in spinRWLock.state while a writer waits for
+	// the lock; it keeps new readers out so that writers cannot starve.
+	spinWriterWaiting = int32(1) << 29
+
+	// spinReaders masks the reader count of spinRWLock.state.
+	spinReaders = spinWriterWaiting - 1
+
+	// spinActiveIterations is the number of times a spinRWLock polls the lock
+	// before it starts yielding the processor between polls.
+	spinActiveIterations = 64
+)
+
+// spinRWLock is a writer-preferring read-write spin lock for critical
+// sections of tens of nanoseconds. A waiting goroutine polls the lock word
+// spinActiveIterations times and then calls runtime.Gosched between polls,
+// so it never parks in the runtime, but also never burns a processor for
+// long when the holder was descheduled. The zero value is unlocked.
+type spinRWLock struct {
+	state atomic.Int32
+}
+
+// Lock locks for writing.
+func (l *spinRWLock) Lock() {
+	for i := 0; ; i++ {
+		s := l.state.Load()
+
+		switch {
+		case s&(spinWriter|spinReaders) == 0:
+			// acquiring clears the waiting flag; other waiting writers set it again
+			if l.state.CompareAndSwap(s, spinWriter) {
+				return
+			}
+		case s&spinWriterWaiting == 0:
+			l.state.CompareAndSwap(s, s|spinWriterWaiting)
+		}
+
+		spinWait(i)
+	}
+}
+
+// TryLock tries to lock for writing and reports whether it succeeded.
+func (l *spinRWLock) TryLock() bool {
+	s := l.state.Load()
+
+	return s&(spinWriter|spinReaders) == 0 && l.state.CompareAndSwap(s, spinWriter)
+}
+
+// Unlock unlocks for writing.
+func (l *spinRWLock) Unlock() {
+	l.state.Add(-spinWriter)
+}
+
+// RLock locks for reading.
+func (l *spinRWLock) RLock() {
+	for i := 0; !l.TryRLock(); i++ {
+		spinWait(i)
+	}
+}
+
+// TryRLock tries to lock for reading and reports whether it succeeded. It
+// fails while a writer holds or waits for the lock.
+func (l *spinRWLock) TryRLock() bool {
+	for {
+		s := l.state.Load()
+		if s&(spinWriter|spinWriterWaiting) != 0 {
+			return false
+		}
+
+		if l.state.CompareAndSwap(s, s+1) {
+			return true
+		}
+	}
+}
+
+// RUnlock unlocks for reading.
+func (l *spinRWLock) RUnlock() {
+	l.state.Add(-1)
+}
+
+// spinWait waits before poll i+1 of a lock: not at all for the first
+// spinActiveIterations polls, then by yielding the processor.
+func spinWait(i int) {
+	if i >= spinActiveIterations {
+		runtime.Gosched()
+	}
+}
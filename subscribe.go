@@ -0,0 +1,266 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// DefaultMaxPending is the SubscribeOptions.MaxPending used when it is zero
+// or negative.
+const DefaultMaxPending = 1 << 16
+
+// ErrSubscriberTooSlow ends a Subscription whose receiver fell more than
+// MaxPending records behind the writers.
+var ErrSubscriberTooSlow = errors.New("subscriber too slow")
+
+// SubscribeOptions configure a Subscription.
+type SubscribeOptions struct {
+	// Replay streams the current contents of the map as MutationPut records
+	// with Replayed set before the live records, so that a subscriber joining
+	// a populated map can build a mirror without a separate bootstrap.
+	Replay bool
+
+	// ReplayRate is the maximum number of replayed records per second; zero
+	// or less replays as fast as the receiver takes them.
+	ReplayRate int
+
+	// MaxPending is the number of live records that may queue up for the
+	// receiver, DefaultMaxPending when zero or negative. Writers never wait
+	// for a subscriber; one falling further behind is ended with
+	// ErrSubscriberTooSlow.
+	MaxPending int
+}
+
+// Subscription delivers the records of a RecordingTxMap on C, in sequence
+// order, until its context ends, Close is called or it falls too far behind.
+// C is closed then, and Err tells why.
+//
+// With Replay, the map is replayed one bucket at a time, each bucket copied
+// while writes are held off; every replayed record carries the Seq of the
+// last write before its bucket was copied. A live record whose Seq is not
+// above that of the replayed record of the same hash is therefore already
+// reflected in it; mirrors can skip such records or apply all records
+// idempotently, treating puts of existing hashes as sets and deletes of
+// missing ones as no-ops.
+type Subscription struct {
+	// C receives the records.
+	C <-chan MutationRecord
+
+	r          *RecordingTxMap
+	ch         chan MutationRecord
+	maxPending int
+
+	mu      sync.Mutex
+	pending []MutationRecord
+	err     error
+
+	wake      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Subscribe returns a Subscription to the records of every write made after
+// it returns, preceded by a replay of the current contents if opts.Replay is
+// set. Records are also passed to the recorder of the map as before.
+//
+// Params:
+//   - ctx: Ends the subscription when done.
+//   - opts: Replay, its rate and the queue limit.
+//
+// Returns:
+//   - *Subscription: The subscription; Close releases it.
+func (r *RecordingTxMap) Subscribe(ctx context.Context, opts SubscribeOptions) *Subscription {
+	r.mu.Lock()
+	s := r.subscribeLocked(opts)
+	r.mu.Unlock()
+
+	go s.run(ctx, opts)
+
+	return s
+}
+
+// subscribeLocked registers a new Subscription, which receives every record
+// after the current one once its run loop is started. The caller must hold
+// the lock.
+func (r *RecordingTxMap) subscribeLocked(opts SubscribeOptions) *Subscription {
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = DefaultMaxPending
+	}
+
+	s := &Subscription{
+		r:          r,
+		ch:         make(chan MutationRecord),
+		maxPending: opts.MaxPending,
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	s.C = s.ch
+
+	if r.subs == nil {
+		r.subs = make(map[*Subscription]struct{})
+	}
+
+	r.subs[s] = struct{}{}
+
+	return s
+}
+
+// Err returns the reason the subscription ended: the context error,
+// ErrSubscriberTooSlow, or nil while it runs and after Close.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// run replays the map if requested and then delivers the queued records
+// until the subscription ends.
+func (s *Subscription) run(ctx context.Context, opts SubscribeOptions) {
+	defer close(s.ch)
+	defer s.r.unsubscribe(s)
+
+	if opts.Replay && !s.replay(ctx, opts.ReplayRate) {
+		return
+	}
+
+	for {
+		s.mu.Lock()
+		batch, err := s.pending, s.err
+		s.pending = nil
+		s.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+
+		for _, rec := range batch {
+			if !s.send(ctx, rec) {
+				return
+			}
+		}
+
+		if len(batch) > 0 {
+			continue
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			s.fail(ctx.Err())
+			return
+		}
+	}
+}
+
+// replay sends the contents of the map one bucket at a time, at no more than
+// rate records per second, and reports whether the subscription goes on.
+func (s *Subscription) replay(ctx context.Context, rate int) bool {
+	slice := warmUpCheckEvery
+	if rate > 0 {
+		slice = max(1, rate/warmUpSlicesPerSecond)
+	}
+
+	start, n := time.Now(), 0
+
+	for _, bucket := range txMapBuckets(s.r.m) {
+		var kvs []KV
+
+		s.r.mu.Lock()
+		seq := s.r.seq
+
+		bucket.Iter(func(hash chainhash.Hash, value uint64) bool {
+			kvs = append(kvs, KV{Hash: hash, Value: value})
+			return false
+		})
+		s.r.mu.Unlock()
+
+		for _, kv := range kvs {
+			rec := MutationRecord{
+				Seq:      seq,
+				Time:     time.Now(),
+				Op:       MutationPut,
+				Hashes:   []chainhash.Hash{kv.Hash},
+				Value:    kv.Value,
+				Applied:  true,
+				Replayed: true,
+			}
+
+			if !s.send(ctx, rec) {
+				return false
+			}
+
+			if n++; n%slice == 0 {
+				if err := warmUpPause(ctx, start, n, rate); err != nil {
+					s.fail(err)
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// send delivers rec and reports whether the subscription goes on.
+func (s *Subscription) send(ctx context.Context, rec MutationRecord) bool {
+	select {
+	case s.ch <- rec:
+		return true
+	case <-s.done:
+		return false
+	case <-ctx.Done():
+		s.fail(ctx.Err())
+		return false
+	}
+}
+
+// push queues a live record. It is called with the lock of the map held and
+// never blocks.
+func (s *Subscription) push(rec MutationRecord) {
+	s.mu.Lock()
+
+	switch {
+	case s.err != nil:
+	case len(s.pending) >= s.maxPending:
+		s.err, s.pending = ErrSubscriberTooSlow, nil
+	default:
+		s.pending = append(s.pending, rec)
+	}
+
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// fail records the first reason the subscription ended.
+func (s *Subscription) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// unsubscribe stops passing records to s.
+func (r *RecordingTxMap) unsubscribe(s *Subscription) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.subs, s)
+}
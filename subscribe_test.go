@@ -0,0 +1,103 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubscribeReplay tests that a late subscriber applying the replay and
+// the live records builds a mirror of the map while it is being written.
+func TestSubscribeReplay(t *testing.T) {
+	r := NewRecordingTxMap(NewSplitSwissMapUint64(0, 8), NewMutationRing(1))
+	for i := range 500 {
+		require.NoError(t, r.Put(hashN(i), uint64(i)))
+	}
+
+	sub := r.Subscribe(context.Background(), SubscribeOptions{Replay: true, ReplayRate: 100_000})
+	defer sub.Close()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 500; i < 600; i++ {
+			assert.NoError(t, r.Put(hashN(i), uint64(i)))
+			assert.NoError(t, r.Delete(hashN(i-500)))
+		}
+	}()
+
+	mirror := NewNativeMapUint64(0)
+	replayed := 0
+
+	// a last delete marks the end of the writes
+	<-done
+	require.NoError(t, r.Put(hashN(1000), 0))
+	require.NoError(t, r.Delete(hashN(1000)))
+
+	for rec := range sub.C {
+
+		switch hash := rec.Hashes[0]; {
+		case rec.Replayed:
+			replayed++
+
+			_, _ = mirror.SetIfNotExists(hash, rec.Value)
+		case rec.Op == MutationPut:
+			_, _ = mirror.SetIfNotExists(hash, rec.Value)
+		case rec.Op == MutationDelete:
+			_ = mirror.Delete(hash)
+		}
+
+		if rec.Op == MutationDelete && rec.Hashes[0] == hashN(1000) {
+			break
+		}
+	}
+
+	assert.GreaterOrEqual(t, replayed, 500)
+	requireSameContents(t, r, mirror)
+}
+
+// TestSubscribeEnds tests the ends of a subscription: a receiver falling
+// behind, the context and Close.
+func TestSubscribeEnds(t *testing.T) {
+	r := NewRecordingTxMap(NewSwissMapUint64(0), NewMutationRing(1))
+
+	slow := r.Subscribe(context.Background(), SubscribeOptions{MaxPending: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := r.Subscribe(ctx, SubscribeOptions{})
+
+	closed := r.Subscribe(context.Background(), SubscribeOptions{})
+	closed.Close()
+
+	for i := range 10 {
+		require.NoError(t, r.Put(hashN(i), 1))
+	}
+
+	for range slow.C {
+	}
+
+	require.ErrorIs(t, slow.Err(), ErrSubscriberTooSlow)
+
+	cancel()
+
+	for range cancelled.C {
+	}
+
+	require.ErrorIs(t, cancelled.Err(), context.Canceled)
+
+	for range closed.C {
+	}
+
+	require.NoError(t, closed.Err())
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		return len(r.subs) == 0
+	}, time.Second, time.Millisecond)
+}
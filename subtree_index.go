@@ -0,0 +1,185 @@
+package txmap
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Subtree indexing
+//
+// A subtree assigns every transaction added to it the next position, and a
+// lookup by hash must find that position later. SubtreeIndex keeps the
+// positions in a SplitSwissMapUint64 and assigns them itself on PutNext, so
+// the position and the insert are one atomic step and a hash that is already
+// indexed, or a failed insert, consumes no position: the indices are always
+// gap-free.
+//
+// There are two modes. SubtreeIndexGlobal numbers all hashes 0, 1, 2, ... in
+// the order of their PutNext, which serializes the inserts behind one lock.
+// SubtreeIndexPerBucket numbers the hashes of every bucket on their own, so
+// inserts into different buckets do not wait for each other; the position of
+// a hash is then its bucket and the index within it, and Hashes orders them
+// bucket by bucket.
+
+// SubtreeIndexMode selects how a SubtreeIndex numbers its hashes.
+type SubtreeIndexMode uint8
+
+const (
+	// SubtreeIndexGlobal numbers all hashes in one sequence, in the order of
+	// their PutNext.
+	SubtreeIndexGlobal SubtreeIndexMode = iota
+
+	// SubtreeIndexPerBucket numbers the hashes of every bucket in a sequence
+	// of its own.
+	SubtreeIndexPerBucket
+)
+
+// String returns the lower-case name of the mode.
+func (m SubtreeIndexMode) String() string {
+	switch m {
+	case SubtreeIndexGlobal:
+		return "global"
+	case SubtreeIndexPerBucket:
+		return "per-bucket"
+	default:
+		return "unknown"
+	}
+}
+
+// SubtreeIndex is a SplitSwissMapUint64 from hashes to gap-free indices it
+// assigns on insert. See the notes at the top of subtree_index.go. All writes
+// must go through the SubtreeIndex; it offers no Delete, which would leave a
+// gap.
+type SubtreeIndex struct {
+	m    *SplitSwissMapUint64
+	mode SubtreeIndexMode
+
+	// mu serializes PutNext in global mode, where next[0] is the next index.
+	// In per-bucket mode next[i] is the next index of bucket i, guarded by
+	// the lock of that bucket.
+	mu   sync.Mutex
+	next []uint64
+}
+
+// NewSubtreeIndex creates an empty SubtreeIndex.
+//
+// Params:
+//   - length: The expected number of hashes, used for preallocation.
+//   - mode: How the hashes are numbered.
+//   - buckets: Optionally the number of buckets, as for NewSplitSwissMapUint64.
+//
+// Returns:
+//   - *SubtreeIndex: The new index.
+func NewSubtreeIndex(length uint32, mode SubtreeIndexMode, buckets ...uint16) *SubtreeIndex {
+	m := NewSplitSwissMapUint64(length, buckets...)
+
+	return &SubtreeIndex{
+		m:    m,
+		mode: mode,
+		next: make([]uint64, int(m.nrOfBuckets)+1),
+	}
+}
+
+// PutNext adds hash with the next index.
+//
+// Returns:
+//   - uint64: The index of hash; for a hash that is already indexed, the
+//     index it has.
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     hash is already indexed.
+func (s *SubtreeIndex) PutNext(hash chainhash.Hash) (uint64, error) {
+	next := &s.next[0]
+
+	if s.mode == SubtreeIndexGlobal {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	} else {
+		next = &s.next[s.Bucket(hash)]
+	}
+
+	var loaded bool
+
+	index, err := s.m.Upsert(hash, func(old uint64, exists bool) uint64 {
+		if exists {
+			loaded = true
+			return old
+		}
+
+		*next++
+
+		return *next - 1
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if loaded {
+		return index, fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+	}
+
+	return index, nil
+}
+
+// Index returns the index of hash, and false if it is not indexed.
+func (s *SubtreeIndex) Index(hash chainhash.Hash) (uint64, bool) {
+	return s.m.Get(hash)
+}
+
+// Bucket returns the bucket of hash, which in per-bucket mode is the sequence
+// its index belongs to.
+func (s *SubtreeIndex) Bucket(hash chainhash.Hash) uint16 {
+	return Bytes2Uint16Buckets(hash, s.m.nrOfBuckets)
+}
+
+// Mode returns how the index numbers its hashes.
+func (s *SubtreeIndex) Mode() SubtreeIndexMode { return s.mode }
+
+// Length returns the number of indexed hashes.
+func (s *SubtreeIndex) Length() int { return s.m.Length() }
+
+// Map returns a read-only view of the map from hashes to indices. Like the
+// view returned by FreezeReadOnly, it cannot be type-asserted back to the
+// writable map, so indices can only be assigned through PutNext.
+func (s *SubtreeIndex) Map() ReadOnlyTxMap { return readOnlyTxMap{m: s.m} }
+
+// Hashes returns the indexed hashes in the order of their indices: by index
+// in global mode, and bucket by bucket, each by index, in per-bucket mode. It
+// relies on the indices being gap-free, so it must not run concurrently with
+// PutNext; Freeze the index first if writers may still be running.
+func (s *SubtreeIndex) Hashes() []chainhash.Hash {
+	// offsets[i] is the position of the first hash of bucket i
+	offsets := make([]int, len(s.next))
+
+	if s.mode == SubtreeIndexPerBucket {
+		total := 0
+
+		for i := range offsets {
+			offsets[i] = total
+			total += s.m.m[uint16(i)].Length() //nolint:gosec // i is a bucket number
+		}
+	}
+
+	hashes := make([]chainhash.Hash, s.m.Length())
+
+	for i := range offsets {
+		s.m.m[uint16(i)].Iter(func(hash chainhash.Hash, index uint64) bool { //nolint:gosec // i is a bucket number
+			hashes[offsets[i]+int(index)] = hash //nolint:gosec // indices are below the length
+			return false
+		})
+	}
+
+	return hashes
+}
+
+// Freeze marks the index read-only. See the lifecycle notes at the top of
+// freeze.go.
+func (s *SubtreeIndex) Freeze() { s.m.Freeze() }
+
+// Clear empties the index, restarts the numbering at 0 and un-freezes it. It
+// must not run concurrently with any other operation on the index.
+func (s *SubtreeIndex) Clear() {
+	s.m.Clear()
+	clear(s.next)
+}
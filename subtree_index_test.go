@@ -0,0 +1,129 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSubtreeIndexGlobal tests that global mode numbers hashes in insert
+// order without gaps, also across duplicates.
+func TestSubtreeIndexGlobal(t *testing.T) {
+	s := NewSubtreeIndex(0, SubtreeIndexGlobal)
+	assert.Equal(t, "global", s.Mode().String())
+
+	for i := range 10 {
+		index, err := s.PutNext(hashN(i))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), index) //nolint:gosec // G115 test values
+	}
+
+	index, err := s.PutNext(hashN(3))
+	require.ErrorIs(t, err, ErrHashAlreadyExists)
+	assert.Equal(t, uint64(3), index)
+
+	index, err = s.PutNext(hashN(10))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), index, "a duplicate consumes no index")
+
+	got, ok := s.Index(hashN(7))
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), got)
+	assert.Equal(t, 11, s.Length())
+
+	for i, hash := range s.Hashes() {
+		assert.Equal(t, hashN(i), hash)
+	}
+
+	s.Freeze()
+	_, err = s.PutNext(hashN(11))
+	require.ErrorIs(t, err, ErrMapFrozen)
+
+	s.Clear()
+	index, err = s.PutNext(hashN(11))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), index)
+}
+
+// TestSubtreeIndexMapReadOnly tests that the map returned by Map reads the
+// live index but cannot be type-asserted back to a writable map.
+func TestSubtreeIndexMapReadOnly(t *testing.T) {
+	s := NewSubtreeIndex(0, SubtreeIndexGlobal)
+	ro := s.Map()
+
+	_, ok := ro.(TxMap)
+	require.False(t, ok, "read-only view must not expose write methods")
+
+	_, ok = ro.(*SplitSwissMapUint64)
+	require.False(t, ok, "read-only view must not expose the index map")
+
+	_, err := s.PutNext(hashN(1))
+	require.NoError(t, err)
+
+	index, ok := ro.Get(hashN(1))
+	require.True(t, ok)
+	assert.Equal(t, uint64(0), index)
+	assert.Equal(t, 1, ro.Length())
+}
+
+// TestSubtreeIndexPerBucket tests that per-bucket mode numbers the hashes of
+// every bucket without gaps under concurrent inserts.
+func TestSubtreeIndexPerBucket(t *testing.T) {
+	const (
+		workers = 8
+		perWork = 1000
+	)
+
+	s := NewSubtreeIndex(0, SubtreeIndexPerBucket, 16)
+
+	var wg sync.WaitGroup
+
+	for w := range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range perWork {
+				_, err := s.PutNext(hashN(w*perWork + i))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	require.Equal(t, workers*perWork, s.Length())
+
+	indices := make(map[uint16][]bool)
+
+	s.Map().Iter(func(hash chainhash.Hash, _ uint64) bool {
+		indices[s.Bucket(hash)] = append(indices[s.Bucket(hash)], false)
+		return false
+	})
+
+	s.Map().Iter(func(hash chainhash.Hash, index uint64) bool {
+		seen := indices[s.Bucket(hash)]
+		require.Less(t, index, uint64(len(seen)), "indices are gap-free")
+		require.False(t, seen[index], "indices are unique")
+		seen[index] = true
+
+		return false
+	})
+
+	hashes := s.Hashes()
+	require.Len(t, hashes, workers*perWork)
+
+	for i := 1; i < len(hashes); i++ {
+		prev, cur := s.Bucket(hashes[i-1]), s.Bucket(hashes[i])
+		require.LessOrEqual(t, prev, cur, "hashes are ordered by bucket")
+
+		if prev == cur {
+			a, _ := s.Index(hashes[i-1])
+			b, _ := s.Index(hashes[i])
+			require.Equal(t, a+1, b, "and by index within a bucket")
+		}
+	}
+}
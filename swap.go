@@ -0,0 +1,349 @@
+package txmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+const (
+	// swapStripes is the number of hash lock stripes used while migrating.
+	swapStripes = 256
+
+	// swapCheckEvery is the number of hashes copied between context checks.
+	swapCheckEvery = 4096
+)
+
+// errSwapMigrating tells PutMulti that a migration is running, so that it
+// falls back to mirrored writes.
+var errSwapMigrating = errors.New("backend migration in progress")
+
+// check that SwappableTxMap implements TxMap
+var _ TxMap = (*SwappableTxMap)(nil)
+
+// SwappableTxMap forwards every operation to a backend that can be replaced
+// at runtime by SwapBackend, e.g. to move a live map to the backend that won
+// a benchmark without downtime.
+//
+// Reads always go to the current backend through a single atomic load. Writes
+// take a shared lock that SwapBackend only holds exclusively for the instants
+// it starts and finishes a migration. While a migration runs, every write is
+// applied to the current backend and then mirrored to the new one.
+type SwappableTxMap struct {
+	current atomic.Pointer[swapBackend]
+
+	// writeMu is held shared by writers and exclusively by SwapBackend while
+	// it installs or removes target.
+	writeMu sync.RWMutex
+	target  TxMap
+	stripes [swapStripes]sync.Mutex
+
+	// swapMu serializes SwapBackend calls.
+	swapMu sync.Mutex
+
+	// epoch counts the calls to Clear and the completed swaps, see Epoch.
+	epoch atomic.Uint64
+}
+
+// swapBackend boxes the current backend for atomic.Pointer.
+type swapBackend struct {
+	m TxMap
+}
+
+// NewSwappableTxMap returns a SwappableTxMap serving from m.
+//
+// Params:
+//   - m: The initial backend.
+//
+// Returns:
+//   - *SwappableTxMap: The wrapping map.
+func NewSwappableTxMap(m TxMap) *SwappableTxMap {
+	m = nonNilTxMap(m)
+
+	s := &SwappableTxMap{}
+	s.current.Store(&swapBackend{m: m})
+
+	return s
+}
+
+// Backend returns the current backend.
+func (s *SwappableTxMap) Backend() TxMap {
+	return s.current.Load().m
+}
+
+// SwapBackend copies the contents of the current backend into newMap, bucket
+// by bucket in parallel, while the current backend keeps serving reads and
+// writes, then atomically redirects the map to newMap.
+//
+// Every hash is copied under a lock that also covers the writes to that
+// hash, and writes are mirrored while the copy runs, so no write is lost and
+// no deleted hash is resurrected in newMap.
+//
+// Params:
+//   - ctx: Cancels the migration; the current backend stays in place.
+//   - newMap: The backend to move to; it should be empty.
+//   - workers: The number of buckets copied in parallel; values below one mean one.
+//
+// Returns:
+//   - TxMap: The previous backend, which is no longer written to. Readers that
+//     loaded it before the swap may still be using it.
+//   - error: ErrNilTxMap if newMap is nil, the context error, or the first
+//     error writing to newMap. newMap is left partially filled on error.
+func (s *SwappableTxMap) SwapBackend(ctx context.Context, newMap TxMap, workers int) (TxMap, error) {
+	if isNilTxMap(newMap) {
+		return nil, ErrNilTxMap
+	}
+
+	s.swapMu.Lock()
+	defer s.swapMu.Unlock()
+
+	s.writeMu.Lock()
+	old := s.current.Load().m
+	s.target = newMap
+	s.writeMu.Unlock()
+
+	err := s.copyBuckets(ctx, old, newMap, max(workers, 1))
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.target = nil
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.current.Store(&swapBackend{m: newMap})
+	s.epoch.Add(1)
+
+	return old, nil
+}
+
+// Exists checks if the given hash exists in the current backend.
+func (s *SwappableTxMap) Exists(hash chainhash.Hash) bool {
+	return s.Backend().Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the current backend.
+func (s *SwappableTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return s.Backend().Get(hash)
+}
+
+// Keys returns all hashes in the current backend.
+func (s *SwappableTxMap) Keys() []chainhash.Hash {
+	return s.Backend().Keys()
+}
+
+// Length returns the number of hashes in the current backend.
+func (s *SwappableTxMap) Length() int {
+	return s.Backend().Length()
+}
+
+// Iter iterates over the current backend. Stops iterating if f returns true.
+func (s *SwappableTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	s.Backend().Iter(f)
+}
+
+// Put adds hash to the current backend.
+func (s *SwappableTxMap) Put(hash chainhash.Hash, value uint64) error {
+	return s.write(hash, func(m TxMap) error {
+		return m.Put(hash, value)
+	})
+}
+
+// PutMulti adds hashes to the current backend. While a migration runs the
+// hashes are added one by one, stopping at the first error.
+func (s *SwappableTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	err := s.writeAll(func(m TxMap) error {
+		return m.PutMulti(hashes, value)
+	})
+	if !errors.Is(err, errSwapMigrating) {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if err := s.Put(hash, value); err != nil {
+			return fmt.Errorf("failed to put multi: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value of hash in the current backend.
+func (s *SwappableTxMap) Set(hash chainhash.Hash, value uint64) error {
+	return s.write(hash, func(m TxMap) error {
+		return m.Set(hash, value)
+	})
+}
+
+// SetIfExists updates the value of hash in the current backend if it exists.
+func (s *SwappableTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	var ok bool
+
+	err := s.write(hash, func(m TxMap) (err error) {
+		ok, err = m.SetIfExists(hash, value)
+		return err
+	})
+
+	return ok, err
+}
+
+// SetIfNotExists adds hash to the current backend if it does not exist.
+func (s *SwappableTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	var ok bool
+
+	err := s.write(hash, func(m TxMap) (err error) {
+		ok, err = m.SetIfNotExists(hash, value)
+		return err
+	})
+
+	return ok, err
+}
+
+// Delete removes hash from the current backend.
+func (s *SwappableTxMap) Delete(hash chainhash.Hash) error {
+	return s.write(hash, func(m TxMap) error {
+		return m.Delete(hash)
+	})
+}
+
+// Freeze freezes the current backend. It must not be called while
+// SwapBackend runs.
+func (s *SwappableTxMap) Freeze() {
+	s.Backend().Freeze()
+}
+
+// Clear clears the current backend, and the new one if a migration is running.
+func (s *SwappableTxMap) Clear() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.Backend().Clear()
+
+	if s.target != nil {
+		s.target.Clear()
+	}
+
+	s.epoch.Add(1)
+}
+
+// writeAll applies f to the current backend unless a migration is running,
+// in which case it returns errSwapMigrating without calling f.
+func (s *SwappableTxMap) writeAll(f func(m TxMap) error) error {
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
+
+	if s.target != nil {
+		return errSwapMigrating
+	}
+
+	return f(s.current.Load().m)
+}
+
+// write applies f to the current backend and, while a migration runs,
+// mirrors the resulting state of hash to the new backend.
+func (s *SwappableTxMap) write(hash chainhash.Hash, f func(m TxMap) error) error {
+	s.writeMu.RLock()
+	defer s.writeMu.RUnlock()
+
+	m := s.current.Load().m
+	if s.target == nil {
+		return f(m)
+	}
+
+	stripe := &s.stripes[Bytes2Uint16Buckets(hash, swapStripes)]
+
+	stripe.Lock()
+	defer stripe.Unlock()
+
+	err := f(m)
+
+	if syncErr := syncSwapHash(m, s.target, hash); syncErr != nil && err == nil {
+		err = syncErr
+	}
+
+	return err
+}
+
+// copyBuckets copies every bucket of old into newMap with the given number of
+// workers, each hash under its stripe lock.
+func (s *SwappableTxMap) copyBuckets(ctx context.Context, old, newMap TxMap, workers int) error {
+	buckets := txMapBuckets(old)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+	)
+
+	for w := 0; w < min(workers, len(buckets)); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := int(next.Add(1) - 1); i < len(buckets) && ctx.Err() == nil; i = int(next.Add(1) - 1) {
+				if err := s.copyBucket(ctx, old, newMap, buckets[i].Keys()); err != nil {
+					cancel(err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return context.Cause(ctx)
+}
+
+// copyBucket copies the given hashes of old into newMap.
+func (s *SwappableTxMap) copyBucket(ctx context.Context, old, newMap TxMap, hashes []chainhash.Hash) error {
+	for i, hash := range hashes {
+		if i%swapCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		stripe := &s.stripes[Bytes2Uint16Buckets(hash, swapStripes)]
+
+		stripe.Lock()
+		err := syncSwapHash(old, newMap, hash)
+		stripe.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncSwapHash makes the entry of hash in dst match the one in src, adding,
+// updating or deleting it as needed.
+func syncSwapHash(src, dst TxMap, hash chainhash.Hash) error {
+	value, ok := src.Get(hash)
+	if !ok {
+		if err := dst.Delete(hash); err != nil && !errors.Is(err, ErrHashDoesNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	added, err := dst.SetIfNotExists(hash, value)
+	if err != nil || added {
+		return err
+	}
+
+	_, err = dst.SetIfExists(hash, value)
+
+	return err
+}
@@ -0,0 +1,88 @@
+package txmap
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSwappableTxMap runs the TxMap conformance tests against a SwappableTxMap.
+func TestSwappableTxMap(t *testing.T) {
+	testTxMap(t, NewSwappableTxMap(NewSplitSwissMapUint64(100, 16)))
+}
+
+// TestSwappableTxMapSwapBackend tests a migration racing writers that insert,
+// update and delete, and that the new backend ends up with exactly the
+// contents the old one would have had.
+func TestSwappableTxMapSwapBackend(t *testing.T) {
+	old := NewSplitSwissMapUint64(20000, 64)
+	s := NewSwappableTxMap(old)
+
+	for i := 0; i < 10000; i++ {
+		require.NoError(t, s.Put(hashN(i), uint64(i)))
+	}
+
+	var (
+		wg      sync.WaitGroup
+		stop    atomic.Bool
+		written atomic.Int64
+	)
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// writer w owns the hashes i with i%4 == w
+			for i := w; !stop.Load() && i < 10000; i += 4 {
+				assert.NoError(t, s.Set(hashN(i), 7))
+				assert.NoError(t, s.Delete(hashN(i)))
+				assert.NoError(t, s.PutMulti([]chainhash.Hash{hashN(10000 + i)}, 1))
+				written.Add(1)
+			}
+		}()
+	}
+
+	newMap := NewNativeSplitMapUint64(20000, 64)
+
+	previous, err := s.SwapBackend(context.Background(), newMap, 8)
+	require.NoError(t, err)
+	assert.Same(t, old, previous)
+	assert.Same(t, TxMap(newMap), s.Backend())
+
+	stop.Store(true)
+	wg.Wait()
+
+	n := int(written.Load())
+	assert.Equal(t, 10000, s.Length(), "%d writes raced the migration", n)
+
+	for i := 0; i < 10000; i++ {
+		_, inNew := newMap.Get(hashN(i))
+		_, moved := newMap.Get(hashN(10000 + i))
+		assert.NotEqual(t, inNew, moved, "hash %d", i)
+	}
+}
+
+// TestSwappableTxMapSwapCancelled tests that a cancelled migration keeps the
+// current backend.
+func TestSwappableTxMapSwapCancelled(t *testing.T) {
+	old := NewNativeMapUint64(10)
+	s := NewSwappableTxMap(old)
+	require.NoError(t, s.Put(hashN(1), 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.SwapBackend(ctx, NewNativeMapUint64(10), 1)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Same(t, TxMap(old), s.Backend())
+
+	require.NoError(t, s.Put(hashN(2), 2))
+	assert.Equal(t, 2, s.Length())
+}
@@ -0,0 +1,276 @@
+package txmap
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/dolthub/swiss"
+)
+
+// check that SwissMapBytes implements TxMapOf
+var _ TxMapOf[[]byte] = (*SwissMapBytes)(nil)
+
+// SwissMapBytes is a concurrent-safe map from transaction hashes to small
+// variable-length values, such as serialized merkle paths, so that callers
+// need no second structure next to a TxMap to keep them.
+//
+// Values are copy-on-write: the writes store a private copy of the value they
+// are given, and never modify a stored value in place but replace it, so the
+// slices returned by Get and Iter stay valid and unchanged after the lock is
+// released, without a copy per read. Callers must not modify them in turn.
+//
+// Size reports the total length of the stored values, for callers that bound
+// the memory of the map.
+type SwissMapBytes struct {
+	mu     mapLock
+	m      *swiss.Map[chainhash.Hash, []byte]
+	length atomic.Int64
+	size   atomic.Int64
+	frozen atomic.Bool
+}
+
+// NewSwissMapBytes creates a new SwissMapBytes with the specified initial
+// length.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//
+// Returns:
+//   - *SwissMapBytes: A pointer to the newly created SwissMapBytes instance.
+func NewSwissMapBytes(length uint32) *SwissMapBytes {
+	return &SwissMapBytes{
+		m: swiss.NewMap[chainhash.Hash, []byte](length),
+	}
+}
+
+// Exists checks if the given hash exists in the map.
+func (s *SwissMapBytes) Exists(hash chainhash.Hash) bool {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return s.m.Has(hash)
+}
+
+// Get retrieves the value associated with the given hash. The value is
+// shared with the map and must not be modified.
+//
+// Returns:
+//   - []byte: The value, or nil if the hash does not exist.
+//   - bool: True if the hash was found in the map.
+func (s *SwissMapBytes) Get(hash chainhash.Hash) ([]byte, bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return s.m.Get(hash)
+}
+
+// Put adds a new hash with a copy of value to the map.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     hash already exists.
+func (s *SwissMapBytes) Put(hash chainhash.Hash, value []byte) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	value = bytes.Clone(value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.putUnlocked(hash, value)
+}
+
+// PutMulti adds multiple hashes with the given value under a single lock,
+// sharing one copy of value between them. The first hash that already exists
+// stops the call with an error.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists.
+func (s *SwissMapBytes) PutMulti(hashes []chainhash.Hash, value []byte) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	value = bytes.Clone(value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hash := range hashes {
+		if err := s.putUnlocked(hash, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putUnlocked adds hash with value, which the map owns. The caller must hold
+// the write lock.
+func (s *SwissMapBytes) putUnlocked(hash chainhash.Hash, value []byte) error {
+	if s.m.Has(hash) {
+		return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+	}
+
+	debugAssertHash(hash)
+	s.m.Put(hash, value)
+	debugAssertLength(s.length.Add(1))
+	s.size.Add(int64(len(value)))
+
+	return nil
+}
+
+// Set replaces the value of an existing hash with a copy of value.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist.
+func (s *SwissMapBytes) Set(hash chainhash.Hash, value []byte) error {
+	ok, err := s.SetIfExists(hash, value)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return nil
+}
+
+// SetIfExists replaces the value of hash with a copy of value if it exists.
+// Slices returned for the old value are not modified.
+//
+// Returns:
+//   - bool: True if the hash was found and updated.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *SwissMapBytes) SetIfExists(hash chainhash.Hash, value []byte) (bool, error) {
+	if s.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	value = bytes.Clone(value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.m.Get(hash)
+	if !ok {
+		return false, nil
+	}
+
+	s.m.Put(hash, value)
+	s.size.Add(int64(len(value) - len(old)))
+
+	return true, nil
+}
+
+// SetIfNotExists adds hash with a copy of value if it does not exist yet.
+//
+// Returns:
+//   - bool: True if the hash was added, false if it already existed.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *SwissMapBytes) SetIfNotExists(hash chainhash.Hash, value []byte) (bool, error) {
+	if s.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.m.Has(hash) {
+		return false, nil
+	}
+
+	// the hash is new, so the copy is never wasted
+	return true, s.putUnlocked(hash, bytes.Clone(value))
+}
+
+// Delete removes a hash and its value from the map.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist.
+func (s *SwissMapBytes) Delete(hash chainhash.Hash) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.m.Get(hash)
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	s.m.Delete(hash)
+	debugAssertLength(s.length.Add(-1))
+	s.size.Add(-int64(len(old)))
+
+	return nil
+}
+
+// Length returns the current number of hashes in the map.
+func (s *SwissMapBytes) Length() int {
+	return int(s.length.Load())
+}
+
+// Size returns the total length in bytes of the stored values. Values shared
+// by PutMulti count once per hash; the hashes and the map's own overhead are
+// not included.
+func (s *SwissMapBytes) Size() int64 {
+	return s.size.Load()
+}
+
+// Keys returns a slice of all hashes in the map, in no particular order.
+func (s *SwissMapBytes) Keys() []chainhash.Hash {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	keys := make([]chainhash.Hash, 0, s.length.Load())
+
+	s.m.Iter(func(k chainhash.Hash, _ []byte) (stop bool) {
+		keys = append(keys, k)
+		return false
+	})
+
+	return keys
+}
+
+// Iter calls f for every hash and value under the read lock. The values are
+// shared with the map and must not be modified. Stops iterating if f returns
+// true.
+func (s *SwissMapBytes) Iter(f func(hash chainhash.Hash, value []byte) bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	s.m.Iter(f)
+}
+
+// Freeze marks the map read-only. See the lifecycle notes at the top of
+// freeze.go.
+func (s *SwissMapBytes) Freeze() { s.frozen.Store(true) }
+
+// Clear empties the map without releasing its backing storage and un-freezes
+// it. Like SwissMapUint64.Clear, it must not run concurrently with any other
+// operation on the map.
+func (s *SwissMapBytes) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.m.Clear()
+	s.length.Store(0)
+	s.size.Store(0)
+	s.frozen.Store(false)
+}
@@ -0,0 +1,108 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSwissMapBytes tests the TxMapOf methods of SwissMapBytes and its size
+// accounting.
+func TestSwissMapBytes(t *testing.T) {
+	m := NewSwissMapBytes(0)
+
+	require.NoError(t, m.Put(hashN(1), []byte("path-1")))
+	require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(2), hashN(3)}, []byte("ab")))
+	require.ErrorIs(t, m.Put(hashN(1), nil), ErrHashAlreadyExists)
+	assert.Equal(t, 3, m.Length())
+	assert.Equal(t, int64(10), m.Size())
+
+	require.NoError(t, m.Set(hashN(1), []byte("p")))
+	assert.Equal(t, int64(5), m.Size())
+	require.ErrorIs(t, m.Set(hashN(9), nil), ErrHashDoesNotExist)
+
+	added, err := m.SetIfNotExists(hashN(4), []byte("xyz"))
+	require.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = m.SetIfNotExists(hashN(4), []byte("other"))
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, int64(8), m.Size())
+
+	require.NoError(t, m.Delete(hashN(2)))
+	require.ErrorIs(t, m.Delete(hashN(2)), ErrHashDoesNotExist)
+	assert.Equal(t, int64(6), m.Size())
+	assert.ElementsMatch(t, []chainhash.Hash{hashN(1), hashN(3), hashN(4)}, m.Keys())
+
+	m.Freeze()
+	require.ErrorIs(t, m.Put(hashN(5), nil), ErrMapFrozen)
+
+	value, ok := m.Get(hashN(4))
+	require.True(t, ok)
+	assert.Equal(t, []byte("xyz"), value)
+
+	m.Clear()
+	assert.Equal(t, 0, m.Length())
+	assert.Equal(t, int64(0), m.Size())
+	require.NoError(t, m.Put(hashN(5), nil))
+}
+
+// TestSwissMapBytesCopyOnWrite tests that the map neither keeps the caller's
+// slice nor modifies a value it handed out.
+func TestSwissMapBytesCopyOnWrite(t *testing.T) {
+	m := NewSwissMapBytes(0)
+
+	input := []byte("original")
+	require.NoError(t, m.Put(hashN(1), input))
+
+	input[0] = 'X'
+
+	before, _ := m.Get(hashN(1))
+	assert.Equal(t, []byte("original"), before)
+
+	require.NoError(t, m.Set(hashN(1), []byte("replaced")))
+	assert.Equal(t, []byte("original"), before, "a value handed out is never modified")
+
+	after, _ := m.Get(hashN(1))
+	assert.Equal(t, []byte("replaced"), after)
+}
+
+// TestSwissMapBytesConcurrent tests readers holding values while writers
+// replace them.
+func TestSwissMapBytesConcurrent(t *testing.T) {
+	m := NewSwissMapBytes(0)
+	require.NoError(t, m.Put(hashN(1), []byte{0, 0, 0, 0}))
+
+	var wg sync.WaitGroup
+
+	for w := range 4 {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				b := byte(w*1000 + i) //nolint:gosec // G115 test values
+				assert.NoError(t, m.Set(hashN(1), []byte{b, b, b, b}))
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for range 1000 {
+				value, ok := m.Get(hashN(1))
+				if assert.True(t, ok) {
+					assert.Equal(t, []byte{value[0], value[0], value[0], value[0]}, value)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int64(4), m.Size())
+}
@@ -10,10 +10,16 @@ import (
 // SyncedMap is a thread-safe generic map with read-write mutex synchronization.
 // It supports concurrent access and provides an optional item limit for constrained storage.
 type SyncedMap[K comparable, V any] struct {
-	mu     sync.RWMutex
-	m      map[K]V
-	limit  int
-	frozen atomic.Bool
+	mu      sync.RWMutex
+	m       map[K]V
+	limit   int
+	frozen  atomic.Bool
+	onEvict EvictCallback[K, V]
+
+	// evictWeight and evictSamples select the victims at the item limit; see
+	// SetEvictionWeight.
+	evictWeight  func(value V) float64
+	evictSamples int
 }
 
 // NewSyncedMap creates and returns a new SyncedMap with an optional item limit.
@@ -24,7 +30,8 @@ type SyncedMap[K comparable, V any] struct {
 // Returns:
 //   - *SyncedMap[K, V]: A pointer to a new, empty SyncedMap instance.
 //
-// If a limit is set and the map reaches its capacity, a random item will be deleted to make room for new entries.
+// If a limit is set and the map reaches its capacity, a random item will be deleted to make room for new entries;
+// SetEvictionWeight biases that choice.
 func NewSyncedMap[K comparable, V any](l ...int) *SyncedMap[K, V] {
 	limit := 0
 	if len(l) > 0 {
@@ -45,6 +52,22 @@ func NewSyncedMap[K comparable, V any](l ...int) *SyncedMap[K, V] {
 // See the lifecycle notes at the top of freeze.go.
 func (m *SyncedMap[K, V]) Freeze() { m.frozen.Store(true) }
 
+// OnEvict registers a callback that is invoked for every entry the SyncedMap
+// removes on its own: entries dropped to respect the item limit are reported
+// with EvictReasonCapacity, and entries removed by Clear with
+// EvictReasonCleared. Explicit Delete calls are not reported.
+// Passing nil removes a previously registered callback.
+//
+// Parameters:
+//   - cb: The callback to invoke; it runs under the map's write lock and must
+//     not call back into the map.
+func (m *SyncedMap[K, V]) OnEvict(cb EvictCallback[K, V]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onEvict = cb
+}
+
 // Length returns the number of key-value pairs currently stored in the SyncedMap.
 //
 // Returns:
@@ -197,10 +220,8 @@ func (m *SyncedMap[K, V]) SetIfNotExists(key K, value V) (V, bool) {
 
 func (m *SyncedMap[K, V]) setUnlocked(key K, value V) {
 	if m.limit > 0 && len(m.m) >= m.limit {
-		for k := range m.m {
-			// delete a random item
-			delete(m.m, k)
-			break
+		if _, exists := m.m[key]; !exists {
+			m.evictUnlocked()
 		}
 	}
 
@@ -305,6 +326,12 @@ func (m *SyncedMap[K, V]) Clear() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.onEvict != nil {
+		for k, v := range m.m {
+			m.onEvict(k, v, EvictReasonCleared)
+		}
+	}
+
 	m.m = make(map[K]V)
 	m.frozen.Store(false)
 
@@ -430,6 +457,7 @@ type SyncedSwissMap[K comparable, V any] struct {
 	mu       sync.RWMutex
 	swissMap *swiss.Map[K, V]
 	frozen   atomic.Bool
+	onEvict  EvictCallback[K, V]
 }
 
 // NewSyncedSwissMap creates and returns a new SyncedSwissMap with the specified initial capacity.
@@ -453,6 +481,20 @@ func NewSyncedSwissMap[K comparable, V any](length uint32) *SyncedSwissMap[K, V]
 // See the lifecycle notes at the top of freeze.go.
 func (m *SyncedSwissMap[K, V]) Freeze() { m.frozen.Store(true) }
 
+// OnEvict registers a callback that is invoked with EvictReasonCleared for
+// every entry removed by Clear. Explicit Delete and DeleteBatch calls are not
+// reported. Passing nil removes a previously registered callback.
+//
+// Parameters:
+//   - cb: The callback to invoke; it runs under the map's write lock and must
+//     not call back into the map.
+func (m *SyncedSwissMap[K, V]) OnEvict(cb EvictCallback[K, V]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onEvict = cb
+}
+
 // Get returns the value associated with the given key in the SyncedSwissMap.
 //
 // Parameters:
@@ -574,6 +616,13 @@ func (m *SyncedSwissMap[K, V]) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.onEvict != nil {
+		m.swissMap.Iter(func(key K, value V) bool {
+			m.onEvict(key, value, EvictReasonCleared)
+			return false
+		})
+	}
+
 	m.swissMap.Clear()
 	m.frozen.Store(false)
 }
@@ -0,0 +1,315 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that SyncedTxMap implements TxMap
+var _ TxMap = (*SyncedTxMap)(nil)
+
+// SyncedTxMap exposes a SyncedMap[chainhash.Hash, uint64] through the TxMap
+// interface, so that components written against TxMap (decorators,
+// conformance tests, snapshots) can be used with it.
+//
+// Unlike the SyncedMap methods, which panic when the map is frozen, the TxMap
+// methods return ErrMapFrozen. The SyncedMap's item limit and eviction
+// callback still apply to inserts made through the adapter.
+type SyncedTxMap struct {
+	m *SyncedMap[chainhash.Hash, uint64]
+}
+
+// NewSyncedTxMap returns a TxMap view of m. The view and m share their contents.
+//
+// Params:
+//   - m: The SyncedMap to expose.
+//
+// Returns:
+//   - *SyncedTxMap: The adapter.
+func NewSyncedTxMap(m *SyncedMap[chainhash.Hash, uint64]) *SyncedTxMap {
+	return &SyncedTxMap{m: m}
+}
+
+// Exists checks if the given hash exists in the SyncedMap.
+func (s *SyncedTxMap) Exists(hash chainhash.Hash) bool {
+	return s.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the SyncedMap.
+func (s *SyncedTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return s.m.Get(hash)
+}
+
+// Keys returns all hashes in the SyncedMap.
+func (s *SyncedTxMap) Keys() []chainhash.Hash {
+	return s.m.Keys()
+}
+
+// Length returns the number of hashes in the SyncedMap.
+func (s *SyncedTxMap) Length() int {
+	return s.m.Length()
+}
+
+// Iter iterates over the SyncedMap. Stops iterating if f returns true.
+func (s *SyncedTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	s.m.Iterate(func(hash chainhash.Hash, value uint64) bool {
+		return !f(hash, value)
+	})
+}
+
+// Put adds hash to the SyncedMap, failing if it already exists.
+func (s *SyncedTxMap) Put(hash chainhash.Hash, value uint64) error {
+	return s.PutMulti([]chainhash.Hash{hash}, value)
+}
+
+// PutMulti adds hashes to the SyncedMap under one lock, stopping at the first
+// hash that already exists.
+func (s *SyncedTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	if s.m.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, hash := range hashes {
+		if _, ok := s.m.m[hash]; ok {
+			return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+		}
+
+		s.m.setUnlocked(hash, value)
+	}
+
+	return nil
+}
+
+// Set updates the value of an existing hash.
+func (s *SyncedTxMap) Set(hash chainhash.Hash, value uint64) error {
+	ok, err := s.SetIfExists(hash, value)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return nil
+}
+
+// SetIfExists updates the value of hash if it exists.
+func (s *SyncedTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if s.m.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.m[hash]; !ok {
+		return false, nil
+	}
+
+	s.m.m[hash] = value
+
+	return true, nil
+}
+
+// SetIfNotExists adds hash if it does not exist yet.
+func (s *SyncedTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if s.m.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	_, added := s.m.SetIfNotExists(hash, value)
+
+	return added, nil
+}
+
+// Delete removes hash from the SyncedMap, failing if it does not exist.
+func (s *SyncedTxMap) Delete(hash chainhash.Hash) error {
+	if s.m.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.m[hash]; !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	delete(s.m.m, hash)
+
+	return nil
+}
+
+// Freeze freezes the SyncedMap.
+func (s *SyncedTxMap) Freeze() {
+	s.m.Freeze()
+}
+
+// Clear empties and un-freezes the SyncedMap.
+func (s *SyncedTxMap) Clear() {
+	s.m.Clear()
+}
+
+// HashSyncedMap is the method set of SyncedMap[chainhash.Hash, uint64], for
+// components that want to accept either a SyncedMap or a TxMap exposed
+// through NewTxMapSyncedView.
+type HashSyncedMap interface {
+	Length() int
+	Exists(hash chainhash.Hash) bool
+	Get(hash chainhash.Hash) (uint64, bool)
+	Range() map[chainhash.Hash]uint64
+	Keys() []chainhash.Hash
+	Iterate(f func(hash chainhash.Hash, value uint64) bool)
+	Set(hash chainhash.Hash, value uint64)
+	SetIfNotExists(hash chainhash.Hash, value uint64) (uint64, bool)
+	SetMulti(hashes []chainhash.Hash, value uint64)
+	Delete(hash chainhash.Hash) bool
+	Clear() bool
+	Freeze()
+}
+
+// Compile-time checks that both sides satisfy HashSyncedMap.
+var (
+	_ HashSyncedMap = (*SyncedMap[chainhash.Hash, uint64])(nil)
+	_ HashSyncedMap = (*TxMapSyncedView)(nil)
+)
+
+// TxMapSyncedView exposes a TxMap through the SyncedMap method set. Like a
+// SyncedMap, its write methods panic when the map is frozen; other errors of
+// the TxMap cannot occur for the operations it performs.
+type TxMapSyncedView struct {
+	m TxMap
+}
+
+// NewTxMapSyncedView returns a SyncedMap-style view of m. The view and m
+// share their contents.
+//
+// Params:
+//   - m: The TxMap to expose.
+//
+// Returns:
+//   - *TxMapSyncedView: The adapter.
+func NewTxMapSyncedView(m TxMap) *TxMapSyncedView {
+	m = nonNilTxMap(m)
+
+	return &TxMapSyncedView{m: m}
+}
+
+// Length returns the number of hashes in the TxMap.
+func (v *TxMapSyncedView) Length() int {
+	return v.m.Length()
+}
+
+// Exists checks if the given hash exists in the TxMap.
+func (v *TxMapSyncedView) Exists(hash chainhash.Hash) bool {
+	return v.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the TxMap.
+func (v *TxMapSyncedView) Get(hash chainhash.Hash) (uint64, bool) {
+	return v.m.Get(hash)
+}
+
+// Range returns a copy of the TxMap's contents.
+func (v *TxMapSyncedView) Range() map[chainhash.Hash]uint64 {
+	items := make(map[chainhash.Hash]uint64, v.m.Length())
+
+	v.m.Iter(func(hash chainhash.Hash, value uint64) bool {
+		items[hash] = value
+		return false
+	})
+
+	return items
+}
+
+// Keys returns all hashes in the TxMap.
+func (v *TxMapSyncedView) Keys() []chainhash.Hash {
+	return v.m.Keys()
+}
+
+// Iterate calls f for every entry of the TxMap. The iteration stops if f returns false.
+func (v *TxMapSyncedView) Iterate(f func(hash chainhash.Hash, value uint64) bool) {
+	v.m.Iter(func(hash chainhash.Hash, value uint64) bool {
+		return !f(hash, value)
+	})
+}
+
+// Set inserts hash or updates its value.
+func (v *TxMapSyncedView) Set(hash chainhash.Hash, value uint64) {
+	for {
+		updated, err := v.m.SetIfExists(hash, value)
+		mustNotFail(err)
+
+		if updated {
+			return
+		}
+
+		added, err := v.m.SetIfNotExists(hash, value)
+		mustNotFail(err)
+
+		if added {
+			return
+		}
+	}
+}
+
+// SetIfNotExists inserts hash if it does not exist yet.
+//
+// Returns:
+//   - uint64: The value that was set or already existed.
+//   - bool: True if the value was set.
+func (v *TxMapSyncedView) SetIfNotExists(hash chainhash.Hash, value uint64) (uint64, bool) {
+	for {
+		added, err := v.m.SetIfNotExists(hash, value)
+		mustNotFail(err)
+
+		if added {
+			return value, true
+		}
+
+		if existing, ok := v.m.Get(hash); ok {
+			return existing, false
+		}
+	}
+}
+
+// SetMulti inserts or updates every hash with value.
+func (v *TxMapSyncedView) SetMulti(hashes []chainhash.Hash, value uint64) {
+	for _, hash := range hashes {
+		v.Set(hash, value)
+	}
+}
+
+// Delete removes hash from the TxMap. Like SyncedMap.Delete it returns true
+// whether or not the hash existed.
+func (v *TxMapSyncedView) Delete(hash chainhash.Hash) bool {
+	if err := v.m.Delete(hash); !errors.Is(err, ErrHashDoesNotExist) {
+		mustNotFail(err)
+	}
+
+	return true
+}
+
+// Clear empties and un-freezes the TxMap.
+func (v *TxMapSyncedView) Clear() bool {
+	v.m.Clear()
+	return true
+}
+
+// Freeze freezes the TxMap.
+func (v *TxMapSyncedView) Freeze() {
+	v.m.Freeze()
+}
+
+// mustNotFail panics on err, mirroring SyncedMap's panics on frozen writes.
+func mustNotFail(err error) {
+	if err != nil {
+		panic(fmt.Sprintf("txmap: %v", err))
+	}
+}
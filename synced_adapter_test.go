@@ -0,0 +1,62 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSyncedTxMap runs the TxMap conformance test against a SyncedMap adapter
+// and checks that frozen writes return errors instead of panicking.
+func TestSyncedTxMap(t *testing.T) {
+	testTxMap(t, NewSyncedTxMap(NewSyncedMap[chainhash.Hash, uint64]()))
+
+	m := NewSyncedTxMap(NewSyncedMap[chainhash.Hash, uint64]())
+	require.NoError(t, m.Put(hashN(1), 1))
+	require.ErrorIs(t, m.Delete(hashN(2)), ErrHashDoesNotExist)
+	require.ErrorIs(t, m.Set(hashN(2), 1), ErrHashDoesNotExist)
+
+	m.Freeze()
+	require.ErrorIs(t, m.Put(hashN(2), 1), ErrMapFrozen)
+	require.ErrorIs(t, m.Delete(hashN(1)), ErrMapFrozen)
+
+	_, err := m.SetIfNotExists(hashN(2), 1)
+	require.ErrorIs(t, err, ErrMapFrozen)
+}
+
+// TestTxMapSyncedView tests the SyncedMap method set on top of a TxMap.
+func TestTxMapSyncedView(t *testing.T) {
+	var v HashSyncedMap = NewTxMapSyncedView(NewNativeSplitMapUint64(16, 4))
+
+	v.Set(hashN(1), 1)
+	v.Set(hashN(1), 2)
+	v.SetMulti([]chainhash.Hash{hashN(2), hashN(3)}, 3)
+
+	value, added := v.SetIfNotExists(hashN(1), 9)
+	assert.False(t, added)
+	assert.Equal(t, uint64(2), value)
+
+	value, added = v.SetIfNotExists(hashN(4), 9)
+	assert.True(t, added)
+	assert.Equal(t, uint64(9), value)
+
+	assert.True(t, v.Delete(hashN(4)))
+	assert.True(t, v.Delete(hashN(4)))
+
+	assert.Equal(t, map[chainhash.Hash]uint64{hashN(1): 2, hashN(2): 3, hashN(3): 3}, v.Range())
+
+	visited := 0
+
+	v.Iterate(func(chainhash.Hash, uint64) bool {
+		visited++
+		return false
+	})
+	assert.Equal(t, 1, visited)
+
+	v.Freeze()
+	assert.Panics(t, func() { v.Set(hashN(5), 1) })
+	assert.True(t, v.Clear())
+	assert.Equal(t, 0, v.Length())
+}
@@ -0,0 +1,333 @@
+package txmap
+
+import "sync"
+
+// SyncedPriorityQueue is a thread-safe double-ended priority queue: a min-max
+// heap under a mutex, ordered by a caller-supplied less function. It gives both
+// the smallest and the largest item in O(1) and removes either in O(log n),
+// e.g. to pick the next transactions by highest fee rate while evicting the
+// lowest ones when the queue is full.
+//
+// The queue holds items, not map entries: callers that keep it next to a TxMap
+// store the hash in T and push and pop under their own write path.
+type SyncedPriorityQueue[T any] struct {
+	mu    sync.Mutex
+	items []T
+	less  func(a, b T) bool
+}
+
+// NewSyncedPriorityQueue creates and returns a new, empty SyncedPriorityQueue.
+//
+// Parameters:
+//   - less: Reports whether a orders before b. PopMin returns the item that
+//     orders first, PopMax the one that orders last.
+//   - length (optional): The initial capacity of the queue.
+//
+// Returns:
+//   - *SyncedPriorityQueue[T]: A pointer to a new, empty SyncedPriorityQueue instance.
+func NewSyncedPriorityQueue[T any](less func(a, b T) bool, length ...int) *SyncedPriorityQueue[T] {
+	initialLength := 0
+	if len(length) > 0 {
+		initialLength = length[0]
+	}
+
+	return &SyncedPriorityQueue[T]{
+		items: make([]T, 0, initialLength),
+		less:  less,
+	}
+}
+
+// Length returns the number of items in the queue.
+//
+// Returns:
+//   - int: The number of items in the queue.
+func (q *SyncedPriorityQueue[T]) Length() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// Push adds an item to the queue.
+//
+// Parameters:
+//   - item: The item to add.
+func (q *SyncedPriorityQueue[T]) Push(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.push(item)
+}
+
+// PushBatch adds all items to the queue under a single lock acquisition.
+//
+// Parameters:
+//   - items: The items to add.
+func (q *SyncedPriorityQueue[T]) PushBatch(items []T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, item := range items {
+		q.push(item)
+	}
+}
+
+// PeekMin returns the item that orders first without removing it.
+//
+// Returns:
+//   - T: The first item, or the zero value if the queue is empty.
+//   - bool: True if the queue was not empty.
+func (q *SyncedPriorityQueue[T]) PeekMin() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return q.items[0], true
+}
+
+// PeekMax returns the item that orders last without removing it.
+//
+// Returns:
+//   - T: The last item, or the zero value if the queue is empty.
+//   - bool: True if the queue was not empty.
+func (q *SyncedPriorityQueue[T]) PeekMax() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return q.items[q.maxIndex()], true
+}
+
+// PopMin removes and returns the item that orders first.
+//
+// Returns:
+//   - T: The first item, or the zero value if the queue is empty.
+//   - bool: True if an item was removed.
+func (q *SyncedPriorityQueue[T]) PopMin() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return q.removeAt(0), true
+}
+
+// PopMax removes and returns the item that orders last.
+//
+// Returns:
+//   - T: The last item, or the zero value if the queue is empty.
+//   - bool: True if an item was removed.
+func (q *SyncedPriorityQueue[T]) PopMax() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return q.removeAt(q.maxIndex()), true
+}
+
+// PopMinN removes and returns up to n items in ascending order.
+//
+// Parameters:
+//   - n: The maximum number of items to remove.
+//
+// Returns:
+//   - []T: The removed items, first item first.
+func (q *SyncedPriorityQueue[T]) PopMinN(n int) []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]T, 0, min(max(n, 0), len(q.items)))
+
+	for len(out) < cap(out) {
+		out = append(out, q.removeAt(0))
+	}
+
+	return out
+}
+
+// PopMaxN removes and returns up to n items in descending order.
+//
+// Parameters:
+//   - n: The maximum number of items to remove.
+//
+// Returns:
+//   - []T: The removed items, last item first.
+func (q *SyncedPriorityQueue[T]) PopMaxN(n int) []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]T, 0, min(max(n, 0), len(q.items)))
+
+	for len(out) < cap(out) {
+		out = append(out, q.removeAt(q.maxIndex()))
+	}
+
+	return out
+}
+
+// Clear removes all items from the queue, keeping its capacity.
+func (q *SyncedPriorityQueue[T]) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	clear(q.items)
+	q.items = q.items[:0]
+}
+
+// push appends item and restores the heap order. The caller must hold the lock.
+func (q *SyncedPriorityQueue[T]) push(item T) {
+	q.items = append(q.items, item)
+	q.bubbleUp(len(q.items) - 1)
+}
+
+// maxIndex returns the index of the item that orders last: the root if it is
+// the only item, otherwise the larger of its children, which sit on the first
+// max level. The queue must not be empty.
+func (q *SyncedPriorityQueue[T]) maxIndex() int {
+	switch len(q.items) {
+	case 1:
+		return 0
+	case 2:
+		return 1
+	default:
+		if q.less(q.items[1], q.items[2]) {
+			return 2
+		}
+
+		return 1
+	}
+}
+
+// removeAt removes and returns the item at i, moving the last item into its
+// place and trickling it down.
+func (q *SyncedPriorityQueue[T]) removeAt(i int) T {
+	last := len(q.items) - 1
+	item := q.items[i]
+
+	q.items[i] = q.items[last]
+
+	var zero T
+
+	q.items[last] = zero
+	q.items = q.items[:last]
+
+	if i < last {
+		q.trickleDown(i, isMinLevel(i))
+	}
+
+	return item
+}
+
+// bubbleUp moves the item at i up to its place after it was appended.
+func (q *SyncedPriorityQueue[T]) bubbleUp(i int) {
+	if i == 0 {
+		return
+	}
+
+	parent := (i - 1) / 2
+	minLevel := isMinLevel(i)
+
+	// a min-level item larger than its max-level parent belongs on the max
+	// levels above, and vice versa
+	if !q.ordered(q.items[parent], q.items[i], minLevel) {
+		q.bubbleUpLevel(i, minLevel)
+		return
+	}
+
+	q.items[i], q.items[parent] = q.items[parent], q.items[i]
+	q.bubbleUpLevel(parent, !minLevel)
+}
+
+// bubbleUpLevel moves the item at i up through its grandparents, which are on
+// the same kind of level.
+func (q *SyncedPriorityQueue[T]) bubbleUpLevel(i int, minLevel bool) {
+	for i > 2 {
+		grandparent := ((i-1)/2 - 1) / 2
+		if !q.ordered(q.items[i], q.items[grandparent], minLevel) {
+			return
+		}
+
+		q.items[i], q.items[grandparent] = q.items[grandparent], q.items[i]
+		i = grandparent
+	}
+}
+
+// trickleDown moves the item at i down to its place. On min levels the
+// smallest descendant is pulled up, on max levels the largest.
+func (q *SyncedPriorityQueue[T]) trickleDown(i int, minLevel bool) {
+	for {
+		m := q.extremeDescendant(i, minLevel)
+		if m < 0 || !q.ordered(q.items[m], q.items[i], minLevel) {
+			return
+		}
+
+		q.items[i], q.items[m] = q.items[m], q.items[i]
+
+		if m <= 2*i+2 {
+			// m was a child, on a level of the other kind: nothing below it
+			// can be out of order
+			return
+		}
+
+		if parent := (m - 1) / 2; q.ordered(q.items[parent], q.items[m], minLevel) {
+			q.items[m], q.items[parent] = q.items[parent], q.items[m]
+		}
+
+		i = m
+	}
+}
+
+// extremeDescendant returns the index of the smallest (minLevel) or largest
+// child or grandchild of i, or -1 if i is a leaf.
+func (q *SyncedPriorityQueue[T]) extremeDescendant(i int, minLevel bool) int {
+	best := -1
+
+	for _, c := range [...]int{2*i + 1, 2*i + 2, 4*i + 3, 4*i + 4, 4*i + 5, 4*i + 6} {
+		if c >= len(q.items) {
+			continue
+		}
+
+		if best < 0 || q.ordered(q.items[c], q.items[best], minLevel) {
+			best = c
+		}
+	}
+
+	return best
+}
+
+// ordered reports whether a strictly belongs above b on a min level (a < b)
+// or on a max level (a > b).
+func (q *SyncedPriorityQueue[T]) ordered(a, b T, minLevel bool) bool {
+	if minLevel {
+		return q.less(a, b)
+	}
+
+	return q.less(b, a)
+}
+
+// isMinLevel reports whether heap index i is on a min level, i.e. at an even
+// depth.
+func isMinLevel(i int) bool {
+	depth := 0
+
+	for i++; i > 1; i >>= 1 {
+		depth++
+	}
+
+	return depth%2 == 0
+}
@@ -0,0 +1,146 @@
+package txmap
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// intLess orders ints ascending.
+func intLess(a, b int) bool { return a < b }
+
+// TestSyncedPriorityQueueEmpty tests the empty queue.
+func TestSyncedPriorityQueueEmpty(t *testing.T) {
+	q := NewSyncedPriorityQueue(intLess)
+
+	_, ok := q.PopMin()
+	assert.False(t, ok)
+	_, ok = q.PopMax()
+	assert.False(t, ok)
+	_, ok = q.PeekMin()
+	assert.False(t, ok)
+	_, ok = q.PeekMax()
+	assert.False(t, ok)
+	assert.Empty(t, q.PopMinN(3))
+	assert.Equal(t, 0, q.Length())
+}
+
+// TestSyncedPriorityQueueOrder tests random mixes of pushes and pops from both
+// ends against a sorted reference slice.
+func TestSyncedPriorityQueueOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test data
+
+	for round := 0; round < 50; round++ {
+		q := NewSyncedPriorityQueue(intLess, 16)
+
+		var ref []int
+
+		for op := 0; op < 500; op++ {
+			switch rng.Intn(4) {
+			case 0, 1:
+				v := rng.Intn(100)
+				q.Push(v)
+				ref = append(ref, v)
+				slices.Sort(ref)
+			case 2:
+				v, ok := q.PopMin()
+				require.Equal(t, len(ref) > 0, ok)
+
+				if ok {
+					require.Equal(t, ref[0], v)
+					ref = ref[1:]
+				}
+			case 3:
+				v, ok := q.PopMax()
+				require.Equal(t, len(ref) > 0, ok)
+
+				if ok {
+					require.Equal(t, ref[len(ref)-1], v)
+					ref = ref[:len(ref)-1]
+				}
+			}
+
+			require.Equal(t, len(ref), q.Length())
+		}
+	}
+}
+
+// TestSyncedPriorityQueueBatch tests PushBatch and draining from both ends,
+// keyed by the fee rate of transactions.
+func TestSyncedPriorityQueueBatch(t *testing.T) {
+	type tx struct {
+		hash    chainhash.Hash
+		feeRate uint64
+	}
+
+	q := NewSyncedPriorityQueue(func(a, b tx) bool { return a.feeRate < b.feeRate })
+
+	batch := make([]tx, 0, 100)
+	for i := 0; i < 100; i++ {
+		batch = append(batch, tx{hash: hashN(i), feeRate: uint64((i * 37) % 100)})
+	}
+
+	q.PushBatch(batch)
+	require.Equal(t, 100, q.Length())
+
+	best, ok := q.PeekMax()
+	require.True(t, ok)
+	assert.Equal(t, uint64(99), best.feeRate)
+
+	worst, ok := q.PeekMin()
+	require.True(t, ok)
+	assert.Equal(t, uint64(0), worst.feeRate)
+
+	top := q.PopMaxN(3)
+	require.Len(t, top, 3)
+	assert.Equal(t, []uint64{99, 98, 97}, []uint64{top[0].feeRate, top[1].feeRate, top[2].feeRate})
+
+	bottom := q.PopMinN(2)
+	assert.Equal(t, []uint64{0, 1}, []uint64{bottom[0].feeRate, bottom[1].feeRate})
+	assert.Equal(t, 95, q.Length())
+
+	assert.Len(t, q.PopMinN(1000), 95)
+
+	q.PushBatch(batch)
+	q.Clear()
+	assert.Equal(t, 0, q.Length())
+}
+
+// TestSyncedPriorityQueueConcurrent tests concurrent pushes and pops.
+func TestSyncedPriorityQueueConcurrent(t *testing.T) {
+	q := NewSyncedPriorityQueue(intLess)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				q.Push(i)
+
+				if i%2 == 0 {
+					q.PopMin()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 2000, q.Length())
+
+	prev := -1
+
+	for _, v := range q.PopMinN(2000) {
+		require.GreaterOrEqual(t, v, prev)
+		prev = v
+	}
+}
@@ -0,0 +1,152 @@
+package txmap
+
+import "sync/atomic"
+
+// ShardedSyncedSlice is a multi-queue of SyncedSlice shards. Producers append
+// to one shard each, chosen round-robin or by a caller-supplied key, so that
+// concurrent producers rarely contend on the same lock, the same way the split
+// maps spread writers over buckets. Consumers drain the shards round-robin,
+// taking one item from each shard in turn, so every shard keeps its FIFO order
+// and no shard starves the others.
+type ShardedSyncedSlice[V any] struct {
+	shards []*SyncedSlice[V]
+	next   atomic.Uint64
+	drain  atomic.Uint64
+}
+
+// NewShardedSyncedSlice creates and returns a new, empty ShardedSyncedSlice.
+//
+// Parameters:
+//   - shards: The number of shards; values below one mean one.
+//   - length (optional): The initial capacity of each shard.
+//
+// Returns:
+//   - *ShardedSyncedSlice[V]: A pointer to a new, empty ShardedSyncedSlice instance.
+func NewShardedSyncedSlice[V any](shards int, length ...int) *ShardedSyncedSlice[V] {
+	s := &ShardedSyncedSlice[V]{
+		shards: make([]*SyncedSlice[V], max(shards, 1)),
+	}
+
+	for i := range s.shards {
+		s.shards[i] = NewSyncedSlice[V](length...)
+	}
+
+	return s
+}
+
+// Shards returns the number of shards.
+//
+// Returns:
+//   - int: The number of shards.
+func (s *ShardedSyncedSlice[V]) Shards() int {
+	return len(s.shards)
+}
+
+// Length returns the number of items in all shards. Shards are counted one by
+// one, so the total is approximate while producers or consumers are active.
+//
+// Returns:
+//   - int: The number of items.
+func (s *ShardedSyncedSlice[V]) Length() int {
+	length := 0
+
+	for _, shard := range s.shards {
+		length += shard.Length()
+	}
+
+	return length
+}
+
+// Append adds an item to the next shard in round-robin order.
+//
+// Parameters:
+//   - item: A pointer to the item to append.
+func (s *ShardedSyncedSlice[V]) Append(item *V) {
+	s.shards[(s.next.Add(1)-1)%uint64(len(s.shards))].Append(item)
+}
+
+// AppendKey adds an item to the shard selected by key, so that items with the
+// same key are drained in the order they were appended.
+//
+// Parameters:
+//   - key: Selects the shard, e.g. a producer id or bytes of a hash.
+//   - item: A pointer to the item to append.
+func (s *ShardedSyncedSlice[V]) AppendKey(key uint64, item *V) {
+	s.shards[key%uint64(len(s.shards))].Append(item)
+}
+
+// Drain removes all items from all shards and merges them round-robin: the
+// first item of every shard, then the second of every shard, and so on.
+// Each shard is emptied under its own lock, so items appended while Drain runs
+// are either included or left for the next call.
+//
+// Returns:
+//   - []*V: The removed items, or nil if all shards were empty.
+func (s *ShardedSyncedSlice[V]) Drain() []*V {
+	taken := make([][]*V, len(s.shards))
+	total := 0
+
+	for i, shard := range s.shards {
+		taken[i] = shard.takeAll()
+		total += len(taken[i])
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	out := make([]*V, 0, total)
+
+	for pos := 0; len(out) < total; pos++ {
+		for _, items := range taken {
+			if pos < len(items) {
+				out = append(out, items[pos])
+			}
+		}
+	}
+
+	return out
+}
+
+// DrainN removes up to n items, taking one item from each shard in turn. Each
+// call starts at the shard after the one the previous call started at, so
+// small batches are spread fairly over the shards.
+//
+// Parameters:
+//   - n: The maximum number of items to remove.
+//
+// Returns:
+//   - []*V: The removed items.
+func (s *ShardedSyncedSlice[V]) DrainN(n int) []*V {
+	out := make([]*V, 0, max(0, min(n, s.Length())))
+	start := int((s.drain.Add(1) - 1) % uint64(len(s.shards))) //nolint:gosec // result is below the shard count
+
+	for len(out) < n {
+		took := false
+
+		for i := 0; i < len(s.shards) && len(out) < n; i++ {
+			if item, ok := s.shards[(start+i)%len(s.shards)].Shift(); ok {
+				out = append(out, item)
+				took = true
+			}
+		}
+
+		if !took {
+			break
+		}
+	}
+
+	return out
+}
+
+// takeAll removes and returns all items of the slice, leaving it empty with a
+// fresh backing array of the same capacity.
+func (s *SyncedSlice[V]) takeAll() []*V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.items
+	s.items = make([]*V, 0, cap(items))
+
+	return items
+}
@@ -0,0 +1,100 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestShardedSyncedSliceDrain tests that Drain merges shards round-robin and
+// keeps the order within each shard.
+func TestShardedSyncedSliceDrain(t *testing.T) {
+	s := NewShardedSyncedSlice[int](3)
+	assert.Equal(t, 3, s.Shards())
+	assert.Nil(t, s.Drain())
+
+	values := make([]int, 7)
+	for i := range values {
+		values[i] = i
+	}
+
+	// shard 0: 0, 3, 6; shard 1: 1, 4; shard 2: 2, 5
+	for i := range values {
+		s.AppendKey(uint64(i), &values[i]) //nolint:gosec // i is small
+	}
+
+	assert.Equal(t, 7, s.Length())
+
+	got := make([]int, 0, 7)
+	for _, v := range s.Drain() {
+		got = append(got, *v)
+	}
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, got)
+	assert.Equal(t, 0, s.Length())
+}
+
+// TestShardedSyncedSliceDrainN tests partial drains rotating their start shard.
+func TestShardedSyncedSliceDrainN(t *testing.T) {
+	s := NewShardedSyncedSlice[int](2, 4)
+
+	values := []int{10, 11, 20, 21}
+	s.AppendKey(0, &values[0])
+	s.AppendKey(0, &values[1])
+	s.AppendKey(1, &values[2])
+	s.AppendKey(1, &values[3])
+
+	first := s.DrainN(1)
+	require.Len(t, first, 1)
+	assert.Equal(t, 10, *first[0])
+
+	second := s.DrainN(1)
+	require.Len(t, second, 1)
+	assert.Equal(t, 20, *second[0])
+
+	rest := s.DrainN(10)
+	require.Len(t, rest, 2)
+	assert.ElementsMatch(t, []int{11, 21}, []int{*rest[0], *rest[1]})
+
+	assert.Empty(t, s.DrainN(10))
+	assert.Empty(t, s.DrainN(0))
+}
+
+// TestShardedSyncedSliceConcurrent tests concurrent producers and a consumer
+// losing no items.
+func TestShardedSyncedSliceConcurrent(t *testing.T) {
+	s := NewShardedSyncedSlice[int](4)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		consumed int
+	)
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				v := i
+				s.Append(&v)
+
+				if i%100 == 0 {
+					n := len(s.DrainN(50))
+
+					mu.Lock()
+					consumed += n
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 8000, consumed+len(s.Drain()))
+}
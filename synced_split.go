@@ -0,0 +1,234 @@
+package txmap
+
+import (
+	"hash/maphash"
+)
+
+// SplitSyncedMap is a SyncedMap split into shards, each a SyncedMap with its
+// own lock, the way the split tx maps spread hashes over buckets. Keys of any
+// comparable type, such as high-cardinality strings, are routed to their shard
+// by a seeded hash, so concurrent users of different keys rarely contend.
+//
+// It offers the methods of SyncedMap with the same semantics, except that
+// operations spanning shards (Length, Range, Keys, Iterate, Clear) visit the
+// shards one at a time and are not atomic across them.
+type SplitSyncedMap[K comparable, V any] struct {
+	shards []*SyncedMap[K, V]
+	seed   maphash.Seed
+}
+
+// NewSplitSyncedMap creates and returns a new, empty SplitSyncedMap.
+//
+// Parameters:
+//   - shards: The number of shards; values below one mean one.
+//   - l (optional): The maximum number of items, split evenly over the shards
+//     and rounded up, so that each shard evicts on its own once it holds its
+//     share. If omitted or zero, the map has no limit.
+//
+// Returns:
+//   - *SplitSyncedMap[K, V]: A pointer to a new, empty SplitSyncedMap instance.
+func NewSplitSyncedMap[K comparable, V any](shards int, l ...int) *SplitSyncedMap[K, V] {
+	s := &SplitSyncedMap[K, V]{
+		shards: make([]*SyncedMap[K, V], max(shards, 1)),
+		seed:   maphash.MakeSeed(),
+	}
+
+	limit := 0
+	if len(l) > 0 && l[0] > 0 {
+		limit = (l[0] + len(s.shards) - 1) / len(s.shards)
+	}
+
+	for i := range s.shards {
+		s.shards[i] = NewSyncedMap[K, V](limit)
+	}
+
+	return s
+}
+
+// Shards returns the number of shards.
+func (s *SplitSyncedMap[K, V]) Shards() int {
+	return len(s.shards)
+}
+
+// shardIndex returns the index of the shard of key.
+func (s *SplitSyncedMap[K, V]) shardIndex(key K) int {
+	return int(maphash.Comparable(s.seed, key) % uint64(len(s.shards))) //nolint:gosec // G115 below the number of shards
+}
+
+// shard returns the shard of key.
+func (s *SplitSyncedMap[K, V]) shard(key K) *SyncedMap[K, V] {
+	return s.shards[s.shardIndex(key)]
+}
+
+// OnEvict registers cb with every shard; see SyncedMap.OnEvict.
+func (s *SplitSyncedMap[K, V]) OnEvict(cb EvictCallback[K, V]) {
+	for _, shard := range s.shards {
+		shard.OnEvict(cb)
+	}
+}
+
+// SetEvictionWeight sets the weighted eviction of every shard; see
+// SyncedMap.SetEvictionWeight.
+func (s *SplitSyncedMap[K, V]) SetEvictionWeight(weight func(value V) float64, samples int) {
+	for _, shard := range s.shards {
+		shard.SetEvictionWeight(weight, samples)
+	}
+}
+
+// Freeze freezes every shard; see SyncedMap.Freeze.
+func (s *SplitSyncedMap[K, V]) Freeze() {
+	for _, shard := range s.shards {
+		shard.Freeze()
+	}
+}
+
+// Length returns the number of key-value pairs in all shards.
+func (s *SplitSyncedMap[K, V]) Length() int {
+	length := 0
+
+	for _, shard := range s.shards {
+		length += shard.Length()
+	}
+
+	return length
+}
+
+// Exists checks if a key exists in the map.
+func (s *SplitSyncedMap[K, V]) Exists(key K) bool {
+	return s.shard(key).Exists(key)
+}
+
+// Get returns the value associated with the given key.
+func (s *SplitSyncedMap[K, V]) Get(key K) (V, bool) {
+	return s.shard(key).Get(key)
+}
+
+// Range returns a copy of the map as a standard Go map, copied shard by shard.
+func (s *SplitSyncedMap[K, V]) Range() map[K]V {
+	items := make(map[K]V, s.Length())
+
+	s.Iterate(func(key K, value V) bool {
+		items[key] = value
+		return true
+	})
+
+	return items
+}
+
+// Keys returns a slice of all keys in the map, collected shard by shard.
+func (s *SplitSyncedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, s.Length())
+
+	for _, shard := range s.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+
+	return keys
+}
+
+// Iterate calls f for each key-value pair, one shard at a time under its read
+// lock. The iteration stops if f returns false.
+func (s *SplitSyncedMap[K, V]) Iterate(f func(key K, value V) bool) {
+	stopped := false
+
+	for _, shard := range s.shards {
+		shard.Iterate(func(key K, value V) bool {
+			stopped = !f(key, value)
+			return !stopped
+		})
+
+		if stopped {
+			return
+		}
+	}
+}
+
+// Set sets the value for the given key. Panics if the map is frozen.
+func (s *SplitSyncedMap[K, V]) Set(key K, value V) {
+	s.shard(key).Set(key, value)
+}
+
+// SetIfNotExists sets the value for the key if it does not exist yet. Panics
+// if the map is frozen.
+//
+// Returns:
+//   - V: The value that was set or already existed.
+//   - bool: True if the value was set, false if the key already existed.
+func (s *SplitSyncedMap[K, V]) SetIfNotExists(key K, value V) (V, bool) {
+	return s.shard(key).SetIfNotExists(key, value)
+}
+
+// SetMulti sets the given value for multiple keys, taking the lock of each
+// shard once. Panics if the map is frozen.
+func (s *SplitSyncedMap[K, V]) SetMulti(keys []K, value V) {
+	for i, positions := range s.groupKeys(keys) {
+		shardKeys := make([]K, len(positions))
+
+		for j, p := range positions {
+			shardKeys[j] = keys[p]
+		}
+
+		s.shards[i].SetMulti(shardKeys, value)
+	}
+}
+
+// SetIfNotExistsMulti inserts each keys[i] -> values[i] pair that does not
+// exist yet, taking the lock of each shard once; see
+// SyncedMap.SetIfNotExistsMulti. Panics if the map is frozen.
+//
+// Returns:
+//   - []bool: wasInserted[i] is true if keys[i] was newly added.
+func (s *SplitSyncedMap[K, V]) SetIfNotExistsMulti(keys []K, values []V) []bool {
+	keys = keys[:min(len(keys), len(values))]
+	wasInserted := make([]bool, len(keys))
+
+	for i, positions := range s.groupKeys(keys) {
+		shardKeys := make([]K, len(positions))
+		shardValues := make([]V, len(positions))
+
+		for j, p := range positions {
+			shardKeys[j], shardValues[j] = keys[p], values[p]
+		}
+
+		for j, inserted := range s.shards[i].SetIfNotExistsMulti(shardKeys, shardValues) {
+			wasInserted[positions[j]] = inserted
+		}
+	}
+
+	return wasInserted
+}
+
+// groupKeys returns the positions of keys grouped by shard index, each group
+// in input order.
+func (s *SplitSyncedMap[K, V]) groupKeys(keys []K) map[int][]int {
+	groups := make(map[int][]int)
+
+	for i, key := range keys {
+		shard := s.shardIndex(key)
+		groups[shard] = append(groups[shard], i)
+	}
+
+	return groups
+}
+
+// Delete removes the key and its value from the map. Panics if the map is
+// frozen.
+//
+// Returns:
+//   - bool: True if the key was deleted, as SyncedMap.Delete.
+func (s *SplitSyncedMap[K, V]) Delete(key K) bool {
+	return s.shard(key).Delete(key)
+}
+
+// Clear clears and un-freezes every shard; see SyncedMap.Clear for the
+// lifecycle contract.
+//
+// Returns:
+//   - bool: True if the map was cleared successfully.
+func (s *SplitSyncedMap[K, V]) Clear() bool {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+
+	return true
+}
@@ -0,0 +1,114 @@
+package txmap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSplitSyncedMap tests the single-key methods of SplitSyncedMap across its shards.
+func TestSplitSyncedMap(t *testing.T) {
+	m := NewSplitSyncedMap[string, int](8)
+	assert.Equal(t, 8, m.Shards())
+
+	for i := range 1000 {
+		m.Set("key-"+strconv.Itoa(i), i)
+	}
+
+	assert.Equal(t, 1000, m.Length())
+	assert.Len(t, m.Keys(), 1000)
+	assert.Len(t, m.Range(), 1000)
+
+	value, ok := m.Get("key-42")
+	require.True(t, ok)
+	assert.Equal(t, 42, value)
+
+	existing, set := m.SetIfNotExists("key-42", 0)
+	assert.False(t, set)
+	assert.Equal(t, 42, existing)
+
+	assert.True(t, m.Delete("key-42"))
+	assert.False(t, m.Exists("key-42"))
+
+	visited := 0
+
+	m.Iterate(func(string, int) bool {
+		visited++
+		return visited < 10
+	})
+	assert.Equal(t, 10, visited)
+
+	m.Freeze()
+	assert.Panics(t, func() { m.Set("new", 1) })
+
+	assert.True(t, m.Clear())
+	assert.Equal(t, 0, m.Length())
+	m.Set("new", 1)
+}
+
+// TestSplitSyncedMapMulti tests that the multi-key methods keep the input order of their results.
+func TestSplitSyncedMapMulti(t *testing.T) {
+	m := NewSplitSyncedMap[string, int](4)
+	m.Set("b", 0)
+
+	m.SetMulti([]string{"x", "y", "z"}, 7)
+	assert.Equal(t, map[string]int{"b": 0, "x": 7, "y": 7, "z": 7}, m.Range())
+
+	inserted := m.SetIfNotExistsMulti([]string{"a", "b", "c", "a", "extra"}, []int{1, 2, 3, 4})
+	assert.Equal(t, []bool{true, false, true, false}, inserted)
+
+	value, _ := m.Get("a")
+	assert.Equal(t, 1, value)
+	assert.False(t, m.Exists("extra"))
+}
+
+// TestSplitSyncedMapLimit tests that the item limit is split over the shards.
+func TestSplitSyncedMapLimit(t *testing.T) {
+	m := NewSplitSyncedMap[int, int](4, 100)
+
+	evicted := 0
+
+	var mu sync.Mutex
+
+	m.OnEvict(func(_ int, _ int, reason EvictReason) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, EvictReasonCapacity, reason)
+		evicted++
+	})
+
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+
+	assert.LessOrEqual(t, m.Length(), 100)
+	assert.Equal(t, 1000-m.Length(), evicted)
+}
+
+// TestSplitSyncedMapConcurrent tests concurrent writers on distinct keys.
+func TestSplitSyncedMapConcurrent(t *testing.T) {
+	m := NewSplitSyncedMap[string, int](16)
+
+	var wg sync.WaitGroup
+
+	for w := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				key := strconv.Itoa(w) + "/" + strconv.Itoa(i)
+				m.Set(key, i)
+				assert.True(t, m.Exists(key))
+			}
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 8000, m.Length())
+}
@@ -0,0 +1,240 @@
+package txmap
+
+import (
+	"sync"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that TaggedTxMap implements TxMap
+var _ TxMap = (*TaggedTxMap)(nil)
+
+// TaggedTxMap wraps a TxMap and lets entries be tagged on insert, e.g. with
+// the id of the block or subtree they belong to, so that a whole group can be
+// expired at once with DeleteByTag in time proportional to the group's size.
+//
+// Each entry has at most one tag; entries inserted through the plain TxMap
+// methods are untagged. Reads go straight to the wrapped map, but writes are
+// serialized by the TaggedTxMap so that the tag index always matches the
+// wrapped map. All writes must go through the TaggedTxMap.
+type TaggedTxMap struct {
+	m TxMap
+
+	mu     sync.Mutex
+	tags   map[chainhash.Hash]uint64
+	groups map[uint64]map[chainhash.Hash]struct{}
+}
+
+// NewTaggedTxMap returns a TaggedTxMap forwarding every operation to m.
+//
+// Params:
+//   - m: The map to wrap.
+//
+// Returns:
+//   - *TaggedTxMap: The wrapping map.
+func NewTaggedTxMap(m TxMap) *TaggedTxMap {
+	m = nonNilTxMap(m)
+
+	return &TaggedTxMap{
+		m:      m,
+		tags:   make(map[chainhash.Hash]uint64),
+		groups: make(map[uint64]map[chainhash.Hash]struct{}),
+	}
+}
+
+// PutTagged adds hash to the wrapped map with the given tag.
+//
+// Params:
+//   - hash: The hash to add.
+//   - value: The value to associate with the hash.
+//   - tag: The group the entry belongs to.
+//
+// Returns:
+//   - error: Any error returned by the wrapped map's Put; the entry is not
+//     tagged then.
+func (t *TaggedTxMap) PutTagged(hash chainhash.Hash, value, tag uint64) error {
+	return t.PutMultiTagged([]chainhash.Hash{hash}, value, tag)
+}
+
+// PutMultiTagged adds hashes to the wrapped map with the given tag. If the
+// wrapped map fails part-way, the hashes it inserted are still tagged, while
+// hashes that already existed keep their previous tag.
+func (t *TaggedTxMap) PutMultiTagged(hashes []chainhash.Hash, value, tag uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	existed := make([]bool, len(hashes))
+
+	for i, hash := range hashes {
+		existed[i] = t.m.Exists(hash)
+	}
+
+	err := t.m.PutMulti(hashes, value)
+
+	for i, hash := range hashes {
+		if !existed[i] && t.m.Exists(hash) {
+			t.tagUnlocked(hash, tag)
+		}
+	}
+
+	return err
+}
+
+// Tag returns the tag of hash, and false if it is not tagged.
+func (t *TaggedTxMap) Tag(hash chainhash.Hash) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tag, ok := t.tags[hash]
+
+	return tag, ok
+}
+
+// DeleteByTag deletes every entry with the given tag.
+//
+// Params:
+//   - tag: The group to delete.
+//
+// Returns:
+//   - int: The number of entries deleted.
+func (t *TaggedTxMap) DeleteByTag(tag uint64) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	deleted := 0
+
+	for hash := range t.groups[tag] {
+		if err := t.m.Delete(hash); err == nil {
+			deleted++
+		}
+
+		delete(t.tags, hash)
+	}
+
+	delete(t.groups, tag)
+
+	return deleted
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (t *TaggedTxMap) Exists(hash chainhash.Hash) bool {
+	return t.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (t *TaggedTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return t.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (t *TaggedTxMap) Keys() []chainhash.Hash {
+	return t.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (t *TaggedTxMap) Length() int {
+	return t.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (t *TaggedTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	t.m.Iter(f)
+}
+
+// Put adds an untagged hash to the wrapped map.
+func (t *TaggedTxMap) Put(hash chainhash.Hash, value uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.m.Put(hash, value)
+}
+
+// PutMulti adds untagged hashes to the wrapped map.
+func (t *TaggedTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.m.PutMulti(hashes, value)
+}
+
+// Set updates the value of an existing hash; its tag is kept.
+func (t *TaggedTxMap) Set(hash chainhash.Hash, value uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.m.Set(hash, value)
+}
+
+// SetIfExists updates the value of hash if it exists; its tag is kept.
+func (t *TaggedTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash untagged if it does not exist yet.
+func (t *TaggedTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.m.SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the wrapped map and from its tag group.
+func (t *TaggedTxMap) Delete(hash chainhash.Hash) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.m.Delete(hash); err != nil {
+		return err
+	}
+
+	t.untagUnlocked(hash)
+
+	return nil
+}
+
+// Freeze freezes the wrapped map.
+func (t *TaggedTxMap) Freeze() {
+	t.m.Freeze()
+}
+
+// Clear empties the wrapped map and drops all tags.
+func (t *TaggedTxMap) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.m.Clear()
+	clear(t.tags)
+	clear(t.groups)
+}
+
+// tagUnlocked moves hash into the group of tag. The caller must hold the lock.
+func (t *TaggedTxMap) tagUnlocked(hash chainhash.Hash, tag uint64) {
+	t.untagUnlocked(hash)
+
+	group, ok := t.groups[tag]
+	if !ok {
+		group = make(map[chainhash.Hash]struct{})
+		t.groups[tag] = group
+	}
+
+	group[hash] = struct{}{}
+	t.tags[hash] = tag
+}
+
+// untagUnlocked removes hash from its group. The caller must hold the lock.
+func (t *TaggedTxMap) untagUnlocked(hash chainhash.Hash) {
+	tag, ok := t.tags[hash]
+	if !ok {
+		return
+	}
+
+	delete(t.tags, hash)
+	delete(t.groups[tag], hash)
+
+	if len(t.groups[tag]) == 0 {
+		delete(t.groups, tag)
+	}
+}
@@ -0,0 +1,50 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTaggedTxMap tests deleting entries by tag, and that untagged entries,
+// deleted entries and failed inserts are handled consistently.
+func TestTaggedTxMap(t *testing.T) {
+	m := NewTaggedTxMap(NewNativeSplitMapUint64(64, 8))
+
+	for i := 0; i < 30; i++ {
+		require.NoError(t, m.PutTagged(hashN(i), uint64(i), uint64(i%3)))
+	}
+
+	require.NoError(t, m.Put(hashN(100), 1))
+
+	// hashN(0) already exists with tag 0 and makes the insert fail
+	err := m.PutMultiTagged([]chainhash.Hash{hashN(200), hashN(0), hashN(201)}, 1, 7)
+	require.ErrorIs(t, err, ErrHashAlreadyExists)
+
+	tag, ok := m.Tag(hashN(0))
+	require.True(t, ok)
+	assert.Equal(t, uint64(0), tag)
+
+	tag, ok = m.Tag(hashN(200))
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), tag)
+
+	require.NoError(t, m.Delete(hashN(3)))
+
+	assert.Equal(t, 9, m.DeleteByTag(0))
+	assert.Equal(t, 0, m.DeleteByTag(0))
+	assert.False(t, m.Exists(hashN(6)))
+	assert.True(t, m.Exists(hashN(1)))
+	assert.True(t, m.Exists(hashN(100)))
+
+	_, ok = m.Tag(hashN(100))
+	assert.False(t, ok)
+
+	assert.Equal(t, 10, m.DeleteByTag(1))
+
+	m.Clear()
+	assert.Equal(t, 0, m.DeleteByTag(2))
+	assert.Equal(t, 0, m.Length())
+}
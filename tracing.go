@@ -0,0 +1,212 @@
+package txmap
+
+import (
+	"io"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Tracing
+//
+// TracingTxMap records spans for the batch operations of a map so that
+// distributed traces of block processing show the time spent inside tx maps.
+// To keep this package free of a tracing SDK dependency, spans are created
+// through the small Tracer interface below; an OpenTelemetry adapter is a few
+// lines in the application:
+//
+//	type otelTracer struct{ ctx context.Context; t trace.Tracer }
+//
+//	func (o otelTracer) StartSpan(name string) txmap.Span {
+//		_, span := o.t.Start(o.ctx, name)
+//		return otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetInt(key string, value int) { s.SetAttributes(attribute.Int(key, value)) }
+//
+// Single-hash operations (Get, Put, ...) are not traced: they take tens of
+// nanoseconds and a span each would cost more than the operation.
+
+const (
+	// spanAttrCount is the span attribute holding the number of entries processed.
+	spanAttrCount = "txmap.count"
+
+	// spanAttrBuckets is the span attribute holding the number of buckets of a split map.
+	spanAttrBuckets = "txmap.buckets"
+)
+
+// Tracer starts the spans recorded by TracingTxMap.
+type Tracer interface {
+	// StartSpan starts a span with the given name. The implementation decides
+	// its parent, e.g. from a context it was created with.
+	StartSpan(name string) Span
+}
+
+// Span is the part of a tracing span used by TracingTxMap.
+type Span interface {
+	// SetInt sets an integer attribute on the span.
+	SetInt(key string, value int)
+
+	// SetError records a failed operation on the span.
+	SetError(err error)
+
+	// End ends the span.
+	End()
+}
+
+// check that TracingTxMap implements TxMap
+var _ TxMap = (*TracingTxMap)(nil)
+
+// TracingTxMap wraps a TxMap and records a span for each batch operation
+// (PutMulti, Keys, Iter, Clear, Snapshot), with the number of entries and, on
+// a ShardedTxMap, the number of buckets as attributes.
+type TracingTxMap struct {
+	m       TxMap
+	tracer  Tracer
+	buckets int
+}
+
+// NewTracingTxMap returns a TracingTxMap recording spans for m with tracer.
+//
+// Params:
+//   - m: The map to wrap.
+//   - tracer: The tracer starting the spans.
+//
+// Returns:
+//   - *TracingTxMap: The wrapping map.
+func NewTracingTxMap(m TxMap, tracer Tracer) *TracingTxMap {
+	m = nonNilTxMap(m)
+
+	t := &TracingTxMap{
+		m:      m,
+		tracer: tracer,
+	}
+
+	if sm, ok := AsSharded(m); ok {
+		t.buckets = int(sm.Buckets()) + 1
+	}
+
+	return t
+}
+
+// Snapshot exports the wrapped map to w (see Export) within a span.
+func (t *TracingTxMap) Snapshot(w io.Writer) error {
+	span := t.start("txmap.Snapshot")
+	defer span.End()
+
+	span.SetInt(spanAttrCount, t.m.Length())
+
+	err := Export(w, t.m)
+	if err != nil {
+		span.SetError(err)
+	}
+
+	return err
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (t *TracingTxMap) Exists(hash chainhash.Hash) bool {
+	return t.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (t *TracingTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return t.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map within a span.
+func (t *TracingTxMap) Keys() []chainhash.Hash {
+	span := t.start("txmap.Keys")
+	defer span.End()
+
+	keys := t.m.Keys()
+	span.SetInt(spanAttrCount, len(keys))
+
+	return keys
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (t *TracingTxMap) Length() int {
+	return t.m.Length()
+}
+
+// Iter iterates over the wrapped map within a span counting the entries visited.
+func (t *TracingTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	span := t.start("txmap.Iter")
+	defer span.End()
+
+	visited := 0
+
+	t.m.Iter(func(hash chainhash.Hash, value uint64) bool {
+		visited++
+		return f(hash, value)
+	})
+
+	span.SetInt(spanAttrCount, visited)
+}
+
+// Put adds hash to the wrapped map.
+func (t *TracingTxMap) Put(hash chainhash.Hash, value uint64) error {
+	return t.m.Put(hash, value)
+}
+
+// PutMulti adds hashes to the wrapped map within a span.
+func (t *TracingTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	span := t.start("txmap.PutMulti")
+	defer span.End()
+
+	span.SetInt(spanAttrCount, len(hashes))
+
+	err := t.m.PutMulti(hashes, value)
+	if err != nil {
+		span.SetError(err)
+	}
+
+	return err
+}
+
+// Set updates the value of an existing hash in the wrapped map.
+func (t *TracingTxMap) Set(hash chainhash.Hash, value uint64) error {
+	return t.m.Set(hash, value)
+}
+
+// SetIfExists updates the value of hash in the wrapped map if it exists.
+func (t *TracingTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return t.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash to the wrapped map if it does not exist yet.
+func (t *TracingTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return t.m.SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the wrapped map.
+func (t *TracingTxMap) Delete(hash chainhash.Hash) error {
+	return t.m.Delete(hash)
+}
+
+// Freeze freezes the wrapped map.
+func (t *TracingTxMap) Freeze() {
+	t.m.Freeze()
+}
+
+// Clear empties the wrapped map within a span counting the entries discarded.
+func (t *TracingTxMap) Clear() {
+	span := t.start("txmap.Clear")
+	defer span.End()
+
+	span.SetInt(spanAttrCount, t.m.Length())
+	t.m.Clear()
+}
+
+// start starts a span with the attributes common to all operations.
+func (t *TracingTxMap) start(name string) Span {
+	span := t.tracer.StartSpan(name)
+
+	if t.buckets > 0 {
+		span.SetInt(spanAttrBuckets, t.buckets)
+	}
+
+	return span
+}
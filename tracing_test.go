@@ -0,0 +1,77 @@
+package txmap
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedSpan is a span recorded by testTracer.
+type recordedSpan struct {
+	name  string
+	ints  map[string]int
+	err   error
+	ended bool
+}
+
+// SetInt records an attribute.
+func (s *recordedSpan) SetInt(key string, value int) { s.ints[key] = value }
+
+// SetError records an error.
+func (s *recordedSpan) SetError(err error) { s.err = err }
+
+// End marks the span ended.
+func (s *recordedSpan) End() { s.ended = true }
+
+// testTracer records every span started.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+// StartSpan starts and records a span.
+func (t *testTracer) StartSpan(name string) Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := &recordedSpan{name: name, ints: make(map[string]int)}
+	t.spans = append(t.spans, span)
+
+	return span
+}
+
+// TestTracingTxMap tests the spans recorded for batch operations.
+func TestTracingTxMap(t *testing.T) {
+	tracer := &testTracer{}
+	m := NewTracingTxMap(NewNativeSplitMapUint64(16, 8), tracer)
+
+	require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(1), hashN(2), hashN(3)}, 1))
+	require.NoError(t, m.Put(hashN(4), 1))
+	require.ErrorIs(t, m.PutMulti([]chainhash.Hash{hashN(4)}, 1), ErrHashAlreadyExists)
+	assert.Len(t, m.Keys(), 4)
+	require.NoError(t, m.Snapshot(io.Discard))
+	m.Clear()
+
+	require.Len(t, tracer.spans, 5)
+
+	want := []struct {
+		name  string
+		count int
+	}{{"txmap.PutMulti", 3}, {"txmap.PutMulti", 1}, {"txmap.Keys", 4}, {"txmap.Snapshot", 4}, {"txmap.Clear", 4}}
+
+	for i, w := range want {
+		span := tracer.spans[i]
+		assert.Equal(t, w.name, span.name)
+		assert.Equal(t, w.count, span.ints[spanAttrCount], w.name)
+		assert.Equal(t, 9, span.ints[spanAttrBuckets])
+		assert.True(t, span.ended)
+	}
+
+	assert.True(t, errors.Is(tracer.spans[1].err, ErrHashAlreadyExists))
+	assert.NoError(t, tracer.spans[0].err)
+}
@@ -0,0 +1,112 @@
+package txmap
+
+import "github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+// KeyTransform maps a hash to the canonical form it is stored under.
+type KeyTransform func(hash chainhash.Hash) chainhash.Hash
+
+// check that TransformingTxMap implements TxMap
+var _ TxMap = (*TransformingTxMap)(nil)
+
+// TransformingTxMap wraps a TxMap and applies a KeyTransform to every hash
+// passed to it, so that keys arriving in different forms, e.g. in display
+// order from RPC and in internal order from the wire, cannot create two
+// entries for the same logical key.
+//
+// The transform must be deterministic and idempotent (transforming a
+// canonical hash returns it unchanged), since Keys and Iter return the stored,
+// canonical hashes and callers may pass them back in.
+type TransformingTxMap struct {
+	m         TxMap
+	transform KeyTransform
+}
+
+// NewTransformingTxMap returns a TransformingTxMap storing hashes in m under
+// their transformed form.
+//
+// Params:
+//   - m: The map to wrap.
+//   - transform: Applied to the hash of every operation.
+//
+// Returns:
+//   - *TransformingTxMap: The wrapping map.
+func NewTransformingTxMap(m TxMap, transform KeyTransform) *TransformingTxMap {
+	m = nonNilTxMap(m)
+
+	return &TransformingTxMap{
+		m:         m,
+		transform: transform,
+	}
+}
+
+// Exists checks if the transformed hash exists in the wrapped map.
+func (t *TransformingTxMap) Exists(hash chainhash.Hash) bool {
+	return t.m.Exists(t.transform(hash))
+}
+
+// Get retrieves the value of the transformed hash from the wrapped map.
+func (t *TransformingTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return t.m.Get(t.transform(hash))
+}
+
+// Keys returns all hashes in the wrapped map, in canonical form.
+func (t *TransformingTxMap) Keys() []chainhash.Hash {
+	return t.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (t *TransformingTxMap) Length() int {
+	return t.m.Length()
+}
+
+// Iter iterates over the wrapped map, passing hashes in canonical form. Stops
+// iterating if f returns true.
+func (t *TransformingTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	t.m.Iter(f)
+}
+
+// Put adds the transformed hash to the wrapped map.
+func (t *TransformingTxMap) Put(hash chainhash.Hash, value uint64) error {
+	return t.m.Put(t.transform(hash), value)
+}
+
+// PutMulti adds the transformed hashes to the wrapped map.
+func (t *TransformingTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	transformed := make([]chainhash.Hash, len(hashes))
+
+	for i, hash := range hashes {
+		transformed[i] = t.transform(hash)
+	}
+
+	return t.m.PutMulti(transformed, value)
+}
+
+// Set updates the value of the transformed hash in the wrapped map.
+func (t *TransformingTxMap) Set(hash chainhash.Hash, value uint64) error {
+	return t.m.Set(t.transform(hash), value)
+}
+
+// SetIfExists updates the value of the transformed hash if it exists.
+func (t *TransformingTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return t.m.SetIfExists(t.transform(hash), value)
+}
+
+// SetIfNotExists adds the transformed hash if it does not exist yet.
+func (t *TransformingTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return t.m.SetIfNotExists(t.transform(hash), value)
+}
+
+// Delete removes the transformed hash from the wrapped map.
+func (t *TransformingTxMap) Delete(hash chainhash.Hash) error {
+	return t.m.Delete(t.transform(hash))
+}
+
+// Freeze freezes the wrapped map.
+func (t *TransformingTxMap) Freeze() {
+	t.m.Freeze()
+}
+
+// Clear empties the wrapped map.
+func (t *TransformingTxMap) Clear() {
+	t.m.Clear()
+}
@@ -0,0 +1,51 @@
+package txmap
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTransformingTxMap tests that a hash and its byte-reversed form are the
+// same logical key under a normalizing transform.
+func TestTransformingTxMap(t *testing.T) {
+	// canonical form: the smaller of the hash and its reversal
+	normalize := func(hash chainhash.Hash) chainhash.Hash {
+		reversed := hash
+		slices.Reverse(reversed[:])
+
+		if slices.Compare(reversed[:], hash[:]) < 0 {
+			return reversed
+		}
+
+		return hash
+	}
+
+	m := NewTransformingTxMap(NewNativeMapUint64(8), normalize)
+
+	internal := bloomHash(1)
+	display := internal
+	slices.Reverse(display[:])
+
+	require.NoError(t, m.Put(internal, 5))
+	require.ErrorIs(t, m.Put(display, 6), ErrHashAlreadyExists)
+	require.ErrorIs(t, m.PutMulti([]chainhash.Hash{display}, 6), ErrHashAlreadyExists)
+
+	v, ok := m.Get(display)
+	require.True(t, ok)
+	assert.Equal(t, uint64(5), v)
+
+	require.NoError(t, m.Set(display, 7))
+
+	added, err := m.SetIfNotExists(internal, 1)
+	require.NoError(t, err)
+	assert.False(t, added)
+
+	assert.Equal(t, []chainhash.Hash{normalize(internal)}, m.Keys())
+
+	require.NoError(t, m.Delete(display))
+	assert.False(t, m.Exists(internal))
+}
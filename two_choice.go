@@ -0,0 +1,373 @@
+package txmap
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Two-choice placement
+//
+// The split maps route a hash to a bucket by its first two bytes. Those are
+// uniform for real transaction ids, but not for every key set: keys derived
+// from a common prefix, or ids ground to a pattern, pile up in a few buckets,
+// which then grow, rehash and contend for their locks far more than the rest.
+//
+// TwoChoiceSplitMap gives every hash a second candidate bucket, chosen by its
+// last two bytes, and inserts it into whichever of the two holds fewer
+// entries; reads check both. By the power of two choices this keeps the
+// largest bucket close to the mean even when one of the choices is badly
+// skewed, at the price of a second lookup for hashes that are missing or live
+// in their second bucket. Writes lock both candidate buckets, in index order,
+// so that a hash can never end up in both.
+//
+// BucketStats describes the resulting balance; compare the Skew of a
+// SplitSwissMapUint64 and a TwoChoiceSplitMap holding the same keys.
+
+// BucketStats describes how the entries of a split map are spread over its
+// buckets. It covers the buckets hashes are routed to, 0..Buckets()-1; the
+// bucket numbered Buckets() is never routed to and is left out.
+type BucketStats struct {
+	// Buckets is the number of buckets covered.
+	Buckets int
+
+	// Entries is the number of entries in those buckets.
+	Entries int
+
+	// Min and Max are the entries of the smallest and largest bucket.
+	Min int
+	Max int
+
+	// Mean is the average number of entries per bucket.
+	Mean float64
+
+	// StdDev is the standard deviation of the entries per bucket.
+	StdDev float64
+
+	// Skew is Max divided by Mean: 1 for perfectly even buckets, and the
+	// factor by which the largest bucket exceeds its fair share otherwise.
+	// It is 0 for an empty map.
+	Skew float64
+}
+
+// String formats the stats for logs.
+func (s BucketStats) String() string {
+	return fmt.Sprintf("%d entries in %d buckets, min %d, max %d, mean %.1f, stddev %.1f, skew %.2f",
+		s.Entries, s.Buckets, s.Min, s.Max, s.Mean, s.StdDev, s.Skew)
+}
+
+// check that TwoChoiceSplitMap implements TxMap
+var _ TxMap = (*TwoChoiceSplitMap)(nil)
+
+// TwoChoiceSplitMap is a split map that places every hash in the lighter of
+// two candidate buckets. See the notes at the top of this file.
+type TwoChoiceSplitMap struct {
+	m           []*SwissMapUint64
+	nrOfBuckets uint16
+}
+
+// NewTwoChoiceSplitMap creates a new TwoChoiceSplitMap with the specified
+// initial length.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - buckets: Optionally the number of buckets, 1024 by default.
+//
+// Returns:
+//   - *TwoChoiceSplitMap: A pointer to the newly created TwoChoiceSplitMap instance.
+func NewTwoChoiceSplitMap(length uint32, buckets ...uint16) *TwoChoiceSplitMap {
+	useBuckets := uint16(1024)
+	if len(buckets) > 0 {
+		useBuckets = buckets[0]
+	}
+
+	g := &TwoChoiceSplitMap{
+		m:           make([]*SwissMapUint64, int(useBuckets)+1),
+		nrOfBuckets: useBuckets,
+	}
+
+	// the same 20% headroom as NewSplitSwissMapUint64; balanced buckets need
+	// no more than that
+	perBucket := (length + length/5) / uint32(useBuckets)
+
+	for i := uint16(0); i <= useBuckets; i++ {
+		g.m[i] = NewSwissMapUint64(perBucket)
+	}
+
+	return g
+}
+
+// Buckets returns the highest bucket index; buckets 0..Buckets() inclusive exist.
+func (g *TwoChoiceSplitMap) Buckets() uint16 {
+	return g.nrOfBuckets
+}
+
+// Stats returns how the entries are spread over the buckets.
+func (g *TwoChoiceSplitMap) Stats() BucketStats {
+	return splitBucketStats(g.m, g.nrOfBuckets)
+}
+
+// Exists checks if the given hash exists in either of its buckets.
+func (g *TwoChoiceSplitMap) Exists(hash chainhash.Hash) bool {
+	first, second := g.candidates(hash)
+
+	return g.m[first].Exists(hash) || (second != first && g.m[second].Exists(hash))
+}
+
+// Get retrieves the value of the given hash from either of its buckets.
+func (g *TwoChoiceSplitMap) Get(hash chainhash.Hash) (uint64, bool) {
+	first, second := g.candidates(hash)
+
+	if value, ok := g.m[first].Get(hash); ok || second == first {
+		return value, ok
+	}
+
+	return g.m[second].Get(hash)
+}
+
+// Put adds the hash with value n to the lighter of its buckets; a hash that
+// exists in either is handled by the duplicate policy of its bucket.
+func (g *TwoChoiceSplitMap) Put(hash chainhash.Hash, n uint64) error {
+	return g.write(hash, func(first, second *SwissMapUint64) error {
+		return g.target(hash, first, second).putUnlocked(hash, n)
+	})
+}
+
+// PutMulti adds all hashes with value n, and returns the first error.
+func (g *TwoChoiceSplitMap) PutMulti(hashes []chainhash.Hash, n uint64) error {
+	for _, hash := range hashes {
+		if err := g.Put(hash, n); err != nil {
+			return fmt.Errorf("failed to put multi: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value of an existing hash.
+func (g *TwoChoiceSplitMap) Set(hash chainhash.Hash, value uint64) error {
+	return g.write(hash, func(first, second *SwissMapUint64) error {
+		holder := holderOf(hash, first, second)
+		if holder == nil {
+			return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+		}
+
+		holder.m.Put(hash, value)
+
+		return nil
+	})
+}
+
+// SetIfExists updates the value of the hash if it exists.
+func (g *TwoChoiceSplitMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	var set bool
+
+	err := g.write(hash, func(first, second *SwissMapUint64) error {
+		if holder := holderOf(hash, first, second); holder != nil {
+			holder.m.Put(hash, value)
+			set = true
+		}
+
+		return nil
+	})
+
+	return set, err
+}
+
+// SetIfNotExists adds the hash to the lighter of its buckets if it exists in
+// neither.
+func (g *TwoChoiceSplitMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	var set bool
+
+	err := g.write(hash, func(first, second *SwissMapUint64) error {
+		if holderOf(hash, first, second) != nil {
+			return nil
+		}
+
+		set = true
+
+		return g.target(hash, first, second).putUnlocked(hash, value)
+	})
+
+	return set, err
+}
+
+// Delete removes the hash from whichever of its buckets holds it.
+func (g *TwoChoiceSplitMap) Delete(hash chainhash.Hash) error {
+	return g.write(hash, func(first, second *SwissMapUint64) error {
+		holder := holderOf(hash, first, second)
+		if holder == nil {
+			return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+		}
+
+		holder.m.Delete(hash)
+		debugAssertLength(holder.length.Add(-1))
+
+		return nil
+	})
+}
+
+// Length returns the number of hashes in the map.
+func (g *TwoChoiceSplitMap) Length() int {
+	length := 0
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		length += g.m[i].Length()
+	}
+
+	return length
+}
+
+// Keys returns all hashes in the map, one bucket at a time.
+func (g *TwoChoiceSplitMap) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, g.Length())
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		keys = append(keys, g.m[i].Keys()...)
+	}
+
+	return keys
+}
+
+// Iter iterates over the buckets in order. Stops iterating if f returns true.
+func (g *TwoChoiceSplitMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
+	}
+}
+
+// Freeze freezes every bucket. See the lifecycle notes in freeze.go.
+func (g *TwoChoiceSplitMap) Freeze() {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].Freeze()
+	}
+}
+
+// Clear empties and un-freezes every bucket.
+func (g *TwoChoiceSplitMap) Clear() {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].Clear()
+	}
+}
+
+// candidates returns the two buckets of hash, which may be the same.
+func (g *TwoChoiceSplitMap) candidates(hash chainhash.Hash) (uint16, uint16) {
+	return Bytes2Uint16Buckets(hash, g.nrOfBuckets), (uint16(hash[31])<<8 | uint16(hash[30])) % g.nrOfBuckets
+}
+
+// target returns the bucket a write of hash goes to: the one holding it, or
+// else the one with fewer entries.
+func (g *TwoChoiceSplitMap) target(hash chainhash.Hash, first, second *SwissMapUint64) *SwissMapUint64 {
+	if holder := holderOf(hash, first, second); holder != nil {
+		return holder
+	}
+
+	if second.length.Load() < first.length.Load() {
+		return second
+	}
+
+	return first
+}
+
+// write calls f with the candidate buckets of hash while holding both their
+// write locks, taken in index order. It returns ErrMapFrozen if either bucket
+// is frozen once the locks are held, so that a write never lands in a bucket
+// frozen while it waited.
+func (g *TwoChoiceSplitMap) write(hash chainhash.Hash, f func(first, second *SwissMapUint64) error) error {
+	i, j := g.candidates(hash)
+
+	first, err := bucketAt(g.m, i)
+	if err != nil {
+		return err
+	}
+
+	second, err := bucketAt(g.m, j)
+	if err != nil {
+		return err
+	}
+
+	lo, hi := first, second
+	if j < i {
+		lo, hi = second, first
+	}
+
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+
+	if hi != lo {
+		hi.mu.Lock()
+		defer hi.mu.Unlock()
+	}
+
+	if first.frozen.Load() || second.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	return f(first, second)
+}
+
+// holderOf returns the bucket holding hash, or nil. The caller must hold the
+// locks of both buckets.
+func holderOf(hash chainhash.Hash, first, second *SwissMapUint64) *SwissMapUint64 {
+	if first.m.Has(hash) {
+		return first
+	}
+
+	if second.m.Has(hash) {
+		return second
+	}
+
+	return nil
+}
+
+// Stats returns how the entries are spread over the buckets.
+func (g *SplitSwissMap) Stats() BucketStats { return splitBucketStats(g.m, g.nrOfBuckets) }
+
+// Stats returns how the entries are spread over the buckets.
+func (g *SplitSwissMapUint64) Stats() BucketStats { return splitBucketStats(g.m, g.nrOfBuckets) }
+
+// Stats returns how the entries are spread over the buckets.
+func (g *NativeSplitMap) Stats() BucketStats { return splitBucketStats(g.m, g.nrOfBuckets) }
+
+// Stats returns how the entries are spread over the buckets.
+func (g *NativeSplitMapUint64) Stats() BucketStats { return splitBucketStats(g.m, g.nrOfBuckets) }
+
+// splitBucketStats computes the BucketStats of buckets 0..nrOfBuckets-1.
+func splitBucketStats[M interface{ Length() int }](buckets []M, nrOfBuckets uint16) BucketStats {
+	stats := BucketStats{Buckets: int(nrOfBuckets), Min: math.MaxInt}
+
+	lengths := make([]int, nrOfBuckets)
+
+	for i := range lengths {
+		lengths[i] = buckets[uint16(i)].Length() //nolint:gosec // G115 i is below nrOfBuckets
+		stats.Entries += lengths[i]
+		stats.Min = min(stats.Min, lengths[i])
+		stats.Max = max(stats.Max, lengths[i])
+	}
+
+	if len(lengths) == 0 {
+		stats.Min = 0
+		return stats
+	}
+
+	stats.Mean = float64(stats.Entries) / float64(len(lengths))
+
+	variance := 0.0
+	for _, l := range lengths {
+		variance += (float64(l) - stats.Mean) * (float64(l) - stats.Mean)
+	}
+
+	stats.StdDev = math.Sqrt(variance / float64(len(lengths)))
+
+	if stats.Mean > 0 {
+		stats.Skew = float64(stats.Max) / stats.Mean
+	}
+
+	return stats
+}
@@ -0,0 +1,112 @@
+package txmap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// skewedHashes returns n random hashes of which every other one starts with
+// one of four prefixes.
+func skewedHashes(n int) []chainhash.Hash {
+	r := rand.New(rand.NewSource(7)) //nolint:gosec // deterministic test data
+	hashes := make([]chainhash.Hash, n)
+
+	for i := range hashes {
+		hashes[i] = randomHash(r)
+
+		if i%2 == 0 {
+			hashes[i][0], hashes[i][1] = 0xab, byte(i%4)
+		}
+	}
+
+	return hashes
+}
+
+// TestTwoChoiceSplitMapSkew tests that two-choice placement evens out the
+// buckets that prefix routing overloads.
+func TestTwoChoiceSplitMapSkew(t *testing.T) {
+	hashes := skewedHashes(50_000)
+
+	prefix := NewSplitSwissMapUint64(0, 64)
+	twoChoice := NewTwoChoiceSplitMap(0, 64)
+
+	require.NoError(t, prefix.PutMulti(hashes, 1))
+	require.NoError(t, twoChoice.PutMulti(hashes, 1))
+
+	ps, ts := prefix.Stats(), twoChoice.Stats()
+	t.Logf("prefix: %s", ps)
+	t.Logf("two-choice: %s", ts)
+
+	assert.Equal(t, 50_000, ts.Entries)
+	assert.Equal(t, 64, ts.Buckets)
+	assert.Greater(t, ps.Skew, 5.0)
+	assert.Less(t, ts.Skew, 1.5)
+}
+
+// TestTwoChoiceSplitMap tests the TxMap operations across both candidates.
+func TestTwoChoiceSplitMap(t *testing.T) {
+	hashes := skewedHashes(2000)
+	g := NewTwoChoiceSplitMap(0, 16)
+
+	require.NoError(t, g.PutMulti(hashes, 1))
+	require.ErrorIs(t, g.Put(hashes[10], 2), ErrHashAlreadyExists)
+	assert.Equal(t, 2000, g.Length())
+	assert.Len(t, g.Keys(), 2000)
+
+	for i, hash := range hashes {
+		require.NoError(t, g.Set(hash, uint64(i)))
+	}
+
+	for i, hash := range hashes {
+		value, ok := g.Get(hash)
+		require.True(t, ok)
+		require.Equal(t, uint64(i), value)
+	}
+
+	set, err := g.SetIfNotExists(hashes[0], 9)
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	for _, hash := range hashes[:1000] {
+		require.NoError(t, g.Delete(hash))
+	}
+
+	require.ErrorIs(t, g.Delete(hashes[0]), ErrHashDoesNotExist)
+	assert.False(t, g.Exists(hashes[0]))
+	assert.Equal(t, 1000, g.Length())
+
+	set, err = g.SetIfExists(hashes[0], 9)
+	require.NoError(t, err)
+	assert.False(t, set)
+
+	g.Freeze()
+	require.ErrorIs(t, g.Put(hashes[0], 1), ErrMapFrozen)
+
+	g.Clear()
+	assert.Equal(t, 0, g.Length())
+	assert.Equal(t, BucketStats{Buckets: 16}, g.Stats())
+}
+
+// TestTwoChoiceSplitMapFrozenSecondBucket tests that a write fails when only
+// the second candidate bucket of its hash is frozen.
+func TestTwoChoiceSplitMapFrozenSecondBucket(t *testing.T) {
+	g := NewTwoChoiceSplitMap(0, 16)
+
+	hash := hashN(0)
+	for i := 1; ; i++ {
+		if first, second := g.candidates(hash); first != second {
+			g.m[second].Freeze()
+			break
+		}
+
+		hash = hashN(i)
+	}
+
+	require.ErrorIs(t, g.Put(hash, 1), ErrMapFrozen)
+	require.ErrorIs(t, g.Delete(hash), ErrMapFrozen)
+	assert.Equal(t, 0, g.Length())
+}
@@ -56,26 +56,34 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"sync"
 	"sync/atomic"
 
 	"github.com/bsv-blockchain/go-bt/v2/chainhash"
 	"github.com/dolthub/swiss"
 )
 
-// TxMap is a map that stores transaction hashes and associated uint64 values.
-type TxMap interface {
-	Delete(hash chainhash.Hash) error
+// ReadOnlyTxMap is the read side of a TxMap: it stores transaction hashes and
+// associated uint64 values but offers no way to change them. APIs that only
+// query a map should accept ReadOnlyTxMap, so the type system rules out
+// accidental writes; FreezeReadOnly returns one that is also lock-free.
+type ReadOnlyTxMap interface {
 	Exists(hash chainhash.Hash) bool
 	Get(hash chainhash.Hash) (uint64, bool)
 	Keys() []chainhash.Hash
 	Length() int
+	Iter(f func(hash chainhash.Hash, value uint64) bool)
+}
+
+// MutableTxMap extends ReadOnlyTxMap with the methods that modify the map.
+type MutableTxMap interface {
+	ReadOnlyTxMap
+
+	Delete(hash chainhash.Hash) error
 	Put(hash chainhash.Hash, value uint64) error
 	PutMulti(hashes []chainhash.Hash, value uint64) error
 	Set(hash chainhash.Hash, value uint64) error
 	SetIfExists(hash chainhash.Hash, value uint64) (bool, error)
 	SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error)
-	Iter(f func(hash chainhash.Hash, value uint64) bool)
 
 	// Freeze marks the map read-only. After Freeze, read methods skip locking
 	// (on lock-based implementations) and every write method returns ErrMapFrozen.
@@ -87,6 +95,11 @@ type TxMap interface {
 	Clear()
 }
 
+// TxMap is a map that stores transaction hashes and associated uint64 values.
+// It is the full read-write interface, kept under its original name; every
+// TxMap is also a ReadOnlyTxMap.
+type TxMap = MutableTxMap
+
 // Uint64 is a map that stores uint64's and associated uint64 value.
 type Uint64 interface {
 	Exists(hash uint64) bool
@@ -121,10 +134,11 @@ type TxHashMap interface {
 
 // SwissMap is a simple concurrent-safe map that uses the swiss package
 type SwissMap struct {
-	mu     sync.RWMutex
-	m      *swiss.Map[chainhash.Hash, struct{}]
-	length int
-	frozen atomic.Bool
+	mu         mapLock
+	m          *swiss.Map[chainhash.Hash, struct{}]
+	length     atomic.Int64
+	frozen     atomic.Bool
+	duplicates duplicateHandler[chainhash.Hash]
 }
 
 var (
@@ -161,7 +175,8 @@ const (
 // Considerations: The length is not enforced, and the map can grow beyond this size.
 func NewSwissMap(length uint32) *SwissMap {
 	return &SwissMap{
-		m: swiss.NewMap[chainhash.Hash, struct{}](length),
+		m:          swiss.NewMap[chainhash.Hash, struct{}](length),
+		duplicates: duplicateHandler[chainhash.Hash]{policy: DuplicateIgnore},
 	}
 }
 
@@ -204,13 +219,14 @@ func (s *SwissMap) Get(hash chainhash.Hash) (uint64, bool) {
 	return 0, ok
 }
 
-// Put adds a new hash to the map. It increments the length of the map.
+// Put adds a new hash to the map. It increments the length of the map if the hash is new;
+// an existing hash is handled by the duplicate policy (DuplicateIgnore by default).
 //
 // Params:
 //   - hash: The hash to add to the map.
 //
 // Returns:
-//   - error: always returns nil, as this map does not have any constraints on adding hashes.
+//   - error: nil unless the duplicate policy rejects an existing hash.
 func (s *SwissMap) Put(hash chainhash.Hash) error {
 	if s.frozen.Load() {
 		return ErrMapFrozen
@@ -219,20 +235,17 @@ func (s *SwissMap) Put(hash chainhash.Hash) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.length++
-
-	s.m.Put(hash, struct{}{})
-
-	return nil
+	return s.putUnlocked(hash)
 }
 
-// PutMulti adds multiple hashes to the map. It increments the length of the map for each hash added.
+// PutMulti adds multiple hashes to the map. It increments the length of the map for each new hash;
+// existing hashes are handled by the duplicate policy (DuplicateIgnore by default).
 //
 // Params:
 //   - hashes: A slice of hashes to add to the map.
 //
 // Returns:
-//   - error: always returns nil, as this map does not have any constraints on adding hashes.
+//   - error: nil unless the duplicate policy rejects an existing hash.
 func (s *SwissMap) PutMulti(hashes []chainhash.Hash) error {
 	if s.frozen.Load() {
 		return ErrMapFrozen
@@ -242,15 +255,31 @@ func (s *SwissMap) PutMulti(hashes []chainhash.Hash) error {
 	defer s.mu.Unlock()
 
 	for _, hash := range hashes {
-		s.m.Put(hash, struct{}{})
+		if err := s.putUnlocked(hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-		s.length++
+// putUnlocked adds hash, applying the duplicate policy if it already exists.
+// The caller must hold the write lock.
+func (s *SwissMap) putUnlocked(hash chainhash.Hash) error {
+	debugAssertHash(hash)
+
+	if s.m.Has(hash) {
+		_, _, err := s.duplicates.resolve(hash, 0, 0)
+		return err
 	}
 
+	s.m.Put(hash, struct{}{})
+	debugAssertLength(s.length.Add(1))
+
 	return nil
 }
 
-// Delete removes a hash from the map. It decrements the length of the map.
+// Delete removes a hash from the map, if present, decrementing its length.
 //
 // Params:
 //   - hash: The hash to remove from the map.
@@ -265,10 +294,14 @@ func (s *SwissMap) Delete(hash chainhash.Hash) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.length--
+	if !s.m.Has(hash) {
+		return nil
+	}
 
 	s.m.Delete(hash)
 
+	debugAssertLength(s.length.Add(-1))
+
 	return nil
 }
 
@@ -282,7 +315,7 @@ func (s *SwissMap) Length() int {
 		defer s.mu.RUnlock()
 	}
 
-	return s.length
+	return int(s.length.Load())
 }
 
 // Clear empties the map without releasing the underlying group/ctrl backing
@@ -293,7 +326,7 @@ func (s *SwissMap) Clear() {
 	defer s.mu.Unlock()
 
 	s.m.Clear()
-	s.length = 0
+	s.length.Store(0)
 	s.frozen.Store(false)
 }
 
@@ -309,7 +342,7 @@ func (s *SwissMap) Keys() []chainhash.Hash {
 		defer s.mu.RUnlock()
 	}
 
-	keys := make([]chainhash.Hash, 0, s.length)
+	keys := make([]chainhash.Hash, 0, s.length.Load())
 
 	s.m.Iter(func(k chainhash.Hash, _ struct{}) (stop bool) {
 		keys = append(keys, k)
@@ -346,10 +379,12 @@ var _ TxMap = (*SwissMapUint64)(nil)
 // SwissMapUint64 is a concurrent-safe map that uses the swiss package to store
 // transaction hashes as keys and uint64 values.
 type SwissMapUint64 struct {
-	mu     sync.RWMutex
-	m      *swiss.Map[chainhash.Hash, uint64]
-	length int
-	frozen atomic.Bool
+	mu         mapLock
+	m          *swiss.Map[chainhash.Hash, uint64]
+	length     atomic.Int64
+	frozen     atomic.Bool
+	epoch      atomic.Uint64
+	duplicates duplicateHandler[chainhash.Hash]
 }
 
 // NewSwissMapUint64 creates a new SwissMapUint64 with the specified initial length.
@@ -367,14 +402,33 @@ func NewSwissMapUint64(length uint32) *SwissMapUint64 {
 	}
 }
 
-// Map returns the underlying swiss map used by SwissMapUint64.
+// UnsafeMap returns the underlying swiss map used by SwissMapUint64.
 //
 // Returns:
 //   - *swiss.Map[chainhash.Hash, uint64]: A pointer to the underlying swiss map.
-func (s *SwissMapUint64) Map() *swiss.Map[chainhash.Hash, uint64] {
+//
+// Considerations: The map is returned without the lock, so any access to it
+// races concurrent writes to SwissMapUint64. Use WithMap unless the map is
+// frozen or not shared.
+func (s *SwissMapUint64) UnsafeMap() *swiss.Map[chainhash.Hash, uint64] {
 	return s.m
 }
 
+// WithMap calls fn with the underlying swiss map while holding the read lock,
+// or without it if the map is frozen. fn must not modify the map, nor keep it
+// after returning, and must not call methods of SwissMapUint64 that write.
+//
+// Params:
+//   - fn: The function to call with the underlying swiss map.
+func (s *SwissMapUint64) WithMap(fn func(m *swiss.Map[chainhash.Hash, uint64])) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	fn(s.m)
+}
+
 // Exists checks if the given hash exists in the map.
 // It returns true if the hash is found, false otherwise.
 //
@@ -395,8 +449,8 @@ func (s *SwissMapUint64) Exists(hash chainhash.Hash) bool {
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
-// It checks if the hash already exists in the map and returns an error if it does.
-// If the hash does not exist, it adds the hash and increments the length of the map.
+// If the hash already exists, the duplicate policy decides the outcome; by default
+// (DuplicateError) it returns an error. Otherwise it adds the hash and increments the length of the map.
 //
 // Params:
 //   - hash: The hash to add to the map.
@@ -412,21 +466,12 @@ func (s *SwissMapUint64) Put(hash chainhash.Hash, n uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	exists := s.m.Has(hash)
-	if exists {
-		return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
-	}
-
-	s.m.Put(hash, n)
-
-	s.length++
-
-	return nil
+	return s.putUnlocked(hash, n)
 }
 
 // PutMulti adds multiple hashes with an associated uint64 value to the map.
-// It checks if any of the hashes already exist in the map and returns an error if any do.
-// If none of the hashes exist, it adds each hash with the value and increments the length of the map.
+// Hashes that already exist are handled by the duplicate policy; by default (DuplicateError)
+// the first one stops the call with an error. New hashes are added and increment the length of the map.
 //
 // Params:
 //   - hashes: A slice of hashes to add to the map.
@@ -443,17 +488,33 @@ func (s *SwissMapUint64) PutMulti(hashes []chainhash.Hash, n uint64) error {
 	defer s.mu.Unlock()
 
 	for _, hash := range hashes {
-		exists := s.m.Has(hash)
-		if exists {
-			return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+		if err := s.putUnlocked(hash, n); err != nil {
+			return err
 		}
+	}
 
+	return nil
+}
+
+// putUnlocked adds hash with value n, applying the duplicate policy if it
+// already exists. The caller must hold the write lock.
+func (s *SwissMapUint64) putUnlocked(hash chainhash.Hash, n uint64) error {
+	debugAssertHash(hash)
+
+	existing, exists := s.m.Get(hash)
+	if !exists {
 		s.m.Put(hash, n)
+		debugAssertLength(s.length.Add(1))
 
-		s.length++
+		return nil
 	}
 
-	return nil
+	value, store, err := s.duplicates.resolve(hash, existing, n)
+	if store {
+		s.m.Put(hash, value)
+	}
+
+	return err
 }
 
 // Set updates the value associated with the given hash in the map.
@@ -533,9 +594,10 @@ func (s *SwissMapUint64) SetIfNotExists(hash chainhash.Hash, value uint64) (bool
 		return false, nil
 	}
 
+	debugAssertHash(hash)
 	s.m.Put(hash, value)
 
-	s.length++
+	debugAssertLength(s.length.Add(1))
 
 	return true, nil
 }
@@ -575,7 +637,7 @@ func (s *SwissMapUint64) Length() int {
 		defer s.mu.RUnlock()
 	}
 
-	return s.length
+	return int(s.length.Load())
 }
 
 // Clear empties the map without releasing the underlying group/ctrl backing
@@ -594,8 +656,9 @@ func (s *SwissMapUint64) Clear() {
 	defer s.mu.Unlock()
 
 	s.m.Clear()
-	s.length = 0
+	s.length.Store(0)
 	s.frozen.Store(false)
+	s.epoch.Add(1)
 }
 
 // Keys returns a slice of all hashes currently stored in the map.
@@ -610,7 +673,7 @@ func (s *SwissMapUint64) Keys() []chainhash.Hash {
 		defer s.mu.RUnlock()
 	}
 
-	keys := make([]chainhash.Hash, 0, s.length)
+	keys := make([]chainhash.Hash, 0, s.length.Load())
 
 	s.m.Iter(func(k chainhash.Hash, _ uint64) (stop bool) {
 		keys = append(keys, k)
@@ -659,16 +722,17 @@ func (s *SwissMapUint64) Delete(hash chainhash.Hash) error {
 
 	s.m.Delete(hash)
 
-	s.length--
+	debugAssertLength(s.length.Add(-1))
 
 	return nil
 }
 
 // SwissLockFreeMapUint64 is a lock-free map for uint64 keys and values
 type SwissLockFreeMapUint64 struct {
-	m      *swiss.Map[uint64, uint64]
-	length atomic.Uint32
-	frozen atomic.Bool
+	m          *swiss.Map[uint64, uint64]
+	length     atomic.Uint32
+	frozen     atomic.Bool
+	duplicates duplicateHandler[uint64]
 }
 
 // NewSwissLockFreeMapUint64 creates a new SwissLockFreeMapUint64 with the specified initial length.
@@ -720,15 +784,15 @@ func (s *SwissLockFreeMapUint64) Exists(hash uint64) bool {
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
-// It checks if the hash already exists in the map and returns an error if it does.
-// If the hash does not exist, it adds the hash and increments the length of the map.
+// If the hash already exists, the duplicate policy decides the outcome; by default
+// (DuplicateError) it returns an error. Otherwise it adds the hash and increments the length of the map.
 //
 // Params:
 //   - hash: The hash to add to the map.
 //   - n: The uint64 value to associate with the hash.
 //
 // Returns:
-//   - error: An error if the hash already exists in the map, nil otherwise.
+//   - error: An error if the hash already exists and the duplicate policy rejects it, nil otherwise.
 //
 // Considerations: This method does not lock the map, so it is not suitable for concurrent access.
 func (s *SwissLockFreeMapUint64) Put(hash, n uint64) error {
@@ -736,15 +800,20 @@ func (s *SwissLockFreeMapUint64) Put(hash, n uint64) error {
 		return ErrMapFrozen
 	}
 
-	exists := s.m.Has(hash)
-	if exists {
-		return ErrHashAlreadyExists
+	existing, exists := s.m.Get(hash)
+	if !exists {
+		s.m.Put(hash, n)
+		s.length.Add(1)
+
+		return nil
 	}
 
-	s.m.Put(hash, n)
-	s.length.Add(1)
+	value, store, err := s.duplicates.resolve(hash, existing, n)
+	if store {
+		s.m.Put(hash, value)
+	}
 
-	return nil
+	return err
 }
 
 // Get retrieves the uint64 value associated with the given hash from the map.
@@ -783,8 +852,9 @@ var _ TxMap = (*SplitSwissMap)(nil)
 // It uses SwissMapUint64 for each bucket to store the hashes and their associated uint64 values.
 // Since SwissMapUint64 is concurrent-safe, SplitSwissMap can handle concurrent access without additional locks.
 type SplitSwissMap struct {
-	m           map[uint16]*SwissMapUint64
+	m           []*SwissMapUint64
 	nrOfBuckets uint16
+	epoch       atomic.Uint64
 }
 
 // NewSplitSwissMap creates a new SplitSwissMap with the specified initial length.
@@ -805,7 +875,7 @@ func NewSplitSwissMap(length int, buckets ...uint16) *SplitSwissMap {
 	}
 
 	m := &SplitSwissMap{
-		m:           make(map[uint16]*SwissMapUint64, useBuckets),
+		m:           make([]*SwissMapUint64, int(useBuckets)+1),
 		nrOfBuckets: useBuckets,
 	}
 
@@ -857,7 +927,15 @@ func (g *SplitSwissMap) Get(hash chainhash.Hash) (uint64, bool) {
 // Returns:
 //   - error: An error if the hash already exists in the map, nil otherwise.
 func (g *SplitSwissMap) Put(hash chainhash.Hash, n uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n)
+	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
+
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash, n)
 }
 
 // PutMulti adds multiple hashes with an associated uint64 value to the map.
@@ -873,7 +951,7 @@ func (g *SplitSwissMap) Put(hash chainhash.Hash, n uint64) error {
 //   - error: An error if any of the hashes already exist in the map, nil otherwise.
 func (g *SplitSwissMap) PutMulti(hashes []chainhash.Hash, n uint64) (err error) {
 	for _, hash := range hashes {
-		if err = g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n); err != nil {
+		if err = g.Put(hash, n); err != nil {
 			return fmt.Errorf("failed to put multi in bucket %d: %w", Bytes2Uint16Buckets(hash, g.nrOfBuckets), err)
 		}
 	}
@@ -892,11 +970,12 @@ func (g *SplitSwissMap) PutMulti(hashes []chainhash.Hash, n uint64) (err error)
 // Returns:
 //   - error: An error if the bucket does not exist or if there is an issue adding the hashes, nil otherwise.
 func (g *SplitSwissMap) PutMultiBucket(bucket uint16, hashes []chainhash.Hash, n uint64) error {
-	if bucket > g.nrOfBuckets {
-		return fmt.Errorf("%w: %d, max bucket is %d", ErrBucketDoesNotExist, bucket, g.nrOfBuckets)
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
 	}
 
-	return g.m[bucket].PutMulti(hashes, n)
+	return b.PutMulti(hashes, n)
 }
 
 // Set updates the value associated with the given hash in the map.
@@ -908,7 +987,12 @@ func (g *SplitSwissMap) PutMultiBucket(bucket uint16, hashes []chainhash.Hash, n
 // Returns:
 //   - error: An error if the hash does not exist in the map, nil otherwise.
 func (g *SplitSwissMap) Set(hash chainhash.Hash, value uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Set(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Set(hash, value)
 }
 
 // SetIfExists updates the value associated with the given hash in the map if it exists.
@@ -923,7 +1007,12 @@ func (g *SplitSwissMap) Set(hash chainhash.Hash, value uint64) error {
 //   - bool: True if the hash was found and updated, false otherwise.
 //   - error: An error if there was an issue updating the hash, nil otherwise.
 func (g *SplitSwissMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfExists(hash, value)
 }
 
 // SetIfNotExists adds the hash with the given value to the map only if the hash does not already exist.
@@ -938,7 +1027,12 @@ func (g *SplitSwissMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, er
 //   - bool: True if the hash was added, false if it already existed.
 //   - error: An error if there was an issue adding the hash, nil otherwise.
 func (g *SplitSwissMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfNotExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfNotExists(hash, value)
 }
 
 // Keys returns a slice of all hashes currently stored in the map.
@@ -982,33 +1076,18 @@ func (g *SplitSwissMap) Length() int {
 //   - error: An error if the hash does not exist in the map or if the bucket does not exist, nil otherwise.
 func (g *SplitSwissMap) Delete(hash chainhash.Hash) error {
 	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
 
-	if _, ok := g.m[bucket]; !ok {
-		return fmt.Errorf("%w: %d", ErrBucketDoesNotExist, bucket)
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
 	}
 
-	if !g.m[bucket].Exists(hash) {
+	if !b.Exists(hash) {
 		return fmt.Errorf("%w in bucket %d: %s", ErrHashDoesNotExist, bucket, hash)
 	}
 
-	return g.m[bucket].Delete(hash)
-}
-
-// Map returns the underlying map of all buckets used by SplitSwissMap.
-//
-// Returns:
-//   - TxMap: A map where the keys are bucket indices and the values are pointers to SwissMapUint64 instances.
-func (g *SplitSwissMap) Map() *SwissMapUint64 {
-	m := NewSwissMapUint64(uint32(g.Length())) //nolint:gosec // integer overflow conversion int -> uint32
-	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		keys := g.m[i].Keys()
-		for _, key := range keys {
-			val, _ := g.m[i].Get(key)
-			_ = m.Put(key, val)
-		}
-	}
-
-	return m
+	return b.Delete(hash)
 }
 
 // Iter iterates over all key-value pairs in the map and applies the provided function to each pair.
@@ -1017,8 +1096,13 @@ func (g *SplitSwissMap) Map() *SwissMapUint64 {
 // Params:
 //   - f: A function that takes a hash and its associated uint64 value.
 func (g *SplitSwissMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
-	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		g.m[i].Iter(f)
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
 	}
 }
 
@@ -1029,8 +1113,9 @@ var _ TxMap = (*SplitSwissMapUint64)(nil)
 // It uses SwissMapUint64 for each bucket to store the hashes and their associated uint64 values.
 // The number of buckets is fixed at 1024, and the length is divided by this number to determine the size of each bucket.
 type SplitSwissMapUint64 struct {
-	m           map[uint16]*SwissMapUint64
+	m           []*SwissMapUint64
 	nrOfBuckets uint16
+	epoch       atomic.Uint64
 }
 
 // NewSplitSwissMapUint64 creates a new SplitSwissMapUint64 with the specified initial length.
@@ -1051,7 +1136,7 @@ func NewSplitSwissMapUint64(length uint32, buckets ...uint16) *SplitSwissMapUint
 	}
 
 	m := &SplitSwissMapUint64{
-		m:           make(map[uint16]*SwissMapUint64, useBuckets),
+		m:           make([]*SwissMapUint64, int(useBuckets)+1),
 		nrOfBuckets: useBuckets,
 	}
 
@@ -1080,12 +1165,38 @@ func (g *SplitSwissMapUint64) Exists(hash chainhash.Hash) bool {
 	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Exists(hash)
 }
 
-// Map returns the underlying map of buckets used by SplitSwissMapUint64.
+// Map returns a new map of the buckets used by SplitSwissMapUint64. Changing
+// the map does not change the buckets of the SplitSwissMapUint64; see the notes
+// at the top of buckets.go.
 //
 // Returns:
 //   - map[uint16]*SwissMapUint64: A map where the keys are bucket indices and the values are pointers to SwissMapUint64 instances.
 func (g *SplitSwissMapUint64) Map() map[uint16]*SwissMapUint64 {
-	return g.m
+	return bucketsMap[uint16](g.m)
+}
+
+// Buckets returns the number of buckets in the SplitSwissMapUint64.
+func (g *SplitSwissMapUint64) Buckets() uint16 {
+	return g.nrOfBuckets
+}
+
+// PutMultiBucket adds multiple hashes with an associated uint64 value to a specific bucket.
+// It checks if the bucket exists and then adds the hashes directly to that bucket.
+//
+// Params:
+//   - bucket: The bucket index to add the hashes to.
+//   - hashes: A slice of hashes to add to the specified bucket.
+//   - n: The uint64 value to associate with each hash.
+//
+// Returns:
+//   - error: An error if the bucket does not exist or if there is an issue adding the hashes, nil otherwise.
+func (g *SplitSwissMapUint64) PutMultiBucket(bucket uint16, hashes []chainhash.Hash, n uint64) error {
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
+	}
+
+	return b.PutMulti(hashes, n)
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
@@ -1099,7 +1210,15 @@ func (g *SplitSwissMapUint64) Map() map[uint16]*SwissMapUint64 {
 // Returns:
 //   - error: An error if the hash already exists in the map, nil otherwise.
 func (g *SplitSwissMapUint64) Put(hash chainhash.Hash, n uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n)
+	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
+
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash, n)
 }
 
 // PutMulti adds multiple hashes with an associated uint64 value to the map.
@@ -1115,7 +1234,7 @@ func (g *SplitSwissMapUint64) Put(hash chainhash.Hash, n uint64) error {
 //   - error: An error if any of the hashes already exist in the map, nil otherwise.
 func (g *SplitSwissMapUint64) PutMulti(hashes []chainhash.Hash, n uint64) error {
 	for _, hash := range hashes {
-		if err := g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n); err != nil {
+		if err := g.Put(hash, n); err != nil {
 			return fmt.Errorf("failed to put multi in bucket %d: %w", Bytes2Uint16Buckets(hash, g.nrOfBuckets), err)
 		}
 	}
@@ -1133,7 +1252,12 @@ func (g *SplitSwissMapUint64) PutMulti(hashes []chainhash.Hash, n uint64) error
 // Returns:
 //   - error: An error if the hash does not exist in the map, nil otherwise.
 func (g *SplitSwissMapUint64) Set(hash chainhash.Hash, value uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Set(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Set(hash, value)
 }
 
 // SetIfExists updates the value associated with the given hash in the map if it exists.
@@ -1148,7 +1272,12 @@ func (g *SplitSwissMapUint64) Set(hash chainhash.Hash, value uint64) error {
 //   - bool: True if the hash was found and updated, false otherwise.
 //   - error: An error if there was an issue updating the hash, nil otherwise.
 func (g *SplitSwissMapUint64) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfExists(hash, value)
 }
 
 // SetIfNotExists adds the hash with the given value to the map only if the hash does not already exist.
@@ -1163,7 +1292,12 @@ func (g *SplitSwissMapUint64) SetIfExists(hash chainhash.Hash, value uint64) (bo
 //   - bool: True if the hash was added, false if it already existed.
 //   - error: An error if there was an issue adding the hash, nil otherwise.
 func (g *SplitSwissMapUint64) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfNotExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfNotExists(hash, value)
 }
 
 // Get retrieves the uint64 value associated with the given hash from the map.
@@ -1185,8 +1319,13 @@ func (g *SplitSwissMapUint64) Get(hash chainhash.Hash) (uint64, bool) {
 // Params:
 //   - f: A function that takes a hash and its associated uint64 value.
 func (g *SplitSwissMapUint64) Iter(f func(hash chainhash.Hash, value uint64) bool) {
-	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		g.m[i].Iter(f)
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
 	}
 }
 
@@ -1198,7 +1337,7 @@ func (g *SplitSwissMapUint64) Iter(f func(hash chainhash.Hash, value uint64) boo
 func (g *SplitSwissMapUint64) Length() int {
 	length := 0
 	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		length += g.m[i].length
+		length += int(g.m[i].length.Load())
 	}
 
 	return length
@@ -1216,6 +1355,8 @@ func (g *SplitSwissMapUint64) Clear() {
 	for i := uint16(0); i <= g.nrOfBuckets; i++ {
 		g.m[i].Clear()
 	}
+
+	g.epoch.Add(1)
 }
 
 // Delete removes a hash from the map.
@@ -1229,22 +1370,24 @@ func (g *SplitSwissMapUint64) Clear() {
 //   - error: An error if the hash does not exist in the map or if the bucket does not exist, nil otherwise.
 func (g *SplitSwissMapUint64) Delete(hash chainhash.Hash) error {
 	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
 
-	if _, ok := g.m[bucket]; !ok {
-		return fmt.Errorf("%w: %d", ErrBucketDoesNotExist, bucket)
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
 	}
 
-	if !g.m[bucket].Exists(hash) {
+	if !b.Exists(hash) {
 		return fmt.Errorf("%w in bucket %d: %s", ErrHashDoesNotExist, bucket, hash)
 	}
 
-	return g.m[bucket].Delete(hash)
+	return b.Delete(hash)
 }
 
 // SplitSwissLockFreeMapUint64 is a map that splits the data into multiple buckets to reduce contention.
 // It uses SwissLockFreeMapUint64 for each bucket to store the hashes and their associated uint64 values.
 type SplitSwissLockFreeMapUint64 struct {
-	m           map[uint64]*SwissLockFreeMapUint64
+	m           []*SwissLockFreeMapUint64
 	nrOfBuckets uint64
 }
 
@@ -1265,7 +1408,7 @@ func newSplitSwissLockFreeMapUint64(length int, buckets ...uint64) *SplitSwissLo
 	}
 
 	m := &SplitSwissLockFreeMapUint64{
-		m:           make(map[uint64]*SwissLockFreeMapUint64, useBuckets),
+		m:           make([]*SwissLockFreeMapUint64, useBuckets+1),
 		nrOfBuckets: useBuckets,
 	}
 
@@ -1290,15 +1433,17 @@ func (g *SplitSwissLockFreeMapUint64) Exists(hash uint64) bool {
 	return g.m[hash%g.nrOfBuckets].Exists(hash)
 }
 
-// Map returns the underlying map of buckets used by SplitSwissLockFreeMapUint64.
-// It provides access to the map for operations that do not require locking.
+// Map returns a new map of the buckets used by SplitSwissLockFreeMapUint64,
+// for operations that do not require locking. Changing the map does not change
+// the buckets of the SplitSwissLockFreeMapUint64; see the notes at the top of
+// buckets.go.
 //
 // Returns:
 //   - map[uint64]*SwissLockFreeMapUint64: A map where the keys are bucket indices and the values are pointers to SwissLockFreeMapUint64 instances.
 //
 // Considerations: This method does not lock the map, so it is not suitable for concurrent access.
 func (g *SplitSwissLockFreeMapUint64) Map() map[uint64]*SwissLockFreeMapUint64 {
-	return g.m
+	return bucketsMap[uint64](g.m)
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
@@ -1310,11 +1455,16 @@ func (g *SplitSwissLockFreeMapUint64) Map() map[uint64]*SwissLockFreeMapUint64 {
 //   - n: The uint64 value to associate with the hash.
 //
 // Returns:
-//   - error: An error if the hash already exists in the map, nil otherwise.
+//   - error: An error if the hash already exists and the duplicate policy rejects it, nil otherwise.
 //
 // Considerations: This method does not lock the map, so it is not suitable for concurrent access.
 func (g *SplitSwissLockFreeMapUint64) Put(hash, n uint64) error {
-	return g.m[hash%g.nrOfBuckets].Put(hash, n)
+	b, err := bucketAt(g.m, hash%g.nrOfBuckets)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash, n)
 }
 
 // Get retrieves the uint64 value associated with the given hash from the map.
@@ -1374,9 +1524,13 @@ func (g *SplitSwissLockFreeMapUint64) IterAll(f func(k, v uint64) (stop bool)) {
 	}
 }
 
-// DeleteBucket removes the bucket at index h from the map of buckets.
+// DeleteBucket discards the contents of bucket h, replacing it with an empty
+// bucket so that the map stays usable. A bucket number out of range is
+// ignored.
 func (g *SplitSwissLockFreeMapUint64) DeleteBucket(h uint64) {
-	delete(g.m, h)
+	if h <= g.nrOfBuckets {
+		g.m[h] = NewSwissLockFreeMapUint64(0)
+	}
 }
 
 // Bytes2Uint16Buckets converts the first two bytes of a chainhash.Hash to a uint16 value
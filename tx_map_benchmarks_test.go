@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"sync"
 	"testing"
 
@@ -205,6 +206,85 @@ func formatBytes(b uint64) string {
 	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "KMGTPE"[exp])
 }
 
+// heapUsage holds the growth of the heap caused by loading a map.
+type heapUsage struct {
+	alloc uint64
+	inuse uint64
+}
+
+// measureHeap returns the growth of HeapAlloc and HeapInuse caused by the
+// value populate returns, which is kept alive until both are read. Growth is
+// measured after a GC on both sides, so garbage created while loading is not
+// counted.
+func measureHeap(populate func() interface{}) heapUsage {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	m := populate()
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(m)
+
+	var usage heapUsage
+
+	if after.HeapAlloc > before.HeapAlloc {
+		usage.alloc = after.HeapAlloc - before.HeapAlloc
+	}
+
+	if after.HeapInuse > before.HeapInuse {
+		usage.inuse = after.HeapInuse - before.HeapInuse
+	}
+
+	return usage
+}
+
+// BenchmarkMemoryPerEntry loads N entries into every TxMap implementation and
+// reports the heap growth per entry, so that backends can be compared on
+// memory efficiency and not only on ns/op. The reported ns/op includes the
+// garbage collections around every load.
+// Run with: go test -run=^$ -bench=BenchmarkMemoryPerEntry -benchtime=3x
+func BenchmarkMemoryPerEntry(b *testing.B) {
+	impls := txMapImpls()
+	names := make([]string, 0, len(impls))
+
+	for name := range impls {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		hashes := getTestHashes(n)
+
+		for _, name := range names {
+			b.Run(fmt.Sprintf("%s/%d", name, n), func(b *testing.B) {
+				var total heapUsage
+
+				for i := 0; i < b.N; i++ {
+					usage := measureHeap(func() interface{} {
+						m := impls[name]()
+						for j, hash := range hashes {
+							_ = m.Put(hash, uint64(j)) //nolint:gosec // G115: j is non-negative
+						}
+
+						return m
+					})
+
+					total.alloc += usage.alloc
+					total.inuse += usage.inuse
+				}
+
+				entries := float64(b.N) * float64(n)
+				b.ReportMetric(float64(total.alloc)/entries, "alloc-B/entry")
+				b.ReportMetric(float64(total.inuse)/entries, "inuse-B/entry")
+			})
+		}
+	}
+}
+
 // benchCase holds a benchmark name and its run function for table-driven benchmarks.
 type benchCase struct {
 	name string
@@ -794,3 +874,37 @@ func BenchmarkSplitSwissMapUint64_NoRehash(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkLockPolicy measures a parallel 90% Get / 10% Set workload on a
+// NativeMapUint64 with every lock policy, where the critical sections are
+// short and the lock itself dominates.
+// Run with: go test -run=^$ -bench=BenchmarkLockPolicy -cpu 1,8,32
+func BenchmarkLockPolicy(b *testing.B) {
+	hashes := getTestHashes(1024)
+
+	for _, policy := range []LockPolicy{LockWriterPreferring, LockReaderPreferring, LockSpin} {
+		b.Run(policy.String(), func(b *testing.B) {
+			m := NewNativeMapUint64(1024)
+			if err := m.SetLockPolicy(policy); err != nil {
+				b.Fatal(err)
+			}
+
+			for i, hash := range hashes {
+				_ = m.Put(hash, uint64(i)) //nolint:gosec // G115: i is non-negative
+			}
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					hash := hashes[i%len(hashes)]
+					if i%10 == 0 {
+						_ = m.Set(hash, uint64(i)) //nolint:gosec // G115: i is non-negative
+					} else {
+						_, _ = m.Get(hash)
+					}
+				}
+			})
+		})
+	}
+}
@@ -9,7 +9,6 @@ package txmap
 import (
 	"fmt"
 	"math"
-	"sync"
 	"sync/atomic"
 
 	"github.com/bsv-blockchain/go-bt/v2/chainhash"
@@ -20,10 +19,11 @@ type Map = NativeMap
 
 // NativeMap is a simple concurrent-safe map that uses Go's native map
 type NativeMap struct {
-	mu     sync.RWMutex
-	m      map[chainhash.Hash]struct{}
-	length int
-	frozen atomic.Bool
+	mu         mapLock
+	m          map[chainhash.Hash]struct{}
+	length     atomic.Int64
+	frozen     atomic.Bool
+	duplicates duplicateHandler[chainhash.Hash]
 }
 
 // NewNativeMap creates a new NativeMap with the specified initial length.
@@ -39,7 +39,8 @@ type NativeMap struct {
 // Considerations: The length is not enforced, and the map can grow beyond this size.
 func NewNativeMap(length uint32) *NativeMap {
 	return &NativeMap{
-		m: make(map[chainhash.Hash]struct{}, length),
+		m:          make(map[chainhash.Hash]struct{}, length),
+		duplicates: duplicateHandler[chainhash.Hash]{policy: DuplicateIgnore},
 	}
 }
 
@@ -87,13 +88,14 @@ func (s *NativeMap) Get(hash chainhash.Hash) (uint64, bool) {
 	return 0, ok
 }
 
-// Put adds a new hash to the map. It increments the length of the map.
+// Put adds a new hash to the map. It increments the length of the map if the hash is new;
+// an existing hash is handled by the duplicate policy (DuplicateIgnore by default).
 //
 // Params:
 //   - hash: The hash to add to the map.
 //
 // Returns:
-//   - error: always returns nil, as this map does not have any constraints on adding hashes.
+//   - error: nil unless the duplicate policy rejects an existing hash.
 func (s *NativeMap) Put(hash chainhash.Hash) error {
 	if s.frozen.Load() {
 		return ErrMapFrozen
@@ -102,20 +104,17 @@ func (s *NativeMap) Put(hash chainhash.Hash) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.length++
-
-	s.m[hash] = struct{}{}
-
-	return nil
+	return s.putUnlocked(hash)
 }
 
-// PutMulti adds multiple hashes to the map. It increments the length of the map for each hash added.
+// PutMulti adds multiple hashes to the map. It increments the length of the map for each new hash;
+// existing hashes are handled by the duplicate policy (DuplicateIgnore by default).
 //
 // Params:
 //   - hashes: A slice of hashes to add to the map.
 //
 // Returns:
-//   - error: always returns nil, as this map does not have any constraints on adding hashes.
+//   - error: nil unless the duplicate policy rejects an existing hash.
 func (s *NativeMap) PutMulti(hashes []chainhash.Hash) error {
 	if s.frozen.Load() {
 		return ErrMapFrozen
@@ -125,15 +124,31 @@ func (s *NativeMap) PutMulti(hashes []chainhash.Hash) error {
 	defer s.mu.Unlock()
 
 	for _, hash := range hashes {
-		s.m[hash] = struct{}{}
+		if err := s.putUnlocked(hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-		s.length++
+// putUnlocked adds hash, applying the duplicate policy if it already exists.
+// The caller must hold the write lock.
+func (s *NativeMap) putUnlocked(hash chainhash.Hash) error {
+	debugAssertHash(hash)
+
+	if _, exists := s.m[hash]; exists {
+		_, _, err := s.duplicates.resolve(hash, 0, 0)
+		return err
 	}
 
+	s.m[hash] = struct{}{}
+	debugAssertLength(s.length.Add(1))
+
 	return nil
 }
 
-// Delete removes a hash from the map. It decrements the length of the map.
+// Delete removes a hash from the map, if present, decrementing its length.
 //
 // Params:
 //   - hash: The hash to remove from the map.
@@ -148,10 +163,14 @@ func (s *NativeMap) Delete(hash chainhash.Hash) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.length--
+	if _, ok := s.m[hash]; !ok {
+		return nil
+	}
 
 	delete(s.m, hash)
 
+	debugAssertLength(s.length.Add(-1))
+
 	return nil
 }
 
@@ -165,7 +184,7 @@ func (s *NativeMap) Length() int {
 		defer s.mu.RUnlock()
 	}
 
-	return s.length
+	return int(s.length.Load())
 }
 
 // Keys returns a slice of all hashes currently stored in the map.
@@ -180,7 +199,7 @@ func (s *NativeMap) Keys() []chainhash.Hash {
 		defer s.mu.RUnlock()
 	}
 
-	keys := make([]chainhash.Hash, 0, s.length)
+	keys := make([]chainhash.Hash, 0, s.length.Load())
 
 	for k := range s.m {
 		keys = append(keys, k)
@@ -222,10 +241,12 @@ type MapUint64 = NativeMapUint64
 // NativeMapUint64 is a concurrent-safe map that uses Go's native map to store
 // transaction hashes as keys and uint64 values.
 type NativeMapUint64 struct {
-	mu     sync.RWMutex
-	m      map[chainhash.Hash]uint64
-	length int
-	frozen atomic.Bool
+	mu         mapLock
+	m          map[chainhash.Hash]uint64
+	length     atomic.Int64
+	frozen     atomic.Bool
+	epoch      atomic.Uint64
+	duplicates duplicateHandler[chainhash.Hash]
 }
 
 // NewNativeMapUint64 creates a new NativeMapUint64 with the specified initial length.
@@ -249,14 +270,33 @@ func NewDefaultMapUint64(length uint32) *NativeMapUint64 {
 	return NewNativeMapUint64(length)
 }
 
-// Map returns the underlying native map used by NativeMapUint64.
+// UnsafeMap returns the underlying native map used by NativeMapUint64.
 //
 // Returns:
 //   - map[chainhash.Hash]uint64: The underlying native map.
-func (s *NativeMapUint64) Map() map[chainhash.Hash]uint64 {
+//
+// Considerations: The map is returned without the lock, so any access to it
+// races concurrent writes to NativeMapUint64. Use WithMap unless the map is
+// frozen or not shared.
+func (s *NativeMapUint64) UnsafeMap() map[chainhash.Hash]uint64 {
 	return s.m
 }
 
+// WithMap calls fn with the underlying native map while holding the read lock,
+// or without it if the map is frozen. fn must not modify the map, nor keep it
+// after returning, and must not call methods of NativeMapUint64 that write.
+//
+// Params:
+//   - fn: The function to call with the underlying native map.
+func (s *NativeMapUint64) WithMap(fn func(m map[chainhash.Hash]uint64)) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	fn(s.m)
+}
+
 // Exists checks if the given hash exists in the map.
 // It returns true if the hash is found, false otherwise.
 //
@@ -277,8 +317,8 @@ func (s *NativeMapUint64) Exists(hash chainhash.Hash) bool {
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
-// It checks if the hash already exists in the map and returns an error if it does.
-// If the hash does not exist, it adds the hash and increments the length of the map.
+// If the hash already exists, the duplicate policy decides the outcome; by default
+// (DuplicateError) it returns an error. Otherwise it adds the hash and increments the length of the map.
 //
 // Params:
 //   - hash: The hash to add to the map.
@@ -294,21 +334,12 @@ func (s *NativeMapUint64) Put(hash chainhash.Hash, n uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, exists := s.m[hash]
-	if exists {
-		return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
-	}
-
-	s.m[hash] = n
-
-	s.length++
-
-	return nil
+	return s.putUnlocked(hash, n)
 }
 
 // PutMulti adds multiple hashes with an associated uint64 value to the map.
-// It checks if any of the hashes already exist in the map and returns an error if any do.
-// If none of the hashes exist, it adds each hash with the value and increments the length of the map.
+// Hashes that already exist are handled by the duplicate policy; by default (DuplicateError)
+// the first one stops the call with an error. New hashes are added and increment the length of the map.
 //
 // Params:
 //   - hashes: A slice of hashes to add to the map.
@@ -325,17 +356,33 @@ func (s *NativeMapUint64) PutMulti(hashes []chainhash.Hash, n uint64) error {
 	defer s.mu.Unlock()
 
 	for _, hash := range hashes {
-		_, exists := s.m[hash]
-		if exists {
-			return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+		if err := s.putUnlocked(hash, n); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
 
+// putUnlocked adds hash with value n, applying the duplicate policy if it
+// already exists. The caller must hold the write lock.
+func (s *NativeMapUint64) putUnlocked(hash chainhash.Hash, n uint64) error {
+	debugAssertHash(hash)
+
+	existing, exists := s.m[hash]
+	if !exists {
 		s.m[hash] = n
+		debugAssertLength(s.length.Add(1))
 
-		s.length++
+		return nil
 	}
 
-	return nil
+	value, store, err := s.duplicates.resolve(hash, existing, n)
+	if store {
+		s.m[hash] = value
+	}
+
+	return err
 }
 
 // Set updates the value associated with the given hash in the map.
@@ -418,9 +465,10 @@ func (s *NativeMapUint64) SetIfNotExists(hash chainhash.Hash, value uint64) (boo
 		return false, nil
 	}
 
+	debugAssertHash(hash)
 	s.m[hash] = value
 
-	s.length++
+	debugAssertLength(s.length.Add(1))
 
 	return true, nil
 }
@@ -460,7 +508,7 @@ func (s *NativeMapUint64) Length() int {
 		defer s.mu.RUnlock()
 	}
 
-	return s.length
+	return int(s.length.Load())
 }
 
 // Keys returns a slice of all hashes currently stored in the map.
@@ -475,7 +523,7 @@ func (s *NativeMapUint64) Keys() []chainhash.Hash {
 		defer s.mu.RUnlock()
 	}
 
-	keys := make([]chainhash.Hash, 0, s.length)
+	keys := make([]chainhash.Hash, 0, s.length.Load())
 
 	for k := range s.m {
 		keys = append(keys, k)
@@ -526,7 +574,7 @@ func (s *NativeMapUint64) Delete(hash chainhash.Hash) error {
 
 	delete(s.m, hash)
 
-	s.length--
+	debugAssertLength(s.length.Add(-1))
 
 	return nil
 }
@@ -537,9 +585,10 @@ type LockFreeMapUint64 = NativeLockFreeMapUint64
 
 // NativeLockFreeMapUint64 is a lock-free map for uint64 keys and values
 type NativeLockFreeMapUint64 struct {
-	m      map[uint64]uint64
-	length atomic.Uint32
-	frozen atomic.Bool
+	m          map[uint64]uint64
+	length     atomic.Uint32
+	frozen     atomic.Bool
+	duplicates duplicateHandler[uint64]
 }
 
 // NewNativeLockFreeMapUint64 creates a new NativeLockFreeMapUint64 with the specified initial length.
@@ -601,15 +650,15 @@ func (s *NativeLockFreeMapUint64) Exists(hash uint64) bool {
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
-// It checks if the hash already exists in the map and returns an error if it does.
-// If the hash does not exist, it adds the hash and increments the length of the map.
+// If the hash already exists, the duplicate policy decides the outcome; by default
+// (DuplicateError) it returns an error. Otherwise it adds the hash and increments the length of the map.
 //
 // Params:
 //   - hash: The hash to add to the map.
 //   - n: The uint64 value to associate with the hash.
 //
 // Returns:
-//   - error: An error if the hash already exists in the map, nil otherwise.
+//   - error: An error if the hash already exists and the duplicate policy rejects it, nil otherwise.
 //
 // Considerations: This method does not lock the map, so it is not suitable for concurrent access.
 func (s *NativeLockFreeMapUint64) Put(hash, n uint64) error {
@@ -617,15 +666,20 @@ func (s *NativeLockFreeMapUint64) Put(hash, n uint64) error {
 		return ErrMapFrozen
 	}
 
-	_, exists := s.m[hash]
-	if exists {
-		return ErrHashAlreadyExists
+	existing, exists := s.m[hash]
+	if !exists {
+		s.m[hash] = n
+		s.length.Add(1)
+
+		return nil
 	}
 
-	s.m[hash] = n
-	s.length.Add(1)
+	value, store, err := s.duplicates.resolve(hash, existing, n)
+	if store {
+		s.m[hash] = value
+	}
 
-	return nil
+	return err
 }
 
 // Get retrieves the uint64 value associated with the given hash from the map.
@@ -667,8 +721,9 @@ type SplitMap = NativeSplitMap
 // NativeSplitMap splits the data into multiple buckets to reduce contention.
 // It uses NativeMapUint64 for each bucket. NativeMapUint64 is concurrent-safe.
 type NativeSplitMap struct {
-	m           map[uint16]*NativeMapUint64
+	m           []*NativeMapUint64
 	nrOfBuckets uint16
+	epoch       atomic.Uint64
 }
 
 // NewNativeSplitMap creates a new NativeSplitMap with the specified initial length.
@@ -689,7 +744,7 @@ func NewNativeSplitMap(length int, buckets ...uint16) *NativeSplitMap {
 	}
 
 	m := &NativeSplitMap{
-		m:           make(map[uint16]*NativeMapUint64, useBuckets),
+		m:           make([]*NativeMapUint64, int(useBuckets)+1),
 		nrOfBuckets: useBuckets,
 	}
 
@@ -747,7 +802,15 @@ func (g *NativeSplitMap) Get(hash chainhash.Hash) (uint64, bool) {
 // Returns:
 //   - error: An error if the hash already exists in the map, nil otherwise.
 func (g *NativeSplitMap) Put(hash chainhash.Hash, n uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n)
+	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
+
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash, n)
 }
 
 // PutMulti adds multiple hashes with an associated uint64 value to the map.
@@ -763,7 +826,7 @@ func (g *NativeSplitMap) Put(hash chainhash.Hash, n uint64) error {
 //   - error: An error if any of the hashes already exist in the map, nil otherwise.
 func (g *NativeSplitMap) PutMulti(hashes []chainhash.Hash, n uint64) (err error) {
 	for _, hash := range hashes {
-		if err = g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n); err != nil {
+		if err = g.Put(hash, n); err != nil {
 			return fmt.Errorf("failed to put multi in bucket %d: %w", Bytes2Uint16Buckets(hash, g.nrOfBuckets), err)
 		}
 	}
@@ -782,11 +845,12 @@ func (g *NativeSplitMap) PutMulti(hashes []chainhash.Hash, n uint64) (err error)
 // Returns:
 //   - error: An error if the bucket does not exist or if there is an issue adding the hashes, nil otherwise.
 func (g *NativeSplitMap) PutMultiBucket(bucket uint16, hashes []chainhash.Hash, n uint64) error {
-	if bucket > g.nrOfBuckets {
-		return fmt.Errorf("%w: %d, max bucket is %d", ErrBucketDoesNotExist, bucket, g.nrOfBuckets)
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
 	}
 
-	return g.m[bucket].PutMulti(hashes, n)
+	return b.PutMulti(hashes, n)
 }
 
 // Set updates the value associated with the given hash in the map.
@@ -798,7 +862,12 @@ func (g *NativeSplitMap) PutMultiBucket(bucket uint16, hashes []chainhash.Hash,
 // Returns:
 //   - error: An error if the hash does not exist in the map, nil otherwise.
 func (g *NativeSplitMap) Set(hash chainhash.Hash, value uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Set(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Set(hash, value)
 }
 
 // SetIfExists updates the value associated with the given hash in the map if it exists.
@@ -813,7 +882,12 @@ func (g *NativeSplitMap) Set(hash chainhash.Hash, value uint64) error {
 //   - bool: True if the hash was found and updated, false otherwise.
 //   - error: An error if there was an issue updating the hash, nil otherwise.
 func (g *NativeSplitMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfExists(hash, value)
 }
 
 // SetIfNotExists adds the hash with the given value to the map only if the hash does not already exist.
@@ -828,7 +902,12 @@ func (g *NativeSplitMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, e
 //   - bool: True if the hash was added, false if it already existed.
 //   - error: An error if there was an issue adding the hash, nil otherwise.
 func (g *NativeSplitMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfNotExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfNotExists(hash, value)
 }
 
 // Keys returns a slice of all hashes currently stored in the map.
@@ -872,33 +951,18 @@ func (g *NativeSplitMap) Length() int {
 //   - error: An error if the hash does not exist in the map or if the bucket does not exist, nil otherwise.
 func (g *NativeSplitMap) Delete(hash chainhash.Hash) error {
 	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
 
-	if _, ok := g.m[bucket]; !ok {
-		return fmt.Errorf("%w: %d", ErrBucketDoesNotExist, bucket)
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
 	}
 
-	if !g.m[bucket].Exists(hash) {
+	if !b.Exists(hash) {
 		return fmt.Errorf("%w in bucket %d: %s", ErrHashDoesNotExist, bucket, hash)
 	}
 
-	return g.m[bucket].Delete(hash)
-}
-
-// Map returns the underlying map of all buckets used by NativeSplitMap.
-//
-// Returns:
-//   - TxMap: A map where the keys are bucket indices and the values are pointers to NativeMapUint64 instances.
-func (g *NativeSplitMap) Map() *NativeMapUint64 {
-	m := NewNativeMapUint64(uint32(g.Length())) //nolint:gosec // integer overflow conversion int -> uint32
-	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		keys := g.m[i].Keys()
-		for _, key := range keys {
-			val, _ := g.m[i].Get(key)
-			_ = m.Put(key, val)
-		}
-	}
-
-	return m
+	return b.Delete(hash)
 }
 
 // Iter iterates over all key-value pairs in the map and applies the provided function to each pair.
@@ -907,8 +971,13 @@ func (g *NativeSplitMap) Map() *NativeMapUint64 {
 // Params:
 //   - f: A function that takes a hash and its associated uint64 value.
 func (g *NativeSplitMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
-	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		g.m[i].Iter(f)
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
 	}
 }
 
@@ -922,8 +991,9 @@ type SplitMapUint64 = NativeSplitMapUint64
 // NativeSplitMapUint64 splits the data into multiple buckets to reduce contention.
 // It uses NativeMapUint64 for each bucket. Buckets is fixed at 1024.
 type NativeSplitMapUint64 struct {
-	m           map[uint16]*NativeMapUint64
+	m           []*NativeMapUint64
 	nrOfBuckets uint16
+	epoch       atomic.Uint64
 }
 
 // NewNativeSplitMapUint64 creates a new NativeSplitMapUint64 with the specified initial length.
@@ -942,7 +1012,7 @@ func NewNativeSplitMapUint64(length uint32, buckets ...uint16) *NativeSplitMapUi
 	}
 
 	m := &NativeSplitMapUint64{
-		m:           make(map[uint16]*NativeMapUint64, useBuckets),
+		m:           make([]*NativeMapUint64, int(useBuckets)+1),
 		nrOfBuckets: useBuckets,
 	}
 
@@ -971,12 +1041,38 @@ func (g *NativeSplitMapUint64) Exists(hash chainhash.Hash) bool {
 	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Exists(hash)
 }
 
-// Map returns the underlying map of buckets used by NativeSplitMapUint64.
+// Map returns a new map of the buckets used by NativeSplitMapUint64. Changing
+// the map does not change the buckets of the NativeSplitMapUint64; see the
+// notes at the top of buckets.go.
 //
 // Returns:
 //   - map[uint16]*NativeMapUint64: A map where the keys are bucket indices and the values are pointers to NativeMapUint64 instances.
 func (g *NativeSplitMapUint64) Map() map[uint16]*NativeMapUint64 {
-	return g.m
+	return bucketsMap[uint16](g.m)
+}
+
+// Buckets returns the number of buckets in the NativeSplitMapUint64.
+func (g *NativeSplitMapUint64) Buckets() uint16 {
+	return g.nrOfBuckets
+}
+
+// PutMultiBucket adds multiple hashes with an associated uint64 value to a specific bucket.
+// It checks if the bucket exists and then adds the hashes directly to that bucket.
+//
+// Params:
+//   - bucket: The bucket index to add the hashes to.
+//   - hashes: A slice of hashes to add to the specified bucket.
+//   - n: The uint64 value to associate with each hash.
+//
+// Returns:
+//   - error: An error if the bucket does not exist or if there is an issue adding the hashes, nil otherwise.
+func (g *NativeSplitMapUint64) PutMultiBucket(bucket uint16, hashes []chainhash.Hash, n uint64) error {
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
+	}
+
+	return b.PutMulti(hashes, n)
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
@@ -990,7 +1086,15 @@ func (g *NativeSplitMapUint64) Map() map[uint16]*NativeMapUint64 {
 // Returns:
 //   - error: An error if the hash already exists in the map, nil otherwise.
 func (g *NativeSplitMapUint64) Put(hash chainhash.Hash, n uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n)
+	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
+
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash, n)
 }
 
 // PutMulti adds multiple hashes with an associated uint64 value to the map.
@@ -1006,7 +1110,7 @@ func (g *NativeSplitMapUint64) Put(hash chainhash.Hash, n uint64) error {
 //   - error: An error if any of the hashes already exist in the map, nil otherwise.
 func (g *NativeSplitMapUint64) PutMulti(hashes []chainhash.Hash, n uint64) error {
 	for _, hash := range hashes {
-		if err := g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Put(hash, n); err != nil {
+		if err := g.Put(hash, n); err != nil {
 			return fmt.Errorf("failed to put multi in bucket %d: %w", Bytes2Uint16Buckets(hash, g.nrOfBuckets), err)
 		}
 	}
@@ -1024,7 +1128,12 @@ func (g *NativeSplitMapUint64) PutMulti(hashes []chainhash.Hash, n uint64) error
 // Returns:
 //   - error: An error if the hash does not exist in the map, nil otherwise.
 func (g *NativeSplitMapUint64) Set(hash chainhash.Hash, value uint64) error {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].Set(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Set(hash, value)
 }
 
 // SetIfExists updates the value associated with the given hash in the map if it exists.
@@ -1039,7 +1148,12 @@ func (g *NativeSplitMapUint64) Set(hash chainhash.Hash, value uint64) error {
 //   - bool: True if the hash was found and updated, false otherwise.
 //   - error: An error if there was an issue updating the hash, nil otherwise.
 func (g *NativeSplitMapUint64) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfExists(hash, value)
 }
 
 // SetIfNotExists adds the hash with the given value to the map only if the hash does not already exist.
@@ -1054,7 +1168,12 @@ func (g *NativeSplitMapUint64) SetIfExists(hash chainhash.Hash, value uint64) (b
 //   - bool: True if the hash was added, false if it already existed.
 //   - error: An error if there was an issue adding the hash, nil otherwise.
 func (g *NativeSplitMapUint64) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
-	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)].SetIfNotExists(hash, value)
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfNotExists(hash, value)
 }
 
 // Get retrieves the uint64 value associated with the given hash from the map.
@@ -1076,8 +1195,13 @@ func (g *NativeSplitMapUint64) Get(hash chainhash.Hash) (uint64, bool) {
 // Params:
 //   - f: A function that takes a hash and its associated uint64 value.
 func (g *NativeSplitMapUint64) Iter(f func(hash chainhash.Hash, value uint64) bool) {
-	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		g.m[i].Iter(f)
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].Iter(func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
 	}
 }
 
@@ -1089,7 +1213,7 @@ func (g *NativeSplitMapUint64) Iter(f func(hash chainhash.Hash, value uint64) bo
 func (g *NativeSplitMapUint64) Length() int {
 	length := 0
 	for i := uint16(0); i <= g.nrOfBuckets; i++ {
-		length += g.m[i].length
+		length += int(g.m[i].length.Load())
 	}
 
 	return length
@@ -1106,16 +1230,18 @@ func (g *NativeSplitMapUint64) Length() int {
 //   - error: An error if the hash does not exist in the map or if the bucket does not exist, nil otherwise.
 func (g *NativeSplitMapUint64) Delete(hash chainhash.Hash) error {
 	bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+	debugAssertBucket(bucket, g.nrOfBuckets)
 
-	if _, ok := g.m[bucket]; !ok {
-		return fmt.Errorf("%w: %d", ErrBucketDoesNotExist, bucket)
+	b, err := bucketAt(g.m, bucket)
+	if err != nil {
+		return err
 	}
 
-	if !g.m[bucket].Exists(hash) {
+	if !b.Exists(hash) {
 		return fmt.Errorf("%w in bucket %d: %s", ErrHashDoesNotExist, bucket, hash)
 	}
 
-	return g.m[bucket].Delete(hash)
+	return b.Delete(hash)
 }
 
 // Keys returns a slice of all hashes currently stored in the map.
@@ -1141,7 +1267,7 @@ type SplitLockFreeMapUint64 = NativeSplitLockFreeMapUint64
 // NativeSplitLockFreeMapUint64 is a map that splits the data into multiple buckets to reduce contention.
 // It uses NativeLockFreeMapUint64 for each bucket to store the hashes and their associated uint64 values.
 type NativeSplitLockFreeMapUint64 struct {
-	m           map[uint64]*NativeLockFreeMapUint64
+	m           []*NativeLockFreeMapUint64
 	nrOfBuckets uint64
 }
 
@@ -1161,7 +1287,7 @@ func NewNativeSplitLockFreeMapUint64(length int, buckets ...uint64) *NativeSplit
 	}
 
 	m := &NativeSplitLockFreeMapUint64{
-		m:           make(map[uint64]*NativeLockFreeMapUint64, useBuckets),
+		m:           make([]*NativeLockFreeMapUint64, useBuckets+1),
 		nrOfBuckets: useBuckets,
 	}
 
@@ -1211,15 +1337,17 @@ func (g *NativeSplitLockFreeMapUint64) Exists(hash uint64) bool {
 	return g.m[hash%g.nrOfBuckets].Exists(hash)
 }
 
-// Map returns the underlying map of buckets used by NativeSplitLockFreeMapUint64.
-// It provides access to the map for operations that do not require locking.
+// Map returns a new map of the buckets used by NativeSplitLockFreeMapUint64,
+// for operations that do not require locking. Changing the map does not change
+// the buckets of the NativeSplitLockFreeMapUint64; see the notes at the top of
+// buckets.go.
 //
 // Returns:
 //   - map[uint64]*NativeLockFreeMapUint64: A map where the keys are bucket indices and the values are pointers to NativeLockFreeMapUint64 instances.
 //
 // Considerations: This method does not lock the map, so it is not suitable for concurrent access.
 func (g *NativeSplitLockFreeMapUint64) Map() map[uint64]*NativeLockFreeMapUint64 {
-	return g.m
+	return bucketsMap[uint64](g.m)
 }
 
 // Put adds a new hash with an associated uint64 value to the map.
@@ -1231,11 +1359,16 @@ func (g *NativeSplitLockFreeMapUint64) Map() map[uint64]*NativeLockFreeMapUint64
 //   - n: The uint64 value to associate with the hash.
 //
 // Returns:
-//   - error: An error if the hash already exists in the map, nil otherwise.
+//   - error: An error if the hash already exists and the duplicate policy rejects it, nil otherwise.
 //
 // Considerations: This method does not lock the map, so it is not suitable for concurrent access.
 func (g *NativeSplitLockFreeMapUint64) Put(hash, n uint64) error {
-	return g.m[hash%g.nrOfBuckets].Put(hash, n)
+	b, err := bucketAt(g.m, hash%g.nrOfBuckets)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash, n)
 }
 
 // Get retrieves the uint64 value associated with the given hash from the map.
@@ -1277,7 +1410,11 @@ func (g *NativeSplitLockFreeMapUint64) IterAll(f func(k, v uint64) (stop bool))
 	}
 }
 
-// DeleteBucket removes the bucket at index h from the map of buckets.
+// DeleteBucket discards the contents of bucket h, replacing it with an empty
+// bucket so that the map stays usable. A bucket number out of range is
+// ignored.
 func (g *NativeSplitLockFreeMapUint64) DeleteBucket(h uint64) {
-	delete(g.m, h)
+	if h <= g.nrOfBuckets {
+		g.m[h] = NewNativeLockFreeMapUint64(0)
+	}
 }
@@ -0,0 +1,451 @@
+package txmap
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/dolthub/swiss"
+)
+
+// TxMapOf is a TxMap storing values of type V instead of uint64, for callers
+// that key block heights, fee rates or small structs by transaction hash
+// without keeping a parallel map. Its methods behave as those of TxMap; the
+// uint64 maps implement TxMapOf[uint64].
+type TxMapOf[V any] interface {
+	Exists(hash chainhash.Hash) bool
+	Get(hash chainhash.Hash) (V, bool)
+	Keys() []chainhash.Hash
+	Length() int
+	Iter(f func(hash chainhash.Hash, value V) bool)
+
+	Delete(hash chainhash.Hash) error
+	Put(hash chainhash.Hash, value V) error
+	PutMulti(hashes []chainhash.Hash, value V) error
+	Set(hash chainhash.Hash, value V) error
+	SetIfExists(hash chainhash.Hash, value V) (bool, error)
+	SetIfNotExists(hash chainhash.Hash, value V) (bool, error)
+
+	// Freeze marks the map read-only, see TxMap.
+	Freeze()
+
+	// Clear empties the map in place and un-freezes it, see TxMap.
+	Clear()
+}
+
+// check that the generic maps implement TxMapOf, and that the uint64 maps
+// are TxMapOf[uint64]
+var (
+	_ TxMapOf[int32]  = (*SwissMapOf[int32])(nil)
+	_ TxMapOf[int32]  = (*SplitSwissMapOf[int32])(nil)
+	_ TxMapOf[uint64] = (*SwissMapUint64)(nil)
+	_ TxMapOf[uint64] = (*SplitSwissMapUint64)(nil)
+)
+
+// SwissMapOf is the generic counterpart of SwissMapUint64: a concurrent-safe
+// map from transaction hashes to values of type V, backed by a swiss map
+// under a single lock.
+type SwissMapOf[V any] struct {
+	mu     mapLock
+	m      *swiss.Map[chainhash.Hash, V]
+	length atomic.Int64
+	frozen atomic.Bool
+}
+
+// NewSwissMapOf creates a new SwissMapOf with the specified initial length.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//
+// Returns:
+//   - *SwissMapOf[V]: A pointer to the newly created SwissMapOf instance.
+func NewSwissMapOf[V any](length uint32) *SwissMapOf[V] {
+	return &SwissMapOf[V]{
+		m: swiss.NewMap[chainhash.Hash, V](length),
+	}
+}
+
+// Exists checks if the given hash exists in the map.
+func (s *SwissMapOf[V]) Exists(hash chainhash.Hash) bool {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return s.m.Has(hash)
+}
+
+// Get retrieves the value associated with the given hash.
+//
+// Returns:
+//   - V: The value, or the zero value if the hash does not exist.
+//   - bool: True if the hash was found in the map.
+func (s *SwissMapOf[V]) Get(hash chainhash.Hash) (V, bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	return s.m.Get(hash)
+}
+
+// Put adds a new hash with the given value to the map.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists if the
+//     hash already exists.
+func (s *SwissMapOf[V]) Put(hash chainhash.Hash, value V) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.putUnlocked(hash, value)
+}
+
+// PutMulti adds multiple hashes with the given value under a single lock.
+// The first hash that already exists stops the call with an error.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashAlreadyExists.
+func (s *SwissMapOf[V]) PutMulti(hashes []chainhash.Hash, value V) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hash := range hashes {
+		if err := s.putUnlocked(hash, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putUnlocked adds hash with value. The caller must hold the write lock.
+func (s *SwissMapOf[V]) putUnlocked(hash chainhash.Hash, value V) error {
+	if s.m.Has(hash) {
+		return fmt.Errorf(errWrapFormat, ErrHashAlreadyExists, hash)
+	}
+
+	debugAssertHash(hash)
+	s.m.Put(hash, value)
+	debugAssertLength(s.length.Add(1))
+
+	return nil
+}
+
+// Set updates the value associated with an existing hash.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist.
+func (s *SwissMapOf[V]) Set(hash chainhash.Hash, value V) error {
+	ok, err := s.SetIfExists(hash, value)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf(errWrapFormat, ErrHashDoesNotExist, hash)
+	}
+
+	return nil
+}
+
+// SetIfExists updates the value associated with hash if it exists.
+//
+// Returns:
+//   - bool: True if the hash was found and updated.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *SwissMapOf[V]) SetIfExists(hash chainhash.Hash, value V) (bool, error) {
+	if s.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.m.Has(hash) {
+		return false, nil
+	}
+
+	s.m.Put(hash, value)
+
+	return true, nil
+}
+
+// SetIfNotExists adds hash with value if it does not exist yet.
+//
+// Returns:
+//   - bool: True if the hash was added, false if it already existed.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *SwissMapOf[V]) SetIfNotExists(hash chainhash.Hash, value V) (bool, error) {
+	if s.frozen.Load() {
+		return false, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.m.Has(hash) {
+		return false, nil
+	}
+
+	debugAssertHash(hash)
+	s.m.Put(hash, value)
+	debugAssertLength(s.length.Add(1))
+
+	return true, nil
+}
+
+// Delete removes a hash from the map.
+//
+// Returns:
+//   - error: ErrMapFrozen, or an error wrapping ErrHashDoesNotExist.
+func (s *SwissMapOf[V]) Delete(hash chainhash.Hash) error {
+	if s.frozen.Load() {
+		return ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.m.Delete(hash) {
+		return fmt.Errorf("%w: %s", ErrHashDoesNotExist, hash)
+	}
+
+	debugAssertLength(s.length.Add(-1))
+
+	return nil
+}
+
+// Length returns the current number of hashes in the map.
+func (s *SwissMapOf[V]) Length() int {
+	return int(s.length.Load())
+}
+
+// Keys returns a slice of all hashes in the map, in no particular order.
+func (s *SwissMapOf[V]) Keys() []chainhash.Hash {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	keys := make([]chainhash.Hash, 0, s.length.Load())
+
+	s.m.Iter(func(k chainhash.Hash, _ V) (stop bool) {
+		keys = append(keys, k)
+		return false
+	})
+
+	return keys
+}
+
+// Iter calls f for every hash and value. Stops iterating if f returns true.
+func (s *SwissMapOf[V]) Iter(f func(hash chainhash.Hash, value V) bool) {
+	if !s.frozen.Load() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	}
+
+	s.m.Iter(f)
+}
+
+// Freeze marks the map read-only. See the lifecycle notes at the top of
+// freeze.go.
+func (s *SwissMapOf[V]) Freeze() { s.frozen.Store(true) }
+
+// Clear empties the map without releasing its backing storage and un-freezes
+// it. Like SwissMapUint64.Clear, it must not run concurrently with any other
+// operation on the map.
+func (s *SwissMapOf[V]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.m.Clear()
+	s.length.Store(0)
+	s.frozen.Store(false)
+}
+
+// SplitSwissMapOf is the generic counterpart of SplitSwissMapUint64: it
+// spreads the hashes over buckets 0..nrOfBuckets, each a SwissMapOf with its
+// own lock, routed by Bytes2Uint16Buckets.
+type SplitSwissMapOf[V any] struct {
+	m           []*SwissMapOf[V]
+	nrOfBuckets uint16
+}
+
+// NewSplitSwissMapOf creates a new SplitSwissMapOf with the specified initial
+// length, preallocating each bucket with the same 20% headroom as
+// NewSplitSwissMapUint64.
+//
+// Params:
+//   - length: The initial length of the map, used for preallocation.
+//   - buckets: Optionally the number of buckets, 1024 by default.
+//
+// Returns:
+//   - *SplitSwissMapOf[V]: A pointer to the newly created SplitSwissMapOf instance.
+func NewSplitSwissMapOf[V any](length uint32, buckets ...uint16) *SplitSwissMapOf[V] {
+	useBuckets := uint16(1024)
+	if len(buckets) > 0 {
+		useBuckets = buckets[0]
+	}
+
+	m := &SplitSwissMapOf[V]{
+		m:           make([]*SwissMapOf[V], int(useBuckets)+1),
+		nrOfBuckets: useBuckets,
+	}
+
+	perBucket := (length + length/5) / uint32(max(m.nrOfBuckets, 1))
+
+	for i := uint16(0); i <= m.nrOfBuckets; i++ {
+		m.m[i] = NewSwissMapOf[V](perBucket)
+	}
+
+	return m
+}
+
+// Buckets returns the number of buckets in the map.
+func (g *SplitSwissMapOf[V]) Buckets() uint16 {
+	return g.nrOfBuckets
+}
+
+// bucket returns the bucket of hash.
+func (g *SplitSwissMapOf[V]) bucket(hash chainhash.Hash) *SwissMapOf[V] {
+	return g.m[Bytes2Uint16Buckets(hash, g.nrOfBuckets)]
+}
+
+// Exists checks if the given hash exists in the map.
+func (g *SplitSwissMapOf[V]) Exists(hash chainhash.Hash) bool {
+	return g.bucket(hash).Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from its bucket.
+func (g *SplitSwissMapOf[V]) Get(hash chainhash.Hash) (V, bool) {
+	return g.bucket(hash).Get(hash)
+}
+
+// Put adds a new hash with the given value to its bucket.
+func (g *SplitSwissMapOf[V]) Put(hash chainhash.Hash, value V) error {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Put(hash, value)
+}
+
+// PutMulti adds multiple hashes with the given value, one at a time. The
+// first hash that already exists stops the call with an error.
+func (g *SplitSwissMapOf[V]) PutMulti(hashes []chainhash.Hash, value V) error {
+	for _, hash := range hashes {
+		bucket := Bytes2Uint16Buckets(hash, g.nrOfBuckets)
+
+		b, err := bucketAt(g.m, bucket)
+		if err != nil {
+			return err
+		}
+
+		if err = b.Put(hash, value); err != nil {
+			return fmt.Errorf("failed to put multi in bucket %d: %w", bucket, err)
+		}
+	}
+
+	return nil
+}
+
+// Set updates the value associated with an existing hash.
+func (g *SplitSwissMapOf[V]) Set(hash chainhash.Hash, value V) error {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Set(hash, value)
+}
+
+// SetIfExists updates the value associated with hash if it exists.
+func (g *SplitSwissMapOf[V]) SetIfExists(hash chainhash.Hash, value V) (bool, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash with value if it does not exist yet.
+func (g *SplitSwissMapOf[V]) SetIfNotExists(hash chainhash.Hash, value V) (bool, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return false, err
+	}
+
+	return b.SetIfNotExists(hash, value)
+}
+
+// Delete removes a hash from its bucket.
+func (g *SplitSwissMapOf[V]) Delete(hash chainhash.Hash) error {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return err
+	}
+
+	return b.Delete(hash)
+}
+
+// Length returns the sum of the bucket lengths.
+func (g *SplitSwissMapOf[V]) Length() int {
+	length := 0
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		length += g.m[i].Length()
+	}
+
+	return length
+}
+
+// Keys returns all hashes in the map, bucket by bucket. The result is not an
+// atomic snapshot across buckets.
+func (g *SplitSwissMapOf[V]) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, g.Length())
+
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		keys = append(keys, g.m[i].Keys()...)
+	}
+
+	return keys
+}
+
+// Iter calls f for every hash and value, one bucket at a time. Stops
+// iterating if f returns true.
+func (g *SplitSwissMapOf[V]) Iter(f func(hash chainhash.Hash, value V) bool) {
+	stopped := false
+
+	for i := uint16(0); i <= g.nrOfBuckets && !stopped; i++ {
+		g.m[i].Iter(func(hash chainhash.Hash, value V) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
+	}
+}
+
+// Freeze freezes every bucket.
+func (g *SplitSwissMapOf[V]) Freeze() {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].Freeze()
+	}
+}
+
+// Clear empties and un-freezes every bucket. Callers should ensure no other
+// goroutine is using the map.
+func (g *SplitSwissMapOf[V]) Clear() {
+	for i := uint16(0); i <= g.nrOfBuckets; i++ {
+		g.m[i].Clear()
+	}
+}
@@ -0,0 +1,85 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// feeEntry is a small struct value for the generic map tests.
+type feeEntry struct {
+	Height  uint32
+	FeeRate float64
+}
+
+// TestTxMapOf runs the same operations against both generic maps.
+func TestTxMapOf(t *testing.T) {
+	maps := map[string]TxMapOf[feeEntry]{
+		"SwissMapOf":      NewSwissMapOf[feeEntry](0),
+		"SplitSwissMapOf": NewSplitSwissMapOf[feeEntry](100, 8),
+	}
+
+	for name, m := range maps {
+		t.Run(name, func(t *testing.T) {
+			for i := range 100 {
+				require.NoError(t, m.Put(hashN(i), feeEntry{Height: uint32(i), FeeRate: float64(i) / 2})) //nolint:gosec // G115 test values
+			}
+
+			require.ErrorIs(t, m.Put(hashN(1), feeEntry{}), ErrHashAlreadyExists)
+			require.ErrorIs(t, m.PutMulti([]chainhash.Hash{hashN(200), hashN(2)}, feeEntry{}), ErrHashAlreadyExists)
+			assert.Equal(t, 101, m.Length())
+
+			value, ok := m.Get(hashN(7))
+			require.True(t, ok)
+			assert.Equal(t, feeEntry{Height: 7, FeeRate: 3.5}, value)
+
+			require.NoError(t, m.Set(hashN(7), feeEntry{Height: 8}))
+			require.ErrorIs(t, m.Set(hashN(999), feeEntry{}), ErrHashDoesNotExist)
+
+			ok, err := m.SetIfNotExists(hashN(7), feeEntry{})
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			ok, err = m.SetIfExists(hashN(999), feeEntry{})
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			value, _ = m.Get(hashN(7))
+			assert.Equal(t, uint32(8), value.Height)
+
+			require.NoError(t, m.Delete(hashN(200)))
+			require.ErrorIs(t, m.Delete(hashN(200)), ErrHashDoesNotExist)
+			assert.Len(t, m.Keys(), 100)
+
+			var heights uint32
+
+			m.Iter(func(_ chainhash.Hash, value feeEntry) bool {
+				heights += value.Height
+				return false
+			})
+			assert.Equal(t, uint32(99*100/2+1), heights)
+
+			m.Freeze()
+			require.ErrorIs(t, m.Put(hashN(500), feeEntry{}), ErrMapFrozen)
+			assert.True(t, m.Exists(hashN(0)))
+
+			m.Clear()
+			assert.Equal(t, 0, m.Length())
+			require.NoError(t, m.Put(hashN(500), feeEntry{}))
+		})
+	}
+}
+
+// TestTxMapOfUint64 tests that the uint64 maps can be used where a
+// TxMapOf[uint64] is expected.
+func TestTxMapOfUint64(t *testing.T) {
+	var m TxMapOf[uint64] = NewSwissMapUint64(0)
+
+	require.NoError(t, m.Put(hashN(1), 42))
+
+	value, ok := m.Get(hashN(1))
+	require.True(t, ok)
+	assert.Equal(t, uint64(42), value)
+}
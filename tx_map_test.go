@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/dolthub/swiss"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -88,9 +89,6 @@ func TestNewSplitSwissMap(t *testing.T) {
 		assert.NotNil(t, m)
 
 		testTxMap(t, m)
-
-		mm := m.Map()
-		assert.NotNil(t, mm)
 	})
 }
 
@@ -151,7 +149,7 @@ func TestNewSplitSwissMapUint64Headroom(t *testing.T) {
 
 	// Capacity() on dolthub/swiss.Map returns remaining capacity until rehash.
 	// Since the map is freshly allocated and empty, this equals the load-factor limit.
-	cap0 := bucket0.Map().Capacity()
+	cap0 := bucket0.UnsafeMap().Capacity()
 	require.GreaterOrEqual(t, cap0, expectedMin,
 		"bucket 0 should have at least %d capacity (length=%d, buckets=%d, 1.2x headroom); got %d",
 		expectedMin, length, nrOfBuckets, cap0)
@@ -165,11 +163,32 @@ func TestNewSwissMapUint64(t *testing.T) {
 
 		testTxMap(t, m)
 
-		mm := m.Map()
+		mm := m.UnsafeMap()
 		assert.NotNil(t, mm)
 	})
 }
 
+// TestWithMap tests that WithMap hands the underlying maps to the callback.
+func TestWithMap(t *testing.T) {
+	swissMap := NewSwissMapUint64(0)
+	nativeMap := NewNativeMapUint64(0)
+
+	for i := range 100 {
+		require.NoError(t, swissMap.Put(hashN(i), uint64(i)))
+		require.NoError(t, nativeMap.Put(hashN(i), uint64(i)))
+	}
+
+	swissMap.WithMap(func(m *swiss.Map[chainhash.Hash, uint64]) {
+		assert.Equal(t, 100, m.Count())
+	})
+
+	nativeMap.Freeze()
+	nativeMap.WithMap(func(m map[chainhash.Hash]uint64) {
+		assert.Len(t, m, 100)
+		assert.Equal(t, uint64(42), m[hashN(42)])
+	})
+}
+
 // TestSplitSwissLockFreeMapUint64 tests the creation and basic usage of a SplitSwissLockFreeMapUint64.
 func TestSplitSwissLockFreeMapUint64(t *testing.T) {
 	t.Run("SplitSwissLockFreeMapUint64", func(t *testing.T) {
@@ -347,6 +366,10 @@ func testTxMap(t *testing.T, m TxMap) {
 		return false
 	})
 	assert.Equal(t, 4, nrOfKeys)
+
+	if checker, ok := m.(InvariantChecker); ok {
+		require.NoError(t, checker.CheckInvariants())
+	}
 }
 
 // testTxMapUint64 tests the basic operations of a Uint64 map implementation.
@@ -425,6 +448,36 @@ func TestSplitSwissMapBuckets(t *testing.T) {
 	assert.Equal(t, uint16(1024), m.Buckets())
 }
 
+// TestSplitMapIterStops tests that Iter of each split map stops as soon as f
+// returns true, both within a bucket and across the buckets that follow.
+func TestSplitMapIterStops(t *testing.T) {
+	maps := map[string]TxMap{
+		"SplitSwissMap":        NewSplitSwissMap(100, 4),
+		"SplitSwissMapUint64":  NewSplitSwissMapUint64(100, 4),
+		"NativeSplitMap":       NewNativeSplitMap(100, 4),
+		"NativeSplitMapUint64": NewNativeSplitMapUint64(100, 4),
+	}
+
+	for name, m := range maps {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i))) //nolint:gosec // i is never negative
+			}
+
+			for _, stopAt := range []int{1, 60} {
+				calls := 0
+
+				m.Iter(func(_ chainhash.Hash, _ uint64) bool {
+					calls++
+					return calls == stopAt
+				})
+
+				assert.Equal(t, stopAt, calls)
+			}
+		})
+	}
+}
+
 // TestSplitSwissMapPutMulti tests the PutMulti method of SplitSwissMap.
 func TestSplitSwissMapPutMulti(t *testing.T) {
 	t.Run("empty slice", func(t *testing.T) {
@@ -478,7 +531,7 @@ func TestSplitSwissMapDelete(t *testing.T) {
 			prepare: func(m *SplitSwissMap) chainhash.Hash {
 				hash := chainhash.Hash{0x00, 0x03}
 				bucket := Bytes2Uint16Buckets(hash, m.nrOfBuckets)
-				delete(m.m, bucket)
+				simulateMissingBucket(m.m, bucket)
 
 				return hash
 			},
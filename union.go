@@ -0,0 +1,131 @@
+package txmap
+
+import "github.com/bsv-blockchain/go-bt/v2/chainhash"
+
+// unionView is the ReadOnlyTxMap returned by NewUnionView.
+type unionView struct {
+	maps []ReadOnlyTxMap
+}
+
+// NewUnionView returns a read-only view over maps that behaves like their
+// union without copying any entries, e.g. for querying "current block + last N
+// blocks" as one map.
+//
+// A hash present in several members is reported once, with the value of the
+// first member holding it, by every method: Get and Exists consult the members
+// in order and stop at the first hit, and Iter, Keys and Length skip entries
+// shadowed by an earlier member.
+//
+// Params:
+//   - maps: The members, in lookup priority order. The slice is copied; the
+//     members are not, so the view reflects later changes to them.
+//
+// Returns:
+//   - ReadOnlyTxMap: The union view.
+//
+// Considerations: Exists and Get cost up to one lookup per member. Iter, Keys
+// and Length visit every entry of every member and check each against the
+// members before it, so prefer few members and put the largest one first.
+func NewUnionView(maps ...ReadOnlyTxMap) ReadOnlyTxMap {
+	return &unionView{maps: append([]ReadOnlyTxMap(nil), maps...)}
+}
+
+// Exists checks if the given hash exists in any member.
+func (u *unionView) Exists(hash chainhash.Hash) bool {
+	for _, m := range u.maps {
+		if m.Exists(hash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get returns the value of hash in the first member that holds it.
+func (u *unionView) Get(hash chainhash.Hash) (uint64, bool) {
+	for _, m := range u.maps {
+		if v, ok := m.Get(hash); ok {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
+
+// Keys returns every distinct hash of the union.
+func (u *unionView) Keys() []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, u.firstLength())
+
+	u.Iter(func(hash chainhash.Hash, _ uint64) bool {
+		keys = append(keys, hash)
+		return false
+	})
+
+	return keys
+}
+
+// Length returns the number of distinct hashes in the union.
+func (u *unionView) Length() int {
+	length := 0
+
+	for i, m := range u.maps {
+		if i == 0 {
+			// nothing can shadow the first member
+			length += m.Length()
+			continue
+		}
+
+		m.Iter(func(hash chainhash.Hash, _ uint64) bool {
+			if !u.shadowed(i, hash) {
+				length++
+			}
+
+			return false
+		})
+	}
+
+	return length
+}
+
+// Iter iterates over the members in order, skipping hashes already visited in
+// an earlier member. Stops iterating if f returns true.
+func (u *unionView) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	stopped := false
+
+	for i, m := range u.maps {
+		m.Iter(func(hash chainhash.Hash, value uint64) bool {
+			if u.shadowed(i, hash) {
+				return false
+			}
+
+			stopped = f(hash, value)
+
+			return stopped
+		})
+
+		if stopped {
+			return
+		}
+	}
+}
+
+// shadowed reports whether hash exists in a member before member i.
+func (u *unionView) shadowed(i int, hash chainhash.Hash) bool {
+	for _, m := range u.maps[:i] {
+		if m.Exists(hash) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstLength returns the length of the first member, a lower bound of the
+// union's length used to size allocations.
+func (u *unionView) firstLength() int {
+	if len(u.maps) == 0 {
+		return 0
+	}
+
+	return u.maps[0].Length()
+}
@@ -0,0 +1,63 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUnionView tests first-hit-wins lookups and deduplicated iteration.
+func TestUnionView(t *testing.T) {
+	current := NewNativeMapUint64(10)
+	previous := NewSwissMapUint64(10)
+	older := NewNativeSplitMapUint64(10)
+
+	require.NoError(t, current.Put(hashN(1), 100))
+	require.NoError(t, previous.Put(hashN(1), 200))
+	require.NoError(t, previous.Put(hashN(2), 200))
+	require.NoError(t, older.Put(hashN(2), 300))
+	require.NoError(t, older.Put(hashN(3), 300))
+
+	u := NewUnionView(current, previous, older)
+
+	for hash, want := range map[chainhash.Hash]uint64{hashN(1): 100, hashN(2): 200, hashN(3): 300} {
+		v, ok := u.Get(hash)
+		require.True(t, ok)
+		assert.Equal(t, want, v)
+		assert.True(t, u.Exists(hash))
+	}
+
+	_, ok := u.Get(hashN(4))
+	assert.False(t, ok)
+	assert.False(t, u.Exists(hashN(4)))
+
+	assert.Equal(t, 3, u.Length())
+	assert.ElementsMatch(t, []chainhash.Hash{hashN(1), hashN(2), hashN(3)}, u.Keys())
+
+	seen := make(map[chainhash.Hash]uint64)
+
+	u.Iter(func(hash chainhash.Hash, value uint64) bool {
+		seen[hash] = value
+		return false
+	})
+	assert.Equal(t, map[chainhash.Hash]uint64{hashN(1): 100, hashN(2): 200, hashN(3): 300}, seen)
+
+	// stopping in a later member stops the whole iteration
+	visited := 0
+
+	u.Iter(func(chainhash.Hash, uint64) bool {
+		visited++
+		return visited == 2
+	})
+	assert.Equal(t, 2, visited)
+
+	// the view reflects later changes to its members
+	require.NoError(t, older.Put(hashN(4), 400))
+	assert.Equal(t, 4, u.Length())
+
+	empty := NewUnionView()
+	assert.Equal(t, 0, empty.Length())
+	assert.Empty(t, empty.Keys())
+}
@@ -0,0 +1,383 @@
+package txmap
+
+import (
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Read-modify-write
+//
+// A caller that decides on a value from the one it finds, e.g. to keep the
+// lowest block height seen for a hash, cannot do so with Get followed by Put
+// or Set: another writer can change the hash in between. GetOrPut and Upsert
+// do the read and the write under one hold of the lock of the hash's bucket,
+// so they are atomic with respect to every other write to the map. The
+// function passed to Upsert runs under that lock and must be fast and must
+// not call back into the map.
+//
+// Both bypass the duplicate policy, which only governs Put, and return
+// ErrMapFrozen on a frozen map, as the other writes do.
+
+// UpsertTxMap is implemented by the TxMaps that offer atomic read-modify-write.
+type UpsertTxMap interface {
+	TxMap
+
+	// GetOrPut returns the value of hash if it exists, and otherwise adds
+	// hash with value.
+	GetOrPut(hash chainhash.Hash, value uint64) (actual uint64, loaded bool, err error)
+
+	// Upsert stores the value fn returns for the current value of hash, or
+	// for a missing hash, and returns the stored value.
+	Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error)
+}
+
+// check that the uint64 maps implement UpsertTxMap
+var (
+	_ UpsertTxMap = (*SwissMapUint64)(nil)
+	_ UpsertTxMap = (*NativeMapUint64)(nil)
+	_ UpsertTxMap = (*SplitSwissMap)(nil)
+	_ UpsertTxMap = (*SplitSwissMapUint64)(nil)
+	_ UpsertTxMap = (*NativeSplitMap)(nil)
+	_ UpsertTxMap = (*NativeSplitMapUint64)(nil)
+	_ UpsertTxMap = (*TwoChoiceSplitMap)(nil)
+	_ UpsertTxMap = (*HybridSplitMap)(nil)
+	_ UpsertTxMap = (*LRUMap)(nil)
+	_ UpsertTxMap = (*ExpiringMap)(nil)
+)
+
+// getOrPut implements GetOrPut on top of the Upsert of a map.
+func getOrPut(upsert func(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error),
+	hash chainhash.Hash, value uint64,
+) (uint64, bool, error) {
+	var loaded bool
+
+	actual, err := upsert(hash, func(old uint64, exists bool) uint64 {
+		if exists {
+			loaded = true
+			return old
+		}
+
+		return value
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return actual, loaded, nil
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value, atomically. See the notes at the top of upsert.go.
+//
+// Returns:
+//   - uint64: The existing value, or value if it was added.
+//   - bool: True if the hash existed.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *SwissMapUint64) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	return getOrPut(s.Upsert, hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, atomically. See the
+// notes at the top of upsert.go.
+//
+// Returns:
+//   - uint64: The stored value.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *SwissMapUint64) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	if s.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.m.Get(hash)
+	value := fn(old, exists)
+
+	if !exists {
+		debugAssertHash(hash)
+		debugAssertLength(s.length.Add(1))
+	}
+
+	s.m.Put(hash, value)
+
+	return value, nil
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value, atomically. See the notes at the top of upsert.go.
+//
+// Returns:
+//   - uint64: The existing value, or value if it was added.
+//   - bool: True if the hash existed.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *NativeMapUint64) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	return getOrPut(s.Upsert, hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, atomically. See the
+// notes at the top of upsert.go.
+//
+// Returns:
+//   - uint64: The stored value.
+//   - error: ErrMapFrozen if the map is frozen.
+func (s *NativeMapUint64) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	if s.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.m[hash]
+	value := fn(old, exists)
+
+	if !exists {
+		debugAssertHash(hash)
+		debugAssertLength(s.length.Add(1))
+	}
+
+	s.m[hash] = value
+
+	return value, nil
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value, atomically. See the notes at the top of upsert.go.
+func (g *SplitSwissMap) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return b.GetOrPut(hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, atomically. See the
+// notes at the top of upsert.go.
+func (g *SplitSwissMap) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Upsert(hash, fn)
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value, atomically. See the notes at the top of upsert.go.
+func (g *SplitSwissMapUint64) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return b.GetOrPut(hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, atomically. See the
+// notes at the top of upsert.go.
+func (g *SplitSwissMapUint64) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Upsert(hash, fn)
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value, atomically. See the notes at the top of upsert.go.
+func (g *NativeSplitMap) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return b.GetOrPut(hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, atomically. See the
+// notes at the top of upsert.go.
+func (g *NativeSplitMap) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Upsert(hash, fn)
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value, atomically. See the notes at the top of upsert.go.
+func (g *NativeSplitMapUint64) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return b.GetOrPut(hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, atomically. See the
+// notes at the top of upsert.go.
+func (g *NativeSplitMapUint64) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	return b.Upsert(hash, fn)
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value to the lighter of its buckets, atomically. See the notes at the
+// top of upsert.go.
+func (g *TwoChoiceSplitMap) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	return getOrPut(g.Upsert, hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, in the bucket holding
+// it or else the lighter of its buckets, atomically. See the notes at the top
+// of upsert.go.
+func (g *TwoChoiceSplitMap) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	var value uint64
+
+	err := g.write(hash, func(first, second *SwissMapUint64) error {
+		holder := holderOf(hash, first, second)
+		if holder == nil {
+			value = fn(0, false)
+			return g.target(hash, first, second).putUnlocked(hash, value)
+		}
+
+		old, _ := holder.m.Get(hash)
+		value = fn(old, true)
+		holder.m.Put(hash, value)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// GetOrPut returns the value of hash if it exists, and otherwise adds hash
+// with value, atomically. See the notes at the top of upsert.go.
+func (g *HybridSplitMap) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	return getOrPut(g.Upsert, hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash, atomically, demoting
+// its bucket if it is promoted. See the notes at the top of upsert.go.
+func (g *HybridSplitMap) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	if g.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	b, err := bucketAt(g.m, Bytes2Uint16Buckets(hash, g.nrOfBuckets))
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	old, exists := b.m.Get(hash)
+	value := fn(old, exists)
+
+	b.writable().Put(hash, value)
+
+	if !exists {
+		b.length.Add(1)
+	}
+
+	return value, nil
+}
+
+// GetOrPut returns the value of hash if it exists, marking it as used, and
+// otherwise adds hash with value, evicting if the map is full, atomically.
+// See the notes at the top of upsert.go.
+func (m *LRUMap) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	return getOrPut(m.Upsert, hash, value)
+}
+
+// Upsert stores fn(old, exists) as the value of hash and marks it as the most
+// recently used, evicting if the map is full, atomically. See the notes at
+// the top of upsert.go.
+func (m *LRUMap) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	if m.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.m[hash]
+	if !ok {
+		value := fn(0, false)
+		m.insertUnlocked(hash, value)
+
+		return value, nil
+	}
+
+	e.value = fn(e.value, true)
+	m.moveToFront(e)
+
+	return e.value, nil
+}
+
+// GetOrPut returns the value of hash if it exists and has not expired, and
+// otherwise adds hash with value and the default time to live, atomically.
+// See the notes at the top of upsert.go.
+func (x *ExpiringMap) GetOrPut(hash chainhash.Hash, value uint64) (uint64, bool, error) {
+	if x.frozen.Load() {
+		return 0, false, ErrMapFrozen
+	}
+
+	var (
+		actual uint64
+		loaded bool
+	)
+
+	err := x.e.put(hash, value, x.e.ttl, func(existing uint64, exists bool) (uint64, bool, error) {
+		actual, loaded = existing, exists
+		if !exists {
+			actual = value
+		}
+
+		// an existing entry keeps its time to live
+		return value, !exists, nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	return actual, loaded, nil
+}
+
+// Upsert stores fn(old, exists) as the value of hash with the default time to
+// live, renewing it for an existing hash, atomically. An expired hash counts
+// as missing. See the notes at the top of upsert.go.
+func (x *ExpiringMap) Upsert(hash chainhash.Hash, fn func(old uint64, exists bool) uint64) (uint64, error) {
+	if x.frozen.Load() {
+		return 0, ErrMapFrozen
+	}
+
+	var value uint64
+
+	err := x.e.put(hash, 0, x.e.ttl, func(existing uint64, exists bool) (uint64, bool, error) {
+		if !exists {
+			existing = 0
+		}
+
+		value = fn(existing, exists)
+
+		return value, true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,135 @@
+package txmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upsertImpls returns a fresh instance of every concrete type that implements
+// UpsertTxMap, keyed by type name.
+func upsertImpls() map[string]func() UpsertTxMap {
+	return map[string]func() UpsertTxMap{
+		"SwissMapUint64":       func() UpsertTxMap { return NewSwissMapUint64(1024) },
+		"NativeMapUint64":      func() UpsertTxMap { return NewNativeMapUint64(1024) },
+		"SplitSwissMap":        func() UpsertTxMap { return NewSplitSwissMap(1024) },
+		"SplitSwissMapUint64":  func() UpsertTxMap { return NewSplitSwissMapUint64(1024) },
+		"NativeSplitMap":       func() UpsertTxMap { return NewNativeSplitMap(1024) },
+		"NativeSplitMapUint64": func() UpsertTxMap { return NewNativeSplitMapUint64(1024) },
+		"TwoChoiceSplitMap":    func() UpsertTxMap { return NewTwoChoiceSplitMap(1024) },
+		"HybridSplitMap":       func() UpsertTxMap { return NewHybridSplitMap(1024, time.Hour) },
+		"LRUMap":               func() UpsertTxMap { return NewLRUMap(0) },
+		"ExpiringMap": func() UpsertTxMap {
+			return NewExpiringMap(1024, ExpiryOptions{SweepInterval: -1})
+		},
+	}
+}
+
+// TestGetOrPutUpsert tests GetOrPut and Upsert on every UpsertTxMap,
+// including the frozen case.
+func TestGetOrPutUpsert(t *testing.T) {
+	for name, factory := range upsertImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			actual, loaded, err := m.GetOrPut(hashN(1), 10)
+			require.NoError(t, err)
+			assert.False(t, loaded)
+			assert.Equal(t, uint64(10), actual)
+
+			actual, loaded, err = m.GetOrPut(hashN(1), 20)
+			require.NoError(t, err)
+			assert.True(t, loaded)
+			assert.Equal(t, uint64(10), actual)
+
+			value, err := m.Upsert(hashN(2), func(old uint64, exists bool) uint64 {
+				assert.False(t, exists)
+				assert.Equal(t, uint64(0), old)
+
+				return 5
+			})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(5), value)
+
+			value, err = m.Upsert(hashN(2), func(old uint64, exists bool) uint64 {
+				assert.True(t, exists)
+				return old + 1
+			})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(6), value)
+
+			got, ok := m.Get(hashN(2))
+			require.True(t, ok)
+			assert.Equal(t, uint64(6), got)
+			assert.Equal(t, 2, m.Length())
+
+			m.Freeze()
+
+			_, _, err = m.GetOrPut(hashN(3), 1)
+			require.ErrorIs(t, err, ErrMapFrozen)
+
+			_, err = m.Upsert(hashN(1), func(old uint64, _ bool) uint64 { return old })
+			require.ErrorIs(t, err, ErrMapFrozen)
+		})
+	}
+}
+
+// TestUpsertConcurrent tests that concurrent Upserts of the same hashes lose
+// no increments, and that concurrent GetOrPuts agree on a single winner.
+func TestUpsertConcurrent(t *testing.T) {
+	const (
+		workers = 8
+		rounds  = 1024
+		hashes  = 16
+	)
+
+	for name, factory := range upsertImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+
+			var (
+				wg      sync.WaitGroup
+				winners [hashes]int
+				mu      sync.Mutex
+			)
+
+			for w := range workers {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					for i := range rounds {
+						_, err := m.Upsert(hashN(i%hashes), func(old uint64, _ bool) uint64 { return old + 1 })
+						assert.NoError(t, err)
+					}
+
+					for i := range hashes {
+						_, loaded, err := m.GetOrPut(hashN(hashes+i), uint64(w)) //nolint:gosec // G115 test values
+						assert.NoError(t, err)
+
+						if !loaded {
+							mu.Lock()
+							winners[i]++
+							mu.Unlock()
+						}
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			for i := range hashes {
+				value, ok := m.Get(hashN(i))
+				require.True(t, ok)
+				assert.Equal(t, uint64(workers*rounds/hashes), value)
+				assert.Equal(t, 1, winners[i])
+			}
+
+			assert.Equal(t, 2*hashes, m.Length())
+		})
+	}
+}
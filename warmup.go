@@ -0,0 +1,90 @@
+package txmap
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+const (
+	// warmUpSlicesPerSecond is the number of slices a second of warm-up is
+	// divided into; WarmUp sleeps at most once per slice.
+	warmUpSlicesPerSecond = 100
+
+	// warmUpCheckEvery is the number of entries between context checks of an
+	// unthrottled WarmUp.
+	warmUpCheckEvery = 4096
+)
+
+// WarmUp fills m from src at no more than ratePerSec entries per second, so
+// that restoring a large map on a live node leaves memory bandwidth and CPU to
+// the work the node is doing meanwhile. Entries are put in slices of a
+// hundredth of a second's worth; after each slice WarmUp sleeps until the
+// slice is due, so bursts stay short and the rate holds on average.
+//
+// Params:
+//   - ctx: Cancels the warm-up, including while it sleeps.
+//   - m: The map to fill, with Put and its duplicate policy.
+//   - src: The entries to put, e.g. All of another map.
+//   - ratePerSec: The maximum number of entries per second; zero or less
+//     puts as fast as possible, still checking ctx regularly.
+//
+// Returns:
+//   - int: The number of entries put, also on error.
+//   - error: The context error, or the first error of Put, wrapped with its
+//     hash. The entries put before it remain in m.
+func WarmUp(ctx context.Context, m TxMap, src iter.Seq2[chainhash.Hash, uint64], ratePerSec int) (int, error) {
+	slice := warmUpCheckEvery
+	if ratePerSec > 0 {
+		slice = max(1, ratePerSec/warmUpSlicesPerSecond)
+	}
+
+	var (
+		n     int
+		err   error
+		start = time.Now()
+	)
+
+	for hash, value := range src {
+		if err = m.Put(hash, value); err != nil {
+			err = fmt.Errorf("warm up %s: %w", hash, err)
+			break
+		}
+
+		if n++; n%slice != 0 {
+			continue
+		}
+
+		if err = warmUpPause(ctx, start, n, ratePerSec); err != nil {
+			break
+		}
+	}
+
+	return n, err
+}
+
+// warmUpPause returns once n entries are due at ratePerSec since start, or
+// immediately without a rate, and returns the context error if ctx ends first.
+func warmUpPause(ctx context.Context, start time.Time, n, ratePerSec int) error {
+	if ratePerSec <= 0 {
+		return ctx.Err()
+	}
+
+	wait := time.Until(start.Add(time.Duration(n) * time.Second / time.Duration(ratePerSec)))
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+package txmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seqOf returns the pairs hashN(i), i for i in 0..n-1.
+func seqOf(n int) func(yield func(chainhash.Hash, uint64) bool) {
+	return func(yield func(chainhash.Hash, uint64) bool) {
+		for i := range n {
+			if !yield(hashN(i), uint64(i)) {
+				return
+			}
+		}
+	}
+}
+
+// TestWarmUp tests that WarmUp fills the map and holds the rate.
+func TestWarmUp(t *testing.T) {
+	m := NewNativeMapUint64(0)
+
+	start := time.Now()
+	n, err := WarmUp(context.Background(), m, seqOf(1000), 10_000)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1000, n)
+	assert.Equal(t, 1000, m.Length())
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	dst := NewSplitSwissMapUint64(0, 8)
+
+	n, err = WarmUp(context.Background(), dst, All(m), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, n)
+	requireSameContents(t, m, dst)
+}
+
+// TestWarmUpStops tests cancellation while sleeping and Put errors.
+func TestWarmUpStops(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	m := NewNativeMapUint64(0)
+
+	n, err := WarmUp(ctx, m, seqOf(1000), 100)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, n, 1000)
+	assert.Equal(t, n, m.Length())
+
+	_, err = WarmUp(context.Background(), m, seqOf(10), 0)
+	require.ErrorIs(t, err, ErrHashAlreadyExists)
+}
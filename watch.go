@@ -0,0 +1,236 @@
+package txmap
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// check that WatchableTxMap implements TxMap
+var _ TxMap = (*WatchableTxMap)(nil)
+
+// WatchableTxMap wraps a TxMap and lets callers wait for a specific hash to be
+// inserted into or deleted from it, instead of polling Exists in a loop.
+//
+// All writes must go through the WatchableTxMap; changes made directly to the
+// wrapped map are not observed.
+type WatchableTxMap struct {
+	m TxMap
+
+	mu       sync.Mutex
+	watchers map[chainhash.Hash][]chan struct{}
+
+	// nrOfWatchers lets the write path skip the watcher mutex entirely while
+	// nobody is waiting, which is the common case on a busy map.
+	nrOfWatchers atomic.Int64
+}
+
+// NewWatchableTxMap returns a WatchableTxMap that forwards every operation to m
+// and notifies watchers after each successful insertion or deletion.
+//
+// Params:
+//   - m: The map to wrap.
+//
+// Returns:
+//   - *WatchableTxMap: The wrapping map.
+func NewWatchableTxMap(m TxMap) *WatchableTxMap {
+	m = nonNilTxMap(m)
+
+	return &WatchableTxMap{
+		m:        m,
+		watchers: make(map[chainhash.Hash][]chan struct{}),
+	}
+}
+
+// Watch returns a channel that is closed the next time hash is inserted into or
+// deleted from the map (including by Clear). Each call returns a new channel
+// that fires exactly once.
+//
+// To wait for a hash without missing an insertion that races with the call,
+// register the watch before checking for existence:
+//
+//	ch := m.Watch(hash)
+//	if !m.Exists(hash) {
+//		<-ch
+//	}
+//
+// Params:
+//   - hash: The hash to watch.
+//
+// Returns:
+//   - <-chan struct{}: A channel closed on the next membership change of hash.
+//
+// Considerations: a channel that never fires stays registered; call Unwatch
+// when giving up on it (e.g. on context cancellation) to release it.
+func (w *WatchableTxMap) Watch(hash chainhash.Hash) <-chan struct{} {
+	ch := make(chan struct{})
+
+	w.mu.Lock()
+	w.nrOfWatchers.Add(1)
+	w.watchers[hash] = append(w.watchers[hash], ch)
+	w.mu.Unlock()
+
+	return ch
+}
+
+// Unwatch removes a channel previously returned by Watch for hash without
+// closing it. It is a no-op if the channel already fired.
+//
+// Params:
+//   - hash: The hash the channel was registered for.
+//   - ch: The channel returned by Watch.
+func (w *WatchableTxMap) Unwatch(hash chainhash.Hash, ch <-chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	chans := w.watchers[hash]
+	for i, c := range chans {
+		if c == ch {
+			chans = append(chans[:i], chans[i+1:]...)
+			w.nrOfWatchers.Add(-1)
+
+			break
+		}
+	}
+
+	if len(chans) == 0 {
+		delete(w.watchers, hash)
+	} else {
+		w.watchers[hash] = chans
+	}
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (w *WatchableTxMap) Exists(hash chainhash.Hash) bool {
+	return w.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (w *WatchableTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return w.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (w *WatchableTxMap) Keys() []chainhash.Hash {
+	return w.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (w *WatchableTxMap) Length() int {
+	return w.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (w *WatchableTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	w.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map and fires its watchers on success.
+func (w *WatchableTxMap) Put(hash chainhash.Hash, value uint64) error {
+	if err := w.m.Put(hash, value); err != nil {
+		return err
+	}
+
+	w.notify(hash)
+
+	return nil
+}
+
+// PutMulti adds hashes to the wrapped map and fires the watchers of every hash
+// that was inserted. If the wrapped map fails part-way, watchers of the hashes
+// that made it in are still fired; watchers of the duplicate that caused the
+// failure may fire as well, which is harmless under the Watch-then-Exists
+// pattern.
+func (w *WatchableTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	err := w.m.PutMulti(hashes, value)
+
+	if w.nrOfWatchers.Load() > 0 {
+		for _, hash := range hashes {
+			if err == nil || w.m.Exists(hash) {
+				w.notify(hash)
+			}
+		}
+	}
+
+	return err
+}
+
+// Set updates the value of an existing hash. Membership does not change, so no
+// watchers fire.
+func (w *WatchableTxMap) Set(hash chainhash.Hash, value uint64) error {
+	return w.m.Set(hash, value)
+}
+
+// SetIfExists updates the value of hash if it exists. Membership does not
+// change, so no watchers fire.
+func (w *WatchableTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return w.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash if it does not exist yet and fires its watchers when
+// it was added.
+func (w *WatchableTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	added, err := w.m.SetIfNotExists(hash, value)
+	if added {
+		w.notify(hash)
+	}
+
+	return added, err
+}
+
+// Delete removes hash from the wrapped map and fires its watchers on success.
+func (w *WatchableTxMap) Delete(hash chainhash.Hash) error {
+	if err := w.m.Delete(hash); err != nil {
+		return err
+	}
+
+	w.notify(hash)
+
+	return nil
+}
+
+// Freeze freezes the wrapped map.
+func (w *WatchableTxMap) Freeze() {
+	w.m.Freeze()
+}
+
+// Clear empties the wrapped map and fires every registered watcher, since every
+// hash that was present has been deleted.
+func (w *WatchableTxMap) Clear() {
+	w.m.Clear()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for hash, chans := range w.watchers {
+		for _, ch := range chans {
+			close(ch)
+		}
+
+		w.nrOfWatchers.Add(-int64(len(chans)))
+		delete(w.watchers, hash)
+	}
+}
+
+// notify closes and unregisters every channel watching hash.
+func (w *WatchableTxMap) notify(hash chainhash.Hash) {
+	if w.nrOfWatchers.Load() == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	chans, ok := w.watchers[hash]
+	delete(w.watchers, hash)
+	w.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	w.nrOfWatchers.Add(-int64(len(chans)))
+
+	for _, ch := range chans {
+		close(ch)
+	}
+}
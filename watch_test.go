@@ -0,0 +1,101 @@
+package txmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requireFired fails the test unless ch is closed within a short timeout.
+func requireFired(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		require.Fail(t, "watch channel did not fire")
+	}
+}
+
+// requireNotFired fails the test if ch is already closed.
+func requireNotFired(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-ch:
+		require.Fail(t, "watch channel fired unexpectedly")
+	default:
+	}
+}
+
+// TestWatchableTxMap tests the basic TxMap behavior of WatchableTxMap.
+func TestWatchableTxMap(t *testing.T) {
+	testTxMap(t, NewWatchableTxMap(NewSwissMapUint64(100)))
+}
+
+// TestWatchableTxMapWatch tests that watchers fire on insertion and deletion,
+// but not on value updates.
+func TestWatchableTxMapWatch(t *testing.T) {
+	m := NewWatchableTxMap(NewNativeSplitMap(100))
+	h1, h2 := hashN(1), hashN(2)
+
+	ch := m.Watch(h1)
+	other := m.Watch(h2)
+
+	go func() {
+		_ = m.Put(h1, 1)
+	}()
+
+	requireFired(t, ch)
+	requireNotFired(t, other)
+
+	ch = m.Watch(h1)
+	require.NoError(t, m.Set(h1, 2))
+	requireNotFired(t, ch)
+
+	require.NoError(t, m.Delete(h1))
+	requireFired(t, ch)
+
+	added, err := m.SetIfNotExists(h2, 3)
+	require.NoError(t, err)
+	require.True(t, added)
+	requireFired(t, other)
+}
+
+// TestWatchableTxMapPutMulti tests that PutMulti fires the watchers of every
+// inserted hash, including on a partial failure.
+func TestWatchableTxMapPutMulti(t *testing.T) {
+	m := NewWatchableTxMap(NewSwissMapUint64(100))
+	h1, h2, h3 := hashN(1), hashN(2), hashN(3)
+
+	require.NoError(t, m.Put(h2, 0))
+
+	ch1 := m.Watch(h1)
+	ch3 := m.Watch(h3)
+
+	require.ErrorIs(t, m.PutMulti([]chainhash.Hash{h1, h2, h3}, 1), ErrHashAlreadyExists)
+	requireFired(t, ch1)
+	requireNotFired(t, ch3)
+}
+
+// TestWatchableTxMapClearAndUnwatch tests that Clear fires every watcher and
+// that Unwatch releases a channel without firing it.
+func TestWatchableTxMapClearAndUnwatch(t *testing.T) {
+	m := NewWatchableTxMap(NewNativeMapUint64(100))
+	h1, h2 := hashN(1), hashN(2)
+
+	ch1 := m.Watch(h1)
+	ch2 := m.Watch(h2)
+
+	m.Unwatch(h2, ch2)
+	assert.Equal(t, int64(1), m.nrOfWatchers.Load())
+
+	m.Clear()
+	requireFired(t, ch1)
+	requireNotFired(t, ch2)
+	assert.Equal(t, int64(0), m.nrOfWatchers.Load())
+	assert.Empty(t, m.watchers)
+}
@@ -0,0 +1,201 @@
+package txmap
+
+import (
+	"runtime"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// Cooperative yielding
+//
+// Iter and Keys hold the read lock of a bucket while they scan it, so on a
+// bucket of hundreds of millions of entries a full scan blocks writers to that
+// bucket for seconds. IterYield and KeysYield instead release the read lock
+// every yieldEvery entries, let waiting writers run, and then continue the
+// scan where they left off.
+//
+// The price is consistency: a bucket is no longer scanned as a whole under its
+// lock. Entries added while the lock was released may or may not be visited,
+// entries deleted may still be visited, and an entry updated may be visited
+// with its old value; every entry that exists for the whole scan is visited
+// exactly once. Frozen maps take no locks, so their scans never yield.
+
+// YieldingTxMap is implemented by maps whose full scans can periodically
+// release their locks.
+type YieldingTxMap interface {
+	TxMap
+
+	// IterYield iterates like Iter, releasing the read lock every yieldEvery
+	// entries. Values below one never yield.
+	IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool)
+
+	// KeysYield returns all hashes like Keys, releasing the read lock every
+	// yieldEvery entries. Values below one never yield.
+	KeysYield(yieldEvery int) []chainhash.Hash
+}
+
+// Compile-time checks of the maps that implement YieldingTxMap.
+var (
+	_ YieldingTxMap = (*SwissMapUint64)(nil)
+	_ YieldingTxMap = (*NativeMapUint64)(nil)
+	_ YieldingTxMap = (*SplitSwissMap)(nil)
+	_ YieldingTxMap = (*SplitSwissMapUint64)(nil)
+	_ YieldingTxMap = (*NativeSplitMap)(nil)
+	_ YieldingTxMap = (*NativeSplitMapUint64)(nil)
+)
+
+// IterYield iterates over the map like Iter, releasing the read lock every
+// yieldEvery entries. See the notes at the top of this file.
+func (s *SwissMapUint64) IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool) {
+	if yieldEvery < 1 || s.frozen.Load() {
+		s.Iter(f)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+
+	s.m.Iter(func(k chainhash.Hash, v uint64) (stop bool) {
+		if f(k, v) {
+			return true
+		}
+
+		if n++; n%yieldEvery == 0 {
+			yieldRLock(&s.mu)
+		}
+
+		return false
+	})
+}
+
+// KeysYield returns all hashes in the map like Keys, releasing the read lock
+// every yieldEvery entries.
+func (s *SwissMapUint64) KeysYield(yieldEvery int) []chainhash.Hash {
+	return keysYield(s, yieldEvery)
+}
+
+// IterYield iterates over the map like Iter, releasing the read lock every
+// yieldEvery entries. See the notes at the top of this file.
+func (s *NativeMapUint64) IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool) {
+	if yieldEvery < 1 || s.frozen.Load() {
+		s.Iter(f)
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+
+	// the iterator only touches the map while the read lock is held, and Go
+	// maps support writes between the steps of a range loop
+	for k, v := range s.m {
+		if f(k, v) {
+			return
+		}
+
+		if n++; n%yieldEvery == 0 {
+			yieldRLock(&s.mu)
+		}
+	}
+}
+
+// KeysYield returns all hashes in the map like Keys, releasing the read lock
+// every yieldEvery entries.
+func (s *NativeMapUint64) KeysYield(yieldEvery int) []chainhash.Hash {
+	return keysYield(s, yieldEvery)
+}
+
+// IterYield iterates over the buckets like Iter, releasing the read lock of
+// the bucket being scanned every yieldEvery entries.
+func (g *SplitSwissMap) IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool) {
+	splitIterYield(g.m, g.nrOfBuckets, yieldEvery, f)
+}
+
+// KeysYield returns all hashes like Keys, releasing the read lock of the
+// bucket being scanned every yieldEvery entries.
+func (g *SplitSwissMap) KeysYield(yieldEvery int) []chainhash.Hash {
+	return keysYield(g, yieldEvery)
+}
+
+// IterYield iterates over the buckets like Iter, releasing the read lock of
+// the bucket being scanned every yieldEvery entries.
+func (g *SplitSwissMapUint64) IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool) {
+	splitIterYield(g.m, g.nrOfBuckets, yieldEvery, f)
+}
+
+// KeysYield returns all hashes like Keys, releasing the read lock of the
+// bucket being scanned every yieldEvery entries.
+func (g *SplitSwissMapUint64) KeysYield(yieldEvery int) []chainhash.Hash {
+	return keysYield(g, yieldEvery)
+}
+
+// IterYield iterates over the buckets like Iter, releasing the read lock of
+// the bucket being scanned every yieldEvery entries.
+func (g *NativeSplitMap) IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool) {
+	splitIterYield(g.m, g.nrOfBuckets, yieldEvery, f)
+}
+
+// KeysYield returns all hashes like Keys, releasing the read lock of the
+// bucket being scanned every yieldEvery entries.
+func (g *NativeSplitMap) KeysYield(yieldEvery int) []chainhash.Hash {
+	return keysYield(g, yieldEvery)
+}
+
+// IterYield iterates over the buckets like Iter, releasing the read lock of
+// the bucket being scanned every yieldEvery entries.
+func (g *NativeSplitMapUint64) IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool) {
+	splitIterYield(g.m, g.nrOfBuckets, yieldEvery, f)
+}
+
+// KeysYield returns all hashes like Keys, releasing the read lock of the
+// bucket being scanned every yieldEvery entries.
+func (g *NativeSplitMapUint64) KeysYield(yieldEvery int) []chainhash.Hash {
+	return keysYield(g, yieldEvery)
+}
+
+// rLocker is the read side of a sync.RWMutex.
+type rLocker interface {
+	RLock()
+	RUnlock()
+}
+
+// yieldRLock releases the read lock held by the caller, lets other goroutines,
+// e.g. writers waiting for the lock, run, and reacquires it.
+func yieldRLock(mu rLocker) {
+	mu.RUnlock()
+	runtime.Gosched()
+	mu.RLock()
+}
+
+// iterYielder is the IterYield method shared by the leaf and split maps.
+type iterYielder interface {
+	Length() int
+	IterYield(yieldEvery int, f func(hash chainhash.Hash, value uint64) bool)
+}
+
+// keysYield implements KeysYield on top of IterYield.
+func keysYield(m iterYielder, yieldEvery int) []chainhash.Hash {
+	keys := make([]chainhash.Hash, 0, m.Length())
+
+	m.IterYield(yieldEvery, func(hash chainhash.Hash, _ uint64) bool {
+		keys = append(keys, hash)
+		return false
+	})
+
+	return keys
+}
+
+// splitIterYield implements IterYield for a split map.
+func splitIterYield[M iterYielder](buckets []M, nrOfBuckets uint16, yieldEvery int, f func(hash chainhash.Hash, value uint64) bool) {
+	stopped := false
+
+	for i := uint16(0); i <= nrOfBuckets && !stopped; i++ {
+		buckets[i].IterYield(yieldEvery, func(hash chainhash.Hash, value uint64) bool {
+			stopped = f(hash, value)
+			return stopped
+		})
+	}
+}
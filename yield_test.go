@@ -0,0 +1,104 @@
+package txmap
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIterYield tests that IterYield and KeysYield visit every entry of every
+// TxMap implementation and stop early like Iter.
+func TestIterYield(t *testing.T) {
+	const n = 1000
+
+	for name, factory := range txMapImpls() {
+		t.Run(name, func(t *testing.T) {
+			m := factory()
+			for i := 0; i < n; i++ {
+				require.NoError(t, m.Put(hashN(i), uint64(i))) //nolint:gosec // test sizes are small
+			}
+
+			y, ok := m.(YieldingTxMap)
+			require.True(t, ok)
+
+			assert.ElementsMatch(t, m.Keys(), y.KeysYield(7))
+
+			visited := 0
+			y.IterYield(3, func(_ chainhash.Hash, _ uint64) bool {
+				visited++
+				return visited == 10
+			})
+			assert.Equal(t, 10, visited)
+
+			m.Freeze()
+			assert.Len(t, y.KeysYield(1), n)
+		})
+	}
+}
+
+// TestIterYieldLetsWritersIn tests that a writer blocked on the map's lock
+// gets through while a yielding scan is still running.
+func TestIterYieldLetsWritersIn(t *testing.T) {
+	for name, m := range map[string]YieldingTxMap{
+		"SwissMapUint64":  NewSwissMapUint64(0),
+		"NativeMapUint64": NewNativeMapUint64(0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				require.NoError(t, m.Put(hashN(i), 1))
+			}
+
+			written := make(chan struct{})
+			started := make(chan struct{})
+
+			var once sync.Once
+
+			m.IterYield(1, func(_ chainhash.Hash, _ uint64) bool {
+				once.Do(func() {
+					close(started)
+
+					go func() {
+						_ = m.Put(hashN(1000), 1)
+						close(written)
+					}()
+				})
+
+				select {
+				case <-written:
+					return true
+				case <-time.After(time.Millisecond):
+					return false
+				}
+			})
+
+			<-started
+			select {
+			case <-written:
+			case <-time.After(time.Second):
+				t.Fatal("writer did not get the lock during the scan")
+			}
+
+			assert.True(t, m.Exists(hashN(1000)))
+		})
+	}
+}
+
+// TestExportYield tests that an export with YieldEvery produces the same
+// snapshot contents.
+func TestExportYield(t *testing.T) {
+	m := populatedMap(t, 1000)
+
+	var buf bytes.Buffer
+	require.NoError(t, ExportWithOptions(&buf, m, ExportOptions{YieldEvery: 10}))
+
+	dst := NewNativeMapUint64(0)
+	_, err := Import(context.Background(), dst, &buf, ImportOptions{})
+	require.NoError(t, err)
+	requireSameContents(t, m, dst)
+}
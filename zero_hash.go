@@ -0,0 +1,119 @@
+package txmap
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+)
+
+// ErrZeroHash is returned by a map created with WithRejectZeroHash when the
+// all-zero hash is inserted.
+var ErrZeroHash = errors.New("all-zero hash rejected")
+
+// check that RejectZeroHashTxMap implements TxMap
+var _ TxMap = (*RejectZeroHashTxMap)(nil)
+
+// RejectZeroHashTxMap wraps a TxMap and refuses to insert the all-zero
+// chainhash. A zero hash almost always comes from an uninitialized value
+// upstream; rejecting it at insertion reports the bug where it happens rather
+// than when the bogus entry is found much later.
+type RejectZeroHashTxMap struct {
+	m TxMap
+}
+
+// WithRejectZeroHash returns a RejectZeroHashTxMap forwarding every operation
+// to m.
+//
+// Params:
+//   - m: The map to wrap.
+//
+// Returns:
+//   - *RejectZeroHashTxMap: The wrapping map.
+func WithRejectZeroHash(m TxMap) *RejectZeroHashTxMap {
+	m = nonNilTxMap(m)
+
+	return &RejectZeroHashTxMap{m: m}
+}
+
+// Exists checks if the given hash exists in the wrapped map.
+func (r *RejectZeroHashTxMap) Exists(hash chainhash.Hash) bool {
+	return r.m.Exists(hash)
+}
+
+// Get retrieves the value associated with the given hash from the wrapped map.
+func (r *RejectZeroHashTxMap) Get(hash chainhash.Hash) (uint64, bool) {
+	return r.m.Get(hash)
+}
+
+// Keys returns all hashes in the wrapped map.
+func (r *RejectZeroHashTxMap) Keys() []chainhash.Hash {
+	return r.m.Keys()
+}
+
+// Length returns the number of hashes in the wrapped map.
+func (r *RejectZeroHashTxMap) Length() int {
+	return r.m.Length()
+}
+
+// Iter iterates over the wrapped map. Stops iterating if f returns true.
+func (r *RejectZeroHashTxMap) Iter(f func(hash chainhash.Hash, value uint64) bool) {
+	r.m.Iter(f)
+}
+
+// Put adds hash to the wrapped map, or returns an error wrapping ErrZeroHash
+// for the all-zero hash.
+func (r *RejectZeroHashTxMap) Put(hash chainhash.Hash, value uint64) error {
+	if hash == (chainhash.Hash{}) {
+		return fmt.Errorf("%w: Put", ErrZeroHash)
+	}
+
+	return r.m.Put(hash, value)
+}
+
+// PutMulti adds hashes to the wrapped map. If any of them is the all-zero
+// hash, nothing is inserted and an error wrapping ErrZeroHash reports its index.
+func (r *RejectZeroHashTxMap) PutMulti(hashes []chainhash.Hash, value uint64) error {
+	for i, hash := range hashes {
+		if hash == (chainhash.Hash{}) {
+			return fmt.Errorf("%w: PutMulti index %d", ErrZeroHash, i)
+		}
+	}
+
+	return r.m.PutMulti(hashes, value)
+}
+
+// Set updates the value of an existing hash in the wrapped map.
+func (r *RejectZeroHashTxMap) Set(hash chainhash.Hash, value uint64) error {
+	return r.m.Set(hash, value)
+}
+
+// SetIfExists updates the value of hash in the wrapped map if it exists.
+func (r *RejectZeroHashTxMap) SetIfExists(hash chainhash.Hash, value uint64) (bool, error) {
+	return r.m.SetIfExists(hash, value)
+}
+
+// SetIfNotExists adds hash to the wrapped map if it does not exist yet, or
+// returns an error wrapping ErrZeroHash for the all-zero hash.
+func (r *RejectZeroHashTxMap) SetIfNotExists(hash chainhash.Hash, value uint64) (bool, error) {
+	if hash == (chainhash.Hash{}) {
+		return false, fmt.Errorf("%w: SetIfNotExists", ErrZeroHash)
+	}
+
+	return r.m.SetIfNotExists(hash, value)
+}
+
+// Delete removes hash from the wrapped map.
+func (r *RejectZeroHashTxMap) Delete(hash chainhash.Hash) error {
+	return r.m.Delete(hash)
+}
+
+// Freeze freezes the wrapped map.
+func (r *RejectZeroHashTxMap) Freeze() {
+	r.m.Freeze()
+}
+
+// Clear empties the wrapped map.
+func (r *RejectZeroHashTxMap) Clear() {
+	r.m.Clear()
+}
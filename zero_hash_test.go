@@ -0,0 +1,30 @@
+package txmap
+
+import (
+	"testing"
+
+	"github.com/bsv-blockchain/go-bt/v2/chainhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithRejectZeroHash tests that every insertion path rejects the all-zero
+// hash without modifying the map.
+func TestWithRejectZeroHash(t *testing.T) {
+	m := WithRejectZeroHash(NewNativeSplitMapUint64(16, 4))
+
+	require.ErrorIs(t, m.Put(chainhash.Hash{}, 1), ErrZeroHash)
+
+	err := m.PutMulti([]chainhash.Hash{hashN(1), {}, hashN(2)}, 1)
+	require.ErrorIs(t, err, ErrZeroHash)
+	assert.Contains(t, err.Error(), "index 1")
+
+	added, err := m.SetIfNotExists(chainhash.Hash{}, 1)
+	require.ErrorIs(t, err, ErrZeroHash)
+	assert.False(t, added)
+
+	assert.Equal(t, 0, m.Length())
+
+	require.NoError(t, m.PutMulti([]chainhash.Hash{hashN(1), hashN(2)}, 1))
+	assert.Equal(t, 2, m.Length())
+}